@@ -302,9 +302,9 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 						ctx = context.WithValue(ctx, ViewKey{}, h.ViewName)
 					}
 					if r.Question[0].Qtype != dns.TypeDS {
-						rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
+						rcode, err := h.pluginChain.ServeDNS(ctx, w, r)
 						if !plugin.ClientWrite(rcode) {
-							errorFunc(s.Addr, w, r, rcode)
+							errorFunc(s.Addr, w, r, rcode, err)
 						}
 						return
 					}
@@ -325,9 +325,9 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	if r.Question[0].Qtype == dns.TypeDS && dshandler != nil && dshandler.pluginChain != nil {
 		// DS request, and we found a zone, use the handler for the query.
-		rcode, _ := dshandler.pluginChain.ServeDNS(ctx, w, r)
+		rcode, err := dshandler.pluginChain.ServeDNS(ctx, w, r)
 		if !plugin.ClientWrite(rcode) {
-			errorFunc(s.Addr, w, r, rcode)
+			errorFunc(s.Addr, w, r, rcode, err)
 		}
 		return
 	}
@@ -350,9 +350,9 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 					// if there was a view defined for this Config, set the view name in the context
 					ctx = context.WithValue(ctx, ViewKey{}, h.ViewName)
 				}
-				rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
+				rcode, err := h.pluginChain.ServeDNS(ctx, w, r)
 				if !plugin.ClientWrite(rcode) {
-					errorFunc(s.Addr, w, r, rcode)
+					errorFunc(s.Addr, w, r, rcode, err)
 				}
 				return
 			}
@@ -395,13 +395,17 @@ func (s *Server) Tracer() ot.Tracer {
 	return s.trace.Tracer()
 }
 
-// errorFunc responds to an DNS request with an error.
-func errorFunc(server string, w dns.ResponseWriter, r *dns.Msg, rc int) {
+// errorFunc responds to an DNS request with an error. If err carries an Extended DNS Error, it is
+// added to the response.
+func errorFunc(server string, w dns.ResponseWriter, r *dns.Msg, rc int, err error) {
 	state := request.Request{W: w, Req: r}
 
 	answer := new(dns.Msg)
 	answer.SetRcode(r, rc)
 	state.SizeAndDo(answer)
+	if code, text, ok := edns.ExtendedErrorFrom(err); ok {
+		edns.SetExtendedError(r, answer, code, text)
+	}
 
 	w.WriteMsg(answer)
 }
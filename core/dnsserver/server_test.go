@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/test"
 
@@ -180,3 +182,28 @@ func BenchmarkCoreServeDNS(b *testing.B) {
 		s.ServeDNS(ctx, w, m)
 	}
 }
+
+func TestErrorFuncExtendedError(t *testing.T) {
+	err := plugin.Error("forward", edns.WithExtendedError(errors.New("no healthy upstream"), dns.ExtendedErrorCodeNoReachableAuthority, "No healthy upstream"))
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	req.SetEdns0(4096, false)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	errorFunc("", rec, req, dns.RcodeServerFailure, err)
+	if rec.Msg.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected SERVFAIL, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	if ede := edns.ExtendedError(rec.Msg); ede == nil || ede.InfoCode != dns.ExtendedErrorCodeNoReachableAuthority {
+		t.Errorf("Expected Extended DNS Error %d, got %v", dns.ExtendedErrorCodeNoReachableAuthority, ede)
+	}
+
+	// A client without EDNS doesn't get an Extended DNS Error.
+	req = new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	errorFunc("", rec, req, dns.RcodeServerFailure, err)
+	if rec.Msg.IsEdns0() != nil {
+		t.Errorf("Expected no OPT record, got %v", rec.Msg.IsEdns0())
+	}
+}
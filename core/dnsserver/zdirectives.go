@@ -21,6 +21,7 @@ var Directives = []string{
 	"multisocket",
 	"reload",
 	"nsid",
+	"cookie",
 	"bufsize",
 	"bind",
 	"debug",
@@ -32,9 +33,11 @@ var Directives = []string{
 	"errors",
 	"log",
 	"dnstap",
+	"rrl",
 	"local",
 	"dns64",
 	"acl",
+	"blocklist",
 	"any",
 	"chaos",
 	"loadbalance",
@@ -47,7 +50,10 @@ var Directives = []string{
 	"minimal",
 	"template",
 	"transfer",
+	"gslb",
 	"hosts",
+	"docker",
+	"leases",
 	"route53",
 	"azure",
 	"clouddns",
@@ -55,11 +61,14 @@ var Directives = []string{
 	"kubernetes",
 	"file",
 	"auto",
+	"sql",
 	"secondary",
 	"etcd",
 	"loop",
 	"rewrite_ip",
+	"validate",
 	"forward",
+	"recursor",
 	"https",
 	"grpc",
 	"erratic",
@@ -68,4 +77,5 @@ var Directives = []string{
 	"sign",
 	"view",
 	"nomad",
+	"consul",
 }
@@ -7,6 +7,8 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cookie"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/request"
 
@@ -38,6 +40,13 @@ type policy struct {
 	action action
 	qtypes map[uint16]struct{}
 	filter *iptree.Tree
+
+	// limiter, exceed and exemptCookie are only used by ratelimit policies, exceed is the
+	// action performed on queries exceeding the rate. Queries with a valid server cookie
+	// are not limited when exemptCookie is set.
+	limiter      *limiter
+	exceed       action
+	exemptCookie bool
 }
 
 const (
@@ -51,6 +60,15 @@ const (
 	actionFilter
 	// actionDrop does not respond for queries towards the protected DNS zones.
 	actionDrop
+	// actionRateLimit limits the rate of queries towards the protected DNS zones.
+	actionRateLimit
+	// actionLimitRefuse returns REFUSED for queries exceeding a rate limit.
+	actionLimitRefuse
+	// actionLimitDrop does not respond for queries exceeding a rate limit.
+	actionLimitDrop
+	// actionLimitTruncate returns an empty truncated response for queries exceeding a
+	// rate limit, forcing clients to retry over TCP.
+	actionLimitTruncate
 )
 
 var log = clog.NewWithPlugin("acl")
@@ -67,7 +85,7 @@ RulesCheckLoop:
 			continue
 		}
 
-		action := matchWithPolicies(rule.policies, w, r)
+		action := matchWithPolicies(ctx, rule.policies, w, r)
 		switch action {
 		case actionDrop:
 			{
@@ -89,6 +107,24 @@ RulesCheckLoop:
 			{
 				break RulesCheckLoop
 			}
+		case actionLimitRefuse, actionLimitDrop, actionLimitTruncate:
+			{
+				RequestRateLimitCount.WithLabelValues(metrics.WithServer(ctx), zone, metrics.WithView(ctx)).Inc()
+				if action == actionLimitDrop {
+					return dns.RcodeSuccess, nil
+				}
+				m := new(dns.Msg)
+				m.SetReply(r)
+				// Truncation is meaningless for stream based transports, refuse those instead.
+				if action == actionLimitTruncate && state.Proto() == "udp" {
+					m.Truncated = true
+				} else {
+					m.Rcode = dns.RcodeRefused
+					edns.SetExtendedError(r, m, dns.ExtendedErrorCodeProhibited, "Rate limit exceeded")
+				}
+				w.WriteMsg(m)
+				return dns.RcodeSuccess, nil
+			}
 		case actionFilter:
 			{
 				m := new(dns.Msg).
@@ -109,7 +145,7 @@ RulesCheckLoop:
 
 // matchWithPolicies matches the DNS query with a list of ACL polices and returns suitable
 // action against the query.
-func matchWithPolicies(policies []policy, w dns.ResponseWriter, r *dns.Msg) action {
+func matchWithPolicies(ctx context.Context, policies []policy, w dns.ResponseWriter, r *dns.Msg) action {
 	state := request.Request{W: w, Req: r}
 
 	var ip net.IP
@@ -139,7 +175,16 @@ func matchWithPolicies(policies []policy, w dns.ResponseWriter, r *dns.Msg) acti
 			continue
 		}
 
-		// matched.
+		// matched, queries within the rate limit continue with the next policy.
+		if policy.action == actionRateLimit {
+			if policy.exemptCookie && cookie.Valid(ctx) {
+				continue
+			}
+			if policy.limiter.allow(ip) {
+				continue
+			}
+			return policy.exceed
+		}
 		return policy.action
 	}
 	return actionNone
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/cookie"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -597,3 +599,156 @@ func TestACLServeDNS(t *testing.T) {
 		})
 	}
 }
+
+func TestACLRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		sourceIPs []string
+		tcp       bool
+		// wantRcodes holds the expected rcode of each query, -1 means no response.
+		wantRcodes []int
+		truncated  bool
+		// cookie marks the queries as carrying a valid server cookie.
+		cookie bool
+		// wantEDE is the Extended DNS Error expected in the last response, if any.
+		wantEDE uint16
+	}{
+		{
+			name: "Refuse after rate",
+			config: `acl example.org {
+				ratelimit 1 2
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.1", "192.168.0.1"},
+			wantRcodes: []int{dns.RcodeSuccess, dns.RcodeSuccess, dns.RcodeRefused},
+			wantEDE:    dns.ExtendedErrorCodeProhibited,
+		},
+		{
+			name: "Per source IP",
+			config: `acl example.org {
+				ratelimit 1
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.2", "192.168.0.1"},
+			wantRcodes: []int{dns.RcodeSuccess, dns.RcodeSuccess, dns.RcodeRefused},
+		},
+		{
+			name: "Per prefix",
+			config: `acl example.org {
+				ratelimit 1 prefix 24 64
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.2", "2001:db8::1", "2001:db8::2"},
+			wantRcodes: []int{dns.RcodeSuccess, dns.RcodeRefused, dns.RcodeSuccess, dns.RcodeRefused},
+		},
+		{
+			name: "Drop",
+			config: `acl example.org {
+				ratelimit 1 exceed drop
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.1"},
+			wantRcodes: []int{dns.RcodeSuccess, -1},
+		},
+		{
+			name: "Truncate",
+			config: `acl example.org {
+				ratelimit 1 exceed truncate
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.1"},
+			wantRcodes: []int{dns.RcodeSuccess, dns.RcodeSuccess},
+			truncated:  true,
+		},
+		{
+			name: "Truncate over TCP refuses",
+			config: `acl example.org {
+				ratelimit 1 exceed truncate
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.1"},
+			tcp:        true,
+			wantRcodes: []int{dns.RcodeSuccess, dns.RcodeRefused},
+		},
+		{
+			name: "Other networks are not limited",
+			config: `acl example.org {
+				ratelimit 1 net 10.0.0.0/8
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.1"},
+			wantRcodes: []int{dns.RcodeSuccess, dns.RcodeSuccess},
+		},
+		{
+			name: "Within rate continues with next policy",
+			config: `acl example.org {
+				ratelimit 1
+				block net 192.168.0.0/16
+			}`,
+			sourceIPs:  []string{"192.168.0.1"},
+			wantRcodes: []int{dns.RcodeRefused},
+		},
+		{
+			name: "Valid cookie is exempt",
+			config: `acl example.org {
+				ratelimit 1 exempt_cookie
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.1", "192.168.0.1"},
+			wantRcodes: []int{dns.RcodeSuccess, dns.RcodeSuccess, dns.RcodeSuccess},
+			cookie:     true,
+		},
+		{
+			name: "Cookie without exempt_cookie",
+			config: `acl example.org {
+				ratelimit 1
+			}`,
+			sourceIPs:  []string{"192.168.0.1", "192.168.0.1"},
+			wantRcodes: []int{dns.RcodeSuccess, dns.RcodeRefused},
+			cookie:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.cookie {
+				ctx = cookie.WithValid(ctx)
+			}
+			ctr := caddy.NewTestController("dns", tt.config)
+			a, err := parse(ctr)
+			if err != nil {
+				t.Fatalf("Error: Cannot parse acl from config: %v", err)
+			}
+			// Write a response, so we can tell allowed queries from dropped ones.
+			a.Next = test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+				m := new(dns.Msg)
+				m.SetReply(r)
+				w.WriteMsg(m)
+				return dns.RcodeSuccess, nil
+			})
+
+			for i, ip := range tt.sourceIPs {
+				w := &testResponseWriter{}
+				w.TCP = tt.tcp
+				w.setRemoteIP(ip)
+				m := new(dns.Msg)
+				m.SetQuestion("www.example.org.", dns.TypeA)
+				m.SetEdns0(4096, false)
+				a.ServeDNS(ctx, w, m)
+
+				if tt.wantRcodes[i] == -1 {
+					if w.Msg != nil {
+						t.Errorf("Query %d: expected no response, got %v", i, w.Msg)
+					}
+					continue
+				}
+				if w.Msg == nil {
+					t.Fatalf("Query %d: expected a response", i)
+				}
+				if w.Rcode != tt.wantRcodes[i] {
+					t.Errorf("Query %d: expected rcode %v, got %v", i, tt.wantRcodes[i], w.Rcode)
+				}
+				if i == len(tt.sourceIPs)-1 && w.Msg.Truncated != tt.truncated {
+					t.Errorf("Query %d: expected truncated %t, got %t", i, tt.truncated, w.Msg.Truncated)
+				}
+				if ede := edns.ExtendedError(w.Msg); i == len(tt.sourceIPs)-1 && tt.wantEDE != 0 && (ede == nil || ede.InfoCode != tt.wantEDE) {
+					t.Errorf("Query %d: expected Extended DNS Error %d, got %v", i, tt.wantEDE, ede)
+				}
+			}
+		})
+	}
+}
@@ -36,4 +36,11 @@ var (
 		Name:      "dropped_requests_total",
 		Help:      "Counter of DNS requests being dropped.",
 	}, []string{"server", "zone", "view"})
+	// RequestRateLimitCount is the number of DNS requests exceeding a rate limit.
+	RequestRateLimitCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "ratelimited_requests_total",
+		Help:      "Counter of DNS requests exceeding a rate limit.",
+	}, []string{"server", "zone", "view"})
 )
@@ -0,0 +1,95 @@
+package acl
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"time"
+)
+
+// defaultLimiterSize is the maximum number of clients a limiter keeps track of.
+const defaultLimiterSize = 100000
+
+// limiter limits the query rate of clients, or client prefixes, with a token bucket per client.
+// The buckets are kept in an LRU list of bounded size, so a flood of queries from spoofed sources
+// only evicts the least recently seen clients and memory stays flat.
+type limiter struct {
+	rate  float64 // tokens per second
+	burst float64 // size of the bucket
+
+	v4mask net.IPMask
+	v6mask net.IPMask
+
+	size int
+	now  func() time.Time
+
+	mu sync.Mutex
+	ll *list.List
+	m  map[string]*list.Element
+}
+
+// bucket is the token bucket of a single client.
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate, burst float64, v4len, v6len int) *limiter {
+	return &limiter{
+		rate:   rate,
+		burst:  burst,
+		v4mask: net.CIDRMask(v4len, 32),
+		v6mask: net.CIDRMask(v6len, 128),
+		size:   defaultLimiterSize,
+		now:    time.Now,
+		ll:     list.New(),
+		m:      make(map[string]*list.Element),
+	}
+}
+
+// allow takes a token from the bucket of the client ip and returns false if none was left.
+func (l *limiter) allow(ip net.IP) bool {
+	var key string
+	if ip4 := ip.To4(); ip4 != nil {
+		key = string(ip4.Mask(l.v4mask))
+	} else {
+		key = string(ip.Mask(l.v6mask))
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var b *bucket
+	if e, ok := l.m[key]; ok {
+		l.ll.MoveToFront(e)
+		b = e.Value.(*bucket)
+		b.tokens += now.Sub(b.last).Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	} else {
+		if l.ll.Len() >= l.size {
+			oldest := l.ll.Back()
+			l.ll.Remove(oldest)
+			delete(l.m, oldest.Value.(*bucket).key)
+		}
+		b = &bucket{key: key, tokens: l.burst, last: now}
+		l.m[key] = l.ll.PushFront(b)
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Len returns the number of clients tracked.
+func (l *limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
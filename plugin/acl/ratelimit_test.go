@@ -0,0 +1,70 @@
+package acl
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	l := newLimiter(2, 4, 32, 128)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	ip := net.ParseIP("192.168.0.1")
+	for i := range 4 {
+		if !l.allow(ip) {
+			t.Fatalf("Expected query %d within the burst to be allowed", i)
+		}
+	}
+	if l.allow(ip) {
+		t.Fatalf("Expected query after the burst to be limited")
+	}
+
+	// Half a second gives us one token back.
+	now = now.Add(500 * time.Millisecond)
+	if !l.allow(ip) {
+		t.Fatalf("Expected query to be allowed after refill")
+	}
+	if l.allow(ip) {
+		t.Fatalf("Expected query to be limited")
+	}
+
+	// Refills never exceed the burst.
+	now = now.Add(time.Hour)
+	for range 4 {
+		l.allow(ip)
+	}
+	if l.allow(ip) {
+		t.Fatalf("Expected query after the burst to be limited")
+	}
+}
+
+func TestLimiterLRU(t *testing.T) {
+	l := newLimiter(1, 1, 32, 128)
+	l.size = 2
+
+	a, b, c := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")
+	l.allow(a)
+	l.allow(b)
+	l.allow(a) // a is now the most recently used client.
+	l.allow(c) // evicts b.
+
+	if l.Len() != 2 {
+		t.Fatalf("Expected 2 clients, got %d", l.Len())
+	}
+	if _, ok := l.m[string(b.To4())]; ok {
+		t.Errorf("Expected least recently used client to be evicted")
+	}
+	if _, ok := l.m[string(a.To4())]; !ok {
+		t.Errorf("Expected recently used client to be kept")
+	}
+}
+
+func TestLimiterIPv4Mapped(t *testing.T) {
+	l := newLimiter(1, 1, 32, 128)
+	l.allow(net.ParseIP("192.168.0.1"))
+	if l.allow(net.ParseIP("::ffff:192.168.0.1")) {
+		t.Errorf("Expected IPv4-mapped address to share the bucket of its IPv4 address")
+	}
+}
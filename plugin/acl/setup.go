@@ -2,6 +2,7 @@ package acl
 
 import (
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/coredns/caddy"
@@ -59,8 +60,11 @@ func parse(c *caddy.Controller) (ACL, error) {
 				p.action = actionFilter
 			case "drop":
 				p.action = actionDrop
+			case "ratelimit":
+				p.action = actionRateLimit
+				p.exceed = actionLimitRefuse
 			default:
-				return a, c.Errf("unexpected token %q; expect 'allow', 'block', 'filter' or 'drop'", c.Val())
+				return a, c.Errf("unexpected token %q; expect 'allow', 'block', 'filter', 'drop' or 'ratelimit'", c.Val())
 			}
 
 			p.qtypes = make(map[uint16]struct{})
@@ -70,12 +74,50 @@ func parse(c *caddy.Controller) (ACL, error) {
 			hasNetSection := false
 
 			remainingTokens := c.RemainingArgs()
+
+			// ratelimit QPS [BURST], by default per source IP.
+			var (
+				qps, burst   float64
+				v4len, v6len = 32, 128
+			)
+			if p.action == actionRateLimit {
+				var rates []float64
+				for len(remainingTokens) > 0 && len(rates) < 2 && !isPreservedIdentifier(remainingTokens[0]) {
+					r, err := strconv.ParseFloat(remainingTokens[0], 64)
+					if err != nil || r <= 0 {
+						return a, c.Errf("illegal rate %q; expect a positive number", remainingTokens[0])
+					}
+					rates = append(rates, r)
+					remainingTokens = remainingTokens[1:]
+				}
+				if len(rates) == 0 {
+					return a, c.Errf("ratelimit needs a rate in queries per second")
+				}
+				qps, burst = rates[0], max(rates[0], 1)
+				if len(rates) == 2 {
+					burst = rates[1]
+				}
+				if burst < 1 {
+					return a, c.Errf("illegal burst %v; expect at least 1", burst)
+				}
+			}
+
 			for len(remainingTokens) > 0 {
 				if !isPreservedIdentifier(remainingTokens[0]) {
 					return a, c.Errf("unexpected token %q; expect 'type | net'", remainingTokens[0])
 				}
 				section := strings.ToLower(remainingTokens[0])
 
+				// exempt_cookie is a flag, it takes no tokens.
+				if section == "exempt_cookie" {
+					if p.action != actionRateLimit {
+						return a, c.Errf("%q section is only allowed for 'ratelimit'", section)
+					}
+					p.exemptCookie = true
+					remainingTokens = remainingTokens[1:]
+					continue
+				}
+
 				i := 1
 				var tokens []string
 				for ; i < len(remainingTokens) && !isPreservedIdentifier(remainingTokens[i]); i++ {
@@ -115,11 +157,48 @@ func parse(c *caddy.Controller) (ACL, error) {
 						}
 						p.filter.InplaceInsertNet(source, struct{}{})
 					}
+				case "prefix":
+					if p.action != actionRateLimit {
+						return a, c.Errf("%q section is only allowed for 'ratelimit'", section)
+					}
+					if len(tokens) > 2 {
+						return a, c.Errf("too many tokens in %q section", section)
+					}
+					var err error
+					if v4len, err = strconv.Atoi(tokens[0]); err != nil || v4len < 0 || v4len > 32 {
+						return a, c.Errf("illegal IPv4 prefix length %q", tokens[0])
+					}
+					if len(tokens) == 2 {
+						if v6len, err = strconv.Atoi(tokens[1]); err != nil || v6len < 0 || v6len > 128 {
+							return a, c.Errf("illegal IPv6 prefix length %q", tokens[1])
+						}
+					}
+				case "exceed":
+					if p.action != actionRateLimit {
+						return a, c.Errf("%q section is only allowed for 'ratelimit'", section)
+					}
+					if len(tokens) != 1 {
+						return a, c.Errf("too many tokens in %q section", section)
+					}
+					switch strings.ToLower(tokens[0]) {
+					case "refuse":
+						p.exceed = actionLimitRefuse
+					case "drop":
+						p.exceed = actionLimitDrop
+					case "truncate":
+						p.exceed = actionLimitTruncate
+					default:
+						return a, c.Errf("unexpected token %q; expect 'refuse', 'drop' or 'truncate'", tokens[0])
+					}
 				default:
 					return a, c.Errf("unexpected token %q; expect 'type | net'", section)
 				}
 			}
 
+			if p.action == actionRateLimit {
+				p.limiter = newLimiter(qps, burst, v4len, v6len)
+			}
+
 			// optional `type` section means all record types.
 			if !hasTypeSection {
 				p.qtypes[dns.TypeNone] = struct{}{}
@@ -139,7 +218,7 @@ func parse(c *caddy.Controller) (ACL, error) {
 
 func isPreservedIdentifier(token string) bool {
 	identifier := strings.ToLower(token)
-	return identifier == "type" || identifier == "net"
+	return identifier == "type" || identifier == "net" || identifier == "prefix" || identifier == "exceed" || identifier == "exempt_cookie"
 }
 
 // normalize appends '/32' for any single IPv4 address and '/128' for IPv6.
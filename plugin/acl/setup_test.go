@@ -227,6 +227,85 @@ func TestSetup(t *testing.T) {
 			}`,
 			true,
 		},
+		// Rate limit tests.
+		{
+			"Ratelimit 1",
+			`acl {
+				ratelimit 10
+			}`,
+			false,
+		},
+		{
+			"Ratelimit 2",
+			`acl {
+				ratelimit 10 50 type A AAAA net 192.168.0.0/16 prefix 24 56 exceed truncate
+				block net 192.168.0.0/16
+			}`,
+			false,
+		},
+		{
+			"Ratelimit 3",
+			`acl {
+				ratelimit 0.5 net * exceed drop
+			}`,
+			false,
+		},
+		{
+			"Ratelimit missing rate",
+			`acl {
+				ratelimit net 192.168.0.0/16
+			}`,
+			true,
+		},
+		{
+			"Ratelimit illegal rate",
+			`acl {
+				ratelimit -1
+			}`,
+			true,
+		},
+		{
+			"Ratelimit illegal burst",
+			`acl {
+				ratelimit 10 0.5
+			}`,
+			true,
+		},
+		{
+			"Ratelimit illegal prefix",
+			`acl {
+				ratelimit 10 prefix 33
+			}`,
+			true,
+		},
+		{
+			"Ratelimit illegal exceed",
+			`acl {
+				ratelimit 10 exceed servfail
+			}`,
+			true,
+		},
+		{
+			"Ratelimit exempt_cookie",
+			`acl {
+				ratelimit 10 exempt_cookie net 192.168.0.0/16
+			}`,
+			false,
+		},
+		{
+			"Exempt_cookie without ratelimit",
+			`acl {
+				block exempt_cookie
+			}`,
+			true,
+		},
+		{
+			"Prefix without ratelimit",
+			`acl {
+				block net 192.168.0.0/16 prefix 24
+			}`,
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
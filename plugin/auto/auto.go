@@ -24,6 +24,7 @@ type (
 
 		metrics  *metrics.Metrics
 		transfer *transfer.Transfer
+		catalog  *catalogZone
 		loader
 	}
 
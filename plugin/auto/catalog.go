@@ -0,0 +1,62 @@
+package auto
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/catalog"
+)
+
+// catalogZone is a catalog zone (RFC 9432) listing the zones loaded from the directory, so secondaries
+// can provision these zones from it.
+type catalogZone struct {
+	name    string
+	members []string // Member zones in the current version of the catalog zone.
+	serial  uint32
+}
+
+// updateCatalog generates a new version of the catalog zone, if the zones loaded from the directory have
+// changed. The SOA serial is the current time in seconds, or one more than the previous serial if that is
+// higher.
+func (a Auto) updateCatalog() {
+	if a.catalog == nil {
+		return
+	}
+	members := []string{}
+	for _, n := range a.Names() {
+		if n != a.catalog.name {
+			members = append(members, n)
+		}
+	}
+	sort.Strings(members)
+	if a.Zones.Zones(a.catalog.name) != nil && slices.Equal(members, a.catalog.members) {
+		return
+	}
+
+	serial := uint32(time.Now().Unix())
+	if serial <= a.catalog.serial {
+		serial = a.catalog.serial + 1
+	}
+	zo := file.NewZone(a.catalog.name, "stdin")
+	for _, rr := range catalog.Records(a.catalog.name, serial, members) {
+		if err := zo.Insert(rr); err != nil {
+			log.Warningf("Failed to generate catalog zone `%s': %v", a.catalog.name, err)
+			return
+		}
+	}
+
+	a.Lock()
+	if a.Z == nil {
+		a.Z = make(map[string]*file.Zone)
+	}
+	if _, ok := a.Z[a.catalog.name]; !ok {
+		a.names = append(a.names, a.catalog.name)
+	}
+	a.Z[a.catalog.name] = zo
+	a.Unlock()
+
+	a.catalog.members, a.catalog.serial = members, serial
+	log.Infof("Updated catalog zone `%s' with %d member zones, SOA serial is %d", a.catalog.name, len(members), serial)
+}
@@ -0,0 +1,77 @@
+package auto
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/catalog"
+
+	"github.com/miekg/dns"
+)
+
+func TestWalkCatalog(t *testing.T) {
+	t.Parallel()
+	tempdir, err := createFiles(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := Auto{
+		loader: loader{
+			directory: tempdir,
+			re:        regexp.MustCompile(`db\.(.*)`),
+			template:  `${1}`,
+		},
+		Zones:   &Zones{},
+		catalog: &catalogZone{name: "catalog.invalid."},
+	}
+
+	a.Walk()
+	members := catalogMembers(t, a)
+	if len(members) != 2 {
+		t.Fatalf("Expected 2 member zones, got %d", len(members))
+	}
+	for _, name := range []string{"example.com.", "example.org."} {
+		if _, ok := members[name]; !ok {
+			t.Errorf("Expected %s to be a member zone", name)
+		}
+	}
+	serial := a.Zones.Zones("catalog.invalid.").SOA.Serial
+
+	// Without changes, the catalog zone stays the same.
+	a.Walk()
+	if s := a.Zones.Zones("catalog.invalid.").SOA.Serial; s != serial {
+		t.Errorf("Expected SOA serial %d, got %d", serial, s)
+	}
+
+	if err := os.Remove(filepath.Join(tempdir, "db.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	a.Walk()
+	members = catalogMembers(t, a)
+	if _, ok := members["example.com."]; ok || len(members) != 1 {
+		t.Errorf("Expected only example.org. as a member zone, got %v", members)
+	}
+	if s := a.Zones.Zones("catalog.invalid.").SOA.Serial; s <= serial {
+		t.Errorf("Expected SOA serial larger than %d, got %d", serial, s)
+	}
+}
+
+func catalogMembers(t *testing.T, a Auto) map[string]catalog.Member {
+	t.Helper()
+	z := a.Zones.Zones("catalog.invalid.")
+	if z == nil {
+		t.Fatal("Expected catalog zone")
+	}
+	rrs := []dns.RR{}
+	for _, e := range z.All() {
+		rrs = append(rrs, e.All()...)
+	}
+	members, err := catalog.Members("catalog.invalid.", rrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return members
+}
@@ -163,6 +163,15 @@ func autoParse(c *caddy.Controller) (Auto, error) {
 				}
 				a.ReloadInterval = d
 
+			case "catalog": // catalog ZONE
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return a, c.ArgErr()
+				}
+				name := plugin.OriginsFromArgsOrServerBlock(args, c.ServerBlockKeys)[0]
+				a.catalog = &catalogZone{name: name}
+				a.origins = append(a.origins, name)
+
 			case "upstream":
 				// remove soon
 				c.RemainingArgs() // eat remaining args
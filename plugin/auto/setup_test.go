@@ -51,7 +51,22 @@ func TestAutoParse(t *testing.T) {
 			}`,
 			false, "/tmp", "bliep", `(.*)`, 10 * time.Second,
 		},
+		{
+			`auto example.org {
+				directory /tmp
+				catalog catalog.example.org
+			}`,
+			false, "/tmp", "${1}", `db\.(.*)`, 60 * time.Second,
+		},
 		// errors
+		// catalog without a zone.
+		{
+			`auto example.org {
+				directory /tmp
+				catalog
+			}`,
+			true, "/tmp", "${1}", `db\.(.*)`, 60 * time.Second,
+		},
 		// NO_RELOAD has been deprecated.
 		{
 			`auto {
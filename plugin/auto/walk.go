@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/coredns/coredns/plugin/file"
 
@@ -18,6 +19,9 @@ func (a Auto) Walk() error {
 	for _, n := range a.Names() {
 		toDelete[n] = true
 	}
+	if a.catalog != nil {
+		delete(toDelete, a.catalog.name)
+	}
 
 	filepath.Walk(a.directory, func(path string, info os.FileInfo, e error) error {
 		if e != nil {
@@ -83,13 +87,19 @@ func (a Auto) Walk() error {
 		log.Infof("Deleting zone `%s'", origin)
 	}
 
+	a.updateCatalog()
+
 	return nil
 }
 
 // matches re to filename, if it is a match, the subexpression will be used to expand
 // template to an origin. When match is true that origin is returned. Origin is fully qualified.
+// The extension of YAML and JSON zone files is not part of the filename that is matched.
 func matches(re *regexp.Regexp, filename, template string) (match bool, origin string) {
 	base := filepath.Base(filename)
+	if file.FormatOf(base) != file.RFC1035 {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
 
 	matches := re.FindStringSubmatchIndex(base)
 	if matches == nil {
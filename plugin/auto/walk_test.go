@@ -82,3 +82,26 @@ func createFiles(t *testing.T) (string, error) {
 
 	return dir, nil
 }
+
+func TestWalkStructured(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	yaml := "soa:\n  ns: ns1\nrecords:\n  www:\n    A: 127.0.0.1\n"
+	if err := os.WriteFile(filepath.Join(dir, "db.example.org.yaml"), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := Auto{
+		loader: loader{directory: dir, re: regexp.MustCompile(`db\.(.*)`), template: `${1}`},
+		Zones:  &Zones{},
+	}
+	a.Walk()
+
+	z, ok := a.Z["example.org."]
+	if !ok {
+		t.Fatalf("example.org. should have been added from db.example.org.yaml")
+	}
+	if z.SOA == nil || z.SOA.Ns != "ns1.example.org." {
+		t.Errorf("Expected the zone to be parsed from YAML, got SOA %v", z.SOA)
+	}
+}
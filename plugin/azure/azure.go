@@ -11,6 +11,7 @@ import (
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/pkg/zonerefresh"
 	"github.com/coredns/coredns/request"
 
 	publicdns "github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
@@ -23,42 +24,37 @@ type zone struct {
 	z       *file.Zone
 	zone    string
 	private bool
+
+	// version and fetched are only read and modified by the goroutine refreshing the zone.
+	version string    // the etag and number of record sets of the zone when its records were fetched
+	fetched time.Time // when the records were fetched
 }
 
 type zones map[string][]*zone
 
 // Azure is the core struct of the azure plugin.
 type Azure struct {
-	zoneNames     []string
-	publicClient  publicdns.RecordSetsClient
-	privateClient privatedns.RecordSetsClient
-	upstream      *upstream.Upstream
-	zMu           sync.RWMutex
-	zones         zones
+	zoneNames []string
+	client    azureDNS
+	upstream  *upstream.Upstream
+	refresh   zonerefresh.Options
+	zMu       sync.RWMutex
+	zones     zones
 
 	Next plugin.Handler
 	Fall fall.F
 }
 
 // New validates the input DNS zones and initializes the Azure struct.
-func New(ctx context.Context, publicClient publicdns.RecordSetsClient, privateClient privatedns.RecordSetsClient, keys map[string][]string, accessMap map[string]string) (*Azure, error) {
+func New(ctx context.Context, client azureDNS, keys map[string][]string, accessMap map[string]string) (*Azure, error) {
 	zones := make(map[string][]*zone, len(keys))
 	names := make([]string, 0, len(keys))
-	var private bool
 
 	for resourceGroup, znames := range keys {
 		for _, name := range znames {
-			switch accessMap[resourceGroup+name] {
-			case "public":
-				if _, err := publicClient.ListAllByDNSZone(context.Background(), resourceGroup, name, nil, ""); err != nil {
-					return nil, err
-				}
-				private = false
-			case "private":
-				if _, err := privateClient.ListComplete(context.Background(), resourceGroup, name, nil, ""); err != nil {
-					return nil, err
-				}
-				private = true
+			private := accessMap[resourceGroup+name] == "private"
+			if err := client.zoneExists(ctx, resourceGroup, name, private); err != nil {
+				return nil, err
 			}
 
 			fqdn := dns.Fqdn(name)
@@ -70,63 +66,44 @@ func New(ctx context.Context, publicClient publicdns.RecordSetsClient, privateCl
 	}
 
 	return &Azure{
-		publicClient:  publicClient,
-		privateClient: privateClient,
-		zones:         zones,
-		zoneNames:     names,
-		upstream:      upstream.New(),
+		client:    client,
+		zones:     zones,
+		zoneNames: names,
+		upstream:  upstream.New(),
+		refresh:   zonerefresh.New(),
 	}, nil
 }
 
-// Run updates the zone from azure.
+// Run updates the zone from azure, and spins up an update loop for each zone.
 func (h *Azure) Run(ctx context.Context) error {
 	if err := h.updateZones(ctx); err != nil {
 		return err
 	}
-	go func() {
-		delay := 1 * time.Minute
-		timer := time.NewTimer(delay)
-		defer timer.Stop()
-		for {
-			timer.Reset(delay)
-			select {
-			case <-ctx.Done():
-				log.Debugf("Breaking out of Azure update loop for %v: %v", h.zoneNames, ctx.Err())
-				return
-			case <-timer.C:
-				if err := h.updateZones(ctx); err != nil && ctx.Err() == nil {
-					log.Errorf("Failed to update zones %v: %v", h.zoneNames, err)
-				}
-			}
+	for _, z := range h.zones {
+		for _, hostedZone := range z {
+			go h.runZone(ctx, hostedZone)
 		}
-	}()
+	}
 	return nil
 }
 
+// runZone refreshes the records of hostedZone in its resource group until ctx is done.
+func (h *Azure) runZone(ctx context.Context, hostedZone *zone) {
+	h.refresh.Run(ctx, hostedZone.zone, func(ctx context.Context) {
+		if err := h.updateZone(ctx, hostedZone); err != nil && ctx.Err() == nil {
+			log.Errorf("Failed to update zone %v:%v, serving the records fetched before: %v", hostedZone.id, hostedZone.zone, err)
+		}
+	})
+	log.Debugf("Breaking out of Azure update loop for %v:%v: %v", hostedZone.id, hostedZone.zone, ctx.Err())
+}
+
 func (h *Azure) updateZones(ctx context.Context) error {
-	var err error
-	var publicSet publicdns.RecordSetListResultPage
-	var privateSet privatedns.RecordSetListResultPage
 	errs := make([]string, 0)
-	for zName, z := range h.zones {
-		for i, hostedZone := range z {
-			newZ := file.NewZone(zName, "")
-			if hostedZone.private {
-				for privateSet, err = h.privateClient.List(ctx, hostedZone.id, hostedZone.zone, nil, ""); privateSet.NotDone(); err = privateSet.NextWithContext(ctx) {
-					updateZoneFromPrivateResourceSet(privateSet, newZ)
-				}
-			} else {
-				for publicSet, err = h.publicClient.ListByDNSZone(ctx, hostedZone.id, hostedZone.zone, nil, ""); publicSet.NotDone(); err = publicSet.NextWithContext(ctx) {
-					updateZoneFromPublicResourceSet(publicSet, newZ)
-				}
-			}
-			if err != nil {
-				errs = append(errs, fmt.Sprintf("failed to list resource records for %v from azure: %v", hostedZone.zone, err))
+	for _, z := range h.zones {
+		for _, hostedZone := range z {
+			if err := h.updateZone(ctx, hostedZone); err != nil {
+				errs = append(errs, err.Error())
 			}
-			newZ.Upstream = h.upstream
-			h.zMu.Lock()
-			(*z[i]).z = newZ
-			h.zMu.Unlock()
 		}
 	}
 
@@ -136,6 +113,45 @@ func (h *Azure) updateZones(ctx context.Context) error {
 	return nil
 }
 
+// updateZone re-queries the record sets of hostedZone. When incremental refresh is enabled, they are only
+// re-queried if the etag or the number of record sets of the zone changed, or a full refresh is due. If this
+// fails, the records fetched before are kept.
+func (h *Azure) updateZone(ctx context.Context, hostedZone *zone) (err error) {
+	defer func() {
+		if err != nil {
+			zoneStale.WithLabelValues(hostedZone.zone, hostedZone.id).Set(1)
+			zoneRefreshCount.WithLabelValues(hostedZone.zone, hostedZone.id, "failed").Inc()
+			return
+		}
+		zoneStale.WithLabelValues(hostedZone.zone, hostedZone.id).Set(0)
+		zoneRefreshTime.WithLabelValues(hostedZone.zone, hostedZone.id).SetToCurrentTime()
+	}()
+
+	var version string
+	if h.refresh.Incremental {
+		version, err = h.client.zoneVersion(ctx, hostedZone.id, hostedZone.zone, hostedZone.private)
+		if err != nil {
+			return fmt.Errorf("failed to get zone %v from azure: %v", hostedZone.zone, err)
+		}
+		if h.refresh.Unchanged(hostedZone.zone, hostedZone.version, version, hostedZone.fetched) {
+			zoneRefreshCount.WithLabelValues(hostedZone.zone, hostedZone.id, "unchanged").Inc()
+			return nil
+		}
+	}
+
+	newZ := file.NewZone(dns.Fqdn(hostedZone.zone), "")
+	if err := h.client.listRecords(ctx, hostedZone.id, hostedZone.zone, hostedZone.private, newZ); err != nil {
+		return fmt.Errorf("failed to list resource records for %v from azure: %v", hostedZone.zone, err)
+	}
+	newZ.Upstream = h.upstream
+	h.zMu.Lock()
+	hostedZone.z = newZ
+	h.zMu.Unlock()
+	hostedZone.version, hostedZone.fetched = version, time.Now()
+	zoneRefreshCount.WithLabelValues(hostedZone.zone, hostedZone.id, "updated").Inc()
+	return nil
+}
+
 func updateZoneFromPublicResourceSet(recordSet publicdns.RecordSetListResultPage, newZ *file.Zone) {
 	for _, result := range *(recordSet.Response().Value) {
 		resultFqdn := *(result.Fqdn)
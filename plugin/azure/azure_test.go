@@ -2,8 +2,11 @@ package azure
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
@@ -12,6 +15,7 @@ import (
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 var demoAzure = Azure{
@@ -178,3 +182,190 @@ func TestAzure(t *testing.T) {
 		}
 	}
 }
+
+// fakeAzureDNS is an Azure DNS of which the records can change, and which can fail.
+type fakeAzureDNS struct {
+	mu      sync.Mutex
+	records map[string]string // name -> address
+	version string            // the etag and number of record sets of the zone
+	lists   int               // the number of times the records were listed
+	fail    bool
+	missing bool // the zone was deleted
+}
+
+func (f *fakeAzureDNS) zoneExists(ctx context.Context, resourceGroup, zoneName string, private bool) error {
+	return nil
+}
+
+func (f *fakeAzureDNS) zoneVersion(ctx context.Context, resourceGroup, zoneName string, private bool) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return "", errors.New("too many requests")
+	}
+	if f.missing {
+		return version(nil, nil)
+	}
+	return f.version, nil
+}
+
+func (f *fakeAzureDNS) listRecords(ctx context.Context, resourceGroup, zoneName string, private bool, z *file.Zone) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("too many requests")
+	}
+	f.lists++
+	z.Insert(test.SOA("example.org.	300	IN	SOA	ns1-06.azure-dns.com. azuredns-hostmaster.microsoft.com. 1 3600 300 2419200 300"))
+	for name, addr := range f.records {
+		z.Insert(test.A(name + "	300	IN	A	" + addr))
+	}
+	return nil
+}
+
+func (f *fakeAzureDNS) set(name, addr, version string, fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records[name], f.version, f.fail = addr, version, fail
+}
+
+func TestAzureIncremental(t *testing.T) {
+	ctx := t.Context()
+	fake := &fakeAzureDNS{records: map[string]string{"www.example.org.": "1.2.3.4"}, version: "etag-1:1"}
+	h, err := New(ctx, fake, map[string][]string{"resource_group": {"example.org"}}, map[string]string{"resource_groupexample.org": "public"})
+	if err != nil {
+		t.Fatalf("Failed to create azure: %v", err)
+	}
+	h.refresh.Incremental = true
+	if err := h.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+	hostedZone := h.zones["example.org."][0]
+
+	check := func(step, expected string, lists int) {
+		t.Helper()
+		req := new(dns.Msg)
+		req.SetQuestion("www.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := h.ServeDNS(ctx, rec, req); err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != expected {
+			t.Errorf("%s: expected %s, got %v", step, expected, rec.Msg.Answer)
+		}
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if fake.lists != lists {
+			t.Errorf("%s: expected the records to be listed %d times, got %d", step, lists, fake.lists)
+		}
+	}
+	check("initial", "1.2.3.4", 1)
+
+	// The zone didn't change, so the records are not listed again.
+	fake.set("www.example.org.", "1.2.3.5", "etag-1:1", false)
+	if err := h.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("unchanged", "1.2.3.4", 1)
+
+	// After five refresh intervals the records are listed anyway, value edits don't change the version.
+	hostedZone.fetched = time.Now().Add(-5 * time.Minute)
+	if err := h.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("full refresh", "1.2.3.5", 2)
+
+	fake.set("www.example.org.", "1.2.3.6", "etag-1:2", false)
+	if err := h.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("changed", "1.2.3.6", 3)
+
+	// When Azure fails, the records fetched before are served, and the zone is reported stale.
+	fake.set("www.example.org.", "1.2.3.7", "etag-2:2", true)
+	if err := h.updateZone(ctx, hostedZone); err == nil {
+		t.Fatal("Expected an error when Azure fails")
+	}
+	check("failed", "1.2.3.6", 3)
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org", "resource_group")); stale != 1 {
+		t.Errorf("Expected the zone to be reported stale, got %v", stale)
+	}
+
+	fake.set("www.example.org.", "1.2.3.7", "etag-2:2", false)
+	if err := h.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("recovered", "1.2.3.7", 4)
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org", "resource_group")); stale != 0 {
+		t.Errorf("Expected the zone not to be reported stale, got %v", stale)
+	}
+}
+
+func TestAzureRefresh(t *testing.T) {
+	h, err := New(t.Context(), &fakeAzureDNS{}, map[string][]string{"resource_group": {"example.org", "example.com"}}, map[string]string{})
+	if err != nil {
+		t.Fatalf("Failed to create azure: %v", err)
+	}
+	h.refresh.Zones["example.org."] = 10 * time.Second
+	if err := h.refresh.Validate(h.zoneNames); err != nil {
+		t.Fatalf("Expected the refresh interval of example.org. to be valid: %v", err)
+	}
+	if d := h.refresh.Refresh(h.zones["example.org."][0].zone); d != 10*time.Second {
+		t.Errorf("Expected refresh 10s for example.org., got %s", d)
+	}
+	if d := h.refresh.Refresh(h.zones["example.com."][0].zone); d != time.Minute {
+		t.Errorf("Expected the default refresh for example.com., got %s", d)
+	}
+
+	h.refresh.Zones["example.net."] = 10 * time.Second
+	if err := h.refresh.Validate(h.zoneNames); err == nil {
+		t.Error("Expected an error for a refresh interval of an unknown zone")
+	}
+}
+
+func TestAzureZoneNotFound(t *testing.T) {
+	ctx := t.Context()
+	fake := &fakeAzureDNS{records: map[string]string{"www.example.org.": "1.2.3.4"}, version: "etag-1:1"}
+	h, err := New(ctx, fake, map[string][]string{"other_group": {"example.org"}}, map[string]string{"other_groupexample.org": "public"})
+	if err != nil {
+		t.Fatalf("Failed to create azure: %v", err)
+	}
+	h.refresh.Incremental = true
+	if err := h.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+
+	// The zone is gone, so the records fetched before are served and the zone is reported stale.
+	fake.mu.Lock()
+	fake.missing = true
+	fake.mu.Unlock()
+	if err := h.updateZone(ctx, h.zones["example.org."][0]); err == nil {
+		t.Fatal("Expected an error for a zone that isn't found")
+	}
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org", "other_group")); stale != 1 {
+		t.Errorf("Expected the zone to be reported stale, got %v", stale)
+	}
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := h.ServeDNS(ctx, rec, req); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != "1.2.3.4" {
+		t.Errorf("Expected the records fetched before, got %v", rec.Msg.Answer)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	if _, err := version(nil, nil); err == nil {
+		t.Error("Expected an error for a zone without etag and record sets")
+	}
+	etag, count := "etag-1", int64(3)
+	v, err := version(&etag, &count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "etag-1:3" {
+		t.Errorf("Expected version etag-1:3, got %s", v)
+	}
+}
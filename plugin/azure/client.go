@@ -0,0 +1,98 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/coredns/coredns/plugin/file"
+
+	publicdns "github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	privatedns "github.com/Azure/azure-sdk-for-go/profiles/latest/privatedns/mgmt/privatedns"
+)
+
+// azureDNS is the part of the Azure DNS API used by the plugin, exposed for testing.
+type azureDNS interface {
+	zoneExists(ctx context.Context, resourceGroup, zoneName string, private bool) error
+	zoneVersion(ctx context.Context, resourceGroup, zoneName string, private bool) (string, error)
+	listRecords(ctx context.Context, resourceGroup, zoneName string, private bool, z *file.Zone) error
+}
+
+type azureClient struct {
+	publicRecords  publicdns.RecordSetsClient
+	privateRecords privatedns.RecordSetsClient
+	publicZones    publicdns.ZonesClient
+	privateZones   privatedns.PrivateZonesClient
+}
+
+// zoneExists checks if the record sets of the zone in the resource group can be listed.
+func (c azureClient) zoneExists(ctx context.Context, resourceGroup, zoneName string, private bool) error {
+	if private {
+		_, err := c.privateRecords.ListComplete(ctx, resourceGroup, zoneName, nil, "")
+		return err
+	}
+	_, err := c.publicRecords.ListAllByDNSZone(ctx, resourceGroup, zoneName, nil, "")
+	return err
+}
+
+// zoneVersion returns the etag and the number of record sets of the zone in the resource group. Azure
+// doesn't change the etag of a zone when its record sets change, so the number of record sets is added.
+// Edits of record values change neither, those are picked up by a full refresh.
+func (c azureClient) zoneVersion(ctx context.Context, resourceGroup, zoneName string, private bool) (string, error) {
+	var (
+		etag  *string
+		count *int64
+	)
+	if private {
+		z, err := c.privateZones.Get(ctx, resourceGroup, zoneName)
+		if err != nil {
+			return "", err
+		}
+		etag = z.Etag
+		if z.PrivateZoneProperties != nil {
+			count = z.NumberOfRecordSets
+		}
+	} else {
+		z, err := c.publicZones.Get(ctx, resourceGroup, zoneName)
+		if err != nil {
+			return "", err
+		}
+		etag = z.Etag
+		if z.ZoneProperties != nil {
+			count = z.NumberOfRecordSets
+		}
+	}
+	return version(etag, count)
+}
+
+// version returns the version of a zone from its etag and number of record sets. It returns an error if the
+// zone has neither, as that is what Azure returns for a zone that isn't there.
+func version(etag *string, count *int64) (string, error) {
+	if etag == nil && count == nil {
+		return "", errors.New("zone not found")
+	}
+	v := ""
+	if etag != nil {
+		v = *etag
+	}
+	if count != nil {
+		v += ":" + strconv.FormatInt(*count, 10)
+	}
+	return v, nil
+}
+
+// listRecords inserts the records of the zone in the resource group into z.
+func (c azureClient) listRecords(ctx context.Context, resourceGroup, zoneName string, private bool, z *file.Zone) error {
+	if private {
+		set, err := c.privateRecords.List(ctx, resourceGroup, zoneName, nil, "")
+		for ; err == nil && set.NotDone(); err = set.NextWithContext(ctx) {
+			updateZoneFromPrivateResourceSet(set, z)
+		}
+		return err
+	}
+	set, err := c.publicRecords.ListByDNSZone(ctx, resourceGroup, zoneName, nil, "")
+	for ; err == nil && set.NotDone(); err = set.NextWithContext(ctx) {
+		updateZoneFromPublicResourceSet(set, z)
+	}
+	return err
+}
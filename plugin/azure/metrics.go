@@ -0,0 +1,32 @@
+package azure
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// zoneRefreshCount is the number of refreshes of a zone, by result.
+	zoneRefreshCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "azure",
+		Name:      "zone_refreshes_total",
+		Help:      "Counter of refreshes of zones, by result: updated, unchanged or failed.",
+	}, []string{"zone", "resource_group", "result"})
+	// zoneStale is whether the last refresh of a zone failed, and records fetched before are served.
+	zoneStale = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "azure",
+		Name:      "zone_stale",
+		Help:      "Whether the last refresh of a zone failed and records fetched before are served.",
+	}, []string{"zone", "resource_group"})
+	// zoneRefreshTime is the timestamp of the last successful refresh of a zone.
+	zoneRefreshTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "azure",
+		Name:      "zone_last_refresh_timestamp_seconds",
+		Help:      "The timestamp of the last successful refresh of a zone.",
+	}, []string{"zone", "resource_group"})
+)
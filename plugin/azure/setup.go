@@ -9,6 +9,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/zonerefresh"
 
 	publicAzureDNS "github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
 	privateAzureDNS "github.com/Azure/azure-sdk-for-go/profiles/latest/privatedns/mgmt/privatedns"
@@ -21,7 +22,7 @@ var log = clog.NewWithPlugin("azure")
 func init() { plugin.Register("azure", setup) }
 
 func setup(c *caddy.Controller) error {
-	env, keys, accessMap, fall, err := parse(c)
+	env, keys, accessMap, fall, refresh, err := parse(c)
 	if err != nil {
 		return plugin.Error("azure", err)
 	}
@@ -39,12 +40,35 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("azure", err)
 	}
 
-	h, err := New(ctx, publicDNSClient, privateDNSClient, keys, accessMap)
+	publicZonesClient := publicAzureDNS.NewZonesClient(env.Values[auth.SubscriptionID])
+	if publicZonesClient.Authorizer, err = env.GetAuthorizer(); err != nil {
+		cancel()
+		return plugin.Error("azure", err)
+	}
+
+	privateZonesClient := privateAzureDNS.NewPrivateZonesClient(env.Values[auth.SubscriptionID])
+	if privateZonesClient.Authorizer, err = env.GetAuthorizer(); err != nil {
+		cancel()
+		return plugin.Error("azure", err)
+	}
+
+	client := azureClient{
+		publicRecords:  publicDNSClient,
+		privateRecords: privateDNSClient,
+		publicZones:    publicZonesClient,
+		privateZones:   privateZonesClient,
+	}
+	h, err := New(ctx, client, keys, accessMap)
 	if err != nil {
 		cancel()
 		return plugin.Error("azure", err)
 	}
 	h.Fall = fall
+	h.refresh = refresh
+	if err := refresh.Validate(h.zoneNames); err != nil {
+		cancel()
+		return plugin.Error("azure", c.Err(err.Error()))
+	}
 	if err := h.Run(ctx); err != nil {
 		cancel()
 		return plugin.Error("azure", err)
@@ -58,7 +82,7 @@ func setup(c *caddy.Controller) error {
 	return nil
 }
 
-func parse(c *caddy.Controller) (auth.EnvironmentSettings, map[string][]string, map[string]string, fall.F, error) {
+func parse(c *caddy.Controller) (auth.EnvironmentSettings, map[string][]string, map[string]string, fall.F, zonerefresh.Options, error) {
 	resourceGroupMapping := map[string][]string{}
 	accessMap := map[string]string{}
 	resourceGroupSet := map[string]struct{}{}
@@ -66,6 +90,7 @@ func parse(c *caddy.Controller) (auth.EnvironmentSettings, map[string][]string,
 	env := auth.EnvironmentSettings{Values: map[string]string{}}
 
 	var fall fall.F
+	refresh := zonerefresh.New()
 	var access string
 	var resourceGroup string
 	var zoneName string
@@ -76,14 +101,14 @@ func parse(c *caddy.Controller) (auth.EnvironmentSettings, map[string][]string,
 		for i := range args {
 			parts := strings.SplitN(args[i], ":", 2)
 			if len(parts) != 2 {
-				return env, resourceGroupMapping, accessMap, fall, c.Errf("invalid resource group/zone: %q", args[i])
+				return env, resourceGroupMapping, accessMap, fall, refresh, c.Errf("invalid resource group/zone: %q", args[i])
 			}
 			resourceGroup, zoneName = parts[0], parts[1]
 			if resourceGroup == "" || zoneName == "" {
-				return env, resourceGroupMapping, accessMap, fall, c.Errf("invalid resource group/zone: %q", args[i])
+				return env, resourceGroupMapping, accessMap, fall, refresh, c.Errf("invalid resource group/zone: %q", args[i])
 			}
 			if _, ok := resourceGroupSet[resourceGroup+zoneName]; ok {
-				return env, resourceGroupMapping, accessMap, fall, c.Errf("conflicting zone: %q", args[i])
+				return env, resourceGroupMapping, accessMap, fall, refresh, c.Errf("conflicting zone: %q", args[i])
 			}
 
 			resourceGroupSet[resourceGroup+zoneName] = struct{}{}
@@ -95,50 +120,56 @@ func parse(c *caddy.Controller) (auth.EnvironmentSettings, map[string][]string,
 			switch c.Val() {
 			case "subscription":
 				if !c.NextArg() {
-					return env, resourceGroupMapping, accessMap, fall, c.ArgErr()
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.ArgErr()
 				}
 				env.Values[auth.SubscriptionID] = c.Val()
 			case "tenant":
 				if !c.NextArg() {
-					return env, resourceGroupMapping, accessMap, fall, c.ArgErr()
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.ArgErr()
 				}
 				env.Values[auth.TenantID] = c.Val()
 			case "client":
 				if !c.NextArg() {
-					return env, resourceGroupMapping, accessMap, fall, c.ArgErr()
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.ArgErr()
 				}
 				env.Values[auth.ClientID] = c.Val()
 			case "secret":
 				if !c.NextArg() {
-					return env, resourceGroupMapping, accessMap, fall, c.ArgErr()
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.ArgErr()
 				}
 				env.Values[auth.ClientSecret] = c.Val()
 			case "environment":
 				if !c.NextArg() {
-					return env, resourceGroupMapping, accessMap, fall, c.ArgErr()
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.ArgErr()
 				}
 				var err error
 				if azureEnv, err = azurerest.EnvironmentFromName(c.Val()); err != nil {
-					return env, resourceGroupMapping, accessMap, fall, c.Errf("cannot set azure environment: %q", err.Error())
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.Errf("cannot set azure environment: %q", err.Error())
 				}
 			case "fallthrough":
 				fall.SetZonesFromArgs(c.RemainingArgs())
 			case "access":
 				if !c.NextArg() {
-					return env, resourceGroupMapping, accessMap, fall, c.ArgErr()
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.ArgErr()
 				}
 				access = c.Val()
 				if access != "public" && access != "private" {
-					return env, resourceGroupMapping, accessMap, fall, c.Errf("invalid access value: can be public/private, found: %s", access)
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.Errf("invalid access value: can be public/private, found: %s", access)
 				}
 				accessMap[resourceGroup+zoneName] = access
 			default:
-				return env, resourceGroupMapping, accessMap, fall, c.Errf("unknown property: %q", c.Val())
+				ok, err := refresh.Parse(c)
+				if err != nil {
+					return env, resourceGroupMapping, accessMap, fall, refresh, err
+				}
+				if !ok {
+					return env, resourceGroupMapping, accessMap, fall, refresh, c.Errf("unknown property: %q", c.Val())
+				}
 			}
 		}
 	}
 
 	env.Values[auth.Resource] = azureEnv.ResourceManagerEndpoint
 	env.Environment = azureEnv
-	return env, resourceGroupMapping, accessMap, fall, nil
+	return env, resourceGroupMapping, accessMap, fall, refresh, nil
 }
@@ -60,11 +60,35 @@ func TestSetup(t *testing.T) {
 		{`azure resource-set:zone {
 			access foo
 		}`, true},
+		{`azure resource_set:zone {
+    refresh 90
+}`, false},
+		{`azure resource_set:zone {
+    refresh 0
+}`, true},
+		{`azure resource_set:zone {
+    refresh
+}`, true},
+		{`azure resource_set:zone {
+    refresh 10s zone
+}`, false},
+		{`azure resource_set:zone {
+    incremental
+}`, false},
+		{`azure resource_set:zone {
+    incremental 6h
+}`, false},
+		{`azure resource_set:zone {
+    incremental 0
+}`, true},
+		{`azure resource_set:zone {
+    incremental 1h 2h
+}`, true},
 	}
 
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.body)
-		if _, _, _, _, err := parse(c); (err == nil) == test.expectedError {
+		if _, _, _, _, _, err := parse(c); (err == nil) == test.expectedError {
 			t.Fatalf("Unexpected errors: %v in test: %d\n\t%s", err, i, test.body)
 		}
 	}
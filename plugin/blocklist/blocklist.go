@@ -0,0 +1,111 @@
+// Package blocklist implements a plugin that blocks queries for names found in (large) lists of
+// domain names.
+package blocklist
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Blocklist is the plugin handler.
+type Blocklist struct {
+	Next  plugin.Handler
+	Zones []string
+
+	lists  []*source // lists with names to block
+	allows []*source // lists with names to allow
+	inline []string  // names to allow from the Corefile
+
+	response response
+	ttl      uint32
+	reload   time.Duration
+
+	sets atomic.Pointer[sets]
+}
+
+// response is the kind of reply we send for blocked names.
+type response int
+
+const (
+	// responseNXDomain replies with NXDOMAIN.
+	responseNXDomain response = iota
+	// responseZero replies with 0.0.0.0 or :: for A and AAAA queries and with NODATA for other types.
+	responseZero
+	// responseRefused replies with REFUSED.
+	responseRefused
+)
+
+// sets holds the block and allow sets build from all lists, they are swapped as a whole on reload.
+type sets struct {
+	block *set
+	allow *set
+	names []string // names of the lists, indexed by the list index stored in block.
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (b *Blocklist) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	zone := plugin.Zones(b.Zones).Matches(qname)
+	if zone == "" {
+		return plugin.NextOrFailure(b.Name(), b.Next, ctx, w, r)
+	}
+
+	s := b.sets.Load()
+	if s == nil {
+		return plugin.NextOrFailure(b.Name(), b.Next, ctx, w, r)
+	}
+	l, ok := s.block.match(qname)
+	if !ok {
+		return plugin.NextOrFailure(b.Name(), b.Next, ctx, w, r)
+	}
+	if _, ok := s.allow.match(qname); ok {
+		AllowCount.WithLabelValues(metrics.WithServer(ctx), metrics.WithView(ctx)).Inc()
+		return plugin.NextOrFailure(b.Name(), b.Next, ctx, w, r)
+	}
+
+	BlockCount.WithLabelValues(metrics.WithServer(ctx), s.names[l], metrics.WithView(ctx)).Inc()
+
+	m := b.reply(state)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// reply returns the message to send back for a blocked name.
+func (b *Blocklist) reply(state request.Request) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+
+	switch b.response {
+	case responseNXDomain:
+		m.Rcode = dns.RcodeNameError
+	case responseRefused:
+		m.Rcode = dns.RcodeRefused
+	case responseZero:
+		hdr := dns.RR_Header{Name: state.QName(), Class: dns.ClassINET, Ttl: b.ttl}
+		switch state.QType() {
+		case dns.TypeA:
+			hdr.Rrtype = dns.TypeA
+			m.Answer = []dns.RR{&dns.A{Hdr: hdr, A: net.IPv4zero}}
+		case dns.TypeAAAA:
+			hdr.Rrtype = dns.TypeAAAA
+			m.Answer = []dns.RR{&dns.AAAA{Hdr: hdr, AAAA: net.IPv6zero}}
+		}
+	}
+
+	edns.SetExtendedError(state.Req, m, dns.ExtendedErrorCodeBlocked, "")
+	return m
+}
+
+// Name implements the plugin.Handler interface.
+func (b *Blocklist) Name() string { return "blocklist" }
@@ -0,0 +1,166 @@
+package blocklist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func newTestBlocklist(t *testing.T, resp response) *Blocklist {
+	t.Helper()
+	dir := t.TempDir()
+	ads := filepath.Join(dir, "ads")
+	if err := os.WriteFile(ads, []byte("0.0.0.0 ads.example.org\n||tracker.example.org^\n@@||ok.tracker.example.org^\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	malware := filepath.Join(dir, "malware")
+	if err := os.WriteFile(malware, []byte("malware.example.net\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Blocklist{
+		Next:     test.NextHandler(dns.RcodeSuccess, nil),
+		Zones:    []string{"."},
+		lists:    []*source{{name: "ads", path: ads}, {name: "malware", path: malware}},
+		inline:   []string{"allowed.malware.example.net."},
+		response: resp,
+		ttl:      300,
+	}
+	if err := b.load(); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBlocklistServeDNS(t *testing.T) {
+	tests := []struct {
+		qname    string
+		qtype    uint16
+		response response
+		edns     bool
+		rcode    int
+		answer   []dns.RR
+		blocked  bool
+	}{
+		{qname: "ads.example.org.", qtype: dns.TypeA, response: responseNXDomain, rcode: dns.RcodeNameError, blocked: true},
+		{qname: "www.ads.example.org.", qtype: dns.TypeA, response: responseNXDomain, rcode: dns.RcodeNameError, blocked: true},
+		{qname: "tracker.example.org.", qtype: dns.TypeMX, response: responseRefused, edns: true, rcode: dns.RcodeRefused, blocked: true},
+		{qname: "malware.example.net.", qtype: dns.TypeA, response: responseZero, rcode: dns.RcodeSuccess, blocked: true,
+			answer: []dns.RR{test.A("malware.example.net. 300 IN A 0.0.0.0")}},
+		{qname: "malware.example.net.", qtype: dns.TypeAAAA, response: responseZero, rcode: dns.RcodeSuccess, blocked: true,
+			answer: []dns.RR{test.AAAA("malware.example.net. 300 IN AAAA ::")}},
+		{qname: "malware.example.net.", qtype: dns.TypeTXT, response: responseZero, rcode: dns.RcodeSuccess, blocked: true},
+		// allowed through exceptions
+		{qname: "ok.tracker.example.org.", qtype: dns.TypeA, response: responseNXDomain},
+		{qname: "allowed.malware.example.net.", qtype: dns.TypeA, response: responseNXDomain},
+		// not on any list
+		{qname: "example.org.", qtype: dns.TypeA, response: responseNXDomain},
+	}
+
+	for i, tc := range tests {
+		b := newTestBlocklist(t, tc.response)
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		if tc.edns {
+			m.SetEdns0(1232, false)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := b.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		if !tc.blocked {
+			// test.NextHandler doesn't write a response.
+			if rec.Msg != nil {
+				t.Errorf("Test %d: expected %s to be passed on, got %s", i, tc.qname, rec.Msg)
+			}
+			continue
+		}
+		if rec.Msg == nil {
+			t.Fatalf("Test %d: expected %s to be blocked", i, tc.qname)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rec.Msg.Rcode)
+		}
+		if err := test.Section(test.Case{Answer: tc.answer}, test.Answer, rec.Msg.Answer); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+		}
+
+		opt := rec.Msg.IsEdns0()
+		if !tc.edns {
+			if opt != nil {
+				t.Errorf("Test %d: expected no OPT record for non EDNS query", i)
+			}
+			continue
+		}
+		if opt == nil || len(opt.Option) != 1 {
+			t.Fatalf("Test %d: expected an OPT record with an EDE option", i)
+		}
+		if ede, ok := opt.Option[0].(*dns.EDNS0_EDE); !ok || ede.InfoCode != dns.ExtendedErrorCodeBlocked {
+			t.Errorf("Test %d: expected EDE %d, got %v", i, dns.ExtendedErrorCodeBlocked, opt.Option[0])
+		}
+	}
+}
+
+func TestBlocklistZones(t *testing.T) {
+	b := newTestBlocklist(t, responseNXDomain)
+	b.Zones = []string{"example.net."}
+
+	m := new(dns.Msg)
+	m.SetQuestion("ads.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	b.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg != nil {
+		t.Errorf("Expected query outside of zones to be passed on")
+	}
+}
+
+func TestBlocklistReload(t *testing.T) {
+	b := newTestBlocklist(t, responseNXDomain)
+	if b.changed() {
+		t.Fatalf("Expected lists to be unchanged after load")
+	}
+
+	if _, ok := b.sets.Load().block.match("new.example.org."); ok {
+		t.Fatalf("Expected new.example.org. not to be blocked")
+	}
+
+	// Make sure the modification time changes.
+	mtime := time.Now().Add(time.Minute)
+	path := b.lists[0].path
+	if err := os.WriteFile(path, []byte("new.example.org\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if !b.changed() {
+		t.Fatalf("Expected lists to be changed")
+	}
+	if err := b.load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.sets.Load().block.match("new.example.org."); !ok {
+		t.Errorf("Expected new.example.org. to be blocked after reload")
+	}
+	if _, ok := b.sets.Load().block.match("ads.example.org."); ok {
+		t.Errorf("Expected ads.example.org. not to be blocked after reload")
+	}
+
+	// A list that disappears keeps the current sets.
+	os.Remove(path)
+	if err := b.load(); err == nil {
+		t.Errorf("Expected error loading missing list")
+	}
+	if _, ok := b.sets.Load().block.match("new.example.org."); !ok {
+		t.Errorf("Expected new.example.org. to still be blocked")
+	}
+}
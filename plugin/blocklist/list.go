@@ -0,0 +1,211 @@
+package blocklist
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+// source is a list file on disk.
+type source struct {
+	name string
+	path string
+
+	// mtime and size are only read and modified by a single goroutine
+	mtime time.Time
+	size  int64
+}
+
+// changed returns true when the file on disk differs from the one we loaded last.
+func (s *source) changed() bool {
+	stat, err := os.Stat(s.path)
+	if err != nil {
+		return false
+	}
+	return !s.mtime.Equal(stat.ModTime()) || s.size != stat.Size()
+}
+
+// read opens the file and calls parse on it, recording its modification time and size.
+func (s *source) read(block, allow func(name string)) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := parse(file, block, allow); err != nil {
+		return err
+	}
+	s.mtime = stat.ModTime()
+	s.size = stat.Size()
+	return nil
+}
+
+// parse reads a list from r and calls block for every name to block and allow for every exception
+// found. It understands three formats and detects them for each line:
+//
+//	0.0.0.0 ads.example.org tracker.example.org   # hosts
+//	ads.example.org                               # plain domain
+//	||ads.example.org^                            # adblock
+//	@@||good.example.org^                         # adblock exception
+//
+// Adblock rules that can't be expressed in DNS, i.e. those with paths, wildcards or modifiers other
+// than $important, are skipped.
+func parse(r io.Reader, block, allow func(name string)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '!' || line[0] == '[' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "@@||") {
+			if name, ok := adblock(line[4:]); ok {
+				allow(name)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "||") {
+			if name, ok := adblock(line[2:]); ok {
+				block(name)
+			}
+			continue
+		}
+
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		switch len(f) {
+		case 0:
+			continue
+		case 1:
+			if name, ok := domain(f[0]); ok {
+				block(name)
+			}
+		default:
+			if net.ParseIP(f[0]) == nil {
+				continue
+			}
+			for _, n := range f[1:] {
+				if _, ok := local[strings.ToLower(n)]; ok {
+					continue
+				}
+				if name, ok := domain(n); ok {
+					block(name)
+				}
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// adblock parses the part of an adblock rule after the leading "||".
+func adblock(rule string) (string, bool) {
+	i := strings.IndexByte(rule, '^')
+	if i < 0 {
+		return "", false
+	}
+	if mod := rule[i+1:]; mod != "" && mod != "$important" {
+		return "", false
+	}
+	return domain(rule[:i])
+}
+
+// domain returns the normalized name if s is a valid domain name.
+func domain(s string) (string, bool) {
+	if s == "" || strings.ContainsAny(s, "*/:") || net.ParseIP(s) != nil {
+		return "", false
+	}
+	if _, ok := dns.IsDomainName(s); !ok {
+		return "", false
+	}
+	name := plugin.Name(s).Normalize()
+	if name == "." {
+		return "", false
+	}
+	return name, true
+}
+
+// local holds the names hosts files commonly map to the loopback address, these are never blocked.
+var local = map[string]struct{}{
+	"localhost":             {},
+	"localhost.localdomain": {},
+	"local":                 {},
+	"broadcasthost":         {},
+	"ip6-localhost":         {},
+	"ip6-loopback":          {},
+	"ip6-localnet":          {},
+	"ip6-mcastprefix":       {},
+	"ip6-allnodes":          {},
+	"ip6-allrouters":        {},
+	"ip6-allhosts":          {},
+}
+
+// load reads all lists and atomically replaces the sets in use. When a list can't be read the
+// current sets are kept.
+func (b *Blocklist) load() error {
+	block, allow := &builder{}, &builder{}
+	names := make([]string, len(b.lists))
+
+	for i, s := range b.lists {
+		names[i] = s.name
+		l := uint16(i)
+		err := s.read(
+			func(name string) { block.add(name, l) },
+			func(name string) { allow.add(name, 0) },
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range b.allows {
+		add := func(name string) { allow.add(name, 0) }
+		if err := s.read(add, add); err != nil {
+			return err
+		}
+	}
+	for _, name := range b.inline {
+		allow.add(name, 0)
+	}
+
+	s := &sets{block: block.build(), allow: allow.build(), names: names}
+	b.sets.Store(s)
+
+	entries := make([]int, len(names))
+	for _, l := range s.block.list {
+		entries[l]++
+	}
+	for i, n := range names {
+		blocklistEntries.WithLabelValues(n).Set(float64(entries[i]))
+	}
+	blocklistReloadTime.Set(float64(time.Now().UnixNano()) / 1e9)
+	log.Debugf("Loaded %d names to block and %d names to allow", s.block.Len(), s.allow.Len())
+	return nil
+}
+
+// changed returns true if any of the lists changed on disk since they were last loaded.
+func (b *Blocklist) changed() bool {
+	for _, s := range b.lists {
+		if s.changed() {
+			return true
+		}
+	}
+	for _, s := range b.allows {
+		if s.changed() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package blocklist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const list = `# hosts
+127.0.0.1 localhost
+0.0.0.0 ads.example.org tracker.example.org # trailing comment
+::  ipv6.example.org
+
+# plain
+plain.example.org
+Upper.Example.Org.
+not_a valid line here
+
+! adblock
+[Adblock Plus 2.0]
+||adblock.example.org^
+||important.example.org^$important
+||third.example.org^$third-party
+||example.org/path^
+||*.wild.example.org^
+@@||exception.example.org^
+`
+
+func TestParse(t *testing.T) {
+	var block, allow []string
+	err := parse(strings.NewReader(list),
+		func(name string) { block = append(block, name) },
+		func(name string) { allow = append(allow, name) },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectBlock := []string{
+		"ads.example.org.", "tracker.example.org.", "ipv6.example.org.",
+		"plain.example.org.", "upper.example.org.",
+		"adblock.example.org.", "important.example.org.",
+	}
+	if !reflect.DeepEqual(block, expectBlock) {
+		t.Errorf("Expected block %v, got %v", expectBlock, block)
+	}
+	expectAllow := []string{"exception.example.org."}
+	if !reflect.DeepEqual(allow, expectAllow) {
+		t.Errorf("Expected allow %v, got %v", expectAllow, allow)
+	}
+}
@@ -0,0 +1,39 @@
+package blocklist
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// BlockCount is the number of DNS requests being blocked, per list.
+	BlockCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "blocklist",
+		Name:      "blocked_requests_total",
+		Help:      "Counter of DNS requests being blocked.",
+	}, []string{"server", "list", "view"})
+	// AllowCount is the number of DNS requests for blocked names that were allowed by an allowlist.
+	AllowCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "blocklist",
+		Name:      "allowed_requests_total",
+		Help:      "Counter of DNS requests for blocked names being allowed by an allowlist.",
+	}, []string{"server", "view"})
+	// blocklistEntries is the number of names in each list.
+	blocklistEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "blocklist",
+		Name:      "entries",
+		Help:      "The number of names in each blocklist.",
+	}, []string{"list"})
+	// blocklistReloadTime is the timestamp of the last reload of the lists.
+	blocklistReloadTime = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "blocklist",
+		Name:      "reload_timestamp_seconds",
+		Help:      "The timestamp of the last reload of the blocklists.",
+	})
+)
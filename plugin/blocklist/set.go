@@ -0,0 +1,103 @@
+package blocklist
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// set is an immutable set of domain names. All names are stored back to back in a single string and
+// are found with a binary search over their offsets. For lists with millions of entries this costs
+// a fraction of the memory a map or a trie with a node per label would need.
+type set struct {
+	data string
+	off  []uint32 // off[i] is the start of name i in data, off[len(off)-1] is len(data).
+	list []uint16 // list[i] is the index of the list name i came from.
+}
+
+// Len returns the number of names in the set.
+func (s *set) Len() int {
+	if s == nil || len(s.off) == 0 {
+		return 0
+	}
+	return len(s.off) - 1
+}
+
+func (s *set) at(i int) string { return s.data[s.off[i]:s.off[i+1]] }
+
+// find returns the index of name in s, or -1 when it isn't present.
+func (s *set) find(name string) int {
+	n := s.Len()
+	i := sort.Search(n, func(i int) bool { return s.at(i) >= name })
+	if i < n && s.at(i) == name {
+		return i
+	}
+	return -1
+}
+
+// match checks if name, or any of its parents, is in the set. The most specific match wins and the
+// index of the list it came from is returned.
+func (s *set) match(name string) (uint16, bool) {
+	if s.Len() == 0 {
+		return 0, false
+	}
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		if i := s.find(name[off:]); i >= 0 {
+			return s.list[i], true
+		}
+	}
+	return 0, false
+}
+
+// builder collects names and turns them into a set.
+type builder struct {
+	names []string
+	list  []uint16
+}
+
+// add adds name from list l to the builder. Name must be normalized.
+func (b *builder) add(name string, l uint16) {
+	b.names = append(b.names, name)
+	b.list = append(b.list, l)
+}
+
+// Len implements sort.Interface.
+func (b *builder) Len() int { return len(b.names) }
+
+// Less implements sort.Interface.
+func (b *builder) Less(i, j int) bool { return b.names[i] < b.names[j] }
+
+// Swap implements sort.Interface.
+func (b *builder) Swap(i, j int) {
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+	b.list[i], b.list[j] = b.list[j], b.list[i]
+}
+
+// build returns the set of all names added. Duplicates are removed, the first list a name was
+// added from is retained.
+func (b *builder) build() *set {
+	sort.Stable(b)
+
+	size := 0
+	for _, n := range b.names {
+		size += len(n)
+	}
+
+	s := &set{off: make([]uint32, 0, len(b.names)+1), list: make([]uint16, 0, len(b.names))}
+	data := strings.Builder{}
+	data.Grow(size)
+	for i, n := range b.names {
+		if i > 0 && b.names[i-1] == n {
+			continue
+		}
+		s.off = append(s.off, uint32(data.Len()))
+		s.list = append(s.list, b.list[i])
+		data.WriteString(n)
+	}
+	s.off = append(s.off, uint32(data.Len()))
+	s.data = data.String()
+
+	b.names, b.list = nil, nil
+	return s
+}
@@ -0,0 +1,54 @@
+package blocklist
+
+import "testing"
+
+func TestSetMatch(t *testing.T) {
+	b := &builder{}
+	b.add("example.org.", 0)
+	b.add("ads.example.net.", 1)
+	b.add("example.org.", 1) // duplicate, first list wins
+	b.add("tracker.", 2)
+	s := b.build()
+
+	if s.Len() != 3 {
+		t.Fatalf("Expected 3 names, got %d", s.Len())
+	}
+
+	tests := []struct {
+		name  string
+		list  uint16
+		match bool
+	}{
+		{"example.org.", 0, true},
+		{"www.example.org.", 0, true},
+		{"a.b.c.example.org.", 0, true},
+		{"ads.example.net.", 1, true},
+		{"x.ads.example.net.", 1, true},
+		{"example.net.", 0, false},
+		{"badexample.org.", 0, false},
+		{"tracker.", 2, true},
+		{"org.", 0, false},
+		{".", 0, false},
+	}
+	for _, tc := range tests {
+		l, ok := s.match(tc.name)
+		if ok != tc.match {
+			t.Errorf("Expected match %t for %s, got %t", tc.match, tc.name, ok)
+			continue
+		}
+		if ok && l != tc.list {
+			t.Errorf("Expected list %d for %s, got %d", tc.list, tc.name, l)
+		}
+	}
+}
+
+func TestSetEmpty(t *testing.T) {
+	s := (&builder{}).build()
+	if _, ok := s.match("example.org."); ok {
+		t.Errorf("Expected no match in empty set")
+	}
+	var n *set
+	if _, ok := n.match("example.org."); ok {
+		t.Errorf("Expected no match in nil set")
+	}
+}
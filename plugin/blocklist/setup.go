@@ -0,0 +1,186 @@
+package blocklist
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+var log = clog.NewWithPlugin("blocklist")
+
+func init() { plugin.Register("blocklist", setup) }
+
+func periodicReload(b *Blocklist) chan bool {
+	reloadChan := make(chan bool)
+
+	if b.reload == 0 {
+		return reloadChan
+	}
+
+	go func() {
+		ticker := time.NewTicker(b.reload)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-reloadChan:
+				return
+			case <-ticker.C:
+				if !b.changed() {
+					continue
+				}
+				if err := b.load(); err != nil {
+					log.Errorf("Failed to reload lists, keeping the current ones: %s", err)
+				}
+			}
+		}
+	}()
+	return reloadChan
+}
+
+func setup(c *caddy.Controller) error {
+	b, err := blocklistParse(c)
+	if err != nil {
+		return plugin.Error("blocklist", err)
+	}
+
+	var reloadChan chan bool
+	c.OnStartup(func() error {
+		if err := b.load(); err != nil {
+			return plugin.Error("blocklist", err)
+		}
+		reloadChan = periodicReload(b)
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		if reloadChan != nil {
+			close(reloadChan)
+		}
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		b.Next = next
+		return b
+	})
+
+	return nil
+}
+
+func blocklistParse(c *caddy.Controller) (*Blocklist, error) {
+	config := dnsserver.GetConfig(c)
+
+	b := &Blocklist{
+		response: responseNXDomain,
+		ttl:      3600,
+		reload:   time.Minute,
+	}
+
+	path := func(p string) string {
+		if !filepath.IsAbs(p) && config.Root != "" {
+			return filepath.Join(config.Root, p)
+		}
+		return p
+	}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		b.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "list":
+				args := c.RemainingArgs()
+				if len(args) < 1 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				s := &source{path: path(args[0]), name: filepath.Base(args[0])}
+				if len(args) == 2 {
+					s.name = args[1]
+				}
+				b.lists = append(b.lists, s)
+			case "allowlist":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				b.allows = append(b.allows, &source{path: path(args[0]), name: filepath.Base(args[0])})
+			case "allow":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					name, ok := domain(a)
+					if !ok {
+						return nil, c.Errf("invalid domain name %q", a)
+					}
+					b.inline = append(b.inline, name)
+				}
+			case "response":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				switch strings.ToLower(args[0]) {
+				case "nxdomain":
+					b.response = responseNXDomain
+				case "zero":
+					b.response = responseZero
+				case "refused":
+					b.response = responseRefused
+				default:
+					return nil, c.Errf("unknown response %q; expect 'nxdomain', 'zero' or 'refused'", args[0])
+				}
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				ttl, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, c.Errf("ttl needs a number of second")
+				}
+				if ttl <= 0 || ttl > 65535 {
+					return nil, c.Errf("ttl provided is invalid")
+				}
+				b.ttl = uint32(ttl)
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.Errf("reload needs a duration (zero seconds to disable)")
+				}
+				reload, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, c.Errf("invalid duration for reload '%s'", args[0])
+				}
+				if reload < 0 {
+					return nil, c.Errf("invalid negative duration for reload '%s'", args[0])
+				}
+				b.reload = reload
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(b.lists) == 0 {
+		return nil, c.Err("at least one list is required")
+	}
+	if len(b.lists) > 1<<16 {
+		return nil, c.Errf("too many lists: %d", len(b.lists))
+	}
+
+	return b, nil
+}
@@ -0,0 +1,105 @@
+package blocklist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input          string
+		shouldErr      bool
+		expectedZones  []string
+		expectedLists  []string
+		expectedResp   response
+		expectedTTL    uint32
+		expectedReload time.Duration
+	}{
+		{`blocklist {
+			list ads.txt
+		}`, false, []string{"."}, []string{"ads.txt"}, responseNXDomain, 3600, time.Minute},
+		{`blocklist example.org {
+			list /lists/ads.txt ads
+			list malware.txt
+			allowlist allow.txt
+			allow good.example.org
+			response zero
+			ttl 60
+			reload 0
+		}`, false, []string{"example.org."}, []string{"ads", "malware.txt"}, responseZero, 60, 0},
+		{`blocklist {
+			list ads.txt
+			response refused
+		}`, false, []string{"."}, []string{"ads.txt"}, responseRefused, 3600, time.Minute},
+		// errors
+		{`blocklist`, true, nil, nil, 0, 0, 0},
+		{`blocklist {
+			list
+		}`, true, nil, nil, 0, 0, 0},
+		{`blocklist {
+			list ads.txt
+			response servfail
+		}`, true, nil, nil, 0, 0, 0},
+		{`blocklist {
+			list ads.txt
+			ttl -1
+		}`, true, nil, nil, 0, 0, 0},
+		{`blocklist {
+			list ads.txt
+			reload -1s
+		}`, true, nil, nil, 0, 0, 0},
+		{`blocklist {
+			list ads.txt
+			allow *.example.org
+		}`, true, nil, nil, 0, 0, 0},
+		{`blocklist {
+			list ads.txt
+			unknown
+		}`, true, nil, nil, 0, 0, 0},
+		{`blocklist {
+			list ads.txt
+		}
+		blocklist {
+			list ads.txt
+		}`, true, nil, nil, 0, 0, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		c.ServerBlockKeys = []string{"."}
+		b, err := blocklistParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s: %s", i, tc.input, err)
+			continue
+		}
+
+		if len(b.Zones) != len(tc.expectedZones) || b.Zones[0] != tc.expectedZones[0] {
+			t.Errorf("Test %d: expected zones %v, got %v", i, tc.expectedZones, b.Zones)
+		}
+		if len(b.lists) != len(tc.expectedLists) {
+			t.Fatalf("Test %d: expected %d lists, got %d", i, len(tc.expectedLists), len(b.lists))
+		}
+		for j, l := range b.lists {
+			if l.name != tc.expectedLists[j] {
+				t.Errorf("Test %d: expected list name %s, got %s", i, tc.expectedLists[j], l.name)
+			}
+		}
+		if b.response != tc.expectedResp {
+			t.Errorf("Test %d: expected response %d, got %d", i, tc.expectedResp, b.response)
+		}
+		if b.ttl != tc.expectedTTL {
+			t.Errorf("Test %d: expected ttl %d, got %d", i, tc.expectedTTL, b.ttl)
+		}
+		if b.reload != tc.expectedReload {
+			t.Errorf("Test %d: expected reload %s, got %s", i, tc.expectedReload, b.reload)
+		}
+	}
+}
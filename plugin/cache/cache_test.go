@@ -9,6 +9,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
@@ -425,6 +426,30 @@ func TestServeFromStaleCache(t *testing.T) {
 	}
 }
 
+func TestServeFromStaleCacheExtendedError(t *testing.T) {
+	c := New()
+	c.Next = ttlBackend(60)
+	c.staleUpTo = 1 * time.Hour
+
+	req := new(dns.Msg)
+	req.SetQuestion("cached.org.", dns.TypeA)
+	req.SetEdns0(4096, false)
+	ctx := context.TODO()
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(ctx, rec, req)
+	if ede := edns.ExtendedError(rec.Msg); ede != nil {
+		t.Errorf("Expected no Extended DNS Error for a fresh answer, got %v", ede)
+	}
+
+	c.now = func() time.Time { return time.Now().Add(30 * time.Minute) }
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(ctx, rec, req)
+	if ede := edns.ExtendedError(rec.Msg); ede == nil || ede.InfoCode != dns.ExtendedErrorCodeStaleAnswer {
+		t.Errorf("Expected Extended DNS Error %d for a stale answer, got %v", dns.ExtendedErrorCodeStaleAnswer, ede)
+	}
+}
+
 func TestServeFromStaleCacheFetchVerify(t *testing.T) {
 	c := New()
 	c.Next = ttlBackend(120)
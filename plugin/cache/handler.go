@@ -8,6 +8,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -77,6 +78,13 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		now = i.stored
 	}
 	resp := i.toMsg(r, now, do, ad)
+	if ttl < 0 {
+		code := dns.ExtendedErrorCodeStaleAnswer
+		if resp.Rcode == dns.RcodeNameError {
+			code = dns.ExtendedErrorCodeStaleNXDOMAINAnswer
+		}
+		edns.SetExtendedError(r, resp, code, "")
+	}
 	w.WriteMsg(resp)
 	return dns.RcodeSuccess, nil
 }
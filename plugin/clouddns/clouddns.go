@@ -14,6 +14,7 @@ import (
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/pkg/zonerefresh"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -28,6 +29,7 @@ type CloudDNS struct {
 	zoneNames []string
 	client    gcpDNS
 	upstream  *upstream.Upstream
+	refresh   zonerefresh.Options
 
 	zMu   sync.RWMutex
 	zones zones
@@ -38,6 +40,10 @@ type zone struct {
 	zoneName    string
 	z           *file.Zone
 	dns         string
+
+	// version and fetched are only used by the refresh of the hosted zone, they aren't locked.
+	version string    // the ID of the latest change of the hosted zone when its records were fetched
+	fetched time.Time // when the records were fetched
 }
 
 type zones map[string][]*zone
@@ -72,35 +78,34 @@ func New(ctx context.Context, c gcpDNS, keys map[string][]string, up *upstream.U
 		zoneNames: zoneNames,
 		zones:     zones,
 		upstream:  up,
+		refresh:   zonerefresh.New(),
 	}, nil
 }
 
-// Run executes first update, spins up an update forever-loop.
+// Run executes first update, spins up an update forever-loop for each hosted zone.
 // Returns error if first update fails.
 func (h *CloudDNS) Run(ctx context.Context) error {
 	if err := h.updateZones(ctx); err != nil {
 		return err
 	}
-	go func() {
-		delay := 1 * time.Minute
-		timer := time.NewTimer(delay)
-		defer timer.Stop()
-		for {
-			timer.Reset(delay)
-			select {
-			case <-ctx.Done():
-				log.Debugf("Breaking out of CloudDNS update loop for %v: %v", h.zoneNames, ctx.Err())
-				return
-			case <-timer.C:
-				if err := h.updateZones(ctx); err != nil && ctx.Err() == nil /* Don't log error if ctx expired. */ {
-					log.Errorf("Failed to update zones %v: %v", h.zoneNames, err)
-				}
-			}
+	for _, z := range h.zones {
+		for _, hostedZone := range z {
+			go h.runZone(ctx, hostedZone)
 		}
-	}()
+	}
 	return nil
 }
 
+// runZone refreshes the records of hostedZone in its project until ctx is done.
+func (h *CloudDNS) runZone(ctx context.Context, hostedZone *zone) {
+	h.refresh.Run(ctx, hostedZone.dns, func(ctx context.Context) {
+		if err := h.updateZone(ctx, hostedZone); err != nil && ctx.Err() == nil /* Don't log error if ctx expired. */ {
+			log.Errorf("Failed to update zone %v, serving the records fetched before: %v", hostedZone.name(), err)
+		}
+	})
+	log.Debugf("Breaking out of CloudDNS update loop for %v: %v", hostedZone.name(), ctx.Err())
+}
+
 // ServeDNS implements the plugin.Handler interface.
 func (h *CloudDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 	state := request.Request{W: w, Req: r}
@@ -184,29 +189,19 @@ func updateZoneFromRRS(rrs *gcp.ResourceRecordSetsListResponse, z *file.Zone) er
 func (h *CloudDNS) updateZones(ctx context.Context) error {
 	errc := make(chan error)
 	defer close(errc)
-	for zName, z := range h.zones {
-		go func(zName string, z []*zone) {
+	for _, z := range h.zones {
+		go func(z []*zone) {
 			var err error
-			var rrListResponse *gcp.ResourceRecordSetsListResponse
 			defer func() {
 				errc <- err
 			}()
 
-			for i, hostedZone := range z {
-				newZ := file.NewZone(zName, "")
-				newZ.Upstream = h.upstream
-				rrListResponse, err = h.client.listRRSets(ctx, hostedZone.projectName, hostedZone.zoneName)
-				if err != nil {
-					err = fmt.Errorf("failed to list resource records for %v:%v:%v from gcp: %v", zName, hostedZone.projectName, hostedZone.zoneName, err)
+			for _, hostedZone := range z {
+				if err = h.updateZone(ctx, hostedZone); err != nil {
 					return
 				}
-				updateZoneFromRRS(rrListResponse, newZ)
-
-				h.zMu.Lock()
-				(*z[i]).z = newZ
-				h.zMu.Unlock()
 			}
-		}(zName, z)
+		}(z)
 	}
 	// Collect errors (if any). This will also sync on all zones updates
 	// completion.
@@ -223,5 +218,50 @@ func (h *CloudDNS) updateZones(ctx context.Context) error {
 	return nil
 }
 
+// updateZone lists the resource record sets of hostedZone into a new zone, and swaps it in. With incremental
+// refresh, the changes of the hosted zone are checked first and the listing is skipped if there's no new
+// one, unless a full refresh is due. On failure the old zone stays in place.
+func (h *CloudDNS) updateZone(ctx context.Context, hostedZone *zone) (err error) {
+	defer func() {
+		if err != nil {
+			zoneStale.WithLabelValues(hostedZone.dns, hostedZone.name()).Set(1)
+			zoneRefreshCount.WithLabelValues(hostedZone.dns, hostedZone.name(), "failed").Inc()
+			return
+		}
+		zoneStale.WithLabelValues(hostedZone.dns, hostedZone.name()).Set(0)
+		zoneRefreshTime.WithLabelValues(hostedZone.dns, hostedZone.name()).SetToCurrentTime()
+	}()
+
+	var version string
+	if h.refresh.Incremental {
+		version, err = h.client.latestChange(ctx, hostedZone.projectName, hostedZone.zoneName)
+		if err != nil {
+			return fmt.Errorf("failed to list changes for %v:%v from gcp: %v", hostedZone.dns, hostedZone.name(), err)
+		}
+		if h.refresh.Unchanged(hostedZone.dns, hostedZone.version, version, hostedZone.fetched) {
+			zoneRefreshCount.WithLabelValues(hostedZone.dns, hostedZone.name(), "unchanged").Inc()
+			return nil
+		}
+	}
+
+	newZ := file.NewZone(hostedZone.dns, "")
+	newZ.Upstream = h.upstream
+	rrListResponse, err := h.client.listRRSets(ctx, hostedZone.projectName, hostedZone.zoneName)
+	if err != nil {
+		return fmt.Errorf("failed to list resource records for %v:%v from gcp: %v", hostedZone.dns, hostedZone.name(), err)
+	}
+	updateZoneFromRRS(rrListResponse, newZ)
+
+	h.zMu.Lock()
+	hostedZone.z = newZ
+	h.zMu.Unlock()
+	hostedZone.version, hostedZone.fetched = version, time.Now()
+	zoneRefreshCount.WithLabelValues(hostedZone.dns, hostedZone.name(), "updated").Inc()
+	return nil
+}
+
+// name returns the project and name of the hosted zone, separated by a colon.
+func (z *zone) name() string { return z.projectName + ":" + z.zoneName }
+
 // Name implements the Handler interface.
 func (h *CloudDNS) Name() string { return "clouddns" }
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
@@ -15,6 +16,7 @@ import (
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	gcp "google.golang.org/api/dns/v1"
 )
 
@@ -26,6 +28,10 @@ func (c fakeGCPClient) zoneExists(projectName, hostedZoneName string) error {
 	return nil
 }
 
+func (c fakeGCPClient) latestChange(ctx context.Context, projectName, hostedZoneName string) (string, error) {
+	return "", nil
+}
+
 func (c fakeGCPClient) listRRSets(ctx context.Context, projectName, hostedZoneName string) (*gcp.ResourceRecordSetsListResponse, error) {
 	if projectName == "bad-project" || hostedZoneName == "bad-zone" {
 		return nil, errors.New("the 'parameters.managedZone' resource named 'bad-zone' does not exist")
@@ -456,3 +462,168 @@ func TestCloudDNSConcurrentLookupNameCache(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+// fakeChangingGCPClient is a Cloud DNS of which the records can change, and which can fail.
+type fakeChangingGCPClient struct {
+	mu      sync.Mutex
+	records map[string]string // name -> address
+	change  string            // the ID of the latest change of the hosted zone, empty if it's gone
+	lists   int               // the number of times the records were listed
+	fail    bool
+}
+
+func (c *fakeChangingGCPClient) zoneExists(projectName, hostedZoneName string) error { return nil }
+
+func (c *fakeChangingGCPClient) latestChange(ctx context.Context, projectName, hostedZoneName string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail {
+		return "", errors.New("rate limit exceeded")
+	}
+	if c.change == "" {
+		return changeVersion(nil)
+	}
+	return c.change, nil
+}
+
+func (c *fakeChangingGCPClient) listRRSets(ctx context.Context, projectName, hostedZoneName string) (*gcp.ResourceRecordSetsListResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail {
+		return nil, errors.New("rate limit exceeded")
+	}
+	c.lists++
+	rr := []*gcp.ResourceRecordSet{{
+		Name:    "example.org.",
+		Ttl:     300,
+		Type:    "SOA",
+		Rrdatas: []string{"ns-cloud-c1.googledomains.com. cloud-dns-hostmaster.google.com. 1 21600 300 259200 300"},
+	}}
+	for name, addr := range c.records {
+		rr = append(rr, &gcp.ResourceRecordSet{Name: name, Ttl: 300, Type: "A", Rrdatas: []string{addr}})
+	}
+	return &gcp.ResourceRecordSetsListResponse{Rrsets: rr}, nil
+}
+
+func (c *fakeChangingGCPClient) set(name, addr, change string, fail bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records[name], c.change, c.fail = addr, change, fail
+}
+
+func TestCloudDNSIncremental(t *testing.T) {
+	ctx := t.Context()
+	fake := &fakeChangingGCPClient{records: map[string]string{"www.example.org.": "1.2.3.4"}, change: "1:done"}
+	r, err := New(ctx, fake, map[string][]string{"example.org.": {"sample-project-1:sample-zone-1"}}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Cloud DNS: %v", err)
+	}
+	r.refresh.Incremental = true
+	if err := r.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+	hostedZone := r.zones["example.org."][0]
+
+	check := func(step, expected string, lists int) {
+		t.Helper()
+		req := new(dns.Msg)
+		req.SetQuestion("www.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(ctx, rec, req); err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != expected {
+			t.Errorf("%s: expected %s, got %v", step, expected, rec.Msg.Answer)
+		}
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if fake.lists != lists {
+			t.Errorf("%s: expected the records to be listed %d times, got %d", step, lists, fake.lists)
+		}
+	}
+	check("initial", "1.2.3.4", 1)
+
+	// Without a new change, the records are not listed again.
+	fake.set("www.example.org.", "1.2.3.5", "1:done", false)
+	if err := r.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("unchanged", "1.2.3.4", 1)
+
+	// After five refresh intervals the records are listed anyway.
+	hostedZone.fetched = time.Now().Add(-5 * time.Minute)
+	if err := r.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("full refresh", "1.2.3.5", 2)
+
+	fake.set("www.example.org.", "1.2.3.6", "2:pending", false)
+	if err := r.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("changed", "1.2.3.6", 3)
+
+	// When Cloud DNS fails, the records fetched before are served, and the hosted zone is reported stale.
+	fake.set("www.example.org.", "1.2.3.7", "3:done", true)
+	if err := r.updateZone(ctx, hostedZone); err == nil {
+		t.Fatal("Expected an error when Cloud DNS fails")
+	}
+	check("failed", "1.2.3.6", 3)
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org.", "sample-project-1:sample-zone-1")); stale != 1 {
+		t.Errorf("Expected the hosted zone to be reported stale, got %v", stale)
+	}
+
+	fake.set("www.example.org.", "1.2.3.7", "3:done", false)
+	if err := r.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("recovered", "1.2.3.7", 4)
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org.", "sample-project-1:sample-zone-1")); stale != 0 {
+		t.Errorf("Expected the hosted zone not to be reported stale, got %v", stale)
+	}
+}
+
+func TestCloudDNSZoneNotFound(t *testing.T) {
+	ctx := t.Context()
+	fake := &fakeChangingGCPClient{records: map[string]string{"www.example.org.": "1.2.3.4"}, change: "1:done"}
+	r, err := New(ctx, fake, map[string][]string{"example.org.": {"sample-project-2:sample-zone-2"}}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Cloud DNS: %v", err)
+	}
+	r.refresh.Incremental = true
+	if err := r.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+
+	// The hosted zone has no changes anymore, so it was deleted: the records fetched before are served.
+	fake.set("www.example.org.", "1.2.3.5", "", false)
+	hostedZone := r.zones["example.org."][0]
+	if err := r.updateZone(ctx, hostedZone); err == nil {
+		t.Fatal("Expected an error for a hosted zone without changes")
+	}
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org.", "sample-project-2:sample-zone-2")); stale != 1 {
+		t.Errorf("Expected the hosted zone to be reported stale, got %v", stale)
+	}
+	req := new(dns.Msg)
+	req.SetQuestion("www.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(ctx, rec, req); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != "1.2.3.4" {
+		t.Errorf("Expected the records fetched before, got %v", rec.Msg.Answer)
+	}
+}
+
+func TestChangeVersion(t *testing.T) {
+	if _, err := changeVersion(nil); err == nil {
+		t.Error("Expected an error for a hosted zone without changes")
+	}
+	v, err := changeVersion([]*gcp.Change{{Id: "7", Status: "pending"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "7:pending" {
+		t.Errorf("Expected version 7:pending, got %s", v)
+	}
+}
@@ -2,6 +2,7 @@ package clouddns
 
 import (
 	"context"
+	"errors"
 
 	gcp "google.golang.org/api/dns/v1"
 )
@@ -9,6 +10,7 @@ import (
 type gcpDNS interface {
 	zoneExists(projectName, hostedZoneName string) error
 	listRRSets(ctx context.Context, projectName, hostedZoneName string) (*gcp.ResourceRecordSetsListResponse, error)
+	latestChange(ctx context.Context, projectName, hostedZoneName string) (string, error)
 }
 
 type gcpClient struct {
@@ -38,3 +40,23 @@ func (c gcpClient) listRRSets(ctx context.Context, projectName, hostedZoneName s
 	}
 	return &gcp.ResourceRecordSetsListResponse{Rrsets: rs}, nil
 }
+
+// latestChange is a wrapper method around `gcp.Service.Changes.List`
+// it returns the ID and status of the latest change of a hosted zone.
+func (c gcpClient) latestChange(ctx context.Context, projectName, hostedZoneName string) (string, error) {
+	resp, err := c.Changes.List(projectName, hostedZoneName).SortBy("changeSequence").SortOrder("descending").MaxResults(1).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	return changeVersion(resp.Changes)
+}
+
+// changeVersion returns the version of a hosted zone from its latest changes. Cloud DNS lists the creation
+// of a hosted zone as its first change, so a hosted zone without changes isn't there (anymore).
+func changeVersion(changes []*gcp.Change) (string, error) {
+	if len(changes) == 0 {
+		return "", errors.New("hosted zone not found")
+	}
+	// A pending change is reported again once it is done, so its records are fetched again then.
+	return changes[0].Id + ":" + changes[0].Status, nil
+}
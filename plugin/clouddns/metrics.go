@@ -0,0 +1,32 @@
+package clouddns
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// zoneRefreshCount is the number of refreshes of a hosted zone, by result.
+	zoneRefreshCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "clouddns",
+		Name:      "zone_refreshes_total",
+		Help:      "Counter of refreshes of hosted zones, by result: updated, unchanged or failed.",
+	}, []string{"zone", "hosted_zone", "result"})
+	// zoneStale is whether the last refresh of a hosted zone failed, and records fetched before are served.
+	zoneStale = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "clouddns",
+		Name:      "zone_stale",
+		Help:      "Whether the last refresh of a hosted zone failed and records fetched before are served.",
+	}, []string{"zone", "hosted_zone"})
+	// zoneRefreshTime is the timestamp of the last successful refresh of a hosted zone.
+	zoneRefreshTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "clouddns",
+		Name:      "zone_last_refresh_timestamp_seconds",
+		Help:      "The timestamp of the last successful refresh of a hosted zone.",
+	}, []string{"zone", "hosted_zone"})
+)
@@ -10,6 +10,7 @@ import (
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/pkg/zonerefresh"
 
 	gcp "google.golang.org/api/dns/v1"
 	"google.golang.org/api/option"
@@ -40,6 +41,7 @@ func setup(c *caddy.Controller) error {
 
 		var fall fall.F
 		up := upstream.New()
+		refresh := zonerefresh.New()
 
 		args := c.RemainingArgs()
 
@@ -74,7 +76,13 @@ func setup(c *caddy.Controller) error {
 			case "fallthrough":
 				fall.SetZonesFromArgs(c.RemainingArgs())
 			default:
-				return plugin.Error("clouddns", c.Errf("unknown property %q", c.Val()))
+				ok, err := refresh.Parse(c)
+				if err != nil {
+					return plugin.Error("clouddns", err)
+				}
+				if !ok {
+					return plugin.Error("clouddns", c.Errf("unknown property %q", c.Val()))
+				}
 			}
 		}
 
@@ -91,6 +99,11 @@ func setup(c *caddy.Controller) error {
 			return plugin.Error("clouddns", c.Errf("failed to create plugin: %v", err))
 		}
 		h.Fall = fall
+		h.refresh = refresh
+		if err := refresh.Validate(h.zoneNames); err != nil {
+			cancel()
+			return plugin.Error("clouddns", c.Err(err.Error()))
+		}
 
 		if err := h.Run(ctx); err != nil {
 			cancel()
@@ -38,6 +38,33 @@ func TestSetupCloudDNS(t *testing.T) {
 
 		{`clouddns example.org {
 	}`, true},
+		{`clouddns example.org.:example-project:zone-name {
+    refresh 90
+}`, false},
+		{`clouddns example.org.:example-project:zone-name {
+    refresh 0
+}`, true},
+		{`clouddns example.org.:example-project:zone-name {
+    refresh
+}`, true},
+		{`clouddns example.org.:example-project:zone-name {
+    refresh 10s example.org
+}`, false},
+		{`clouddns example.org.:example-project:zone-name {
+    refresh 10s example.com.
+}`, true},
+		{`clouddns example.org.:example-project:zone-name {
+    incremental
+}`, false},
+		{`clouddns example.org.:example-project:zone-name {
+    incremental 6h
+}`, false},
+		{`clouddns example.org.:example-project:zone-name {
+    incremental 0
+}`, true},
+		{`clouddns example.org.:example-project:zone-name {
+    incremental 1h 2h
+}`, true},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,174 @@
+package consul
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/retry"
+)
+
+// retryBackoff is how long a watch waits before it retries a failed query.
+var retryBackoff = 2 * time.Second
+
+// dcCache holds the healthy instances of all services in a datacenter. It is kept up to date with blocking
+// queries: one on the catalog to learn about services coming and going, and one per service on its health.
+// When Consul can't be reached the last known instances are served.
+type dcCache struct {
+	client *client
+	dc     string
+
+	mu       sync.RWMutex
+	services map[string][]serviceEntry     // keyed by the lower cased service name
+	watches  map[string]context.CancelFunc // keyed by the service name
+
+	synced chan struct{} // closed once all services are fetched for the first time
+}
+
+func newDCCache(client *client, dc string) *dcCache {
+	return &dcCache{
+		client:   client,
+		dc:       dc,
+		services: map[string][]serviceEntry{},
+		watches:  map[string]context.CancelFunc{},
+		synced:   make(chan struct{}),
+	}
+}
+
+// isSynced returns true once the catalog of the datacenter and the instances of its services have been
+// fetched.
+func (d *dcCache) isSynced() bool {
+	return retry.Closed(d.synced)
+}
+
+// lookup returns the healthy instances of service. exists is false if the service isn't in the catalog, and
+// fetched is false if it is, but its instances couldn't be fetched from Consul yet.
+func (d *dcCache) lookup(service string) (entries []serviceEntry, exists, fetched bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if entries, ok := d.services[strings.ToLower(service)]; ok {
+		return entries, true, true
+	}
+	for name := range d.watches {
+		if strings.EqualFold(name, service) {
+			return nil, true, false
+		}
+	}
+	return nil, false, false
+}
+
+// node returns the instances running on node.
+func (d *dcCache) node(node string) []serviceEntry {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var entries []serviceEntry
+	for _, sx := range d.services {
+		for _, s := range sx {
+			if strings.EqualFold(s.Node.Node, node) {
+				entries = append(entries, s)
+			}
+		}
+	}
+	return entries
+}
+
+// run watches the catalog of the datacenter until ctx is done.
+func (d *dcCache) run(ctx context.Context) {
+	var index uint64
+	for {
+		services, newIndex, err := d.client.services(ctx, d.dc, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warningf("Failed to watch the catalog of datacenter %q: %s", d.dc, err)
+			watchErrors.WithLabelValues(d.dc).Inc()
+			if !retry.Sleep(ctx, retryBackoff) {
+				return
+			}
+			continue
+		}
+		index = nextIndex(index, newIndex)
+
+		d.update(ctx, services)
+		if !d.isSynced() {
+			close(d.synced)
+		}
+	}
+}
+
+// update starts watching new services and stops watching the ones that are gone. New services are fetched
+// before update returns, so they can be served right away.
+func (d *dcCache) update(ctx context.Context, services map[string][]string) {
+	d.mu.Lock()
+	for name, cancel := range d.watches {
+		if _, ok := services[name]; !ok {
+			cancel()
+			delete(d.watches, name)
+			delete(d.services, strings.ToLower(name))
+		}
+	}
+	var added []string
+	for name := range services {
+		if _, ok := d.watches[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	d.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, name := range added {
+		ctx, cancel := context.WithCancel(ctx)
+		d.mu.Lock()
+		d.watches[name] = cancel
+		d.mu.Unlock()
+
+		wg.Add(1)
+		go d.watch(ctx, name, wg.Done)
+	}
+	wg.Wait()
+	d.mu.RLock()
+	cacheServices.WithLabelValues(d.dc).Set(float64(len(d.watches)))
+	d.mu.RUnlock()
+}
+
+// watch keeps the instances of service up to date until ctx is done. fetched is called after the first fetch,
+// whether it succeeded or not.
+func (d *dcCache) watch(ctx context.Context, service string, fetched func()) {
+	var index uint64
+	for {
+		entries, newIndex, err := d.client.health(ctx, d.dc, service, index)
+		if ctx.Err() != nil {
+			if fetched != nil {
+				fetched()
+			}
+			return
+		}
+		if err != nil {
+			log.Warningf("Failed to watch service %q in datacenter %q: %s", service, d.dc, err)
+			watchErrors.WithLabelValues(d.dc).Inc()
+		} else {
+			index = nextIndex(index, newIndex)
+			healthy := make([]serviceEntry, 0, len(entries))
+			for _, e := range entries {
+				if e.passing() {
+					healthy = append(healthy, e)
+				}
+			}
+			d.mu.Lock()
+			// The service may be gone by now.
+			if _, ok := d.watches[service]; ok {
+				d.services[strings.ToLower(service)] = healthy
+			}
+			d.mu.Unlock()
+		}
+		if fetched != nil {
+			fetched()
+			fetched = nil
+		}
+		if err != nil && !retry.Sleep(ctx, retryBackoff) {
+			return
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// blockingWait is how long Consul may hold a blocking query before it returns.
+	blockingWait = 5 * time.Minute
+	// requestTimeout is the timeout of a request to Consul, on top of the blocking wait.
+	requestTimeout = 30 * time.Second
+)
+
+// serviceEntry is an instance of a service, as returned by the health endpoint of Consul.
+type serviceEntry struct {
+	Node struct {
+		Node       string
+		Address    string
+		Datacenter string
+	}
+	Service struct {
+		ID      string
+		Service string
+		Address string
+		Port    int
+		Tags    []string
+	}
+	Checks []struct {
+		Status string
+	}
+}
+
+// address returns the address of the service instance, which is the address of the node if the service has none.
+func (s *serviceEntry) address() string {
+	if s.Service.Address != "" {
+		return s.Service.Address
+	}
+	return s.Node.Address
+}
+
+// passing returns true if all health checks of the instance pass.
+func (s *serviceEntry) passing() bool {
+	for _, c := range s.Checks {
+		if c.Status != "passing" {
+			return false
+		}
+	}
+	return true
+}
+
+// client talks to the HTTP API of Consul. When a request to an address fails the next one is used.
+type client struct {
+	addresses []string
+	token     string
+	http      *http.Client
+
+	mu      sync.Mutex
+	current int
+}
+
+func newClient(addresses []string, token string) *client {
+	return &client{addresses: addresses, token: token, http: &http.Client{}}
+}
+
+// get does a request for path with query and decodes the response into out. If index is not 0 it is a
+// blocking query, which returns when the index changed or the wait time passed. The index of the response is
+// returned.
+func (c *client) get(ctx context.Context, path string, query url.Values, index uint64, out any) (uint64, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	timeout := requestTimeout
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", blockingWait.String())
+		timeout += blockingWait
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	c.mu.Lock()
+	current := c.current
+	c.mu.Unlock()
+
+	var err error
+	for i := range c.addresses {
+		idx := (current + i) % len(c.addresses)
+		var newIndex uint64
+		newIndex, err = c.do(ctx, c.addresses[idx], path, query, out)
+		if err == nil {
+			c.mu.Lock()
+			c.current = idx
+			c.mu.Unlock()
+			return newIndex, nil
+		}
+		if ctx.Err() != nil {
+			return 0, err
+		}
+	}
+	return 0, err
+}
+
+func (c *client) do(ctx context.Context, address, path string, query url.Values, out any) (uint64, error) {
+	u := strings.TrimSuffix(address, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %q from %s", resp.Status, u)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("failed to decode response from %s: %w", u, err)
+	}
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	return index, nil
+}
+
+// services returns the names of the services in the catalog of dc.
+func (c *client) services(ctx context.Context, dc string, index uint64) (map[string][]string, uint64, error) {
+	services := map[string][]string{}
+	index, err := c.get(ctx, "/v1/catalog/services", dcQuery(dc), index, &services)
+	return services, index, err
+}
+
+// health returns the instances of service in dc with passing health checks.
+func (c *client) health(ctx context.Context, dc, service string, index uint64) ([]serviceEntry, uint64, error) {
+	query := dcQuery(dc)
+	query.Set("passing", "true")
+	var entries []serviceEntry
+	index, err := c.get(ctx, "/v1/health/service/"+url.PathEscape(service), query, index, &entries)
+	return entries, index, err
+}
+
+// datacenters returns the known datacenters.
+func (c *client) datacenters(ctx context.Context) ([]string, error) {
+	var dcs []string
+	_, err := c.get(ctx, "/v1/catalog/datacenters", nil, 0, &dcs)
+	return dcs, err
+}
+
+func dcQuery(dc string) url.Values {
+	query := url.Values{}
+	if dc != "" {
+		query.Set("dc", dc)
+	}
+	return query
+}
+
+// nextIndex returns the index to use for the next blocking query, see
+// https://developer.hashicorp.com/consul/api-docs/features/blocking#implementation-details.
+func nextIndex(old, index uint64) uint64 {
+	if index < old {
+		// Reset, the next query doesn't block.
+		return 0
+	}
+	if index == 0 {
+		return 1
+	}
+	return index
+}
@@ -0,0 +1,303 @@
+// Package consul implements a plugin that serves the services in the catalog of Consul.
+package consul
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const pluginName = "consul"
+
+var (
+	log        = clog.NewWithPlugin(pluginName)
+	defaultTTL = 30
+)
+
+// datacentersRefresh is how long the list of datacenters is used before it is fetched again.
+const datacentersRefresh = 30 * time.Second
+
+// Consul is a plugin that serves the healthy instances of the services registered in Consul.
+type Consul struct {
+	Next plugin.Handler
+
+	Zone       string
+	ttl        uint32
+	datacenter string // the datacenter for names without one, empty for the one of the agent
+	client     *client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	dcs         map[string]*dcCache
+	datacenters []string
+	dcsFetched  time.Time
+}
+
+// Name implements the plugin.Handler interface.
+func (c *Consul) Name() string { return pluginName }
+
+// ServeDNS implements the plugin.Handler interface.
+func (c *Consul) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones([]string{c.Zone}).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if state.Name() == c.Zone {
+		if state.QType() == dns.TypeSOA {
+			m.Answer = []dns.RR{c.soa()}
+		} else {
+			m.Ns = []dns.RR{c.soa()}
+		}
+		requestSuccessCount.WithLabelValues(metrics.WithServer(ctx), c.datacenter).Inc()
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	}
+
+	q, ok := parseName(state.Name(), c.Zone)
+	if !ok {
+		return c.nxdomain(ctx, w, m, "")
+	}
+	dc := q.datacenter
+	if dc == "" {
+		dc = c.datacenter
+	}
+	d, err := c.dcCache(ctx, dc)
+	if err != nil {
+		log.Warning(err)
+		return c.servfail(ctx, dc)
+	}
+	if d == nil {
+		return c.nxdomain(ctx, w, m, dc)
+	}
+	if !d.isSynced() {
+		return c.servfail(ctx, dc)
+	}
+
+	var entries []serviceEntry
+	if q.node != "" {
+		entries = d.node(q.node)
+		if len(entries) == 0 {
+			return c.nxdomain(ctx, w, m, dc)
+		}
+	} else {
+		all, exists, fetched := d.lookup(q.service)
+		switch {
+		case !exists:
+			return c.nxdomain(ctx, w, m, dc)
+		case !fetched:
+			return c.servfail(ctx, dc)
+		}
+		for _, e := range all {
+			if q.tag == "" || slices.ContainsFunc(e.Service.Tags, func(t string) bool { return strings.EqualFold(t, q.tag) }) {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	for _, e := range entries {
+		ip := net.ParseIP(e.address())
+		if ip == nil {
+			continue
+		}
+		switch state.QType() {
+		case dns.TypeA:
+			if ip.To4() != nil {
+				m.Answer = append(m.Answer, c.address(state.QName(), ip))
+			}
+		case dns.TypeAAAA:
+			if ip.To4() == nil {
+				m.Answer = append(m.Answer, c.address(state.QName(), ip))
+			}
+		case dns.TypeSRV:
+			if q.node != "" {
+				continue
+			}
+			target := c.nodeName(e)
+			m.Answer = append(m.Answer, &dns.SRV{
+				Hdr:      dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: c.ttl},
+				Priority: 1,
+				Weight:   1,
+				Port:     uint16(e.Service.Port),
+				Target:   target,
+			})
+			m.Extra = append(m.Extra, c.address(target, ip))
+		}
+	}
+	m.Answer = dedup(m.Answer)
+	m.Extra = dedup(m.Extra)
+	if len(m.Answer) == 0 {
+		m.Ns = []dns.RR{c.soa()}
+	}
+
+	requestSuccessCount.WithLabelValues(metrics.WithServer(ctx), dc).Inc()
+	return dns.RcodeSuccess, w.WriteMsg(m)
+}
+
+// nxdomain writes a name error response.
+func (c *Consul) nxdomain(ctx context.Context, w dns.ResponseWriter, m *dns.Msg, dc string) (int, error) {
+	m.Rcode = dns.RcodeNameError
+	m.Ns = []dns.RR{c.soa()}
+	requestSuccessCount.WithLabelValues(metrics.WithServer(ctx), dc).Inc()
+	return dns.RcodeSuccess, w.WriteMsg(m)
+}
+
+// servfail returns a server failure, for when the instances can't be fetched from Consul. The server writes
+// the response.
+func (c *Consul) servfail(ctx context.Context, dc string) (int, error) {
+	requestFailedCount.WithLabelValues(metrics.WithServer(ctx), dc).Inc()
+	return dns.RcodeServerFailure, nil
+}
+
+// dcCache returns the cache for dc, starting it if this is the first query for dc. It returns nil if dc
+// doesn't exist.
+func (c *Consul) dcCache(ctx context.Context, dc string) (*dcCache, error) {
+	c.mu.Lock()
+	d, ok := c.dcs[dc]
+	if !ok {
+		known, err := c.knownDatacenter(ctx, dc)
+		if err != nil || !known {
+			c.mu.Unlock()
+			return nil, err
+		}
+		d = newDCCache(c.client, dc)
+		c.dcs[dc] = d
+		go d.run(c.ctx)
+	}
+	c.mu.Unlock()
+
+	// Give a cache started by this query a chance to be filled.
+	select {
+	case <-d.synced:
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+	}
+	return d, nil
+}
+
+// knownDatacenter returns true if dc is one of the datacenters of Consul. The lock must be held.
+func (c *Consul) knownDatacenter(ctx context.Context, dc string) (bool, error) {
+	if time.Since(c.dcsFetched) > datacentersRefresh {
+		dcs, err := c.client.datacenters(ctx)
+		if err != nil {
+			return false, err
+		}
+		c.datacenters, c.dcsFetched = dcs, time.Now()
+	}
+	return slices.Contains(c.datacenters, dc), nil
+}
+
+// address returns an A or AAAA record for ip.
+func (c *Consul) address(name string, ip net.IP) dns.RR {
+	if ip.To4() != nil {
+		return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: c.ttl}, A: ip}
+	}
+	return &dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: c.ttl}, AAAA: ip}
+}
+
+// nodeName returns the name of the node an instance runs on: <node>.node.<datacenter>.<zone>.
+func (c *Consul) nodeName(e serviceEntry) string {
+	labels := []string{strings.ToLower(e.Node.Node), "node"}
+	if e.Node.Datacenter != "" {
+		labels = append(labels, strings.ToLower(e.Node.Datacenter))
+	}
+	return dns.Fqdn(strings.Join(append(labels, c.Zone), "."))
+}
+
+func (c *Consul) soa() *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: c.Zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: c.ttl},
+		Ns:      dns.Fqdn("ns." + c.Zone),
+		Mbox:    dns.Fqdn("hostmaster." + c.Zone),
+		Serial:  uint32(time.Now().Unix()),
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  uint32(defaultTTL),
+	}
+}
+
+// query is a parsed query name.
+type query struct {
+	service    string
+	tag        string
+	node       string
+	datacenter string
+}
+
+// parseName parses name in zone. The names are [<tag>.]<service>.service[.<datacenter>].<zone> and
+// <node>.node[.<datacenter>].<zone>.
+func parseName(name, zone string) (query, bool) {
+	labels := dns.SplitDomainName(strings.TrimSuffix(name, zone))
+	q := query{}
+	if len(labels) >= 3 && (labels[len(labels)-2] == "service" || labels[len(labels)-2] == "node") {
+		q.datacenter = labels[len(labels)-1]
+		labels = labels[:len(labels)-1]
+	}
+	if len(labels) < 2 {
+		return q, false
+	}
+	kind := labels[len(labels)-1]
+	labels = labels[:len(labels)-1]
+
+	switch {
+	case kind == "node" && len(labels) == 1:
+		q.node = labels[0]
+	case kind == "service" && len(labels) == 1:
+		q.service = labels[0]
+	case kind == "service" && len(labels) == 2:
+		q.tag, q.service = labels[0], labels[1]
+	default:
+		return q, false
+	}
+	return q, true
+}
+
+// dedup removes duplicate records from rrs.
+func dedup(rrs []dns.RR) []dns.RR {
+	out := rrs[:0]
+Records:
+	for _, rr := range rrs {
+		for _, o := range out {
+			if dns.IsDuplicate(rr, o) {
+				continue Records
+			}
+		}
+		out = append(out, rr)
+	}
+	return out
+}
+
+// OnStartup starts watching the catalog of the default datacenter.
+func (c *Consul) OnStartup() error {
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	d := newDCCache(c.client, c.datacenter)
+	c.mu.Lock()
+	c.dcs = map[string]*dcCache{c.datacenter: d}
+	c.mu.Unlock()
+	go d.run(c.ctx)
+	return nil
+}
+
+// OnShutdown stops all watches.
+func (c *Consul) OnShutdown() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return nil
+}
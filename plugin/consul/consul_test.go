@@ -0,0 +1,305 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// fakeConsul is a stand-in for the HTTP API of Consul, with support for blocking queries.
+type fakeConsul struct {
+	mu      sync.Mutex
+	index   *test.Index
+	dcs     map[string]map[string][]serviceEntry // datacenter -> service -> instances
+	failing map[string]bool                      // services of which the health can't be fetched
+	token   string
+}
+
+func newFakeConsul(token string) *fakeConsul {
+	return &fakeConsul{index: test.NewIndex(), dcs: map[string]map[string][]serviceEntry{}, failing: map[string]bool{}, token: token}
+}
+
+func entry(node, nodeAddr, dc, svcAddr string, port int, status string, tags ...string) serviceEntry {
+	e := serviceEntry{}
+	e.Node.Node, e.Node.Address, e.Node.Datacenter = node, nodeAddr, dc
+	e.Service.Service, e.Service.Address, e.Service.Port, e.Service.Tags = "", svcAddr, port, tags
+	e.Checks = append(e.Checks, struct{ Status string }{Status: status})
+	return e
+}
+
+func (f *fakeConsul) set(dc, service string, entries ...serviceEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dcs[dc] == nil {
+		f.dcs[dc] = map[string][]serviceEntry{}
+	}
+	if entries == nil {
+		delete(f.dcs[dc], service)
+	} else {
+		f.dcs[dc][service] = entries
+	}
+	f.index.Change()
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.token != "" && r.Header.Get("X-Consul-Token") != f.token {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	if !f.index.Wait(r.Context(), r.URL.Query().Get("index")) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dc := r.URL.Query().Get("dc")
+	if dc == "" {
+		dc = "dc1"
+	}
+	w.Header().Set("X-Consul-Index", f.index.String())
+
+	switch {
+	case r.URL.Path == "/v1/catalog/datacenters":
+		dcs := []string{}
+		for dc := range f.dcs {
+			dcs = append(dcs, dc)
+		}
+		json.NewEncoder(w).Encode(dcs)
+	case r.URL.Path == "/v1/catalog/services":
+		services := map[string][]string{}
+		for name := range f.dcs[dc] {
+			services[name] = []string{}
+		}
+		json.NewEncoder(w).Encode(services)
+	case strings.HasPrefix(r.URL.Path, "/v1/health/service/"):
+		name := strings.TrimPrefix(r.URL.Path, "/v1/health/service/")
+		if f.failing[name] {
+			http.Error(w, "rpc error", http.StatusInternalServerError)
+			return
+		}
+		entries := []serviceEntry{}
+		for _, e := range f.dcs[dc][name] {
+			if r.URL.Query().Get("passing") == "true" && !e.passing() {
+				continue
+			}
+			entries = append(entries, e)
+		}
+		json.NewEncoder(w).Encode(entries)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestConsul(t *testing.T, f *fakeConsul, token string) *Consul {
+	t.Helper()
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+
+	c := &Consul{
+		Next:   test.ErrorHandler(),
+		Zone:   "consul.",
+		ttl:    uint32(defaultTTL),
+		client: newClient([]string{s.URL}, token),
+	}
+	c.OnStartup()
+	t.Cleanup(func() { c.OnShutdown() })
+
+	for range 50 {
+		if c.Ready() {
+			return c
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Consul plugin did not become ready")
+	return nil
+}
+
+func TestConsul(t *testing.T) {
+	f := newFakeConsul("secret")
+	f.set("dc1", "web",
+		entry("node1", "10.0.0.1", "dc1", "", 8080, "passing", "v1"),
+		entry("node2", "10.0.0.2", "dc1", "10.1.0.2", 8081, "passing", "v2"),
+		entry("node3", "10.0.0.3", "dc1", "", 8080, "critical", "v1"),
+	)
+	f.set("dc1", "db", entry("node4", "fd00::4", "dc1", "", 5432, "passing"))
+	f.set("dc1", "down", entry("node5", "10.0.0.5", "dc1", "", 80, "critical"))
+	f.set("dc2", "web", entry("node6", "10.2.0.6", "dc2", "", 8080, "passing"))
+
+	c := newTestConsul(t, f, "secret")
+
+	cases := []test.Case{
+		{
+			Qname: "web.service.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.service.consul.	30	IN	A	10.0.0.1"),
+				test.A("web.service.consul.	30	IN	A	10.1.0.2"),
+			},
+		},
+		{
+			Qname: "v1.web.service.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("v1.web.service.consul.	30	IN	A	10.0.0.1"),
+			},
+		},
+		{
+			Qname: "web.service.consul.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{
+				test.SRV("web.service.consul.	30	IN	SRV	1 1 8080 node1.node.dc1.consul."),
+				test.SRV("web.service.consul.	30	IN	SRV	1 1 8081 node2.node.dc1.consul."),
+			},
+			Extra: []dns.RR{
+				test.A("node1.node.dc1.consul.	30	IN	A	10.0.0.1"),
+				test.A("node2.node.dc1.consul.	30	IN	A	10.1.0.2"),
+			},
+		},
+		{
+			Qname: "db.service.consul.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{
+				test.AAAA("db.service.consul.	30	IN	AAAA	fd00::4"),
+			},
+		},
+		{
+			Qname: "db.service.consul.", Qtype: dns.TypeA,
+			Ns: []dns.RR{
+				test.SOA("consul.	30	IN	SOA	ns.consul. hostmaster.consul. 0 3600 600 86400 30"),
+			},
+		},
+		{
+			// All instances fail their health checks.
+			Qname: "down.service.consul.", Qtype: dns.TypeA,
+			Ns: []dns.RR{
+				test.SOA("consul.	30	IN	SOA	ns.consul. hostmaster.consul. 0 3600 600 86400 30"),
+			},
+		},
+		{
+			Qname: "web.service.dc2.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.service.dc2.consul.	30	IN	A	10.2.0.6"),
+			},
+		},
+		{
+			Qname: "node1.node.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("node1.node.consul.	30	IN	A	10.0.0.1"),
+			},
+		},
+		{
+			Qname: "nonexistent.service.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA("consul.	30	IN	SOA	ns.consul. hostmaster.consul. 0 3600 600 86400 30"),
+			},
+		},
+		{
+			Qname: "web.service.dc3.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA("consul.	30	IN	SOA	ns.consul. hostmaster.consul. 0 3600 600 86400 30"),
+			},
+		},
+		{
+			Qname: "web.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA("consul.	30	IN	SOA	ns.consul. hostmaster.consul. 0 3600 600 86400 30"),
+			},
+		},
+	}
+	test.Run(t, c, cases)
+}
+
+func TestConsulUpdates(t *testing.T) {
+	f := newFakeConsul("")
+	f.set("dc1", "web", entry("node1", "10.0.0.1", "dc1", "", 8080, "passing"))
+	c := newTestConsul(t, f, "")
+
+	f.set("dc1", "web", entry("node1", "10.0.0.1", "dc1", "", 8080, "critical"), entry("node2", "10.0.0.2", "dc1", "", 8080, "passing"))
+	f.set("dc1", "api", entry("node3", "10.0.0.3", "dc1", "", 80, "passing"))
+	test.WaitFor(t, func() bool {
+		web, _, _ := c.dcs[""].lookup("web")
+		_, api, _ := c.dcs[""].lookup("api")
+		return len(web) == 1 && web[0].Node.Node == "node2" && api
+	})
+
+	f.set("dc1", "api")
+	test.WaitFor(t, func() bool {
+		_, api, _ := c.dcs[""].lookup("api")
+		return !api
+	})
+}
+
+func TestConsulNotFetched(t *testing.T) {
+	f := newFakeConsul("")
+	f.set("dc1", "web", entry("node1", "10.0.0.1", "dc1", "", 8080, "passing"))
+	f.set("dc1", "api", entry("node2", "10.0.0.2", "dc1", "", 80, "passing"))
+	f.failing["api"] = true
+	c := newTestConsul(t, f, "")
+
+	// api is in the catalog, but its instances couldn't be fetched: that's a server failure, not NXDOMAIN.
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	rcode, err := c.ServeDNS(context.Background(), w, new(dns.Msg).SetQuestion("api.service.consul.", dns.TypeA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeServerFailure, rcode)
+	}
+	if w.Msg != nil {
+		t.Errorf("Expected no response to be written, got %v", w.Msg)
+	}
+
+	cases := []test.Case{
+		{
+			Qname: "web.service.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("web.service.consul.	30	IN	A	10.0.0.1")},
+		},
+	}
+	test.Run(t, c, cases)
+}
+
+func TestConsulToken(t *testing.T) {
+	f := newFakeConsul("secret")
+	s := httptest.NewServer(f)
+	defer s.Close()
+
+	cl := newClient([]string{s.URL}, "wrong")
+	if _, _, err := cl.services(context.Background(), "", 0); err == nil {
+		t.Error("Expected an error with the wrong token")
+	}
+	cl = newClient([]string{"http://127.0.0.1:1", s.URL}, "secret")
+	if _, _, err := cl.services(context.Background(), "", 0); err != nil {
+		t.Errorf("Expected no error after failing over to the second address, got %v", err)
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected query
+		ok       bool
+	}{
+		{"web.service.consul.", query{service: "web"}, true},
+		{"v1.web.service.consul.", query{service: "web", tag: "v1"}, true},
+		{"web.service.dc2.consul.", query{service: "web", datacenter: "dc2"}, true},
+		{"v1.web.service.dc2.consul.", query{service: "web", tag: "v1", datacenter: "dc2"}, true},
+		{"node1.node.consul.", query{node: "node1"}, true},
+		{"node1.node.dc2.consul.", query{node: "node1", datacenter: "dc2"}, true},
+		{"service.consul.", query{}, false},
+		{"a.b.c.service.consul.", query{}, false},
+		{"web.consul.", query{}, false},
+	}
+	for _, tc := range tests {
+		q, ok := parseName(tc.name, "consul.")
+		if ok != tc.ok || (ok && q != tc.expected) {
+			t.Errorf("%s: expected %+v (%t), got %+v (%t)", tc.name, tc.expected, tc.ok, q, ok)
+		}
+	}
+}
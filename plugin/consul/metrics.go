@@ -0,0 +1,39 @@
+package consul
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// requestSuccessCount is the number of DNS requests handled successfully.
+	requestSuccessCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "success_requests_total",
+		Help:      "Counter of DNS requests handled successfully.",
+	}, []string{"server", "datacenter"})
+	// requestFailedCount is the number of DNS requests that failed.
+	requestFailedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "failed_requests_total",
+		Help:      "Counter of DNS requests failed.",
+	}, []string{"server", "datacenter"})
+	// cacheServices is the number of services watched.
+	cacheServices = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "services",
+		Help:      "The number of services watched.",
+	}, []string{"datacenter"})
+	// watchErrors is the number of failed blocking queries.
+	watchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "watch_errors_total",
+		Help:      "Counter of blocking queries to Consul that failed.",
+	}, []string{"datacenter"})
+)
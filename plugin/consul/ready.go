@@ -0,0 +1,11 @@
+package consul
+
+// Ready signals when the plugin is ready for use.
+// In case of Consul, when the services of the default datacenter
+// have been fetched the plugin is ready.
+func (c *Consul) Ready() bool {
+	c.mu.Lock()
+	d := c.dcs[c.datacenter]
+	c.mu.Unlock()
+	return d != nil && d.isSynced()
+}
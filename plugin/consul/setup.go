@@ -0,0 +1,114 @@
+package consul
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+const defaultAddress = "http://127.0.0.1:8500"
+
+// init registers this plugin.
+func init() { plugin.Register(pluginName, setup) }
+
+// setup is the function that gets called when the config parser sees the token "consul". Setup is responsible
+// for parsing any extra options the consul plugin may have. The first token this function sees is "consul".
+func setup(c *caddy.Controller) error {
+	cs := &Consul{ttl: uint32(defaultTTL)}
+
+	if err := parse(c, cs); err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	c.OnStartup(cs.OnStartup)
+	c.OnShutdown(cs.OnShutdown)
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		cs.Next = next
+		return cs
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller, cs *Consul) error {
+	// The environment variables of the Consul CLI are used as defaults.
+	addresses := []string{}
+	token := os.Getenv("CONSUL_HTTP_TOKEN")
+
+	// Expect the first token to be "consul"
+	if !c.Next() {
+		return c.Err("expected 'consul' token")
+	}
+
+	args := c.RemainingArgs()
+	switch len(args) {
+	case 0:
+		cs.Zone = "consul."
+	case 1:
+		cs.Zone = dns.Fqdn(strings.ToLower(args[0]))
+	default:
+		return c.ArgErr()
+	}
+
+	for c.NextBlock() {
+		selector := strings.ToLower(c.Val())
+
+		switch selector {
+		case "address":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return c.Err("at least one address is required")
+			}
+			addresses = append(addresses, args...)
+		case "token":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.Err("exactly one token is required")
+			}
+			token = args[0]
+		case "datacenter":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.Err("exactly one datacenter is required")
+			}
+			cs.datacenter = strings.ToLower(args[0])
+		case "ttl":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.Err("exactly one ttl value is required")
+			}
+			t, err := strconv.Atoi(args[0])
+			if err != nil {
+				return c.Err("error parsing ttl: " + err.Error())
+			}
+			if t < 0 || t > 3600 {
+				return c.Errf("ttl must be in range [0, 3600]: %d", t)
+			}
+			cs.ttl = uint32(t)
+		default:
+			return c.Errf("unknown property '%s'", selector)
+		}
+	}
+
+	if len(addresses) == 0 {
+		addresses = append(addresses, defaultAddress)
+		if addr := os.Getenv("CONSUL_HTTP_ADDR"); addr != "" {
+			addresses[0] = addr
+		}
+	}
+	for i, addr := range addresses {
+		if !strings.Contains(addr, "://") {
+			addresses[i] = "http://" + addr
+		}
+	}
+
+	cs.client = newClient(addresses, token)
+	return nil
+}
@@ -0,0 +1,130 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetupConsul(t *testing.T) {
+	tests := []struct {
+		name              string
+		config            string
+		shouldErr         bool
+		expectedZone      string
+		expectedTTL       uint32
+		expectedDC        string
+		expectedToken     string
+		expectedAddresses []string
+	}{
+		{
+			name:              "defaults",
+			config:            `consul`,
+			expectedZone:      "consul.",
+			expectedTTL:       uint32(defaultTTL),
+			expectedAddresses: []string{defaultAddress},
+		},
+		{
+			name: "full",
+			config: `
+consul example.org {
+    address 127.0.0.1:8500 https://127.0.0.2:8501
+    token test-token
+    datacenter DC2
+    ttl 60
+}`,
+			expectedZone:      "example.org.",
+			expectedTTL:       60,
+			expectedDC:        "dc2",
+			expectedToken:     "test-token",
+			expectedAddresses: []string{"http://127.0.0.1:8500", "https://127.0.0.2:8501"},
+		},
+		{
+			name: "invalid_ttl",
+			config: `
+consul {
+    ttl 3601
+}`,
+			shouldErr: true,
+		},
+		{
+			name: "missing_token",
+			config: `
+consul {
+    token
+}`,
+			shouldErr: true,
+		},
+		{
+			name:      "too_many_zones",
+			config:    `consul a.org b.org`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid_property",
+			config: `
+consul {
+    invalid_property
+}`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONSUL_HTTP_ADDR", "")
+			t.Setenv("CONSUL_HTTP_TOKEN", "")
+			c := caddy.NewTestController("dns", tt.config)
+			cs := &Consul{ttl: uint32(defaultTTL)}
+
+			err := parse(c, cs)
+			if tt.shouldErr && err == nil {
+				t.Fatalf("Test %s: expected error but got none", tt.name)
+			}
+			if !tt.shouldErr && err != nil {
+				t.Fatalf("Test %s: expected no error but got: %v", tt.name, err)
+			}
+			if tt.shouldErr {
+				return
+			}
+
+			if cs.Zone != tt.expectedZone {
+				t.Errorf("Test %s: expected zone %s, got %s", tt.name, tt.expectedZone, cs.Zone)
+			}
+			if cs.ttl != tt.expectedTTL {
+				t.Errorf("Test %s: expected TTL %d, got %d", tt.name, tt.expectedTTL, cs.ttl)
+			}
+			if cs.datacenter != tt.expectedDC {
+				t.Errorf("Test %s: expected datacenter %q, got %q", tt.name, tt.expectedDC, cs.datacenter)
+			}
+			if cs.client.token != tt.expectedToken {
+				t.Errorf("Test %s: expected token %q, got %q", tt.name, tt.expectedToken, cs.client.token)
+			}
+			if len(cs.client.addresses) != len(tt.expectedAddresses) {
+				t.Fatalf("Test %s: expected addresses %v, got %v", tt.name, tt.expectedAddresses, cs.client.addresses)
+			}
+			for i := range cs.client.addresses {
+				if cs.client.addresses[i] != tt.expectedAddresses[i] {
+					t.Errorf("Test %s: expected addresses %v, got %v", tt.name, tt.expectedAddresses, cs.client.addresses)
+				}
+			}
+		})
+	}
+}
+
+func TestSetupConsulEnvironment(t *testing.T) {
+	t.Setenv("CONSUL_HTTP_ADDR", "10.0.0.1:8500")
+	t.Setenv("CONSUL_HTTP_TOKEN", "env-token")
+
+	c := caddy.NewTestController("dns", `consul`)
+	cs := &Consul{}
+	if err := parse(c, cs); err != nil {
+		t.Fatal(err)
+	}
+	if cs.client.addresses[0] != "http://10.0.0.1:8500" {
+		t.Errorf("Expected the address from the environment, got %v", cs.client.addresses)
+	}
+	if cs.client.token != "env-token" {
+		t.Errorf("Expected the token from the environment, got %q", cs.client.token)
+	}
+}
@@ -0,0 +1,99 @@
+// Package cookie implements a plugin that adds DNS Cookies (RFC 7873) to responses and validates
+// the server cookies sent by clients.
+package cookie
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	pkgcookie "github.com/coredns/coredns/plugin/pkg/cookie"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Cookie is the cookie plugin.
+type Cookie struct {
+	Next plugin.Handler
+
+	server  *pkgcookie.Server
+	require bool
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (c *Cookie) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	o := pkgcookie.Find(r)
+	if o == nil {
+		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
+	}
+
+	state := request.Request{W: w, Req: r}
+	server := metrics.WithServer(ctx)
+
+	client, cookie, err := pkgcookie.Parse(o)
+	if err != nil {
+		RequestCount.WithLabelValues(server, "malformed").Inc()
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeFormatError)
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
+
+	ip := clientIP(state)
+	valid := cookie != nil && c.server.Valid(client, cookie, ip)
+	switch {
+	case valid:
+		RequestCount.WithLabelValues(server, "valid").Inc()
+		ctx = pkgcookie.WithValid(ctx)
+	case cookie != nil:
+		RequestCount.WithLabelValues(server, "invalid").Inc()
+	default:
+		RequestCount.WithLabelValues(server, "client").Inc()
+	}
+
+	fresh := pkgcookie.Option(client, c.server.Generate(client, ip))
+
+	// TCP proves the client owns its address, so only UDP needs a valid server cookie.
+	if !valid && c.require && state.Proto() == "udp" {
+		BadCookieCount.WithLabelValues(server).Inc()
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeBadCookie)
+		m.SetEdns0(uint16(state.Size()), state.Do())
+		pkgcookie.Set(m, fresh)
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
+
+	cw := &ResponseWriter{ResponseWriter: w, state: state, cookie: fresh}
+	return plugin.NextOrFailure(c.Name(), c.Next, ctx, cw, r)
+}
+
+// Name implements the plugin.Handler interface.
+func (c *Cookie) Name() string { return "cookie" }
+
+// ResponseWriter adds the server cookie to the response.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	state  request.Request
+	cookie *dns.EDNS0_COOKIE
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	if res.IsEdns0() == nil {
+		res.SetEdns0(uint16(w.state.Size()), w.state.Do())
+	}
+	pkgcookie.Set(res, w.cookie)
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+func clientIP(state request.Request) net.IP {
+	addr := state.IP()
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	return net.ParseIP(addr)
+}
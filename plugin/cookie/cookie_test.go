@@ -0,0 +1,97 @@
+package cookie
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/cookie"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+var client = []byte("01234567")
+
+// answer replies with a fixed answer and records if the request carried a valid server cookie.
+func answer(valid *bool) test.HandlerFunc {
+	return func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		*valid = cookie.Valid(ctx)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A("example.org. 3600 IN A 127.0.0.1")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
+}
+
+func TestCookie(t *testing.T) {
+	srv := cookie.NewServer(cookie.NewSecret())
+	valid := srv.Generate(client, []byte{10, 240, 0, 1})
+
+	tests := []struct {
+		name      string
+		option    *dns.EDNS0_COOKIE // nil sends no cookie
+		require   bool
+		tcp       bool
+		rcode     int
+		valid     bool // server cookie seen as valid by the next plugin
+		rewritten bool // response carries a fresh cookie
+	}{
+		{name: "No cookie", rcode: dns.RcodeSuccess},
+		{name: "Client cookie", option: cookie.Option(client, nil), rcode: dns.RcodeSuccess, rewritten: true},
+		{name: "Valid server cookie", option: cookie.Option(client, valid), rcode: dns.RcodeSuccess, valid: true, rewritten: true},
+		{name: "Invalid server cookie", option: cookie.Option(client, make([]byte, 16)), rcode: dns.RcodeSuccess, rewritten: true},
+		{name: "Malformed", option: &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "0102"}, rcode: dns.RcodeFormatError},
+		{name: "Require client cookie", option: cookie.Option(client, nil), require: true, rcode: dns.RcodeBadCookie, rewritten: true},
+		{name: "Require valid server cookie", option: cookie.Option(client, valid), require: true, rcode: dns.RcodeSuccess, valid: true, rewritten: true},
+		{name: "Require over TCP", option: cookie.Option(client, nil), require: true, tcp: true, rcode: dns.RcodeSuccess, rewritten: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var seen bool
+			c := &Cookie{Next: answer(&seen), server: srv, require: tc.require}
+
+			m := new(dns.Msg)
+			m.SetQuestion("example.org.", dns.TypeA)
+			if tc.option != nil {
+				m.SetEdns0(4096, false)
+				cookie.Set(m, tc.option)
+			}
+
+			rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tc.tcp})
+			c.ServeDNS(context.TODO(), rec, m)
+			if rec.Msg == nil {
+				t.Fatal("Expected a response")
+			}
+			if rec.Msg.Rcode != tc.rcode {
+				t.Errorf("Expected rcode %s, got %s", dns.RcodeToString[tc.rcode], dns.RcodeToString[rec.Msg.Rcode])
+			}
+			if seen != tc.valid {
+				t.Errorf("Expected valid cookie %t, got %t", tc.valid, seen)
+			}
+
+			o := cookie.Find(rec.Msg)
+			if !tc.rewritten {
+				if o != nil {
+					t.Errorf("Expected no cookie in the response, got %s", o.Cookie)
+				}
+				return
+			}
+			if o == nil {
+				t.Fatal("Expected a cookie in the response")
+			}
+			cl, server, err := cookie.Parse(o)
+			if err != nil {
+				t.Fatalf("Expected a well formed cookie, got %s", err)
+			}
+			if string(cl) != string(client) {
+				t.Errorf("Expected the client cookie to be echoed, got %x", cl)
+			}
+			if !srv.Valid(cl, server, []byte{10, 240, 0, 1}) {
+				t.Errorf("Expected a valid server cookie, got %x", server)
+			}
+		})
+	}
+}
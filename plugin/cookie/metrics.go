@@ -0,0 +1,25 @@
+package cookie
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// RequestCount is the number of requests with a COOKIE option, by the result of the validation.
+	RequestCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "cookie",
+		Name:      "requests_total",
+		Help:      "Counter of requests with a COOKIE option, by the result of validating the server cookie.",
+	}, []string{"server", "result"})
+	// BadCookieCount is the number of BADCOOKIE responses sent.
+	BadCookieCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "cookie",
+		Name:      "badcookie_responses_total",
+		Help:      "Counter of BADCOOKIE responses sent.",
+	}, []string{"server"})
+)
@@ -0,0 +1,119 @@
+package cookie
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	pkgcookie "github.com/coredns/coredns/plugin/pkg/cookie"
+)
+
+func init() { plugin.Register("cookie", setup) }
+
+func setup(c *caddy.Controller) error {
+	ck, rotate, err := cookieParse(c)
+	if err != nil {
+		return plugin.Error("cookie", err)
+	}
+
+	if rotate > 0 {
+		stop := make(chan struct{})
+		c.OnStartup(func() error {
+			go func() {
+				ticker := time.NewTicker(rotate)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-stop:
+						return
+					case <-ticker.C:
+						ck.server.Rotate(pkgcookie.NewSecret())
+					}
+				}
+			}()
+			return nil
+		})
+		c.OnShutdown(func() error {
+			close(stop)
+			return nil
+		})
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		ck.Next = next
+		return ck
+	})
+
+	return nil
+}
+
+func cookieParse(c *caddy.Controller) (*Cookie, time.Duration, error) {
+	ck := &Cookie{}
+	var (
+		secrets [][16]byte
+		rotate  time.Duration
+	)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, 0, plugin.ErrOnce
+		}
+		i++
+
+		if len(c.RemainingArgs()) != 0 {
+			return nil, 0, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "secret":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, 0, c.ArgErr()
+				}
+				for _, a := range args {
+					b, err := hex.DecodeString(a)
+					if err != nil || len(b) != 16 {
+						return nil, 0, c.Errf("secret must be 16 bytes in hex, got %q", a)
+					}
+					var s [16]byte
+					copy(s[:], b)
+					secrets = append(secrets, s)
+				}
+			case "rotate":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, 0, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, 0, c.Errf("invalid duration for rotate '%s'", args[0])
+				}
+				if d <= 0 {
+					return nil, 0, c.Errf("rotate must be positive, got '%s'", args[0])
+				}
+				rotate = d
+			case "require":
+				if c.NextArg() {
+					return nil, 0, c.ArgErr()
+				}
+				ck.require = true
+			default:
+				return nil, 0, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(secrets) > 0 && rotate > 0 {
+		return nil, 0, c.Err("rotate can't be used together with secret")
+	}
+	if len(secrets) == 0 {
+		secrets = append(secrets, pkgcookie.NewSecret())
+	}
+	ck.server = pkgcookie.NewServer(secrets...)
+
+	return ck, rotate, nil
+}
@@ -0,0 +1,76 @@
+package cookie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rotate    time.Duration
+		require   bool
+	}{
+		{`cookie`, false, 0, false},
+		{`cookie {
+			secret e5e973e5a6b2a43f48e7dc849e37bfcf dd3bdf9344b678b185a6f5cb60fca715
+			require
+		}`, false, 0, true},
+		{`cookie {
+			rotate 1h
+		}`, false, time.Hour, false},
+		// errors
+		{`cookie example.org`, true, 0, false},
+		{`cookie {
+			secret 0102
+		}`, true, 0, false},
+		{`cookie {
+			secret zze973e5a6b2a43f48e7dc849e37bfcf
+		}`, true, 0, false},
+		{`cookie {
+			secret
+		}`, true, 0, false},
+		{`cookie {
+			rotate -1h
+		}`, true, 0, false},
+		{`cookie {
+			rotate 1h
+			secret e5e973e5a6b2a43f48e7dc849e37bfcf
+		}`, true, 0, false},
+		{`cookie {
+			require yes
+		}`, true, 0, false},
+		{`cookie {
+			unknown
+		}`, true, 0, false},
+		{`cookie
+		cookie`, true, 0, false},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		ck, rotate, err := cookieParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if ck.server == nil {
+			t.Errorf("Test %d: expected a server", i)
+		}
+		if rotate != tc.rotate {
+			t.Errorf("Test %d: expected rotate %s, got %s", i, tc.rotate, rotate)
+		}
+		if ck.require != tc.require {
+			t.Errorf("Test %d: expected require %t, got %t", i, tc.require, ck.require)
+		}
+	}
+}
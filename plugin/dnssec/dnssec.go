@@ -42,7 +42,15 @@ func New(zones []string, keys []*DNSKEY, splitkeys bool, next plugin.Handler, c
 // Signatures will be cached for a short while. By default we sign for 8 days,
 // starting 3 hours ago.
 func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.Msg {
+	m, _ := d.signResponse(state, now, server)
+	return m
+}
+
+// signResponse signs the response like Sign does, it also returns false if one or more RRsets could
+// not be signed.
+func (d Dnssec) signResponse(state request.Request, now time.Time, server string) (*dns.Msg, bool) {
 	req := state.Req
+	ok := true
 
 	incep, expir := incepExpir(now)
 
@@ -60,25 +68,33 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 		if len(ds) == 0 {
 			if sigs, err := d.nsec(state, mt, ttl, incep, expir, server); err == nil {
 				req.Ns = append(req.Ns, sigs...)
+			} else {
+				ok = false
 			}
 		} else if sigs, err := d.sign(ds, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			ok = false
 		}
-		return req
+		return req, ok
 	}
 
 	if mt == response.NameError || mt == response.NoData {
 		if req.Ns[0].Header().Rrtype != dns.TypeSOA || len(req.Ns) > 1 {
-			return req
+			return req, ok
 		}
 
 		ttl := req.Ns[0].Header().Ttl
 
 		if sigs, err := d.sign(req.Ns, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			ok = false
 		}
 		if sigs, err := d.nsec(state, mt, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			ok = false
 		}
 		if len(req.Ns) > 1 { // actually added nsec and sigs, reset the rcode
 			req.Rcode = dns.RcodeSuccess
@@ -87,28 +103,34 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 				req.Ns = nil
 			}
 		}
-		return req
+		return req, ok
 	}
 
 	for _, r := range rrSets(req.Answer) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Answer = append(req.Answer, sigs...)
+		} else {
+			ok = false
 		}
 	}
 	for _, r := range rrSets(req.Ns) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			ok = false
 		}
 	}
 	for _, r := range rrSets(req.Extra) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Extra = append(req.Extra, sigs...)
+		} else {
+			ok = false
 		}
 	}
-	return req
+	return req, ok
 }
 
 func (d Dnssec) sign(rrs []dns.RR, signerName string, ttl, incep, expir uint32, server string) ([]dns.RR, error) {
@@ -8,6 +8,7 @@ import (
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/pkg/cache"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -251,3 +252,33 @@ deleg			IN		NS		ns01.deleg
 				IN		DS		18512 13 2 D4E806322598BC97A003EF1ACDFF352EEFF7B42DBB0D41B8224714C36AEF08D9
 unsigned		IN		NS		ns01.deleg
 `
+
+func TestExtendedErrorExpired(t *testing.T) {
+	dnskey, rm1, rm2 := newKey(t)
+	defer rm1()
+	defer rm2()
+	c := cache.New(defaultCap)
+	// A backend serving pre-signed data with a long expired signature.
+	next := test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{
+			test.A("www.miek.nl.	1800	IN	A	127.0.0.1"),
+			test.RRSIG("www.miek.nl.	1800	IN	RRSIG	A 13 3 1800 20160503150844 20160425120844 18512 miek.nl. Iw/kNOyM"),
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+	dh := New([]string{"miek.nl."}, []*DNSKEY{dnskey}, false, next, c)
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.miek.nl.", dns.TypeA)
+	m.SetEdns0(4096, true)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := dh.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ede := edns.ExtendedError(rec.Msg); ede == nil || ede.InfoCode != dns.ExtendedErrorCodeSignatureExpired {
+		t.Errorf("Expected Extended DNS Error %d, got %v", dns.ExtendedErrorCodeSignatureExpired, ede)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -13,7 +14,8 @@ import (
 type ResponseWriter struct {
 	dns.ResponseWriter
 	d      Dnssec
-	server string // server label for metrics.
+	server string   // server label for metrics.
+	req    *dns.Msg // the client's request, for adding Extended DNS Errors.
 }
 
 // WriteMsg implements the dns.ResponseWriter interface.
@@ -28,13 +30,40 @@ func (d *ResponseWriter) WriteMsg(res *dns.Msg) error {
 	}
 	state.Zone = zone
 
-	res = d.d.Sign(state, time.Now().UTC(), d.server)
+	now := time.Now().UTC()
+	code, invalid := validity(res, now)
+	res, ok := d.d.signResponse(state, now, d.server)
 	cacheSize.WithLabelValues(d.server, "signature").Set(float64(d.d.cache.Len()))
 	// No need for EDNS0 trickery, as that is handled by the server.
 
+	switch {
+	case !ok:
+		edns.SetExtendedError(d.req, res, dns.ExtendedErrorCodeRRSIGsMissing, "Signing failed")
+	case invalid:
+		edns.SetExtendedError(d.req, res, code, "")
+	}
+
 	return d.ResponseWriter.WriteMsg(res)
 }
 
+// validity checks the RRSIGs already present in the answer of m, these come from pre-signed data.
+// It returns the Extended DNS Error for the first one that is expired or not yet valid, and false
+// if all of them are valid.
+func validity(m *dns.Msg, now time.Time) (uint16, bool) {
+	for _, rr := range m.Answer {
+		sig, ok := rr.(*dns.RRSIG)
+		if !ok || sig.ValidityPeriod(now) {
+			continue
+		}
+		// Serial number arithmetic, see RFC 4034 Section 3.1.5.
+		if int32(sig.Inception-uint32(now.Unix())) > 0 {
+			return dns.ExtendedErrorCodeSignatureNotYetValid, true
+		}
+		return dns.ExtendedErrorCodeSignatureExpired, true
+	}
+	return 0, false
+}
+
 // Write implements the dns.ResponseWriter interface.
 func (d *ResponseWriter) Write(buf []byte) (int, error) {
 	log.Warning("Dnssec called with Write: not signing reply")
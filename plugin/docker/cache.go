@@ -0,0 +1,284 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/retry"
+
+	"github.com/miekg/dns"
+)
+
+// retryBackoff is how long the cache waits before it reconnects to the Engine API.
+var retryBackoff = 2 * time.Second
+
+// Labels set by Docker Compose on the containers of a project.
+const (
+	composeProject = "com.docker.compose.project"
+	composeService = "com.docker.compose.service"
+)
+
+// entry is the address of a container on a network, under one of its names.
+type entry struct {
+	target string // the canonical name of the container on the network, relative to the zone
+	ip4    net.IP
+	ip6    net.IP
+	ports  []port
+}
+
+// port is a port exposed by a container.
+type port struct {
+	number uint16
+	proto  string
+}
+
+// cache holds the running containers and the names they are known by. It is filled with the list of
+// containers and kept up to date with the events stream of the Engine API. When the Engine API can't be
+// reached the last known containers are served.
+type cache struct {
+	client *client
+
+	mu         sync.RWMutex
+	containers map[string]*container // container ID -> container
+	names      map[string][]entry    // name relative to the zone -> entries
+	ptrs       map[string]string     // reverse name of an address -> canonical name relative to the zone
+
+	synced chan struct{} // closed once the containers are fetched for the first time
+}
+
+func newCache(c *client) *cache {
+	return &cache{
+		client:     c,
+		containers: map[string]*container{},
+		names:      map[string][]entry{},
+		ptrs:       map[string]string{},
+		synced:     make(chan struct{}),
+	}
+}
+
+// isSynced returns true once the running containers have been listed from the Engine API.
+func (c *cache) isSynced() bool {
+	return retry.Closed(c.synced)
+}
+
+// lookup returns the entries of name, which is relative to the zone.
+func (c *cache) lookup(name string) ([]entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.names[name]
+	return e, ok
+}
+
+// ptr returns the canonical name, relative to the zone, of the container that has the address of the reverse
+// name.
+func (c *cache) ptr(name string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	target, ok := c.ptrs[name]
+	return target, ok
+}
+
+// run lists the containers and follows the Engine API events until ctx is done, reconnecting when the
+// event stream breaks.
+func (c *cache) run(ctx context.Context) {
+	synced := sync.OnceFunc(func() { close(c.synced) })
+	for {
+		// Subscribe to the events before the containers are listed, so no change is missed.
+		events, err := c.client.events(ctx)
+		if err == nil {
+			err = c.sync(ctx)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warningf("Failed to fetch the containers: %s", err)
+			streamErrors.Inc()
+			if !retry.Sleep(ctx, retryBackoff) {
+				return
+			}
+			continue
+		}
+		synced()
+
+		for e := range events {
+			c.handle(ctx, e)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warning("The events stream ended, reconnecting")
+		streamErrors.Inc()
+		if !retry.Sleep(ctx, retryBackoff) {
+			return
+		}
+	}
+}
+
+// sync fetches all running containers.
+func (c *cache) sync(ctx context.Context) error {
+	ids, err := c.client.containers(ctx)
+	if err != nil {
+		return err
+	}
+	containers := make(map[string]*container, len(ids))
+	for _, id := range ids {
+		ct, err := c.client.inspect(ctx, id)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if ct.State.Running {
+			containers[ct.ID] = ct
+		}
+	}
+
+	c.mu.Lock()
+	c.containers = containers
+	c.rebuild()
+	c.mu.Unlock()
+	return nil
+}
+
+// handle updates the container an event is about.
+func (c *cache) handle(ctx context.Context, e event) {
+	id := e.Actor.ID
+	switch e.Type {
+	case "container":
+		switch e.Action {
+		case "start", "unpause", "rename", "die", "stop", "kill", "pause", "destroy":
+		default:
+			return
+		}
+	case "network":
+		if e.Action != "connect" && e.Action != "disconnect" {
+			return
+		}
+		id = e.Actor.Attributes["container"]
+	default:
+		return
+	}
+	if id == "" {
+		return
+	}
+
+	ct, err := c.client.inspect(ctx, id)
+	if err != nil && !isNotFound(err) {
+		// Keep what is known, the events stream is probably gone too and the containers are synced again.
+		log.Warningf("Failed to inspect container %s: %s", id, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil || !ct.State.Running {
+		delete(c.containers, id)
+	} else {
+		c.containers[ct.ID] = ct
+	}
+	c.rebuild()
+}
+
+// rebuild builds the names from the containers. The lock must be held.
+func (c *cache) rebuild() {
+	names := map[string][]entry{}
+	ptrs := map[string]string{}
+	for _, ct := range c.containers {
+		name := containerName(ct)
+		if name == "" {
+			continue
+		}
+		ports := exposedPorts(ct)
+		for network, ep := range ct.NetworkSettings.Networks {
+			if ep == nil {
+				continue
+			}
+			network = label(network)
+			e := entry{
+				target: name + "." + network,
+				ip4:    net.ParseIP(ep.IPAddress).To4(),
+				ip6:    net.ParseIP(ep.GlobalIPv6Address),
+				ports:  ports,
+			}
+			if e.ip4 == nil && e.ip6 == nil {
+				continue
+			}
+
+			aliases := []string{name}
+			aliases = append(aliases, ep.Aliases...)
+			aliases = append(aliases, ep.DNSNames...)
+			seen := map[string]struct{}{}
+			for _, a := range aliases {
+				a = strings.ToLower(strings.Trim(a, "."))
+				if _, ok := seen[a]; ok || a == "" {
+					continue
+				}
+				seen[a] = struct{}{}
+				names[a+"."+network] = append(names[a+"."+network], e)
+			}
+			if project, service := ct.Config.Labels[composeProject], ct.Config.Labels[composeService]; project != "" && service != "" {
+				key := label(service) + "." + label(project)
+				names[key] = append(names[key], e)
+			}
+
+			for _, ip := range []net.IP{e.ip4, e.ip6} {
+				if ip == nil {
+					continue
+				}
+				rev, err := dns.ReverseAddr(ip.String())
+				if err != nil {
+					continue
+				}
+				// Addresses are unique per network, but be deterministic if they are not.
+				if old, ok := ptrs[rev]; !ok || e.target < old {
+					ptrs[rev] = e.target
+				}
+			}
+		}
+	}
+	for _, entries := range names {
+		slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.target, b.target) })
+	}
+	c.names, c.ptrs = names, ptrs
+	cacheContainers.Set(float64(len(c.containers)))
+}
+
+// containerName returns the name of ct as a DNS label.
+func containerName(ct *container) string {
+	return label(strings.TrimPrefix(ct.Name, "/"))
+}
+
+// label returns s as a DNS label. Dots can't be part of a label, they are replaced with dashes.
+func label(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), ".", "-")
+}
+
+// exposedPorts returns the ports ct exposes, sorted.
+func exposedPorts(ct *container) []port {
+	var ports []port
+	for p := range ct.Config.ExposedPorts {
+		number, proto, _ := strings.Cut(p, "/")
+		n, err := strconv.ParseUint(number, 10, 16)
+		if err != nil {
+			continue
+		}
+		if proto == "" {
+			proto = "tcp"
+		}
+		ports = append(ports, port{number: uint16(n), proto: proto})
+	}
+	slices.SortFunc(ports, func(a, b port) int {
+		if a.number != b.number {
+			return int(a.number) - int(b.number)
+		}
+		return strings.Compare(a.proto, b.proto)
+	})
+	return ports
+}
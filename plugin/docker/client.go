@@ -0,0 +1,168 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// container is the part of the inspect response of the Engine API that is used.
+type container struct {
+	ID    string
+	Name  string
+	State struct {
+		Running bool
+	}
+	Config struct {
+		Hostname     string
+		Labels       map[string]string
+		ExposedPorts map[string]struct{}
+	}
+	NetworkSettings struct {
+		Networks map[string]*endpoint
+	}
+}
+
+// endpoint is the connection of a container to a network.
+type endpoint struct {
+	IPAddress         string
+	GlobalIPv6Address string
+	Aliases           []string
+	DNSNames          []string
+}
+
+// event is a message from the events stream of the Engine API.
+type event struct {
+	Type   string
+	Action string
+	Actor  struct {
+		ID         string
+		Attributes map[string]string
+	}
+}
+
+// client talks to the Engine API of Docker or Podman.
+type client struct {
+	endpoint string
+	base     string // the URL the paths are appended to
+	http     *http.Client
+}
+
+// newClient returns a client for endpoint, which is a unix:// or tcp:// (or http://) URL.
+func newClient(endpoint string) (*client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		tr := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		// The host is not used, but needs to be valid.
+		return &client{endpoint: endpoint, base: "http://docker", http: &http.Client{Transport: tr}}, nil
+	case "tcp", "http":
+		return &client{endpoint: endpoint, base: "http://" + u.Host, http: &http.Client{}}, nil
+	}
+	return nil, fmt.Errorf("unsupported endpoint %q, want unix:// or tcp://", endpoint)
+}
+
+func (c *client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &statusError{path: path, code: resp.StatusCode, status: resp.Status}
+	}
+	return resp, nil
+}
+
+func (c *client) decode(ctx context.Context, path string, out any) error {
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// containers returns the IDs of the running containers.
+func (c *client) containers(ctx context.Context) ([]string, error) {
+	var list []struct{ ID string }
+	if err := c.decode(ctx, "/containers/json", &list); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(list))
+	for i := range list {
+		ids[i] = list[i].ID
+	}
+	return ids, nil
+}
+
+// inspect returns the details of the container with id.
+func (c *client) inspect(ctx context.Context, id string) (*container, error) {
+	ct := new(container)
+	if err := c.decode(ctx, "/containers/"+url.PathEscape(id)+"/json", ct); err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+// statusError is returned when the Engine API responds with an unexpected status.
+type statusError struct {
+	path   string
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %q for %s", e.status, e.path)
+}
+
+// isNotFound returns true if err is returned because the container is gone.
+func isNotFound(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.code == http.StatusNotFound
+}
+
+// events returns the stream of container and network events. The stream ends when ctx is done or the
+// connection is lost, the returned channel is closed then.
+func (c *client) events(ctx context.Context) (<-chan event, error) {
+	filters := url.QueryEscape(`{"type":["container","network"]}`)
+	resp, err := c.get(ctx, "/events?filters="+filters)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan event)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var e event
+			if err := dec.Decode(&e); err != nil {
+				return
+			}
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
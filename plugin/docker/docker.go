@@ -0,0 +1,180 @@
+// Package docker implements a plugin that resolves the names of Docker and Podman containers.
+package docker
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const pluginName = "docker"
+
+var (
+	log        = clog.NewWithPlugin(pluginName)
+	defaultTTL = 30
+)
+
+// Docker is a plugin that serves the addresses of running containers, read from the Engine API of Docker
+// or Podman.
+type Docker struct {
+	Next plugin.Handler
+
+	Zone   string
+	ttl    uint32
+	client *client
+	cache  *cache
+
+	cancel context.CancelFunc
+}
+
+// Name implements the plugin.Handler interface.
+func (d *Docker) Name() string { return pluginName }
+
+// ServeDNS implements the plugin.Handler interface.
+func (d *Docker) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if plugin.Zones([]string{d.Zone}).Matches(state.Name()) == "" {
+		// Reverse lookups of addresses of containers are answered, all others are passed on.
+		if state.QType() != dns.TypePTR || !d.cache.isSynced() {
+			return plugin.NextOrFailure(d.Name(), d.Next, ctx, w, r)
+		}
+		target, ok := d.cache.ptr(state.Name())
+		if !ok {
+			return plugin.NextOrFailure(d.Name(), d.Next, ctx, w, r)
+		}
+		m.Answer = []dns.RR{&dns.PTR{
+			Hdr: dns.RR_Header{Name: state.QName(), Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: d.ttl},
+			Ptr: d.fqdn(target),
+		}}
+		requestSuccessCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	}
+
+	if state.Name() == d.Zone {
+		if state.QType() == dns.TypeSOA {
+			m.Answer = []dns.RR{d.soa()}
+		} else {
+			m.Ns = []dns.RR{d.soa()}
+		}
+		requestSuccessCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	}
+
+	if !d.cache.isSynced() {
+		// The server writes the response for a server failure.
+		requestFailedCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+		return dns.RcodeServerFailure, nil
+	}
+
+	name := strings.TrimSuffix(strings.TrimSuffix(state.Name(), d.Zone), ".")
+	// SRV records can be asked for with the protocol in front of the name: _tcp.<name>.
+	proto := ""
+	if state.QType() == dns.TypeSRV {
+		if p, rest, ok := strings.Cut(name, "."); ok && (p == "_tcp" || p == "_udp") {
+			proto, name = p[1:], rest
+		}
+	}
+	entries, ok := d.cache.lookup(name)
+	if !ok {
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{d.soa()}
+		requestSuccessCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	}
+
+	for _, e := range entries {
+		switch state.QType() {
+		case dns.TypeA:
+			if e.ip4 != nil {
+				m.Answer = append(m.Answer, d.address(state.QName(), e.ip4))
+			}
+		case dns.TypeAAAA:
+			if e.ip6 != nil {
+				m.Answer = append(m.Answer, d.address(state.QName(), e.ip6))
+			}
+		case dns.TypeSRV:
+			target := d.fqdn(e.target)
+			for _, p := range e.ports {
+				if proto != "" && p.proto != proto {
+					continue
+				}
+				m.Answer = append(m.Answer, &dns.SRV{
+					Hdr:      dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: d.ttl},
+					Priority: 10,
+					Weight:   10,
+					Port:     p.number,
+					Target:   target,
+				})
+			}
+			for _, ip := range []net.IP{e.ip4, e.ip6} {
+				if ip != nil && len(e.ports) > 0 {
+					m.Extra = append(m.Extra, d.address(target, ip))
+				}
+			}
+		}
+	}
+	m.Answer = dns.Dedup(m.Answer, nil)
+	m.Extra = dns.Dedup(m.Extra, nil)
+	if len(m.Answer) == 0 {
+		m.Extra = nil
+		m.Ns = []dns.RR{d.soa()}
+	}
+
+	requestSuccessCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+	return dns.RcodeSuccess, w.WriteMsg(m)
+}
+
+// OnStartup starts the cache of the containers.
+func (d *Docker) OnStartup() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.cache = newCache(d.client)
+	go d.cache.run(ctx)
+	return nil
+}
+
+// OnShutdown stops the cache of the containers.
+func (d *Docker) OnShutdown() error {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	return nil
+}
+
+// fqdn returns name, which is relative to the zone, as a fully qualified name.
+func (d *Docker) fqdn(name string) string { return dnsutil.Join(name, d.Zone) }
+
+// address returns an A or AAAA record for ip.
+func (d *Docker) address(name string, ip net.IP) dns.RR {
+	if ip.To4() != nil {
+		return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: d.ttl}, A: ip}
+	}
+	return &dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: d.ttl}, AAAA: ip}
+}
+
+func (d *Docker) soa() *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: d.Zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: d.ttl},
+		Ns:      dns.Fqdn("ns." + d.Zone),
+		Mbox:    dns.Fqdn("hostmaster." + d.Zone),
+		Serial:  uint32(time.Now().Unix()),
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  uint32(defaultTTL),
+	}
+}
@@ -0,0 +1,266 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// fakeEngine is a stand-in for the Engine API of Docker, served on a unix socket.
+type fakeEngine struct {
+	mu          sync.Mutex
+	containers  map[string]*container
+	subscribers []chan event
+}
+
+func newFakeEngine() *fakeEngine { return &fakeEngine{containers: map[string]*container{}} }
+
+func newContainer(id, name string, labels map[string]string, ports ...string) *container {
+	ct := &container{ID: id, Name: "/" + name}
+	ct.State.Running = true
+	ct.Config.Labels = labels
+	ct.Config.ExposedPorts = map[string]struct{}{}
+	for _, p := range ports {
+		ct.Config.ExposedPorts[p] = struct{}{}
+	}
+	ct.NetworkSettings.Networks = map[string]*endpoint{}
+	return ct
+}
+
+// run adds ct, and sends the event of its start.
+func (f *fakeEngine) run(ct *container) {
+	f.mu.Lock()
+	f.containers[ct.ID] = ct
+	f.mu.Unlock()
+	f.send("container", "start", ct.ID, nil)
+}
+
+// remove removes the container with id, and sends the event of its destruction.
+func (f *fakeEngine) remove(id string) {
+	f.mu.Lock()
+	delete(f.containers, id)
+	f.mu.Unlock()
+	f.send("container", "destroy", id, nil)
+}
+
+func (f *fakeEngine) send(typ, action, id string, attrs map[string]string) {
+	e := event{Type: typ, Action: action}
+	e.Actor.ID, e.Actor.Attributes = id, attrs
+	f.mu.Lock()
+	subscribers := f.subscribers
+	f.mu.Unlock()
+	for _, ch := range subscribers {
+		ch <- e
+	}
+}
+
+func (f *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/events":
+		ch := make(chan event, 10)
+		f.mu.Lock()
+		f.subscribers = append(f.subscribers, ch)
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		enc := json.NewEncoder(w)
+		for {
+			select {
+			case e := <-ch:
+				enc.Encode(e)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	case r.URL.Path == "/containers/json":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		list := []map[string]string{}
+		for id := range f.containers {
+			list = append(list, map[string]string{"Id": id})
+		}
+		json.NewEncoder(w).Encode(list)
+	case strings.HasPrefix(r.URL.Path, "/containers/"):
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		ct, ok := f.containers[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/json")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(ct)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestDocker(t *testing.T, f *fakeEngine) *Docker {
+	t.Helper()
+	// The path of a unix socket is limited in length, so t.TempDir can't be used.
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewUnstartedServer(f)
+	s.Listener = l
+	s.Start()
+	t.Cleanup(s.Close)
+
+	client, err := newClient("unix://" + sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Docker{Next: test.ErrorHandler(), Zone: "docker.", ttl: uint32(defaultTTL), client: client}
+	d.OnStartup()
+	t.Cleanup(func() { d.OnShutdown() })
+
+	test.WaitFor(t, d.Ready)
+	return d
+}
+
+func TestDocker(t *testing.T) {
+	f := newFakeEngine()
+	web := newContainer("c1", "proj-web-1", map[string]string{composeProject: "proj", composeService: "web"}, "80/tcp", "443/tcp")
+	web.NetworkSettings.Networks["proj_default"] = &endpoint{IPAddress: "172.18.0.2", GlobalIPv6Address: "fd00::2", Aliases: []string{"web", "c1"}}
+	web.NetworkSettings.Networks["bridge"] = &endpoint{IPAddress: "172.17.0.2"}
+	f.run(web)
+	web2 := newContainer("c2", "proj-web-2", map[string]string{composeProject: "proj", composeService: "web"}, "80/tcp")
+	web2.NetworkSettings.Networks["proj_default"] = &endpoint{IPAddress: "172.18.0.3", Aliases: []string{"web"}}
+	f.run(web2)
+	dns1 := newContainer("c3", "resolver", nil, "53/udp", "53/tcp")
+	dns1.NetworkSettings.Networks["bridge"] = &endpoint{IPAddress: "172.17.0.4"}
+	f.run(dns1)
+	stopped := newContainer("c4", "stopped", nil)
+	stopped.State.Running = false
+	stopped.NetworkSettings.Networks["bridge"] = &endpoint{IPAddress: "172.17.0.5"}
+	f.run(stopped)
+
+	d := newTestDocker(t, f)
+
+	cases := []test.Case{
+		{
+			Qname: "proj-web-1.bridge.docker.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("proj-web-1.bridge.docker.	30	IN	A	172.17.0.2"),
+			},
+		},
+		{
+			Qname: "proj-web-1.proj_default.docker.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{
+				test.AAAA("proj-web-1.proj_default.docker.	30	IN	AAAA	fd00::2"),
+			},
+		},
+		{
+			// A network alias shared by two containers.
+			Qname: "web.proj_default.docker.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.proj_default.docker.	30	IN	A	172.18.0.2"),
+				test.A("web.proj_default.docker.	30	IN	A	172.18.0.3"),
+			},
+		},
+		{
+			// The compose service in its project.
+			Qname: "web.proj.docker.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.proj.docker.	30	IN	A	172.17.0.2"),
+				test.A("web.proj.docker.	30	IN	A	172.18.0.2"),
+				test.A("web.proj.docker.	30	IN	A	172.18.0.3"),
+			},
+		},
+		{
+			Qname: "proj-web-2.proj_default.docker.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{
+				test.SRV("proj-web-2.proj_default.docker.	30	IN	SRV	10 10 80 proj-web-2.proj_default.docker."),
+			},
+			Extra: []dns.RR{
+				test.A("proj-web-2.proj_default.docker.	30	IN	A	172.18.0.3"),
+			},
+		},
+		{
+			Qname: "_udp.resolver.bridge.docker.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{
+				test.SRV("_udp.resolver.bridge.docker.	30	IN	SRV	10 10 53 resolver.bridge.docker."),
+			},
+			Extra: []dns.RR{
+				test.A("resolver.bridge.docker.	30	IN	A	172.17.0.4"),
+			},
+		},
+		{
+			Qname: "2.0.18.172.in-addr.arpa.", Qtype: dns.TypePTR,
+			Answer: []dns.RR{
+				test.PTR("2.0.18.172.in-addr.arpa.	30	IN	PTR	proj-web-1.proj_default.docker."),
+			},
+		},
+		{
+			Qname: "resolver.bridge.docker.", Qtype: dns.TypeAAAA,
+			Ns: []dns.RR{
+				test.SOA("docker.	30	IN	SOA	ns.docker. hostmaster.docker. 0 3600 600 86400 30"),
+			},
+		},
+		{
+			Qname: "stopped.bridge.docker.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA("docker.	30	IN	SOA	ns.docker. hostmaster.docker. 0 3600 600 86400 30"),
+			},
+		},
+	}
+	test.Run(t, d, cases)
+
+	// Reverse lookups of other addresses are passed on.
+	r := new(dns.Msg)
+	r.SetQuestion("9.0.18.172.in-addr.arpa.", dns.TypePTR)
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	if rcode, _ := d.ServeDNS(context.Background(), w, r); rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected the query for an unknown address to be passed on, got rcode %d", rcode)
+	}
+}
+
+func TestDockerEvents(t *testing.T) {
+	f := newFakeEngine()
+	d := newTestDocker(t, f)
+
+	ct := newContainer("c1", "app", nil)
+	ct.NetworkSettings.Networks["bridge"] = &endpoint{IPAddress: "172.17.0.2"}
+	f.run(ct)
+	test.WaitFor(t, func() bool {
+		_, ok := d.cache.lookup("app.bridge")
+		return ok
+	})
+
+	// Connecting the container to another network is picked up.
+	f.mu.Lock()
+	ct.NetworkSettings.Networks["backend"] = &endpoint{IPAddress: "172.20.0.2", Aliases: []string{"api"}}
+	f.mu.Unlock()
+	f.send("network", "connect", "n1", map[string]string{"container": "c1"})
+	test.WaitFor(t, func() bool {
+		_, ok := d.cache.lookup("api.backend")
+		return ok
+	})
+
+	f.remove("c1")
+	test.WaitFor(t, func() bool {
+		_, ok := d.cache.lookup("app.bridge")
+		_, rev := d.cache.ptr("2.0.17.172.in-addr.arpa.")
+		return !ok && !rev
+	})
+}
@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// requestSuccessCount is the number of DNS requests handled successfully.
+	requestSuccessCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "success_requests_total",
+		Help:      "Counter of DNS requests handled successfully.",
+	}, []string{"server"})
+	// requestFailedCount is the number of DNS requests that failed.
+	requestFailedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "failed_requests_total",
+		Help:      "Counter of DNS requests failed.",
+	}, []string{"server"})
+	// cacheContainers is the number of running containers known.
+	cacheContainers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "containers",
+		Help:      "The number of running containers known.",
+	})
+	// streamErrors is the number of times the containers couldn't be fetched or the events stream was lost.
+	streamErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "stream_errors_total",
+		Help:      "Counter of failures to fetch the containers or to follow the events stream.",
+	})
+)
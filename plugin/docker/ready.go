@@ -0,0 +1,6 @@
+package docker
+
+// Ready signals when the plugin is ready for use.
+// In case of Docker, when the running containers
+// have been fetched the plugin is ready.
+func (d *Docker) Ready() bool { return d.cache != nil && d.cache.isSynced() }
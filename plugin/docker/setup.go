@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+const defaultEndpoint = "unix:///var/run/docker.sock"
+
+// init registers this plugin.
+func init() { plugin.Register(pluginName, setup) }
+
+// setup is the function that gets called when the config parser sees the token "docker". Setup is responsible
+// for parsing any extra options the docker plugin may have. The first token this function sees is "docker".
+func setup(c *caddy.Controller) error {
+	d := &Docker{ttl: uint32(defaultTTL)}
+
+	if err := parse(c, d); err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	c.OnStartup(d.OnStartup)
+	c.OnShutdown(d.OnShutdown)
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		d.Next = next
+		return d
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller, d *Docker) error {
+	// The environment variable of the Docker CLI is used as the default.
+	endpoint := os.Getenv("DOCKER_HOST")
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	// Expect the first token to be "docker"
+	if !c.Next() {
+		return c.Err("expected 'docker' token")
+	}
+
+	args := c.RemainingArgs()
+	switch len(args) {
+	case 0:
+		d.Zone = "docker."
+	case 1:
+		d.Zone = dns.Fqdn(strings.ToLower(args[0]))
+	default:
+		return c.ArgErr()
+	}
+
+	for c.NextBlock() {
+		selector := strings.ToLower(c.Val())
+
+		switch selector {
+		case "endpoint":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.Err("exactly one endpoint is required")
+			}
+			endpoint = args[0]
+		case "ttl":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.Err("exactly one ttl value is required")
+			}
+			t, err := strconv.Atoi(args[0])
+			if err != nil {
+				return c.Err("error parsing ttl: " + err.Error())
+			}
+			if t < 0 || t > 3600 {
+				return c.Errf("ttl must be in range [0, 3600]: %d", t)
+			}
+			d.ttl = uint32(t)
+		default:
+			return c.Errf("unknown property '%s'", selector)
+		}
+	}
+
+	client, err := newClient(endpoint)
+	if err != nil {
+		return c.Err(err.Error())
+	}
+	d.client = client
+	return nil
+}
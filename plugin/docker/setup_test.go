@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetupDocker(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           string
+		shouldErr        bool
+		expectedZone     string
+		expectedTTL      uint32
+		expectedEndpoint string
+		expectedBase     string
+	}{
+		{
+			name:             "defaults",
+			config:           `docker`,
+			expectedZone:     "docker.",
+			expectedTTL:      uint32(defaultTTL),
+			expectedEndpoint: defaultEndpoint,
+			expectedBase:     "http://docker",
+		},
+		{
+			name: "full",
+			config: `
+docker Containers.Local {
+    endpoint unix:///run/user/1000/podman/podman.sock
+    ttl 60
+}`,
+			expectedZone:     "containers.local.",
+			expectedTTL:      60,
+			expectedEndpoint: "unix:///run/user/1000/podman/podman.sock",
+			expectedBase:     "http://docker",
+		},
+		{
+			name: "tcp",
+			config: `
+docker {
+    endpoint tcp://127.0.0.1:2375
+}`,
+			expectedZone:     "docker.",
+			expectedTTL:      uint32(defaultTTL),
+			expectedEndpoint: "tcp://127.0.0.1:2375",
+			expectedBase:     "http://127.0.0.1:2375",
+		},
+		{
+			name: "invalid_endpoint",
+			config: `
+docker {
+    endpoint ssh://host
+}`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid_ttl",
+			config: `
+docker {
+    ttl -1
+}`,
+			shouldErr: true,
+		},
+		{
+			name:      "too_many_zones",
+			config:    `docker a.org b.org`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid_property",
+			config: `
+docker {
+    invalid_property
+}`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", "")
+			c := caddy.NewTestController("dns", tt.config)
+			d := &Docker{ttl: uint32(defaultTTL)}
+
+			err := parse(c, d)
+			if tt.shouldErr && err == nil {
+				t.Fatalf("Test %s: expected error but got none", tt.name)
+			}
+			if !tt.shouldErr && err != nil {
+				t.Fatalf("Test %s: expected no error but got: %v", tt.name, err)
+			}
+			if tt.shouldErr {
+				return
+			}
+
+			if d.Zone != tt.expectedZone {
+				t.Errorf("Test %s: expected zone %s, got %s", tt.name, tt.expectedZone, d.Zone)
+			}
+			if d.ttl != tt.expectedTTL {
+				t.Errorf("Test %s: expected TTL %d, got %d", tt.name, tt.expectedTTL, d.ttl)
+			}
+			if d.client.endpoint != tt.expectedEndpoint {
+				t.Errorf("Test %s: expected endpoint %s, got %s", tt.name, tt.expectedEndpoint, d.client.endpoint)
+			}
+			if d.client.base != tt.expectedBase {
+				t.Errorf("Test %s: expected base %s, got %s", tt.name, tt.expectedBase, d.client.base)
+			}
+		})
+	}
+}
+
+func TestSetupDockerEnvironment(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "unix:///run/podman/podman.sock")
+
+	c := caddy.NewTestController("dns", `docker`)
+	d := &Docker{}
+	if err := parse(c, d); err != nil {
+		t.Fatal(err)
+	}
+	if d.client.endpoint != "unix:///run/podman/podman.sock" {
+		t.Errorf("Expected the endpoint from the environment, got %s", d.client.endpoint)
+	}
+}
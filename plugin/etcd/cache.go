@@ -0,0 +1,320 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/retry"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// watchProgress is how often the cache asks etcd for the progress of the watch, to know it is not stale,
+	// and refreshes the TTLs of the leases of the keys.
+	watchProgress = 10 * time.Second
+	// watchBackoff is how long the cache waits before it retries after etcd failed.
+	watchBackoff = time.Second
+)
+
+var errCompacted = errors.New("watch revision compacted")
+
+// cache is an in-memory mirror of the keys under the path prefix, kept up to date with a watch. Lookups are
+// served from it, so they don't depend on the latency or availability of etcd. If etcd can't be reached the
+// last known keys are served.
+type cache struct {
+	client *etcdcv3.Client
+	prefix string // the path prefix with a trailing slash
+
+	mu       sync.RWMutex
+	kvs      map[string]*mvccpb.KeyValue
+	keys     []string            // the keys of kvs, sorted
+	deleted  map[string]int64    // the revision keys that are no longer in kvs were deleted at
+	leases   map[int64]time.Time // the expiration of the leases of the keys
+	revision int64               // the etcd revision the cache is at, 0 until it is synced
+	contact  time.Time           // the last time the cache was known to be up to date
+
+	// onChange is called with the revision and the keys that changed after the cache is updated.
+	onChange func(revision int64, keys []string)
+}
+
+func newCache(client *etcdcv3.Client, pathPrefix string) *cache {
+	return &cache{
+		client:  client,
+		prefix:  "/" + strings.Trim(pathPrefix, "/") + "/",
+		kvs:     map[string]*mvccpb.KeyValue{},
+		deleted: map[string]int64{},
+		leases:  map[int64]time.Time{},
+	}
+}
+
+// synced returns true if the cache has been filled from etcd.
+func (c *cache) synced() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.revision > 0
+}
+
+// get returns the keys that Etcd.get would return from etcd, from the cache.
+func (c *cache) get(path string, recursive bool) ([]*mvccpb.KeyValue, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if recursive {
+		if !strings.HasSuffix(path, "/") {
+			path = path + "/"
+		}
+		var kvs []*mvccpb.KeyValue
+		for i := sort.SearchStrings(c.keys, path); i < len(c.keys) && strings.HasPrefix(c.keys[i], path); i++ {
+			kvs = append(kvs, c.kvs[c.keys[i]])
+		}
+		if len(kvs) > 0 {
+			return kvs, nil
+		}
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	if kv, ok := c.kvs[path]; ok {
+		return []*mvccpb.KeyValue{kv}, nil
+	}
+	return nil, errKeyNotFound
+}
+
+// snapshot returns all keys under path and the revision of the last change under path.
+func (c *cache) snapshot(path string) ([]*mvccpb.KeyValue, int64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var kvs []*mvccpb.KeyValue
+	for i := sort.SearchStrings(c.keys, path); i < len(c.keys) && strings.HasPrefix(c.keys[i], path); i++ {
+		kvs = append(kvs, c.kvs[c.keys[i]])
+	}
+	return kvs, c.lastChange(path)
+}
+
+// changeRevision returns the revision of the last change under path: the last time a key under it was
+// written or deleted.
+func (c *cache) changeRevision(path string) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastChange(path)
+}
+
+// lastChange is changeRevision for a caller that holds the lock.
+func (c *cache) lastChange(path string) int64 {
+	var rev int64
+	for i := sort.SearchStrings(c.keys, path); i < len(c.keys) && strings.HasPrefix(c.keys[i], path); i++ {
+		rev = max(rev, c.kvs[c.keys[i]].ModRevision)
+	}
+	for key, r := range c.deleted {
+		if strings.HasPrefix(key, path) {
+			rev = max(rev, r)
+		}
+	}
+	return rev
+}
+
+// currentRevision returns the revision the cache is at.
+func (c *cache) currentRevision() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.revision
+}
+
+// lease returns the expiration of lease, if it is known.
+func (c *cache) lease(lease int64) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, ok := c.leases[lease]
+	return t, ok
+}
+
+// reset replaces the contents of the cache with kvs, at revision.
+func (c *cache) reset(kvs []*mvccpb.KeyValue, revision int64) {
+	c.mu.Lock()
+	changed := make([]string, 0, len(c.keys)+len(kvs))
+	changed = append(changed, c.keys...)
+	old := c.kvs
+	c.kvs = make(map[string]*mvccpb.KeyValue, len(kvs))
+	c.keys = make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		c.kvs[string(kv.Key)] = kv
+		c.keys = append(c.keys, string(kv.Key))
+		changed = append(changed, string(kv.Key))
+		delete(c.deleted, string(kv.Key))
+	}
+	sort.Strings(c.keys)
+	// Keys that are gone were deleted somewhere before revision, we missed when.
+	for key := range old {
+		if _, ok := c.kvs[key]; !ok {
+			c.deleted[key] = revision
+		}
+	}
+	c.revision = revision
+	c.contact = time.Now()
+	c.mu.Unlock()
+
+	cacheRevision.WithLabelValues(c.prefix).Set(float64(revision))
+	cacheContact.WithLabelValues(c.prefix).SetToCurrentTime()
+	cacheKeys.WithLabelValues(c.prefix).Set(float64(len(kvs)))
+	if c.onChange != nil {
+		c.onChange(revision, changed)
+	}
+}
+
+// apply applies the events of a watch response at revision to the cache.
+func (c *cache) apply(events []*etcdcv3.Event, revision int64) {
+	c.mu.Lock()
+	changed := make([]string, 0, len(events))
+	for _, ev := range events {
+		key := string(ev.Kv.Key)
+		changed = append(changed, key)
+		i := sort.SearchStrings(c.keys, key)
+		exists := i < len(c.keys) && c.keys[i] == key
+		switch ev.Type {
+		case mvccpb.PUT:
+			c.kvs[key] = ev.Kv
+			delete(c.deleted, key)
+			if !exists {
+				c.keys = append(c.keys, "")
+				copy(c.keys[i+1:], c.keys[i:])
+				c.keys[i] = key
+			}
+		case mvccpb.DELETE:
+			delete(c.kvs, key)
+			c.deleted[key] = revision
+			if ev.Kv.ModRevision > 0 {
+				c.deleted[key] = ev.Kv.ModRevision
+			}
+			if exists {
+				c.keys = append(c.keys[:i], c.keys[i+1:]...)
+			}
+		}
+	}
+	if revision > c.revision {
+		c.revision = revision
+	}
+	c.contact = time.Now()
+	n := len(c.keys)
+	c.mu.Unlock()
+
+	cacheRevision.WithLabelValues(c.prefix).Set(float64(revision))
+	cacheContact.WithLabelValues(c.prefix).SetToCurrentTime()
+	cacheKeys.WithLabelValues(c.prefix).Set(float64(n))
+	if c.onChange != nil && len(changed) > 0 {
+		c.onChange(revision, changed)
+	}
+}
+
+// run fills the cache and follows the watch on the prefix until ctx is done. When the watch fails, or its
+// revision got compacted, the cache is filled again from scratch.
+func (c *cache) run(ctx context.Context) {
+	for {
+		err := c.sync(ctx)
+		if err == nil {
+			err = c.watch(ctx)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errCompacted) {
+			log.Infof("Resyncing the cache of %s: %s", c.prefix, err)
+		} else {
+			log.Warningf("Failed to keep the cache of %s up to date, serving the last known data: %s", c.prefix, err)
+		}
+		cacheResyncs.WithLabelValues(c.prefix).Inc()
+
+		if !retry.Sleep(ctx, watchBackoff) {
+			return
+		}
+	}
+}
+
+// sync fills the cache with the keys under the prefix.
+func (c *cache) sync(ctx context.Context) error {
+	ctx1, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+	r, err := c.client.Get(ctx1, c.prefix, etcdcv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	c.reset(r.Kvs, r.Header.Revision)
+	c.refreshLeases(ctx)
+	return nil
+}
+
+// watch applies the changes under the prefix to the cache, until the watch fails.
+func (c *cache) watch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(etcdcv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	c.mu.RLock()
+	rev := c.revision
+	c.mu.RUnlock()
+
+	wch := c.client.Watch(ctx, c.prefix, etcdcv3.WithPrefix(), etcdcv3.WithRev(rev+1))
+	tick := time.NewTicker(watchProgress)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+			// The response comes in on the watch channel and marks the cache as up to date.
+			if err := c.client.RequestProgress(ctx); err != nil {
+				log.Debugf("Failed to request the progress of the watch on %s: %s", c.prefix, err)
+			}
+			c.refreshLeases(ctx)
+		case wr, ok := <-wch:
+			if !ok {
+				return errors.New("watch closed")
+			}
+			if wr.CompactRevision != 0 || errors.Is(wr.Err(), rpctypes.ErrCompacted) {
+				return errCompacted
+			}
+			if err := wr.Err(); err != nil {
+				return err
+			}
+			c.apply(wr.Events, wr.Header.Revision)
+		}
+	}
+}
+
+// refreshLeases looks up the TTL of the leases of the keys in the cache, and forgets the ones that are no
+// longer used.
+func (c *cache) refreshLeases(ctx context.Context) {
+	c.mu.RLock()
+	used := map[int64]bool{}
+	for _, kv := range c.kvs {
+		if kv.Lease != 0 {
+			used[kv.Lease] = true
+		}
+	}
+	c.mu.RUnlock()
+
+	leases := make(map[int64]time.Time, len(used))
+	for lease := range used {
+		ctx1, cancel := context.WithTimeout(ctx, etcdTimeout)
+		resp, err := c.client.TimeToLive(ctx1, etcdcv3.LeaseID(lease))
+		cancel()
+		if err != nil {
+			// Keep what we know.
+			if t, ok := c.lease(lease); ok {
+				leases[lease] = t
+			}
+			continue
+		}
+		if resp.TTL > 0 {
+			leases[lease] = time.Now().Add(time.Duration(resp.TTL) * time.Second)
+		}
+	}
+
+	c.mu.Lock()
+	c.leases = leases
+	c.mu.Unlock()
+}
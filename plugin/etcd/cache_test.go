@@ -0,0 +1,114 @@
+package etcd
+
+import (
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+func put(key, value string) *etcdcv3.Event {
+	return &etcdcv3.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}}
+}
+
+func del(key string) *etcdcv3.Event {
+	return &etcdcv3.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte(key)}}
+}
+
+func TestCacheGet(t *testing.T) {
+	c := newCache(nil, "skydns")
+	if c.synced() {
+		t.Fatal("Expected cache not to be synced before it is filled")
+	}
+	c.reset([]*mvccpb.KeyValue{
+		{Key: []byte("/skydns/test/skydns/a"), Value: []byte("a")},
+		{Key: []byte("/skydns/test/skydns/b/x"), Value: []byte("bx")},
+		{Key: []byte("/skydns/test/skydns/b/y"), Value: []byte("by")},
+		{Key: []byte("/skydns/test/skydnsx/c"), Value: []byte("c")},
+	}, 10)
+	if !c.synced() {
+		t.Fatal("Expected cache to be synced")
+	}
+
+	tests := []struct {
+		path      string
+		recursive bool
+		expected  []string
+	}{
+		{"/skydns/test/skydns", true, []string{"a", "bx", "by"}},
+		{"/skydns/test/skydns/b", true, []string{"bx", "by"}},
+		{"/skydns/test/skydns/a", true, []string{"a"}},
+		{"/skydns/test/skydns/a", false, []string{"a"}},
+		{"/skydns/test/skydns/b", false, nil},
+		{"/skydns/test/skydns/z", true, nil},
+	}
+	for i, tc := range tests {
+		kvs, err := c.get(tc.path, tc.recursive)
+		if tc.expected == nil {
+			if err != errKeyNotFound {
+				t.Errorf("Test %d: expected errKeyNotFound, got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if len(kvs) != len(tc.expected) {
+			t.Errorf("Test %d: expected %d keys, got %d", i, len(tc.expected), len(kvs))
+			continue
+		}
+		for j := range kvs {
+			if string(kvs[j].Value) != tc.expected[j] {
+				t.Errorf("Test %d: expected value %q, got %q", i, tc.expected[j], kvs[j].Value)
+			}
+		}
+	}
+}
+
+func TestCacheApply(t *testing.T) {
+	c := newCache(nil, "skydns")
+	c.reset([]*mvccpb.KeyValue{
+		{Key: []byte("/skydns/test/b"), Value: []byte("b")},
+	}, 10)
+
+	var changed []string
+	c.onChange = func(revision int64, keys []string) {
+		if revision != 11 {
+			t.Errorf("Expected revision 11, got %d", revision)
+		}
+		changed = keys
+	}
+	c.apply([]*etcdcv3.Event{put("/skydns/test/c", "c"), put("/skydns/test/a", "a"), del("/skydns/test/b"), put("/skydns/test/c", "c2")}, 11)
+
+	if len(changed) != 4 {
+		t.Errorf("Expected 4 changed keys, got %v", changed)
+	}
+	if c.revision != 11 {
+		t.Errorf("Expected revision 11, got %d", c.revision)
+	}
+	kvs, err := c.get("/skydns/test", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 || string(kvs[0].Value) != "a" || string(kvs[1].Value) != "c2" {
+		t.Errorf("Expected keys a and c2, got %v", kvs)
+	}
+	if _, err := c.get("/skydns/test/b", false); err != errKeyNotFound {
+		t.Errorf("Expected deleted key to be gone, got %v", err)
+	}
+}
+
+func TestCacheLeaseTTL(t *testing.T) {
+	e := &Etcd{cache: newCache(nil, "skydns")}
+	e.cache.reset(nil, 1)
+	e.cache.leases[42] = time.Now().Add(time.Minute)
+
+	if ttl := e.leaseTTL(42); ttl < 58 || ttl > 60 {
+		t.Errorf("Expected a lease TTL of about 60, got %d", ttl)
+	}
+	if ttl := e.leaseTTL(43); ttl != 0 {
+		t.Errorf("Expected a lease TTL of 0 for an unknown lease, got %d", ttl)
+	}
+}
@@ -7,12 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/etcd/msg"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -40,8 +42,13 @@ type Etcd struct {
 	Client      *etcdcv3.Client
 	MinLeaseTTL uint32 // minimum TTL for lease-based records
 	MaxLeaseTTL uint32 // maximum TTL for lease-based records
+	Watch       bool   // serve from a cache kept up to date with a watch, instead of reading from etcd
 
 	endpoints []string // Stored here as well, to aid in testing.
+	cache     *cache
+	stop      context.CancelFunc
+	revision  atomic.Int64 // the latest etcd revision seen
+	transfer  *transfer.Transfer
 }
 
 // Services implements the ServiceBackend interface.
@@ -76,12 +83,22 @@ func (e *Etcd) Records(ctx context.Context, state request.Request, exact bool) (
 	name := state.Name()
 
 	path, star := msg.PathWithWildcard(name, e.PathPrefix)
-	r, err := e.get(ctx, path, !exact)
-	if err != nil {
-		return nil, err
+	var kvs []*mvccpb.KeyValue
+	if e.cache != nil && e.cache.synced() {
+		var err error
+		kvs, err = e.cache.get(path, !exact)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		r, err := e.get(ctx, path, !exact)
+		if err != nil {
+			return nil, err
+		}
+		kvs = r.Kvs
 	}
 	segments := strings.Split(msg.Path(name, e.PathPrefix), "/")
-	return e.loopNodes(r.Kvs, segments, star, state.QType())
+	return e.loopNodes(kvs, segments, star, state.QType())
 }
 
 func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.GetResponse, error) {
@@ -95,6 +112,7 @@ func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.G
 		if err != nil {
 			return nil, err
 		}
+		e.seenRevision(r.Header.Revision)
 		if r.Count == 0 {
 			path = strings.TrimSuffix(path, "/")
 			r, err = e.Client.Get(ctx, path)
@@ -112,6 +130,7 @@ func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.G
 	if err != nil {
 		return nil, err
 	}
+	e.seenRevision(r.Header.Revision)
 	if r.Count == 0 {
 		return nil, errKeyNotFound
 	}
@@ -166,9 +185,8 @@ func (e *Etcd) TTL(kv *mvccpb.KeyValue, serv *msg.Service) uint32 {
 	var etcdTTL uint32
 
 	// Get actual lease TTL from etcd if lease exists and client is available
-	if kv.Lease != 0 && e.Client != nil {
-		if resp, err := e.Client.TimeToLive(context.Background(), etcdcv3.LeaseID(kv.Lease)); err == nil && resp.TTL > 0 {
-			leaseTTL := resp.TTL
+	if kv.Lease != 0 {
+		if leaseTTL := e.leaseTTL(kv.Lease); leaseTTL > 0 {
 
 			// Get bounds with defaults
 			minTTL := e.MinLeaseTTL
@@ -209,6 +227,23 @@ func (e *Etcd) TTL(kv *mvccpb.KeyValue, serv *msg.Service) uint32 {
 	return serv.TTL
 }
 
+// leaseTTL returns the remaining TTL of lease in seconds, or 0 if it is not known.
+func (e *Etcd) leaseTTL(lease int64) int64 {
+	if e.cache != nil {
+		if t, ok := e.cache.lease(lease); ok {
+			return int64(time.Until(t) / time.Second)
+		}
+	}
+	if e.Client == nil {
+		return 0
+	}
+	resp, err := e.Client.TimeToLive(context.Background(), etcdcv3.LeaseID(lease))
+	if err != nil {
+		return 0
+	}
+	return resp.TTL
+}
+
 // shouldInclude returns true if the service should be included in a list of records, given the qType. For all the
 // currently supported lookup types, the only one to allow for an empty Host field in the service are TXT records
 // which resolve directly.  If a TXT record is being resolved by CNAME, then we expect the Host field to have a
@@ -217,8 +252,25 @@ func shouldInclude(serv *msg.Service, qType uint16) bool {
 	return (qType == dns.TypeTXT && serv.Text != "") || serv.Host != ""
 }
 
+// OnStartup starts the watch that keeps the cache up to date, if it is enabled. Changes seen by the watch
+// trigger notifies for the zones they are in.
+func (e *Etcd) OnStartup() error {
+	if !e.Watch || e.Client == nil {
+		return nil
+	}
+	e.cache = newCache(e.Client, e.PathPrefix)
+	e.cache.onChange = func(_ int64, keys []string) { go e.notify(keys) }
+	ctx, cancel := context.WithCancel(context.Background())
+	e.stop = cancel
+	go e.cache.run(ctx)
+	return nil
+}
+
 // OnShutdown shuts down etcd client when caddy instance restart
 func (e *Etcd) OnShutdown() error {
+	if e.stop != nil {
+		e.stop()
+	}
 	if e.Client != nil {
 		e.Client.Close()
 	}
@@ -0,0 +1,39 @@
+package etcd
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// cacheContact is the last time the watch cache was known to be up to date with etcd.
+	cacheContact = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "cache_last_update_timestamp_seconds",
+		Help:      "The timestamp of the last time the watch cache was known to be up to date with etcd.",
+	}, []string{"path"})
+	// cacheRevision is the etcd revision the watch cache is at.
+	cacheRevision = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "cache_revision",
+		Help:      "The etcd revision the watch cache is at.",
+	}, []string{"path"})
+	// cacheKeys is the number of keys in the watch cache.
+	cacheKeys = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "cache_keys",
+		Help:      "The number of keys in the watch cache.",
+	}, []string{"path"})
+	// cacheResyncs is the number of times the watch cache was reloaded from etcd after the watch failed.
+	cacheResyncs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "cache_resyncs_total",
+		Help:      "Counter of the times the watch cache was reloaded after the watch failed or was compacted.",
+	}, []string{"path"})
+)
@@ -11,12 +11,16 @@ import (
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	mwtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
 
 	etcdcv3 "go.etcd.io/etcd/client/v3"
 )
 
+var log = clog.NewWithPlugin("etcd")
+
 func init() { plugin.Register("etcd", setup) }
 
 func setup(c *caddy.Controller) error {
@@ -25,6 +29,13 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("etcd", err)
 	}
 
+	c.OnStartup(func() error {
+		t := dnsserver.GetConfig(c).Handler("transfer")
+		if t != nil {
+			e.transfer = t.(*transfer.Transfer)
+		}
+		return e.OnStartup()
+	})
 	c.OnShutdown(e.OnShutdown)
 
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
@@ -96,6 +107,11 @@ func etcdParse(c *caddy.Controller) (*Etcd, error) {
 					return &Etcd{}, c.Errf("credentials requires 2 arguments, username and password")
 				}
 				username, password = args[0], args[1]
+			case "watch":
+				if c.NextArg() {
+					return &Etcd{}, c.ArgErr()
+				}
+				etc.Watch = true
 			case "min-lease-ttl":
 				if !c.NextArg() {
 					return &Etcd{}, c.ArgErr()
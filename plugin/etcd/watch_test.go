@@ -0,0 +1,46 @@
+//go:build etcd
+
+package etcd
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestWatchLookup(t *testing.T) {
+	etc := newEtcdPlugin()
+	etc.Watch = true
+	set(t, etc, "a.watch.skydns.test.", 0, &msg.Service{Host: "10.0.0.1"})
+	defer remove(t, etc, "a.watch.skydns.test.")
+
+	etc.OnStartup()
+	defer etc.stop()
+
+	test.WaitFor(t, func() bool { return etc.cache.synced() })
+	assertA(t, etc, "a.watch.skydns.test.", "10.0.0.1")
+
+	set(t, etc, "a.watch.skydns.test.", 0, &msg.Service{Host: "10.0.0.2"})
+	test.WaitFor(t, func() bool {
+		kvs, err := etc.cache.get("/skydns/test/skydns/watch/a", false)
+		return err == nil && string(kvs[0].Value) == `{"host":"10.0.0.2"}`
+	})
+	assertA(t, etc, "a.watch.skydns.test.", "10.0.0.2")
+}
+
+func assertA(t *testing.T, etc *Etcd, name, addr string) {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(name, dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := etc.ServeDNS(ctxt, rec, m); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != addr {
+		t.Errorf("Expected %s, got %v", addr, rec.Msg.Answer)
+	}
+}
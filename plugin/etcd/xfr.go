@@ -1,13 +1,33 @@
 package etcd
 
 import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
 )
 
-// Serial returns the serial number to use.
+// Serial returns the serial number to use. With the cache this is the revision of the last change to a key
+// in the zone, as the cache sees the deletes as well. Otherwise it is the etcd revision the data is read at,
+// which changes with every key in etcd.
 func (e *Etcd) Serial(state request.Request) uint32 {
+	if e.cache != nil && e.cache.synced() && state.Zone != "" {
+		return uint32(e.cache.changeRevision(e.zonePath(state.Zone)))
+	}
+	if rev := e.currentRevision(); rev > 0 {
+		return uint32(rev)
+	}
 	return uint32(time.Now().Unix())
 }
 
@@ -15,3 +35,222 @@ func (e *Etcd) Serial(state request.Request) uint32 {
 func (e *Etcd) MinTTL(state request.Request) uint32 {
 	return 30
 }
+
+// currentRevision returns the latest etcd revision seen, asking etcd if none has been seen yet.
+func (e *Etcd) currentRevision() int64 {
+	if e.cache != nil && e.cache.synced() {
+		return e.cache.currentRevision()
+	}
+	if rev := e.revision.Load(); rev > 0 || e.Client == nil {
+		return rev
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	r, err := e.Client.Get(ctx, "/"+e.PathPrefix+"/", etcdcv3.WithPrefix(), etcdcv3.WithCountOnly())
+	if err != nil {
+		return 0
+	}
+	e.seenRevision(r.Header.Revision)
+	return r.Header.Revision
+}
+
+// seenRevision records rev as the latest revision seen, unless a later one was seen already.
+func (e *Etcd) seenRevision(rev int64) {
+	for {
+		old := e.revision.Load()
+		if rev <= old || e.revision.CompareAndSwap(old, rev) {
+			return
+		}
+	}
+}
+
+// Transfer implements the transfer.Transferer interface.
+func (e *Etcd) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	if plugin.Zones(e.Zones).Matches(zone) != zone {
+		return nil, transfer.ErrNotAuthoritative
+	}
+
+	kvs, rev, err := e.zone(zone)
+	if err != nil {
+		return nil, err
+	}
+	rrs := e.zoneRecords(zone, kvs)
+
+	// state is not used here, hence the empty request.Request{}
+	soa, _ := plugin.SOA(context.TODO(), e, zone, request.Request{}, plugin.Options{})
+	soa[0].(*dns.SOA).Serial = uint32(rev)
+
+	ch := make(chan []dns.RR)
+	go func() {
+		defer close(ch)
+		// ixfr fallback
+		if serial != 0 && !lessSerial(serial, uint32(rev)) {
+			ch <- soa
+			return
+		}
+		ch <- soa
+		for _, rrset := range rrs {
+			ch <- rrset
+		}
+		ch <- soa
+	}()
+	return ch, nil
+}
+
+// zonePath returns the path of the keys of zone.
+func (e *Etcd) zonePath(zone string) string {
+	path := msg.Path(zone, e.PathPrefix)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return path
+}
+
+// zone returns all keys of zone and its serial: the revision of its last change with the cache, otherwise
+// the revision the keys were read at.
+func (e *Etcd) zone(zone string) ([]*mvccpb.KeyValue, int64, error) {
+	path := e.zonePath(zone)
+	if e.cache != nil && e.cache.synced() {
+		kvs, rev := e.cache.snapshot(path)
+		return kvs, rev, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	r, err := e.Client.Get(ctx, path, etcdcv3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+	e.seenRevision(r.Header.Revision)
+	return r.Kvs, r.Header.Revision, nil
+}
+
+// zoneRecords converts the keys of zone to RRsets. The NS RRset of the zone is first, it is synthesized from
+// the address records under ns.dns.<zone>, the same as for NS queries.
+func (e *Etcd) zoneRecords(zone string, kvs []*mvccpb.KeyValue) [][]dns.RR {
+	nsName := dnsutil.Join("ns.dns", zone)
+	reverse := dnsutil.IsReverse(zone) > 0
+
+	var rrs, ns []dns.RR
+	for _, kv := range kvs {
+		name := msg.Domain(string(kv.Key))
+		if plugin.Zones(e.Zones).Matches(name) != zone {
+			// In a zone below this one.
+			continue
+		}
+		serv := new(msg.Service)
+		if err := json.Unmarshal(kv.Value, serv); err != nil {
+			log.Warningf("Skipping %s in transfer of %s: %s", kv.Key, zone, err)
+			continue
+		}
+		serv.Key = string(kv.Key)
+		serv.TTL = e.TTL(kv, serv)
+		if serv.Priority == 0 {
+			serv.Priority = defaultPriority
+		}
+
+		if serv.Text != "" {
+			rrs = append(rrs, serv.NewTXT(name))
+		}
+		if serv.Host == "" {
+			continue
+		}
+
+		what, ip := serv.HostType()
+		switch what {
+		case dns.TypeA:
+			rrs = append(rrs, serv.NewA(name, ip))
+		case dns.TypeAAAA:
+			rrs = append(rrs, serv.NewAAAA(name, ip))
+		}
+		if what != dns.TypeCNAME && dns.IsSubDomain(nsName, name) {
+			ns = append(ns, &dns.NS{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: serv.TTL}, Ns: name})
+		}
+
+		// Services with an address are the target of the SRV and MX records at their own name.
+		target := *serv
+		if what != dns.TypeCNAME {
+			target.Host = name
+		}
+		switch {
+		case serv.Mail:
+			rrs = append(rrs, target.NewMX(name))
+		case serv.Port > 0:
+			rrs = append(rrs, target.NewSRV(name, 100))
+		case what == dns.TypeCNAME && reverse:
+			rrs = append(rrs, serv.NewPTR(name, serv.Host))
+		case what == dns.TypeCNAME:
+			rrs = append(rrs, serv.NewCNAME(name, serv.Host))
+		}
+	}
+
+	if len(ns) == 0 {
+		ns = append(ns, &dns.NS{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: defaultTTL}, Ns: nsName})
+	}
+	return append([][]dns.RR{dedup(ns)}, rrsets(rrs)...)
+}
+
+// rrsets groups rrs into RRsets, sorted by name and type.
+func rrsets(rrs []dns.RR) [][]dns.RR {
+	sort.SliceStable(rrs, func(i, j int) bool {
+		hi, hj := rrs[i].Header(), rrs[j].Header()
+		if hi.Name != hj.Name {
+			return hi.Name < hj.Name
+		}
+		return hi.Rrtype < hj.Rrtype
+	})
+
+	var sets [][]dns.RR
+	for i := 0; i < len(rrs); {
+		j := i + 1
+		for j < len(rrs) && rrs[j].Header().Name == rrs[i].Header().Name && rrs[j].Header().Rrtype == rrs[i].Header().Rrtype {
+			j++
+		}
+		sets = append(sets, dedup(rrs[i:j]))
+		i = j
+	}
+	return sets
+}
+
+// dedup removes duplicate records from rrset, and sets the TTL of all records to the lowest one.
+func dedup(rrset []dns.RR) []dns.RR {
+	ttl := rrset[0].Header().Ttl
+	out := make([]dns.RR, 0, len(rrset))
+Records:
+	for _, rr := range rrset {
+		for _, o := range out {
+			if dns.IsDuplicate(rr, o) {
+				continue Records
+			}
+		}
+		ttl = min(ttl, rr.Header().Ttl)
+		out = append(out, rr)
+	}
+	for _, rr := range out {
+		rr.Header().Ttl = ttl
+	}
+	return out
+}
+
+// lessSerial returns true if serial a is older than b, using serial number arithmetic (RFC 1982).
+func lessSerial(a, b uint32) bool {
+	return a != b && int32(b-a) > 0
+}
+
+// notify sends notifies for the zones that have a key in keys.
+func (e *Etcd) notify(keys []string) {
+	if e.transfer == nil {
+		return
+	}
+	zones := map[string]struct{}{}
+	for _, key := range keys {
+		if zone := plugin.Zones(e.Zones).Matches(msg.Domain(key)); zone != "" {
+			zones[zone] = struct{}{}
+		}
+	}
+	for zone := range zones {
+		if err := e.transfer.Notify(zone); err != nil {
+			log.Warningf("Failed to send notifies for %s: %s", zone, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package etcd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+func newTransferPlugin(t *testing.T, services map[string]*msg.Service) *Etcd {
+	t.Helper()
+	e := &Etcd{
+		PathPrefix: "skydns",
+		Zones:      []string{"skydns.test.", "sub.skydns.test."},
+		cache:      newCache(nil, "skydns"),
+	}
+	var kvs []*mvccpb.KeyValue
+	for name, s := range services {
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kvs = append(kvs, &mvccpb.KeyValue{Key: []byte(msg.Path(name, e.PathPrefix)), Value: b, ModRevision: 42})
+	}
+	e.cache.reset(kvs, 42)
+	return e
+}
+
+func TestTransferAXFR(t *testing.T) {
+	e := newTransferPlugin(t, map[string]*msg.Service{
+		"a.skydns.test.":       {Host: "10.0.0.1"},
+		"b.skydns.test.":       {Host: "10.0.0.2", Port: 8080},
+		"c.skydns.test.":       {Host: "a.skydns.test."},
+		"d.skydns.test.":       {Text: "hello"},
+		"mx.skydns.test.":      {Host: "mail.example.org", Mail: true},
+		"x.ns.dns.skydns.test": {Host: "10.0.0.53"},
+		"a.sub.skydns.test.":   {Host: "10.0.1.1"},
+	})
+
+	if _, err := e.Transfer("example.org.", 0); err != transfer.ErrNotAuthoritative {
+		t.Fatalf("Expected ErrNotAuthoritative, got %v", err)
+	}
+
+	ch, err := e.Transfer("skydns.test.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrs []dns.RR
+	for rrset := range ch {
+		rrs = append(rrs, rrset...)
+	}
+
+	expected := []string{
+		"skydns.test.	30	IN	SOA	ns.dns.skydns.test. hostmaster.skydns.test. 42 7200 1800 86400 30",
+		"skydns.test.	300	IN	NS	x.ns.dns.skydns.test.",
+		"a.skydns.test.	300	IN	A	10.0.0.1",
+		"b.skydns.test.	300	IN	A	10.0.0.2",
+		"b.skydns.test.	300	IN	SRV	10 100 8080 b.skydns.test.",
+		"c.skydns.test.	300	IN	CNAME	a.skydns.test.",
+		"d.skydns.test.	300	IN	TXT	\"hello\"",
+		"mx.skydns.test.	300	IN	MX	10 mail.example.org.",
+		"x.ns.dns.skydns.test.	300	IN	A	10.0.0.53",
+		"skydns.test.	30	IN	SOA	ns.dns.skydns.test. hostmaster.skydns.test. 42 7200 1800 86400 30",
+	}
+	if len(rrs) != len(expected) {
+		t.Fatalf("Expected %d records, got %d: %v", len(expected), len(rrs), rrs)
+	}
+	for i := range rrs {
+		if rrs[i].String() != expected[i] {
+			t.Errorf("Record %d: expected %q, got %q", i, expected[i], rrs[i].String())
+		}
+	}
+}
+
+func TestTransferNSSynthesis(t *testing.T) {
+	e := newTransferPlugin(t, map[string]*msg.Service{
+		"a.sub.skydns.test.": {Host: "10.0.1.1"},
+	})
+
+	ch, err := e.Transfer("sub.skydns.test.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrs []dns.RR
+	for rrset := range ch {
+		rrs = append(rrs, rrset...)
+	}
+	if len(rrs) != 4 {
+		t.Fatalf("Expected 4 records, got %d: %v", len(rrs), rrs)
+	}
+	ns, ok := rrs[1].(*dns.NS)
+	if !ok || ns.Ns != "ns.dns.sub.skydns.test." {
+		t.Errorf("Expected synthesized NS record, got %v", rrs[1])
+	}
+}
+
+func TestTransferIXFR(t *testing.T) {
+	e := newTransferPlugin(t, map[string]*msg.Service{
+		"a.skydns.test.": {Host: "10.0.0.1"},
+	})
+
+	tests := []struct {
+		serial   uint32
+		expected int
+	}{
+		{42, 1}, // up to date
+		{50, 1}, // newer
+		{41, 4}, // older, AXFR fallback
+		{0, 4},  // AXFR
+	}
+	for i, tc := range tests {
+		ch, err := e.Transfer("skydns.test.", tc.serial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for rrset := range ch {
+			n += len(rrset)
+		}
+		if n != tc.expected {
+			t.Errorf("Test %d: expected %d records, got %d", i, tc.expected, n)
+		}
+	}
+}
+
+func TestSerial(t *testing.T) {
+	e := newTransferPlugin(t, nil)
+	if s := e.Serial(request.Request{}); s != 42 {
+		t.Errorf("Expected serial 42, got %d", s)
+	}
+	e.cache.apply([]*etcdcv3.Event{put("/skydns/test/skydns/a", `{"host":"10.0.0.1"}`)}, 43)
+	if s := e.Serial(request.Request{}); s != 43 {
+		t.Errorf("Expected serial 43, got %d", s)
+	}
+}
+
+func TestSerialZone(t *testing.T) {
+	e := newTransferPlugin(t, map[string]*msg.Service{
+		"a.skydns.test.":     {Host: "10.0.0.1"},
+		"a.sub.skydns.test.": {Host: "10.0.1.1"},
+	})
+	zone := request.Request{Zone: "sub.skydns.test."}
+	if s := e.Serial(zone); s != 42 {
+		t.Errorf("Expected serial 42, got %d", s)
+	}
+
+	// A change in another zone doesn't change the serial.
+	ev := put("/skydns/test/skydns/b", `{"host":"10.0.0.2"}`)
+	ev.Kv.ModRevision = 43
+	e.cache.apply([]*etcdcv3.Event{ev}, 43)
+	if s := e.Serial(zone); s != 42 {
+		t.Errorf("Expected serial 42 after a change in another zone, got %d", s)
+	}
+
+	// Deleting a key does, even though no key in the zone has a later revision.
+	ev = del("/skydns/test/skydns/sub/a")
+	ev.Kv.ModRevision = 44
+	e.cache.apply([]*etcdcv3.Event{ev}, 44)
+	if s := e.Serial(zone); s != 44 {
+		t.Errorf("Expected serial 44 after a delete, got %d", s)
+	}
+
+	// As do keys that are gone after a resync.
+	kvs, _ := e.cache.snapshot("/skydns/test/skydns/")
+	var keep []*mvccpb.KeyValue
+	for _, kv := range kvs {
+		if string(kv.Key) != "/skydns/test/skydns/b" {
+			keep = append(keep, kv)
+		}
+	}
+	e.cache.reset(keep, 50)
+	if s := e.Serial(request.Request{Zone: "skydns.test."}); s != 50 {
+		t.Errorf("Expected serial 50 after a key was gone in a resync, got %d", s)
+	}
+}
+
+func TestLessSerial(t *testing.T) {
+	tests := []struct {
+		a, b     uint32
+		expected bool
+	}{
+		{1, 2, true},
+		{2, 1, false},
+		{2, 2, false},
+		{0xffffffff, 1, true},
+		{1, 0xffffffff, false},
+	}
+	for i, tc := range tests {
+		if got := lessSerial(tc.a, tc.b); got != tc.expected {
+			t.Errorf("Test %d: expected %t for %d < %d, got %t", i, tc.expected, tc.a, tc.b, got)
+		}
+	}
+}
@@ -54,6 +54,10 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeServerFailure, nil
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		return f.update(ctx, state, z)
+	}
+
 	// If transfer is not loaded, we'll see these, answer with refused (no transfer allowed).
 	if state.QType() == dns.TypeAXFR || state.QType() == dns.TypeIXFR {
 		return dns.RcodeRefused, nil
@@ -143,8 +147,13 @@ func (s *serialErr) Error() string {
 
 // Parse parses the zone in filename and returns a new Zone or an error.
 // If serial >= 0 it will reload the zone, if the SOA hasn't changed
-// it returns an error indicating nothing was read.
+// it returns an error indicating nothing was read. The format of the
+// zone is detected by the extension of filename, see FormatOf.
 func Parse(f io.Reader, origin, fileName string, serial int64) (*Zone, error) {
+	if format := FormatOf(fileName); format != RFC1035 {
+		return parseZone(f, origin, fileName, format, serial)
+	}
+
 	zp := dns.NewZoneParser(f, dns.Fqdn(origin), fileName)
 	zp.SetIncludeAllowed(true)
 	z := NewZone(origin, fileName)
@@ -179,3 +188,21 @@ func Parse(f io.Reader, origin, fileName string, serial int64) (*Zone, error) {
 
 	return z, nil
 }
+
+// parseZone parses the structured zone in filename, written in format, like Parse.
+func parseZone(f io.Reader, origin, fileName string, format Format, serial int64) (*Zone, error) {
+	rrs, err := parseStructured(f, origin, fileName, format)
+	if err != nil {
+		return nil, err
+	}
+	if s := rrs[0].(*dns.SOA); serial >= 0 && s.Serial == uint32(serial) {
+		return nil, &serialErr{err: "no change in SOA serial", origin: origin, zone: fileName, serial: serial}
+	}
+	z := NewZone(origin, fileName)
+	for _, rr := range rrs {
+		if err := z.Insert(rr); err != nil {
+			return nil, err
+		}
+	}
+	return z, nil
+}
@@ -0,0 +1,89 @@
+package file
+
+import (
+	"github.com/miekg/dns"
+)
+
+// DefaultIXFRHistory is the number of changed records kept for incremental transfers when ixfr is
+// enabled without a number.
+const DefaultIXFRHistory = 10000
+
+// swap replaces the records of z with the ones of z1, the result of the changes in diffs, and adds
+// these changes to the history of z.
+func (z *Zone) swap(z1 *Zone, diffs ...diff) {
+	z.Lock()
+	z.Apex = z1.Apex
+	z.Tree = z1.Tree
+	z.NSEC3 = z1.NSEC3
+	if len(diffs) == 0 {
+		z.history, z.historySize = nil, 0
+	}
+	for _, d := range diffs {
+		z.record(d)
+	}
+	z.Unlock()
+}
+
+// record adds d to the history of z. The oldest changes are dropped when the history holds more than
+// z.IXFRHistory records. The caller must hold the write lock.
+func (z *Zone) record(d diff) {
+	if z.IXFRHistory <= 0 {
+		return
+	}
+	if len(z.history) > 0 && z.history[len(z.history)-1].newSOA.Serial != d.oldSOA.Serial {
+		z.history, z.historySize = nil, 0
+	}
+	n := len(d.deleted) + len(d.added)
+	if n > z.IXFRHistory {
+		z.history, z.historySize = nil, 0
+		return
+	}
+
+	z.history = append(z.history, d)
+	z.historySize += n
+	for z.historySize > z.IXFRHistory {
+		z.historySize -= len(z.history[0].deleted) + len(z.history[0].added)
+		z.history = z.history[1:]
+	}
+}
+
+// ixfr returns the current SOA of z and the changes since the version with serial. If the history
+// doesn't go back to serial, nil is returned.
+func (z *Zone) ixfr(serial uint32) (*dns.SOA, []diff) {
+	z.RLock()
+	defer z.RUnlock()
+	if z.SOA == nil || len(z.history) == 0 || z.history[len(z.history)-1].newSOA.Serial != z.SOA.Serial {
+		return nil, nil
+	}
+	for i, d := range z.history {
+		if d.oldSOA.Serial == serial {
+			return z.SOA, append([]diff(nil), z.history[i:]...)
+		}
+	}
+	return nil, nil
+}
+
+// changes returns the change from z to z1 for the history of z, if the history is kept.
+func (z *Zone) changes(z1 *Zone) []diff {
+	if z.IXFRHistory <= 0 || z1.SOA == nil {
+		return nil
+	}
+	z.RLock()
+	if z.SOA == nil {
+		z.RUnlock()
+		return nil
+	}
+	old := z.rrsets()
+	z.RUnlock()
+	return []diff{old.diff(z1.rrsets(), z.origin)}
+}
+
+// rrs returns the records of d, as sent in an incremental transfer: the old SOA, the deleted records, the
+// new SOA and the added records.
+func (d diff) rrs() []dns.RR {
+	rrs := make([]dns.RR, 0, len(d.deleted)+len(d.added)+2)
+	rrs = append(rrs, d.oldSOA)
+	rrs = append(rrs, d.deleted...)
+	rrs = append(rrs, d.newSOA)
+	return append(rrs, d.added...)
+}
@@ -0,0 +1,209 @@
+package file
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// transferAll returns all records z sends in a transfer for serial.
+func transferAll(t *testing.T, z *Zone, serial uint32) []dns.RR {
+	t.Helper()
+	ch, err := z.Transfer(serial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rrs := []dns.RR{}
+	for x := range ch {
+		rrs = append(rrs, x...)
+	}
+	return rrs
+}
+
+func serials(rrs []dns.RR) []uint32 {
+	s := []uint32{}
+	for _, rr := range rrs {
+		if soa, ok := rr.(*dns.SOA); ok {
+			s = append(s, soa.Serial)
+		}
+	}
+	return s
+}
+
+func TestTransferIXFR(t *testing.T) {
+	z, _ := newUpdateZone(t)
+	z.IXFRHistory = 10
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.RemoveRRset([]dns.RR{test.A("dyn.example.org. 0 IN A 192.0.2.20")})
+	m.Insert([]dns.RR{test.A("dyn.example.org. 300 IN A 192.0.2.21")})
+	if rcode := update(t, z, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+	m = new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.AAAA("host.example.org. 300 IN AAAA 2001:db8::1")})
+	if rcode := update(t, z, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+
+	tests := []struct {
+		serial  uint32
+		serials []uint32
+		records int
+	}{
+		{1000, []uint32{1002, 1000, 1001, 1001, 1002, 1002}, 9},
+		{1001, []uint32{1002, 1001, 1002, 1002}, 5},
+		{1002, []uint32{1002}, 1},
+		{999, []uint32{1002, 1002}, 8}, // not in the history, AXFR fallback
+	}
+	for _, tc := range tests {
+		rrs := transferAll(t, z, tc.serial)
+		if s := serials(rrs); len(s) != len(tc.serials) {
+			t.Errorf("Serial %d: expected SOA serials %v, got %v", tc.serial, tc.serials, s)
+		} else {
+			for i := range s {
+				if s[i] != tc.serials[i] {
+					t.Errorf("Serial %d: expected SOA serials %v, got %v", tc.serial, tc.serials, s)
+					break
+				}
+			}
+		}
+		if len(rrs) != tc.records {
+			t.Errorf("Serial %d: expected %d records, got %d: %v", tc.serial, tc.records, len(rrs), rrs)
+		}
+	}
+}
+
+func TestIXFRHistoryBound(t *testing.T) {
+	z, _ := newUpdateZone(t)
+	z.IXFRHistory = 2
+
+	for _, ip := range []string{"192.0.2.30", "192.0.2.31", "192.0.2.32"} {
+		m := new(dns.Msg)
+		m.SetUpdate("example.org.")
+		m.Insert([]dns.RR{test.A("host.example.org. 300 IN A " + ip)})
+		if rcode := update(t, z, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+			t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+		}
+	}
+
+	if soa, _ := z.ixfr(1000); soa != nil {
+		t.Errorf("Expected the change from 1000 to be dropped from the history")
+	}
+	if soa, diffs := z.ixfr(1001); soa == nil || len(diffs) != 2 {
+		t.Errorf("Expected 2 changes from 1001, got %d", len(diffs))
+	}
+
+	// A change larger than the history clears it.
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.RemoveName([]dns.RR{test.A("host.example.org. 0 IN A 192.0.2.30")})
+	z.UpdatePolicies[0].Types = nil
+	if rcode := update(t, z, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+	if soa, _ := z.ixfr(1003); soa != nil {
+		t.Errorf("Expected the history to be cleared")
+	}
+}
+
+func TestReloadChanges(t *testing.T) {
+	z, err := Parse(strings.NewReader(dbUpdateTest), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.IXFRHistory = 10
+
+	z1, err := Parse(strings.NewReader(strings.Replace(strings.Replace(dbUpdateTest, " 1000 ", " 1001 ", 1), "192.0.2.20", "192.0.2.21", 1)), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs := z.changes(z1)
+	if len(diffs) != 1 {
+		t.Fatalf("Expected 1 change, got %d", len(diffs))
+	}
+	d := diffs[0]
+	if d.oldSOA.Serial != 1000 || d.newSOA.Serial != 1001 {
+		t.Errorf("Expected change from 1000 to 1001, got from %d to %d", d.oldSOA.Serial, d.newSOA.Serial)
+	}
+	if len(d.deleted) != 1 || len(d.added) != 1 {
+		t.Errorf("Expected 1 deleted and 1 added record, got %v and %v", d.deleted, d.added)
+	}
+}
+
+func TestTransferInIXFR(t *testing.T) {
+	primary, _ := newUpdateZone(t)
+	primary.IXFRHistory = 10
+
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		var serial uint32
+		if r.Question[0].Qtype == dns.TypeIXFR {
+			serial = r.Ns[0].(*dns.SOA).Serial
+		}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = transferAll(t, primary, serial)
+		w.WriteMsg(m)
+	})
+	defer s.Close()
+
+	secondary, err := Parse(strings.NewReader(dbUpdateTest), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondary.TransferFrom = []string{s.Addr}
+	secondary.IXFRHistory = 10
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.RemoveRRset([]dns.RR{test.A("dyn.example.org. 0 IN A 192.0.2.20")})
+	m.Insert([]dns.RR{test.A("dyn.example.org. 300 IN A 192.0.2.21")})
+	if rcode := update(t, primary, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+
+	if err := secondary.TransferIn(); err != nil {
+		t.Fatal(err)
+	}
+	if secondary.SOA.Serial != 1001 {
+		t.Errorf("Expected SOA serial 1001, got %d", secondary.SOA.Serial)
+	}
+	if answer := lookup(secondary, "dyn.example.org.", dns.TypeA); len(answer) != 1 || answer[0].(*dns.A).A.String() != "192.0.2.21" {
+		t.Errorf("Expected updated A record, got %v", answer)
+	}
+	if answer := lookup(secondary, "www.example.org.", dns.TypeA); len(answer) != 1 {
+		t.Errorf("Expected unchanged A record, got %v", answer)
+	}
+	// The secondary can serve the change incrementally as well.
+	if soa, diffs := secondary.ixfr(1000); soa == nil || len(diffs) != 1 {
+		t.Errorf("Expected the change from 1000 in the history of the secondary")
+	}
+
+	// Without the history on the primary, the whole zone is transferred.
+	m = new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.AAAA("host.example.org. 300 IN AAAA 2001:db8::1")})
+	if rcode := update(t, primary, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+	primary.history = nil
+
+	if err := secondary.TransferIn(); err != nil {
+		t.Fatal(err)
+	}
+	if secondary.SOA.Serial != 1002 {
+		t.Errorf("Expected SOA serial 1002, got %d", secondary.SOA.Serial)
+	}
+	if answer := lookup(secondary, "host.example.org.", dns.TypeAAAA); len(answer) != 1 {
+		t.Errorf("Expected added AAAA record, got %v", answer)
+	}
+	if soa, diffs := secondary.ixfr(1001); soa == nil || len(diffs) != 1 {
+		t.Errorf("Expected the change from 1001 in the history of the secondary")
+	}
+}
@@ -0,0 +1,255 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// diff is a change to a zone, from the version with oldSOA to the one with newSOA.
+type diff struct {
+	oldSOA  *dns.SOA
+	deleted []dns.RR
+	newSOA  *dns.SOA
+	added   []dns.RR
+}
+
+// journal returns the path of the journal of z, where updates are recorded until they are written back
+// to the zone file.
+func (z *Zone) journal() string { return z.File() + ".jnl" }
+
+// appendJournal appends d to the journal of z. Like in an IXFR (RFC 1995), each change is written as the
+// old SOA, the deleted records, the new SOA and the added records.
+func (z *Zone) appendJournal(d diff) error {
+	f, err := os.OpenFile(z.journal(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, rr := range d.rrs() {
+		fmt.Fprintln(w, rr.String())
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readJournal reads the changes in the journal from r.
+func readJournal(r io.Reader, fileName string) ([]diff, error) {
+	zp := dns.NewZoneParser(r, ".", fileName)
+	rrs := []dns.RR{}
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	diffs, err := parseDiffs(rrs)
+	if err != nil {
+		return nil, fmt.Errorf("journal %q %s", fileName, err)
+	}
+	return diffs, nil
+}
+
+// parseDiffs parses a sequence of changes, each is the old SOA, the deleted records, the new SOA and the
+// added records. This is also the format of the changes in an incremental transfer.
+func parseDiffs(rrs []dns.RR) ([]diff, error) {
+	var (
+		diffs []diff
+		d     *diff
+	)
+	for _, rr := range rrs {
+		soa, isSOA := rr.(*dns.SOA)
+		switch {
+		case isSOA && (d == nil || d.newSOA != nil):
+			if d != nil {
+				diffs = append(diffs, *d)
+			}
+			d = &diff{oldSOA: soa}
+		case isSOA:
+			d.newSOA = soa
+		case d == nil:
+			return nil, fmt.Errorf("does not start with a SOA record")
+		case d.newSOA == nil:
+			d.deleted = append(d.deleted, rr)
+		default:
+			d.added = append(d.added, rr)
+		}
+	}
+	if d != nil {
+		if d.newSOA == nil {
+			return nil, fmt.Errorf("ends with an incomplete change")
+		}
+		diffs = append(diffs, *d)
+	}
+	return diffs, nil
+}
+
+// apply applies the change d to s. The records in d are normalized.
+func (s rrsets) apply(origin string, d diff) {
+	for _, rr := range d.deleted {
+		normalize(rr)
+		s.remove(strings.ToLower(rr.Header().Name), rr.Header().Rrtype, func(r dns.RR) bool { return dns.IsDuplicate(r, rr) })
+	}
+	for _, rr := range d.added {
+		normalize(rr)
+		s.add(rr)
+	}
+	normalize(d.newSOA)
+	s[origin][dns.TypeSOA] = []dns.RR{d.newSOA}
+}
+
+// replayJournal applies the changes in the journal of z, that were not yet written back to the zone file.
+func (z *Zone) replayJournal() error {
+	f, err := os.Open(filepath.Clean(z.journal()))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	diffs, err := readJournal(f, z.journal())
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	z.RLock()
+	if z.SOA == nil {
+		z.RUnlock()
+		return nil
+	}
+	s := z.rrsets()
+	z.RUnlock()
+
+	applied := []diff{}
+	for _, d := range diffs {
+		soa := s[z.origin][dns.TypeSOA][0].(*dns.SOA)
+		if d.oldSOA.Serial != soa.Serial {
+			continue
+		}
+		s.apply(z.origin, d)
+		applied = append(applied, d)
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+	if err := z.set(s, applied...); err != nil {
+		return err
+	}
+	z.dirty = true
+
+	log.Infof("Replayed %d changes from journal %q for zone %q, SOA serial is %d", len(applied), z.journal(), z.origin, z.SOASerialIfDefined())
+	return nil
+}
+
+// WriteBackUpdates writes the zone to its zone file every z.WriteBack, when it has been updated. If
+// z.WriteBack is zero, no write-back is done.
+func (z *Zone) WriteBackUpdates() {
+	if z.WriteBack == 0 || len(z.UpdatePolicies) == 0 {
+		return
+	}
+	tick := time.NewTicker(z.WriteBack)
+
+	go func() {
+		for {
+			select {
+			case <-tick.C:
+				if err := z.writeBack(); err != nil {
+					log.Errorf("Failed to write back zone %q to %q: %s", z.origin, z.File(), err)
+				}
+
+			case <-z.writeBackShutdown:
+				tick.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// writeBack writes z to its zone file, when it has updates that aren't written back yet. The journal is
+// removed afterwards.
+func (z *Zone) writeBack() error {
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+	if !z.dirty {
+		return nil
+	}
+
+	zFile := z.File()
+	f, err := os.CreateTemp(filepath.Dir(zFile), filepath.Base(zFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if fi, err := os.Stat(zFile); err == nil {
+		f.Chmod(fi.Mode())
+	}
+
+	if err := z.Export(f, FormatOf(zFile)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), zFile); err != nil {
+		return err
+	}
+	if err := os.Remove(z.journal()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	z.dirty = false
+
+	log.Infof("Wrote back zone %q to %q with %d SOA serial", z.origin, zFile, z.SOASerialIfDefined())
+	return nil
+}
+
+// print writes the records of z in the presentation format to w.
+func (z *Zone) print(w io.Writer) error {
+	apex, err := z.ApexIfDefined()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, rr := range apex {
+		fmt.Fprintln(bw, rr.String())
+	}
+
+	z.RLock()
+	write := func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			fmt.Fprintln(bw, rr.String())
+		}
+		return nil
+	}
+	z.Walk(write)
+	if z.NSEC3 != nil {
+		z.NSEC3.Walk(write)
+	}
+	z.RUnlock()
+
+	return bw.Flush()
+}
@@ -40,6 +40,10 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 	z.RLock()
 	ap := z.Apex
 	tr := z.Tree
+	var n3 *nsec3
+	if z.NSEC3 != nil {
+		n3 = &nsec3{Tree: z.NSEC3, origin: z.origin}
+	}
 	z.RUnlock()
 	if ap.SOA == nil {
 		return nil, nil, nil, ServerFailure
@@ -163,6 +167,9 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 			if do {
 				dss := typeFromElem(elem, dns.TypeDS, do)
 				nsrrs = append(nsrrs, dss...)
+				if len(dss) == 0 && n3 != nil {
+					nsrrs = append(nsrrs, n3.nodata(elem.Name())...)
+				}
 			}
 
 			return nil, nsrrs, glue, Delegation
@@ -188,7 +195,9 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 		// NODATA
 		if len(rrs) == 0 {
 			ret := ap.soa(do)
-			if do {
+			if do && n3 != nil {
+				ret = append(ret, n3.nodata(qname)...)
+			} else if do {
 				nsec := typeFromElem(elem, dns.TypeNSEC, do)
 				ret = append(ret, nsec...)
 			}
@@ -227,7 +236,9 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 		// NODATA response.
 		if len(rrs) == 0 {
 			ret := ap.soa(do)
-			if do {
+			if do && n3 != nil {
+				ret = append(ret, n3.wildcardNodata(qname, wildElem.Name()[2:])...)
+			} else if do {
 				nsec := typeFromElem(wildElem, dns.TypeNSEC, do)
 				ret = append(ret, nsec...)
 			}
@@ -237,7 +248,9 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 		auth := ap.ns(do)
 		if do {
 			// An NSEC is needed to say no longer name exists under this wildcard.
-			if deny, found := tr.Prev(qname); found {
+			if n3 != nil {
+				auth = append(auth, n3.wildcard(qname, wildElem.Name()[2:])...)
+			} else if deny, found := tr.Prev(qname); found {
 				nsec := typeFromElem(deny, dns.TypeNSEC, do)
 				auth = append(auth, nsec...)
 			}
@@ -260,7 +273,13 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 	}
 
 	ret := ap.soa(do)
-	if do {
+	if do && n3 != nil {
+		if rcode == NameError {
+			ret = append(ret, n3.nxdomain(qname)...)
+		} else {
+			ret = append(ret, n3.nodata(qname)...)
+		}
+	} else if do {
 		deny, found := tr.Prev(qname)
 		if !found {
 			goto Out
@@ -0,0 +1,132 @@
+package file
+
+import (
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// nsec3 is the NSEC3 chain (RFC 5155) of a zone. The NSEC3 records and their signatures are stored by their
+// hashed owner name, separate from the other records in the zone.
+type nsec3 struct {
+	*tree.Tree
+	origin string
+}
+
+// insertNSEC3 inserts r, an NSEC3 record or its signature, into the NSEC3 chain of z.
+func (z *Zone) insertNSEC3(r dns.RR) {
+	if z.NSEC3 == nil {
+		z.NSEC3 = &tree.Tree{}
+	}
+	z.NSEC3.Insert(r)
+}
+
+// hash returns the hashed owner name of the NSEC3 record for name, using the parameters of the chain.
+func (n nsec3) hash(name string) string {
+	e := n.Min()
+	if e == nil || len(e.Type(dns.TypeNSEC3)) == 0 {
+		return ""
+	}
+	p := e.Type(dns.TypeNSEC3)[0].(*dns.NSEC3)
+	return strings.ToLower(dns.HashName(name, p.Hash, p.Iterations, p.Salt)) + "." + n.origin
+}
+
+// match returns the NSEC3 record, and its signatures, matching name. If there is none, nil is returned.
+func (n nsec3) match(name string) []dns.RR {
+	e, found := n.Search(n.hash(name))
+	if !found {
+		return nil
+	}
+	return typeFromElem(e, dns.TypeNSEC3, true)
+}
+
+// cover returns the NSEC3 record, and its signatures, covering name.
+func (n nsec3) cover(name string) []dns.RR {
+	e, found := n.Prev(n.hash(name))
+	if !found {
+		e = n.Max() // wrap around, the last NSEC3 record covers the hashes before the first one
+	}
+	if e == nil {
+		return nil
+	}
+	return typeFromElem(e, dns.TypeNSEC3, true)
+}
+
+// closestEncloser returns the closest provable encloser of qname: the longest ancestor of qname that has a
+// matching NSEC3 record. The records returned prove this: the NSEC3 matching the closest encloser and the
+// one covering the next closer name, see RFC 5155 section 7.2.1.
+func (n nsec3) closestEncloser(qname string) (string, []dns.RR) {
+	labels := dns.Split(qname)
+	for i := 1; i < len(labels); i++ {
+		ce := qname[labels[i]:]
+		if !dns.IsSubDomain(n.origin, ce) {
+			break
+		}
+		if m := n.match(ce); m != nil {
+			return ce, dedup(m, n.cover(qname[labels[i-1]:]))
+		}
+	}
+	return n.origin, n.match(n.origin)
+}
+
+// nodata returns the NSEC3 records proving name exists, but doesn't have the queried type. If there is no
+// matching NSEC3 record, the name is covered by an opt-out NSEC3 record and the closest encloser proof is
+// returned, see RFC 5155 section 7.2.3 and 7.2.4.
+func (n nsec3) nodata(name string) []dns.RR {
+	if m := n.match(name); m != nil {
+		return m
+	}
+	_, rrs := n.closestEncloser(name)
+	return rrs
+}
+
+// nxdomain returns the NSEC3 records proving qname doesn't exist: the closest encloser proof and the
+// NSEC3 record covering the wildcard at the closest encloser, see RFC 5155 section 7.2.2.
+func (n nsec3) nxdomain(qname string) []dns.RR {
+	ce, rrs := n.closestEncloser(qname)
+	return dedup(rrs, n.cover("*."+ce))
+}
+
+// wildcard returns the NSEC3 record proving qname doesn't exist, so it is answered from the wildcard
+// at ce, see RFC 5155 section 7.2.6.
+func (n nsec3) wildcard(qname, ce string) []dns.RR {
+	return n.cover(nextCloser(qname, ce))
+}
+
+// wildcardNodata returns the NSEC3 records proving qname is answered from the wildcard at ce, which doesn't
+// have the queried type, see RFC 5155 section 7.2.5.
+func (n nsec3) wildcardNodata(qname, ce string) []dns.RR {
+	return dedup(n.match(ce), n.cover(nextCloser(qname, ce)), n.match("*."+ce))
+}
+
+// nextCloser returns the name one label longer than the closest encloser ce of qname.
+func nextCloser(qname, ce string) string {
+	labels := dns.Split(qname)
+	i := len(labels) - dns.CountLabel(ce) - 1
+	if i < 0 {
+		return qname
+	}
+	return qname[labels[i]:]
+}
+
+// dedup returns the records in sets, without the records that have already been seen.
+func dedup(sets ...[]dns.RR) []dns.RR {
+	rrs := []dns.RR{}
+	for _, set := range sets {
+		for _, rr := range set {
+			dup := false
+			for _, r := range rrs {
+				if dns.IsDuplicate(r, rr) {
+					dup = true
+					break
+				}
+			}
+			if !dup {
+				rrs = append(rrs, rr)
+			}
+		}
+	}
+	return rrs
+}
@@ -1,24 +1,142 @@
 package file
 
 import (
+	"context"
 	"strings"
 	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
 )
 
 func TestParseNSEC3PARAM(t *testing.T) {
-	_, err := Parse(strings.NewReader(nsec3paramTest), "miek.nl", "stdin", 0)
-	if err == nil {
-		t.Fatalf("Expected error when reading zone, got nothing")
+	z, err := Parse(strings.NewReader(nsec3paramTest), "miek.nl", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	if apex, _ := z.Search("miek.nl."); apex == nil || len(apex.Type(dns.TypeNSEC3PARAM)) != 1 {
+		t.Errorf("Expected NSEC3PARAM record at the apex")
 	}
 }
 
 func TestParseNSEC3(t *testing.T) {
-	_, err := Parse(strings.NewReader(nsec3Test), "miek.nl", "stdin", 0)
-	if err == nil {
-		t.Fatalf("Expected error when reading zone, got nothing")
+	z, err := Parse(strings.NewReader(nsec3Test), "example.org", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	if z.NSEC3 == nil || z.NSEC3.Count != 1 {
+		t.Fatalf("Expected 1 name in the NSEC3 chain")
+	}
+	if e, _ := z.Search("aub8v9ce95ie18spjubsr058h41n7pa5.example.org."); e != nil {
+		t.Errorf("Expected NSEC3 records to be kept out of the zone's names")
+	}
+}
+
+func TestLookupNSEC3(t *testing.T) {
+	zone, err := Parse(strings.NewReader(dbNSEC3), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm := File{Next: test.ErrorHandler(), Zones: Zones{Z: map[string]*Zone{"example.org.": zone}, Names: []string{"example.org."}}}
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		rcode  int
+		match  []string // names that must have a matching NSEC3 record in the authority section
+		cover  []string // names that must have a covering NSEC3 record in the authority section
+		answer int
+	}{
+		// NXDOMAIN: closest encloser proof and the wildcard at the closest encloser doesn't exist.
+		{"x.example.org.", dns.TypeA, dns.RcodeNameError, []string{"example.org."}, []string{"x.example.org.", "*.example.org."}, 0},
+		{"x.y.a.example.org.", dns.TypeA, dns.RcodeNameError, []string{"a.example.org."}, []string{"y.a.example.org.", "*.a.example.org."}, 0},
+		// NODATA
+		{"a.example.org.", dns.TypeTXT, dns.RcodeSuccess, []string{"a.example.org."}, nil, 0},
+		// NODATA for an empty non-terminal
+		{"c.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"c.example.org."}, nil, 0},
+		// Wildcard answer, the next closer name doesn't exist.
+		{"foo.w.example.org.", dns.TypeA, dns.RcodeSuccess, nil, []string{"foo.w.example.org."}, 1},
+		// Wildcard NODATA
+		{"foo.w.example.org.", dns.TypeTXT, dns.RcodeSuccess, []string{"w.example.org.", "*.w.example.org."}, []string{"foo.w.example.org."}, 0},
+		// Insecure delegation, the DS doesn't exist.
+		{"www.deleg.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"deleg.example.org."}, nil, 0},
+		// DS NODATA
+		{"deleg.example.org.", dns.TypeDS, dns.RcodeSuccess, []string{"deleg.example.org."}, nil, 0},
+	}
+
+	for _, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		m.SetEdns0(4096, true)
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := fm.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatal(err)
+		}
+		resp := rec.Msg
+		if resp.Rcode != tc.rcode {
+			t.Errorf("%s %s: expected rcode %s, got %s", tc.qname, dns.TypeToString[tc.qtype], dns.RcodeToString[tc.rcode], dns.RcodeToString[resp.Rcode])
+		}
+		if len(resp.Answer) != tc.answer {
+			t.Errorf("%s %s: expected %d answers, got %d", tc.qname, dns.TypeToString[tc.qtype], tc.answer, len(resp.Answer))
+		}
+
+		nsec3s := []*dns.NSEC3{}
+		for _, rr := range resp.Ns {
+			if x, ok := rr.(*dns.NSEC3); ok {
+				nsec3s = append(nsec3s, x)
+			}
+		}
+		// A record may both match and cover names, so there can be fewer records than proofs.
+		if len(nsec3s) == 0 || len(nsec3s) > len(tc.match)+len(tc.cover) {
+			t.Errorf("%s %s: expected at most %d NSEC3 records, got %d: %v", tc.qname, dns.TypeToString[tc.qtype], len(tc.match)+len(tc.cover), len(nsec3s), nsec3s)
+		}
+		for _, name := range tc.match {
+			if !nsec3Proves(nsec3s, name, (*dns.NSEC3).Match) {
+				t.Errorf("%s %s: expected NSEC3 matching %s", tc.qname, dns.TypeToString[tc.qtype], name)
+			}
+		}
+		for _, name := range tc.cover {
+			if !nsec3Proves(nsec3s, name, (*dns.NSEC3).Cover) {
+				t.Errorf("%s %s: expected NSEC3 covering %s", tc.qname, dns.TypeToString[tc.qtype], name)
+			}
+		}
 	}
 }
 
+func nsec3Proves(nsec3s []*dns.NSEC3, name string, f func(*dns.NSEC3, string) bool) bool {
+	for _, x := range nsec3s {
+		if f(x, name) {
+			return true
+		}
+	}
+	return false
+}
+
+const dbNSEC3 = `example.org.   3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300
+example.org.   3600 IN NS ns.example.org.
+example.org.   0 IN NSEC3PARAM 1 0 0 -
+ns.example.org. 3600 IN A 192.0.2.1
+a.example.org.  3600 IN A 192.0.2.2
+b.c.example.org. 3600 IN A 192.0.2.3
+*.w.example.org. 3600 IN A 192.0.2.4
+deleg.example.org. 3600 IN NS ns.deleg.example.org.
+ns.deleg.example.org. 3600 IN A 192.0.2.5
+secure.example.org. 3600 IN NS ns.example.net.
+secure.example.org. 3600 IN DS 60485 13 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A
+1s1pi9tjgnu6e58j6vburdor8b34boav.example.org.	300	IN	NSEC3	1 0 0 - 3GEMHHITRH1RLDACME4C3L7B2DFDER2C A RRSIG
+3gemhhitrh1rldacme4c3l7b2dfder2c.example.org.	300	IN	NSEC3	1 0 0 - 5VQM4IQG11NEC1VV12HP2AONVG05A83I NS RRSIG
+5vqm4iqg11nec1vv12hp2aonvg05a83i.example.org.	300	IN	NSEC3	1 0 0 - 6HSUDPCUGOVCSU6RIB34SA6RM87TQM57 A RRSIG
+6hsudpcugovcsu6rib34sa6rm87tqm57.example.org.	300	IN	NSEC3	1 0 0 - 8UM1KJCJMOFVVMQ7CB0OP7JT39LG8R9J A RRSIG
+8um1kjcjmofvvmq7cb0op7jt39lg8r9j.example.org.	300	IN	NSEC3	1 0 0 - GQO7H7R357FJ31QJIUDOG4AMTM030PLU NS SOA RRSIG NSEC3PARAM
+gqo7h7r357fj31qjiudog4amtm030plu.example.org.	300	IN	NSEC3	1 0 0 - H0K0TC6LVJGBU028K6QCVDUJ3JT9URL5
+h0k0tc6lvjgbu028k6qcvduj3jt9url5.example.org.	300	IN	NSEC3	1 0 0 - JRFH8DK3OOFI50C0CT4KAU7H45DL0K8C NS DS RRSIG
+jrfh8dk3oofi50c0ct4kau7h45dl0k8c.example.org.	300	IN	NSEC3	1 0 0 - L9QCRTNKG05MBACGV440V6VLRI1DUP6M
+l9qcrtnkg05mbacgv440v6vlri1dup6m.example.org.	300	IN	NSEC3	1 0 0 - 1S1PI9TJGNU6E58J6VBURDOR8B34BOAV A RRSIG
+`
+
 const nsec3paramTest = `miek.nl.	1800	IN	SOA	linode.atoom.net. miek.miek.nl. 1460175181 14400 3600 604800 14400
 miek.nl.		1800	IN	NS	omval.tednet.nl.
 miek.nl.		0	IN	NSEC3PARAM 1 0 5 A3DEBC9CC4F695C7`
@@ -36,11 +36,25 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 					continue
 				}
 
-				// copy elements we need
-				z.Lock()
-				z.Apex = zone.Apex
-				z.Tree = zone.Tree
-				z.Unlock()
+				// With dynamic updates the zone file is behind until the updates are written back. Only
+				// reload when the file has a newer serial, the updates in the journal are then dropped.
+				dynamic := len(z.UpdatePolicies) > 0
+				if dynamic {
+					z.updateMu.Lock()
+					if serial := z.SOASerialIfDefined(); serial >= 0 && !less(uint32(serial), zone.SOA.Serial) {
+						z.updateMu.Unlock()
+						continue
+					}
+					if err := os.Remove(z.journal()); err != nil && !os.IsNotExist(err) {
+						log.Warningf("Failed to remove journal %q: %s", z.journal(), err)
+					}
+					z.dirty = false
+				}
+
+				z.swap(zone, z.changes(zone)...)
+				if dynamic {
+					z.updateMu.Unlock()
+				}
 
 				log.Infof("Successfully reloaded zone %q in %q with %d SOA serial", z.origin, zFile, z.SOA.Serial)
 				if t != nil {
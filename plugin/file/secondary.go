@@ -1,24 +1,36 @@
 package file
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/miekg/dns"
 )
 
-// TransferIn retrieves the zone from the masters, parses it and sets it live.
+// TransferIn retrieves the zone from the masters, parses it and sets it live. If we already have the
+// zone, an incremental transfer is requested; masters that don't have the changes send the whole zone.
 func (z *Zone) TransferIn() error {
 	if len(z.TransferFrom) == 0 {
 		return nil
 	}
+	z.RLock()
+	soa := z.SOA
+	z.RUnlock()
+
 	m := new(dns.Msg)
-	m.SetAxfr(z.origin)
+	if soa != nil {
+		m.SetIxfr(z.origin, soa.Serial, soa.Ns, soa.Mbox)
+	} else {
+		m.SetAxfr(z.origin)
+	}
 
-	z1 := z.CopyWithoutApex()
 	var (
-		Err error
-		tr  string
+		Err   error
+		tr    string
+		z1    *Zone
+		diffs []diff
+		incr  bool
 	)
 
 Transfer:
@@ -30,19 +42,21 @@ Transfer:
 			Err = err
 			continue Transfer
 		}
+		rrs := []dns.RR{}
 		for env := range c {
 			if env.Error != nil {
 				log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, env.Error)
 				Err = env.Error
 				continue Transfer
 			}
-			for _, rr := range env.RR {
-				if err := z1.Insert(rr); err != nil {
-					log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, err)
-					Err = err
-					continue Transfer
-				}
-			}
+			rrs = append(rrs, env.RR...)
+		}
+		incr = incremental(rrs, soa)
+		z1, diffs, err = z.transferred(rrs, soa)
+		if err != nil {
+			log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, err)
+			Err = err
+			continue Transfer
 		}
 		Err = nil
 		break
@@ -50,16 +64,77 @@ Transfer:
 	if Err != nil {
 		return Err
 	}
+	if z1 == nil {
+		log.Infof("Transferred: %s from %s, zone is up to date", z.origin, tr)
+		return nil
+	}
 
+	z.swap(z1, diffs...)
 	z.Lock()
-	z.Tree = z1.Tree
-	z.Apex = z1.Apex
 	z.Expired = false
 	z.Unlock()
-	log.Infof("Transferred: %s from %s", z.origin, tr)
+	if incr {
+		log.Infof("Transferred: %s from %s, with %d incremental changes", z.origin, tr, len(diffs))
+	} else {
+		log.Infof("Transferred: %s from %s", z.origin, tr)
+	}
+	if z.OnTransfer != nil {
+		z.OnTransfer()
+	}
 	return nil
 }
 
+// transferred returns the zone with the records received in a transfer, and the changes to z. When the
+// records are an incremental transfer (RFC 1995), the changes are applied to the records of z, which has
+// soa as its SOA record. If the zone is up to date, a nil zone is returned.
+func (z *Zone) transferred(rrs []dns.RR, soa *dns.SOA) (*Zone, []diff, error) {
+	if len(rrs) == 0 {
+		return nil, nil, fmt.Errorf("empty transfer")
+	}
+	if len(rrs) == 1 { // only the SOA record, we are up to date
+		return nil, nil, nil
+	}
+
+	if !incremental(rrs, soa) {
+		z1 := z.CopyWithoutApex()
+		for _, rr := range rrs {
+			if err := z1.Insert(rr); err != nil {
+				return nil, nil, err
+			}
+		}
+		return z1, z.changes(z1), nil
+	}
+
+	diffs, err := parseDiffs(rrs[1 : len(rrs)-1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("incremental transfer %s", err)
+	}
+	z.RLock()
+	s := z.rrsets()
+	z.RUnlock()
+	for i := range diffs {
+		if cur := s[z.origin][dns.TypeSOA][0].(*dns.SOA); diffs[i].oldSOA.Serial != cur.Serial {
+			return nil, nil, fmt.Errorf("incremental transfer from %d SOA serial does not apply to %d", diffs[i].oldSOA.Serial, cur.Serial)
+		}
+		s.apply(z.origin, diffs[i])
+	}
+
+	z1, err := s.zone(z.origin, z.file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return z1, diffs, nil
+}
+
+// incremental returns true if rrs is an incremental transfer from the version of the zone with soa.
+func incremental(rrs []dns.RR, soa *dns.SOA) bool {
+	if soa == nil || len(rrs) < 3 {
+		return false
+	}
+	x, ok := rrs[1].(*dns.SOA)
+	return ok && x.Serial == soa.Serial
+}
+
 // shouldTransfer checks the primaries of zone, retrieves the SOA record, checks the current serial
 // and the remote serial and will return true if the remote one is higher than the locally configured one.
 func (z *Zone) shouldTransfer() (bool, error) {
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coredns/caddy"
@@ -12,6 +14,8 @@ import (
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/plugin/transfer"
+
+	"github.com/miekg/dns"
 )
 
 func init() { plugin.Register("file", setup) }
@@ -55,7 +59,10 @@ func setup(c *caddy.Controller) error {
 		z := zones.Z[n]
 		c.OnShutdown(z.OnShutdown)
 		c.OnStartup(func() error {
-			z.StartupOnce.Do(func() { z.Reload(f.transfer) })
+			z.StartupOnce.Do(func() {
+				z.Reload(f.transfer)
+				z.WriteBackUpdates()
+			})
 			return nil
 		})
 	}
@@ -77,6 +84,8 @@ func fileParse(c *caddy.Controller) (Zones, fall.F, error) {
 
 	var openErr error
 	reload := 1 * time.Minute
+	writeBack := 1 * time.Minute
+	history := 0
 
 	for c.Next() {
 		// file db.file [zones...]
@@ -117,6 +126,7 @@ func fileParse(c *caddy.Controller) (Zones, fall.F, error) {
 			return Zones{}, fall, err
 		}
 
+		policies := []UpdatePolicy{}
 		for c.NextBlock() {
 			switch c.Val() {
 			case "fallthrough":
@@ -134,15 +144,56 @@ func fileParse(c *caddy.Controller) (Zones, fall.F, error) {
 			case "upstream":
 				// remove soon
 				c.RemainingArgs()
+			case "update":
+				p, err := updateParse(c, origins)
+				if err != nil {
+					return Zones{}, fall, err
+				}
+				policies = append(policies, p)
+			case "writeback":
+				t := c.RemainingArgs()
+				if len(t) != 1 {
+					return Zones{}, fall, c.ArgErr()
+				}
+				d, err := time.ParseDuration(t[0])
+				if err != nil {
+					return Zones{}, fall, plugin.Error("file", err)
+				}
+				if d < 0 {
+					return Zones{}, fall, c.Errf("writeback duration must be positive: %s", t[0])
+				}
+				writeBack = d
+			case "ixfr":
+				n, err := ixfrParse(c)
+				if err != nil {
+					return Zones{}, fall, err
+				}
+				history = n
 
 			default:
 				return Zones{}, fall, c.Errf("unknown property '%s'", c.Val())
 			}
 		}
 
+		if len(policies) > 0 && len(origins) > 1 {
+			return Zones{}, fall, c.Errf("update is only supported for a single zone per file, got %d zones", len(origins))
+		}
+		// Writing the zone back would replace the $INCLUDE directives with the included records.
+		if len(policies) > 0 && writeBack > 0 && openErr == nil && includes(fileName) {
+			return Zones{}, fall, c.Errf("writeback can't be used for %s, as it uses $INCLUDE: use writeback 0", fileName)
+		}
+
 		for i := range origins {
 			z[origins[i]].ReloadInterval = reload
 			z[origins[i]].Upstream = upstream.New()
+			z[origins[i]].UpdatePolicies = policies
+			z[origins[i]].WriteBack = writeBack
+			z[origins[i]].IXFRHistory = history
+			if len(policies) > 0 && openErr == nil {
+				if err := z[origins[i]].replayJournal(); err != nil {
+					return Zones{}, fall, plugin.Error("file", err)
+				}
+			}
 		}
 	}
 
@@ -155,3 +206,64 @@ func fileParse(c *caddy.Controller) (Zones, fall.F, error) {
 	}
 	return Zones{Z: z, Names: names}, fall, nil
 }
+
+// updateParse parses an update policy: update KEY NAME [TYPE...].
+func updateParse(c *caddy.Controller, origins []string) (UpdatePolicy, error) {
+	args := c.RemainingArgs()
+	if len(args) < 2 {
+		return UpdatePolicy{}, c.ArgErr()
+	}
+	p := UpdatePolicy{
+		Key:   dns.Fqdn(strings.ToLower(args[0])),
+		Name:  dns.Fqdn(strings.ToLower(args[1])),
+		Types: map[uint16]struct{}{},
+	}
+	if plugin.Zones(origins).Matches(p.Name) == "" {
+		return UpdatePolicy{}, c.Errf("update name %q is not in zone %s", args[1], strings.Join(origins, ", "))
+	}
+	for _, t := range args[2:] {
+		qtype, ok := dns.StringToType[strings.ToUpper(t)]
+		if !ok {
+			return UpdatePolicy{}, c.Errf("invalid record type %q", t)
+		}
+		p.Types[qtype] = struct{}{}
+	}
+	return p, nil
+}
+
+// ixfrParse parses the number of changed records kept for incremental transfers: ixfr [RECORDS].
+func ixfrParse(c *caddy.Controller) (int, error) {
+	args := c.RemainingArgs()
+	switch len(args) {
+	case 0:
+		return DefaultIXFRHistory, nil
+	case 1:
+	default:
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, c.Errf("invalid ixfr value %q: %s", args[0], err)
+	}
+	if n < 0 {
+		return 0, c.Errf("ixfr value must be positive: %d", n)
+	}
+	return n, nil
+}
+
+// includes returns true if the zone file fileName has an $INCLUDE directive.
+func includes(fileName string) bool {
+	if FormatOf(fileName) != RFC1035 {
+		return false
+	}
+	buf, err := os.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		return false
+	}
+	for line := range strings.SplitSeq(string(buf), "\n") {
+		if f := strings.Fields(line); len(f) > 0 && strings.EqualFold(f[0], "$INCLUDE") {
+			return true
+		}
+	}
+	return false
+}
@@ -22,6 +22,20 @@ func TestFileParse(t *testing.T) {
 	}
 	defer rm()
 
+	includedFileName, rm, err := test.TempFile(".", "www IN A 127.0.0.1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	zoneFileName3, rm, err := test.TempFile(".", `$ORIGIN example.org.
+@ IN SOA sns.dns.icann.org. noc.dns.icann.org. 2017042766 7200 3600 1209600 3600
+$INCLUDE `+includedFileName+"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
 	tests := []struct {
 		inputFileRules      string
 		shouldErr           bool
@@ -62,7 +76,74 @@ func TestFileParse(t *testing.T) {
 			Zones{Names: []string{"example.org."}},
 			fall.F{Zones: []string{"www.example.org."}},
 		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+					update dhcp.key. dyn.miek.nl. A AAAA
+					update acme.key. miek.nl. TXT
+					writeback 5m
+				}`,
+			false,
+			Zones{Names: []string{"miek.nl."}},
+			fall.Zero,
+		},
+		{
+			`file ` + zoneFileName3 + ` example.org. {
+					update dhcp.key. example.org. A
+					writeback 0
+				}`,
+			false,
+			Zones{Names: []string{"example.org."}},
+			fall.Zero,
+		},
 		// errors.
+		{
+			`file ` + zoneFileName3 + ` example.org. {
+				update dhcp.key. example.org. A
+			}`,
+			true,
+			Zones{},
+			fall.Zero,
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				update dhcp.key.
+			}`,
+			true,
+			Zones{},
+			fall.Zero,
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				update dhcp.key. example.org.
+			}`,
+			true,
+			Zones{},
+			fall.Zero,
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				update dhcp.key. miek.nl. BOGUS
+			}`,
+			true,
+			Zones{},
+			fall.Zero,
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. example.org. {
+				update dhcp.key. miek.nl.
+			}`,
+			true,
+			Zones{},
+			fall.Zero,
+		},
+		{
+			`file ` + zoneFileName1 + ` miek.nl. {
+				writeback -1s
+			}`,
+			true,
+			Zones{},
+			fall.Zero,
+		},
 		{
 			`file ` + zoneFileName1 + ` miek.nl {
 				transfer from 127.0.0.1
@@ -150,3 +231,44 @@ func TestParseReload(t *testing.T) {
 		}
 	}
 }
+
+func TestParseIXFR(t *testing.T) {
+	name, rm, err := test.TempFile(".", dbMiekNL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input   string
+		history int
+	}{
+		{
+			`file ` + name + ` example.org.`,
+			0,
+		},
+		{
+			`file ` + name + ` example.org. {
+			ixfr
+			}`,
+			DefaultIXFRHistory,
+		},
+		{
+			`file ` + name + ` example.org. {
+			ixfr 500
+			}`,
+			500,
+		},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		z, _, err := fileParse(c)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error: %s", i, err)
+		}
+		if x := z.Z["example.org."].IXFRHistory; x != test.history {
+			t.Errorf("Test %d expected ixfr history to be %d, but got %d", i, test.history, x)
+		}
+	}
+}
@@ -5,5 +5,8 @@ func (z *Zone) OnShutdown() error {
 	if 0 < z.ReloadInterval {
 		z.reloadShutdown <- true
 	}
+	if 0 < z.WriteBack && len(z.UpdatePolicies) > 0 {
+		z.writeBackShutdown <- true
+	}
 	return nil
 }
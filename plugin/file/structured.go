@@ -0,0 +1,536 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format of a zone file.
+type Format int
+
+const (
+	// RFC1035 is the master file format of RFC 1035.
+	RFC1035 Format = iota
+	// YAML is the structured zone format, written in YAML.
+	YAML
+	// JSON is the structured zone format, written in JSON.
+	JSON
+)
+
+// FormatOf returns the format of the zone file fileName, detected by its extension: files ending in .yaml
+// or .yml are YAML, files ending in .json are JSON and all others are RFC 1035 master files.
+func FormatOf(fileName string) Format {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		return YAML
+	case ".json":
+		return JSON
+	}
+	return RFC1035
+}
+
+// Defaults of the structured zone format.
+const (
+	defaultTTL     = 3600
+	defaultSerial  = 1
+	defaultRefresh = 7200
+	defaultRetry   = 3600
+	defaultExpire  = 1209600
+	defaultMinTTL  = 3600
+)
+
+// parseStructured parses the structured zone for origin in r, written in format, and returns its records
+// with the SOA record first. Errors carry the line and column in fileName where they were found.
+func parseStructured(r io.Reader, origin, fileName string, format Format) ([]dns.RR, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// YAML is a superset of JSON, but the JSON syntax errors are more precise.
+	if format == JSON {
+		var v any
+		if err := json.Unmarshal(buf, &v); err != nil {
+			var serr *json.SyntaxError
+			if errors.As(err, &serr) {
+				line, col := position(buf, serr.Offset)
+				return nil, fmt.Errorf("%s:%d:%d: %s", fileName, line, col, serr)
+			}
+			return nil, fmt.Errorf("%s: %s", fileName, err)
+		}
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(buf, doc); err != nil {
+		msg := strings.TrimPrefix(err.Error(), "yaml: ")
+		if m := yamlLine.FindStringSubmatch(msg); m != nil {
+			return nil, fmt.Errorf("%s:%s: %s", fileName, m[1], m[2])
+		}
+		return nil, fmt.Errorf("%s: %s", fileName, msg)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%s: empty zone", fileName)
+	}
+
+	p := &structured{file: fileName, origin: dns.Fqdn(strings.ToLower(origin)), ttl: defaultTTL}
+	return p.zone(doc.Content[0])
+}
+
+var yamlLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// position returns the line and column of the byte before offset in buf, which is where encoding/json
+// reports a syntax error.
+func position(buf []byte, offset int64) (line, col int) {
+	if offset > 0 {
+		offset--
+	}
+	if offset > int64(len(buf)) {
+		offset = int64(len(buf))
+	}
+	before := buf[:offset]
+	return 1 + bytes.Count(before, []byte("\n")), int(offset) - bytes.LastIndexByte(before, '\n')
+}
+
+// structured is the parser of the structured zone format.
+type structured struct {
+	file   string
+	origin string
+	ttl    uint32
+}
+
+func (p *structured) errorf(n *yaml.Node, format string, a ...any) error {
+	return fmt.Errorf("%s:%d:%d: %s", p.file, n.Line, n.Column, fmt.Sprintf(format, a...))
+}
+
+// mapping returns the values of the mapping n by key. Keys that are not in keys are an error.
+func (p *structured) mapping(n *yaml.Node, keys ...string) (map[string]*yaml.Node, error) {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		return nil, p.errorf(n, "expected a mapping")
+	}
+	values := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		known := false
+		for _, key := range keys {
+			if k.Value == key {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, p.errorf(k, "unknown key %q", k.Value)
+		}
+		if _, ok := values[k.Value]; ok {
+			return nil, p.errorf(k, "duplicate key %q", k.Value)
+		}
+		values[k.Value] = resolve(v)
+	}
+	return values, nil
+}
+
+func (p *structured) scalar(n *yaml.Node) (string, error) {
+	if n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+		return "", p.errorf(n, "expected a value")
+	}
+	return n.Value, nil
+}
+
+func (p *structured) uint32(n *yaml.Node) (uint32, error) {
+	s, err := p.scalar(n)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, p.errorf(n, "invalid number %q", s)
+	}
+	return uint32(i), nil
+}
+
+// name returns the fully qualified name in n, names that aren't fully qualified are relative to the
+// origin; @ is the origin itself.
+func (p *structured) name(n *yaml.Node) (string, error) {
+	s, err := p.scalar(n)
+	if err != nil {
+		return "", err
+	}
+	if s == "@" {
+		return p.origin, nil
+	}
+	if _, ok := dns.IsDomainName(s); !ok || strings.ContainsAny(s, " \t;()") {
+		return "", p.errorf(n, "invalid name %q", s)
+	}
+	if dns.IsFqdn(s) {
+		return strings.ToLower(s), nil
+	}
+	if p.origin == "." {
+		return strings.ToLower(s) + ".", nil
+	}
+	return strings.ToLower(s) + "." + p.origin, nil
+}
+
+// zone parses the top level of the zone: its origin, the default TTL, the SOA record and the records.
+func (p *structured) zone(n *yaml.Node) ([]dns.RR, error) {
+	top, err := p.mapping(n, "origin", "ttl", "soa", "records")
+	if err != nil {
+		return nil, err
+	}
+	if o, ok := top["origin"]; ok {
+		origin, err := p.name(o)
+		if err != nil {
+			return nil, err
+		}
+		if origin != p.origin {
+			return nil, p.errorf(o, "origin %s does not match the zone %s", origin, p.origin)
+		}
+	}
+	if t, ok := top["ttl"]; ok {
+		if p.ttl, err = p.uint32(t); err != nil {
+			return nil, err
+		}
+	}
+
+	rrs := []dns.RR{}
+	if r, ok := top["records"]; ok {
+		if rrs, err = p.records(r); err != nil {
+			return nil, err
+		}
+	}
+
+	soa := &yaml.Node{Kind: yaml.MappingNode, Line: n.Line, Column: n.Column}
+	if s, ok := top["soa"]; ok {
+		soa = s
+	}
+	s, err := p.soa(soa, rrs)
+	if err != nil {
+		return nil, err
+	}
+	return append([]dns.RR{s}, rrs...), nil
+}
+
+// soa parses the SOA record in n, the fields that are missing get their defaults. The default name server
+// is the first NS record at the apex in rrs.
+func (p *structured) soa(n *yaml.Node, rrs []dns.RR) (*dns.SOA, error) {
+	fields, err := p.mapping(n, "ns", "mbox", "serial", "refresh", "retry", "expire", "minttl", "ttl")
+	if err != nil {
+		return nil, err
+	}
+	soa := &dns.SOA{
+		Hdr:     dns.RR_Header{Name: p.origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: p.ttl},
+		Mbox:    dns.Fqdn("hostmaster." + strings.TrimSuffix(p.origin, ".")),
+		Serial:  defaultSerial,
+		Refresh: defaultRefresh,
+		Retry:   defaultRetry,
+		Expire:  defaultExpire,
+		Minttl:  defaultMinTTL,
+	}
+	for _, rr := range rrs {
+		if ns, ok := rr.(*dns.NS); ok && ns.Hdr.Name == p.origin {
+			soa.Ns = ns.Ns
+			break
+		}
+	}
+
+	names := []struct {
+		key string
+		dst *string
+	}{{"ns", &soa.Ns}, {"mbox", &soa.Mbox}}
+	for _, f := range names {
+		if v, ok := fields[f.key]; ok {
+			if *f.dst, err = p.name(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	numbers := []struct {
+		key string
+		dst *uint32
+	}{
+		{"serial", &soa.Serial}, {"refresh", &soa.Refresh}, {"retry", &soa.Retry}, {"expire", &soa.Expire},
+		{"minttl", &soa.Minttl}, {"ttl", &soa.Hdr.Ttl},
+	}
+	for _, f := range numbers {
+		if v, ok := fields[f.key]; ok {
+			if *f.dst, err = p.uint32(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if soa.Ns == "" {
+		return nil, p.errorf(n, "no ns in the soa and no NS records at the apex")
+	}
+	return soa, nil
+}
+
+// records parses the records, grouped by name and then by type.
+func (p *structured) records(n *yaml.Node) ([]dns.RR, error) {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		return nil, p.errorf(n, "expected a mapping of names")
+	}
+	rrs := []dns.RR{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], resolve(n.Content[i+1])
+		owner, err := p.name(k)
+		if err != nil {
+			return nil, err
+		}
+		if !dns.IsSubDomain(p.origin, owner) {
+			return nil, p.errorf(k, "name %s is not in the zone %s", owner, p.origin)
+		}
+		if v.Kind != yaml.MappingNode {
+			return nil, p.errorf(v, "expected a mapping of types")
+		}
+		for j := 0; j+1 < len(v.Content); j += 2 {
+			t, data := v.Content[j], resolve(v.Content[j+1])
+			typ := strings.ToUpper(t.Value)
+			if _, ok := dns.StringToType[typ]; !ok && !strings.HasPrefix(typ, "TYPE") {
+				return nil, p.errorf(t, "unknown type %q", t.Value)
+			}
+			if typ == "SOA" {
+				return nil, p.errorf(t, "the SOA record is set in the soa section")
+			}
+			set, err := p.rrset(owner, typ, p.ttl, data, false)
+			if err != nil {
+				return nil, err
+			}
+			rrs = append(rrs, set...)
+		}
+	}
+	return rrs, nil
+}
+
+// rrset parses the records of type typ for owner in n. This is the record data, a list of record data, or a
+// mapping with the ttl and the data. The elements of a list may also be such a mapping, so each record has
+// its own TTL.
+func (p *structured) rrset(owner, typ string, ttl uint32, n *yaml.Node, inList bool) ([]dns.RR, error) {
+	n = resolve(n)
+	switch n.Kind {
+	case yaml.ScalarNode:
+		rr, err := p.rr(owner, typ, ttl, n)
+		if err != nil {
+			return nil, err
+		}
+		return []dns.RR{rr}, nil
+
+	case yaml.SequenceNode:
+		if inList {
+			return nil, p.errorf(n, "expected record data")
+		}
+		rrs := []dns.RR{}
+		for _, c := range n.Content {
+			set, err := p.rrset(owner, typ, ttl, c, true)
+			if err != nil {
+				return nil, err
+			}
+			rrs = append(rrs, set...)
+		}
+		return rrs, nil
+
+	case yaml.MappingNode:
+		fields, err := p.mapping(n, "ttl", "data")
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := fields["ttl"]; ok {
+			if ttl, err = p.uint32(t); err != nil {
+				return nil, err
+			}
+		}
+		data, ok := fields["data"]
+		if !ok {
+			return nil, p.errorf(n, "no data for %s %s", owner, typ)
+		}
+		if data.Kind == yaml.MappingNode || (inList && data.Kind != yaml.ScalarNode) {
+			return nil, p.errorf(data, "expected record data")
+		}
+		return p.rrset(owner, typ, ttl, data, inList)
+	}
+	return nil, p.errorf(n, "expected record data")
+}
+
+// rr parses the record data in n into a record. Names in the data that aren't fully qualified are relative
+// to the origin.
+func (p *structured) rr(owner, typ string, ttl uint32, n *yaml.Node) (dns.RR, error) {
+	data, err := p.scalar(n)
+	if err != nil {
+		return nil, p.errorf(n, "expected record data")
+	}
+	zp := dns.NewZoneParser(strings.NewReader(fmt.Sprintf("%s %d IN %s %s", owner, ttl, typ, data)), p.origin, "")
+	rr, ok := zp.Next()
+	if err := zp.Err(); err != nil {
+		msg := strings.TrimPrefix(err.Error(), "dns: ")
+		if i := strings.Index(msg, " at line: "); i > 0 {
+			msg = msg[:i]
+		}
+		return nil, p.errorf(n, "invalid %s record: %s", typ, msg)
+	}
+	if !ok {
+		return nil, p.errorf(n, "expected record data")
+	}
+	if _, more := zp.Next(); more {
+		return nil, p.errorf(n, "more than one %s record in the data", typ)
+	}
+	return rr, nil
+}
+
+// resolve returns the node an alias refers to.
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// Export writes the records of z to w in format.
+func (z *Zone) Export(w io.Writer, format Format) error {
+	if format == RFC1035 {
+		return z.print(w)
+	}
+
+	apex, err := z.ApexIfDefined()
+	if err != nil {
+		return err
+	}
+	rrs := apex[1:]
+	z.RLock()
+	collect := func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		rrs = append(rrs, e.All()...)
+		return nil
+	}
+	z.Walk(collect)
+	if z.NSEC3 != nil {
+		z.NSEC3.Walk(collect)
+	}
+	z.RUnlock()
+
+	doc := newZoneDoc(apex[0].(*dns.SOA), rrs)
+	bw := bufio.NewWriter(w)
+	switch format {
+	case YAML:
+		enc := yaml.NewEncoder(bw)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+	case JSON:
+		enc := json.NewEncoder(bw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown zone format %d", format)
+	}
+	return bw.Flush()
+}
+
+// zoneDoc is a zone in the structured format, for exporting.
+type zoneDoc struct {
+	Origin  string                    `json:"origin" yaml:"origin"`
+	TTL     uint32                    `json:"ttl" yaml:"ttl"`
+	SOA     soaDoc                    `json:"soa" yaml:"soa"`
+	Records map[string]map[string]any `json:"records" yaml:"records"`
+}
+
+type soaDoc struct {
+	NS      string `json:"ns" yaml:"ns"`
+	Mbox    string `json:"mbox" yaml:"mbox"`
+	Serial  uint32 `json:"serial" yaml:"serial"`
+	Refresh uint32 `json:"refresh" yaml:"refresh"`
+	Retry   uint32 `json:"retry" yaml:"retry"`
+	Expire  uint32 `json:"expire" yaml:"expire"`
+	MinTTL  uint32 `json:"minttl" yaml:"minttl"`
+}
+
+// rrsetDoc is record data with a TTL that differs from the default TTL of the zone.
+type rrsetDoc struct {
+	TTL  uint32 `json:"ttl" yaml:"ttl"`
+	Data any    `json:"data" yaml:"data"`
+}
+
+// newZoneDoc groups the records rrs of the zone with SOA record soa by name and type. The TTL of the SOA
+// record is the default TTL of the zone.
+func newZoneDoc(soa *dns.SOA, rrs []dns.RR) zoneDoc {
+	origin := soa.Hdr.Name
+	doc := zoneDoc{
+		Origin: origin,
+		TTL:    soa.Hdr.Ttl,
+		SOA: soaDoc{
+			NS: soa.Ns, Mbox: soa.Mbox, Serial: soa.Serial, Refresh: soa.Refresh, Retry: soa.Retry,
+			Expire: soa.Expire, MinTTL: soa.Minttl,
+		},
+		Records: map[string]map[string]any{},
+	}
+
+	type key struct{ name, typ string }
+	sets := map[key][]dns.RR{}
+	keys := []key{}
+	for _, rr := range rrs {
+		k := key{relative(rr.Header().Name, origin), dns.Type(rr.Header().Rrtype).String()}
+		if _, ok := sets[k]; !ok {
+			keys = append(keys, k)
+		}
+		sets[k] = append(sets[k], rr)
+	}
+
+	for _, k := range keys {
+		set := sets[k]
+		same := true
+		for _, rr := range set[1:] {
+			same = same && rr.Header().Ttl == set[0].Header().Ttl
+		}
+		data := make([]any, len(set))
+		for i, rr := range set {
+			data[i] = rdata(rr)
+			if !same && rr.Header().Ttl != doc.TTL {
+				data[i] = rrsetDoc{TTL: rr.Header().Ttl, Data: data[i]}
+			}
+		}
+
+		var v any = data
+		if len(data) == 1 {
+			v = data[0]
+		}
+		if same && set[0].Header().Ttl != doc.TTL {
+			v = rrsetDoc{TTL: set[0].Header().Ttl, Data: v}
+		}
+		if doc.Records[k.name] == nil {
+			doc.Records[k.name] = map[string]any{}
+		}
+		doc.Records[k.name][k.typ] = v
+	}
+	return doc
+}
+
+// relative returns name relative to origin, or @ for origin itself.
+func relative(name, origin string) string {
+	if strings.EqualFold(name, origin) {
+		return "@"
+	}
+	if origin == "." {
+		return strings.TrimSuffix(name, ".")
+	}
+	return strings.TrimSuffix(name, "."+origin)
+}
+
+// rdata returns the record data of rr in the presentation format.
+func rdata(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
@@ -0,0 +1,181 @@
+package file
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+const yamlZone = `origin: example.org.
+ttl: 300
+soa:
+  mbox: admin
+  serial: 2024010100
+records:
+  "@":
+    NS: [ns1, ns2.example.net.]
+    MX: 10 mail
+  ns1:
+    A: 192.0.2.53
+  www:
+    A:
+      ttl: 60
+      data: [192.0.2.1, 192.0.2.2]
+    AAAA: 2001:db8::1
+  txt:
+    TXT:
+      - '"first"'
+      - ttl: 30
+        data: '"second"'
+  alias:
+    CNAME: www
+`
+
+const jsonZone = `{
+  "ttl": 300,
+  "soa": {"ns": "ns1", "serial": 7},
+  "records": {
+    "ns1": {"A": "192.0.2.53"},
+    "www": {"a": ["192.0.2.1"]}
+  }
+}
+`
+
+func TestParseYAML(t *testing.T) {
+	z, err := Parse(strings.NewReader(yamlZone), "example.org.", "db.example.org.yaml", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The name server of the SOA record defaults to the first NS record at the apex.
+	soa := "example.org.\t300\tIN\tSOA\tns1.example.org. admin.example.org. 2024010100 7200 3600 1209600 3600"
+	if z.SOA.String() != soa {
+		t.Errorf("Expected SOA %q, got %q", soa, z.SOA)
+	}
+
+	expect := []string{
+		"example.org.\t300\tIN\tNS\tns1.example.org.",
+		"example.org.\t300\tIN\tNS\tns2.example.net.",
+		"example.org.\t300\tIN\tMX\t10 mail.example.org.",
+		"ns1.example.org.\t300\tIN\tA\t192.0.2.53",
+		"www.example.org.\t60\tIN\tA\t192.0.2.1",
+		"www.example.org.\t60\tIN\tA\t192.0.2.2",
+		"www.example.org.\t300\tIN\tAAAA\t2001:db8::1",
+		"txt.example.org.\t300\tIN\tTXT\t\"first\"",
+		"txt.example.org.\t30\tIN\tTXT\t\"second\"",
+		"alias.example.org.\t300\tIN\tCNAME\twww.example.org.",
+	}
+	got := map[string]bool{}
+	for _, types := range z.rrsets() {
+		for _, rrs := range types {
+			for _, rr := range rrs {
+				got[rr.String()] = true
+			}
+		}
+	}
+	for _, e := range expect {
+		if !got[e] {
+			t.Errorf("Expected record %q in the zone", e)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	z, err := Parse(strings.NewReader(jsonZone), "example.org.", "db.example.org.json", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z.SOA.Serial != 7 || z.SOA.Ns != "ns1.example.org." || z.SOA.Mbox != "hostmaster.example.org." {
+		t.Errorf("Expected SOA with defaults, got %s", z.SOA)
+	}
+	if n := len(z.rrsets()); n != 3 {
+		t.Errorf("Expected records for 3 names, got %d", n)
+	}
+
+	// Same serial, nothing is read.
+	if _, err := Parse(strings.NewReader(jsonZone), "example.org.", "db.example.org.json", 7); err == nil {
+		t.Errorf("Expected an error for an unchanged SOA serial")
+	}
+}
+
+func TestParseStructuredErrors(t *testing.T) {
+	tests := []struct {
+		file, zone string
+		err        string
+	}{
+		{"z.yaml", "records:\n  www:\n    A: 192.0.2.300\n", "z.yaml:3:8: invalid A record"},
+		{"z.yaml", "records:\n  www:\n    BLAH: x\n", `z.yaml:3:5: unknown type "BLAH"`},
+		{"z.yaml", "ttl: soon\n", `z.yaml:1:6: invalid number "soon"`},
+		{"z.yaml", "tll: 10\n", `z.yaml:1:1: unknown key "tll"`},
+		{"z.yaml", "origin: example.net.\n", "z.yaml:1:9: origin example.net. does not match"},
+		{"z.yaml", "records:\n  www.example.net.:\n    A: 192.0.2.1\n", "z.yaml:2:3: name www.example.net. is not in the zone"},
+		{"z.yaml", "records:\n  www:\n    A: 192.0.2.1\n", "z.yaml:1:1: no ns in the soa"},
+		{"z.yaml", "records:\n  \"@\":\n    SOA: x\n", "z.yaml:3:5: the SOA record is set in the soa section"},
+		{"z.yaml", "records:\n  www:\n    A:\n      ttl: 10\n", "z.yaml:4:7: no data"},
+		{"z.yaml", "records: [\n", "z.yaml:1: did not find expected node content"},
+		{"z.yaml", "", "z.yaml: empty zone"},
+		{"z.json", "{\n  \"ttl\": 300,\n  \"soa\" {}\n}\n", "z.json:3:9: invalid character '{'"},
+		{"z.json", "{\"soa\": {\"serial\": -1}}", `z.json:1:20: invalid number "-1"`},
+	}
+	for _, tc := range tests {
+		_, err := Parse(strings.NewReader(tc.zone), "example.org.", tc.file, 0)
+		if err == nil {
+			t.Errorf("Expected error for %q", tc.zone)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("Expected error starting with %q, got %q", tc.err, err)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	for db, origin := range map[string]string{dbMiekNL: "miek.nl.", dbMiekNLSigned: "miek.nl.", dbNSEC3: "example.org."} {
+		zone, err := Parse(strings.NewReader(db), origin, "stdin", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testExport(t, zone)
+	}
+}
+
+func testExport(t *testing.T, zone *Zone) {
+	t.Helper()
+	for _, format := range []struct {
+		Format
+		file string
+	}{{YAML, "db.yaml"}, {JSON, "db.json"}, {RFC1035, "db"}} {
+		buf := &bytes.Buffer{}
+		if err := zone.Export(buf, format.Format); err != nil {
+			t.Fatal(err)
+		}
+		exported, err := Parse(buf, zone.origin, format.file, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse the exported zone %s: %s", format.file, err)
+		}
+		if !equalZones(zone, exported) {
+			t.Errorf("Expected exported zone %s to have the same records:\n%s", format.file, buf)
+		}
+	}
+}
+
+func equalZones(a, b *Zone) bool {
+	as, bs := a.rrsets(), b.rrsets()
+	if len(as) != len(bs) {
+		return false
+	}
+	for name, types := range as {
+		for typ, rrs := range types {
+			if len(bs[name][typ]) != len(rrs) {
+				return false
+			}
+			for i, rr := range rrs {
+				if !dns.IsDuplicate(rr, bs[name][typ][i]) || rr.Header().Ttl != bs[name][typ][i].Header().Ttl {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package tree
+
+import "github.com/miekg/dns"
+
+// The methods in this file change a tree without modifying it: the nodes on the path to the changed
+// element are copied, all other nodes are shared with the original tree. This lets a zone be changed
+// while lookups that hold on to the original tree continue without a lock. They only implement the
+// bottom-up 2-3 mode the tree is used in.
+
+// Replace returns a copy of t in which the element with owner name is replaced by e. If e is nil the
+// element is removed. Nodes and elements of t are not modified, so e must be a new element.
+func (t *Tree) Replace(name string, e *Elem) *Tree {
+	t1 := &Tree{Root: t.Root, Count: t.Count}
+	_, found := t.Search(name)
+	var d int
+	switch {
+	case e != nil:
+		t1.Root, d = t1.Root.replace(name, e)
+	case found:
+		t1.Root, d = t1.Root.remove(name)
+	default:
+		return t1
+	}
+	t1.Count += d
+	if t1.Root != nil {
+		t1.Root.Color = black
+	}
+	return t1
+}
+
+// NewElem returns a new element holding rrs, which must all have the same owner name.
+func NewElem(name string, rrs []dns.RR) *Elem {
+	e := &Elem{m: make(map[uint16][]dns.RR), name: name}
+	for _, rr := range rrs {
+		e.Insert(rr)
+	}
+	return e
+}
+
+// clone returns a copy of n, a nil node is returned as is.
+func (n *Node) clone() *Node {
+	if n == nil {
+		return nil
+	}
+	n1 := *n
+	return &n1
+}
+
+// The following methods assume n itself is already a copy, and copy the children they modify.
+
+func (n *Node) cloneRotateLeft() *Node {
+	n.Right = n.Right.clone()
+	return n.rotateLeft()
+}
+
+func (n *Node) cloneRotateRight() *Node {
+	n.Left = n.Left.clone()
+	return n.rotateRight()
+}
+
+func (n *Node) cloneFlipColors() {
+	n.Left, n.Right = n.Left.clone(), n.Right.clone()
+	n.flipColors()
+}
+
+func (n *Node) cloneFixUp() *Node {
+	if n.Right.color() == red {
+		n = n.cloneRotateLeft()
+	}
+	if n.Left.color() == red && n.Left.Left.color() == red {
+		n = n.cloneRotateRight()
+	}
+	if n.Left.color() == red && n.Right.color() == red {
+		n.cloneFlipColors()
+	}
+	return n
+}
+
+func (n *Node) cloneMoveRedLeft() *Node {
+	n.cloneFlipColors()
+	if n.Right.Left.color() == red {
+		n.Right = n.Right.cloneRotateRight()
+		n = n.cloneRotateLeft()
+		n.cloneFlipColors()
+	}
+	return n
+}
+
+func (n *Node) cloneMoveRedRight() *Node {
+	n.cloneFlipColors()
+	if n.Left.Left.color() == red {
+		n = n.cloneRotateRight()
+		n.cloneFlipColors()
+	}
+	return n
+}
+
+// replace is insert for a copy of the tree: the element for name is set to e.
+func (n *Node) replace(name string, e *Elem) (root *Node, d int) {
+	if n == nil {
+		return &Node{Elem: e}, 1
+	}
+	n = n.clone()
+
+	switch c := Less(n.Elem, name); {
+	case c == 0:
+		n.Elem = e
+	case c < 0:
+		n.Left, d = n.Left.replace(name, e)
+	default:
+		n.Right, d = n.Right.replace(name, e)
+	}
+
+	if n.Right.color() == red && n.Left.color() == black {
+		n = n.cloneRotateLeft()
+	}
+	if n.Left.color() == red && n.Left.Left.color() == red {
+		n = n.cloneRotateRight()
+	}
+	if n.Left.color() == red && n.Right.color() == red {
+		n.cloneFlipColors()
+	}
+	return n, d
+}
+
+// removeMin is deleteMin for a copy of the tree.
+func (n *Node) removeMin() (root *Node, d int) {
+	n = n.clone()
+	if n.Left == nil {
+		return nil, -1
+	}
+	if n.Left.color() == black && n.Left.Left.color() == black {
+		n = n.cloneMoveRedLeft()
+	}
+	n.Left, d = n.Left.removeMin()
+	return n.cloneFixUp(), d
+}
+
+// remove is delete for a copy of the tree, name must be in the tree.
+func (n *Node) remove(name string) (root *Node, d int) {
+	n = n.clone()
+	if Less(n.Elem, name) < 0 {
+		if n.Left != nil {
+			if n.Left.color() == black && n.Left.Left.color() == black {
+				n = n.cloneMoveRedLeft()
+			}
+			n.Left, d = n.Left.remove(name)
+		}
+	} else {
+		if n.Left.color() == red {
+			n = n.cloneRotateRight()
+		}
+		if n.Right == nil && Less(n.Elem, name) == 0 {
+			return nil, -1
+		}
+		if n.Right != nil {
+			if n.Right.color() == black && n.Right.Left.color() == black {
+				n = n.cloneMoveRedRight()
+			}
+			if Less(n.Elem, name) == 0 {
+				n.Elem = n.Right.min().Elem
+				n.Right, d = n.Right.removeMin()
+			} else {
+				n.Right, d = n.Right.remove(name)
+			}
+		}
+	}
+	return n.cloneFixUp(), d
+}
@@ -0,0 +1,83 @@
+package tree
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func names(t *Tree) []string {
+	var out []string
+	for _, e := range t.All() {
+		out = append(out, e.Name())
+	}
+	return out
+}
+
+// check verifies the left-leaning red-black invariants of n and returns its black height.
+func check(t *testing.T, n *Node) int {
+	t.Helper()
+	if n == nil {
+		return 1
+	}
+	if n.Right.color() == red {
+		t.Fatalf("Right leaning red link at %s", n.Elem.Name())
+	}
+	if n.color() == red && n.Left.color() == red {
+		t.Fatalf("Two red links in a row at %s", n.Elem.Name())
+	}
+	l, r := check(t, n.Left), check(t, n.Right)
+	if l != r {
+		t.Fatalf("Black height differs at %s: %d and %d", n.Elem.Name(), l, r)
+	}
+	if n.color() == black {
+		l++
+	}
+	return l
+}
+
+func TestReplace(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tr := &Tree{}
+	present := map[string]bool{}
+	for i := range 200 {
+		name := fmt.Sprintf("n%d.example.org.", rng.Intn(300))
+		rr, _ := dns.NewRR(fmt.Sprintf("%s 3600 IN A 10.0.0.%d", name, i%250))
+
+		before := names(tr)
+		var tr1 *Tree
+		if present[name] && rng.Intn(2) == 0 {
+			tr1 = tr.Replace(name, nil)
+			delete(present, name)
+		} else {
+			tr1 = tr.Replace(name, NewElem(name, []dns.RR{rr}))
+			present[name] = true
+		}
+
+		// The original tree is not modified.
+		if after := names(tr); fmt.Sprint(after) != fmt.Sprint(before) {
+			t.Fatalf("Step %d: original tree changed", i)
+		}
+		check(t, tr.Root)
+		check(t, tr1.Root)
+		if tr1.Root.color() != black && tr1.Root != nil {
+			t.Fatalf("Step %d: root is not black", i)
+		}
+		if tr1.Len() != len(present) || len(names(tr1)) != len(present) {
+			t.Fatalf("Step %d: expected %d elements, got %d (count %d)", i, len(present), len(names(tr1)), tr1.Len())
+		}
+		for name := range present {
+			if _, ok := tr1.Search(name); !ok {
+				t.Fatalf("Step %d: %s not found", i, name)
+			}
+		}
+		tr = tr1
+	}
+
+	// Replacing a name that isn't in the tree with nil does nothing.
+	if tr1 := tr.Replace("absent.example.org.", nil); tr1.Len() != tr.Len() {
+		t.Errorf("Expected %d elements, got %d", tr.Len(), tr1.Len())
+	}
+}
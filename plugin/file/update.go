@@ -0,0 +1,530 @@
+package file
+
+import (
+	"context"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/tsig"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// UpdatePolicy allows dynamic updates signed with the TSIG key Key, for names equal to or below Name.
+// If Types is not empty only those record types may be updated.
+type UpdatePolicy struct {
+	Key   string
+	Name  string
+	Types map[uint16]struct{}
+}
+
+// allows returns true if p allows an update of rr signed with key.
+func (p UpdatePolicy) allows(key string, rr dns.RR) bool {
+	if !strings.EqualFold(p.Key, key) || !dns.IsSubDomain(p.Name, rr.Header().Name) {
+		return false
+	}
+	if len(p.Types) == 0 {
+		return true
+	}
+	// Deleting all records of a name (type ANY) is only allowed when all types may be updated.
+	_, ok := p.Types[rr.Header().Rrtype]
+	return ok
+}
+
+// update handles a dynamic update (RFC 2136) for zone z.
+func (f File) update(ctx context.Context, state request.Request, z *Zone) (int, error) {
+	key := tsig.KeyName(ctx)
+	rcode, changed := z.applyUpdate(key, state.Req)
+
+	m := new(dns.Msg)
+	m.SetRcode(state.Req, rcode)
+	state.W.WriteMsg(m)
+
+	if !changed {
+		log.Debugf("Update from %s for %s signed with %q: %s", state.IP(), z.origin, key, dns.RcodeToString[rcode])
+		return dns.RcodeSuccess, nil
+	}
+
+	log.Infof("Update from %s for %s signed with %q: new SOA serial %d", state.IP(), z.origin, key, z.SOASerialIfDefined())
+	if f.transfer != nil {
+		go func() {
+			if err := f.transfer.Notify(z.origin); err != nil {
+				log.Warningf("Failed sending notifies: %s", err)
+			}
+		}()
+	}
+	return dns.RcodeSuccess, nil
+}
+
+// applyUpdate checks the prerequisites of the update r, signed with the TSIG key named key, and applies
+// its updates to z. It returns the rcode for the response and true when z was changed.
+func (z *Zone) applyUpdate(key string, r *dns.Msg) (int, bool) {
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		return dns.RcodeFormatError, false
+	}
+	if !strings.EqualFold(r.Question[0].Name, z.origin) || r.Question[0].Qclass != dns.ClassINET {
+		return dns.RcodeNotAuth, false
+	}
+	if len(z.UpdatePolicies) == 0 || key == "" {
+		return dns.RcodeRefused, false
+	}
+
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	// Only the records of the names in the update are copied, so an update doesn't cost more in a
+	// large zone.
+	names := updateNames(z.origin, r)
+	z.RLock()
+	if z.SOA == nil || z.Expired {
+		z.RUnlock()
+		return dns.RcodeServerFailure, false
+	}
+	old := z.rrsetsOf(names)
+	base := &Zone{origin: z.origin, Apex: z.Apex, Tree: z.Tree, NSEC3: z.NSEC3}
+	z.RUnlock()
+
+	if rcode := old.prerequisites(z.origin, r.Answer); rcode != dns.RcodeSuccess {
+		return rcode, false
+	}
+
+	for _, rr := range r.Ns {
+		if rcode := prescan(z.origin, rr); rcode != dns.RcodeSuccess {
+			return rcode, false
+		}
+		if !z.allowed(key, rr) {
+			return dns.RcodeRefused, false
+		}
+	}
+
+	cur := old.copy()
+	for _, rr := range r.Ns {
+		cur.update(z.origin, rr)
+	}
+
+	d := old.diff(cur, z.origin)
+	if len(d.deleted) == 0 && len(d.added) == 0 && d.oldSOA.Serial == d.newSOA.Serial {
+		return dns.RcodeSuccess, false
+	}
+	if d.oldSOA.Serial == d.newSOA.Serial {
+		soa := dns.Copy(d.newSOA).(*dns.SOA)
+		soa.Serial++
+		cur[z.origin][dns.TypeSOA] = []dns.RR{soa}
+		d.newSOA = soa
+	}
+
+	if err := z.appendJournal(d); err != nil {
+		log.Errorf("Failed to write journal for zone %q: %s", z.origin, err)
+		return dns.RcodeServerFailure, false
+	}
+	z.swap(base.replace(names, cur), d)
+	z.dirty = true
+
+	return dns.RcodeSuccess, true
+}
+
+// allowed returns true if one of the update policies of z allows an update of rr signed with key.
+func (z *Zone) allowed(key string, rr dns.RR) bool {
+	for _, p := range z.UpdatePolicies {
+		if p.allows(key, rr) {
+			return true
+		}
+	}
+	return false
+}
+
+// set replaces the records of z with the ones in s, the result of the changes in diffs.
+func (z *Zone) set(s rrsets, diffs ...diff) error {
+	z1, err := s.zone(z.origin, z.file)
+	if err != nil {
+		return err
+	}
+	z.swap(z1, diffs...)
+	return nil
+}
+
+// updateNames returns the owner names of the records in the prerequisite and update sections of r, and
+// the origin.
+func updateNames(origin string, r *dns.Msg) map[string]struct{} {
+	names := map[string]struct{}{origin: {}}
+	for _, rr := range r.Answer {
+		names[strings.ToLower(rr.Header().Name)] = struct{}{}
+	}
+	for _, rr := range r.Ns {
+		names[strings.ToLower(rr.Header().Name)] = struct{}{}
+	}
+	return names
+}
+
+// replace returns a copy of z in which the records of names are replaced by the ones in s. The records of
+// other names are shared with z, neither z nor its trees are modified.
+func (z *Zone) replace(names map[string]struct{}, s rrsets) *Zone {
+	z1 := &Zone{origin: z.origin, Apex: z.Apex, Tree: z.Tree, NSEC3: z.NSEC3}
+	for name := range names {
+		// Let insert sort the records into the apex, the tree and the NSEC3 chain.
+		n := NewZone(z.origin, "")
+		for _, rrs := range s[name] {
+			for _, rr := range rrs {
+				n.insert(rr)
+			}
+		}
+		if name == z.origin {
+			z1.Apex = n.Apex
+		}
+		e, _ := n.Tree.Search(name)
+		z1.Tree = z1.Tree.Replace(name, e)
+		if n.NSEC3 != nil || z1.NSEC3 != nil {
+			if z1.NSEC3 == nil {
+				z1.NSEC3 = &tree.Tree{}
+			}
+			var e3 *tree.Elem
+			if n.NSEC3 != nil {
+				e3, _ = n.NSEC3.Search(name)
+			}
+			z1.NSEC3 = z1.NSEC3.Replace(name, e3)
+		}
+	}
+	return z1
+}
+
+// rrsets holds the records of a zone by owner name and type. RRSIGs are kept as type RRSIG.
+type rrsets map[string]map[uint16][]dns.RR
+
+// rrsets returns the records of z. The caller must hold a read lock.
+func (z *Zone) rrsets() rrsets {
+	s := rrsets{}
+	s.add(z.SOA)
+	for _, rr := range z.NS {
+		s.add(rr)
+	}
+	for _, rr := range z.SIGSOA {
+		s.add(rr)
+	}
+	for _, rr := range z.SIGNS {
+		s.add(rr)
+	}
+	add := func(_ *tree.Elem, m map[uint16][]dns.RR) error {
+		for _, rrs := range m {
+			for _, rr := range rrs {
+				s.add(rr)
+			}
+		}
+		return nil
+	}
+	z.Walk(add)
+	if z.NSEC3 != nil {
+		z.NSEC3.Walk(add)
+	}
+	return s
+}
+
+// rrsetsOf returns the records of z with one of the owner names in names. The caller must hold a read lock.
+func (z *Zone) rrsetsOf(names map[string]struct{}) rrsets {
+	s := rrsets{}
+	if _, ok := names[z.origin]; ok {
+		s.add(z.SOA)
+		for _, rrs := range [][]dns.RR{z.NS, z.SIGSOA, z.SIGNS} {
+			for _, rr := range rrs {
+				s.add(rr)
+			}
+		}
+	}
+	for name := range names {
+		if e, ok := z.Search(name); ok {
+			for _, rr := range e.All() {
+				s.add(rr)
+			}
+		}
+		if z.NSEC3 == nil {
+			continue
+		}
+		if e, ok := z.NSEC3.Search(name); ok {
+			for _, rr := range e.All() {
+				s.add(rr)
+			}
+		}
+	}
+	return s
+}
+
+func (s rrsets) add(rr dns.RR) {
+	name := strings.ToLower(rr.Header().Name)
+	if s[name] == nil {
+		s[name] = map[uint16][]dns.RR{}
+	}
+	s[name][rr.Header().Rrtype] = append(s[name][rr.Header().Rrtype], rr)
+}
+
+// remove removes the records of type qtype from name, for which del returns true.
+func (s rrsets) remove(name string, qtype uint16, del func(dns.RR) bool) {
+	rrs := s[name][qtype]
+	keep := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		if !del(rr) {
+			keep = append(keep, rr)
+		}
+	}
+	if len(keep) > 0 {
+		s[name][qtype] = keep
+		return
+	}
+	delete(s[name], qtype)
+	if len(s[name]) == 0 {
+		delete(s, name)
+	}
+}
+
+// zone returns a new zone with the records in s.
+func (s rrsets) zone(origin, file string) (*Zone, error) {
+	z := NewZone(origin, file)
+	for _, types := range s {
+		for _, rrs := range types {
+			for _, rr := range rrs {
+				if err := z.insert(rr); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return z, nil
+}
+
+// copy returns a copy of s, the records themselves are shared.
+func (s rrsets) copy() rrsets {
+	s1 := make(rrsets, len(s))
+	for name, types := range s {
+		s1[name] = make(map[uint16][]dns.RR, len(types))
+		for t, rrs := range types {
+			s1[name][t] = append([]dns.RR(nil), rrs...)
+		}
+	}
+	return s1
+}
+
+// prerequisites checks the prerequisites of an update, see RFC 2136 section 3.2.
+func (s rrsets) prerequisites(origin string, rrs []dns.RR) int {
+	values := rrsets{}
+	for _, rr := range rrs {
+		h := rr.Header()
+		if h.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+		name := strings.ToLower(h.Name)
+		if !dns.IsSubDomain(origin, name) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+		case dns.ClassANY:
+			if h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeANY {
+				if len(s[name]) == 0 {
+					return dns.RcodeNameError
+				}
+				continue
+			}
+			if len(s[name][h.Rrtype]) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeANY {
+				if len(s[name]) > 0 {
+					return dns.RcodeYXDomain
+				}
+				continue
+			}
+			if len(s[name][h.Rrtype]) > 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			if h.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+			values.add(rr)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	// RRsets that must exist with exactly these values.
+	for name, types := range values {
+		for t, rrs := range types {
+			if !equal(s[name][t], rrs) {
+				return dns.RcodeNXRrset
+			}
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// prescan checks rr from the update section, see RFC 2136 section 3.4.1.
+func prescan(origin string, rr dns.RR) int {
+	h := rr.Header()
+	if !dns.IsSubDomain(origin, strings.ToLower(h.Name)) {
+		return dns.RcodeNotZone
+	}
+
+	switch h.Class {
+	case dns.ClassINET:
+		if meta(h.Rrtype) {
+			return dns.RcodeFormatError
+		}
+	case dns.ClassANY:
+		if h.Ttl != 0 || h.Rdlength != 0 || (meta(h.Rrtype) && h.Rrtype != dns.TypeANY) {
+			return dns.RcodeFormatError
+		}
+	case dns.ClassNONE:
+		if h.Ttl != 0 || meta(h.Rrtype) {
+			return dns.RcodeFormatError
+		}
+	default:
+		return dns.RcodeFormatError
+	}
+
+	switch h.Rrtype {
+	case dns.TypeNSEC3, dns.TypeNSEC3PARAM:
+		return dns.RcodeRefused
+	}
+	return dns.RcodeSuccess
+}
+
+// update applies rr from the update section to s, see RFC 2136 section 3.4.2.
+func (s rrsets) update(origin string, rr dns.RR) {
+	h := rr.Header()
+	name := strings.ToLower(h.Name)
+	apex := name == origin
+
+	switch h.Class {
+	case dns.ClassINET:
+		rr = dns.Copy(rr)
+		normalize(rr)
+
+		switch h.Rrtype {
+		case dns.TypeSOA:
+			if !apex || !less(s[name][dns.TypeSOA][0].(*dns.SOA).Serial, rr.(*dns.SOA).Serial) {
+				return
+			}
+			s[name][dns.TypeSOA] = []dns.RR{rr}
+			return
+		case dns.TypeCNAME:
+			for t := range s[name] {
+				if t != dns.TypeCNAME && t != dns.TypeRRSIG && t != dns.TypeNSEC {
+					return
+				}
+			}
+			delete(s[name], dns.TypeCNAME)
+		default:
+			if len(s[name][dns.TypeCNAME]) > 0 {
+				return
+			}
+			// An existing record with the same rdata is replaced, to update its TTL.
+			s.remove(name, h.Rrtype, func(r dns.RR) bool { return dns.IsDuplicate(r, rr) })
+		}
+		s.add(rr)
+
+	case dns.ClassANY:
+		if h.Rrtype != dns.TypeANY {
+			if apex && (h.Rrtype == dns.TypeSOA || h.Rrtype == dns.TypeNS) {
+				return
+			}
+			s.remove(name, h.Rrtype, func(dns.RR) bool { return true })
+			return
+		}
+		for t := range s[name] {
+			if apex && (t == dns.TypeSOA || t == dns.TypeNS) {
+				continue
+			}
+			s.remove(name, t, func(dns.RR) bool { return true })
+		}
+
+	case dns.ClassNONE:
+		if h.Rrtype == dns.TypeSOA {
+			return
+		}
+		del := dns.Copy(rr)
+		del.Header().Class = dns.ClassINET
+		if apex && h.Rrtype == dns.TypeNS && len(s[name][dns.TypeNS]) == 1 {
+			return
+		}
+		s.remove(name, h.Rrtype, func(r dns.RR) bool { return dns.IsDuplicate(r, del) })
+	}
+}
+
+// diff returns the difference between s and s1, both must have a SOA record at origin.
+func (s rrsets) diff(s1 rrsets, origin string) diff {
+	d := diff{
+		oldSOA: s[origin][dns.TypeSOA][0].(*dns.SOA),
+		newSOA: s1[origin][dns.TypeSOA][0].(*dns.SOA),
+	}
+	d.deleted = s.subtract(s1)
+	d.added = s1.subtract(s)
+	return d
+}
+
+// subtract returns the records in s that are not in s1, SOA records are skipped.
+func (s rrsets) subtract(s1 rrsets) []dns.RR {
+	var rrs []dns.RR
+	for name, types := range s {
+		for t, set := range types {
+			if t == dns.TypeSOA {
+				continue
+			}
+			for _, rr := range set {
+				if !contains(s1[name][t], rr) {
+					rrs = append(rrs, rr)
+				}
+			}
+		}
+	}
+	return rrs
+}
+
+// contains returns true if rrs contains rr with the same TTL.
+func contains(rrs []dns.RR, rr dns.RR) bool {
+	for _, r := range rrs {
+		if dns.IsDuplicate(r, rr) && r.Header().Ttl == rr.Header().Ttl {
+			return true
+		}
+	}
+	return false
+}
+
+// equal returns true if a and b contain the same records, TTLs are ignored.
+func equal(a, b []dns.RR) bool {
+	for _, rr := range a {
+		if !duplicate(b, rr) {
+			return false
+		}
+	}
+	for _, rr := range b {
+		if !duplicate(a, rr) {
+			return false
+		}
+	}
+	return true
+}
+
+// duplicate returns true if rrs contains rr, TTLs are ignored.
+func duplicate(rrs []dns.RR, rr dns.RR) bool {
+	for _, r := range rrs {
+		if dns.IsDuplicate(r, rr) {
+			return true
+		}
+	}
+	return false
+}
+
+// meta returns true if t is a meta or query type, that can't be used in a zone.
+func meta(t uint16) bool {
+	switch t {
+	case dns.TypeANY, dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB, dns.TypeOPT, dns.TypeTSIG, dns.TypeTKEY:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/plugin/tsig"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const dbUpdateTest = `$ORIGIN example.org.
+@       3600 IN SOA   ns1.example.org. admin.example.org. 1000 7200 3600 1209600 3600
+        3600 IN NS    ns1.example.org.
+ns1     3600 IN A     192.0.2.1
+www     3600 IN A     192.0.2.10
+www     3600 IN TXT   "hello"
+dyn     3600 IN A     192.0.2.20
+`
+
+func newUpdateZone(t *testing.T) (*Zone, string) {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "db.example.org")
+	if err := os.WriteFile(fileName, []byte(dbUpdateTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	z, err := Parse(strings.NewReader(dbUpdateTest), "example.org.", fileName, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.UpdatePolicies = []UpdatePolicy{
+		{Key: "dhcp.key.", Name: "example.org.", Types: map[uint16]struct{}{dns.TypeA: {}, dns.TypeAAAA: {}}},
+		{Key: "acme.key.", Name: "_acme-challenge.example.org.", Types: map[uint16]struct{}{dns.TypeTXT: {}}},
+	}
+	return z, fileName
+}
+
+// update sends the update m signed with key (if not empty) through the tsig plugin to z.
+func update(t *testing.T, z *Zone, key string, m *dns.Msg) int {
+	t.Helper()
+	f := File{Zones: Zones{Z: map[string]*Zone{"example.org.": z}, Names: []string{"example.org."}}}
+	h := &tsig.TSIGServer{Zones: []string{"."}, Next: f}
+	if key != "" {
+		m.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+	// Pack and unpack, so that the rdlength of the records is set, like for a message from the wire.
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := h.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Msg.Rcode
+}
+
+func lookup(z *Zone, qname string, qtype uint16) []dns.RR {
+	r := new(dns.Msg)
+	r.SetQuestion(qname, qtype)
+	state := request.Request{W: &test.ResponseWriter{}, Req: r}
+	answer, _, _, _ := z.Lookup(context.TODO(), state, qname)
+	return answer
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		update func(m *dns.Msg)
+		rcode  int
+		qname  string
+		qtype  uint16
+		answer int
+	}{
+		{
+			name:   "add",
+			key:    "dhcp.key.",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("host.example.org. 300 IN A 192.0.2.30")}) },
+			rcode:  dns.RcodeSuccess,
+			qname:  "host.example.org.", qtype: dns.TypeA, answer: 1,
+		},
+		{
+			name:   "add existing",
+			key:    "dhcp.key.",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("dyn.example.org. 3600 IN A 192.0.2.20")}) },
+			rcode:  dns.RcodeSuccess,
+			qname:  "dyn.example.org.", qtype: dns.TypeA, answer: 1,
+		},
+		{
+			name: "replace",
+			key:  "dhcp.key.",
+			update: func(m *dns.Msg) {
+				m.RemoveRRset([]dns.RR{test.A("dyn.example.org. 0 IN A 192.0.2.20")})
+				m.Insert([]dns.RR{test.A("dyn.example.org. 300 IN A 192.0.2.21"), test.A("dyn.example.org. 300 IN A 192.0.2.22")})
+			},
+			rcode: dns.RcodeSuccess,
+			qname: "dyn.example.org.", qtype: dns.TypeA, answer: 2,
+		},
+		{
+			name:   "delete record",
+			key:    "dhcp.key.",
+			update: func(m *dns.Msg) { m.Remove([]dns.RR{test.A("dyn.example.org. 0 IN A 192.0.2.20")}) },
+			rcode:  dns.RcodeSuccess,
+			qname:  "dyn.example.org.", qtype: dns.TypeA, answer: 0,
+		},
+		{
+			name:   "acme challenge",
+			key:    "acme.key.",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.TXT(`_acme-challenge.example.org. 60 IN TXT "token"`)}) },
+			rcode:  dns.RcodeSuccess,
+			qname:  "_acme-challenge.example.org.", qtype: dns.TypeTXT, answer: 1,
+		},
+		{
+			name:   "unsigned",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("host.example.org. 300 IN A 192.0.2.30")}) },
+			rcode:  dns.RcodeRefused,
+			qname:  "host.example.org.", qtype: dns.TypeA, answer: 0,
+		},
+		{
+			name:   "type not allowed",
+			key:    "dhcp.key.",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.TXT(`www.example.org. 300 IN TXT "bye"`)}) },
+			rcode:  dns.RcodeRefused,
+			qname:  "www.example.org.", qtype: dns.TypeTXT, answer: 1,
+		},
+		{
+			name:   "name not allowed",
+			key:    "acme.key.",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.TXT(`www.example.org. 300 IN TXT "bye"`)}) },
+			rcode:  dns.RcodeRefused,
+			qname:  "www.example.org.", qtype: dns.TypeTXT, answer: 1,
+		},
+		{
+			name:   "delete name needs all types",
+			key:    "dhcp.key.",
+			update: func(m *dns.Msg) { m.RemoveName([]dns.RR{test.A("www.example.org. 0 IN A 192.0.2.10")}) },
+			rcode:  dns.RcodeRefused,
+			qname:  "www.example.org.", qtype: dns.TypeA, answer: 1,
+		},
+		{
+			name:   "not in zone",
+			key:    "dhcp.key.",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("host.example.net. 300 IN A 192.0.2.30")}) },
+			rcode:  dns.RcodeNotZone,
+			qname:  "host.example.org.", qtype: dns.TypeA, answer: 0,
+		},
+		{
+			name: "name not used",
+			key:  "dhcp.key.",
+			update: func(m *dns.Msg) {
+				m.NameNotUsed([]dns.RR{test.A("www.example.org. 0 IN A 192.0.2.10")})
+				m.Insert([]dns.RR{test.A("www.example.org. 300 IN A 192.0.2.11")})
+			},
+			rcode: dns.RcodeYXDomain,
+			qname: "www.example.org.", qtype: dns.TypeA, answer: 1,
+		},
+		{
+			name: "rrset used",
+			key:  "dhcp.key.",
+			update: func(m *dns.Msg) {
+				m.RRsetUsed([]dns.RR{test.AAAA("www.example.org. 0 IN AAAA ::1")})
+				m.Insert([]dns.RR{test.A("www.example.org. 300 IN A 192.0.2.11")})
+			},
+			rcode: dns.RcodeNXRrset,
+			qname: "www.example.org.", qtype: dns.TypeA, answer: 1,
+		},
+		{
+			name: "rrset value",
+			key:  "dhcp.key.",
+			update: func(m *dns.Msg) {
+				m.Used([]dns.RR{test.A("www.example.org. 0 IN A 192.0.2.10")})
+				m.Insert([]dns.RR{test.A("www.example.org. 300 IN A 192.0.2.11")})
+			},
+			rcode: dns.RcodeSuccess,
+			qname: "www.example.org.", qtype: dns.TypeA, answer: 2,
+		},
+		{
+			name: "rrset value mismatch",
+			key:  "dhcp.key.",
+			update: func(m *dns.Msg) {
+				m.Used([]dns.RR{test.A("www.example.org. 0 IN A 192.0.2.99")})
+				m.Insert([]dns.RR{test.A("www.example.org. 300 IN A 192.0.2.11")})
+			},
+			rcode: dns.RcodeNXRrset,
+			qname: "www.example.org.", qtype: dns.TypeA, answer: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			z, _ := newUpdateZone(t)
+			serial := z.SOA.Serial
+
+			m := new(dns.Msg)
+			m.SetUpdate("example.org.")
+			tc.update(m)
+
+			if rcode := update(t, z, tc.key, m); rcode != tc.rcode {
+				t.Fatalf("Expected rcode %s, got %s", dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+			}
+			if answer := lookup(z, tc.qname, tc.qtype); len(answer) != tc.answer {
+				t.Errorf("Expected %d answers, got %d: %v", tc.answer, len(answer), answer)
+			}
+
+			changed := tc.rcode == dns.RcodeSuccess && tc.name != "add existing"
+			if changed && z.SOA.Serial != serial+1 {
+				t.Errorf("Expected SOA serial %d, got %d", serial+1, z.SOA.Serial)
+			}
+			if !changed && z.SOA.Serial != serial {
+				t.Errorf("Expected SOA serial %d, got %d", serial, z.SOA.Serial)
+			}
+		})
+	}
+}
+
+func TestUpdateSharesUnchanged(t *testing.T) {
+	z, _ := newUpdateZone(t)
+	z.RLock()
+	tr := z.Tree
+	www, _ := z.Search("www.example.org.")
+	z.RUnlock()
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.RemoveRRset([]dns.RR{test.A("dyn.example.org. 0 IN A 0.0.0.0")})
+	m.Insert([]dns.RR{test.A("host.example.org. 300 IN A 192.0.2.30")})
+	if rcode := update(t, z, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+
+	// The tree lookups may still hold is not modified.
+	if _, ok := tr.Search("host.example.org."); ok {
+		t.Error("Expected host.example.org. not to be added to the previous tree")
+	}
+	if _, ok := tr.Search("dyn.example.org."); !ok {
+		t.Error("Expected dyn.example.org. to stay in the previous tree")
+	}
+
+	// Names that weren't updated are shared with the previous tree.
+	z.RLock()
+	defer z.RUnlock()
+	if e, _ := z.Search("www.example.org."); e != www {
+		t.Error("Expected the records of www.example.org. to be shared")
+	}
+	if _, ok := z.Search("host.example.org."); !ok {
+		t.Error("Expected host.example.org. to be added")
+	}
+	if _, ok := z.Search("dyn.example.org."); ok {
+		t.Error("Expected dyn.example.org. to be removed")
+	}
+	if z.Len() != tr.Len() {
+		t.Errorf("Expected %d names, got %d", tr.Len(), z.Len())
+	}
+}
+
+func TestUpdateNoPolicies(t *testing.T) {
+	z, _ := newUpdateZone(t)
+	z.UpdatePolicies = nil
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("host.example.org. 300 IN A 192.0.2.30")})
+	if rcode := update(t, z, "dhcp.key.", m); rcode != dns.RcodeRefused {
+		t.Errorf("Expected rcode REFUSED, got %s", dns.RcodeToString[rcode])
+	}
+}
+
+func TestUpdateJournal(t *testing.T) {
+	z, fileName := newUpdateZone(t)
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.RemoveRRset([]dns.RR{test.A("dyn.example.org. 0 IN A 192.0.2.20")})
+	m.Insert([]dns.RR{test.A("dyn.example.org. 300 IN A 192.0.2.21")})
+	if rcode := update(t, z, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+	m = new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.AAAA("host.example.org. 300 IN AAAA 2001:db8::1")})
+	if rcode := update(t, z, "dhcp.key.", m); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+
+	// A restart before the write-back replays the journal.
+	z1, err := Parse(strings.NewReader(dbUpdateTest), "example.org.", fileName, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z1.UpdatePolicies = z.UpdatePolicies
+	if err := z1.replayJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if z1.SOA.Serial != 1002 {
+		t.Errorf("Expected SOA serial 1002 after replay, got %d", z1.SOA.Serial)
+	}
+	if answer := lookup(z1, "dyn.example.org.", dns.TypeA); len(answer) != 1 || answer[0].(*dns.A).A.String() != "192.0.2.21" {
+		t.Errorf("Expected updated A record after replay, got %v", answer)
+	}
+	if answer := lookup(z1, "host.example.org.", dns.TypeAAAA); len(answer) != 1 {
+		t.Errorf("Expected added AAAA record after replay, got %v", answer)
+	}
+
+	// Write-back updates the zone file and removes the journal.
+	if err := z.writeBack(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(z.journal()); !os.IsNotExist(err) {
+		t.Errorf("Expected journal to be removed, got %v", err)
+	}
+	reader, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	z2, err := Parse(reader, "example.org.", fileName, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z2.SOA.Serial != 1002 {
+		t.Errorf("Expected SOA serial 1002 in written back zone, got %d", z2.SOA.Serial)
+	}
+	if answer := lookup(z2, "host.example.org.", dns.TypeAAAA); len(answer) != 1 {
+		t.Errorf("Expected added AAAA record in written back zone, got %v", answer)
+	}
+}
@@ -16,8 +16,9 @@ func (f File) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
 	return z.Transfer(serial)
 }
 
-// Transfer transfers a zone with serial in the returned channel and implements IXFR fallback, by just
-// sending a single SOA record.
+// Transfer transfers a zone with serial in the returned channel. For an IXFR the changes since serial are
+// sent when they are in the history of the zone, otherwise it falls back to sending the whole zone. If
+// the zone is up to date a single SOA record is sent.
 func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 	// get soa and apex
 	apex, err := z.ApexIfDefined()
@@ -25,6 +26,14 @@ func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 		return nil, err
 	}
 
+	var (
+		soa   *dns.SOA
+		diffs []diff
+	)
+	if serial != 0 {
+		soa, diffs = z.ixfr(serial)
+	}
+
 	ch := make(chan []dns.RR)
 	go func() {
 		if serial != 0 && apex[0].(*dns.SOA).Serial == serial { // ixfr fallback, only send SOA
@@ -34,8 +43,22 @@ func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 			return
 		}
 
+		if diffs != nil { // incremental transfer, RFC 1995 section 4
+			ch <- []dns.RR{soa}
+			for _, d := range diffs {
+				ch <- d.rrs()
+			}
+			ch <- []dns.RR{soa}
+
+			close(ch)
+			return
+		}
+
 		ch <- apex
 		z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error { ch <- e.All(); return nil })
+		if z.NSEC3 != nil {
+			z.NSEC3.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error { ch <- e.All(); return nil })
+		}
 		ch <- []dns.RR{apex[0]}
 
 		close(ch)
@@ -22,15 +22,28 @@ type Zone struct {
 	Apex
 	Expired bool
 
+	NSEC3 *tree.Tree // NSEC3 records and their signatures, if the zone is signed with NSEC3.
+
 	sync.RWMutex
 
 	StartupOnce  sync.Once
 	TransferFrom []string
+	OnTransfer   func() // Called after a transfer changed the zone.
 
 	ReloadInterval time.Duration
 	reloadShutdown chan bool
 
 	Upstream *upstream.Upstream // Upstream for looking up external names during the resolution process.
+
+	UpdatePolicies    []UpdatePolicy // Policies allowing dynamic updates, if empty updates are refused.
+	WriteBack         time.Duration  // Interval for writing updates back to the zone file.
+	updateMu          sync.Mutex     // Serializes updates and write-backs.
+	dirty             bool           // Zone has updates that are not written back yet.
+	writeBackShutdown chan bool
+
+	IXFRHistory int    // Maximum number of changed records kept for incremental transfers.
+	history     []diff // Changes to the zone, oldest first.
+	historySize int    // Number of changed records in history.
 }
 
 // Apex contains the apex records of a zone: SOA, NS and their potential signatures.
@@ -44,11 +57,12 @@ type Apex struct {
 // NewZone returns a new zone.
 func NewZone(name, file string) *Zone {
 	return &Zone{
-		origin:         dns.Fqdn(name),
-		origLen:        dns.CountLabel(dns.Fqdn(name)),
-		file:           filepath.Clean(file),
-		Tree:           &tree.Tree{},
-		reloadShutdown: make(chan bool),
+		origin:            dns.Fqdn(name),
+		origLen:           dns.CountLabel(dns.Fqdn(name)),
+		file:              filepath.Clean(file),
+		Tree:              &tree.Tree{},
+		reloadShutdown:    make(chan bool),
+		writeBackShutdown: make(chan bool),
 	}
 }
 
@@ -73,30 +87,51 @@ func (z *Zone) CopyWithoutApex() *Zone {
 
 // Insert inserts r into z.
 func (z *Zone) Insert(r dns.RR) error {
-	// r.Header().Name = strings.ToLower(r.Header().Name)
+	normalize(r)
+	return z.insert(r)
+}
+
+// normalize lowercases the owner name of r and the domain names in its rdata that are used during lookups.
+func normalize(r dns.RR) {
 	if r.Header().Rrtype != dns.TypeSRV {
 		r.Header().Name = strings.ToLower(r.Header().Name)
 	}
 
+	switch x := r.(type) {
+	case *dns.NS:
+		x.Ns = strings.ToLower(x.Ns)
+	case *dns.SOA:
+		x.Ns = strings.ToLower(x.Ns)
+		x.Mbox = strings.ToLower(x.Mbox)
+	case *dns.CNAME:
+		x.Target = strings.ToLower(x.Target)
+	case *dns.MX:
+		x.Mx = strings.ToLower(x.Mx)
+	case *dns.SRV:
+		// x.Target = strings.ToLower(x.Target)
+	}
+}
+
+// insert inserts the normalized r into z.
+func (z *Zone) insert(r dns.RR) error {
 	switch h := r.Header().Rrtype; h {
 	case dns.TypeNS:
-		r.(*dns.NS).Ns = strings.ToLower(r.(*dns.NS).Ns)
-
 		if r.Header().Name == z.origin {
 			z.NS = append(z.NS, r)
 			return nil
 		}
 	case dns.TypeSOA:
-		r.(*dns.SOA).Ns = strings.ToLower(r.(*dns.SOA).Ns)
-		r.(*dns.SOA).Mbox = strings.ToLower(r.(*dns.SOA).Mbox)
-
 		z.SOA = r.(*dns.SOA)
 		return nil
-	case dns.TypeNSEC3, dns.TypeNSEC3PARAM:
-		return fmt.Errorf("NSEC3 zone is not supported, dropping RR: %s for zone: %s", r.Header().Name, z.origin)
+	case dns.TypeNSEC3:
+		z.insertNSEC3(r)
+		return nil
 	case dns.TypeRRSIG:
 		x := r.(*dns.RRSIG)
 		switch x.TypeCovered {
+		case dns.TypeNSEC3:
+			z.insertNSEC3(r)
+			return nil
 		case dns.TypeSOA:
 			z.SIGSOA = append(z.SIGSOA, x)
 			return nil
@@ -106,12 +141,6 @@ func (z *Zone) Insert(r dns.RR) error {
 				return nil
 			}
 		}
-	case dns.TypeCNAME:
-		r.(*dns.CNAME).Target = strings.ToLower(r.(*dns.CNAME).Target)
-	case dns.TypeMX:
-		r.(*dns.MX).Mx = strings.ToLower(r.(*dns.MX).Mx)
-	case dns.TypeSRV:
-		// r.(*dns.SRV).Target = strings.ToLower(r.(*dns.SRV).Target)
 	}
 
 	z.Tree.Insert(r)
@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"net"
 	"sync/atomic"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/coredns/coredns/plugin/debug"
 	"github.com/coredns/coredns/plugin/dnstap"
 	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	proxyPkg "github.com/coredns/coredns/plugin/pkg/proxy"
 	"github.com/coredns/coredns/request"
@@ -248,10 +250,24 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 	}
 
 	if upstreamErr != nil {
-		return dns.RcodeServerFailure, upstreamErr
+		return failure(upstreamErr)
 	}
 
-	return dns.RcodeServerFailure, ErrNoHealthy
+	return failure(ErrNoHealthy)
+}
+
+// failure returns SERVFAIL, with an error that carries an Extended DNS Error that tells the client why no
+// upstream answered. The server adds it to the response it writes, for clients that sent an OPT record.
+func failure(err error) (int, error) {
+	code, text := uint16(dns.ExtendedErrorCodeNetworkError), "Error reaching upstream"
+	var nerr net.Error
+	switch {
+	case errors.Is(err, ErrNoHealthy):
+		code, text = dns.ExtendedErrorCodeNoReachableAuthority, "No healthy upstream"
+	case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &nerr) && nerr.Timeout():
+		code, text = dns.ExtendedErrorCodeNoReachableAuthority, "Timeout reaching upstream"
+	}
+	return dns.RcodeServerFailure, edns.WithExtendedError(err, code, text)
 }
 
 func (f *Forward) match(state request.Request) bool {
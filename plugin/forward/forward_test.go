@@ -2,7 +2,9 @@ package forward
 
 import (
 	"context"
+	"errors"
 	"net"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -11,6 +13,7 @@ import (
 	"github.com/coredns/caddy/caddyfile"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin/dnstap"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/proxy"
 	"github.com/coredns/coredns/plugin/pkg/transport"
 
@@ -157,3 +160,31 @@ func TestForward_Regression_NoBusyLoop(t *testing.T) {
 		})
 	}
 }
+
+func TestFailureExtendedError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code uint16
+	}{
+		{"no healthy upstream", ErrNoHealthy, dns.ExtendedErrorCodeNoReachableAuthority},
+		{"timeout", os.ErrDeadlineExceeded, dns.ExtendedErrorCodeNoReachableAuthority},
+		{"network error", errors.New("connection refused"), dns.ExtendedErrorCodeNetworkError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// The server writes the response, so plugins wrapping forward see the SERVFAIL.
+			rcode, err := failure(tc.err)
+			if rcode != dns.RcodeServerFailure {
+				t.Errorf("Expected rcode %d, got %d", dns.RcodeServerFailure, rcode)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Expected error %v, got %v", tc.err, err)
+			}
+			if code, _, ok := edns.ExtendedErrorFrom(err); !ok || code != tc.code {
+				t.Errorf("Expected Extended DNS Error %d, got %d", tc.code, code)
+			}
+		})
+	}
+}
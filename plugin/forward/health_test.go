@@ -2,6 +2,7 @@ package forward
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -329,7 +330,7 @@ func TestAllUpstreamsDown(t *testing.T) {
 		t.Errorf("Expected Response code: %d, Got: %d", dns.RcodeServerFailure, resp)
 	}
 
-	if err != ErrNoHealthy {
+	if !errors.Is(err, ErrNoHealthy) {
 		t.Errorf("Expected error message: no healthy proxies, Got: %s", err.Error())
 	}
 
@@ -344,7 +345,7 @@ func TestAllUpstreamsDown(t *testing.T) {
 	req = new(dns.Msg)
 	req.SetQuestion("example.org.", dns.TypeA)
 	_, err = f.ServeDNS(context.TODO(), &test.ResponseWriter{}, req)
-	if err == ErrNoHealthy {
+	if errors.Is(err, ErrNoHealthy) {
 		t.Error("Unexpected error message: no healthy proxies")
 	}
 
@@ -0,0 +1,261 @@
+package geoip
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// answers are the answer sets of names, selected by the location of the client.
+type answers struct {
+	names map[string][]*answerSet // answer sets by name, the most specific selector first
+	needs int                     // the schemas that the selectors need from the database
+}
+
+// The kinds of selectors, from the most to the least specific.
+const (
+	selectNet = iota
+	selectASN
+	selectCountry
+	selectContinent
+	selectDefault
+)
+
+var selectors = map[string]int{"net": selectNet, "asn": selectASN, "country": selectCountry, "continent": selectContinent}
+
+// answerSet holds the records that are returned to clients that match its selector.
+type answerSet struct {
+	selector string
+	kind     int
+	prefixes []netip.Prefix
+	values   map[string]bool // ASNs, country or continent codes
+	records  []dns.RR
+}
+
+// defaultAnswerTTL is the TTL of records in the answers file without one.
+const defaultAnswerTTL = 300
+
+// parseAnswers parses the answers file in r. Each line holds a record in the answer set of a name:
+//
+//	NAME SELECTOR [TTL] [CLASS] TYPE RDATA
+//
+// where the SELECTOR is one of net:CIDR, asn:NUMBER, country:CODE or continent:CODE, each can list more values
+// separated by commas, or default. Empty lines and lines starting with # are ignored.
+func parseAnswers(r io.Reader, fileName string) (*answers, error) {
+	a := &answers{names: map[string][]*answerSet{}}
+	sets := map[string]*answerSet{}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("%s:%d: expected NAME SELECTOR TYPE RDATA", fileName, line)
+		}
+		name := dns.Fqdn(strings.ToLower(fields[0]))
+		if _, ok := dns.IsDomainName(name); !ok {
+			return nil, fmt.Errorf("%s:%d: invalid name %q", fileName, line, fields[0])
+		}
+
+		key := name + " " + fields[1]
+		set, ok := sets[key]
+		if !ok {
+			var err error
+			if set, err = parseSelector(fields[1]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %s", fileName, line, err)
+			}
+			sets[key] = set
+			a.names[name] = append(a.names[name], set)
+			switch set.kind {
+			case selectASN:
+				a.needs |= asn
+			case selectCountry, selectContinent:
+				a.needs |= city
+			}
+		}
+
+		rr, err := parseAnswer(name, strings.Join(fields[2:], " "))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", fileName, line, err)
+		}
+		set.records = append(set.records, rr)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, sets := range a.names {
+		sort.SliceStable(sets, func(i, j int) bool { return sets[i].more(sets[j]) })
+	}
+	return a, nil
+}
+
+// parseSelector parses a selector: net:CIDR, asn:NUMBER, country:CODE or continent:CODE, with values
+// separated by commas, or default.
+func parseSelector(s string) (*answerSet, error) {
+	if s == "default" {
+		return &answerSet{selector: s, kind: selectDefault}, nil
+	}
+	kind, values, _ := strings.Cut(s, ":")
+	k, ok := selectors[kind]
+	if !ok || values == "" {
+		return nil, fmt.Errorf("invalid selector %q", s)
+	}
+	set := &answerSet{selector: s, kind: k, values: map[string]bool{}}
+	for _, v := range strings.Split(values, ",") {
+		switch k {
+		case selectNet:
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q", v)
+			}
+			set.prefixes = append(set.prefixes, p.Masked())
+		case selectASN:
+			if _, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(v), "AS"), 10, 32); err != nil {
+				return nil, fmt.Errorf("invalid ASN %q", v)
+			}
+			set.values[strings.TrimPrefix(strings.ToUpper(v), "AS")] = true
+		default:
+			set.values[strings.ToUpper(v)] = true
+		}
+	}
+	return set, nil
+}
+
+// parseAnswer parses the record of name in s, which is [TTL] [CLASS] TYPE RDATA.
+func parseAnswer(name, s string) (dns.RR, error) {
+	zp := dns.NewZoneParser(strings.NewReader(name+" "+s), ".", "")
+	zp.SetDefaultTTL(defaultAnswerTTL)
+	rr, ok := zp.Next()
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no record for %s", name)
+	}
+	return rr, nil
+}
+
+// more returns true if s is more specific than t. Networks with a longer prefix are more specific.
+func (s *answerSet) more(t *answerSet) bool {
+	if s.kind != t.kind {
+		return s.kind < t.kind
+	}
+	return s.kind == selectNet && s.bits() > t.bits()
+}
+
+// bits returns the longest prefix of the networks of s.
+func (s *answerSet) bits() int {
+	bits := 0
+	for _, p := range s.prefixes {
+		bits = max(bits, p.Bits())
+	}
+	return bits
+}
+
+// location is what is known of the location of a client.
+type location struct {
+	addr      netip.Addr
+	asn       string
+	country   string
+	continent string
+}
+
+// matches returns true if the client at loc matches the selector of s.
+func (s *answerSet) matches(loc location) bool {
+	switch s.kind {
+	case selectNet:
+		for _, p := range s.prefixes {
+			if p.Contains(loc.addr) {
+				return true
+			}
+		}
+		return false
+	case selectASN:
+		return s.values[loc.asn]
+	case selectCountry:
+		return s.values[loc.country]
+	case selectContinent:
+		return s.values[loc.continent]
+	}
+	return true
+}
+
+// locate returns the location of the client at addr, as far as the database knows it.
+func (g GeoIP) locate(addr netip.Addr) location {
+	loc := location{addr: addr}
+	if g.answers.needs&city != 0 && g.db.provides&city != 0 {
+		if data, err := g.db.City(addr); err == nil {
+			loc.country, loc.continent = data.Country.ISOCode, data.Continent.Code
+		} else {
+			log.Debugf("Failed to look up the location of %s: %v", addr, err)
+		}
+	}
+	if g.answers.needs&asn != 0 && g.db.provides&asn != 0 {
+		if data, err := g.db.ASN(addr); err == nil {
+			loc.asn = strconv.FormatUint(uint64(data.AutonomousSystemNumber), 10)
+		} else {
+			log.Debugf("Failed to look up the ASN of %s: %v", addr, err)
+		}
+	}
+	return loc
+}
+
+// serveAnswers answers the query in state with the records of the most specific answer set in sets that
+// matches the client. If none matches, or the answer set has no records of the query type, the answer is
+// empty.
+func (g GeoIP) serveAnswers(w dns.ResponseWriter, state request.Request, sets []*answerSet) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative = true
+
+	// The scope stays 0 if the answer doesn't depend on the client's location.
+	var scope uint8
+	addr, subnet, ok := g.clientIP(state)
+	if ok {
+		loc := g.locate(addr)
+		for _, set := range sets {
+			if !set.matches(loc) {
+				continue
+			}
+			if set.kind != selectDefault && subnet != nil {
+				scope = subnet.SourceNetmask
+			}
+			for _, rr := range set.records {
+				if rr.Header().Rrtype == state.QType() || rr.Header().Rrtype == dns.TypeCNAME {
+					m.Answer = append(m.Answer, rr)
+				}
+			}
+			break
+		}
+	}
+
+	state.SizeAndDo(m)
+	// An answer selected by location is valid for the whole subnet the client sent, other answers are
+	// valid for all clients (RFC 7871, section 7.2.1).
+	if o := m.IsEdns0(); o != nil && subnet != nil {
+		o.Option = append(o.Option, &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        subnet.Family,
+			SourceNetmask: subnet.SourceNetmask,
+			SourceScope:   scope,
+			Address:       subnet.Address,
+		})
+	}
+	m = state.Scrub(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
@@ -0,0 +1,206 @@
+package geoip
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const testAnswers = `
+www.example.com.  default           A     203.0.113.10
+www.example.com.  continent:EU      A     192.0.2.10
+www.example.com.  country:gb        A     192.0.2.20
+www.example.com.  country:gb        AAAA  2001:db8::20
+www.example.com.  net:81.2.0.0/16   A     192.0.2.30
+www.example.com.  net:81.2.69.0/24  A     192.0.2.40
+api.example.com.  continent:EU      60 IN CNAME eu.api.example.com.
+api.example.com.  default           CNAME us.api.example.com.
+`
+
+func newAnswersGeoIP(t *testing.T, dbPath, text string, edns0 bool) *GeoIP {
+	t.Helper()
+	g, err := newGeoIP(dbPath, edns0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.answers, err = parseAnswers(strings.NewReader(text), "answers"); err != nil {
+		t.Fatal(err)
+	}
+	g.Next = test.NextHandler(dns.RcodeRefused, nil)
+	return g
+}
+
+func query(t *testing.T, g *GeoIP, remote, qname string, qtype uint16, subnet string) *dns.Msg {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(qname, qtype)
+	if subnet != "" {
+		m.SetEdns0(4096, false)
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_SUBNET{
+			Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP(subnet).To4(),
+		})
+	}
+	rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: remote})
+	rcode, err := g.ServeDNS(context.TODO(), rec, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Msg == nil {
+		return &dns.Msg{MsgHdr: dns.MsgHdr{Rcode: rcode}}
+	}
+	return rec.Msg
+}
+
+func rdatas(m *dns.Msg) []string {
+	s := []string{}
+	for _, rr := range m.Answer {
+		s = append(s, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	return s
+}
+
+func TestAnswers(t *testing.T) {
+	const (
+		country = "www.example.com. country:GB A 192.0.2.20\n"
+		eu      = "www.example.com. continent:EU A 192.0.2.10\n"
+		def     = "www.example.com. default A 203.0.113.10\n"
+	)
+	// Only 81.2.69.142 is in the test database: in GB, in Europe.
+	tests := []struct {
+		remote string
+		text   string
+		qname  string
+		qtype  uint16
+		expect []string
+	}{
+		// The most specific selector wins, regardless of the order in the file.
+		{"81.2.69.142", testAnswers, "www.example.com.", dns.TypeA, []string{"192.0.2.40"}},
+		{"81.2.70.1", testAnswers, "www.example.com.", dns.TypeA, []string{"192.0.2.30"}},
+		{"81.2.69.142", def + eu + country, "www.example.com.", dns.TypeA, []string{"192.0.2.20"}},
+		{"81.2.69.142", def + eu, "www.example.com.", dns.TypeA, []string{"192.0.2.10"}},
+		{"127.0.0.1", testAnswers, "www.example.com.", dns.TypeA, []string{"203.0.113.10"}},
+		// The answer set of the client has no AAAA records.
+		{"127.0.0.1", testAnswers, "www.example.com.", dns.TypeAAAA, []string{}},
+		{"81.2.69.142", testAnswers, "api.example.com.", dns.TypeA, []string{"eu.api.example.com."}},
+		{"127.0.0.1", testAnswers, "api.example.com.", dns.TypeA, []string{"us.api.example.com."}},
+		// No matching answer set.
+		{"127.0.0.1", eu, "www.example.com.", dns.TypeA, []string{}},
+	}
+	for i, tc := range tests {
+		g := newAnswersGeoIP(t, cityDBPath, tc.text, false)
+		m := query(t, g, tc.remote, tc.qname, tc.qtype, "")
+		if !m.Authoritative || m.Rcode != dns.RcodeSuccess {
+			t.Errorf("Test %d: expected an authoritative answer, got %v", i, m)
+		}
+		if got := rdatas(m); strings.Join(got, " ") != strings.Join(tc.expect, " ") {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expect, got)
+		}
+	}
+
+	// Names that aren't in the answers are passed on.
+	g := newAnswersGeoIP(t, cityDBPath, testAnswers, false)
+	if m := query(t, g, "81.2.69.142", "mail.example.com.", dns.TypeA, ""); m.Rcode != dns.RcodeRefused {
+		t.Errorf("Expected the query to be passed to the next plugin, got %v", m)
+	}
+}
+
+func TestAnswersSubnet(t *testing.T) {
+	text := "www.example.com. country:GB AAAA 2001:db8::20\nwww.example.com. default AAAA 2001:db8::1\n"
+	g := newAnswersGeoIP(t, cityDBPath, text, true)
+	m := query(t, g, "127.0.0.1", "www.example.com.", dns.TypeAAAA, "81.2.69.142")
+	if got := rdatas(m); len(got) != 1 || got[0] != "2001:db8::20" {
+		t.Errorf("Expected the answer for GB, got %v", got)
+	}
+	// The subnet option is returned with the scope of the source prefix.
+	if subnet := subnetOption(m); subnet == nil || subnet.SourceScope != 24 {
+		t.Errorf("Expected a subnet option with scope 24, got %v", subnet)
+	}
+
+	// The default answer doesn't depend on the subnet, so its scope is 0.
+	m = query(t, g, "127.0.0.1", "www.example.com.", dns.TypeAAAA, "192.0.2.1")
+	if got := rdatas(m); len(got) != 1 || got[0] != "2001:db8::1" {
+		t.Errorf("Expected the default answer, got %v", got)
+	}
+	if subnet := subnetOption(m); subnet == nil || subnet.SourceScope != 0 {
+		t.Errorf("Expected a subnet option with scope 0, got %v", subnet)
+	}
+
+	// Neither is an empty answer when nothing matches.
+	g = newAnswersGeoIP(t, cityDBPath, "www.example.com. country:GB AAAA 2001:db8::20\n", true)
+	m = query(t, g, "127.0.0.1", "www.example.com.", dns.TypeAAAA, "192.0.2.1")
+	if got := rdatas(m); len(got) != 0 {
+		t.Errorf("Expected no answer, got %v", got)
+	}
+	if subnet := subnetOption(m); subnet == nil || subnet.SourceScope != 0 {
+		t.Errorf("Expected a subnet option with scope 0, got %v", subnet)
+	}
+}
+
+// subnetOption returns the EDNS0 subnet option of m, or nil if there is none.
+func subnetOption(m *dns.Msg) *dns.EDNS0_SUBNET {
+	if o := m.IsEdns0(); o != nil {
+		for _, opt := range o.Option {
+			if e, ok := opt.(*dns.EDNS0_SUBNET); ok {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+func TestAnswersASN(t *testing.T) {
+	text := "www.example.com. asn:AS12345 A 192.0.2.50\nwww.example.com. default A 203.0.113.10\n"
+	g := newAnswersGeoIP(t, asnDBPath, text, false)
+	if got := rdatas(query(t, g, "81.2.69.142", "www.example.com.", dns.TypeA, "")); len(got) != 1 || got[0] != "192.0.2.50" {
+		t.Errorf("Expected the answer for AS 12345, got %v", got)
+	}
+}
+
+func TestParseAnswersErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"www.example.com. default A\n", "answers:1: expected NAME SELECTOR TYPE RDATA"},
+		{"\n# comment\nwww.example.com. city:Cambridge A 192.0.2.1\n", `answers:3: invalid selector "city:Cambridge"`},
+		{"www.example.com. net:10.0.0.0 A 192.0.2.1\n", `answers:1: invalid network "10.0.0.0"`},
+		{"www.example.com. asn:ASX A 192.0.2.1\n", `answers:1: invalid ASN "ASX"`},
+		{"www.example.com. country: A 192.0.2.1\n", `answers:1: invalid selector "country:"`},
+		{"www.example.com. default A 192.0.2.300\n", "answers:1: dns: bad A A"},
+	}
+	for _, tc := range tests {
+		_, err := parseAnswers(strings.NewReader(tc.text), "answers")
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("Expected error starting with %q, got %v", tc.err, err)
+		}
+	}
+}
+
+func TestLoadAnswers(t *testing.T) {
+	f, err := os.Open("testdata/answers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	a, err := parseAnswers(f, "testdata/answers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sets := a.names["www.example.com."]; len(sets) != 4 || sets[0].kind != selectNet || sets[3].kind != selectDefault {
+		t.Errorf("Expected 4 answer sets sorted by specificity, got %v", sets)
+	}
+	if sets := a.names["www.example.com."]; sets[3].records[0].Header().Ttl != 60 || sets[2].records[0].Header().Ttl != defaultAnswerTTL {
+		t.Errorf("Expected the TTLs of the file, or the default TTL")
+	}
+
+	if _, err := loadAnswers("testdata/answers", asn); err == nil {
+		t.Errorf("Expected an error for country selectors with an ASN database")
+	}
+}
@@ -20,9 +20,10 @@ var log = clog.NewWithPlugin(pluginName)
 // GeoIP is a plugin that adds geo location and network data to the request context by looking up
 // an MMDB format database, and which data can be later consumed by other middlewares.
 type GeoIP struct {
-	Next  plugin.Handler
-	db    db
-	edns0 bool
+	Next    plugin.Handler
+	db      db
+	edns0   bool
+	answers *answers
 }
 
 type db struct {
@@ -74,16 +75,22 @@ func newGeoIP(dbPath string, edns0 bool) (*GeoIP, error) {
 
 // ServeDNS implements the plugin.Handler interface.
 func (g GeoIP) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	if g.answers != nil {
+		state := request.Request{W: w, Req: r}
+		if set, ok := g.answers.names[state.Name()]; ok {
+			return g.serveAnswers(w, state, set)
+		}
+	}
 	return plugin.NextOrFailure(pluginName, g.Next, ctx, w, r)
 }
 
-// Metadata implements the metadata.Provider Interface in the metadata plugin, and is used to store
-// the data associated with the source IP of every request.
-func (g GeoIP) Metadata(ctx context.Context, state request.Request) context.Context {
+// clientIP returns the address that is looked up for the client of state. This is its source address, or
+// the address in the EDNS0 subnet option, which is then returned too, when g.edns0 is set.
+func (g GeoIP) clientIP(state request.Request) (netip.Addr, *dns.EDNS0_SUBNET, bool) {
 	srcIP, err := netip.ParseAddr(state.IP())
 	if err != nil {
 		log.Debugf("Failed to parse source IP %q: %v", state.IP(), err)
-		return ctx
+		return srcIP, nil, false
 	}
 
 	if g.edns0 {
@@ -92,15 +99,24 @@ func (g GeoIP) Metadata(ctx context.Context, state request.Request) context.Cont
 				if e, ok := s.(*dns.EDNS0_SUBNET); ok {
 					// e.Address is still a net.IP type
 					if addr, ok := netip.AddrFromSlice(e.Address); ok {
-						srcIP = addr
-					} else {
-						log.Debugf("Failed to parse EDNS0 subnet address %v", e.Address)
+						return addr.Unmap(), e, true
 					}
+					log.Debugf("Failed to parse EDNS0 subnet address %v", e.Address)
 					break
 				}
 			}
 		}
 	}
+	return srcIP, nil, true
+}
+
+// Metadata implements the metadata.Provider Interface in the metadata plugin, and is used to store
+// the data associated with the source IP of every request.
+func (g GeoIP) Metadata(ctx context.Context, state request.Request) context.Context {
+	srcIP, _, ok := g.clientIP(state)
+	if !ok {
+		return ctx
+	}
 
 	if g.db.provides&city != 0 {
 		data, err := g.db.City(srcIP)
@@ -1,6 +1,10 @@
 package geoip
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -25,7 +29,7 @@ func setup(c *caddy.Controller) error {
 }
 
 func geoipParse(c *caddy.Controller) (*GeoIP, error) {
-	var dbPath string
+	var dbPath, answersPath string
 	var edns0 bool
 
 	for c.Next() {
@@ -42,10 +46,23 @@ func geoipParse(c *caddy.Controller) (*GeoIP, error) {
 		}
 
 		for c.NextBlock() {
-			if c.Val() != "edns-subnet" {
+			switch c.Val() {
+			case "edns-subnet":
+				edns0 = true
+			case "answers":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				answersPath = c.Val()
+				if !filepath.IsAbs(answersPath) && dnsserver.GetConfig(c).Root != "" {
+					answersPath = filepath.Join(dnsserver.GetConfig(c).Root, answersPath)
+				}
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+			default:
 				return nil, c.Errf("unknown property %q", c.Val())
 			}
-			edns0 = true
 		}
 	}
 
@@ -53,5 +70,30 @@ func geoipParse(c *caddy.Controller) (*GeoIP, error) {
 	if err != nil {
 		return geoIP, c.Err(err.Error())
 	}
+	if answersPath != "" {
+		if geoIP.answers, err = loadAnswers(answersPath, geoIP.db.provides); err != nil {
+			return nil, c.Err(err.Error())
+		}
+	}
 	return geoIP, nil
 }
+
+// loadAnswers reads the answers file in path. Its selectors must only need the schemas the database provides.
+func loadAnswers(path string, provides int) (*answers, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	a, err := parseAnswers(f, path)
+	if err != nil {
+		return nil, err
+	}
+	if a.needs&city != 0 && provides&city == 0 {
+		return nil, fmt.Errorf("answers %q use country or continent selectors, which need a city database", path)
+	}
+	if a.needs&asn != 0 && provides&asn == 0 {
+		return nil, fmt.Errorf("answers %q use asn selectors, which need an ASN database", path)
+	}
+	return a, nil
+}
@@ -56,6 +56,8 @@ func TestGeoIPParse(t *testing.T) {
 		// Valid - ASN database
 		{false, fmt.Sprintf("%s %s\n", pluginName, asnDBPath), "", asn},
 		{false, fmt.Sprintf("%s %s { edns-subnet }", pluginName, asnDBPath), "", asn},
+		// Valid - answers
+		{false, fmt.Sprintf("%s %s {\n\tanswers %s\n}\n", pluginName, cityDBPath, filepath.Join(fixturesDir, "answers")), "", city},
 
 		// Invalid
 		{true, pluginName, "Wrong argument count", 0},
@@ -66,6 +68,9 @@ func TestGeoIPParse(t *testing.T) {
 		{true, fmt.Sprintf("%s /dbpath { city }", pluginName), "unknown property \"city\"", 0},
 		{true, fmt.Sprintf("%s /invalidPath\n", pluginName), "failed to open database file: open /invalidPath: no such file or directory", 0},
 		{true, fmt.Sprintf("%s %s\n", pluginName, unknownDBPath), "reader does not support the \"UnknownDbType\" database type", 0},
+		{true, fmt.Sprintf("%s %s {\n\tanswers\n}\n", pluginName, cityDBPath), "Wrong argument count", 0},
+		{true, fmt.Sprintf("%s %s {\n\tanswers /invalidPath\n}\n", pluginName, cityDBPath), "open /invalidPath: no such file or directory", 0},
+		{true, fmt.Sprintf("%s %s {\n\tanswers %s\n}\n", pluginName, asnDBPath, filepath.Join(fixturesDir, "answers")), "need a city database", 0},
 	}
 
 	for i, test := range tests {
@@ -0,0 +1,154 @@
+package gslb
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// checker checks the health of an endpoint.
+type checker interface {
+	check(ctx context.Context, addr net.IP) error
+}
+
+// tcpCheck checks that a TCP connection can be made to the port of the endpoint.
+type tcpCheck struct {
+	port string
+}
+
+func (c tcpCheck) check(ctx context.Context, addr net.IP) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(addr.String(), c.port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// httpCheck checks that a GET request to the path on the port of the endpoint returns the status, and
+// a body that matches body, if set. The Host header is the name of the pool.
+type httpCheck struct {
+	scheme string
+	port   string
+	path   string
+	host   string
+	status int
+	body   *regexp.Regexp
+	client *http.Client
+}
+
+// maxBody is the size of the response body that is matched.
+const maxBody = 64 * 1024
+
+func newHTTPCheck(scheme, port, path, host string, status int, body *regexp.Regexp) httpCheck {
+	tr := &http.Transport{
+		TLSClientConfig:   &tls.Config{ServerName: host},
+		DisableKeepAlives: true,
+	}
+	return httpCheck{
+		scheme: scheme, port: port, path: path, host: host, status: status, body: body,
+		client: &http.Client{
+			Transport: tr,
+			// Redirects are reported as their status, not followed.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+func (c httpCheck) check(ctx context.Context, addr net.IP) error {
+	url := fmt.Sprintf("%s://%s%s", c.scheme, net.JoinHostPort(addr.String(), c.port), c.path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Host = c.host
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != c.status {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if c.body == nil {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return err
+	}
+	if !c.body.Match(body) {
+		return fmt.Errorf("body does not match %q", c.body)
+	}
+	return nil
+}
+
+// checkAll checks the endpoints of all pools concurrently and updates their health. An endpoint is down
+// after g.fails consecutive failed checks, and up again after a successful one.
+func (g *GSLB) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, p := range g.pools {
+		if p.check == nil {
+			continue
+		}
+		for _, e := range p.endpoints {
+			wg.Add(1)
+			go func(p *pool, e *endpoint) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(ctx, g.timeout)
+				defer cancel()
+				g.update(p, e, p.check.check(ctx, e.addr))
+			}(p, e)
+		}
+	}
+	wg.Wait()
+}
+
+// update records the result err of a health check of endpoint e in pool p.
+func (g *GSLB) update(p *pool, e *endpoint, err error) {
+	if err == nil {
+		e.fails = 0
+		if !e.healthy.Swap(true) {
+			log.Infof("Endpoint %s of %s is up", e.addr, p.name)
+		}
+		healthy.WithLabelValues(p.name, e.addr.String()).Set(1)
+		return
+	}
+
+	checkFailures.WithLabelValues(p.name, e.addr.String()).Inc()
+	e.fails++
+	if e.fails < g.fails {
+		return
+	}
+	if e.healthy.Swap(false) {
+		log.Warningf("Endpoint %s of %s is down: %s", e.addr, p.name, err)
+	}
+	healthy.WithLabelValues(p.name, e.addr.String()).Set(0)
+}
+
+// run checks the endpoints every g.interval until stop is closed.
+func (g *GSLB) run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	tick := time.NewTicker(g.interval)
+	defer tick.Stop()
+	for {
+		g.checkAll(ctx)
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package gslb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestTCPCheck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	c := tcpCheck{port: port}
+	if err := c.check(context.TODO(), net.ParseIP("127.0.0.1")); err != nil {
+		t.Errorf("Expected the check to pass, got %s", err)
+	}
+	l.Close()
+	if err := c.check(context.TODO(), net.ParseIP("127.0.0.1")); err == nil {
+		t.Errorf("Expected the check to fail on a closed port")
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "www.example.org" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte("status: ok\n"))
+		case "/moved":
+			http.Redirect(w, r, "/health", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	addr := net.ParseIP("127.0.0.1")
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+		ok     bool
+	}{
+		{"/health", 200, "", true},
+		{"/health", 200, "status: ok", true},
+		{"/health", 200, "status: failed", false},
+		{"/down", 200, "", false},
+		{"/down", 503, "", true},
+		{"/moved", 200, "", false},
+	}
+	for _, tc := range tests {
+		var body *regexp.Regexp
+		if tc.body != "" {
+			body = regexp.MustCompile(tc.body)
+		}
+		c := newHTTPCheck("http", port, tc.path, "www.example.org", tc.status, body)
+		if err := c.check(context.TODO(), addr); (err == nil) != tc.ok {
+			t.Errorf("Expected check of %s with status %d and body %q to pass: %t, got %v", tc.path, tc.status, tc.body, tc.ok, err)
+		}
+	}
+}
+
+type fakeCheck map[string]error
+
+func (f fakeCheck) check(_ context.Context, addr net.IP) error { return f[addr.String()] }
+
+func TestCheckAll(t *testing.T) {
+	down := errors.New("down")
+	check := fakeCheck{}
+	p := &pool{
+		name:      "www.example.org.",
+		endpoints: []*endpoint{newEndpoint("192.0.2.1", 1), newEndpoint("192.0.2.2", 1)},
+		check:     check,
+	}
+	g := newGSLB(p)
+	g.fails = 2
+	g.timeout = defaultTimeout
+
+	check["192.0.2.1"] = down
+	g.checkAll(context.TODO())
+	if !p.endpoints[0].healthy.Load() {
+		t.Errorf("Expected the endpoint to be up after one failed check")
+	}
+	g.checkAll(context.TODO())
+	if p.endpoints[0].healthy.Load() || !p.endpoints[1].healthy.Load() {
+		t.Errorf("Expected only the first endpoint to be down after two failed checks")
+	}
+
+	delete(check, "192.0.2.1")
+	g.checkAll(context.TODO())
+	if !p.endpoints[0].healthy.Load() {
+		t.Errorf("Expected the endpoint to be up after a successful check")
+	}
+}
@@ -0,0 +1,65 @@
+// Package gslb implements a plugin that serves names from pools of health checked endpoints.
+package gslb
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// GSLB is a plugin that answers for the names of its pools with the healthy endpoints of the pool.
+type GSLB struct {
+	Next  plugin.Handler
+	Zones []string
+
+	pools    map[string]*pool
+	ttl      uint32
+	interval time.Duration
+	timeout  time.Duration
+	fails    int
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (g *GSLB) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	p, ok := g.pools[qname]
+	if !ok || plugin.Zones(g.Zones).Matches(qname) == "" {
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	// Other types than A and AAAA get a NODATA response.
+	switch state.QType() {
+	case dns.TypeA:
+		for _, ip := range p.choose(ctx, false) {
+			m.Answer = append(m.Answer, &dns.A{Hdr: g.hdr(qname, dns.TypeA), A: ip})
+		}
+	case dns.TypeAAAA:
+		for _, ip := range p.choose(ctx, true) {
+			m.Answer = append(m.Answer, &dns.AAAA{Hdr: g.hdr(qname, dns.TypeAAAA), AAAA: ip})
+		}
+	}
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+func (g *GSLB) hdr(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: g.ttl}
+}
+
+// Name implements the plugin.Handler interface.
+func (g *GSLB) Name() string { return "gslb" }
+
+// isIPv6 returns true when ip is an IPv6 address.
+func isIPv6(ip net.IP) bool { return ip.To4() == nil }
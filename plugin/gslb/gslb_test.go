@@ -0,0 +1,164 @@
+package gslb
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func newGSLB(pools ...*pool) *GSLB {
+	g := &GSLB{Zones: []string{"example.org."}, pools: map[string]*pool{}, ttl: defaultTTL, fails: 1}
+	for _, p := range pools {
+		for _, e := range p.endpoints {
+			e.healthy.Store(true)
+		}
+		g.pools[p.name] = p
+	}
+	return g
+}
+
+func newEndpoint(addr string, weight int) *endpoint {
+	return &endpoint{addr: net.ParseIP(addr), weight: weight}
+}
+
+func answers(t *testing.T, g *GSLB, ctx context.Context, qname string, qtype uint16) []string {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(qname, qtype)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := g.ServeDNS(ctx, rec, m); err != nil {
+		t.Fatal(err)
+	}
+	ips := []string{}
+	for _, rr := range rec.Msg.Answer {
+		if rr.Header().Ttl != defaultTTL {
+			t.Errorf("Expected TTL %d, got %d", defaultTTL, rr.Header().Ttl)
+		}
+		switch rr := rr.(type) {
+		case *dns.A:
+			ips = append(ips, rr.A.String())
+		case *dns.AAAA:
+			ips = append(ips, rr.AAAA.String())
+		}
+	}
+	return ips
+}
+
+func TestGSLBFailover(t *testing.T) {
+	p := &pool{
+		name:      "www.example.org.",
+		endpoints: []*endpoint{newEndpoint("192.0.2.1", 1), newEndpoint("192.0.2.2", 1), newEndpoint("2001:db8::1", 1)},
+	}
+	g := newGSLB(p)
+
+	if ips := answers(t, g, context.TODO(), "www.example.org.", dns.TypeA); len(ips) != 1 || ips[0] != "192.0.2.1" {
+		t.Errorf("Expected the first endpoint, got %v", ips)
+	}
+	if ips := answers(t, g, context.TODO(), "www.example.org.", dns.TypeAAAA); len(ips) != 1 || ips[0] != "2001:db8::1" {
+		t.Errorf("Expected the IPv6 endpoint, got %v", ips)
+	}
+	if ips := answers(t, g, context.TODO(), "www.example.org.", dns.TypeMX); len(ips) != 0 {
+		t.Errorf("Expected no answer for MX, got %v", ips)
+	}
+
+	p.endpoints[0].healthy.Store(false)
+	if ips := answers(t, g, context.TODO(), "www.example.org.", dns.TypeA); len(ips) != 1 || ips[0] != "192.0.2.2" {
+		t.Errorf("Expected the second endpoint, got %v", ips)
+	}
+
+	// All down and no fallback addresses: fail open with the first endpoint.
+	p.endpoints[1].healthy.Store(false)
+	if ips := answers(t, g, context.TODO(), "www.example.org.", dns.TypeA); len(ips) != 1 || ips[0] != "192.0.2.1" {
+		t.Errorf("Expected the first endpoint, got %v", ips)
+	}
+
+	p.fallback = []net.IP{net.ParseIP("198.51.100.1")}
+	if ips := answers(t, g, context.TODO(), "www.example.org.", dns.TypeA); len(ips) != 1 || ips[0] != "198.51.100.1" {
+		t.Errorf("Expected the fallback address, got %v", ips)
+	}
+}
+
+func TestGSLBWeighted(t *testing.T) {
+	p := &pool{
+		name:      "www.example.org.",
+		policy:    weighted,
+		endpoints: []*endpoint{newEndpoint("192.0.2.1", 3), newEndpoint("192.0.2.2", 1), newEndpoint("192.0.2.3", 1)},
+	}
+	g := newGSLB(p)
+
+	count := map[string]int{}
+	for range 50 {
+		for _, ip := range answers(t, g, context.TODO(), "www.example.org.", dns.TypeA) {
+			count[ip]++
+		}
+	}
+	if count["192.0.2.1"] != 30 || count["192.0.2.2"] != 10 || count["192.0.2.3"] != 10 {
+		t.Errorf("Expected answers in the ratio of the weights, got %v", count)
+	}
+
+	p.endpoints[0].healthy.Store(false)
+	count = map[string]int{}
+	for range 10 {
+		for _, ip := range answers(t, g, context.TODO(), "www.example.org.", dns.TypeA) {
+			count[ip]++
+		}
+	}
+	if count["192.0.2.1"] != 0 || count["192.0.2.2"] != 5 || count["192.0.2.3"] != 5 {
+		t.Errorf("Expected only healthy endpoints, got %v", count)
+	}
+}
+
+func TestGSLBGeo(t *testing.T) {
+	amsterdam := &endpoint{addr: net.ParseIP("192.0.2.1"), located: true, lat: 52.37, lon: 4.89}
+	newYork := &endpoint{addr: net.ParseIP("192.0.2.2"), located: true, lat: 40.71, lon: -74.01}
+	g := newGSLB(&pool{name: "www.example.org.", policy: geo, endpoints: []*endpoint{amsterdam, newYork}})
+
+	located := func(lat, lon string) context.Context {
+		ctx := metadata.ContextWithMetadata(context.TODO())
+		metadata.SetValueFunc(ctx, "geoip/latitude", func() string { return lat })
+		metadata.SetValueFunc(ctx, "geoip/longitude", func() string { return lon })
+		return ctx
+	}
+
+	// A client in Boston gets New York, one in Berlin gets Amsterdam.
+	if ips := answers(t, g, located("42.36", "-71.06"), "www.example.org.", dns.TypeA); len(ips) != 1 || ips[0] != "192.0.2.2" {
+		t.Errorf("Expected the New York endpoint, got %v", ips)
+	}
+	if ips := answers(t, g, located("52.52", "13.40"), "www.example.org.", dns.TypeA); len(ips) != 1 || ips[0] != "192.0.2.1" {
+		t.Errorf("Expected the Amsterdam endpoint, got %v", ips)
+	}
+	// Unless it is down.
+	amsterdam.healthy.Store(false)
+	if ips := answers(t, g, located("52.52", "13.40"), "www.example.org.", dns.TypeA); len(ips) != 1 || ips[0] != "192.0.2.2" {
+		t.Errorf("Expected the New York endpoint, got %v", ips)
+	}
+	// Clients that can't be located get the first healthy endpoint.
+	amsterdam.healthy.Store(true)
+	if ips := answers(t, g, context.TODO(), "www.example.org.", dns.TypeA); len(ips) != 1 || ips[0] != "192.0.2.1" {
+		t.Errorf("Expected the first endpoint, got %v", ips)
+	}
+}
+
+func TestGSLBNext(t *testing.T) {
+	g := newGSLB(&pool{name: "www.example.org.", endpoints: []*endpoint{newEndpoint("192.0.2.1", 1)}})
+	g.Next = test.NextHandler(dns.RcodeNameError, nil)
+
+	m := new(dns.Msg)
+	m.SetQuestion("other.example.org.", dns.TypeA)
+	if rcode, _ := g.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m); rcode != dns.RcodeNameError {
+		t.Errorf("Expected names without a pool to be passed on, got rcode %d", rcode)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// Amsterdam to New York is about 5860 km.
+	if d := distance(52.37, 4.89, 40.71, -74.01); d < 5800 || d > 5900 {
+		t.Errorf("Expected a distance of about 5860 km, got %f", d)
+	}
+}
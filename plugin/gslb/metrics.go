@@ -0,0 +1,25 @@
+package gslb
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Variables declared for monitoring.
+var (
+	healthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "gslb",
+		Name:      "endpoint_healthy",
+		Help:      "Gauge of the health of an endpoint, 1 if it is healthy and 0 if it is down.",
+	}, []string{"pool", "endpoint"})
+
+	checkFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "gslb",
+		Name:      "health_check_failures_total",
+		Help:      "Counter of failed health checks of an endpoint.",
+	}, []string{"pool", "endpoint"})
+)
@@ -0,0 +1,164 @@
+package gslb
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coredns/coredns/plugin/metadata"
+)
+
+// policy selects the endpoint that is returned from the healthy endpoints of a pool.
+type policy int
+
+const (
+	// failover returns the first healthy endpoint, in the configured order.
+	failover policy = iota
+	// weighted returns the healthy endpoints in a smooth weighted round-robin.
+	weighted
+	// geo returns the healthy endpoint closest to the client, as located by the geoip plugin.
+	geo
+)
+
+var policies = map[string]policy{"failover": failover, "weighted": weighted, "geo": geo}
+
+// pool is the set of endpoints a name is served from.
+type pool struct {
+	name      string
+	policy    policy
+	endpoints []*endpoint
+	check     checker  // nil if the endpoints aren't checked
+	fallback  []net.IP // returned when all endpoints are down
+
+	mu sync.Mutex // protects the round-robin state of the endpoints
+}
+
+// endpoint is an address of a pool.
+type endpoint struct {
+	addr     net.IP
+	weight   int
+	located  bool
+	lat, lon float64
+
+	healthy atomic.Bool
+	fails   int // consecutive failed health checks, only used by the checker
+	current int // round-robin state, protected by pool.mu
+}
+
+// choose returns the addresses of the IPv6 or IPv4 endpoints to answer with. If none of them is healthy,
+// the fallback addresses are returned, or all endpoints if there are no fallback addresses.
+func (p *pool) choose(ctx context.Context, v6 bool) []net.IP {
+	all := []*endpoint{}
+	healthy := []*endpoint{}
+	for _, e := range p.endpoints {
+		if isIPv6(e.addr) != v6 {
+			continue
+		}
+		all = append(all, e)
+		if e.healthy.Load() {
+			healthy = append(healthy, e)
+		}
+	}
+
+	if len(healthy) == 0 {
+		ips := []net.IP{}
+		for _, ip := range p.fallback {
+			if isIPv6(ip) == v6 {
+				ips = append(ips, ip)
+			}
+		}
+		if len(p.fallback) > 0 {
+			return ips
+		}
+		// Without fallback addresses, failing open is better than not answering at all.
+		healthy = all
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	var e *endpoint
+	switch p.policy {
+	case weighted:
+		e = p.next(healthy)
+	case geo:
+		e = closest(ctx, healthy)
+	default:
+		e = healthy[0]
+	}
+	return []net.IP{e.addr}
+}
+
+// next returns the next endpoint of endpoints in a smooth weighted round-robin, as done by nginx.
+func (p *pool) next(endpoints []*endpoint) *endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		best  *endpoint
+		total int
+	)
+	for _, e := range endpoints {
+		e.current += e.weight
+		total += e.weight
+		if best == nil || e.current > best.current {
+			best = e
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// closest returns the endpoint of endpoints closest to the client. If the client or the endpoints can't
+// be located, the first endpoint is returned.
+func closest(ctx context.Context, endpoints []*endpoint) *endpoint {
+	lat, lon, ok := client(ctx)
+	if !ok {
+		return endpoints[0]
+	}
+	var (
+		best     *endpoint
+		shortest = math.Inf(1)
+	)
+	for _, e := range endpoints {
+		if !e.located {
+			continue
+		}
+		if d := distance(lat, lon, e.lat, e.lon); d < shortest {
+			best, shortest = e, d
+		}
+	}
+	if best == nil {
+		return endpoints[0]
+	}
+	return best
+}
+
+// client returns the location of the client, from the metadata of the geoip plugin.
+func client(ctx context.Context) (lat, lon float64, ok bool) {
+	latf, lonf := metadata.ValueFunc(ctx, "geoip/latitude"), metadata.ValueFunc(ctx, "geoip/longitude")
+	if latf == nil || lonf == nil {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(latf(), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	lon, err = strconv.ParseFloat(lonf(), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// distance returns the great-circle distance in kilometers between two locations, with the haversine formula.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const radius = 6371
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dlat, dlon := rad(lat2-lat1), rad(lon2-lon1)
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * radius * math.Asin(math.Sqrt(a))
+}
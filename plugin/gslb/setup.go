@@ -0,0 +1,287 @@
+package gslb
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("gslb")
+
+func init() { plugin.Register("gslb", setup) }
+
+const (
+	defaultTTL      = 30
+	defaultInterval = 10 * time.Second
+	defaultTimeout  = 2 * time.Second
+	defaultFails    = 2
+)
+
+func setup(c *caddy.Controller) error {
+	g, err := gslbParse(c)
+	if err != nil {
+		return plugin.Error("gslb", err)
+	}
+
+	stop := make(chan struct{})
+	c.OnStartup(func() error {
+		go g.run(stop)
+		return nil
+	})
+	c.OnShutdown(func() error {
+		close(stop)
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		g.Next = next
+		return g
+	})
+
+	return nil
+}
+
+func gslbParse(c *caddy.Controller) (*GSLB, error) {
+	g := &GSLB{
+		pools:    map[string]*pool{},
+		ttl:      defaultTTL,
+		interval: defaultInterval,
+		timeout:  defaultTimeout,
+		fails:    defaultFails,
+	}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		g.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "pool":
+				args := c.RemainingArgs()
+				if len(args) < 1 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				name := dns.Fqdn(strings.ToLower(args[0]))
+				if plugin.Zones(g.Zones).Matches(name) == "" {
+					return nil, c.Errf("pool %s is not in the zones %v", name, g.Zones)
+				}
+				if _, ok := g.pools[name]; ok {
+					return nil, c.Errf("pool %s is already defined", name)
+				}
+				p := &pool{name: name}
+				if len(args) == 2 {
+					pol, ok := policies[args[1]]
+					if !ok {
+						return nil, c.Errf("unknown policy %q", args[1])
+					}
+					p.policy = pol
+				}
+				g.pools[name] = p
+
+			case "endpoint":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				p, err := g.pool(c, args[0])
+				if err != nil {
+					return nil, err
+				}
+				e, err := parseEndpoint(c, args[1:])
+				if err != nil {
+					return nil, err
+				}
+				p.endpoints = append(p.endpoints, e)
+
+			case "check":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				p, err := g.pool(c, args[0])
+				if err != nil {
+					return nil, err
+				}
+				if p.check, err = parseCheck(c, p.name, args[1:]); err != nil {
+					return nil, err
+				}
+
+			case "fallback":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				p, err := g.pool(c, args[0])
+				if err != nil {
+					return nil, err
+				}
+				for _, a := range args[1:] {
+					ip := net.ParseIP(a)
+					if ip == nil {
+						return nil, c.Errf("invalid address %q", a)
+					}
+					p.fallback = append(p.fallback, ip)
+				}
+
+			case "interval", "timeout":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil || d <= 0 {
+					return nil, c.Errf("invalid %s %q", x, c.Val())
+				}
+				if x == "interval" {
+					g.interval = d
+				} else {
+					g.timeout = d
+				}
+
+			case "fails":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(c.Val())
+				if err != nil || n < 1 {
+					return nil, c.Errf("invalid fails %q", c.Val())
+				}
+				g.fails = n
+
+			case "ttl":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				ttl, err := strconv.ParseUint(c.Val(), 10, 32)
+				if err != nil {
+					return nil, c.Errf("invalid ttl %q", c.Val())
+				}
+				g.ttl = uint32(ttl)
+
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+	}
+
+	if len(g.pools) == 0 {
+		return nil, c.Err("no pools defined")
+	}
+	for _, p := range g.pools {
+		if len(p.endpoints) == 0 {
+			return nil, c.Errf("pool %s has no endpoints", p.name)
+		}
+		for _, e := range p.endpoints {
+			e.healthy.Store(true)
+			healthy.WithLabelValues(p.name, e.addr.String()).Set(1)
+		}
+	}
+	return g, nil
+}
+
+// pool returns the pool name, which must be defined before it is used.
+func (g *GSLB) pool(c *caddy.Controller, name string) (*pool, error) {
+	p, ok := g.pools[dns.Fqdn(strings.ToLower(name))]
+	if !ok {
+		return nil, c.Errf("unknown pool %q", name)
+	}
+	return p, nil
+}
+
+// parseEndpoint parses ADDRESS [weight WEIGHT] [location LATITUDE LONGITUDE].
+func parseEndpoint(c *caddy.Controller, args []string) (*endpoint, error) {
+	e := &endpoint{addr: net.ParseIP(args[0]), weight: 1}
+	if e.addr == nil {
+		return nil, c.Errf("invalid address %q", args[0])
+	}
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "weight":
+			if i+1 >= len(args) {
+				return nil, c.ArgErr()
+			}
+			w, err := strconv.Atoi(args[i+1])
+			if err != nil || w < 1 {
+				return nil, c.Errf("invalid weight %q", args[i+1])
+			}
+			e.weight = w
+			i++
+		case "location":
+			if i+2 >= len(args) {
+				return nil, c.ArgErr()
+			}
+			lat, err := strconv.ParseFloat(args[i+1], 64)
+			if err != nil || lat < -90 || lat > 90 {
+				return nil, c.Errf("invalid latitude %q", args[i+1])
+			}
+			lon, err := strconv.ParseFloat(args[i+2], 64)
+			if err != nil || lon < -180 || lon > 180 {
+				return nil, c.Errf("invalid longitude %q", args[i+2])
+			}
+			e.lat, e.lon, e.located = lat, lon, true
+			i += 2
+		default:
+			return nil, c.Errf("unknown endpoint option %q", args[i])
+		}
+	}
+	return e, nil
+}
+
+// parseCheck parses tcp PORT, or http|https PORT PATH [STATUS [BODY]].
+func parseCheck(c *caddy.Controller, host string, args []string) (checker, error) {
+	if len(args) < 2 {
+		return nil, c.ArgErr()
+	}
+	port := args[1]
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return nil, c.Errf("invalid port %q", port)
+	}
+
+	switch args[0] {
+	case "tcp":
+		if len(args) != 2 {
+			return nil, c.ArgErr()
+		}
+		return tcpCheck{port: port}, nil
+
+	case "http", "https":
+		if len(args) < 3 || len(args) > 5 {
+			return nil, c.ArgErr()
+		}
+		path := args[2]
+		if !strings.HasPrefix(path, "/") {
+			return nil, c.Errf("invalid path %q", path)
+		}
+		status := 200
+		if len(args) > 3 {
+			s, err := strconv.Atoi(args[3])
+			if err != nil || s < 100 || s > 599 {
+				return nil, c.Errf("invalid status %q", args[3])
+			}
+			status = s
+		}
+		var body *regexp.Regexp
+		if len(args) > 4 {
+			re, err := regexp.Compile(args[4])
+			if err != nil {
+				return nil, c.Errf("invalid body regexp %q: %s", args[4], err)
+			}
+			body = re
+		}
+		return newHTTPCheck(args[0], port, path, strings.TrimSuffix(host, "."), status, body), nil
+	}
+	return nil, c.Errf("unknown check %q", args[0])
+}
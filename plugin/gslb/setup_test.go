@@ -0,0 +1,79 @@
+package gslb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("dns", `gslb example.org {
+		pool www.example.org weighted
+		endpoint www.example.org 192.0.2.1 weight 3 location 52.37 4.89
+		endpoint www.example.org 2001:db8::1
+		check www.example.org http 80 /health 200 ok
+		fallback www.example.org 198.51.100.1
+		pool api.example.org.
+		endpoint api.example.org 192.0.2.10
+		check api.example.org tcp 443
+		interval 5s
+		timeout 1s
+		fails 3
+		ttl 10
+	}`)
+	g, err := gslbParse(c)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+	if len(g.pools) != 2 || g.ttl != 10 || g.fails != 3 || g.interval.String() != "5s" || g.timeout.String() != "1s" {
+		t.Errorf("Unexpected configuration %+v", g)
+	}
+
+	www := g.pools["www.example.org."]
+	if www.policy != weighted || len(www.endpoints) != 2 || len(www.fallback) != 1 {
+		t.Errorf("Unexpected pool %+v", www)
+	}
+	if e := www.endpoints[0]; e.weight != 3 || !e.located || e.lat != 52.37 || e.lon != 4.89 || !e.healthy.Load() {
+		t.Errorf("Unexpected endpoint %+v", e)
+	}
+	if c, ok := www.check.(httpCheck); !ok || c.host != "www.example.org" || c.body == nil {
+		t.Errorf("Unexpected check %+v", www.check)
+	}
+	if c, ok := g.pools["api.example.org."].check.(tcpCheck); !ok || c.port != "443" {
+		t.Errorf("Unexpected check %+v", g.pools["api.example.org."].check)
+	}
+}
+
+func TestSetupErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`gslb`, "no pools defined"},
+		{"gslb example.org {\npool www.example.net\n}", "not in the zones"},
+		{"gslb example.org {\npool www.example.org\n}", "has no endpoints"},
+		{"gslb example.org {\npool www.example.org nearest\n}", "unknown policy"},
+		{"gslb example.org {\nendpoint www.example.org 192.0.2.1\n}", "unknown pool"},
+		{"gslb example.org {\npool www.example.org\nendpoint www.example.org 192.0.2\n}", "invalid address"},
+		{"gslb example.org {\npool www.example.org\nendpoint www.example.org 192.0.2.1 weight 0\n}", "invalid weight"},
+		{"gslb example.org {\npool www.example.org\nendpoint www.example.org 192.0.2.1 location 91 0\n}", "invalid latitude"},
+		{"gslb example.org {\npool www.example.org\ncheck www.example.org icmp 80\n}", "unknown check"},
+		{"gslb example.org {\npool www.example.org\ncheck www.example.org http 80 health\n}", "invalid path"},
+		{"gslb example.org {\npool www.example.org\ncheck www.example.org tcp 0\n}", "invalid port"},
+		{"gslb example.org {\ninterval 0s\n}", "invalid interval"},
+		{"gslb example.org {\nfails 0\n}", "invalid fails"},
+		{"gslb example.org {\nblah\n}", "unknown property"},
+		{"gslb\ngslb", "this plugin"},
+	}
+	for _, tc := range tests {
+		_, err := gslbParse(caddy.NewTestController("dns", tc.input))
+		if err == nil {
+			t.Errorf("Expected error for %q", tc.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Expected error containing %q for %q, got %q", tc.err, tc.input, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package external
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/kubernetes"
@@ -322,6 +323,15 @@ func (external) SvcExtIndexReverse(ip string) (result []*object.Service) {
 	return result
 }
 
+func (external) IngressHostIndex(string) []*object.Ingress     { return nil }
+func (external) GatewayIndex(string) []*object.Gateway         { return nil }
+func (external) GatewayHostIndex(string) []*object.Gateway     { return nil }
+func (external) HTTPRouteHostIndex(string) []*object.HTTPRoute { return nil }
+
+func (external) GetCachedNodeByName(name string) (*object.Node, error) {
+	return nil, fmt.Errorf("node not found")
+}
+
 func (external) GetNamespaceByName(name string) (*object.Namespace, error) {
 	return &object.Namespace{
 		Name: name,
@@ -0,0 +1,49 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+
+	api "k8s.io/api/core/v1"
+)
+
+// srv returns the SRV priority and weight of the records of svc, or of the endpoints ep of svc if ep is not nil.
+// The annotations of the EndpointSlice take precedence over the ones of the service. If annotations are not
+// enabled both are 0, which means the default.
+func (k *Kubernetes) srv(svc *object.Service, ep *object.Endpoints) (priority, weight int) {
+	if !k.annotations {
+		return 0, 0
+	}
+	priority, weight = svc.SRV.Priority, svc.SRV.Weight
+	if ep != nil {
+		if ep.SRV.Priority != 0 {
+			priority = ep.SRV.Priority
+		}
+		if ep.SRV.Weight != 0 {
+			weight = ep.SRV.Weight
+		}
+	}
+	return priority, weight
+}
+
+// findServiceTXT returns the TXT records from the annotations of the services matching r. Only names of
+// services have TXT records, and not the ones of ExternalName services as these are a CNAME.
+func (k *Kubernetes) findServiceTXT(r recordRequest, name string) (services []msg.Service) {
+	if !k.annotations || r.podOrSvc != Svc || r.endpoint != "" || r.port != "" || r.protocol != "" {
+		return nil
+	}
+	if !k.namespaceExposed(r.namespace) {
+		return nil
+	}
+	for _, svc := range k.APIConn.SvcIndex(object.ServiceKey(r.service, r.namespace)) {
+		if !match(r.namespace, svc.Namespace) || !match(r.service, svc.Name) || svc.Type == api.ServiceTypeExternalName {
+			continue
+		}
+		for _, txt := range svc.TXT {
+			services = append(services, msg.Service{Text: txt, TTL: k.ttl, Key: msg.Path(strings.ToLower(name), coredns)})
+		}
+	}
+	return services
+}
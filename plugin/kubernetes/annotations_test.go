@@ -0,0 +1,206 @@
+package kubernetes
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// APIConnAnnotationsTest serves services and endpoints with annotations.
+type APIConnAnnotationsTest struct{ APIConnServeTest }
+
+var annotatedSvcIndex = map[string][]*object.Service{
+	"web.testns": {{
+		Name:       "web",
+		Namespace:  "testns",
+		Index:      object.ServiceKey("web", "testns"),
+		Type:       api.ServiceTypeClusterIP,
+		ClusterIPs: []string{"10.0.0.5"},
+		Ports:      []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}},
+		TXT:        []string{"version=1.2", "proto=h2"},
+		SRV:        object.SRV{Priority: 10},
+	}},
+	"db.testns": {{
+		Name:       "db",
+		Namespace:  "testns",
+		Index:      object.ServiceKey("db", "testns"),
+		Type:       api.ServiceTypeClusterIP,
+		ClusterIPs: []string{api.ClusterIPNone},
+		SRV:        object.SRV{Weight: 1},
+	}},
+	"ext.testns": {{
+		Name:         "ext",
+		Namespace:    "testns",
+		Index:        object.ServiceKey("ext", "testns"),
+		Type:         api.ServiceTypeExternalName,
+		ExternalName: "ext.example.org",
+		TXT:          []string{"ignored"},
+	}},
+}
+
+var annotatedEpsIndex = map[string][]*object.Endpoints{
+	"db.testns": {
+		{
+			Name:      "db-a",
+			Namespace: "testns",
+			Index:     object.EndpointsKey("db", "testns"),
+			Subsets: []object.EndpointSubset{{
+				Addresses: []object.EndpointAddress{{IP: "172.0.1.1"}, {IP: "172.0.1.2"}},
+				Ports:     []object.EndpointPort{{Port: 5432, Protocol: "tcp", Name: "pg"}},
+			}},
+			SRV: object.SRV{Weight: 3},
+		},
+		{
+			// No annotations, the weight of the service is used.
+			Name:      "db-b",
+			Namespace: "testns",
+			Index:     object.EndpointsKey("db", "testns"),
+			Subsets: []object.EndpointSubset{{
+				Addresses: []object.EndpointAddress{{IP: "172.0.1.3"}},
+				Ports:     []object.EndpointPort{{Port: 5432, Protocol: "tcp", Name: "pg"}},
+			}},
+		},
+	},
+}
+
+func (APIConnAnnotationsTest) SvcIndex(s string) []*object.Service  { return annotatedSvcIndex[s] }
+func (APIConnAnnotationsTest) EpIndex(s string) []*object.Endpoints { return annotatedEpsIndex[s] }
+
+func (APIConnAnnotationsTest) ServiceList() (svcs []*object.Service) {
+	for _, svc := range annotatedSvcIndex {
+		svcs = append(svcs, svc...)
+	}
+	return svcs
+}
+
+func TestServeDNSAnnotations(t *testing.T) {
+	k := New([]string{"cluster.local."})
+	k.APIConn = &APIConnAnnotationsTest{}
+	k.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	k.annotations = true
+
+	tests := []struct {
+		qname string
+		qtype uint16
+		want  []string
+	}{
+		{"web.testns.svc.cluster.local.", dns.TypeTXT, []string{
+			`web.testns.svc.cluster.local.	5	IN	TXT	"version=1.2"`,
+			`web.testns.svc.cluster.local.	5	IN	TXT	"proto=h2"`,
+		}},
+		{"_http._tcp.web.testns.svc.cluster.local.", dns.TypeSRV, []string{
+			"_http._tcp.web.testns.svc.cluster.local.	5	IN	SRV	10 100 80 web.testns.svc.cluster.local.",
+		}},
+		// The weights are relative: 3 for each endpoint in db-a, and 1 for the one in db-b.
+		{"_pg._tcp.db.testns.svc.cluster.local.", dns.TypeSRV, []string{
+			"_pg._tcp.db.testns.svc.cluster.local.	5	IN	SRV	0 42 5432 172-0-1-1.db.testns.svc.cluster.local.",
+			"_pg._tcp.db.testns.svc.cluster.local.	5	IN	SRV	0 42 5432 172-0-1-2.db.testns.svc.cluster.local.",
+			"_pg._tcp.db.testns.svc.cluster.local.	5	IN	SRV	0 14 5432 172-0-1-3.db.testns.svc.cluster.local.",
+		}},
+		{"db.testns.svc.cluster.local.", dns.TypeTXT, nil},
+		{"172-0-1-1.db.testns.svc.cluster.local.", dns.TypeTXT, nil},
+	}
+
+	for i, tc := range tests {
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := k.ServeDNS(context.TODO(), w, new(dns.Msg).SetQuestion(tc.qname, tc.qtype)); err != nil {
+			t.Fatalf("Test %d: %s", i, err)
+		}
+		if w.Msg.Rcode != dns.RcodeSuccess {
+			t.Errorf("Test %d: expected NOERROR for %s, got %s", i, tc.qname, dns.RcodeToString[w.Msg.Rcode])
+		}
+		if len(w.Msg.Answer) != len(tc.want) {
+			t.Errorf("Test %d: expected %d records for %s, got %v", i, len(tc.want), tc.qname, w.Msg.Answer)
+			continue
+		}
+		got := map[string]bool{}
+		for _, rr := range w.Msg.Answer {
+			got[rr.String()] = true
+		}
+		for _, want := range tc.want {
+			if !got[want] {
+				t.Errorf("Test %d: expected %q in %v", i, want, w.Msg.Answer)
+			}
+		}
+	}
+
+	// Without the annotations option they are ignored.
+	k.annotations = false
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	k.ServeDNS(context.TODO(), w, new(dns.Msg).SetQuestion("web.testns.svc.cluster.local.", dns.TypeTXT))
+	if len(w.Msg.Answer) != 0 {
+		t.Errorf("Expected no TXT records without annotations, got %v", w.Msg.Answer)
+	}
+}
+
+func TestTransferAnnotations(t *testing.T) {
+	k := New([]string{"cluster.local."})
+	k.APIConn = &APIConnAnnotationsTest{}
+	k.Namespaces = map[string]struct{}{"testns": {}}
+	k.localIPs = []net.IP{net.ParseIP("10.0.0.10")}
+	k.annotations = true
+
+	ch, err := k.Transfer("cluster.local.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for rrs := range ch {
+		for _, rr := range rrs {
+			got[rr.String()] = true
+		}
+	}
+	for _, want := range []string{
+		`web.testns.svc.cluster.local.	5	IN	TXT	"version=1.2"`,
+		`web.testns.svc.cluster.local.	5	IN	TXT	"proto=h2"`,
+		"_http._tcp.web.testns.svc.cluster.local.	5	IN	SRV	10 100 80 web.testns.svc.cluster.local.",
+		"_pg._tcp.db.testns.svc.cluster.local.	5	IN	SRV	0 42 5432 172-0-1-1.db.testns.svc.cluster.local.",
+		"_pg._tcp.db.testns.svc.cluster.local.	5	IN	SRV	0 14 5432 172-0-1-3.db.testns.svc.cluster.local.",
+	} {
+		if !got[want] {
+			t.Errorf("Expected %q in the transfer", want)
+		}
+	}
+	if got[`ext.testns.svc.cluster.local.	5	IN	TXT	"ignored"`] {
+		t.Errorf("Expected no TXT record for an ExternalName service")
+	}
+}
+
+func TestAnnotationsParse(t *testing.T) {
+	svc := &api.Service{ObjectMeta: meta.ObjectMeta{Name: "web", Namespace: "testns", Annotations: map[string]string{
+		object.AnnotationTXT:         "version=1.2\n\n  proto=h2 \n",
+		object.AnnotationSRVPriority: "10",
+		object.AnnotationSRVWeight:   "70000", // out of range
+	}}}
+	o, err := object.ToService(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := o.(*object.Service)
+	if len(s.TXT) != 2 || s.TXT[0] != "version=1.2" || s.TXT[1] != "proto=h2" {
+		t.Errorf("Expected TXT [version=1.2 proto=h2], got %v", s.TXT)
+	}
+	if s.SRV != (object.SRV{Priority: 10}) {
+		t.Errorf("Expected SRV priority 10 and no weight, got %+v", s.SRV)
+	}
+
+	eps := &discovery.EndpointSlice{ObjectMeta: meta.ObjectMeta{Name: "web-a", Namespace: "testns", Annotations: map[string]string{
+		object.AnnotationSRVWeight: "3",
+	}}}
+	o, err = object.EndpointSliceToEndpoints(eps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := o.(*object.Endpoints); e.SRV != (object.SRV{Weight: 3}) {
+		t.Errorf("Expected SRV weight 3, got %+v", e.SRV)
+	}
+}
@@ -41,6 +41,13 @@ func (m *mockAPIConnector) McEpIndex(s string) []*object.MultiClusterEndpoints {
 func (m *mockAPIConnector) GetNodeByName(ctx context.Context, name string) (*api.Node, error) {
 	return nil, nil
 }
+func (m *mockAPIConnector) IngressHostIndex(string) []*object.Ingress     { return nil }
+func (m *mockAPIConnector) GatewayIndex(string) []*object.Gateway         { return nil }
+func (m *mockAPIConnector) GatewayHostIndex(string) []*object.Gateway     { return nil }
+func (m *mockAPIConnector) HTTPRouteHostIndex(string) []*object.HTTPRoute { return nil }
+func (m *mockAPIConnector) GetCachedNodeByName(name string) (*object.Node, error) {
+	return nil, nil
+}
 func (m *mockAPIConnector) GetNamespaceByName(name string) (*object.Namespace, error) {
 	return nil, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,11 +13,15 @@ import (
 
 	api "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
+	networking "k8s.io/api/networking/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	mcs "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
@@ -32,6 +37,10 @@ const (
 	epIPIndex                   = "EndpointsIP"
 	svcImportNameNamespaceIndex = "ServiceImportNameNamespace"
 	mcEpNameNamespaceIndex      = "MultiClusterEndpointsImportNameNamespace"
+	ingressHostIndex            = "IngressHost"
+	gatewayNameNamespaceIndex   = "GatewayNameNamespace"
+	gatewayHostIndex            = "GatewayHost"
+	httpRouteHostIndex          = "HTTPRouteHost"
 )
 
 type ModifiedMode int
@@ -54,8 +63,13 @@ type dnsController interface {
 	EpIndex(string) []*object.Endpoints
 	EpIndexReverse(string) []*object.Endpoints
 	McEpIndex(string) []*object.MultiClusterEndpoints
+	IngressHostIndex(string) []*object.Ingress
+	GatewayIndex(string) []*object.Gateway
+	GatewayHostIndex(string) []*object.Gateway
+	HTTPRouteHostIndex(string) []*object.HTTPRoute
 
 	GetNodeByName(context.Context, string) (*api.Node, error)
+	GetCachedNodeByName(string) (*object.Node, error)
 	GetNamespaceByName(string) (*object.Namespace, error)
 
 	Run()
@@ -80,6 +94,7 @@ type dnsControl struct {
 
 	client    kubernetes.Interface
 	mcsClient mcsClientset.MulticlusterV1alpha1Interface
+	dynClient dynamic.Interface
 
 	selector          labels.Selector
 	namespaceSelector labels.Selector
@@ -88,6 +103,10 @@ type dnsControl struct {
 	podController       cache.Controller
 	epController        cache.Controller
 	nsController        cache.Controller
+	nodeController      cache.Controller
+	ingressController   cache.Controller
+	gatewayController   cache.Controller
+	httpRouteController cache.Controller
 	svcImportController cache.Controller
 	mcEpController      cache.Controller
 
@@ -95,6 +114,10 @@ type dnsControl struct {
 	podLister       cache.Indexer
 	epLister        cache.Indexer
 	nsLister        cache.Store
+	nodeLister      cache.Store
+	ingressLister   cache.Indexer
+	gatewayLister   cache.Indexer
+	httpRouteLister cache.Indexer
 	svcImportLister cache.Indexer
 	mcEpLister      cache.Indexer
 
@@ -113,6 +136,9 @@ type dnsControl struct {
 type dnsControlOpts struct {
 	initPodCache       bool
 	initEndpointsCache bool
+	initNodeCache      bool
+	initIngressCache   bool
+	initGatewayCache   bool
 	ignoreEmptyService bool
 
 	// Label handling.
@@ -127,10 +153,11 @@ type dnsControlOpts struct {
 }
 
 // newdnsController creates a controller for CoreDNS.
-func newdnsController(ctx context.Context, kubeClient kubernetes.Interface, mcsClient mcsClientset.MulticlusterV1alpha1Interface, opts dnsControlOpts) *dnsControl {
+func newdnsController(ctx context.Context, kubeClient kubernetes.Interface, mcsClient mcsClientset.MulticlusterV1alpha1Interface, dynClient dynamic.Interface, opts dnsControlOpts) *dnsControl {
 	dns := dnsControl{
 		client:            kubeClient,
 		mcsClient:         mcsClient,
+		dynClient:         dynClient,
 		selector:          opts.selector,
 		namespaceSelector: opts.namespaceSelector,
 		stopCh:            make(chan struct{}),
@@ -191,6 +218,62 @@ func newdnsController(ctx context.Context, kubeClient kubernetes.Interface, mcsC
 		object.DefaultProcessor(object.ToNamespace, nil),
 	)
 
+	if opts.initNodeCache {
+		dns.nodeLister, dns.nodeController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  nodeListFunc(ctx, dns.client),
+				WatchFunc: nodeWatchFunc(ctx, dns.client),
+			},
+			&api.Node{},
+			cache.ResourceEventHandlerFuncs{},
+			cache.Indexers{},
+			object.DefaultProcessor(object.ToNode, nil),
+		)
+	}
+
+	// Ingresses, Gateways and HTTPRoutes only change the records of the external zones.
+	extHandlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { dns.updateExtModified() },
+		UpdateFunc: func(oldObj, newObj any) { dns.detectHostnameChanges(oldObj, newObj) },
+		DeleteFunc: func(any) { dns.updateExtModified() },
+	}
+
+	if opts.initIngressCache {
+		dns.ingressLister, dns.ingressController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  ingressListFunc(ctx, dns.client, api.NamespaceAll, dns.selector),
+				WatchFunc: ingressWatchFunc(ctx, dns.client, api.NamespaceAll, dns.selector),
+			},
+			&networking.Ingress{},
+			extHandlers,
+			cache.Indexers{ingressHostIndex: ingressHostIndexFunc},
+			object.DefaultProcessor(object.ToIngress, nil),
+		)
+	}
+
+	if opts.initGatewayCache {
+		dns.gatewayLister, dns.gatewayController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  dynamicListFunc(ctx, dns.dynClient, object.GatewayResource, api.NamespaceAll, dns.selector),
+				WatchFunc: dynamicWatchFunc(ctx, dns.dynClient, object.GatewayResource, api.NamespaceAll, dns.selector),
+			},
+			&unstructured.Unstructured{},
+			extHandlers,
+			cache.Indexers{gatewayNameNamespaceIndex: gatewayNameNamespaceIndexFunc, gatewayHostIndex: gatewayHostIndexFunc},
+			object.DefaultProcessor(object.ToGateway, nil),
+		)
+		dns.httpRouteLister, dns.httpRouteController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  dynamicListFunc(ctx, dns.dynClient, object.HTTPRouteResource, api.NamespaceAll, dns.selector),
+				WatchFunc: dynamicWatchFunc(ctx, dns.dynClient, object.HTTPRouteResource, api.NamespaceAll, dns.selector),
+			},
+			&unstructured.Unstructured{},
+			extHandlers,
+			cache.Indexers{httpRouteHostIndex: httpRouteHostIndexFunc},
+			object.DefaultProcessor(object.ToHTTPRoute, nil),
+		)
+	}
+
 	if len(opts.multiclusterZones) > 0 {
 		mcsEpReq, _ := labels.NewRequirement(mcs.LabelServiceName, selection.Exists, []string{})
 		mcsEpSelector := dns.selector
@@ -267,6 +350,38 @@ func svcExtIPIndexFunc(obj any) ([]string, error) {
 	return idx, nil
 }
 
+func ingressHostIndexFunc(obj any) ([]string, error) {
+	ing, ok := obj.(*object.Ingress)
+	if !ok {
+		return nil, errObj
+	}
+	return ing.Hosts, nil
+}
+
+func gatewayNameNamespaceIndexFunc(obj any) ([]string, error) {
+	g, ok := obj.(*object.Gateway)
+	if !ok {
+		return nil, errObj
+	}
+	return []string{g.Index}, nil
+}
+
+func gatewayHostIndexFunc(obj any) ([]string, error) {
+	g, ok := obj.(*object.Gateway)
+	if !ok {
+		return nil, errObj
+	}
+	return g.Hosts, nil
+}
+
+func httpRouteHostIndexFunc(obj any) ([]string, error) {
+	r, ok := obj.(*object.HTTPRoute)
+	if !ok {
+		return nil, errObj
+	}
+	return r.Hosts, nil
+}
+
 func svcNameNamespaceIndexFunc(obj any) ([]string, error) {
 	s, ok := obj.(*object.Service)
 	if !ok {
@@ -347,6 +462,30 @@ func namespaceListFunc(ctx context.Context, c kubernetes.Interface, s labels.Sel
 	}
 }
 
+func ingressListFunc(ctx context.Context, c kubernetes.Interface, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		if s != nil {
+			opts.LabelSelector = s.String()
+		}
+		return c.NetworkingV1().Ingresses(ns).List(ctx, opts)
+	}
+}
+
+func dynamicListFunc(ctx context.Context, c dynamic.Interface, r schema.GroupVersionResource, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		if s != nil {
+			opts.LabelSelector = s.String()
+		}
+		return c.Resource(r).Namespace(ns).List(ctx, opts)
+	}
+}
+
+func nodeListFunc(ctx context.Context, c kubernetes.Interface) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().Nodes().List(ctx, opts)
+	}
+}
+
 func serviceImportListFunc(ctx context.Context, c mcsClientset.MulticlusterV1alpha1Interface, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
 	return func(opts meta.ListOptions) (runtime.Object, error) {
 		if s != nil {
@@ -396,6 +535,30 @@ func namespaceWatchFunc(ctx context.Context, c kubernetes.Interface, s labels.Se
 	}
 }
 
+func ingressWatchFunc(ctx context.Context, c kubernetes.Interface, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		if s != nil {
+			options.LabelSelector = s.String()
+		}
+		return c.NetworkingV1().Ingresses(ns).Watch(ctx, options)
+	}
+}
+
+func dynamicWatchFunc(ctx context.Context, c dynamic.Interface, r schema.GroupVersionResource, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		if s != nil {
+			options.LabelSelector = s.String()
+		}
+		return c.Resource(r).Namespace(ns).Watch(ctx, options)
+	}
+}
+
+func nodeWatchFunc(ctx context.Context, c kubernetes.Interface) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		return c.CoreV1().Nodes().Watch(ctx, options)
+	}
+}
+
 func serviceImportWatchFunc(ctx context.Context, c mcsClientset.MulticlusterV1alpha1Interface, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
 	return func(options meta.ListOptions) (watch.Interface, error) {
 		if s != nil {
@@ -433,6 +596,16 @@ func (dns *dnsControl) Run() {
 		go dns.podController.Run(dns.stopCh)
 	}
 	go dns.nsController.Run(dns.stopCh)
+	if dns.nodeController != nil {
+		go dns.nodeController.Run(dns.stopCh)
+	}
+	if dns.ingressController != nil {
+		go dns.ingressController.Run(dns.stopCh)
+	}
+	if dns.gatewayController != nil {
+		go dns.gatewayController.Run(dns.stopCh)
+		go dns.httpRouteController.Run(dns.stopCh)
+	}
 	if dns.svcImportController != nil {
 		go dns.svcImportController.Run(dns.stopCh)
 	}
@@ -462,7 +635,19 @@ func (dns *dnsControl) HasSynced() bool {
 	if dns.mcEpController != nil {
 		f = dns.mcEpController.HasSynced()
 	}
-	return a && b && c && d && e && f
+	g := true
+	if dns.nodeController != nil {
+		g = dns.nodeController.HasSynced()
+	}
+	h := true
+	if dns.ingressController != nil {
+		h = dns.ingressController.HasSynced()
+	}
+	i := true
+	if dns.gatewayController != nil {
+		i = dns.gatewayController.HasSynced() && dns.httpRouteController.HasSynced()
+	}
+	return a && b && c && d && e && f && g && h && i
 }
 
 func (dns *dnsControl) ServiceList() (svcs []*object.Service) {
@@ -623,6 +808,72 @@ func (dns *dnsControl) McEpIndex(idx string) (ep []*object.MultiClusterEndpoints
 	return ep
 }
 
+// IngressHostIndex returns the ingresses with a rule for host, which may be a wildcard.
+func (dns *dnsControl) IngressHostIndex(host string) (ings []*object.Ingress) {
+	if dns.ingressLister == nil {
+		return nil
+	}
+	os, err := dns.ingressLister.ByIndex(ingressHostIndex, host)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		ing, ok := o.(*object.Ingress)
+		if !ok {
+			continue
+		}
+		ings = append(ings, ing)
+	}
+	return ings
+}
+
+// GatewayIndex returns the gateways with the key idx, see object.GatewayKey.
+func (dns *dnsControl) GatewayIndex(idx string) (gws []*object.Gateway) {
+	return dns.gatewayByIndex(gatewayNameNamespaceIndex, idx)
+}
+
+// GatewayHostIndex returns the gateways with a listener for host, which may be a wildcard.
+func (dns *dnsControl) GatewayHostIndex(host string) (gws []*object.Gateway) {
+	return dns.gatewayByIndex(gatewayHostIndex, host)
+}
+
+func (dns *dnsControl) gatewayByIndex(index, idx string) (gws []*object.Gateway) {
+	if dns.gatewayLister == nil {
+		return nil
+	}
+	os, err := dns.gatewayLister.ByIndex(index, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		g, ok := o.(*object.Gateway)
+		if !ok {
+			continue
+		}
+		gws = append(gws, g)
+	}
+	return gws
+}
+
+// HTTPRouteHostIndex returns the HTTP routes for host, which may be a wildcard.
+func (dns *dnsControl) HTTPRouteHostIndex(host string) (routes []*object.HTTPRoute) {
+	if dns.httpRouteLister == nil {
+		return nil
+	}
+	os, err := dns.httpRouteLister.ByIndex(httpRouteHostIndex, host)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		r, ok := o.(*object.HTTPRoute)
+		if !ok {
+			continue
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
 // GetNodeByName return the node by name. If nothing is found an error is
 // returned. This query causes a round trip to the k8s API server, so use
 // sparingly. Currently, this is only used for Federation.
@@ -647,6 +898,26 @@ func (dns *dnsControl) GetNamespaceByName(name string) (*object.Namespace, error
 	return ns, nil
 }
 
+// GetCachedNodeByName returns the node by name from the node cache, which is only kept when topology aware
+// answers are enabled. If nothing is found an error is returned.
+func (dns *dnsControl) GetCachedNodeByName(name string) (*object.Node, error) {
+	if dns.nodeLister == nil {
+		return nil, fmt.Errorf("node cache not enabled")
+	}
+	o, exists, err := dns.nodeLister.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("node not found")
+	}
+	node, ok := o.(*object.Node)
+	if !ok {
+		return nil, fmt.Errorf("found key but not node")
+	}
+	return node, nil
+}
+
 func (dns *dnsControl) Add(obj any)               { dns.updateModified() }
 func (dns *dnsControl) Delete(obj any)            { dns.updateModified() }
 func (dns *dnsControl) Update(oldObj, newObj any) { dns.detectChanges(oldObj, newObj) }
@@ -711,6 +982,9 @@ func subsetsEquivalent(sa, sb object.EndpointSubset) bool {
 		if aaddr.Hostname != baddr.Hostname {
 			return false
 		}
+		if aaddr.Zone != baddr.Zone || !slices.Equal(aaddr.ForZones, baddr.ForZones) {
+			return false
+		}
 	}
 
 	for port, aport := range sa.Ports {
@@ -735,7 +1009,7 @@ func endpointsEquivalent(a, b *object.Endpoints) bool {
 		return false
 	}
 
-	if len(a.Subsets) != len(b.Subsets) {
+	if len(a.Subsets) != len(b.Subsets) || a.SRV != b.SRV {
 		return false
 	}
 
@@ -820,9 +1094,37 @@ func serviceModified(oldObj, newObj any) (intSvc, extSvc bool) {
 		}
 	}
 
+	// The annotations only affect internal zone records
+	if !slices.Equal(oldSvc.TXT, newSvc.TXT) || oldSvc.SRV != newSvc.SRV {
+		intSvc = true
+	}
+
 	return intSvc, extSvc
 }
 
+// detectHostnameChanges updates the external modified timestamp if the hosts or addresses of an ingress,
+// gateway or HTTP route changed.
+func (dns *dnsControl) detectHostnameChanges(oldObj, newObj any) {
+	switch o := oldObj.(type) {
+	case *object.Ingress:
+		n, ok := newObj.(*object.Ingress)
+		if ok && slices.Equal(o.Hosts, n.Hosts) && slices.Equal(o.Addresses, n.Addresses) {
+			return
+		}
+	case *object.Gateway:
+		n, ok := newObj.(*object.Gateway)
+		if ok && slices.Equal(o.Hosts, n.Hosts) && slices.Equal(o.Addresses, n.Addresses) {
+			return
+		}
+	case *object.HTTPRoute:
+		n, ok := newObj.(*object.HTTPRoute)
+		if ok && slices.Equal(o.Hosts, n.Hosts) && slices.Equal(o.Gateways, n.Gateways) {
+			return
+		}
+	}
+	dns.updateExtModified()
+}
+
 // serviceImportEquivalent checks if the update to a ServiceImport is something
 // that matters to us or if they are effectively equivalent.
 func serviceImportEquivalent(oldObj, newObj any) bool {
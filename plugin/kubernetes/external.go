@@ -38,6 +38,10 @@ func (k *Kubernetes) External(state request.Request, headless bool) ([]msg.Servi
 		// for invalid reverse names, fall through to determine proper nxdomain/nodata response
 	}
 
+	if svcs := k.hostnameServices(state.Name()); len(svcs) > 0 {
+		return svcs, dns.RcodeSuccess
+	}
+
 	base, _ := dnsutil.TrimZone(state.Name(), state.Zone)
 
 	segs := dns.SplitDomainName(base)
@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/etcd/msg"
@@ -115,6 +116,15 @@ func (external) GetNodeByName(ctx context.Context, name string) (*api.Node, erro
 func (external) SvcIndex(s string) []*object.Service                               { return svcIndexExternal[s] }
 func (external) PodIndex(string) []*object.Pod                                     { return nil }
 
+func (external) IngressHostIndex(string) []*object.Ingress     { return nil }
+func (external) GatewayIndex(string) []*object.Gateway         { return nil }
+func (external) GatewayHostIndex(string) []*object.Gateway     { return nil }
+func (external) HTTPRouteHostIndex(string) []*object.HTTPRoute { return nil }
+
+func (external) GetCachedNodeByName(name string) (*object.Node, error) {
+	return nil, fmt.Errorf("node not found")
+}
+
 func (external) GetNamespaceByName(name string) (*object.Namespace, error) {
 	return &object.Namespace{
 		Name: name,
@@ -1141,6 +1141,15 @@ func (APIConnServeTest) GetNodeByName(ctx context.Context, name string) (*api.No
 	}, nil
 }
 
+func (APIConnServeTest) IngressHostIndex(string) []*object.Ingress     { return nil }
+func (APIConnServeTest) GatewayIndex(string) []*object.Gateway         { return nil }
+func (APIConnServeTest) GatewayHostIndex(string) []*object.Gateway     { return nil }
+func (APIConnServeTest) HTTPRouteHostIndex(string) []*object.HTTPRoute { return nil }
+
+func (APIConnServeTest) GetCachedNodeByName(name string) (*object.Node, error) {
+	return nil, fmt.Errorf("node not found")
+}
+
 func (APIConnServeTest) GetNamespaceByName(name string) (*object.Namespace, error) {
 	if name == "pod-nons" { // handler_pod_verified_test.go uses this for non-existent namespace.
 		return nil, fmt.Errorf("namespace not found")
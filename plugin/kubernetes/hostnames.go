@@ -0,0 +1,65 @@
+package kubernetes
+
+import (
+	"github.com/coredns/coredns/plugin/etcd/msg"
+
+	"github.com/miekg/dns"
+)
+
+// The sources of hostnames for the external zones, see the hostnames option.
+const (
+	hostnamesIngress = "ingress"
+	hostnamesGateway = "gateway"
+)
+
+// hostnameServices returns the load balancer addresses of the ingresses, gateways and HTTP routes in the
+// exposed namespaces that have name as a host. An exact host is preferred over a wildcard, and a wildcard
+// closer to name over one further away. As in the Ingress API, a wildcard in an ingress only matches a single
+// label, in the Gateway API it matches one or more labels.
+func (k *Kubernetes) hostnameServices(name string) []msg.Service {
+	if !k.opts.initIngressCache && !k.opts.initGatewayCache {
+		return nil
+	}
+
+	labels := dns.Split(name)
+	for i := range labels {
+		host := name
+		if i > 0 {
+			host = "*." + name[labels[i]:]
+		}
+
+		var addrs []string
+		if i < 2 {
+			for _, ing := range k.APIConn.IngressHostIndex(host) {
+				if k.namespaceExposed(ing.Namespace) {
+					addrs = append(addrs, ing.Addresses...)
+				}
+			}
+		}
+		for _, g := range k.APIConn.GatewayHostIndex(host) {
+			if k.namespaceExposed(g.Namespace) {
+				addrs = append(addrs, g.Addresses...)
+			}
+		}
+		for _, r := range k.APIConn.HTTPRouteHostIndex(host) {
+			if !k.namespaceExposed(r.Namespace) {
+				continue
+			}
+			for _, key := range r.Gateways {
+				for _, g := range k.APIConn.GatewayIndex(key) {
+					addrs = append(addrs, g.Addresses...)
+				}
+			}
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+
+		services := make([]msg.Service, len(addrs))
+		for j, addr := range addrs {
+			services[j] = msg.Service{Host: addr, TTL: k.ttl, Key: msg.Path(name, coredns)}
+		}
+		return services
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	mcsClientsetFake "sigs.k8s.io/mcs-api/pkg/client/clientset/versioned/fake"
+)
+
+func ingress(name, namespace string, labels map[string]string, hosts []string, lb ...networking.IngressLoadBalancerIngress) *networking.Ingress {
+	ing := &networking.Ingress{ObjectMeta: meta.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+	for _, h := range hosts {
+		ing.Spec.Rules = append(ing.Spec.Rules, networking.IngressRule{Host: h})
+	}
+	ing.Status.LoadBalancer.Ingress = lb
+	return ing
+}
+
+func gateway(name, namespace string, hostnames []string, addresses ...map[string]any) *unstructured.Unstructured {
+	var listeners []any
+	for _, h := range hostnames {
+		listeners = append(listeners, map[string]any{"name": h, "hostname": h})
+	}
+	var addrs []any
+	for _, a := range addresses {
+		addrs = append(addrs, a)
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]any{"name": name, "namespace": namespace},
+		"spec":       map[string]any{"listeners": listeners},
+		"status":     map[string]any{"addresses": addrs},
+	}}
+}
+
+func httpRoute(name, namespace string, hostnames []any, parents ...any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]any{"name": name, "namespace": namespace},
+		"spec":       map[string]any{"hostnames": hostnames, "parentRefs": parents},
+	}}
+}
+
+func kubernetesWithHostnames(ctx context.Context, t *testing.T) *Kubernetes {
+	t.Helper()
+	client := fake.NewSimpleClientset()
+	mcsClient := mcsClientsetFake.NewSimpleClientset()
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		object.GatewayResource:   "GatewayList",
+		object.HTTPRouteResource: "HTTPRouteList",
+	})
+
+	for _, ns := range []string{"web", "infra", "hidden"} {
+		if _, err := client.CoreV1().Namespaces().Create(ctx, &api.Namespace{ObjectMeta: meta.ObjectMeta{Name: ns}}, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ing := range []*networking.Ingress{
+		ingress("shop", "web", nil, []string{"shop.example.org", "Store.example.org"}, networking.IngressLoadBalancerIngress{IP: "192.0.2.1"}),
+		ingress("wild", "web", nil, []string{"*.apps.example.org"}, networking.IngressLoadBalancerIngress{Hostname: "lb.cloud.example.net"}),
+		ingress("secret", "hidden", nil, []string{"secret.example.org"}, networking.IngressLoadBalancerIngress{IP: "192.0.2.9"}),
+		ingress("unlabeled", "web", map[string]string{"dns": "off"}, []string{"off.example.org"}, networking.IngressLoadBalancerIngress{IP: "192.0.2.8"}),
+	} {
+		if _, err := client.NetworkingV1().Ingresses(ing.Namespace).Create(ctx, ing, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for r, objs := range map[schema.GroupVersionResource][]*unstructured.Unstructured{
+		object.GatewayResource: {
+			gateway("gw", "infra", []string{"*.gw.example.org"}, map[string]any{"type": "IPAddress", "value": "192.0.2.10"}, map[string]any{"value": "2001:db8::10"}),
+		},
+		object.HTTPRouteResource: {
+			httpRoute("api", "web", []any{"api.example.org"}, map[string]any{"name": "gw", "namespace": "infra"}),
+			httpRoute("local", "web", []any{"local.example.org"}, map[string]any{"name": "gw"}),
+		},
+	} {
+		for _, o := range objs {
+			if _, err := dynClient.Resource(r).Namespace(o.GetNamespace()).Create(ctx, o, meta.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	dco := dnsControlOpts{
+		zones:            []string{"cluster.local."},
+		initIngressCache: true,
+		initGatewayCache: true,
+		selector:         mustSelector(t, "dns!=off"),
+	}
+	k := New([]string{"cluster.local."})
+	k.APIConn = newdnsController(ctx, client, mcsClient.MulticlusterV1alpha1(), dynClient, dco)
+	k.opts = dco
+	k.Namespaces = map[string]struct{}{"web": {}, "infra": {}}
+	return k
+}
+
+func mustSelector(t *testing.T, s string) labels.Selector {
+	t.Helper()
+	sel, err := labels.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sel
+}
+
+func TestHostnames(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	k := kubernetesWithHostnames(ctx, t)
+	go k.APIConn.Run()
+	defer k.APIConn.Stop()
+	for !k.APIConn.HasSynced() {
+		time.Sleep(time.Millisecond)
+	}
+
+	tests := []struct {
+		qname string
+		hosts []string
+	}{
+		{"shop.example.org.", []string{"192.0.2.1"}},
+		{"store.example.org.", []string{"192.0.2.1"}},
+		{"x.apps.example.org.", []string{"lb.cloud.example.net"}},
+		// An ingress wildcard only matches a single label.
+		{"y.x.apps.example.org.", nil},
+		// A gateway wildcard matches one or more labels.
+		{"y.x.gw.example.org.", []string{"192.0.2.10", "2001:db8::10"}},
+		{"api.example.org.", []string{"192.0.2.10", "2001:db8::10"}},
+		// The parent gateway of the route is in the namespace of the route, where there is none.
+		{"local.example.org.", nil},
+		// Not in an exposed namespace.
+		{"secret.example.org.", nil},
+		// Filtered by the label selector.
+		{"off.example.org.", nil},
+	}
+	for i, tc := range tests {
+		state := request.Request{Req: new(dns.Msg).SetQuestion(tc.qname, dns.TypeA), Zone: "example.org."}
+		svcs := k.hostnameServices(state.Name())
+		var hosts []string
+		for _, s := range svcs {
+			hosts = append(hosts, s.Host)
+		}
+		sort.Strings(hosts)
+		if len(hosts) != len(tc.hosts) {
+			t.Errorf("Test %d: expected %v for %s, got %v", i, tc.hosts, tc.qname, hosts)
+			continue
+		}
+		for j := range hosts {
+			if hosts[j] != tc.hosts[j] {
+				t.Errorf("Test %d: expected %v for %s, got %v", i, tc.hosts, tc.qname, hosts)
+			}
+		}
+	}
+
+	// And through External.
+	state := request.Request{Req: new(dns.Msg).SetQuestion("shop.example.org.", dns.TypeA), Zone: "example.org."}
+	svcs, rcode := k.External(state, false)
+	if rcode != dns.RcodeSuccess || len(svcs) != 1 || svcs[0].Host != "192.0.2.1" {
+		t.Errorf("Expected 192.0.2.1 for shop.example.org., got %v (rcode %d)", svcs, rcode)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	api "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -44,6 +45,8 @@ type Kubernetes struct {
 	Namespaces       map[string]struct{}
 	podMode          string
 	endpointNameMode bool
+	topology         bool // Prefer endpoints close to the client, see topology.go.
+	annotations      bool // Serve TXT records and SRV priorities and weights from annotations, see annotations.go.
 	Fall             fall.F
 	ttl              uint32
 	opts             dnsControlOpts
@@ -111,6 +114,12 @@ func (k *Kubernetes) Services(ctx context.Context, state request.Request, exact
 			return []msg.Service{svc}, nil
 		}
 
+		if r, err := parseRequest(state.Name(), state.Zone, k.isMultiClusterZone(state.Zone)); err == nil {
+			if txt := k.findServiceTXT(r, state.Name()); len(txt) > 0 {
+				return txt, nil
+			}
+		}
+
 		// Check if we have an existing record for this query of another type
 		services, _ := k.Records(ctx, state, false)
 
@@ -251,6 +260,14 @@ func (k *Kubernetes) InitKubeCache(ctx context.Context) (onStart func() error, o
 		}
 	}
 
+	var dynClient dynamic.Interface
+	if k.opts.initGatewayCache {
+		dynClient, err = dynamic.NewForConfig(config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create kubernetes gateway notification controller: %q", err)
+		}
+	}
+
 	if k.opts.labelSelector != nil {
 		var selector labels.Selector
 		selector, err = meta.LabelSelectorAsSelector(k.opts.labelSelector)
@@ -288,11 +305,12 @@ func (k *Kubernetes) InitKubeCache(ctx context.Context) (onStart func() error, o
 	}
 
 	k.opts.initPodCache = k.podMode == podModeVerified
+	k.opts.initNodeCache = k.topology
 
 	k.opts.zones = k.Zones
 	k.opts.endpointNameMode = k.endpointNameMode
 
-	k.APIConn = newdnsController(ctx, kubeClient, mcsClient, k.opts)
+	k.APIConn = newdnsController(ctx, kubeClient, mcsClient, dynClient, k.opts)
 
 	onStart = func() error {
 		go func() {
@@ -355,7 +373,7 @@ func (k *Kubernetes) Records(ctx context.Context, state request.Request, exact b
 	var services []msg.Service
 	var err error
 	if !multicluster {
-		services, err = k.findServices(r, state.Zone)
+		services, err = k.findServices(r, state.Zone, k.clientNode(state, r))
 	} else {
 		services, err = k.findMultiClusterServices(r, state.Zone)
 	}
@@ -442,7 +460,8 @@ func (k *Kubernetes) findPods(r recordRequest, zone string) (pods []msg.Service,
 }
 
 // findServices returns the services matching r from the cache.
-func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.Service, err error) {
+// If client is not nil, the endpoints of headless services are limited to the ones closest to it.
+func (k *Kubernetes) findServices(r recordRequest, zone string, client *object.Node) (services []msg.Service, err error) {
 	if !k.namespaceExposed(r.namespace) {
 		return nil, errNoItems
 	}
@@ -512,6 +531,12 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 				endpointsList = endpointsListFunc()
 			}
 
+			near := k.nearest(client, object.EndpointsKey(svc.Name, svc.Namespace), endpointsList)
+			// An answer for the topology of the client must not be cached and given to other clients.
+			ttl := k.ttl
+			if client != nil {
+				ttl = 0
+			}
 			for _, ep := range endpointsList {
 				if object.EndpointsKey(svc.Name, svc.Namespace) != ep.Index {
 					continue
@@ -525,12 +550,16 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 								continue
 							}
 						}
+						if !near(addr) {
+							continue
+						}
 
 						for _, p := range eps.Ports {
 							if !(matchPortAndProtocol(r.port, p.Name, r.protocol, p.Protocol)) {
 								continue
 							}
-							s := msg.Service{Host: addr.IP, Port: int(p.Port), TTL: k.ttl}
+							s := msg.Service{Host: addr.IP, Port: int(p.Port), TTL: ttl}
+							s.Priority, s.Weight = k.srv(svc, ep)
 							s.Key = strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name, endpointHostname(addr, k.endpointNameMode)}, "/")
 
 							err = nil
@@ -553,6 +582,7 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 
 			for _, ip := range svc.ClusterIPs {
 				s := msg.Service{Host: ip, Port: int(p.Port), TTL: k.ttl}
+				s.Priority, s.Weight = k.srv(svc, nil)
 				s.Key = strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name}, "/")
 				services = append(services, s)
 			}
@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"strings"
 	"testing"
@@ -338,6 +339,15 @@ func (APIConnServiceTest) GetNodeByName(ctx context.Context, name string) (*api.
 	}, nil
 }
 
+func (APIConnServiceTest) IngressHostIndex(string) []*object.Ingress     { return nil }
+func (APIConnServiceTest) GatewayIndex(string) []*object.Gateway         { return nil }
+func (APIConnServiceTest) GatewayHostIndex(string) []*object.Gateway     { return nil }
+func (APIConnServiceTest) HTTPRouteHostIndex(string) []*object.HTTPRoute { return nil }
+
+func (APIConnServiceTest) GetCachedNodeByName(name string) (*object.Node, error) {
+	return nil, fmt.Errorf("node not found")
+}
+
 func (APIConnServiceTest) GetNamespaceByName(name string) (*object.Namespace, error) {
 	return &object.Namespace{
 		Name: name,
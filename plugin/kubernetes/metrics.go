@@ -49,6 +49,18 @@ var (
 		},
 		[]string{"code", "method", "host"},
 	)
+
+	// topologyAnswers counts the answers for headless services, partitioned by which endpoints were chosen
+	// for the client: the ones hinted for its zone, in its zone or region, or all of them as a fallback.
+	topologyAnswers = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: plugin.Namespace,
+			Subsystem: "kubernetes",
+			Name:      "topology_answers_total",
+			Help:      "Counter of topology aware answers, partitioned by the endpoints chosen: hint, zone, region or fallback.",
+		},
+		[]string{"result"},
+	)
 )
 
 func init() {
@@ -98,6 +98,15 @@ func (APIConnTest) GetNodeByName(ctx context.Context, name string) (*api.Node, e
 	return &api.Node{}, nil
 }
 
+func (APIConnTest) IngressHostIndex(string) []*object.Ingress     { return nil }
+func (APIConnTest) GatewayIndex(string) []*object.Gateway         { return nil }
+func (APIConnTest) GatewayHostIndex(string) []*object.Gateway     { return nil }
+func (APIConnTest) HTTPRouteHostIndex(string) []*object.HTTPRoute { return nil }
+
+func (APIConnTest) GetCachedNodeByName(name string) (*object.Node, error) {
+	return nil, fmt.Errorf("node not found")
+}
+
 func (APIConnTest) GetNamespaceByName(name string) (*object.Namespace, error) {
 	return nil, fmt.Errorf("namespace not found")
 }
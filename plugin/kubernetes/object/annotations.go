@@ -0,0 +1,58 @@
+package object
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The annotations on Services and EndpointSlices that add metadata to the records of a service.
+const (
+	// AnnotationTXT holds the TXT records of a service, one string per line.
+	AnnotationTXT = "coredns.io/txt"
+	// AnnotationSRVPriority holds the priority of the SRV records of a service or an EndpointSlice.
+	AnnotationSRVPriority = "coredns.io/srv-priority"
+	// AnnotationSRVWeight holds the weight of the SRV records of a service or an EndpointSlice.
+	AnnotationSRVWeight = "coredns.io/srv-weight"
+)
+
+// SRV holds the SRV priority and weight from the annotations of an object. Zero means not set.
+type SRV struct {
+	Priority int
+	Weight   int
+}
+
+// toSRV returns the SRV priority and weight in annotations. Values that are not a number in the range of
+// an SRV record's priority or weight are ignored.
+func toSRV(annotations map[string]string) SRV {
+	return SRV{
+		Priority: annotationUint16(annotations, AnnotationSRVPriority),
+		Weight:   annotationUint16(annotations, AnnotationSRVWeight),
+	}
+}
+
+func annotationUint16(annotations map[string]string, key string) int {
+	v, ok := annotations[key]
+	if !ok {
+		return 0
+	}
+	i, err := strconv.ParseUint(strings.TrimSpace(v), 10, 16)
+	if err != nil {
+		return 0
+	}
+	return int(i)
+}
+
+// toTXT returns the TXT strings in annotations, these are the non-empty lines of the annotation.
+func toTXT(annotations map[string]string) []string {
+	v, ok := annotations[AnnotationTXT]
+	if !ok {
+		return nil
+	}
+	var txt []string
+	for _, line := range strings.Split(v, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			txt = append(txt, line)
+		}
+	}
+	return txt
+}
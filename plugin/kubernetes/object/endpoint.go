@@ -17,6 +17,7 @@ type Endpoints struct {
 	Index     string
 	IndexIP   []string
 	Subsets   []EndpointSubset
+	SRV       SRV // The SRV priority and weight of the endpoints, from the annotations.
 
 	*Empty
 }
@@ -34,6 +35,8 @@ type EndpointAddress struct {
 	Hostname      string
 	NodeName      string
 	TargetRefName string
+	Zone          string   // The zone of the endpoint, if known.
+	ForZones      []string // The zones this endpoint should serve, from the topology aware hints.
 }
 
 // EndpointPort is a tuple that describes a single port.
@@ -58,6 +61,7 @@ func EndpointSliceToEndpoints(obj meta.Object) (meta.Object, error) {
 		Namespace: ends.GetNamespace(),
 		Index:     EndpointsKey(ends.Labels[discovery.LabelServiceName], ends.GetNamespace()),
 		Subsets:   make([]EndpointSubset, 1),
+		SRV:       toSRV(ends.GetAnnotations()),
 	}
 
 	if len(ends.Ports) == 0 {
@@ -99,6 +103,14 @@ func EndpointSliceToEndpoints(obj meta.Object) (meta.Object, error) {
 			if end.NodeName != nil {
 				ea.NodeName = *end.NodeName
 			}
+			if end.Zone != nil {
+				ea.Zone = *end.Zone
+			}
+			if end.Hints != nil {
+				for _, z := range end.Hints.ForZones {
+					ea.ForZones = append(ea.ForZones, z.Name)
+				}
+			}
 			e.Subsets[0].Addresses = append(e.Subsets[0].Addresses, ea)
 			e.IndexIP = append(e.IndexIP, a)
 		}
@@ -142,6 +154,7 @@ func (e *Endpoints) DeepCopyObject() runtime.Object {
 		Index:     e.Index,
 		IndexIP:   make([]string, len(e.IndexIP)),
 		Subsets:   make([]EndpointSubset, len(e.Subsets)),
+		SRV:       e.SRV,
 	}
 	copy(e1.IndexIP, e.IndexIP)
 
@@ -151,7 +164,11 @@ func (e *Endpoints) DeepCopyObject() runtime.Object {
 			Ports:     make([]EndpointPort, len(eps.Ports)),
 		}
 		for j, a := range eps.Addresses {
-			ea := EndpointAddress{IP: a.IP, Hostname: a.Hostname, NodeName: a.NodeName, TargetRefName: a.TargetRefName}
+			ea := EndpointAddress{IP: a.IP, Hostname: a.Hostname, NodeName: a.NodeName, TargetRefName: a.TargetRefName, Zone: a.Zone}
+			if a.ForZones != nil {
+				ea.ForZones = make([]string, len(a.ForZones))
+				copy(ea.ForZones, a.ForZones)
+			}
 			sub.Addresses[j] = ea
 		}
 		for k, p := range eps.Ports {
@@ -0,0 +1,191 @@
+package object
+
+import (
+	"fmt"
+	"slices"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The Gateway API resources. These are watched as unstructured objects, so we don't depend on the Gateway API
+// client.
+var (
+	GatewayResource   = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gateways"}
+	HTTPRouteResource = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}
+)
+
+// Gateway is a stripped down Gateway API Gateway with only the items we need for CoreDNS.
+type Gateway struct {
+	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
+	Version   string
+	Name      string
+	Namespace string
+	Index     string
+	Hosts     []string // The hostnames of the listeners, lower cased and fully qualified, these may be wildcards.
+	Addresses []string // The IPs or hostnames the gateway is reachable on.
+
+	*Empty
+}
+
+// GatewayKey returns a string using for the index.
+func GatewayKey(name, namespace string) string { return name + "." + namespace }
+
+// ToGateway converts an unstructured Gateway to a *Gateway.
+func ToGateway(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	g := &Gateway{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+		Index:     GatewayKey(u.GetName(), u.GetNamespace()),
+	}
+	listeners, _, _ := unstructured.NestedSlice(u.Object, "spec", "listeners")
+	for _, l := range listeners {
+		if l, ok := l.(map[string]any); ok {
+			if host, _ := l["hostname"].(string); host != "" {
+				g.Hosts = appendHost(g.Hosts, host)
+			}
+		}
+	}
+	addresses, _, _ := unstructured.NestedSlice(u.Object, "status", "addresses")
+	for _, a := range addresses {
+		if a, ok := a.(map[string]any); ok {
+			typ, _ := a["type"].(string)
+			value, _ := a["value"].(string)
+			// The type defaults to IPAddress, other implementation specific types are skipped.
+			if value != "" && (typ == "" || typ == "IPAddress" || typ == "Hostname") {
+				g.Addresses = append(g.Addresses, value)
+			}
+		}
+	}
+
+	u.Object = nil
+
+	return g, nil
+}
+
+var _ runtime.Object = &Gateway{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (g *Gateway) DeepCopyObject() runtime.Object {
+	g1 := &Gateway{
+		Version:   g.Version,
+		Name:      g.Name,
+		Namespace: g.Namespace,
+		Index:     g.Index,
+		Hosts:     slices.Clone(g.Hosts),
+		Addresses: slices.Clone(g.Addresses),
+	}
+	return g1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (g *Gateway) GetNamespace() string { return g.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (g *Gateway) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (g *Gateway) GetName() string { return g.Name }
+
+// SetName implements the metav1.Object interface.
+func (g *Gateway) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (g *Gateway) GetResourceVersion() string { return g.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (g *Gateway) SetResourceVersion(version string) {}
+
+// HTTPRoute is a stripped down Gateway API HTTPRoute with only the items we need for CoreDNS.
+type HTTPRoute struct {
+	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
+	Version   string
+	Name      string
+	Namespace string
+	Hosts     []string // The hostnames, lower cased and fully qualified, these may be wildcards.
+	Gateways  []string // The keys of the parent gateways, see GatewayKey.
+
+	*Empty
+}
+
+// ToHTTPRoute converts an unstructured HTTPRoute to a *HTTPRoute.
+func ToHTTPRoute(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	r := &HTTPRoute{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+	}
+	hosts, _, _ := unstructured.NestedStringSlice(u.Object, "spec", "hostnames")
+	for _, host := range hosts {
+		r.Hosts = appendHost(r.Hosts, host)
+	}
+	parents, _, _ := unstructured.NestedSlice(u.Object, "spec", "parentRefs")
+	for _, p := range parents {
+		p, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		// The group and kind default to a Gateway, parents of other kinds are skipped.
+		if group, ok := p["group"].(string); ok && group != GatewayResource.Group {
+			continue
+		}
+		if kind, ok := p["kind"].(string); ok && kind != "Gateway" {
+			continue
+		}
+		name, _ := p["name"].(string)
+		namespace, _ := p["namespace"].(string)
+		if namespace == "" {
+			namespace = r.Namespace
+		}
+		if name != "" {
+			r.Gateways = append(r.Gateways, GatewayKey(name, namespace))
+		}
+	}
+
+	u.Object = nil
+
+	return r, nil
+}
+
+var _ runtime.Object = &HTTPRoute{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (r *HTTPRoute) DeepCopyObject() runtime.Object {
+	r1 := &HTTPRoute{
+		Version:   r.Version,
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Hosts:     slices.Clone(r.Hosts),
+		Gateways:  slices.Clone(r.Gateways),
+	}
+	return r1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (r *HTTPRoute) GetNamespace() string { return r.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (r *HTTPRoute) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (r *HTTPRoute) GetName() string { return r.Name }
+
+// SetName implements the metav1.Object interface.
+func (r *HTTPRoute) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (r *HTTPRoute) GetResourceVersion() string { return r.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (r *HTTPRoute) SetResourceVersion(version string) {}
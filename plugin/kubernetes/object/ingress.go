@@ -0,0 +1,98 @@
+package object
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	networking "k8s.io/api/networking/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Ingress is a stripped down networking.Ingress with only the items we need for CoreDNS.
+type Ingress struct {
+	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
+	Version   string
+	Name      string
+	Namespace string
+	Hosts     []string // The hosts of the rules, lower cased and fully qualified, these may be wildcards.
+	Addresses []string // The IPs or hostnames of the load balancer.
+
+	*Empty
+}
+
+// ToIngress converts a networking.Ingress to an *Ingress.
+func ToIngress(obj meta.Object) (meta.Object, error) {
+	ing, ok := obj.(*networking.Ingress)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	i := &Ingress{
+		Version:   ing.GetResourceVersion(),
+		Name:      ing.GetName(),
+		Namespace: ing.GetNamespace(),
+	}
+	for _, r := range ing.Spec.Rules {
+		if r.Host != "" {
+			i.Hosts = appendHost(i.Hosts, r.Host)
+		}
+	}
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			i.Addresses = append(i.Addresses, lb.IP)
+			continue
+		}
+		if lb.Hostname != "" {
+			i.Addresses = append(i.Addresses, lb.Hostname)
+		}
+	}
+
+	*ing = networking.Ingress{}
+
+	return i, nil
+}
+
+// appendHost appends host, lower cased and fully qualified, to hosts if it is not in there yet.
+func appendHost(hosts []string, host string) []string {
+	host = strings.ToLower(host)
+	if !strings.HasSuffix(host, ".") {
+		host += "."
+	}
+	if slices.Contains(hosts, host) {
+		return hosts
+	}
+	return append(hosts, host)
+}
+
+var _ runtime.Object = &Ingress{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (i *Ingress) DeepCopyObject() runtime.Object {
+	i1 := &Ingress{
+		Version:   i.Version,
+		Name:      i.Name,
+		Namespace: i.Namespace,
+		Hosts:     slices.Clone(i.Hosts),
+		Addresses: slices.Clone(i.Addresses),
+	}
+	return i1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (i *Ingress) GetNamespace() string { return i.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (i *Ingress) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (i *Ingress) GetName() string { return i.Name }
+
+// SetName implements the metav1.Object interface.
+func (i *Ingress) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (i *Ingress) GetResourceVersion() string { return i.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (i *Ingress) SetResourceVersion(version string) {}
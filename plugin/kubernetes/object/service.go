@@ -2,6 +2,7 @@ package object
 
 import (
 	"fmt"
+	"slices"
 
 	api "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -23,6 +24,10 @@ type Service struct {
 	// ExternalIPs we may want to export.
 	ExternalIPs []string
 
+	// TXT records and SRV priority and weight, from the annotations.
+	TXT []string
+	SRV SRV
+
 	*Empty
 }
 
@@ -44,6 +49,9 @@ func ToService(obj meta.Object) (meta.Object, error) {
 		ExternalName: svc.Spec.ExternalName,
 
 		ExternalIPs: make([]string, len(svc.Status.LoadBalancer.Ingress)+len(svc.Spec.ExternalIPs)),
+
+		TXT: toTXT(svc.GetAnnotations()),
+		SRV: toSRV(svc.GetAnnotations()),
 	}
 
 	if len(svc.Spec.ClusterIPs) > 0 {
@@ -94,6 +102,8 @@ func (s *Service) DeepCopyObject() runtime.Object {
 		ClusterIPs:   make([]string, len(s.ClusterIPs)),
 		Ports:        make([]api.ServicePort, len(s.Ports)),
 		ExternalIPs:  make([]string, len(s.ExternalIPs)),
+		TXT:          slices.Clone(s.TXT),
+		SRV:          s.SRV,
 	}
 	copy(s1.ClusterIPs, s.ClusterIPs)
 	copy(s1.Ports, s.Ports)
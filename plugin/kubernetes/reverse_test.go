@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/kubernetes/object"
@@ -145,6 +146,15 @@ func (APIConnReverseTest) GetNodeByName(ctx context.Context, name string) (*api.
 	}, nil
 }
 
+func (APIConnReverseTest) IngressHostIndex(string) []*object.Ingress     { return nil }
+func (APIConnReverseTest) GatewayIndex(string) []*object.Gateway         { return nil }
+func (APIConnReverseTest) GatewayHostIndex(string) []*object.Gateway     { return nil }
+func (APIConnReverseTest) HTTPRouteHostIndex(string) []*object.HTTPRoute { return nil }
+
+func (APIConnReverseTest) GetCachedNodeByName(name string) (*object.Node, error) {
+	return nil, fmt.Errorf("node not found")
+}
+
 func (APIConnReverseTest) GetNamespaceByName(name string) (*object.Namespace, error) {
 	return &object.Namespace{
 		Name: name,
@@ -202,6 +202,31 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 				return nil, c.Errf("ttl must be in range [0, 3600]: %d", t)
 			}
 			k8s.ttl = uint32(t)
+		case "topology":
+			if len(c.RemainingArgs()) != 0 {
+				return nil, c.ArgErr()
+			}
+			k8s.topology = true
+		case "annotations":
+			if len(c.RemainingArgs()) != 0 {
+				return nil, c.ArgErr()
+			}
+			k8s.annotations = true
+		case "hostnames":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return nil, c.ArgErr()
+			}
+			for _, a := range args {
+				switch a {
+				case hostnamesIngress:
+					k8s.opts.initIngressCache = true
+				case hostnamesGateway:
+					k8s.opts.initGatewayCache = true
+				default:
+					return nil, c.Errf("unknown hostnames source '%s', must be one of: ingress, gateway", a)
+				}
+			}
 		case "noendpoints":
 			if len(c.RemainingArgs()) != 0 {
 				return nil, c.ArgErr()
@@ -292,6 +317,10 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 		return nil, c.Errf("namespaces and namespace_labels cannot both be set")
 	}
 
+	if k8s.topology && k8s.podMode != podModeVerified {
+		return nil, c.Errf("topology requires pods verified")
+	}
+
 	for _, multiclusterZone := range k8s.opts.multiclusterZones {
 		if !slices.Contains(k8s.Zones, multiclusterZone) {
 			fmt.Println(k8s.Zones)
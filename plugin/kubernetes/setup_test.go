@@ -591,6 +591,133 @@ func TestKubernetesParseNoEndpoints(t *testing.T) {
 	}
 }
 
+func TestKubernetesParseTopology(t *testing.T) {
+	tests := []struct {
+		input              string // Corefile data as string
+		shouldErr          bool   // true if test case is expected to produce an error.
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+		expectedTopology   bool
+	}{
+		{`kubernetes coredns.local {
+	pods verified
+	topology
+}`, false, "", true},
+		{`kubernetes coredns.local {
+	topology
+}`, true, "topology requires pods verified", false},
+		{`kubernetes coredns.local {
+	pods verified
+	topology zone
+}`, true, "rong argument count or unexpected", false},
+		{`kubernetes coredns.local {
+	pods verified
+}`, false, "", false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k8sController, err := kubernetesParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error, but did not find error for input '%s'. Error was: '%v'", i, test.input, err)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+				continue
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if k8sController.topology != test.expectedTopology {
+			t.Errorf("Test %d: Expected topology to be '%v', found '%v' for input '%s'", i, test.expectedTopology, k8sController.topology, test.input)
+		}
+	}
+}
+
+func TestKubernetesParseAnnotations(t *testing.T) {
+	for i, test := range []struct {
+		input       string
+		shouldErr   bool
+		annotations bool
+	}{
+		{"kubernetes coredns.local {\n\tannotations\n}", false, true},
+		{"kubernetes coredns.local {\n\tannotations txt\n}", true, false},
+		{"kubernetes coredns.local", false, false},
+	} {
+		c := caddy.NewTestController("dns", test.input)
+		k8sController, err := kubernetesParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error, but did not find error for input '%s'", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			continue
+		}
+		if k8sController.annotations != test.annotations {
+			t.Errorf("Test %d: Expected annotations to be '%v', found '%v' for input '%s'", i, test.annotations, k8sController.annotations, test.input)
+		}
+	}
+}
+
+func TestKubernetesParseHostnames(t *testing.T) {
+	tests := []struct {
+		input              string // Corefile data as string
+		shouldErr          bool   // true if test case is expected to produce an error.
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+		expectedIngress    bool
+		expectedGateway    bool
+	}{
+		{`kubernetes coredns.local {
+	hostnames ingress
+}`, false, "", true, false},
+		{`kubernetes coredns.local {
+	hostnames ingress gateway
+}`, false, "", true, true},
+		{`kubernetes coredns.local {
+	hostnames
+}`, true, "rong argument count or unexpected", false, false},
+		{`kubernetes coredns.local {
+	hostnames route
+}`, true, "unknown hostnames source 'route'", false, false},
+		{`kubernetes coredns.local`, false, "", false, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k8sController, err := kubernetesParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error, but did not find error for input '%s'. Error was: '%v'", i, test.input, err)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+				continue
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		if k8sController.opts.initIngressCache != test.expectedIngress || k8sController.opts.initGatewayCache != test.expectedGateway {
+			t.Errorf("Test %d: Expected ingress '%v' and gateway '%v', found '%v' and '%v' for input '%s'", i,
+				test.expectedIngress, test.expectedGateway, k8sController.opts.initIngressCache, k8sController.opts.initGatewayCache, test.input)
+		}
+	}
+}
+
 func TestKubernetesParseIgnoreEmptyService(t *testing.T) {
 	tests := []struct {
 		input                 string // Corefile data as string
@@ -0,0 +1,94 @@
+package kubernetes
+
+import (
+	"slices"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/request"
+)
+
+// The results of choosing the endpoints closest to a client, as reported by the topologyAnswers metric.
+const (
+	topologyHint     = "hint"     // endpoints hinted for the zone of the client
+	topologyZone     = "zone"     // endpoints in the zone of the client
+	topologyRegion   = "region"   // endpoints in the region of the client
+	topologyFallback = "fallback" // all endpoints
+)
+
+// clientNode returns the node the pod that sent the query in state runs on. It returns nil if topology aware
+// answers are disabled, if the query is for a single endpoint or if the client is not a known pod.
+func (k *Kubernetes) clientNode(state request.Request, r recordRequest) *object.Node {
+	if !k.topology || r.endpoint != "" {
+		return nil
+	}
+	for _, p := range k.APIConn.PodIndex(state.IP()) {
+		if p.NodeName == "" {
+			continue
+		}
+		node, err := k.APIConn.GetCachedNodeByName(p.NodeName)
+		if err != nil {
+			log.Debugf("Failed to find node %s of pod %s/%s: %s", p.NodeName, p.Namespace, p.Name, err)
+			return nil
+		}
+		return node
+	}
+	return nil
+}
+
+// nearest returns a function that reports if an address of the endpoints with the given index should be in
+// the answer for client. It prefers, in order, the addresses with a topology hint for the zone of the client,
+// the addresses in the zone of the client and the addresses on nodes in the region of the client. If none of
+// these exist, or client is nil, every address is in the answer.
+func (k *Kubernetes) nearest(client *object.Node, index string, endpoints []*object.Endpoints) func(object.EndpointAddress) bool {
+	all := func(object.EndpointAddress) bool { return true }
+	if client == nil {
+		return all
+	}
+
+	var addrs []object.EndpointAddress
+	for _, ep := range endpoints {
+		if ep.Index != index {
+			continue
+		}
+		for _, eps := range ep.Subsets {
+			addrs = append(addrs, eps.Addresses...)
+		}
+	}
+	if len(addrs) == 0 {
+		return all
+	}
+
+	hint := func(a object.EndpointAddress) bool { return slices.Contains(a.ForZones, client.Zone) }
+	zone := func(a object.EndpointAddress) bool { return a.Zone == client.Zone }
+	regions := map[string]string{}
+	region := func(a object.EndpointAddress) bool {
+		if a.NodeName == "" {
+			return false
+		}
+		r, ok := regions[a.NodeName]
+		if !ok {
+			if node, err := k.APIConn.GetCachedNodeByName(a.NodeName); err == nil {
+				r = node.Region
+			}
+			regions[a.NodeName] = r
+		}
+		return r == client.Region
+	}
+
+	for _, pref := range []struct {
+		result string
+		known  bool
+		near   func(object.EndpointAddress) bool
+	}{
+		{topologyHint, client.Zone != "", hint},
+		{topologyZone, client.Zone != "", zone},
+		{topologyRegion, client.Region != "", region},
+	} {
+		if pref.known && slices.ContainsFunc(addrs, pref.near) {
+			topologyAnswers.WithLabelValues(pref.result).Inc()
+			return pref.near
+		}
+	}
+	topologyAnswers.WithLabelValues(topologyFallback).Inc()
+	return all
+}
@@ -0,0 +1,193 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	api "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	mcsClientsetFake "sigs.k8s.io/mcs-api/pkg/client/clientset/versioned/fake"
+)
+
+// topologyEndpoint is an endpoint of a service on a node, with the zones it is hinted for.
+type topologyEndpoint struct {
+	ip, node, zone string
+	hints          []string
+}
+
+func createTopologyEndpointSlice(ctx context.Context, t *testing.T, client kubernetes.Interface, name, svc string, endpoints []topologyEndpoint) {
+	t.Helper()
+	eps := &discovery.EndpointSlice{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      name,
+			Namespace: "testns",
+			Labels:    map[string]string{discovery.LabelServiceName: svc},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+	}
+	for _, e := range endpoints {
+		end := discovery.Endpoint{Addresses: []string{e.ip}, NodeName: &e.node, Zone: &e.zone}
+		if len(e.hints) > 0 {
+			end.Hints = &discovery.EndpointHints{}
+			for _, h := range e.hints {
+				end.Hints.ForZones = append(end.Hints.ForZones, discovery.ForZone{Name: h})
+			}
+		}
+		eps.Endpoints = append(eps.Endpoints, end)
+	}
+	if _, err := client.DiscoveryV1().EndpointSlices("testns").Create(ctx, eps, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func kubernetesWithTopology(ctx context.Context, t *testing.T) *Kubernetes {
+	t.Helper()
+	client := fake.NewSimpleClientset()
+	mcsClient := mcsClientsetFake.NewSimpleClientset()
+
+	nodes := map[string][2]string{
+		"node-a1": {"a", "r1"},
+		"node-b1": {"b", "r1"},
+		"node-c2": {"c", "r2"},
+		"node-d1": {"d", "r1"},
+		"node-e3": {"e", "r3"},
+	}
+	for name, loc := range nodes {
+		node := &api.Node{ObjectMeta: meta.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{api.LabelTopologyZone: loc[0], api.LabelTopologyRegion: loc[1]},
+		}}
+		if _, err := client.CoreV1().Nodes().Create(ctx, node, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The clients, one pod on every node but node-c2.
+	for ip, node := range map[string]string{"10.0.0.1": "node-a1", "10.0.0.2": "node-b1", "10.0.0.4": "node-d1", "10.0.0.5": "node-e3"} {
+		pod := &api.Pod{
+			ObjectMeta: meta.ObjectMeta{Name: "client-" + node, Namespace: "testns"},
+			Spec:       api.PodSpec{NodeName: node},
+			Status:     api.PodStatus{PodIP: ip},
+		}
+		if _, err := client.CoreV1().Pods("testns").Create(ctx, pod, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := client.CoreV1().Namespaces().Create(ctx, &api.Namespace{ObjectMeta: meta.ObjectMeta{Name: "testns"}}, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, svc := range []string{"hinted", "zoned"} {
+		s := &api.Service{
+			ObjectMeta: meta.ObjectMeta{Name: svc, Namespace: "testns"},
+			Spec:       api.ServiceSpec{ClusterIP: api.ClusterIPNone},
+		}
+		if _, err := client.CoreV1().Services("testns").Create(ctx, s, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createTopologyEndpointSlice(ctx, t, client, "hinted-1", "hinted", []topologyEndpoint{
+		{"10.1.0.1", "node-a1", "a", []string{"a"}},
+		{"10.1.0.2", "node-b1", "b", []string{"b", "d"}},
+		{"10.1.0.3", "node-c2", "c", []string{"c"}},
+	})
+	// The endpoints of zoned are split over two slices.
+	createTopologyEndpointSlice(ctx, t, client, "zoned-1", "zoned", []topologyEndpoint{
+		{"10.2.0.1", "node-a1", "a", nil},
+		{"10.2.0.3", "node-c2", "c", nil},
+	})
+	createTopologyEndpointSlice(ctx, t, client, "zoned-2", "zoned", []topologyEndpoint{
+		{"10.2.0.2", "node-b1", "b", nil},
+	})
+
+	dco := dnsControlOpts{
+		zones:              []string{"cluster.local."},
+		initEndpointsCache: true,
+		initPodCache:       true,
+		initNodeCache:      true,
+	}
+	k := New([]string{"cluster.local."})
+	k.APIConn = newdnsController(ctx, client, mcsClient.MulticlusterV1alpha1(), nil, dco)
+	k.podMode = podModeVerified
+	k.topology = true
+	return k
+}
+
+func TestTopology(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	k := kubernetesWithTopology(ctx, t)
+	go k.APIConn.Run()
+	defer k.APIConn.Stop()
+	for !k.APIConn.HasSynced() {
+		time.Sleep(time.Millisecond)
+	}
+
+	tests := []struct {
+		client string
+		qname  string
+		answer []string
+		result string
+	}{
+		{"10.0.0.1", "hinted.testns.svc.cluster.local.", []string{"10.1.0.1"}, topologyHint},
+		{"10.0.0.4", "hinted.testns.svc.cluster.local.", []string{"10.1.0.2"}, topologyHint},
+		{"10.0.0.5", "hinted.testns.svc.cluster.local.", []string{"10.1.0.1", "10.1.0.2", "10.1.0.3"}, topologyFallback},
+		{"10.0.0.2", "zoned.testns.svc.cluster.local.", []string{"10.2.0.2"}, topologyZone},
+		{"10.0.0.4", "zoned.testns.svc.cluster.local.", []string{"10.2.0.1", "10.2.0.2"}, topologyRegion},
+		{"10.0.0.5", "zoned.testns.svc.cluster.local.", []string{"10.2.0.1", "10.2.0.2", "10.2.0.3"}, topologyFallback},
+		// Not a pod, no preference.
+		{"10.9.9.9", "zoned.testns.svc.cluster.local.", []string{"10.2.0.1", "10.2.0.2", "10.2.0.3"}, ""},
+		// Queries for an endpoint are answered wherever the client is.
+		{"10.0.0.1", "10-2-0-3.zoned.testns.svc.cluster.local.", []string{"10.2.0.3"}, ""},
+	}
+
+	for i, tc := range tests {
+		var before float64
+		if tc.result != "" {
+			before = testutil.ToFloat64(topologyAnswers.WithLabelValues(tc.result))
+		}
+
+		w := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.client})
+		if _, err := k.ServeDNS(ctx, w, new(dns.Msg).SetQuestion(tc.qname, dns.TypeA)); err != nil {
+			t.Fatalf("Test %d: %s", i, err)
+		}
+		got := map[string]bool{}
+		for _, rr := range w.Msg.Answer {
+			got[rr.(*dns.A).A.String()] = true
+		}
+		if len(got) != len(tc.answer) {
+			t.Errorf("Test %d: expected %v for client %s, got %v", i, tc.answer, tc.client, w.Msg.Answer)
+			continue
+		}
+		for _, a := range tc.answer {
+			if !got[a] {
+				t.Errorf("Test %d: expected %s for client %s, got %v", i, a, tc.client, w.Msg.Answer)
+			}
+		}
+
+		// Answers for the topology of the client have a TTL of 0, so they aren't cached.
+		ttl := k.ttl
+		if tc.result != "" {
+			ttl = 0
+		}
+		for _, rr := range w.Msg.Answer {
+			if rr.Header().Ttl != ttl {
+				t.Errorf("Test %d: expected TTL %d for client %s, got %d", i, ttl, tc.client, rr.Header().Ttl)
+			}
+		}
+
+		if tc.result != "" {
+			if after := testutil.ToFloat64(topologyAnswers.WithLabelValues(tc.result)); after != before+1 {
+				t.Errorf("Test %d: expected the %s counter to be incremented", i, tc.result)
+			}
+		}
+	}
+}
@@ -73,6 +73,12 @@ func (k *Kubernetes) transferServices(ch chan []dns.RR, zonePath string) {
 			continue
 		}
 		svcBase := []string{zonePath, Svc, svc.Namespace, svc.Name}
+		if k.annotations && svc.Type != api.ServiceTypeExternalName {
+			for _, txt := range svc.TXT {
+				s := msg.Service{Text: txt, TTL: k.ttl, Key: strings.Join(svcBase, "/")}
+				ch <- []dns.RR{s.NewTXT(msg.Domain(s.Key))}
+			}
+		}
 		switch svc.Type {
 		case api.ServiceTypeClusterIP, api.ServiceTypeNodePort, api.ServiceTypeLoadBalancer:
 			clusterIP := net.ParseIP(svc.ClusterIPs[0])
@@ -88,6 +94,7 @@ func (k *Kubernetes) transferServices(ch chan []dns.RR, zonePath string) {
 
 				for _, p := range svc.Ports {
 					s := msg.Service{Host: host, Port: int(p.Port), TTL: k.ttl}
+					s.Priority, _ = k.srv(svc, nil)
 					s.Key = strings.Join(svcBase, "/")
 
 					// Need to generate this to handle use cases for peer-finder
@@ -111,11 +118,33 @@ func (k *Kubernetes) transferServices(ch chan []dns.RR, zonePath string) {
 
 			endpointsList := k.APIConn.EpIndex(svc.Name + "." + svc.Namespace)
 
+			// With annotations the weights are relative to the ones of all endpoints of the service.
+			var weights []uint16
+			if k.annotations {
+				var services []msg.Service
+				for _, ep := range endpointsList {
+					for _, eps := range ep.Subsets {
+						for range eps.Addresses {
+							s := msg.Service{}
+							s.Priority, s.Weight = k.srv(svc, ep)
+							services = append(services, s)
+						}
+					}
+				}
+				weights = calcSRVWeights(services)
+			}
+
+			i := 0
 			for _, ep := range endpointsList {
 				for _, eps := range ep.Subsets {
 					srvWeight := calcSRVWeight(len(eps.Addresses))
 					for _, addr := range eps.Addresses {
 						s := msg.Service{Host: addr.IP, TTL: k.ttl}
+						if weights != nil {
+							s.Priority, _ = k.srv(svc, ep)
+							srvWeight = weights[i]
+						}
+						i++
 						s.Key = strings.Join(svcBase, "/")
 						// We don't need to change the msg.Service host from IP to Name yet
 						// so disregard the return value here
@@ -257,11 +286,15 @@ func emitAddressRecord(c chan<- []dns.RR, s msg.Service) string {
 // calcSRVWeight borrows the logic implemented in plugin.SRV for dynamically
 // calculating the srv weight and priority
 func calcSRVWeight(numservices int) uint16 {
-	services := make([]msg.Service, 0, numservices)
-	for range numservices {
-		services = append(services, msg.Service{})
+	if numservices == 0 {
+		return 0
 	}
+	return calcSRVWeights(make([]msg.Service, numservices))[0]
+}
 
+// calcSRVWeights returns the weights of the SRV records of services, as plugin.SRV calculates them: the weight
+// of each service relative to the weights of all services with the same priority.
+func calcSRVWeights(services []msg.Service) []uint16 {
 	w := make(map[int]int)
 	for _, serv := range services {
 		weight := 100
@@ -274,11 +307,20 @@ func calcSRVWeight(numservices int) uint16 {
 		}
 		w[serv.Priority] += weight
 	}
-	weight := uint16(math.Floor((100.0 / float64(w[0])) * 100))
-	// weight should be at least 1
-	if weight == 0 {
-		weight = 1
+	weights := make([]uint16, len(services))
+	for i, serv := range services {
+		w1 := 100.0 / float64(w[serv.Priority])
+		if serv.Weight == 0 {
+			w1 *= 100
+		} else {
+			w1 *= float64(serv.Weight)
+		}
+		weights[i] = uint16(math.Floor(w1))
+		// weight should be at least 1
+		if weights[i] == 0 {
+			weights[i] = 1
+		}
 	}
 
-	return weight
+	return weights
 }
@@ -0,0 +1,156 @@
+package leases
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// leaseFile is a lease file of a DHCP server.
+type leaseFile struct {
+	path   string
+	format format
+
+	// mtime and size are only read and modified by a single goroutine
+	mtime time.Time
+	size  int64
+
+	leases []lease
+}
+
+// read reads the leases of f if the size or modification time of the file changed since it was read last.
+// It returns true if it read the file.
+func (f *leaseFile) read() bool {
+	file, err := os.Open(f.path)
+	if err != nil {
+		// A warning is logged on setup if the file doesn't exist. It may be created when the DHCP server starts.
+		return false
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	if f.mtime.Equal(stat.ModTime()) && f.size == stat.Size() {
+		return false
+	}
+
+	leases, err := parseLeases(file, f.format)
+	if err != nil {
+		// Keep the leases that were read before, the file may be read while it is being written.
+		log.Warningf("Failed to parse lease file %s: %s", f.path, err)
+		return false
+	}
+	log.Debugf("Parsed lease file %s into %d leases", f.path, len(leases))
+
+	f.leases = leases
+	f.mtime = stat.ModTime()
+	f.size = stat.Size()
+	leaseEntries.WithLabelValues(f.path).Set(float64(len(leases)))
+	return true
+}
+
+// index holds the leases of all lease files by hostname and by address.
+type index struct {
+	names map[string][]lease // hostname -> leases, in order of preference
+	ptrs  map[string][]lease // reverse name of the address -> leases, in order of preference
+}
+
+// newIndex returns the index of the leases in files. When more than one lease has the same hostname, the one
+// that doesn't expire or expires last is preferred, and after that the one with the lowest address. Expired
+// leases are kept, as the next lease in line is used when a lease expires.
+func newIndex(files []*leaseFile) *index {
+	idx := &index{names: map[string][]lease{}, ptrs: map[string][]lease{}}
+	for _, f := range files {
+		for _, l := range f.leases {
+			name := hostname(l.hostname)
+			if name == "" {
+				continue
+			}
+			l.hostname = name
+			idx.names[name] = append(idx.names[name], l)
+			if rev, err := dns.ReverseAddr(l.ip.String()); err == nil {
+				idx.ptrs[rev] = append(idx.ptrs[rev], l)
+			}
+		}
+	}
+	for _, leases := range idx.names {
+		slices.SortStableFunc(leases, compare)
+	}
+	for _, leases := range idx.ptrs {
+		slices.SortStableFunc(leases, compare)
+	}
+	return idx
+}
+
+// lookup returns the preferred lease of name with an address of the family of qtype that isn't expired at t.
+// It returns false as the second value if name has no leases that aren't expired at all.
+func (idx *index) lookup(name string, qtype uint16, t time.Time) (*lease, bool) {
+	exists := false
+	for i, l := range idx.names[name] {
+		if l.expired(t) {
+			continue
+		}
+		exists = true
+		if (qtype == dns.TypeA) == (l.ip.To4() != nil) && (qtype == dns.TypeA || qtype == dns.TypeAAAA) {
+			return &idx.names[name][i], true
+		}
+	}
+	return nil, exists
+}
+
+// ptr returns the preferred lease of the address of the reverse name that isn't expired at t.
+func (idx *index) ptr(name string, t time.Time) (*lease, bool) {
+	for i, l := range idx.ptrs[name] {
+		if !l.expired(t) {
+			return &idx.ptrs[name][i], true
+		}
+	}
+	return nil, false
+}
+
+// compare orders leases by preference: leases that don't expire, then the ones that expire last, then the
+// one with the lowest address.
+func compare(a, b lease) int {
+	switch {
+	case a.end.IsZero() != b.end.IsZero():
+		if a.end.IsZero() {
+			return -1
+		}
+		return 1
+	case !a.end.Equal(b.end):
+		return b.end.Compare(a.end)
+	}
+	return bytes.Compare(a.ip.To16(), b.ip.To16())
+}
+
+// hostname returns name as a DNS label. Only the first label of a fully qualified name is used, and characters
+// that aren't allowed in a hostname are replaced with dashes.
+func hostname(name string) string {
+	name, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(name)), ".")
+	b := []byte(name)
+	for i, c := range b {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			b[i] = '-'
+		}
+	}
+	name = strings.Trim(string(b), "-")
+	if len(name) > 63 {
+		return ""
+	}
+	return name
+}
+
+// address returns an A or AAAA record for ip.
+func address(name string, ttl uint32, ip net.IP) dns.RR {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl}, A: ip4}
+	}
+	return &dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl}, AAAA: ip}
+}
@@ -0,0 +1,148 @@
+// Package leases implements a plugin that serves the hostnames of the leases of DHCP servers.
+package leases
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const pluginName = "leases"
+
+var (
+	log        = clog.NewWithPlugin(pluginName)
+	defaultTTL = 60
+)
+
+// now returns the current time, the time leases expire is compared to.
+var now = time.Now
+
+// Leases is a plugin that serves forward and reverse records for the active leases in the lease files of
+// DHCP servers.
+type Leases struct {
+	Next plugin.Handler
+	Fall fall.F
+
+	Zone   string
+	ttl    uint32
+	reload time.Duration
+	files  []*leaseFile
+
+	mu  sync.RWMutex
+	idx *index
+
+	stop chan struct{}
+}
+
+// Name implements the plugin.Handler interface.
+func (l *Leases) Name() string { return pluginName }
+
+// ServeDNS implements the plugin.Handler interface.
+func (l *Leases) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	t := now()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if plugin.Zones([]string{l.Zone}).Matches(state.Name()) == "" {
+		// Reverse lookups of leased addresses are answered, all others are passed on.
+		if state.QType() != dns.TypePTR {
+			return plugin.NextOrFailure(l.Name(), l.Next, ctx, w, r)
+		}
+		ls, ok := l.index().ptr(state.Name(), t)
+		if !ok {
+			return plugin.NextOrFailure(l.Name(), l.Next, ctx, w, r)
+		}
+		m.Answer = []dns.RR{&dns.PTR{
+			Hdr: dns.RR_Header{Name: state.QName(), Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: l.ttlOf(ls, t)},
+			Ptr: dns.Fqdn(ls.hostname + "." + l.Zone),
+		}}
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	}
+
+	if state.Name() == l.Zone {
+		if state.QType() == dns.TypeSOA {
+			m.Answer = []dns.RR{l.soa()}
+		} else {
+			m.Ns = []dns.RR{l.soa()}
+		}
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	}
+
+	// Only the hostnames directly below the zone exist.
+	labels := dns.SplitDomainName(state.Name())
+	ls, exists := l.index().lookup(labels[0], state.QType(), t)
+	if len(labels) != dns.CountLabel(l.Zone)+1 || !exists {
+		if l.Fall.Through(state.Name()) {
+			return plugin.NextOrFailure(l.Name(), l.Next, ctx, w, r)
+		}
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{l.soa()}
+		return dns.RcodeSuccess, w.WriteMsg(m)
+	}
+
+	if ls != nil {
+		m.Answer = []dns.RR{address(state.QName(), l.ttlOf(ls, t), ls.ip)}
+	} else {
+		m.Ns = []dns.RR{l.soa()}
+	}
+	return dns.RcodeSuccess, w.WriteMsg(m)
+}
+
+// index returns the current index of the leases.
+func (l *Leases) index() *index {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.idx
+}
+
+// readLeases reads the lease files that changed, and updates the index if any did.
+func (l *Leases) readLeases() {
+	changed := false
+	for _, f := range l.files {
+		if f.read() {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	idx := newIndex(l.files)
+	l.mu.Lock()
+	l.idx = idx
+	l.mu.Unlock()
+	leaseReloadTime.Set(float64(now().UnixNano()) / 1e9)
+}
+
+// ttlOf returns the TTL for the records of ls, which is no longer than the remaining time of the lease.
+func (l *Leases) ttlOf(ls *lease, t time.Time) uint32 {
+	if ls.end.IsZero() {
+		return l.ttl
+	}
+	if remaining := uint32(ls.end.Sub(t) / time.Second); remaining < l.ttl {
+		return remaining
+	}
+	return l.ttl
+}
+
+func (l *Leases) soa() *dns.SOA {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: l.Zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: l.ttl},
+		Ns:      dns.Fqdn("ns." + l.Zone),
+		Mbox:    dns.Fqdn("hostmaster." + l.Zone),
+		Serial:  uint32(time.Now().Unix()),
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  l.ttl,
+	}
+}
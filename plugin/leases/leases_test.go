@@ -0,0 +1,173 @@
+package leases
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func ip(s string) net.IP { return net.ParseIP(s) }
+
+const testDhcpd = `lease 192.168.1.10 {
+  ends 5 2026/10/16 00:00:00;
+  binding state active;
+  client-hostname "laptop";
+}
+lease 192.168.1.11 {
+  ends never;
+  binding state active;
+  client-hostname "printer";
+}
+lease 192.168.1.12 {
+  ends 4 2026/10/15 12:00:00;
+  binding state active;
+  client-hostname "old";
+}
+`
+
+const testDnsmasq = `1792098000 00:11:22:33:44:55 192.168.1.40 laptop *
+1792098000 00:11:22:33:44:66 192.168.1.30 tv *
+0 00:11:22:33:44:77 192.168.1.41 printer *
+duid 00:01:00:01:2c:2f:1a:2b:00:11:22:33:44:55
+1792098000 1234 2001:db8::30 tv *
+`
+
+func newTestLeases(t *testing.T) (*Leases, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{"dhcpd.leases": testDhcpd, "dnsmasq.leases": testDnsmasq} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := &Leases{
+		Next: test.ErrorHandler(),
+		Zone: "lan.",
+		ttl:  uint32(defaultTTL),
+		files: []*leaseFile{
+			{path: filepath.Join(dir, "dhcpd.leases"), format: formatDhcpd},
+			{path: filepath.Join(dir, "dnsmasq.leases"), format: formatDnsmasq},
+		},
+		idx: newIndex(nil),
+	}
+	l.readLeases()
+	return l, dir
+}
+
+func setNow(t *testing.T, tm time.Time) {
+	now = func() time.Time { return tm }
+	t.Cleanup(func() { now = time.Now })
+}
+
+func TestLeases(t *testing.T) {
+	setNow(t, time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC))
+	l, _ := newTestLeases(t)
+
+	cases := []test.Case{
+		{
+			// The lease that expires last wins.
+			Qname: "laptop.lan.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("laptop.lan.	60	IN	A	192.168.1.10")},
+		},
+		{
+			// Of two leases that don't expire, the one with the lowest address wins.
+			Qname: "printer.lan.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("printer.lan.	60	IN	A	192.168.1.11")},
+		},
+		{
+			Qname: "TV.lan.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("TV.lan.	60	IN	AAAA	2001:db8::30")},
+		},
+		{
+			Qname: "laptop.lan.", Qtype: dns.TypeAAAA,
+			Ns: []dns.RR{test.SOA("lan.	60	IN	SOA	ns.lan. hostmaster.lan. 0 3600 600 86400 60")},
+		},
+		{
+			Qname: "old.lan.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("lan.	60	IN	SOA	ns.lan. hostmaster.lan. 0 3600 600 86400 60")},
+		},
+		{
+			Qname: "www.laptop.lan.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("lan.	60	IN	SOA	ns.lan. hostmaster.lan. 0 3600 600 86400 60")},
+		},
+		{
+			Qname: "40.1.168.192.in-addr.arpa.", Qtype: dns.TypePTR,
+			Answer: []dns.RR{test.PTR("40.1.168.192.in-addr.arpa.	60	IN	PTR	laptop.lan.")},
+		},
+		{
+			Qname: "0.3.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", Qtype: dns.TypePTR,
+			Answer: []dns.RR{test.PTR("0.3.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.	60	IN	PTR	tv.lan.")},
+		},
+	}
+	test.Run(t, l, cases)
+
+	// The TTL is capped to the remaining time of the lease, and expired leases are gone.
+	setNow(t, time.Date(2026, 10, 15, 20, 59, 30, 0, time.UTC))
+	cases = []test.Case{
+		{
+			Qname: "tv.lan.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("tv.lan.	30	IN	A	192.168.1.30")},
+		},
+	}
+	test.Run(t, l, cases)
+
+	setNow(t, time.Date(2026, 10, 15, 21, 0, 0, 0, time.UTC))
+	cases = []test.Case{
+		{
+			Qname: "tv.lan.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("lan.	60	IN	SOA	ns.lan. hostmaster.lan. 0 3600 600 86400 60")},
+		},
+	}
+	test.Run(t, l, cases)
+
+	r := new(dns.Msg)
+	r.SetQuestion("30.1.168.192.in-addr.arpa.", dns.TypePTR)
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	if rcode, _ := l.ServeDNS(context.Background(), w, r); rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected the reverse query for an expired lease to be passed on, got rcode %d", rcode)
+	}
+}
+
+func TestLeasesReload(t *testing.T) {
+	setNow(t, time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC))
+	l, dir := newTestLeases(t)
+
+	if err := os.WriteFile(filepath.Join(dir, "dnsmasq.leases"), []byte("1792098000 00:11:22:33:44:88 192.168.1.50 camera *\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l.readLeases()
+
+	cases := []test.Case{
+		{
+			Qname: "camera.lan.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("camera.lan.	60	IN	A	192.168.1.50")},
+		},
+		{
+			Qname: "tv.lan.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("lan.	60	IN	SOA	ns.lan. hostmaster.lan. 0 3600 600 86400 60")},
+		},
+	}
+	test.Run(t, l, cases)
+}
+
+func TestLeasesFallthrough(t *testing.T) {
+	setNow(t, time.Date(2026, 10, 15, 20, 0, 0, 0, time.UTC))
+	l, _ := newTestLeases(t)
+	l.Fall = fall.Root
+
+	r := new(dns.Msg)
+	r.SetQuestion("unknown.lan.", dns.TypeA)
+	w := dnstest.NewRecorder(&test.ResponseWriter{})
+	if rcode, _ := l.ServeDNS(context.Background(), w, r); rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected the query to fall through, got rcode %d", rcode)
+	}
+}
@@ -0,0 +1,25 @@
+package leases
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// leaseEntries is the number of leases with a hostname in a lease file.
+	leaseEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "entries",
+		Help:      "The number of leases with a hostname in a lease file.",
+	}, []string{"path"})
+	// leaseReloadTime is the timestamp of the last reload of the lease files.
+	leaseReloadTime = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "reload_timestamp_seconds",
+		Help:      "The timestamp of the last reload of the lease files.",
+	})
+)
@@ -0,0 +1,260 @@
+package leases
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lease is a lease of an address to a host.
+type lease struct {
+	hostname string
+	ip       net.IP
+	end      time.Time // zero if the lease doesn't expire
+}
+
+// expired returns true if l ended before t.
+func (l lease) expired(t time.Time) bool { return !l.end.IsZero() && !l.end.After(t) }
+
+// format is the format of a lease file.
+type format string
+
+const (
+	formatDhcpd   format = "dhcpd"
+	formatKea     format = "kea"
+	formatDnsmasq format = "dnsmasq"
+)
+
+// parseLeases parses the leases in r, in format f. Leases without a hostname are skipped. When the file has more
+// than one lease for an address, the last one is returned. Leases that are released, declined or otherwise
+// not active are returned as expired.
+func parseLeases(r io.Reader, f format) ([]lease, error) {
+	var (
+		leases []lease
+		err    error
+	)
+	switch f {
+	case formatDhcpd:
+		leases, err = parseDhcpd(r)
+	case formatKea:
+		leases, err = parseKea(r)
+	case formatDnsmasq:
+		leases, err = parseDnsmasq(r)
+	default:
+		return nil, errors.New("unknown lease file format " + string(f))
+	}
+	if err != nil {
+		return nil, err
+	}
+	leases = last(leases)
+	out := leases[:0]
+	for _, l := range leases {
+		if l.hostname != "" {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// released is the end of a lease that isn't active anymore.
+var released = time.Unix(0, 0)
+
+// parseDhcpd parses a dhcpd.leases file of ISC dhcpd. Only the IPv4 leases are used.
+//
+//	lease 192.168.1.10 {
+//	  starts 4 2026/10/15 10:00:00;
+//	  ends 4 2026/10/15 22:00:00;
+//	  binding state active;
+//	  client-hostname "laptop";
+//	}
+func parseDhcpd(r io.Reader) ([]lease, error) {
+	var (
+		leases []lease
+		cur    *lease
+		active bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 && !strings.Contains(line[:i], `"`) {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		if cur == nil {
+			if f := strings.Fields(line); len(f) >= 3 && f[0] == "lease" && f[2] == "{" {
+				cur, active = &lease{ip: net.ParseIP(f[1]).To4()}, true
+			}
+			continue
+		}
+		if line == "}" {
+			// A lease without a binding state is active, older versions of dhcpd don't write it.
+			if !active {
+				cur.end = released
+			}
+			if cur.ip != nil {
+				leases = append(leases, *cur)
+			}
+			cur = nil
+			continue
+		}
+
+		line = strings.TrimSuffix(line, ";")
+		f := strings.Fields(line)
+		switch {
+		case f[0] == "ends":
+			cur.end = dhcpdTime(f[1:])
+		case f[0] == "binding" && len(f) == 3 && f[1] == "state":
+			active = f[2] == "active"
+		case f[0] == "client-hostname" && len(f) == 2:
+			// The hostname used for dynamic DNS updates by the server takes precedence.
+			if cur.hostname == "" {
+				cur.hostname = strings.Trim(f[1], `"`)
+			}
+		case f[0] == "set" && len(f) == 4 && f[1] == "ddns-hostname":
+			cur.hostname = strings.Trim(f[3], `"`)
+		}
+	}
+	return leases, scanner.Err()
+}
+
+// dhcpdTime parses the time of an ends statement: "never", "epoch <seconds>" or "<weekday> <date> <time>" in UTC.
+func dhcpdTime(f []string) time.Time {
+	switch {
+	case len(f) == 1 && f[0] == "never":
+		return time.Time{}
+	case len(f) >= 2 && f[0] == "epoch":
+		sec, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil {
+			return released
+		}
+		return time.Unix(sec, 0)
+	case len(f) >= 3:
+		t, err := time.Parse("2006/01/02 15:04:05", f[1]+" "+f[2])
+		if err != nil {
+			return released
+		}
+		return t
+	}
+	// Treat what can't be parsed as expired.
+	return released
+}
+
+// infinite is the valid lifetime Kea writes for leases that don't expire, the maximum of an uint32.
+const infinite = "4294967295"
+
+// parseKea parses a memfile CSV of Kea, of either the DHCPv4 or the DHCPv6 server. The columns are found by
+// the names in the header.
+//
+//	address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname,state,user_context
+//	192.168.1.10,00:11:22:33:44:55,,3600,1792000000,1,0,0,laptop,0,
+func parseKea(r io.Reader) ([]lease, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"address", "expire", "hostname"} {
+		if _, ok := col[name]; !ok {
+			return nil, errors.New("missing column " + name + " in the header")
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := col[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var leases []lease
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Lease type 2 is a delegated prefix, which isn't an address of a host.
+		if field(record, "lease_type") == "2" {
+			continue
+		}
+		l := lease{ip: net.ParseIP(field(record, "address")), hostname: field(record, "hostname")}
+		if l.ip == nil {
+			continue
+		}
+		expire, err := strconv.ParseInt(field(record, "expire"), 10, 64)
+		switch {
+		case err != nil:
+			l.end = released
+		case field(record, "valid_lifetime") == infinite:
+		default:
+			l.end = time.Unix(expire, 0)
+		}
+		// State 0 is an assigned lease, the others are declined or reclaimed ones.
+		if state := field(record, "state"); state != "" && state != "0" {
+			l.end = released
+		}
+		leases = append(leases, l)
+	}
+	return leases, nil
+}
+
+// parseDnsmasq parses a lease file of dnsmasq. IPv6 leases follow the line with the DUID of the server.
+//
+//	1792000000 00:11:22:33:44:55 192.168.1.10 laptop 01:00:11:22:33:44:55
+func parseDnsmasq(r io.Reader) ([]lease, error) {
+	var leases []lease
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) < 4 || f[0] == "duid" {
+			continue
+		}
+		l := lease{ip: net.ParseIP(f[2]), hostname: strings.TrimPrefix(f[3], "*")}
+		if l.ip == nil {
+			continue
+		}
+		expiry, err := strconv.ParseInt(f[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if expiry != 0 {
+			l.end = time.Unix(expiry, 0)
+		}
+		leases = append(leases, l)
+	}
+	return leases, scanner.Err()
+}
+
+// last removes the leases of which there is a later one for the same address.
+func last(leases []lease) []lease {
+	index := map[string]int{}
+	out := leases[:0]
+	for _, l := range leases {
+		if i, ok := index[l.ip.String()]; ok {
+			out[i] = l
+			continue
+		}
+		index[l.ip.String()] = len(out)
+		out = append(out, l)
+	}
+	return out
+}
@@ -0,0 +1,148 @@
+package leases
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const dhcpdLeases = `# The format of this file is documented in the dhcpd.leases(5) manual page.
+authoring-byte-order little-endian;
+
+lease 192.168.1.10 {
+  starts 4 2026/10/15 10:00:00;
+  ends 4 2026/10/15 22:00:00;
+  binding state active;
+  hardware ethernet 00:11:22:33:44:55;
+  client-hostname "Laptop";
+}
+lease 192.168.1.11 {
+  starts 4 2026/10/15 10:00:00;
+  ends never;
+  binding state active;
+  client-hostname "printer";
+  set ddns-hostname = "office-printer";
+}
+lease 192.168.1.12 {
+  starts 4 2026/10/15 10:00:00;
+  ends epoch 1792000000; # 2026/10/14 17:46:40
+  client-hostname "phone";
+}
+lease 192.168.1.13 {
+  ends 4 2026/10/15 22:00:00;
+  binding state active;
+}
+lease 192.168.1.10 {
+  starts 4 2026/10/15 12:00:00;
+  ends 4 2026/10/16 00:00:00;
+  binding state active;
+  client-hostname "laptop";
+}
+lease 192.168.1.14 {
+  ends 4 2026/10/15 22:00:00;
+  binding state active;
+  client-hostname "tablet";
+}
+lease 192.168.1.14 {
+  ends 4 2026/10/15 14:00:00;
+  binding state free;
+  client-hostname "tablet";
+}
+`
+
+const keaLeases = `address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname,state,user_context,pool_id
+192.168.1.20,00:11:22:33:44:66,,3600,1792000000,1,0,0,nas.example.com.,0,,0
+192.168.1.21,00:11:22:33:44:77,,4294967295,4294967295,1,0,0,camera,0,,0
+192.168.1.22,00:11:22:33:44:88,,3600,1792000000,1,0,0,declined,1,,0
+192.168.1.23,00:11:22:33:44:99,,3600,1792000000,1,0,0,,0,,0
+`
+
+const kea6Leases = `address,duid,valid_lifetime,expire,subnet_id,pref_lifetime,lease_type,iaid,prefix_len,fqdn_fwd,fqdn_rev,hostname,hwaddr,state,user_context,hwtype,hwaddr_source,pool_id
+2001:db8::20,00:01:02,3600,1792000000,1,1800,0,1,128,0,0,nas,,0,,,,0
+2001:db8:1::,00:01:02,3600,1792000000,1,1800,2,1,56,0,0,nas,,0,,,,0
+`
+
+const dnsmasqLeases = `1792000000 00:11:22:33:44:55 192.168.1.30 tv 01:00:11:22:33:44:55
+0 00:11:22:33:44:66 192.168.1.31 server *
+1792000000 00:11:22:33:44:77 192.168.1.32 * *
+duid 00:01:00:01:2c:2f:1a:2b:00:11:22:33:44:55
+1792000000 1234 2001:db8::30 tv 00:01:00:01
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		format   format
+		input    string
+		expected []lease
+	}{
+		{
+			formatDhcpd, dhcpdLeases,
+			[]lease{
+				{hostname: "laptop", ip: ip("192.168.1.10"), end: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+				{hostname: "office-printer", ip: ip("192.168.1.11")},
+				{hostname: "phone", ip: ip("192.168.1.12"), end: time.Unix(1792000000, 0)},
+				{hostname: "tablet", ip: ip("192.168.1.14"), end: released},
+			},
+		},
+		{
+			formatKea, keaLeases,
+			[]lease{
+				{hostname: "nas.example.com.", ip: ip("192.168.1.20"), end: time.Unix(1792000000, 0)},
+				{hostname: "camera", ip: ip("192.168.1.21")},
+				{hostname: "declined", ip: ip("192.168.1.22"), end: released},
+			},
+		},
+		{
+			formatKea, kea6Leases,
+			[]lease{
+				{hostname: "nas", ip: ip("2001:db8::20"), end: time.Unix(1792000000, 0)},
+			},
+		},
+		{
+			formatDnsmasq, dnsmasqLeases,
+			[]lease{
+				{hostname: "tv", ip: ip("192.168.1.30"), end: time.Unix(1792000000, 0)},
+				{hostname: "server", ip: ip("192.168.1.31")},
+				{hostname: "tv", ip: ip("2001:db8::30"), end: time.Unix(1792000000, 0)},
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		leases, err := parseLeases(strings.NewReader(tc.input), tc.format)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if len(leases) != len(tc.expected) {
+			t.Fatalf("Test %d: expected %d leases, got %d: %v", i, len(tc.expected), len(leases), leases)
+		}
+		for j, l := range leases {
+			e := tc.expected[j]
+			if l.hostname != e.hostname || !l.ip.Equal(e.ip) || !l.end.Equal(e.end) {
+				t.Errorf("Test %d: expected lease %d to be %v, got %v", i, j, e, l)
+			}
+		}
+	}
+}
+
+func TestParseKeaWithoutHeader(t *testing.T) {
+	if _, err := parseLeases(strings.NewReader("192.168.1.20,00:11:22:33:44:66\n"), formatKea); err == nil {
+		t.Error("Expected an error for a CSV without a header")
+	}
+}
+
+func TestHostname(t *testing.T) {
+	tests := map[string]string{
+		"Laptop":                "laptop",
+		"nas.example.com.":      "nas",
+		"John's iPhone":         "john-s-iphone",
+		"_printer_":             "printer",
+		"":                      "",
+		strings.Repeat("a", 64): "",
+	}
+	for name, expected := range tests {
+		if got := hostname(name); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, name, got)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package leases
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+)
+
+const defaultReload = 5 * time.Second
+
+// init registers this plugin.
+func init() { plugin.Register(pluginName, setup) }
+
+// setup is the function that gets called when the config parser sees the token "leases". Setup is responsible
+// for parsing any extra options the leases plugin may have. The first token this function sees is "leases".
+func setup(c *caddy.Controller) error {
+	l := &Leases{ttl: uint32(defaultTTL), reload: defaultReload, idx: newIndex(nil)}
+
+	if err := parse(c, l); err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	c.OnStartup(l.OnStartup)
+	c.OnShutdown(l.OnShutdown)
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		l.Next = next
+		return l
+	})
+
+	return nil
+}
+
+// OnStartup reads the lease files, and starts checking them for changes.
+func (l *Leases) OnStartup() error {
+	l.readLeases()
+	l.stop = make(chan struct{})
+	if l.reload == 0 {
+		return nil
+	}
+	go func() {
+		ticker := time.NewTicker(l.reload)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+				l.readLeases()
+			}
+		}
+	}()
+	return nil
+}
+
+// OnShutdown stops checking the lease files for changes.
+func (l *Leases) OnShutdown() error {
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	return nil
+}
+
+func parse(c *caddy.Controller, l *Leases) error {
+	config := dnsserver.GetConfig(c)
+
+	// Expect the first token to be "leases"
+	if !c.Next() {
+		return c.Err("expected 'leases' token")
+	}
+
+	args := c.RemainingArgs()
+	if len(args) > 1 {
+		return c.ArgErr()
+	}
+	origins := plugin.OriginsFromArgsOrServerBlock(args, c.ServerBlockKeys)
+	if len(origins) != 1 {
+		return c.Errf("exactly one zone is required, got %d", len(origins))
+	}
+	l.Zone = strings.ToLower(origins[0])
+
+	for c.NextBlock() {
+		selector := strings.ToLower(c.Val())
+
+		switch selector {
+		case "dhcpd", "kea", "dnsmasq":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return c.Errf("at least one %s lease file is required", selector)
+			}
+			for _, path := range args {
+				if !filepath.IsAbs(path) && config.Root != "" {
+					path = filepath.Join(config.Root, path)
+				}
+				if _, err := os.Stat(path); err != nil {
+					if !os.IsNotExist(err) {
+						return c.Errf("unable to access lease file '%s': %v", path, err)
+					}
+					log.Warningf("File does not exist: %s", path)
+				}
+				l.files = append(l.files, &leaseFile{path: path, format: format(selector)})
+			}
+		case "ttl":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.Err("exactly one ttl value is required")
+			}
+			t, err := strconv.Atoi(args[0])
+			if err != nil {
+				return c.Err("error parsing ttl: " + err.Error())
+			}
+			if t < 0 || t > 3600 {
+				return c.Errf("ttl must be in range [0, 3600]: %d", t)
+			}
+			l.ttl = uint32(t)
+		case "reload":
+			args := c.RemainingArgs()
+			if len(args) != 1 {
+				return c.Err("reload needs a duration (zero seconds to disable)")
+			}
+			reload, err := time.ParseDuration(args[0])
+			if err != nil {
+				return c.Errf("invalid duration for reload '%s'", args[0])
+			}
+			if reload < 0 {
+				return c.Errf("invalid negative duration for reload '%s'", args[0])
+			}
+			l.reload = reload
+		case "fallthrough":
+			l.Fall.SetZonesFromArgs(c.RemainingArgs())
+		default:
+			return c.Errf("unknown property '%s'", selector)
+		}
+	}
+
+	if len(l.files) == 0 {
+		return c.Err("at least one lease file is required")
+	}
+	return nil
+}
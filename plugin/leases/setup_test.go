@@ -0,0 +1,145 @@
+package leases
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetupLeases(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         string
+		keys           []string
+		shouldErr      bool
+		expectedZone   string
+		expectedTTL    uint32
+		expectedReload time.Duration
+		expectedFiles  []leaseFile
+	}{
+		{
+			name: "server_block",
+			config: `
+leases {
+    dnsmasq /var/lib/misc/dnsmasq.leases
+}`,
+			keys:           []string{"lan."},
+			expectedZone:   "lan.",
+			expectedTTL:    uint32(defaultTTL),
+			expectedReload: defaultReload,
+			expectedFiles:  []leaseFile{{path: "/var/lib/misc/dnsmasq.leases", format: formatDnsmasq}},
+		},
+		{
+			name: "full",
+			config: `
+leases Office.Example.Org {
+    dhcpd /var/lib/dhcp/dhcpd.leases
+    kea /var/lib/kea/kea-leases4.csv /var/lib/kea/kea-leases6.csv
+    ttl 30
+    reload 0
+    fallthrough
+}`,
+			keys:           []string{"."},
+			expectedZone:   "office.example.org.",
+			expectedTTL:    30,
+			expectedReload: 0,
+			expectedFiles: []leaseFile{
+				{path: "/var/lib/dhcp/dhcpd.leases", format: formatDhcpd},
+				{path: "/var/lib/kea/kea-leases4.csv", format: formatKea},
+				{path: "/var/lib/kea/kea-leases6.csv", format: formatKea},
+			},
+		},
+		{
+			name:      "no_files",
+			config:    `leases lan`,
+			shouldErr: true,
+		},
+		{
+			name: "too_many_zones",
+			config: `
+leases a.org b.org {
+    dnsmasq /tmp/leases
+}`,
+			shouldErr: true,
+		},
+		{
+			name: "too_many_server_block_keys",
+			config: `
+leases {
+    dnsmasq /tmp/leases
+}`,
+			keys:      []string{"a.org.", "b.org."},
+			shouldErr: true,
+		},
+		{
+			name: "missing_file",
+			config: `
+leases lan {
+    kea
+}`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid_reload",
+			config: `
+leases lan {
+    reload -1s
+}`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid_ttl",
+			config: `
+leases lan {
+    ttl 3601
+}`,
+			shouldErr: true,
+		},
+		{
+			name: "invalid_property",
+			config: `
+leases lan {
+    invalid_property
+}`,
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := caddy.NewTestController("dns", tt.config)
+			c.ServerBlockKeys = tt.keys
+			l := &Leases{ttl: uint32(defaultTTL), reload: defaultReload}
+
+			err := parse(c, l)
+			if tt.shouldErr && err == nil {
+				t.Fatalf("Test %s: expected error but got none", tt.name)
+			}
+			if !tt.shouldErr && err != nil {
+				t.Fatalf("Test %s: expected no error but got: %v", tt.name, err)
+			}
+			if tt.shouldErr {
+				return
+			}
+
+			if l.Zone != tt.expectedZone {
+				t.Errorf("Test %s: expected zone %s, got %s", tt.name, tt.expectedZone, l.Zone)
+			}
+			if l.ttl != tt.expectedTTL {
+				t.Errorf("Test %s: expected TTL %d, got %d", tt.name, tt.expectedTTL, l.ttl)
+			}
+			if l.reload != tt.expectedReload {
+				t.Errorf("Test %s: expected reload %s, got %s", tt.name, tt.expectedReload, l.reload)
+			}
+			if len(l.files) != len(tt.expectedFiles) {
+				t.Fatalf("Test %s: expected %d files, got %d", tt.name, len(tt.expectedFiles), len(l.files))
+			}
+			for i, f := range l.files {
+				if f.path != tt.expectedFiles[i].path || f.format != tt.expectedFiles[i].format {
+					t.Errorf("Test %s: expected file %s (%s), got %s (%s)", tt.name, tt.expectedFiles[i].path, tt.expectedFiles[i].format, f.path, f.format)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/request"
 
@@ -50,6 +51,8 @@ func (l *Loop) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 	}
 
 	if l.seen() > 2 {
+		// Tell the servers in the loop why the query failed, before halting.
+		w.WriteMsg(detected(r))
 		log.Fatalf(`Loop (%s -> %s) detected for zone %q, see https://coredns.io/plugins/loop#troubleshooting. Query: "HINFO %s"`, state.RemoteAddr(), l.address(), l.zone, l.qname)
 	}
 
@@ -59,9 +62,19 @@ func (l *Loop) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 // Name implements the plugin.Handler interface.
 func (l *Loop) Name() string { return "loop" }
 
+// detected returns the SERVFAIL response to the looping query r.
+func detected(r *dns.Msg) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeServerFailure)
+	edns.SetExtendedError(r, m, dns.ExtendedErrorCodeOther, "Forwarding loop detected")
+	return m
+}
+
 func (l *Loop) exchange(addr string) (*dns.Msg, error) {
 	m := new(dns.Msg)
 	m.SetQuestion(l.qname, dns.TypeHINFO)
+	// With EDNS the forwarders in the loop can relay the Extended DNS Error of the response.
+	m.SetEdns0(dns.MinMsgSize, false)
 
 	return dns.Exchange(m, addr)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -80,3 +81,17 @@ func TestLoop_SetAddressAndName(t *testing.T) {
 		t.Fatalf("expected Name() to be 'loop', got %q", l.Name())
 	}
 }
+
+func TestLoop_Detected(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("1.2.example.org.", dns.TypeHINFO)
+	m.SetEdns0(dns.MinMsgSize, false)
+
+	resp := detected(m)
+	if resp.Rcode != dns.RcodeServerFailure {
+		t.Errorf("expected rcode %d, got %d", dns.RcodeServerFailure, resp.Rcode)
+	}
+	if ede := edns.ExtendedError(resp); ede == nil || ede.ExtraText == "" {
+		t.Errorf("expected an Extended DNS Error explaining the loop, got %v", ede)
+	}
+}
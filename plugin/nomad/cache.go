@@ -0,0 +1,325 @@
+package nomad
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/retry"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+var (
+	// blockingWait is how long Nomad may hold a blocking query before it returns.
+	blockingWait = 5 * time.Minute
+	// checksInterval is how often the checks of the allocations are fetched, they don't support blocking queries.
+	checksInterval = 10 * time.Second
+	// retryBackoff is how long a watch waits before it retries a failed query.
+	retryBackoff = 2 * time.Second
+)
+
+// serviceKey identifies a service in a namespace.
+type serviceKey struct {
+	namespace string
+	name      string
+}
+
+// cache holds the registrations of all services and the health of their allocations. It is kept up to date
+// with blocking queries, when Nomad can't be reached the last known registrations are served.
+type cache struct {
+	nomad  *Nomad
+	filter string
+
+	mu       sync.RWMutex
+	services map[serviceKey][]*api.ServiceRegistration
+	watches  map[serviceKey]context.CancelFunc
+	allocs   map[string]string                 // allocation ID -> client status
+	checks   map[string]api.AllocCheckStatuses // allocation ID -> check statuses
+
+	synced chan struct{} // closed once everything is fetched for the first time
+}
+
+func newCache(n *Nomad) *cache {
+	return &cache{
+		nomad:    n,
+		filter:   n.filter,
+		services: map[serviceKey][]*api.ServiceRegistration{},
+		watches:  map[serviceKey]context.CancelFunc{},
+		allocs:   map[string]string{},
+		checks:   map[string]api.AllocCheckStatuses{},
+		synced:   make(chan struct{}),
+	}
+}
+
+func (c *cache) client() (*api.Client, error) { return c.nomad.getClient() }
+
+// isSynced returns true once both the services and the allocations have been fetched from Nomad.
+func (c *cache) isSynced() bool {
+	return retry.Closed(c.synced)
+}
+
+// lookup returns the registrations of service in namespace that are healthy. If datacenter is not empty only
+// the ones in that datacenter are returned.
+func (c *cache) lookup(namespace, service, datacenter string) []*api.ServiceRegistration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var regs []*api.ServiceRegistration
+	for _, r := range c.services[serviceKey{namespace, service}] {
+		if datacenter != "" && r.Datacenter != datacenter {
+			continue
+		}
+		if c.healthy(r) {
+			regs = append(regs, r)
+		}
+	}
+	return regs
+}
+
+// healthy returns true if the allocation of r is running and the checks of r pass. The lock must be held.
+func (c *cache) healthy(r *api.ServiceRegistration) bool {
+	if status, ok := c.allocs[r.AllocID]; ok && status != api.AllocClientStatusRunning {
+		return false
+	}
+	for _, check := range c.checks[r.AllocID] {
+		if check.Service == r.ServiceName && check.Status != "success" {
+			return false
+		}
+	}
+	return true
+}
+
+// run starts the blocking queries on the services and the allocations, and once both returned, polls the
+// checks of the allocations every checksInterval until ctx is done.
+func (c *cache) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go c.runServices(ctx, wg.Done)
+	go c.runAllocations(ctx, wg.Done)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+	c.refreshChecks(ctx)
+	close(c.synced)
+
+	tick := time.NewTicker(checksInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			c.refreshChecks(ctx)
+		}
+	}
+}
+
+// runServices watches the list of services until ctx is done. fetched is called once all services have been
+// fetched for the first time.
+func (c *cache) runServices(ctx context.Context, fetched func()) {
+	fetched = sync.OnceFunc(fetched)
+	defer fetched()
+
+	var index uint64
+	for {
+		stubs, meta, err := c.listServices(ctx, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warningf("Failed to watch the list of services: %s", err)
+			watchErrors.WithLabelValues("services").Inc()
+			if !retry.Sleep(ctx, retryBackoff) {
+				return
+			}
+			continue
+		}
+		index = nextIndex(index, meta.LastIndex)
+
+		keys := map[serviceKey]struct{}{}
+		for _, stub := range stubs {
+			for _, s := range stub.Services {
+				keys[serviceKey{stub.Namespace, s.ServiceName}] = struct{}{}
+			}
+		}
+		c.update(ctx, keys)
+		fetched()
+	}
+}
+
+func (c *cache) listServices(ctx context.Context, index uint64) ([]*api.ServiceRegistrationListStub, *api.QueryMeta, error) {
+	client, err := c.client()
+	if err != nil {
+		return nil, nil, err
+	}
+	q := &api.QueryOptions{Namespace: api.AllNamespacesNamespace, WaitIndex: index, WaitTime: blockingWait}
+	return client.Services().List(q.WithContext(ctx))
+}
+
+// update starts watching new services and stops watching the ones that are gone. New services are fetched
+// before update returns, so they can be served right away.
+func (c *cache) update(ctx context.Context, keys map[serviceKey]struct{}) {
+	c.mu.Lock()
+	for key, cancel := range c.watches {
+		if _, ok := keys[key]; !ok {
+			cancel()
+			delete(c.watches, key)
+			delete(c.services, key)
+		}
+	}
+	added := map[serviceKey]context.Context{}
+	for key := range keys {
+		if _, ok := c.watches[key]; !ok {
+			ctx, cancel := context.WithCancel(ctx)
+			c.watches[key] = cancel
+			added[key] = ctx
+		}
+	}
+	c.mu.Unlock()
+
+	// Fetch the new services once, so they can be served right away, and watch them from there on.
+	var wg sync.WaitGroup
+	for key, ctx := range added {
+		wg.Add(1)
+		go func() {
+			index, _ := c.fetchService(ctx, key, 0)
+			wg.Done()
+			c.watchService(ctx, key, index)
+		}()
+	}
+	wg.Wait()
+}
+
+// watchService keeps the registrations of key up to date until ctx is done.
+func (c *cache) watchService(ctx context.Context, key serviceKey, index uint64) {
+	index = nextIndex(0, index)
+	for {
+		newIndex, err := c.fetchService(ctx, key, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if !retry.Sleep(ctx, retryBackoff) {
+				return
+			}
+			continue
+		}
+		index = nextIndex(index, newIndex)
+	}
+}
+
+// fetchService fetches the registrations of key, blocking until they changed after index if it is not 0.
+func (c *cache) fetchService(ctx context.Context, key serviceKey, index uint64) (uint64, error) {
+	client, err := c.client()
+	if err != nil {
+		return 0, err
+	}
+	q := &api.QueryOptions{Namespace: key.namespace, Filter: c.filter, WaitIndex: index, WaitTime: blockingWait}
+	regs, meta, err := client.Services().Get(key.name, q.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warningf("Failed to watch service %s in namespace %s: %s", key.name, key.namespace, err)
+			watchErrors.WithLabelValues("service").Inc()
+		}
+		return 0, err
+	}
+
+	c.mu.Lock()
+	// The service may be gone by now.
+	if _, ok := c.watches[key]; ok {
+		c.services[key] = regs
+	}
+	c.mu.Unlock()
+	return meta.LastIndex, nil
+}
+
+// runAllocations watches the status of the allocations until ctx is done. fetched is called once they have
+// been fetched for the first time.
+func (c *cache) runAllocations(ctx context.Context, fetched func()) {
+	fetched = sync.OnceFunc(fetched)
+	defer fetched()
+
+	var index uint64
+	for {
+		allocs, meta, err := c.listAllocations(ctx, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warningf("Failed to watch the allocations: %s", err)
+			watchErrors.WithLabelValues("allocations").Inc()
+			if !retry.Sleep(ctx, retryBackoff) {
+				return
+			}
+			continue
+		}
+		index = nextIndex(index, meta.LastIndex)
+
+		status := make(map[string]string, len(allocs))
+		for _, a := range allocs {
+			status[a.ID] = a.ClientStatus
+		}
+		c.mu.Lock()
+		c.allocs = status
+		c.mu.Unlock()
+		fetched()
+	}
+}
+
+func (c *cache) listAllocations(ctx context.Context, index uint64) ([]*api.AllocationListStub, *api.QueryMeta, error) {
+	client, err := c.client()
+	if err != nil {
+		return nil, nil, err
+	}
+	q := &api.QueryOptions{Namespace: api.AllNamespacesNamespace, WaitIndex: index, WaitTime: blockingWait}
+	return client.Allocations().List(q.WithContext(ctx))
+}
+
+// refreshChecks fetches the checks of the allocations of all registrations. When that fails for an
+// allocation, the checks fetched before are kept.
+func (c *cache) refreshChecks(ctx context.Context) {
+	c.mu.RLock()
+	ids := map[string]struct{}{}
+	for _, regs := range c.services {
+		for _, r := range regs {
+			ids[r.AllocID] = struct{}{}
+		}
+	}
+	c.mu.RUnlock()
+
+	client, err := c.client()
+	if err != nil {
+		return
+	}
+	checks := make(map[string]api.AllocCheckStatuses, len(ids))
+	for id := range ids {
+		q := &api.QueryOptions{}
+		cs, err := client.Allocations().Checks(id, q.WithContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Debugf("Failed to fetch the checks of allocation %s: %s", id, err)
+			c.mu.RLock()
+			cs = c.checks[id]
+			c.mu.RUnlock()
+		}
+		checks[id] = cs
+	}
+	c.mu.Lock()
+	c.checks = checks
+	c.mu.Unlock()
+}
+
+// nextIndex returns the index to use for the next blocking query. If the index went backwards it is reset, so
+// the next query doesn't block.
+func nextIndex(old, index uint64) uint64 {
+	if index < old {
+		return 0
+	}
+	if index == 0 {
+		return 1
+	}
+	return index
+}
@@ -0,0 +1,220 @@
+package nomad
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	nomad "github.com/hashicorp/nomad/api"
+	"github.com/miekg/dns"
+)
+
+// fakeNomad is a stand-in for the HTTP API of Nomad, with support for blocking queries.
+type fakeNomad struct {
+	mu     sync.Mutex
+	index  *test.Index
+	regs   []*nomad.ServiceRegistration
+	allocs map[string]string                   // allocation ID -> client status
+	checks map[string]nomad.AllocCheckStatuses // allocation ID -> checks
+	down   bool
+}
+
+func newFakeNomad() *fakeNomad {
+	return &fakeNomad{index: test.NewIndex(), allocs: map[string]string{}, checks: map[string]nomad.AllocCheckStatuses{}}
+}
+
+// change calls f to change the state of the stand-in, and wakes up the blocking queries.
+func (f *fakeNomad) change(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn()
+	f.index.Change()
+}
+
+func (f *fakeNomad) register(id, name, namespace, dc, allocID, addr string, port int, status string) {
+	f.change(func() {
+		f.regs = append(f.regs, &nomad.ServiceRegistration{ID: id, ServiceName: name, Namespace: namespace, Datacenter: dc, AllocID: allocID, Address: addr, Port: port})
+		f.allocs[allocID] = status
+	})
+}
+
+func (f *fakeNomad) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	down := f.down
+	f.mu.Unlock()
+	if down {
+		http.Error(w, "down", http.StatusInternalServerError)
+		return
+	}
+
+	if !f.index.Wait(r.Context(), r.URL.Query().Get("index")) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("X-Nomad-Index", f.index.String())
+	w.Header().Set("X-Nomad-LastContact", "0")
+
+	switch {
+	case r.URL.Path == "/v1/agent/self":
+		w.Write([]byte(`{"Member":{"Name":"nomad1"}}`))
+	case r.URL.Path == "/v1/services":
+		namespaces := map[string]*nomad.ServiceRegistrationListStub{}
+		for _, reg := range f.regs {
+			if namespaces[reg.Namespace] == nil {
+				namespaces[reg.Namespace] = &nomad.ServiceRegistrationListStub{Namespace: reg.Namespace}
+			}
+			namespaces[reg.Namespace].Services = append(namespaces[reg.Namespace].Services, &nomad.ServiceRegistrationStub{ServiceName: reg.ServiceName})
+		}
+		stubs := []*nomad.ServiceRegistrationListStub{}
+		for _, s := range namespaces {
+			stubs = append(stubs, s)
+		}
+		json.NewEncoder(w).Encode(stubs)
+	case strings.HasPrefix(r.URL.Path, "/v1/service/"):
+		name := strings.TrimPrefix(r.URL.Path, "/v1/service/")
+		regs := []*nomad.ServiceRegistration{}
+		for _, reg := range f.regs {
+			if reg.ServiceName == name && reg.Namespace == r.URL.Query().Get("namespace") {
+				regs = append(regs, reg)
+			}
+		}
+		json.NewEncoder(w).Encode(regs)
+	case r.URL.Path == "/v1/allocations":
+		allocs := []*nomad.AllocationListStub{}
+		for id, status := range f.allocs {
+			allocs = append(allocs, &nomad.AllocationListStub{ID: id, ClientStatus: status})
+		}
+		json.NewEncoder(w).Encode(allocs)
+	case strings.HasPrefix(r.URL.Path, "/v1/client/allocation/"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/client/allocation/"), "/checks")
+		checks := f.checks[id]
+		if checks == nil {
+			checks = nomad.AllocCheckStatuses{}
+		}
+		json.NewEncoder(w).Encode(checks)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newCachedNomad(t *testing.T, f *fakeNomad) *Nomad {
+	t.Helper()
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+
+	cfg := nomad.DefaultConfig()
+	cfg.Address = s.URL
+	client, err := nomad.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &Nomad{
+		Next:    test.ErrorHandler(),
+		ttl:     uint32(defaultTTL),
+		Zone:    "service.nomad",
+		clients: []*nomad.Client{client},
+	}
+	n.startCache()
+	t.Cleanup(func() { n.OnShutdown() })
+
+	test.WaitFor(t, n.Ready)
+	return n
+}
+
+func TestNomadCache(t *testing.T) {
+	checksInterval = 50 * time.Millisecond
+	defer func() { checksInterval = 10 * time.Second }()
+
+	f := newFakeNomad()
+	f.register("1", "web", "default", "dc1", "alloc1", "1.2.3.4", 8080, nomad.AllocClientStatusRunning)
+	f.register("2", "web", "default", "dc2", "alloc2", "1.2.3.5", 8080, nomad.AllocClientStatusRunning)
+	f.register("3", "web", "default", "dc1", "alloc3", "1.2.3.6", 8080, nomad.AllocClientStatusPending)
+	f.register("4", "web", "default", "dc1", "alloc4", "1.2.3.7", 8080, nomad.AllocClientStatusRunning)
+	f.register("5", "web", "other", "dc1", "alloc5", "1.2.3.8", 8080, nomad.AllocClientStatusRunning)
+	f.change(func() {
+		f.checks["alloc4"] = nomad.AllocCheckStatuses{"c1": {Service: "web", Status: "failure"}}
+	})
+
+	n := newCachedNomad(t, f)
+	// The checks of alloc4 are fetched before the cache is synced.
+	cases := []test.Case{
+		{
+			Qname: "web.default.service.nomad.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.default.service.nomad.	30	IN	A	1.2.3.4"),
+				test.A("web.default.service.nomad.	30	IN	A	1.2.3.5"),
+			},
+		},
+		{
+			Qname: "web.default.dc2.service.nomad.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.default.dc2.service.nomad.	30	IN	A	1.2.3.5"),
+			},
+		},
+		{
+			Qname: "web.other.service.nomad.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.other.service.nomad.	30	IN	A	1.2.3.8"),
+			},
+		},
+		{
+			Qname: "nonexistent.default.service.nomad.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Answer: []dns.RR{
+				test.SOA("nonexistent.default.service.nomad.	30	IN	SOA	ns1.nonexistent.default.service.nomad. hostmaster.service.nomad. 0 3600 600 86400 30"),
+			},
+		},
+	}
+	runTests(context.Background(), t, n, cases)
+
+	// A new registration and a passing check are picked up, and served while Nomad is down.
+	f.register("6", "api", "default", "dc1", "alloc6", "1.2.3.9", 80, nomad.AllocClientStatusRunning)
+	f.change(func() { f.checks["alloc4"] = nomad.AllocCheckStatuses{"c1": {Service: "web", Status: "success"}} })
+	test.WaitFor(t, func() bool {
+		return len(n.cache.lookup("default", "api", "")) == 1 && len(n.cache.lookup("default", "web", "")) == 3
+	})
+	f.change(func() { f.down = true })
+
+	cases = []test.Case{
+		{
+			Qname: "api.default.service.nomad.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("api.default.service.nomad.	30	IN	A	1.2.3.9"),
+			},
+		},
+		{
+			Qname: "web.default.dc1.service.nomad.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.default.dc1.service.nomad.	30	IN	A	1.2.3.4"),
+				test.A("web.default.dc1.service.nomad.	30	IN	A	1.2.3.7"),
+			},
+		},
+	}
+	runTests(context.Background(), t, n, cases)
+}
+
+func TestNextIndex(t *testing.T) {
+	tests := []struct {
+		old, index, expected uint64
+	}{
+		{0, 10, 10},
+		{10, 12, 12},
+		{10, 10, 10},
+		{10, 5, 0},
+		{0, 0, 1},
+	}
+	for i, tc := range tests {
+		if got := nextIndex(tc.old, tc.index); got != tc.expected {
+			t.Errorf("Test %d: expected %d, got %d", i, tc.expected, got)
+		}
+	}
+}
@@ -22,4 +22,11 @@ var (
 		Name:      "failed_requests_total",
 		Help:      "Counter of DNS requests failed.",
 	}, []string{"server", "namespace"})
+	// watchErrors is the number of failed blocking queries.
+	watchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "watch_errors_total",
+		Help:      "Counter of blocking queries to Nomad that failed.",
+	}, []string{"query"})
 )
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
@@ -30,30 +31,40 @@ type Nomad struct {
 	clients []*api.Client
 	current int
 	filter  string
+
+	mu     sync.Mutex // protects current
+	cache  *cache
+	cancel context.CancelFunc
 }
 
 func (n *Nomad) Name() string {
 	return pluginName
 }
 
-func (n Nomad) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+func (n *Nomad) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 	state := request.Request{W: w, Req: r}
 	qname, originalQName, err := processQName(state.Name(), n.Zone)
 	if err != nil {
 		return plugin.NextOrFailure(n.Name(), n.Next, ctx, w, r)
 	}
 
-	namespace, serviceName, err := extractNamespaceAndService(qname)
+	namespace, serviceName, datacenter, err := extractNamespaceAndService(qname)
 	if err != nil {
 		return plugin.NextOrFailure(n.Name(), n.Next, ctx, w, r)
 	}
 
 	m, header := initializeMessage(state, n.ttl)
 
-	svcRegistrations, _, err := fetchServiceRegistrations(n, serviceName, namespace)
-	if err != nil {
-		log.Warning(err)
-		return handleServiceLookupError(w, m, ctx, namespace)
+	var svcRegistrations []*api.ServiceRegistration
+	if n.cache != nil && n.cache.isSynced() {
+		svcRegistrations = n.cache.lookup(namespace, serviceName, datacenter)
+	} else {
+		svcRegistrations, _, err = fetchServiceRegistrations(n, serviceName, namespace)
+		if err != nil {
+			log.Warning(err)
+			return handleServiceLookupError(w, m, ctx, namespace)
+		}
+		svcRegistrations = inDatacenter(svcRegistrations, datacenter)
 	}
 
 	if len(svcRegistrations) == 0 {
@@ -75,12 +86,32 @@ func processQName(qname, zone string) (string, string, error) {
 	return base, original, err
 }
 
-func extractNamespaceAndService(qname string) (string, string, error) {
+// extractNamespaceAndService returns the namespace, service and datacenter from a name in the format
+// <service>.<namespace>[.<datacenter>]. The datacenter is empty if it is not in the name.
+func extractNamespaceAndService(qname string) (string, string, string, error) {
 	qnameSplit := dns.SplitDomainName(qname)
 	if len(qnameSplit) < 2 {
-		return "", "", fmt.Errorf("invalid query name")
+		return "", "", "", fmt.Errorf("invalid query name")
+	}
+	datacenter := ""
+	if len(qnameSplit) > 2 {
+		datacenter = qnameSplit[2]
 	}
-	return qnameSplit[1], qnameSplit[0], nil
+	return qnameSplit[1], qnameSplit[0], datacenter, nil
+}
+
+// inDatacenter returns the registrations in datacenter, or all of them if datacenter is empty.
+func inDatacenter(regs []*api.ServiceRegistration, datacenter string) []*api.ServiceRegistration {
+	if datacenter == "" {
+		return regs
+	}
+	var out []*api.ServiceRegistration
+	for _, r := range regs {
+		if r.Datacenter == datacenter {
+			out = append(out, r)
+		}
+	}
+	return out
 }
 
 func initializeMessage(state request.Request, ttl uint32) (*dns.Msg, dns.RR_Header) {
@@ -98,7 +129,7 @@ func initializeMessage(state request.Request, ttl uint32) (*dns.Msg, dns.RR_Head
 	return m, header
 }
 
-func fetchServiceRegistrations(n Nomad, serviceName, namespace string) ([]*api.ServiceRegistration, *api.QueryMeta, error) {
+func fetchServiceRegistrations(n *Nomad, serviceName, namespace string) ([]*api.ServiceRegistration, *api.QueryMeta, error) {
 	log.Debugf("Looking up record for svc: %s namespace: %s", serviceName, namespace)
 	nc, err := n.getClient()
 	if err != nil {
@@ -145,7 +176,7 @@ func addServiceResponses(m *dns.Msg, svcRegistrations []*api.ServiceRegistration
 	return nil
 }
 
-func handleResponseError(n Nomad, w dns.ResponseWriter, m *dns.Msg, originalQName string, ttl uint32, ctx context.Context, namespace string, err error) (int, error) {
+func handleResponseError(n *Nomad, w dns.ResponseWriter, m *dns.Msg, originalQName string, ttl uint32, ctx context.Context, namespace string, err error) (int, error) {
 	m.Rcode = dns.RcodeNameError
 	m.Answer = append(m.Answer, createSOARecord(originalQName, ttl, n.Zone))
 
@@ -157,3 +188,18 @@ func handleResponseError(n Nomad, w dns.ResponseWriter, m *dns.Msg, originalQNam
 
 	return dns.RcodeSuccess, err
 }
+
+// startCache starts the cache, that is kept up to date with blocking queries.
+func (n *Nomad) startCache() {
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cache, n.cancel = newCache(n), cancel
+	go n.cache.run(ctx)
+}
+
+// OnShutdown stops the cache.
+func (n *Nomad) OnShutdown() error {
+	if n.cancel != nil {
+		n.cancel()
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package nomad
 
 // Ready signals when the plugin is ready for use.
 // In case of Nomad, when the ping to the Nomad API is successful
-// the plugin is ready.
-func (n Nomad) Ready() bool {
+// and the cache is filled the plugin is ready.
+func (n *Nomad) Ready() bool {
+	if n.cache != nil && !n.cache.isSynced() {
+		return false
+	}
 	client, _ := n.getClient()
 	return client != nil
 }
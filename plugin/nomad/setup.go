@@ -35,11 +35,13 @@ func setup(c *caddy.Controller) error {
 			_, err := client.Agent().Self()
 			if err == nil {
 				n.current = idx
-				return nil
+				break
 			}
 		}
+		n.startCache()
 		return err
 	})
+	c.OnShutdown(n.OnShutdown)
 
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		n.Next = next
@@ -131,6 +133,8 @@ func parse(c *caddy.Controller, n *Nomad) error {
 }
 
 func (n *Nomad) getClient() (*nomad.Client, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	// Don't bother querying Agent().Self() if there is only one client.
 	if len(n.clients) == 1 {
 		return n.clients[0], nil
@@ -54,6 +54,14 @@ func (c *Cache) Get(key uint64) (any, bool) {
 	return c.shards[shard].Get(key)
 }
 
+// GetOrAdd returns the element under key. If there is none, el is added and returned. The lookup and
+// the addition are done under the same lock, so concurrent callers always get the same element.
+// Returns true if el was added.
+func (c *Cache) GetOrAdd(key uint64, el any) (any, bool) {
+	shard := key & (shardSize - 1)
+	return c.shards[shard].GetOrAdd(key, el)
+}
+
 // Remove removes the element indexed with key.
 func (c *Cache) Remove(key uint64) {
 	shard := key & (shardSize - 1)
@@ -98,6 +106,24 @@ func (s *shard) Add(key uint64, el any) bool {
 	return eviction
 }
 
+// GetOrAdd returns the element indexed by key, or adds el under key if there is none.
+// Returns true if el was added.
+func (s *shard) GetOrAdd(key uint64, el any) (any, bool) {
+	s.Lock()
+	defer s.Unlock()
+	if existing, ok := s.items[key]; ok {
+		return existing, false
+	}
+	if len(s.items) >= s.size {
+		for k := range s.items {
+			delete(s.items, k)
+			break
+		}
+	}
+	s.items[key] = el
+	return el, true
+}
+
 // Remove removes the element indexed by key from the cache.
 func (s *shard) Remove(key uint64) {
 	s.Lock()
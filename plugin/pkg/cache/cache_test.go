@@ -24,6 +24,17 @@ func TestCacheAddAndGet(t *testing.T) {
 	}
 }
 
+func TestCacheGetOrAdd(t *testing.T) {
+	c := New(4)
+
+	if el, added := c.GetOrAdd(1, "a"); !added || el != "a" {
+		t.Fatalf("Expected a to be added, got %v, %t", el, added)
+	}
+	if el, added := c.GetOrAdd(1, "b"); added || el != "a" {
+		t.Fatalf("Expected a to be returned, got %v, %t", el, added)
+	}
+}
+
 func TestCacheLen(t *testing.T) {
 	c := New(4)
 
@@ -0,0 +1,120 @@
+// Package catalog implements catalog zones as specified in RFC 9432. A catalog zone lists member zones,
+// so secondaries can provision these zones from the catalog, instead of from their configuration.
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Version is the version of the catalog zone schema that is supported.
+const Version = "2"
+
+// Member is a member zone of a catalog zone.
+type Member struct {
+	Zone   string   // Name of the member zone.
+	ID     string   // Unique ID of the member zone in the catalog.
+	Groups []string // Group properties of the member zone.
+}
+
+// Members returns the member zones in the records rrs of the catalog zone named catalog, keyed by the
+// name of the member zone. An error is returned when the catalog zone has an unsupported version or
+// when a member has more than one PTR record. If a zone is listed more than once, the member with the
+// lowest ID is used.
+func Members(catalog string, rrs []dns.RR) (map[string]Member, error) {
+	catalog = strings.ToLower(dns.Fqdn(catalog))
+	zones := "zones." + catalog
+
+	version := ""
+	ids := map[string]Member{}
+	groups := map[string][]string{}
+	for _, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		switch x := rr.(type) {
+		case *dns.TXT:
+			if name == "version."+catalog {
+				version = strings.Join(x.Txt, "")
+				continue
+			}
+			if !strings.HasPrefix(name, "group.") {
+				continue
+			}
+			if id, ok := member(name[len("group."):], zones); ok {
+				groups[id] = append(groups[id], x.Txt...)
+			}
+		case *dns.PTR:
+			id, ok := member(name, zones)
+			if !ok {
+				continue
+			}
+			if _, dup := ids[id]; dup {
+				return nil, fmt.Errorf("member %q has more than one PTR record", id)
+			}
+			ids[id] = Member{Zone: strings.ToLower(dns.Fqdn(x.Ptr)), ID: id}
+		}
+	}
+	if version != Version {
+		return nil, fmt.Errorf("unsupported catalog zone version %q", version)
+	}
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	members := make(map[string]Member, len(ids))
+	for _, id := range sorted {
+		m := ids[id]
+		if _, ok := members[m.Zone]; ok {
+			continue
+		}
+		m.Groups = groups[id]
+		members[m.Zone] = m
+	}
+	return members, nil
+}
+
+// member returns the ID of the member node name, which is a single label below zones.
+func member(name, zones string) (string, bool) {
+	if !strings.HasSuffix(name, "."+zones) {
+		return "", false
+	}
+	id := name[:len(name)-len(zones)-1]
+	if id == "" || strings.Contains(id, ".") {
+		return "", false
+	}
+	return id, true
+}
+
+// ID returns the ID of the member zone named zone, derived from its name.
+func ID(zone string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(dns.Fqdn(zone))))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Records returns the records of the catalog zone named catalog, with serial as its SOA serial, that has
+// zones as its member zones.
+func Records(catalog string, serial uint32, zones []string) []dns.RR {
+	catalog = strings.ToLower(dns.Fqdn(catalog))
+	rrs := []dns.RR{
+		&dns.SOA{
+			Hdr: dns.RR_Header{Name: catalog, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 0},
+			Ns:  "invalid.", Mbox: "invalid.", Serial: serial, Refresh: 60, Retry: 60, Expire: 3600, Minttl: 0,
+		},
+		&dns.NS{Hdr: dns.RR_Header{Name: catalog, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 0}, Ns: "invalid."},
+		&dns.TXT{Hdr: dns.RR_Header{Name: "version." + catalog, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 0}, Txt: []string{Version}},
+	}
+	for _, z := range zones {
+		rrs = append(rrs, &dns.PTR{
+			Hdr: dns.RR_Header{Name: ID(z) + ".zones." + catalog, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 0},
+			Ptr: strings.ToLower(dns.Fqdn(z)),
+		})
+	}
+	return rrs
+}
@@ -0,0 +1,71 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestMembers(t *testing.T) {
+	rrs := []dns.RR{
+		test.SOA("catalog.invalid. 0 IN SOA invalid. invalid. 1 60 60 3600 0"),
+		test.NS("catalog.invalid. 0 IN NS invalid."),
+		test.TXT(`version.catalog.invalid. 0 IN TXT "2"`),
+		test.PTR("a.zones.catalog.invalid. 0 IN PTR example.org."),
+		test.PTR("b.zones.catalog.invalid. 0 IN PTR Example.NET."),
+		test.PTR("c.zones.catalog.invalid. 0 IN PTR example.org."),
+		test.PTR("ext.d.zones.catalog.invalid. 0 IN PTR example.com."),
+		test.TXT(`group.b.zones.catalog.invalid. 0 IN TXT "internal"`),
+	}
+	members, err := Members("catalog.invalid.", rrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("Expected 2 members, got %d: %v", len(members), members)
+	}
+	if m := members["example.org."]; m.ID != "a" {
+		t.Errorf("Expected member example.org. with ID a, got %q", m.ID)
+	}
+	if m := members["example.net."]; m.ID != "b" || len(m.Groups) != 1 || m.Groups[0] != "internal" {
+		t.Errorf("Expected member example.net. with ID b in group internal, got %v", m)
+	}
+}
+
+func TestMembersInvalid(t *testing.T) {
+	tests := [][]dns.RR{
+		// no version
+		{test.PTR("a.zones.catalog.invalid. 0 IN PTR example.org.")},
+		// unsupported version
+		{test.TXT(`version.catalog.invalid. 0 IN TXT "1"`)},
+		// two PTR records for a member
+		{
+			test.TXT(`version.catalog.invalid. 0 IN TXT "2"`),
+			test.PTR("a.zones.catalog.invalid. 0 IN PTR example.org."),
+			test.PTR("a.zones.catalog.invalid. 0 IN PTR example.net."),
+		},
+	}
+	for i, rrs := range tests {
+		if _, err := Members("catalog.invalid.", rrs); err == nil {
+			t.Errorf("Test %d: expected error, got none", i)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	rrs := Records("catalog.invalid.", 10, []string{"example.org.", "example.net"})
+	if len(rrs) != 5 {
+		t.Fatalf("Expected 5 records, got %d", len(rrs))
+	}
+	members, err := Members("catalog.invalid.", rrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, z := range []string{"example.org.", "example.net."} {
+		if m, ok := members[z]; !ok || m.ID != ID(z) {
+			t.Errorf("Expected member %s with ID %s, got %v", z, ID(z), m)
+		}
+	}
+}
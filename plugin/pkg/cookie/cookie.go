@@ -0,0 +1,191 @@
+// Package cookie implements DNS Cookies (RFC 7873), with interoperable server cookies as specified
+// in RFC 9018.
+package cookie
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// ClientLen is the length of a client cookie.
+	ClientLen = 8
+	// ServerLen is the length of a server cookie as generated by Server.
+	ServerLen = 16
+
+	version = 1
+
+	// A server cookie is valid for an hour, and may be up to 5 minutes in the future to allow for
+	// clock skew between servers sharing a secret, see Section 4.3 of RFC 9018.
+	maxAge    = time.Hour
+	maxFuture = 5 * time.Minute
+)
+
+// ErrMalformed is returned when a COOKIE option has an invalid length.
+var ErrMalformed = errors.New("malformed cookie")
+
+// Parse returns the client and server cookie from the option o. The server cookie is nil when the
+// option only holds a client cookie.
+func Parse(o *dns.EDNS0_COOKIE) (client, server []byte, err error) {
+	b, err := hex.DecodeString(o.Cookie)
+	if err != nil {
+		return nil, nil, ErrMalformed
+	}
+	// A client cookie is 8 bytes, a server cookie between 8 and 32 bytes.
+	switch {
+	case len(b) == ClientLen:
+		return b, nil, nil
+	case len(b) >= ClientLen+8 && len(b) <= ClientLen+32:
+		return b[:ClientLen], b[ClientLen:], nil
+	}
+	return nil, nil, ErrMalformed
+}
+
+// Option returns a COOKIE option holding client and server.
+func Option(client, server []byte) *dns.EDNS0_COOKIE {
+	return &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: hex.EncodeToString(client) + hex.EncodeToString(server)}
+}
+
+// Find returns the COOKIE option in m, or nil when there is none.
+func Find(m *dns.Msg) *dns.EDNS0_COOKIE {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, o := range opt.Option {
+		if c, ok := o.(*dns.EDNS0_COOKIE); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// Set replaces any COOKIE option in the OPT record of m with c. If m has no OPT record nothing is
+// done and false is returned.
+func Set(m *dns.Msg, c *dns.EDNS0_COOKIE) bool {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return false
+	}
+	opt.Option = append(Strip(opt.Option), c)
+	return true
+}
+
+// Strip returns options without any COOKIE option. A new slice is returned when a cookie is found,
+// so options shared with another message are left alone.
+func Strip(options []dns.EDNS0) []dns.EDNS0 {
+	for i, o := range options {
+		if o.Option() != dns.EDNS0COOKIE {
+			continue
+		}
+		stripped := make([]dns.EDNS0, i, len(options))
+		copy(stripped, options[:i])
+		for _, o := range options[i+1:] {
+			if o.Option() != dns.EDNS0COOKIE {
+				stripped = append(stripped, o)
+			}
+		}
+		return stripped
+	}
+	return options
+}
+
+// NewSecret returns a random secret.
+func NewSecret() [16]byte {
+	var s [16]byte
+	rand.Read(s[:])
+	return s
+}
+
+// Server generates and validates server cookies as specified in RFC 9018. Cookies are generated
+// with the first secret, but validated against all of them; this allows for rotating secrets.
+type Server struct {
+	mu      sync.RWMutex
+	secrets [][16]byte
+
+	now func() time.Time
+}
+
+// NewServer returns a server using secrets. At least one secret must be given.
+func NewServer(secrets ...[16]byte) *Server {
+	return &Server{secrets: secrets, now: time.Now}
+}
+
+// Rotate makes secret the one used for generating cookies. The previous secret is still accepted
+// for validation, any older ones are forgotten.
+func (s *Server) Rotate(secret [16]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets = [][16]byte{secret, s.secrets[0]}
+}
+
+// Generate returns a new server cookie for client, sent from ip.
+func (s *Server) Generate(client []byte, ip net.IP) []byte {
+	s.mu.RLock()
+	secret := s.secrets[0]
+	s.mu.RUnlock()
+
+	cookie := make([]byte, ServerLen)
+	cookie[0] = version
+	binary.BigEndian.PutUint32(cookie[4:], uint32(s.now().Unix()))
+	binary.LittleEndian.PutUint64(cookie[8:], hash(secret, client, cookie[:8], ip))
+	return cookie
+}
+
+// Valid returns true if server is a valid server cookie for client, sent from ip.
+func (s *Server) Valid(client, server []byte, ip net.IP) bool {
+	if len(client) != ClientLen || len(server) != ServerLen || server[0] != version {
+		return false
+	}
+
+	ts := time.Unix(int64(binary.BigEndian.Uint32(server[4:8])), 0)
+	now := s.now()
+	if ts.Before(now.Add(-maxAge)) || ts.After(now.Add(maxFuture)) {
+		return false
+	}
+
+	sum := binary.LittleEndian.Uint64(server[8:])
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, secret := range s.secrets {
+		if hash(secret, client, server[:8], ip) == sum {
+			return true
+		}
+	}
+	return false
+}
+
+// hash returns SipHash-2-4(Client Cookie | Version | Reserved | Timestamp | Client-IP).
+func hash(secret [16]byte, client, header []byte, ip net.IP) uint64 {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	buf := make([]byte, 0, len(client)+len(header)+len(ip))
+	buf = append(buf, client...)
+	buf = append(buf, header...)
+	buf = append(buf, ip...)
+	return sum64(secret, buf)
+}
+
+type validKey struct{}
+
+// WithValid returns a context that records the request carried a valid server cookie.
+func WithValid(ctx context.Context) context.Context {
+	return context.WithValue(ctx, validKey{}, true)
+}
+
+// Valid returns true if the request being handled carried a valid server cookie. This is only
+// known when the *cookie* plugin is used.
+func Valid(ctx context.Context) bool {
+	v, _ := ctx.Value(validKey{}).(bool)
+	return v
+}
@@ -0,0 +1,181 @@
+package cookie
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestSipHash(t *testing.T) {
+	// Test vector from the SipHash paper, appendix A.
+	var k [16]byte
+	p := make([]byte, 15)
+	for i := range k {
+		k[i] = byte(i)
+	}
+	for i := range p {
+		p[i] = byte(i)
+	}
+	if h := sum64(k, p); h != 0xa129ca6149be45e5 {
+		t.Errorf("Expected SipHash-2-4 %x, got %x", uint64(0xa129ca6149be45e5), h)
+	}
+}
+
+func mustSecret(t *testing.T, s string) [16]byte {
+	t.Helper()
+	var secret [16]byte
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 16 {
+		t.Fatalf("Invalid secret %q", s)
+	}
+	copy(secret[:], b)
+	return secret
+}
+
+func TestServerRFC9018(t *testing.T) {
+	// Test vectors from appendix A of RFC 9018.
+	tests := []struct {
+		secret string
+		client string
+		ip     string
+		ts     int64
+		cookie string
+	}{
+		{"e5e973e5a6b2a43f48e7dc849e37bfcf", "2464c4abcf10c957", "198.51.100.100", 1559731985, "010000005cf79f111f8130c3eee29480"},
+		{"e5e973e5a6b2a43f48e7dc849e37bfcf", "2464c4abcf10c957", "198.51.100.100", 1559734385, "010000005cf7a871d4a564a1442aca77"},
+		{"dd3bdf9344b678b185a6f5cb60fca715", "22681ab97d52c298", "2001:db8:220:1:59de:d0f4:8769:82b8", 1559741817, "010000005cf7c57926556bd0934c72f8"},
+	}
+
+	for i, tc := range tests {
+		s := NewServer(mustSecret(t, tc.secret))
+		s.now = func() time.Time { return time.Unix(tc.ts, 0) }
+
+		client, _ := hex.DecodeString(tc.client)
+		ip := net.ParseIP(tc.ip)
+		server := s.Generate(client, ip)
+		if got := hex.EncodeToString(server); got != tc.cookie {
+			t.Errorf("Test %d: expected server cookie %s, got %s", i, tc.cookie, got)
+		}
+		if !s.Valid(client, server, ip) {
+			t.Errorf("Test %d: expected server cookie to be valid", i)
+		}
+	}
+}
+
+func TestServerValid(t *testing.T) {
+	now := time.Now()
+	s := NewServer(NewSecret())
+	s.now = func() time.Time { return now }
+
+	client := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	ip := net.ParseIP("192.0.2.1")
+	server := s.Generate(client, ip)
+
+	if !s.Valid(client, server, ip) {
+		t.Fatalf("Expected server cookie to be valid")
+	}
+	if s.Valid(client, server, net.ParseIP("192.0.2.2")) {
+		t.Errorf("Expected server cookie from another address to be invalid")
+	}
+	if s.Valid([]byte{8, 7, 6, 5, 4, 3, 2, 1}, server, ip) {
+		t.Errorf("Expected server cookie for another client cookie to be invalid")
+	}
+	if s.Valid(client, server[:8], ip) {
+		t.Errorf("Expected short server cookie to be invalid")
+	}
+
+	// Rotation keeps the previous secret for validation only.
+	s.Rotate(NewSecret())
+	if !s.Valid(client, server, ip) {
+		t.Errorf("Expected server cookie to be valid after one rotation")
+	}
+	s.Rotate(NewSecret())
+	if s.Valid(client, server, ip) {
+		t.Errorf("Expected server cookie to be invalid after two rotations")
+	}
+
+	// Expiry.
+	s = NewServer(NewSecret())
+	s.now = func() time.Time { return now }
+	server = s.Generate(client, ip)
+	now = now.Add(maxAge + time.Second)
+	if s.Valid(client, server, ip) {
+		t.Errorf("Expected expired server cookie to be invalid")
+	}
+	now = now.Add(-maxAge - maxFuture - 2*time.Second)
+	if s.Valid(client, server, ip) {
+		t.Errorf("Expected server cookie from the future to be invalid")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		cookie    string
+		client    int
+		server    int
+		malformed bool
+	}{
+		{"2464c4abcf10c957", 8, 0, false},
+		{"2464c4abcf10c957010000005cf79f111f8130c3eee29480", 8, 16, false},
+		{"2464c4abcf10c9570100000000000000", 8, 8, false},
+		{"2464c4abcf10c9", 0, 0, true},
+		{"2464c4abcf10c95701", 0, 0, true},
+		{"zz64c4abcf10c957", 0, 0, true},
+	}
+	for i, tc := range tests {
+		client, server, err := Parse(&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: tc.cookie})
+		if tc.malformed {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %s", i, tc.cookie)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(client) != tc.client || len(server) != tc.server {
+			t.Errorf("Test %d: expected %d/%d bytes, got %d/%d", i, tc.client, tc.server, len(client), len(server))
+		}
+	}
+}
+
+func TestSetAndStrip(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if Set(m, Option([]byte{1, 2, 3, 4, 5, 6, 7, 8}, nil)) {
+		t.Fatalf("Expected Set to fail without OPT record")
+	}
+
+	m.SetEdns0(4096, false)
+	nsid := &dns.EDNS0_NSID{Code: dns.EDNS0NSID}
+	shared := []dns.EDNS0{Option([]byte{1, 2, 3, 4, 5, 6, 7, 8}, nil), nsid}
+	m.IsEdns0().Option = shared
+
+	c := Option([]byte{8, 7, 6, 5, 4, 3, 2, 1}, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	if !Set(m, c) {
+		t.Fatalf("Expected Set to succeed")
+	}
+	if f := Find(m); f != c {
+		t.Errorf("Expected cookie %v, got %v", c, f)
+	}
+	if len(m.IsEdns0().Option) != 2 {
+		t.Errorf("Expected 2 options, got %d", len(m.IsEdns0().Option))
+	}
+	if shared[0].Option() != dns.EDNS0COOKIE || shared[1] != nsid {
+		t.Errorf("Expected shared options to be left alone")
+	}
+}
+
+func TestContext(t *testing.T) {
+	if Valid(context.TODO()) {
+		t.Errorf("Expected no valid cookie in empty context")
+	}
+	if !Valid(WithValid(context.TODO())) {
+		t.Errorf("Expected valid cookie")
+	}
+}
@@ -0,0 +1,58 @@
+package cookie
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// sum64 returns the SipHash-2-4 of p under the 128 bit key k.
+func sum64(k [16]byte, p []byte) uint64 {
+	k0 := binary.LittleEndian.Uint64(k[0:8])
+	k1 := binary.LittleEndian.Uint64(k[8:16])
+
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	b := uint64(len(p)) << 56
+	for ; len(p) >= 8; p = p[8:] {
+		m := binary.LittleEndian.Uint64(p)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+	for i, c := range p {
+		b |= uint64(c) << (8 * i)
+	}
+	v3 ^= b
+	round()
+	round()
+	v0 ^= b
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+
+	return v0 ^ v1 ^ v2 ^ v3
+}
@@ -0,0 +1,74 @@
+package edns
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/miekg/dns"
+)
+
+// SetExtendedError adds an Extended DNS Error (RFC 8914) with code and text to m, the response to
+// req. A client that didn't send an OPT record can't receive one, so nothing is added and false is
+// returned. If m has no OPT record yet, one is added that uses the client's buffer size and DO bit.
+// The text is left out if it would make m larger than the client's buffer.
+func SetExtendedError(req, m *dns.Msg, code uint16, text string) bool {
+	o := req.IsEdns0()
+	if o == nil {
+		return false
+	}
+	size := max(o.UDPSize(), dns.MinMsgSize)
+
+	opt := m.IsEdns0()
+	if opt == nil {
+		m.SetEdns0(size, o.Do())
+		opt = m.IsEdns0()
+	}
+
+	// The option adds 4 bytes of option header and 2 bytes for the info code.
+	if text != "" && m.Len()+6+len(text) > int(size) {
+		text = ""
+	}
+	// Clip, so an option slice shared with another message isn't written to.
+	opt.Option = append(slices.Clip(opt.Option), &dns.EDNS0_EDE{InfoCode: code, ExtraText: text})
+	return true
+}
+
+// ExtendedError returns the first Extended DNS Error in m, or nil if there is none.
+func ExtendedError(m *dns.Msg) *dns.EDNS0_EDE {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, o := range opt.Option {
+		if ede, ok := o.(*dns.EDNS0_EDE); ok {
+			return ede
+		}
+	}
+	return nil
+}
+
+// extendedError is an error that carries an Extended DNS Error.
+type extendedError struct {
+	code uint16
+	text string
+	err  error
+}
+
+func (e *extendedError) Error() string { return e.err.Error() }
+func (e *extendedError) Unwrap() error { return e.err }
+
+// WithExtendedError returns err annotated with an Extended DNS Error. A plugin that returns an rcode
+// for which the server writes the response, such as SERVFAIL, returns this error so the server adds the
+// Extended DNS Error to that response.
+func WithExtendedError(err error, code uint16, text string) error {
+	return &extendedError{code: code, text: text, err: err}
+}
+
+// ExtendedErrorFrom returns the Extended DNS Error that err was annotated with by WithExtendedError.
+func ExtendedErrorFrom(err error) (code uint16, text string, ok bool) {
+	var e *extendedError
+	if !errors.As(err, &e) {
+		return 0, "", false
+	}
+	return e.code, e.text, true
+}
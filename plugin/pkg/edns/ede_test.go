@@ -0,0 +1,98 @@
+package edns
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestSetExtendedError(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	req.SetEdns0(1232, true)
+
+	m := new(dns.Msg)
+	m.SetRcode(req, dns.RcodeServerFailure)
+	if !SetExtendedError(req, m, dns.ExtendedErrorCodeNetworkError, "Timeout") {
+		t.Fatal("Expected an EDE to be added")
+	}
+	opt := m.IsEdns0()
+	if opt == nil {
+		t.Fatal("Expected an OPT record")
+	}
+	if opt.UDPSize() != 1232 || !opt.Do() {
+		t.Errorf("Expected the client's buffer size and DO bit, got %d and %t", opt.UDPSize(), opt.Do())
+	}
+	ede := ExtendedError(m)
+	if ede == nil || ede.InfoCode != dns.ExtendedErrorCodeNetworkError || ede.ExtraText != "Timeout" {
+		t.Errorf("Expected EDE %d with text, got %v", dns.ExtendedErrorCodeNetworkError, ede)
+	}
+}
+
+func TestSetExtendedErrorNoEdns(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+
+	m := new(dns.Msg)
+	m.SetRcode(req, dns.RcodeServerFailure)
+	if SetExtendedError(req, m, dns.ExtendedErrorCodeNetworkError, "") {
+		t.Error("Expected no EDE for a client without EDNS")
+	}
+	if m.IsEdns0() != nil {
+		t.Error("Expected no OPT record")
+	}
+}
+
+func TestSetExtendedErrorSize(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	req.SetEdns0(512, false)
+
+	m := new(dns.Msg)
+	m.SetRcode(req, dns.RcodeServerFailure)
+	SetExtendedError(req, m, dns.ExtendedErrorCodeOther, strings.Repeat("x", 512))
+	if ede := ExtendedError(m); ede == nil || ede.ExtraText != "" {
+		t.Errorf("Expected EDE without text, got %v", ede)
+	}
+}
+
+func TestSetExtendedErrorSharedOption(t *testing.T) {
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	req.SetEdns0(1232, false)
+	opt := req.IsEdns0()
+	opt.Option = make([]dns.EDNS0, 0, 4)
+	opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID})
+
+	// A response that reuses the request's options must not change those of the request.
+	m := new(dns.Msg)
+	m.SetRcode(req, dns.RcodeServerFailure)
+	m.SetEdns0(1232, false)
+	m.IsEdns0().Option = opt.Option
+	SetExtendedError(req, m, dns.ExtendedErrorCodeOther, "")
+	if len(m.IsEdns0().Option) != 2 {
+		t.Errorf("Expected 2 options in the response, got %d", len(m.IsEdns0().Option))
+	}
+	if opt.Option[:2][1] != nil {
+		t.Errorf("Expected the request's options to be left alone")
+	}
+}
+
+func TestWithExtendedError(t *testing.T) {
+	base := errors.New("no healthy upstream")
+	err := fmt.Errorf("plugin/forward: %w", WithExtendedError(base, dns.ExtendedErrorCodeNoReachableAuthority, "No healthy upstream"))
+
+	if !errors.Is(err, base) {
+		t.Errorf("Expected the error to wrap %v", base)
+	}
+	code, text, ok := ExtendedErrorFrom(err)
+	if !ok || code != dns.ExtendedErrorCodeNoReachableAuthority || text != "No healthy upstream" {
+		t.Errorf("Expected Extended DNS Error %d, got %d %q %t", dns.ExtendedErrorCodeNoReachableAuthority, code, text, ok)
+	}
+	if _, _, ok := ExtendedErrorFrom(base); ok {
+		t.Error("Expected no Extended DNS Error")
+	}
+}
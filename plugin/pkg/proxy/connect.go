@@ -133,45 +133,66 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts Options
 		state.Req.Id = originId
 	}()
 
-	if err := pc.c.WriteMsg(state.Req); err != nil {
-		pc.c.Close() // not giving it back
-		if err == io.EOF && cached {
-			return nil, ErrCachedClosed
-		}
-		return nil, err
+	// Over UDP we send our own cookie to the upstream, on a copy so the client's request isn't changed.
+	req := state.Req
+	if p.transport.transportTypeFromConn(pc) == typeUDP {
+		req = p.cookies.request(state.Req)
 	}
+	withCookie := req != state.Req
 
 	var ret *dns.Msg
-	pc.c.SetReadDeadline(time.Now().Add(p.readTimeout))
-	for {
-		ret, err = pc.c.ReadMsg()
-		if err != nil {
-			if ret != nil && (state.Req.Id == ret.Id) && p.transport.transportTypeFromConn(pc) == typeUDP && shouldTruncateResponse(err) {
-				// For UDP, if the error is an overflow, we probably have an upstream misbehaving in some way.
-				// (e.g. sending >512 byte responses without an eDNS0 OPT RR).
-				// Instead of returning an error, return an empty response with TC bit set. This will make the
-				// client retry over TCP (if that's supported) or at least receive a clean
-				// error. The connection is still good so we break before the close.
-
-				// Truncate the response.
-				ret = truncateResponse(ret)
-				break
-			}
-
+	for retried := false; ; retried = true {
+		if err := pc.c.WriteMsg(req); err != nil {
 			pc.c.Close() // not giving it back
 			if err == io.EOF && cached {
 				return nil, ErrCachedClosed
 			}
-			// recovery the origin Id after upstream.
-			if ret != nil {
-				ret.Id = originId
+			return nil, err
+		}
+
+		pc.c.SetReadDeadline(time.Now().Add(p.readTimeout))
+		for {
+			ret, err = pc.c.ReadMsg()
+			if err != nil {
+				if ret != nil && (req.Id == ret.Id) && p.transport.transportTypeFromConn(pc) == typeUDP && shouldTruncateResponse(err) {
+					// For UDP, if the error is an overflow, we probably have an upstream misbehaving in some way.
+					// (e.g. sending >512 byte responses without an eDNS0 OPT RR).
+					// Instead of returning an error, return an empty response with TC bit set. This will make the
+					// client retry over TCP (if that's supported) or at least receive a clean
+					// error. The connection is still good so we break before the close.
+
+					// Truncate the response.
+					ret = truncateResponse(ret)
+					break
+				}
+
+				pc.c.Close() // not giving it back
+				if err == io.EOF && cached {
+					return nil, ErrCachedClosed
+				}
+				// recovery the origin Id after upstream.
+				if ret != nil {
+					ret.Id = originId
+				}
+				return ret, err
+			}
+			// drop out-of-order responses, and responses not echoing our client cookie as those are spoofed.
+			if req.Id == ret.Id && (!withCookie || !p.cookies.spoofed(ret)) {
+				break
 			}
-			return ret, err
 		}
-		// drop out-of-order responses
-		if state.Req.Id == ret.Id {
+
+		if !withCookie {
+			break
+		}
+		p.cookies.learn(ret)
+		strip(ret)
+		// A BADCOOKIE response carries a fresh server cookie, retry once with it.
+		if ret.Rcode != dns.RcodeBadCookie || retried {
 			break
 		}
+		req = p.cookies.request(state.Req)
+		req.Id = dns.Id()
 	}
 	// recovery the origin Id after upstream.
 	ret.Id = originId
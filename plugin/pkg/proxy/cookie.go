@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"slices"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/cookie"
+
+	"github.com/miekg/dns"
+)
+
+// cookies holds the client cookie we send to an upstream and the last server cookie it returned,
+// see RFC 7873. They are only used over UDP, as TCP already protects against off-path spoofing.
+type cookies struct {
+	client []byte
+
+	mu     sync.RWMutex
+	server []byte
+}
+
+func newCookies() *cookies {
+	c := &cookies{client: make([]byte, cookie.ClientLen)}
+	rand.Read(c.client)
+	return c
+}
+
+// request returns a copy of r carrying our cookie, replacing any cookie sent by the client. The
+// OPT record is copied, so r is left untouched. If r has no OPT record, r itself is returned.
+func (c *cookies) request(r *dns.Msg) *dns.Msg {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return r
+	}
+
+	c.mu.RLock()
+	o := cookie.Option(c.client, c.server)
+	c.mu.RUnlock()
+
+	copt := *opt
+	copt.Option = append(slices.Clip(cookie.Strip(opt.Option)), o)
+
+	req := new(dns.Msg)
+	*req = *r
+	req.Extra = make([]dns.RR, len(r.Extra))
+	for i, rr := range r.Extra {
+		if rr == opt {
+			rr = &copt
+		}
+		req.Extra[i] = rr
+	}
+	return req
+}
+
+// spoofed returns true if ret carries a cookie that doesn't echo our client cookie. Responses
+// without a cookie are from upstreams not supporting cookies and are accepted.
+func (c *cookies) spoofed(ret *dns.Msg) bool {
+	o := cookie.Find(ret)
+	if o == nil {
+		return false
+	}
+	client, _, err := cookie.Parse(o)
+	return err != nil || !bytes.Equal(client, c.client)
+}
+
+// learn stores the server cookie from ret, to be sent with the next requests.
+func (c *cookies) learn(ret *dns.Msg) {
+	o := cookie.Find(ret)
+	if o == nil {
+		return
+	}
+	client, server, err := cookie.Parse(o)
+	if err != nil || server == nil || !bytes.Equal(client, c.client) {
+		return
+	}
+	c.mu.Lock()
+	c.server = slices.Clone(server)
+	c.mu.Unlock()
+}
+
+// strip removes the cookie from ret, it belongs to us and not to the client.
+func strip(ret *dns.Msg) {
+	if opt := ret.IsEdns0(); opt != nil {
+		opt.Option = cookie.Strip(opt.Option)
+	}
+}
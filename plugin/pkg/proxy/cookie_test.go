@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cookie"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestProxyCookie(t *testing.T) {
+	srv := cookie.NewServer(cookie.NewSecret())
+	var queries atomic.Int32
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		queries.Add(1)
+		client, server, err := cookie.Parse(cookie.Find(r))
+		if err != nil {
+			t.Errorf("Expected a cookie in the request, got %s", err)
+			return
+		}
+		ip := net.ParseIP("127.0.0.1")
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		if !srv.Valid(client, server, ip) {
+			ret.Rcode = dns.RcodeBadCookie
+		} else {
+			ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		}
+		ret.SetEdns0(4096, false)
+		cookie.Set(ret, cookie.Option(client, srv.Generate(client, ip)))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	p := NewProxy("TestProxyCookie", s.Addr, transport.DNS)
+	p.readTimeout = 100 * time.Millisecond
+	p.Start(5 * time.Second)
+	defer p.Stop()
+
+	clientCookie := cookie.Option([]byte("01234567"), nil)
+	for i := range 2 {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.SetEdns0(4096, false)
+		cookie.Set(m, clientCookie)
+
+		req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+		resp, err := p.Connect(context.Background(), req, Options{PreferUDP: true})
+		if err != nil {
+			t.Fatalf("Query %d: failed to connect to testdnsserver: %s", i, err)
+		}
+		if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 1 {
+			t.Errorf("Query %d: expected an answer, got %v", i, resp)
+		}
+		if cookie.Find(resp) != nil {
+			t.Errorf("Query %d: expected the upstream's cookie to be stripped", i)
+		}
+		if o := cookie.Find(m); o != clientCookie {
+			t.Errorf("Query %d: expected the client's request to be left alone, got %v", i, o)
+		}
+	}
+	// The first query gets a BADCOOKIE and is retried, the second uses the learned server cookie.
+	if q := queries.Load(); q != 3 {
+		t.Errorf("Expected 3 queries to the upstream, got %d", q)
+	}
+}
+
+func TestProxyCookieSpoofed(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		client, _, _ := cookie.Parse(cookie.Find(r))
+
+		spoofed := new(dns.Msg)
+		spoofed.SetReply(r)
+		spoofed.Answer = append(spoofed.Answer, test.A("example.org. IN A 192.0.2.1"))
+		spoofed.SetEdns0(4096, false)
+		cookie.Set(spoofed, cookie.Option([]byte("01234567"), nil))
+		w.WriteMsg(spoofed)
+
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		ret.SetEdns0(4096, false)
+		cookie.Set(ret, cookie.Option(client, nil))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	p := NewProxy("TestProxyCookieSpoofed", s.Addr, transport.DNS)
+	p.readTimeout = 100 * time.Millisecond
+	p.Start(5 * time.Second)
+	defer p.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+
+	req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+	resp, err := p.Connect(context.Background(), req, Options{PreferUDP: true})
+	if err != nil {
+		t.Fatalf("Failed to connect to testdnsserver: %s", err)
+	}
+	if x := resp.Answer[0].(*dns.A).A.String(); x != "127.0.0.1" {
+		t.Errorf("Expected the response echoing our cookie, got %s", x)
+	}
+}
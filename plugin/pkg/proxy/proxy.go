@@ -20,6 +20,9 @@ type Proxy struct {
 
 	readTimeout time.Duration
 
+	// client and server cookies used over UDP
+	cookies *cookies
+
 	// health checking
 	probe  *up.Probe
 	health HealthChecker
@@ -35,6 +38,7 @@ func NewProxy(proxyName, addr, trans string) *Proxy {
 		transport:   newTransport(proxyName, addr),
 		health:      NewHealthChecker(proxyName, trans, true, "."),
 		proxyName:   proxyName,
+		cookies:     newCookies(),
 	}
 
 	runtime.SetFinalizer(p, (*Proxy).finalizer)
@@ -0,0 +1,30 @@
+// Package retry holds helpers for the loops that keep a plugin in sync with a backend, and that back off
+// when the backend can't be reached.
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep waits for d. It returns false if ctx is done first, in which case the caller should stop retrying.
+func Sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// Closed returns true if ch is closed, without blocking. It is used to check if an initial sync is done.
+func Closed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
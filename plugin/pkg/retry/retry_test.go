@@ -0,0 +1,30 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	if !Sleep(context.Background(), time.Millisecond) {
+		t.Error("Expected Sleep to return true when the duration passed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if Sleep(ctx, time.Hour) {
+		t.Error("Expected Sleep to return false when the context is done")
+	}
+}
+
+func TestClosed(t *testing.T) {
+	ch := make(chan struct{})
+	if Closed(ch) {
+		t.Error("Expected an open channel not to be closed")
+	}
+	close(ch)
+	if !Closed(ch) {
+		t.Error("Expected a closed channel to be closed")
+	}
+}
@@ -0,0 +1,146 @@
+// Package zonerefresh is used by the plugins that serve zones fetched from the API of a DNS provider, like
+// route53, azure and clouddns. Each zone is refreshed on its own timer. With incremental refresh the records
+// of a zone are only fetched again when the version the provider reports for it changed, or when a full
+// refresh is due.
+package zonerefresh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/durations"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// DefaultInterval is how long between refreshes of a zone, if not configured.
+	DefaultInterval = time.Minute
+	// fullRefreshFactor is how many refresh intervals of a zone pass at most before its records are fetched
+	// again with incremental refresh, if no full refresh interval is configured. The versions reported by
+	// some providers miss edits of record values, this bounds how long such an edit isn't served.
+	fullRefreshFactor = 5
+)
+
+// Options holds how zones are refreshed. They are set with the refresh and incremental properties.
+type Options struct {
+	Interval    time.Duration            // how long between refreshes of a zone
+	Zones       map[string]time.Duration // zone -> how long between its refreshes, if not Interval
+	Incremental bool                     // only fetch the records of a zone when its version changed
+	FullRefresh time.Duration            // with Incremental, how long the records are used at most; 0 for the default
+}
+
+// New returns the Options used if none are configured.
+func New() Options {
+	return Options{Interval: DefaultInterval, Zones: map[string]time.Duration{}}
+}
+
+// Parse parses the current property of c if it is one of:
+//
+//	refresh DURATION [ZONE...]
+//	incremental [FULL_REFRESH]
+//
+// It returns false if the property is another one, so the caller can parse it.
+func (o *Options) Parse(c *caddy.Controller) (bool, error) {
+	switch c.Val() {
+	case "refresh":
+		if !c.NextArg() {
+			return true, c.ArgErr()
+		}
+		d, err := duration(c, c.Val())
+		if err != nil {
+			return true, err
+		}
+		// With zones, the interval is only used for those.
+		zones := c.RemainingArgs()
+		if len(zones) == 0 {
+			o.Interval = d
+		}
+		for _, z := range zones {
+			o.Zones[normalize(z)] = d
+		}
+		return true, nil
+	case "incremental":
+		o.Incremental = true
+		args := c.RemainingArgs()
+		if len(args) > 1 {
+			return true, c.ArgErr()
+		}
+		if len(args) == 1 {
+			d, err := duration(c, args[0])
+			if err != nil {
+				return true, err
+			}
+			o.FullRefresh = d
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// duration parses a positive duration, a number without unit is in seconds.
+func duration(c *caddy.Controller, s string) (time.Duration, error) {
+	d, err := durations.NewDurationFromArg(s)
+	if err != nil {
+		return 0, c.Errf("unable to parse duration: %v", err)
+	}
+	if d <= 0 {
+		return 0, c.Errf("duration must be greater than 0: %q", s)
+	}
+	return d, nil
+}
+
+// Refresh returns how long between refreshes of zone.
+func (o Options) Refresh(zone string) time.Duration {
+	if d, ok := o.Zones[normalize(zone)]; ok {
+		return d
+	}
+	return o.Interval
+}
+
+// Validate returns an error if a refresh interval is set for a zone that isn't one of zones.
+func (o Options) Validate(zones []string) error {
+	known := make(map[string]bool, len(zones))
+	for _, z := range zones {
+		known[normalize(z)] = true
+	}
+	for z := range o.Zones {
+		if !known[z] {
+			return fmt.Errorf("refresh interval for unknown zone %q", z)
+		}
+	}
+	return nil
+}
+
+// Unchanged returns true if the records of zone, fetched at fetched when it had version old, don't have to be
+// fetched again now it has version current.
+func (o Options) Unchanged(zone, old, current string, fetched time.Time) bool {
+	if !o.Incremental || old != current {
+		return false
+	}
+	full := o.FullRefresh
+	if full == 0 {
+		full = fullRefreshFactor * o.Refresh(zone)
+	}
+	return time.Since(fetched) < full
+}
+
+// Run calls refresh every refresh interval of zone until ctx is done.
+func (o Options) Run(ctx context.Context, zone string, refresh func(context.Context)) {
+	timer := time.NewTimer(o.Refresh(zone))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			refresh(ctx)
+			timer.Reset(o.Refresh(zone))
+		}
+	}
+}
+
+func normalize(zone string) string { return strings.ToLower(dns.Fqdn(zone)) }
@@ -0,0 +1,140 @@
+package zonerefresh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input       string
+		shouldErr   bool
+		interval    time.Duration
+		zones       map[string]time.Duration
+		incremental bool
+		fullRefresh time.Duration
+	}{
+		{"refresh 90", false, 90 * time.Second, map[string]time.Duration{}, false, 0},
+		{"refresh 5m", false, 5 * time.Minute, map[string]time.Duration{}, false, 0},
+		{"refresh 30s example.org Example.COM.", false, DefaultInterval, map[string]time.Duration{"example.org.": 30 * time.Second, "example.com.": 30 * time.Second}, false, 0},
+		{"incremental", false, DefaultInterval, map[string]time.Duration{}, true, 0},
+		{"incremental 6h", false, DefaultInterval, map[string]time.Duration{}, true, 6 * time.Hour},
+		// fails
+		{"refresh", true, 0, nil, false, 0},
+		{"refresh foo", true, 0, nil, false, 0},
+		{"refresh 0", true, 0, nil, false, 0},
+		{"refresh -1m", true, 0, nil, false, 0},
+		{"incremental 0", true, 0, nil, false, 0},
+		{"incremental 1h 2h", true, 0, nil, false, 0},
+	}
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		c.Next()
+		o := New()
+		ok, err := o.Parse(c)
+		if !ok {
+			t.Fatalf("Test %d: expected %q to be parsed", i, tc.input)
+		}
+		if (err != nil) != tc.shouldErr {
+			t.Fatalf("Test %d: expected error %t, got %v", i, tc.shouldErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		if o.Interval != tc.interval || o.Incremental != tc.incremental || o.FullRefresh != tc.fullRefresh {
+			t.Errorf("Test %d: expected %s %t %s, got %s %t %s", i, tc.interval, tc.incremental, tc.fullRefresh, o.Interval, o.Incremental, o.FullRefresh)
+		}
+		if len(o.Zones) != len(tc.zones) {
+			t.Errorf("Test %d: expected zones %v, got %v", i, tc.zones, o.Zones)
+		}
+		for z, d := range tc.zones {
+			if o.Zones[z] != d {
+				t.Errorf("Test %d: expected %s for %s, got %s", i, d, z, o.Zones[z])
+			}
+		}
+	}
+
+	c := caddy.NewTestController("dns", "fallthrough")
+	c.Next()
+	o := New()
+	if ok, err := o.Parse(c); ok || err != nil {
+		t.Errorf("Expected another property not to be parsed, got %t, %v", ok, err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	o := New()
+	o.Zones["example.org."] = 10 * time.Second
+	if d := o.Refresh("Example.org"); d != 10*time.Second {
+		t.Errorf("Expected 10s for example.org, got %s", d)
+	}
+	if d := o.Refresh("example.com."); d != DefaultInterval {
+		t.Errorf("Expected %s for example.com., got %s", DefaultInterval, d)
+	}
+
+	if err := o.Validate([]string{"example.org", "example.com."}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := o.Validate([]string{"example.com."}); err == nil {
+		t.Error("Expected an error for an interval of an unknown zone")
+	}
+}
+
+func TestUnchanged(t *testing.T) {
+	o := New()
+	o.Zones["example.org."] = 10 * time.Second
+	now := time.Now()
+	if o.Unchanged("example.com.", "1", "1", now) {
+		t.Error("Expected records to be fetched without incremental refresh")
+	}
+
+	o.Incremental = true
+	if !o.Unchanged("example.com.", "1", "1", now) {
+		t.Error("Expected records of an unchanged zone not to be fetched")
+	}
+	if o.Unchanged("example.com.", "1", "2", now) {
+		t.Error("Expected records of a changed zone to be fetched")
+	}
+	// The full refresh is a few refresh intervals of the zone.
+	if !o.Unchanged("example.com.", "1", "1", now.Add(-time.Minute)) {
+		t.Error("Expected records fetched a refresh interval ago not to be fetched")
+	}
+	if o.Unchanged("example.org.", "1", "1", now.Add(-time.Minute)) {
+		t.Error("Expected records fetched more than 5 refresh intervals ago to be fetched")
+	}
+
+	o.FullRefresh = 6 * time.Hour
+	if !o.Unchanged("example.org.", "1", "1", now.Add(-time.Hour)) {
+		t.Error("Expected records fetched within the full refresh interval not to be fetched")
+	}
+}
+
+func TestRun(t *testing.T) {
+	o := New()
+	o.Zones["example.org."] = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	refreshed := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		o.Run(ctx, "example.org.", func(context.Context) { refreshed <- struct{}{} })
+		close(done)
+	}()
+	for range 2 {
+		select {
+		case <-refreshed:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for a refresh")
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-refreshed:
+		<-done
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return when the context is done")
+	}
+}
@@ -0,0 +1,41 @@
+package recursor
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics"
+
+	"github.com/miekg/dns"
+)
+
+const bufsize = 1232 // EDNS0 buffer size advertised to name servers, see https://www.dnsflagday.net/2020/.
+
+// exchanger sends a query to the name server at addr and returns its response.
+type exchanger interface {
+	exchange(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error)
+}
+
+// client queries name servers over UDP and retries over TCP when the response is truncated.
+type client struct {
+	port    string
+	timeout time.Duration
+}
+
+func (c *client) exchange(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error) {
+	hostport := net.JoinHostPort(addr, c.port)
+	server := metrics.WithServer(ctx)
+
+	queries.WithLabelValues(server, "udp").Inc()
+	udp := &dns.Client{Net: "udp", Timeout: c.timeout, UDPSize: bufsize}
+	ret, _, err := udp.ExchangeContext(ctx, m, hostport)
+	if err != nil || !ret.Truncated {
+		return ret, err
+	}
+
+	queries.WithLabelValues(server, "tcp").Inc()
+	tcp := &dns.Client{Net: "tcp", Timeout: c.timeout}
+	ret, _, err = tcp.ExchangeContext(ctx, m, hostport)
+	return ret, err
+}
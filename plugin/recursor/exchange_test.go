@@ -0,0 +1,43 @@
+package recursor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestExchangeTCPFallback(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if w.RemoteAddr().Network() == "udp" {
+			m.Truncated = true
+		} else {
+			m.Answer = append(m.Answer, test.A("example.org. 300 IN A 192.0.2.1"))
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Close()
+
+	host, port, _ := net.SplitHostPort(s.Addr)
+	if host == "::" || host == "" {
+		host = "127.0.0.1"
+	}
+	c := &client{port: port, timeout: time.Second}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	resp, err := c.exchange(context.TODO(), m, host)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if resp.Truncated || len(resp.Answer) != 1 {
+		t.Errorf("Expected the full response over TCP, got %s", resp)
+	}
+}
@@ -0,0 +1,88 @@
+package recursor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// rootHints are the root name servers, as published by IANA in https://www.internic.net/domain/named.root.
+const rootHints = `.                        3600000      NS    A.ROOT-SERVERS.NET.
+A.ROOT-SERVERS.NET.      3600000      A     198.41.0.4
+A.ROOT-SERVERS.NET.      3600000      AAAA  2001:503:ba3e::2:30
+.                        3600000      NS    B.ROOT-SERVERS.NET.
+B.ROOT-SERVERS.NET.      3600000      A     170.247.170.2
+B.ROOT-SERVERS.NET.      3600000      AAAA  2801:1b8:10::b
+.                        3600000      NS    C.ROOT-SERVERS.NET.
+C.ROOT-SERVERS.NET.      3600000      A     192.33.4.12
+C.ROOT-SERVERS.NET.      3600000      AAAA  2001:500:2::c
+.                        3600000      NS    D.ROOT-SERVERS.NET.
+D.ROOT-SERVERS.NET.      3600000      A     199.7.91.13
+D.ROOT-SERVERS.NET.      3600000      AAAA  2001:500:2d::d
+.                        3600000      NS    E.ROOT-SERVERS.NET.
+E.ROOT-SERVERS.NET.      3600000      A     192.203.230.10
+E.ROOT-SERVERS.NET.      3600000      AAAA  2001:500:a8::e
+.                        3600000      NS    F.ROOT-SERVERS.NET.
+F.ROOT-SERVERS.NET.      3600000      A     192.5.5.241
+F.ROOT-SERVERS.NET.      3600000      AAAA  2001:500:2f::f
+.                        3600000      NS    G.ROOT-SERVERS.NET.
+G.ROOT-SERVERS.NET.      3600000      A     192.112.36.4
+G.ROOT-SERVERS.NET.      3600000      AAAA  2001:500:12::d0d
+.                        3600000      NS    H.ROOT-SERVERS.NET.
+H.ROOT-SERVERS.NET.      3600000      A     198.97.190.53
+H.ROOT-SERVERS.NET.      3600000      AAAA  2001:500:1::53
+.                        3600000      NS    I.ROOT-SERVERS.NET.
+I.ROOT-SERVERS.NET.      3600000      A     192.36.148.17
+I.ROOT-SERVERS.NET.      3600000      AAAA  2001:7fe::53
+.                        3600000      NS    J.ROOT-SERVERS.NET.
+J.ROOT-SERVERS.NET.      3600000      A     192.58.128.30
+J.ROOT-SERVERS.NET.      3600000      AAAA  2001:503:c27::2:30
+.                        3600000      NS    K.ROOT-SERVERS.NET.
+K.ROOT-SERVERS.NET.      3600000      A     193.0.14.129
+K.ROOT-SERVERS.NET.      3600000      AAAA  2001:7fd::1
+.                        3600000      NS    L.ROOT-SERVERS.NET.
+L.ROOT-SERVERS.NET.      3600000      A     199.7.83.42
+L.ROOT-SERVERS.NET.      3600000      AAAA  2001:500:9f::42
+.                        3600000      NS    M.ROOT-SERVERS.NET.
+M.ROOT-SERVERS.NET.      3600000      A     202.12.27.33
+M.ROOT-SERVERS.NET.      3600000      AAAA  2001:dc3::35
+`
+
+// parseHints parses the root hints in r, in zone file format: the NS records of the root zone and the
+// A and AAAA records of these name servers. Other records are ignored.
+func parseHints(r io.Reader, file string) (*delegation, error) {
+	d := &delegation{zone: ".", addrs: map[string][]string{}}
+	glue := map[string][]string{}
+
+	zp := dns.NewZoneParser(r, ".", file)
+	zp.SetDefaultTTL(maxTTL)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch x := rr.(type) {
+		case *dns.NS:
+			if x.Hdr.Name == "." {
+				d.ns = append(d.ns, strings.ToLower(x.Ns))
+			}
+		case *dns.A:
+			name := strings.ToLower(x.Hdr.Name)
+			glue[name] = append(glue[name], x.A.String())
+		case *dns.AAAA:
+			name := strings.ToLower(x.Hdr.Name)
+			glue[name] = append(glue[name], x.AAAA.String())
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, ns := range d.ns {
+		if addrs, ok := glue[ns]; ok {
+			d.addrs[ns] = addrs
+		}
+	}
+	if len(d.addrs) == 0 {
+		return nil, fmt.Errorf("no root name servers with addresses in %q", file)
+	}
+	return d, nil
+}
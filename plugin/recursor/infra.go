@@ -0,0 +1,111 @@
+package recursor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	minTTL = 5     // minimum time in seconds infrastructure records are cached.
+	maxTTL = 86400 // maximum time in seconds infrastructure records are cached.
+)
+
+// delegation is a zone cut: the name servers of a zone and the addresses we got for them as glue.
+type delegation struct {
+	zone   string
+	ns     []string            // Names of the name servers.
+	addrs  map[string][]string // Addresses of the name servers by name, from glue.
+	expire time.Time
+}
+
+// addresses is the set of addresses of a name server that we looked up ourselves.
+type addresses struct {
+	addrs  []string
+	expire time.Time
+}
+
+// infra is the infrastructure cache, it holds the delegations and name server addresses learned while
+// resolving. Answers are not cached here, that is left to the cache plugin.
+type infra struct {
+	delegations *cache.Cache
+	addrs       *cache.Cache
+}
+
+func newInfra(capacity int) *infra {
+	return &infra{delegations: cache.New(capacity), addrs: cache.New(capacity)}
+}
+
+// delegation returns the cached delegation of zone, or nil if there is none.
+func (i *infra) delegation(zone string, now time.Time) *delegation {
+	key := hash(zone)
+	el, ok := i.delegations.Get(key)
+	if !ok {
+		return nil
+	}
+	d := el.(*delegation)
+	if now.After(d.expire) {
+		i.delegations.Remove(key)
+		return nil
+	}
+	return d
+}
+
+func (i *infra) addDelegation(d *delegation) { i.delegations.Add(hash(d.zone), d) }
+
+// addresses returns the cached addresses of the name server ns.
+func (i *infra) addresses(ns string, now time.Time) []string {
+	key := hash(ns)
+	el, ok := i.addrs.Get(key)
+	if !ok {
+		return nil
+	}
+	a := el.(*addresses)
+	if now.After(a.expire) {
+		i.addrs.Remove(key)
+		return nil
+	}
+	return a.addrs
+}
+
+func (i *infra) addAddresses(ns string, addrs []string, expire time.Time) {
+	i.addrs.Add(hash(ns), &addresses{addrs: addrs, expire: expire})
+}
+
+func hash(name string) uint64 { return cache.Hash([]byte(strings.ToLower(name))) }
+
+// expire returns the time the records with a TTL of ttl seconds expire, clamped to minTTL and maxTTL.
+func expire(now time.Time, ttl uint32) time.Time {
+	ttl = min(max(ttl, minTTL), maxTTL)
+	return now.Add(time.Duration(ttl) * time.Second)
+}
+
+// glue adds the addresses of the name servers of d found in rrs to d, as long as they are below zone, the
+// zone of the servers that sent them.
+func glue(d *delegation, rrs []dns.RR, zone string) {
+	for _, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		if !dns.IsSubDomain(zone, name) || !d.serves(name) {
+			continue
+		}
+		switch x := rr.(type) {
+		case *dns.A:
+			d.addrs[name] = append(d.addrs[name], x.A.String())
+		case *dns.AAAA:
+			d.addrs[name] = append(d.addrs[name], x.AAAA.String())
+		}
+	}
+}
+
+// serves returns true if ns is one of the name servers of d.
+func (d *delegation) serves(ns string) bool {
+	for _, n := range d.ns {
+		if n == ns {
+			return true
+		}
+	}
+	return false
+}
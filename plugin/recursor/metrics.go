@@ -0,0 +1,25 @@
+package recursor
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Variables declared for monitoring.
+var (
+	queries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "recursor",
+		Name:      "queries_total",
+		Help:      "Counter of queries sent to authoritative name servers.",
+	}, []string{"server", "proto"})
+
+	failures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "recursor",
+		Name:      "failures_total",
+		Help:      "Counter of queries that could not be resolved.",
+	}, []string{"server"})
+)
@@ -0,0 +1,92 @@
+// Package recursor implements a plugin that resolves queries iteratively, starting at the root name servers.
+package recursor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("recursor")
+
+// Recursor is a recursive resolver. It queries the authoritative name servers itself, following the referrals
+// from the root name servers down to the zone of the query name.
+type Recursor struct {
+	Next  plugin.Handler
+	Zones []string
+
+	hints     *delegation // The root name servers.
+	qmin      bool        // QNAME minimisation (RFC 9156).
+	timeout   time.Duration
+	infra     *infra
+	rtt       *rtt
+	exchanger exchanger
+	upstream  *upstream.Upstream
+
+	now func() time.Time
+}
+
+// New returns a new Recursor that starts resolving at the root name servers in hints, queried on port.
+// Its infrastructure cache holds at most capacity zones.
+func New(zones []string, hints *delegation, port string, capacity int) *Recursor {
+	return &Recursor{
+		Zones:     zones,
+		hints:     hints,
+		qmin:      true,
+		timeout:   defaultTimeout,
+		infra:     newInfra(capacity),
+		rtt:       newRTT(capacity),
+		exchanger: &client{port: port, timeout: defaultTimeout},
+		upstream:  upstream.New(),
+		now:       time.Now,
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (r *Recursor) ServeDNS(ctx context.Context, w dns.ResponseWriter, req *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: req}
+	if plugin.Zones(r.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(r.Name(), r.Next, ctx, w, req)
+	}
+
+	depth, _ := ctx.Value(depthKey{}).(int)
+	l := &lookup{state: state, do: state.Do(), depth: depth}
+	ans, err := r.resolve(ctx, l, state.Name(), state.QType())
+	if err != nil {
+		log.Debugf("Failed to resolve %s %s: %s", state.Name(), state.Type(), err)
+		failures.WithLabelValues(metrics.WithServer(ctx)).Inc()
+
+		m := new(dns.Msg)
+		m.SetRcode(req, dns.RcodeServerFailure)
+		m.RecursionAvailable = true
+		code := dns.ExtendedErrorCodeOther
+		if errors.Is(err, errUnreachable) {
+			code = dns.ExtendedErrorCodeNoReachableAuthority
+		}
+		edns.SetExtendedError(req, m, code, err.Error())
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
+
+	m := new(dns.Msg)
+	m.SetRcode(req, ans.Rcode)
+	m.RecursionAvailable = true
+	m.Answer = ans.Answer
+	m.Ns = ans.Ns
+	state.SizeAndDo(m)
+	m = state.Scrub(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (r *Recursor) Name() string { return "recursor" }
@@ -0,0 +1,311 @@
+package recursor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	dbRoot = `$TTL 3600
+.                   IN SOA a.root-servers.test. admin.test. 1 3600 600 86400 300
+.                   IN NS  a.root-servers.test.
+a.root-servers.test. IN A  198.51.100.1
+org.                IN NS  ns.org.
+ns.org.             IN A   198.51.100.2
+net.                IN NS  ns.net.
+ns.net.             IN A   198.51.100.3
+broken.             IN NS  ns.broken.
+ns.broken.          IN A   198.51.100.99
+`
+	dbOrg = `$TTL 3600
+$ORIGIN org.
+@              IN SOA ns.org. admin.org. 1 3600 600 86400 300
+               IN NS  ns
+ns             IN A   198.51.100.2
+example        IN NS  ns1.example
+ns1.example    IN A   198.51.100.4
+glueless       IN NS  ns.example.net.
+`
+	dbNet = `$TTL 3600
+$ORIGIN net.
+@              IN SOA ns.net. admin.net. 1 3600 600 86400 300
+               IN NS  ns
+ns             IN A   198.51.100.3
+example        IN NS  ns.example
+ns.example     IN A   198.51.100.5
+`
+	dbExampleOrg = `$TTL 300
+$ORIGIN example.org.
+@              IN SOA ns1 admin 1 3600 600 86400 300
+               IN NS  ns1
+ns1            IN A   198.51.100.4
+www            IN A   192.0.2.1
+alias          IN CNAME www
+ext            IN CNAME www.example.net.
+a.b.c          IN A   192.0.2.2
+dname          IN DNAME example.net.
+`
+	dbExampleNet = `$TTL 300
+$ORIGIN example.net.
+@              IN SOA ns admin 1 3600 600 86400 300
+               IN NS  ns
+ns             IN A   198.51.100.5
+www            IN A   192.0.2.10
+`
+	dbGluelessOrg = `$TTL 300
+$ORIGIN glueless.org.
+@              IN SOA ns.example.net. admin 1 3600 600 86400 300
+               IN NS  ns.example.net.
+www            IN A   192.0.2.20
+`
+)
+
+// hierarchy serves zones from the file plugin by server address, it logs the queries it receives.
+type hierarchy struct {
+	servers map[string]plugin.Handler
+
+	sync.Mutex
+	queries []string
+}
+
+func (h *hierarchy) exchange(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error) {
+	h.Lock()
+	h.queries = append(h.queries, fmt.Sprintf("%s %s %s", addr, m.Question[0].Name, dns.TypeToString[m.Question[0].Qtype]))
+	h.Unlock()
+
+	s, ok := h.servers[addr]
+	if !ok {
+		return nil, errors.New("i/o timeout")
+	}
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(ctx, rec, m.Copy())
+	if rec.Msg == nil {
+		return nil, errors.New("no response")
+	}
+	if rec.Msg.Rcode == dns.RcodeServerFailure && len(rec.Msg.Answer) > 0 {
+		// The file plugin fails to look up external CNAME targets without a server, answer with the CNAME
+		// like other authoritative servers do.
+		rec.Msg.Rcode = dns.RcodeSuccess
+	}
+	return rec.Msg, nil
+}
+
+// sent returns the queries sent to addr.
+func (h *hierarchy) sent(addr string) []string {
+	h.Lock()
+	defer h.Unlock()
+	qs := []string{}
+	for _, q := range h.queries {
+		if strings.HasPrefix(q, addr+" ") {
+			qs = append(qs, strings.TrimPrefix(q, addr+" "))
+		}
+	}
+	return qs
+}
+
+func newHierarchy(t *testing.T) *hierarchy {
+	t.Helper()
+	zones := func(dbs map[string]string) plugin.Handler {
+		f := file.File{Zones: file.Zones{Z: map[string]*file.Zone{}}}
+		for origin, db := range dbs {
+			z, err := file.Parse(strings.NewReader(db), origin, "stdin", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Z[origin] = z
+			f.Names = append(f.Names, origin)
+		}
+		return f
+	}
+	return &hierarchy{servers: map[string]plugin.Handler{
+		"198.51.100.1": zones(map[string]string{".": dbRoot}),
+		"198.51.100.2": zones(map[string]string{"org.": dbOrg}),
+		"198.51.100.3": zones(map[string]string{"net.": dbNet}),
+		"198.51.100.4": zones(map[string]string{"example.org.": dbExampleOrg}),
+		"198.51.100.5": zones(map[string]string{"example.net.": dbExampleNet, "glueless.org.": dbGluelessOrg}),
+	}}
+}
+
+func newRecursor(t *testing.T) (*Recursor, *hierarchy) {
+	t.Helper()
+	hints, err := parseHints(strings.NewReader(". NS a.root-servers.test.\na.root-servers.test. A 198.51.100.1\n"), "hints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newHierarchy(t)
+	r := New([]string{"."}, hints, defaultPort, defaultCap)
+	r.exchanger = h
+	return r, h
+}
+
+func TestRecursor(t *testing.T) {
+	r, _ := newRecursor(t)
+
+	tests := []test.Case{
+		{
+			Qname: "www.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example.org. 300 IN A 192.0.2.1")},
+		},
+		{
+			Qname: "alias.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("alias.example.org. 300 IN CNAME www.example.org."),
+				test.A("www.example.org. 300 IN A 192.0.2.1"),
+			},
+		},
+		{
+			Qname: "ext.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("ext.example.org. 300 IN CNAME www.example.net."),
+				test.A("www.example.net. 300 IN A 192.0.2.10"),
+			},
+		},
+		{
+			Qname: "www.dname.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.DNAME("dname.example.org. 300 IN DNAME example.net."),
+				test.CNAME("www.dname.example.org. 300 IN CNAME www.example.net."),
+				test.A("www.example.net. 300 IN A 192.0.2.10"),
+			},
+		},
+		{
+			Qname: "a.b.c.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("a.b.c.example.org. 300 IN A 192.0.2.2")},
+		},
+		{
+			Qname: "www.glueless.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.glueless.org. 300 IN A 192.0.2.20")},
+		},
+		{
+			Qname: "nx.example.org.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{test.SOA("example.org. 300 IN SOA ns1.example.org. admin.example.org. 1 3600 600 86400 300")},
+		},
+		{
+			Qname: "www.example.org.", Qtype: dns.TypeMX,
+			Ns: []dns.RR{test.SOA("example.org. 300 IN SOA ns1.example.org. admin.example.org. 1 3600 600 86400 300")},
+		},
+		{
+			// The DS records are in the parent zone.
+			Qname: "example.org.", Qtype: dns.TypeDS,
+			Ns: []dns.RR{test.SOA("org. 3600 IN SOA ns.org. admin.org. 1 3600 600 86400 300")},
+		},
+	}
+
+	for _, tc := range tests {
+		m := tc.Msg()
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Expected no error for %s, got %s", tc.Qname, err)
+		}
+		if !rec.Msg.RecursionAvailable || rec.Msg.Authoritative {
+			t.Errorf("Expected RA and no AA bit for %s", tc.Qname)
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %s %s: %s", tc.Qname, dns.TypeToString[tc.Qtype], err)
+		}
+	}
+}
+
+func TestRecursorQnameMinimisation(t *testing.T) {
+	tests := []struct {
+		qmin bool
+		root []string // queries received by the root server
+		org  []string // queries received by the org server
+	}{
+		{true, []string{". NS", "org. A"}, []string{"example.org. A"}},
+		{false, []string{". NS", "a.b.c.example.org. AAAA"}, []string{"a.b.c.example.org. AAAA"}},
+	}
+	for _, tc := range tests {
+		r, h := newRecursor(t)
+		r.qmin = tc.qmin
+
+		m := new(dns.Msg)
+		m.SetQuestion("a.b.c.example.org.", dns.TypeAAAA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		r.ServeDNS(context.TODO(), rec, m)
+		if rec.Msg.Rcode != dns.RcodeSuccess {
+			t.Errorf("Expected NOERROR, got %s", dns.RcodeToString[rec.Msg.Rcode])
+		}
+		if got := h.sent("198.51.100.1"); strings.Join(got, ",") != strings.Join(tc.root, ",") {
+			t.Errorf("Expected root server to get %v, got %v", tc.root, got)
+		}
+		if got := h.sent("198.51.100.2"); strings.Join(got, ",") != strings.Join(tc.org, ",") {
+			t.Errorf("Expected org server to get %v, got %v", tc.org, got)
+		}
+		if tc.qmin {
+			// The empty non-terminals are queried one label at a time.
+			want := []string{"b.c.example.org. A", "a.b.c.example.org. AAAA"}
+			got := h.sent("198.51.100.4")
+			if len(got) < 2 || strings.Join(got[len(got)-2:], ",") != strings.Join(want, ",") {
+				t.Errorf("Expected example.org server to end with %v, got %v", want, got)
+			}
+		}
+	}
+}
+
+func TestRecursorInfraCache(t *testing.T) {
+	r, h := newRecursor(t)
+	for _, qname := range []string{"www.example.org.", "alias.example.org."} {
+		m := new(dns.Msg)
+		m.SetQuestion(qname, dns.TypeA)
+		r.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+	}
+	// The second query goes straight to the example.org server.
+	if got := h.sent("198.51.100.1"); len(got) != 2 {
+		t.Errorf("Expected 2 queries to the root server, got %v", got)
+	}
+	if got := h.sent("198.51.100.2"); len(got) != 1 {
+		t.Errorf("Expected 1 query to the org server, got %v", got)
+	}
+}
+
+func TestRecursorUnreachable(t *testing.T) {
+	r, _ := newRecursor(t)
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.broken.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	r.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeServerFailure {
+		t.Fatalf("Expected SERVFAIL, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	ede := edns.ExtendedError(rec.Msg)
+	if ede == nil || ede.InfoCode != dns.ExtendedErrorCodeNoReachableAuthority {
+		t.Errorf("Expected extended error %d, got %v", dns.ExtendedErrorCodeNoReachableAuthority, ede)
+	}
+	if r.rtt.get("198.51.100.99") != defaultTimeout {
+		t.Errorf("Expected unreachable server to get an RTT of %s, got %s", defaultTimeout, r.rtt.get("198.51.100.99"))
+	}
+}
+
+func TestMinimise(t *testing.T) {
+	tests := []struct {
+		qname, zone, expected string
+	}{
+		{"a.b.example.org.", ".", "org."},
+		{"a.b.example.org.", "org.", "example.org."},
+		{"a.b.example.org.", "example.org.", "b.example.org."},
+		{"a.b.example.org.", "b.example.org.", "a.b.example.org."},
+		{"a.b.example.org.", "a.b.example.org.", "a.b.example.org."},
+		{".", ".", "."},
+	}
+	for _, tc := range tests {
+		if got := minimise(tc.qname, tc.zone); got != tc.expected {
+			t.Errorf("Expected minimise(%q, %q) to be %q, got %q", tc.qname, tc.zone, tc.expected, got)
+		}
+	}
+}
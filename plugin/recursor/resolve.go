@@ -0,0 +1,442 @@
+package recursor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	maxReferrals   = 30              // referrals followed for a single name.
+	maxCNAME       = 8               // CNAME and DNAME records followed for a single query.
+	maxDepth       = 4               // nesting of name server address lookups.
+	maxQueries     = 64              // queries sent to name servers for a single lookup.
+	defaultTimeout = 2 * time.Second // timeout of a single query to a name server.
+)
+
+var (
+	errUnreachable = errors.New("no reachable name server")
+	errQueries     = errors.New("too many queries")
+	errReferrals   = errors.New("too many referrals")
+	errCNAME       = errors.New("CNAME chain too long")
+)
+
+// depthKey is the context key holding the nesting depth of a lookup of name server addresses that is sent
+// through the plugin chain.
+type depthKey struct{}
+
+// lookup is the state of the resolution of a single query.
+type lookup struct {
+	state   request.Request // The client's request, used to look up name server addresses through the plugin chain.
+	do      bool            // Ask name servers for DNSSEC records.
+	depth   int             // Nesting of name server address lookups.
+	queries int             // Queries sent so far.
+}
+
+// resolve resolves qname and qtype, following CNAME and DNAME records. The returned message only has its
+// rcode, answer and, for negative answers, authority section set.
+func (r *Recursor) resolve(ctx context.Context, l *lookup, qname string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	name := qname
+	for {
+		resp, zone, err := r.iterate(ctx, l, name, qtype)
+		if err != nil {
+			return nil, err
+		}
+		rrs, next, ok := answer(resp, zone, name, qtype)
+		m.Answer = append(m.Answer, rrs...)
+		if next == "" {
+			m.Rcode = resp.Rcode
+			if !ok {
+				m.Ns = authority(resp, zone)
+			}
+			return m, nil
+		}
+		if chain(m.Answer) > maxCNAME {
+			return nil, errCNAME
+		}
+		name = next
+	}
+}
+
+// iterate sends the query for qname and qtype to the name servers of the closest known zone cut and follows
+// their referrals until it reaches the authoritative servers. It returns their response and the zone they
+// were queried for.
+func (r *Recursor) iterate(ctx context.Context, l *lookup, qname string, qtype uint16) (*dns.Msg, string, error) {
+	d := r.closest(ctx, l, qname, qtype)
+	qmin := r.qmin
+	name := minimise(qname, d.zone)
+	for range maxReferrals {
+		qn, qt := qname, qtype
+		if qmin && !strings.EqualFold(name, qname) {
+			// RFC 9156 recommends A queries for the minimised names, as some servers don't handle NS well.
+			qn, qt = name, dns.TypeA
+		}
+
+		resp, err := r.query(ctx, l, d, qn, qt)
+		if err != nil {
+			if qn != qname && errors.Is(err, errUnreachable) {
+				// Some servers fail on minimised queries, retry with the full name (RFC 9156, Section 2.3).
+				qmin = false
+				continue
+			}
+			return nil, "", err
+		}
+		if child := r.referral(resp, d.zone, qn, qt); child != nil {
+			d = child
+			name = minimise(qname, d.zone)
+			continue
+		}
+		if qn == qname {
+			return resp, d.zone, nil
+		}
+
+		switch resp.Rcode {
+		case dns.RcodeNameError:
+			// Nothing exists below a name that doesn't exist (RFC 8020).
+			return resp, d.zone, nil
+		case dns.RcodeSuccess:
+			// No zone cut at name, add the next label.
+			name = minimise(qname, name)
+		default:
+			qmin = false
+		}
+	}
+	return nil, "", errReferrals
+}
+
+// closest returns the closest zone cut above qname in the infrastructure cache. If there is none the root name
+// servers are primed.
+func (r *Recursor) closest(ctx context.Context, l *lookup, qname string, qtype uint16) *delegation {
+	name := strings.ToLower(qname)
+	if qtype == dns.TypeDS && name != "." {
+		// The DS records are served by the parent zone.
+		name = parent(name)
+	}
+	now := r.now()
+	for {
+		if d := r.infra.delegation(name, now); d != nil {
+			return d
+		}
+		if name == "." {
+			return r.prime(ctx, l)
+		}
+		name = parent(name)
+	}
+}
+
+// prime asks the root hints for the current name servers of the root zone (RFC 8109) and caches these. If that
+// fails the root hints are used as is.
+func (r *Recursor) prime(ctx context.Context, l *lookup) *delegation {
+	resp, err := r.query(ctx, l, r.hints, ".", dns.TypeNS)
+	if err != nil {
+		log.Warningf("Failed to prime the root name servers: %s", err)
+		return r.hints
+	}
+	d := &delegation{zone: ".", addrs: map[string][]string{}}
+	ttl := uint32(maxTTL)
+	for _, rr := range resp.Answer {
+		if ns, ok := rr.(*dns.NS); ok && ns.Hdr.Name == "." {
+			d.ns = append(d.ns, strings.ToLower(ns.Ns))
+			ttl = min(ttl, ns.Hdr.Ttl)
+		}
+	}
+	glue(d, resp.Extra, ".")
+	if len(d.addrs) == 0 {
+		return r.hints
+	}
+	d.expire = expire(r.now(), ttl)
+	r.infra.addDelegation(d)
+	return d
+}
+
+// referral returns the delegation in resp, if resp refers the query for qname and qtype from the servers of zone
+// to those of a zone closer to qname. The delegation is added to the infrastructure cache.
+func (r *Recursor) referral(resp *dns.Msg, zone, qname string, qtype uint16) *delegation {
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) > 0 {
+		return nil
+	}
+	var d *delegation
+	ttl := uint32(maxTTL)
+	for _, rr := range resp.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		child := strings.ToLower(ns.Hdr.Name)
+		if child == zone || !dns.IsSubDomain(zone, child) || !dns.IsSubDomain(child, qname) {
+			continue
+		}
+		if qtype == dns.TypeDS && strings.EqualFold(child, qname) {
+			continue
+		}
+		if d == nil {
+			d = &delegation{zone: child, addrs: map[string][]string{}}
+		}
+		if d.zone != child {
+			continue
+		}
+		d.ns = append(d.ns, strings.ToLower(ns.Ns))
+		ttl = min(ttl, ns.Hdr.Ttl)
+	}
+	if d == nil {
+		return nil
+	}
+	glue(d, resp.Extra, zone)
+	d.expire = expire(r.now(), ttl)
+	r.infra.addDelegation(d)
+	return d
+}
+
+// query sends the query for qname and qtype to the name servers of d until one of them responds. The servers
+// whose addresses are known are tried first, fastest first, then the addresses of the others are looked up.
+func (r *Recursor) query(ctx context.Context, l *lookup, d *delegation, qname string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(qname, qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(bufsize, l.do)
+
+	now := r.now()
+	known := []string{}
+	unknown := []string{}
+	for _, ns := range d.ns {
+		addrs := d.addrs[ns]
+		if len(addrs) == 0 {
+			addrs = r.infra.addresses(ns, now)
+		}
+		if len(addrs) == 0 {
+			unknown = append(unknown, ns)
+			continue
+		}
+		known = append(known, addrs...)
+	}
+
+	resp, err := r.send(ctx, l, m, known)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	for _, ns := range unknown {
+		if dns.IsSubDomain(d.zone, ns) {
+			// Without glue we can't reach this one.
+			continue
+		}
+		resp, err := r.send(ctx, l, m, r.addresses(ctx, l, ns))
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+	return nil, fmt.Errorf("%w for %s", errUnreachable, d.zone)
+}
+
+// send sends m to the servers at addrs, fastest first, and returns the first useful response. If none of the
+// servers respond nil is returned. An error is only returned when the lookup must be aborted.
+func (r *Recursor) send(ctx context.Context, l *lookup, m *dns.Msg, addrs []string) (*dns.Msg, error) {
+	for _, addr := range r.rtt.sort(addrs) {
+		if l.queries >= maxQueries {
+			return nil, errQueries
+		}
+		l.queries++
+
+		start := time.Now()
+		resp, err := r.exchanger.exchange(ctx, m, addr)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Debugf("No response from %s for %s: %s", addr, m.Question[0].Name, err)
+			r.rtt.fail(addr, r.timeout)
+			continue
+		}
+		r.rtt.update(addr, time.Since(start))
+
+		if len(resp.Question) == 0 || !strings.EqualFold(resp.Question[0].Name, m.Question[0].Name) {
+			continue
+		}
+		switch resp.Rcode {
+		case dns.RcodeServerFailure, dns.RcodeRefused, dns.RcodeNotImplemented, dns.RcodeFormatError:
+			// Lame or broken server, try the next one.
+			continue
+		}
+		return resp, nil
+	}
+	return nil, nil
+}
+
+// addresses looks up the addresses of the name server ns and caches them. Where possible this is done through
+// the plugin chain, so the answers are cached by the cache plugin as well.
+func (r *Recursor) addresses(ctx context.Context, l *lookup, ns string) []string {
+	if l.depth >= maxDepth {
+		return nil
+	}
+	ctx = context.WithValue(ctx, depthKey{}, l.depth+1)
+
+	addrs := []string{}
+	ttl := uint32(maxTTL)
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		var (
+			m   *dns.Msg
+			err error
+		)
+		if l.state.Req != nil && plugin.Zones(r.Zones).Matches(ns) != "" {
+			m, err = r.upstream.Lookup(ctx, l.state, ns, qtype)
+		}
+		if m == nil || err != nil {
+			m, err = r.resolve(ctx, &lookup{state: l.state, depth: l.depth + 1}, ns, qtype)
+		}
+		if err != nil {
+			continue
+		}
+		for _, rr := range m.Answer {
+			switch x := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, x.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, x.AAAA.String())
+			default:
+				continue
+			}
+			ttl = min(ttl, rr.Header().Ttl)
+		}
+		if len(addrs) > 0 {
+			break
+		}
+	}
+	if len(addrs) > 0 {
+		r.infra.addAddresses(ns, addrs, expire(r.now(), ttl))
+	}
+	return addrs
+}
+
+// answer collects the records for name and qtype from the answer section of resp, the response of the servers
+// of zone. CNAME and DNAME records are followed as long as the data of their target is in resp. If the chain
+// continues elsewhere, next holds the name to continue with. The returned bool is true if records of qtype
+// were found.
+func answer(resp *dns.Msg, zone, name string, qtype uint16) (rrs []dns.RR, next string, ok bool) {
+	for i := 0; i <= maxCNAME; i++ {
+		seen := false
+		target, dname := "", ""
+		var dnameTTL uint32
+		for _, rr := range resp.Answer {
+			h := rr.Header()
+			if !dns.IsSubDomain(zone, h.Name) {
+				// Out of bailiwick, the servers of zone aren't authoritative for this.
+				continue
+			}
+			if sig, isSig := rr.(*dns.RRSIG); isSig {
+				switch {
+				case strings.EqualFold(h.Name, name) && (sig.TypeCovered == qtype || sig.TypeCovered == dns.TypeCNAME):
+					rrs = append(rrs, rr)
+				case sig.TypeCovered == dns.TypeDNAME && below(h.Name, name):
+					rrs = append(rrs, rr)
+				}
+				continue
+			}
+			switch {
+			case strings.EqualFold(h.Name, name):
+				seen = true
+				if h.Rrtype == qtype || qtype == dns.TypeANY {
+					rrs = append(rrs, rr)
+					ok = true
+				} else if cname, isCNAME := rr.(*dns.CNAME); isCNAME {
+					rrs = append(rrs, rr)
+					target = cname.Target
+				}
+			case h.Rrtype == dns.TypeDNAME && below(h.Name, name):
+				seen = true
+				rrs = append(rrs, rr)
+				dname = substitute(name, h.Name, rr.(*dns.DNAME).Target)
+				dnameTTL = h.Ttl
+			}
+		}
+		if ok {
+			return rrs, "", true
+		}
+		if target == "" && dname != "" {
+			// Synthesize the CNAME the server left out (RFC 6672, Section 3.1).
+			rrs = append(rrs, &dns.CNAME{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: dnameTTL}, Target: dname})
+			target = dname
+		}
+		if !seen && i > 0 {
+			// The target of the chain isn't in resp.
+			return rrs, name, false
+		}
+		if target == "" {
+			return rrs, "", false
+		}
+		name = target
+		if !dns.IsSubDomain(zone, name) {
+			return rrs, name, false
+		}
+	}
+	return rrs, name, false
+}
+
+// authority returns the records from the authority section of the negative response resp that tell why
+// the answer is negative: the SOA record, and the NSEC or NSEC3 records when DNSSEC was requested.
+func authority(resp *dns.Msg, zone string) []dns.RR {
+	var rrs []dns.RR
+	for _, rr := range resp.Ns {
+		if !dns.IsSubDomain(zone, rr.Header().Name) {
+			continue
+		}
+		switch rr.Header().Rrtype {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeRRSIG:
+			rrs = append(rrs, rr)
+		}
+	}
+	return rrs
+}
+
+// chain returns the number of CNAME and DNAME records in rrs.
+func chain(rrs []dns.RR) int {
+	n := 0
+	for _, rr := range rrs {
+		if t := rr.Header().Rrtype; t == dns.TypeCNAME || t == dns.TypeDNAME {
+			n++
+		}
+	}
+	return n
+}
+
+// minimise returns the name made of zone and the next label of qname below it, or qname itself if that's
+// all that's left.
+func minimise(qname, zone string) string {
+	n := dns.CountLabel(qname) - dns.CountLabel(zone) - 1
+	if n <= 0 {
+		return qname
+	}
+	idx := dns.Split(qname)
+	return qname[idx[n]:]
+}
+
+// substitute replaces the owner of a DNAME record at the end of name with its target (RFC 6672, Section 2.2).
+func substitute(name, owner, target string) string {
+	n := dns.CountLabel(name) - dns.CountLabel(owner)
+	idx := dns.Split(name)
+	prefix := name[:idx[n]]
+	if target == "." {
+		return prefix
+	}
+	return prefix + target
+}
+
+// below returns true if name is strictly below zone.
+func below(zone, name string) bool {
+	return dns.IsSubDomain(zone, name) && !strings.EqualFold(zone, name)
+}
+
+// parent returns the parent of name, the root zone is its own parent.
+func parent(name string) string {
+	i, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[i:]
+}
@@ -0,0 +1,98 @@
+package recursor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/rand"
+)
+
+const (
+	initialRTT = 200 * time.Millisecond // RTT assumed for servers that haven't been queried yet.
+	maxRTT     = 10 * time.Second       // RTT at most assigned to a failing server.
+	rttExpire  = 15 * time.Minute       // After which the RTT of a server is forgotten, so it gets another chance.
+)
+
+var rn = rand.New(time.Now().UnixNano())
+
+// rtt tracks the smoothed round trip times of the name servers, so the fastest ones are queried first.
+type rtt struct {
+	servers *cache.Cache
+	now     func() time.Time
+}
+
+type server struct {
+	sync.Mutex
+	srtt    time.Duration
+	updated time.Time
+}
+
+func newRTT(capacity int) *rtt { return &rtt{servers: cache.New(capacity), now: time.Now} }
+
+// get returns the smoothed RTT of the server at addr.
+func (r *rtt) get(addr string) time.Duration {
+	el, ok := r.servers.Get(hash(addr))
+	if !ok {
+		return initialRTT
+	}
+	s := el.(*server)
+	s.Lock()
+	defer s.Unlock()
+	if r.now().Sub(s.updated) > rttExpire {
+		return initialRTT
+	}
+	return s.srtt
+}
+
+// update folds the measured round trip time d of the server at addr into its smoothed RTT.
+func (r *rtt) update(addr string, d time.Duration) {
+	r.set(addr, func(srtt time.Duration, ok bool) time.Duration {
+		if !ok {
+			return d
+		}
+		return (7*srtt + 3*d) / 10
+	})
+}
+
+// fail penalizes the server at addr for not responding within timeout.
+func (r *rtt) fail(addr string, timeout time.Duration) {
+	r.set(addr, func(srtt time.Duration, ok bool) time.Duration {
+		if !ok {
+			srtt = initialRTT
+		}
+		return min(max(2*srtt, timeout), maxRTT)
+	})
+}
+
+func (r *rtt) set(addr string, f func(srtt time.Duration, ok bool) time.Duration) {
+	key := hash(addr)
+	now := r.now()
+	el, ok := r.servers.Get(key)
+	if !ok {
+		s := &server{updated: now}
+		s.srtt = f(0, false)
+		r.servers.Add(key, s)
+		return
+	}
+	s := el.(*server)
+	s.Lock()
+	s.srtt = f(s.srtt, now.Sub(s.updated) <= rttExpire)
+	s.updated = now
+	s.Unlock()
+}
+
+// sort returns addrs ordered by their smoothed RTT, servers with the same RTT are shuffled.
+func (r *rtt) sort(addrs []string) []string {
+	sorted := make([]string, len(addrs))
+	for i, j := range rn.Perm(len(addrs)) {
+		sorted[i] = addrs[j]
+	}
+	rtts := make(map[string]time.Duration, len(addrs))
+	for _, a := range sorted {
+		rtts[a] = r.get(a)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return rtts[sorted[i]] < rtts[sorted[j]] })
+	return sorted
+}
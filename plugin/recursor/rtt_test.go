@@ -0,0 +1,46 @@
+package recursor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRTT(t *testing.T) {
+	now := time.Now()
+	r := newRTT(defaultCap)
+	r.now = func() time.Time { return now }
+
+	r.update("192.0.2.1", 100*time.Millisecond)
+	r.update("192.0.2.2", 10*time.Millisecond)
+	r.fail("192.0.2.3", time.Second)
+
+	if got := r.get("192.0.2.4"); got != initialRTT {
+		t.Errorf("Expected unknown server to have RTT %s, got %s", initialRTT, got)
+	}
+	r.update("192.0.2.1", 200*time.Millisecond)
+	if got := r.get("192.0.2.1"); got != 130*time.Millisecond {
+		t.Errorf("Expected smoothed RTT of 130ms, got %s", got)
+	}
+
+	got := r.sort([]string{"192.0.2.3", "192.0.2.4", "192.0.2.1", "192.0.2.2"})
+	expected := []string{"192.0.2.2", "192.0.2.1", "192.0.2.4", "192.0.2.3"}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected servers sorted as %v, got %v", expected, got)
+		}
+	}
+
+	// Failing servers are backed off, up to maxRTT.
+	for range 10 {
+		r.fail("192.0.2.3", time.Second)
+	}
+	if got := r.get("192.0.2.3"); got != maxRTT {
+		t.Errorf("Expected RTT of failing server to be %s, got %s", maxRTT, got)
+	}
+
+	// And get another chance after a while.
+	now = now.Add(rttExpire + time.Second)
+	if got := r.get("192.0.2.3"); got != initialRTT {
+		t.Errorf("Expected expired RTT to be %s, got %s", initialRTT, got)
+	}
+}
@@ -0,0 +1,121 @@
+package recursor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+)
+
+func init() { plugin.Register("recursor", setup) }
+
+const (
+	defaultCap  = 10000 // default capacity of the infrastructure cache, in zones.
+	defaultPort = "53"
+)
+
+func setup(c *caddy.Controller) error {
+	r, err := recursorParse(c)
+	if err != nil {
+		return plugin.Error("recursor", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		r.Next = next
+		return r
+	})
+
+	return nil
+}
+
+func recursorParse(c *caddy.Controller) (*Recursor, error) {
+	config := dnsserver.GetConfig(c)
+	zones := []string{}
+	capacity := defaultCap
+	port := defaultPort
+	timeout := defaultTimeout
+	qmin := true
+
+	hints, err := parseHints(strings.NewReader(rootHints), "root hints")
+	if err != nil {
+		return nil, err
+	}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		// recursor [zones...]
+		zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "root_hints":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				file := c.Val()
+				if !filepath.IsAbs(file) && config.Root != "" {
+					file = filepath.Join(config.Root, file)
+				}
+				f, err := os.Open(filepath.Clean(file))
+				if err != nil {
+					return nil, err
+				}
+				hints, err = parseHints(f, file)
+				f.Close()
+				if err != nil {
+					return nil, err
+				}
+			case "no_qname_minimization":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				qmin = false
+			case "timeout":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil || d <= 0 {
+					return nil, c.Errf("invalid timeout %q", c.Val())
+				}
+				timeout = d
+			case "infra_cache":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(c.Val())
+				if err != nil || n <= 0 {
+					return nil, c.Errf("invalid infra_cache %q", c.Val())
+				}
+				capacity = n
+			case "port":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(c.Val())
+				if err != nil || n <= 0 || n > 65535 {
+					return nil, c.Errf("invalid port %q", c.Val())
+				}
+				port = c.Val()
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+	}
+
+	r := New(zones, hints, port, capacity)
+	r.qmin = qmin
+	r.timeout = timeout
+	r.exchanger = &client{port: port, timeout: timeout}
+	return r, nil
+}
@@ -0,0 +1,88 @@
+package recursor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	dir := t.TempDir()
+	hints := filepath.Join(dir, "named.root")
+	if err := os.WriteFile(hints, []byte(". 3600000 NS a.root-servers.test.\na.root-servers.test. 3600000 A 192.0.2.53\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nohints := filepath.Join(dir, "empty")
+	if err := os.WriteFile(nohints, []byte(". 3600000 NS a.root-servers.test.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		roots     int
+		qmin      bool
+		timeout   time.Duration
+	}{
+		{`recursor`, false, 13, true, defaultTimeout},
+		{`recursor example.org {
+			root_hints ` + hints + `
+			no_qname_minimization
+			timeout 500ms
+			infra_cache 100
+			port 1053
+		}`, false, 1, false, 500 * time.Millisecond},
+		// errors
+		{`recursor {
+			root_hints
+		}`, true, 0, false, 0},
+		{`recursor {
+			root_hints /does/not/exist
+		}`, true, 0, false, 0},
+		{`recursor {
+			root_hints ` + nohints + `
+		}`, true, 0, false, 0},
+		{`recursor {
+			timeout -1s
+		}`, true, 0, false, 0},
+		{`recursor {
+			infra_cache 0
+		}`, true, 0, false, 0},
+		{`recursor {
+			port 65536
+		}`, true, 0, false, 0},
+		{`recursor {
+			no_qname_minimization yes
+		}`, true, 0, false, 0},
+		{`recursor {
+			blah
+		}`, true, 0, false, 0},
+		{`recursor
+		recursor`, true, 0, false, 0},
+	}
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		r, err := recursorParse(c)
+		if err == nil && tc.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		}
+		if err != nil && !tc.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if tc.shouldErr {
+			continue
+		}
+		if len(r.hints.addrs) != tc.roots {
+			t.Errorf("Test %d expected %d root servers, got %d", i, tc.roots, len(r.hints.addrs))
+		}
+		if r.qmin != tc.qmin {
+			t.Errorf("Test %d expected qname minimization %t, got %t", i, tc.qmin, r.qmin)
+		}
+		if r.timeout != tc.timeout {
+			t.Errorf("Test %d expected timeout %s, got %s", i, tc.timeout, r.timeout)
+		}
+	}
+}
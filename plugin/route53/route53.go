@@ -15,6 +15,7 @@ import (
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/pkg/zonerefresh"
 	"github.com/coredns/coredns/request"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -31,7 +32,7 @@ type Route53 struct {
 	zoneNames []string
 	client    route53Client
 	upstream  *upstream.Upstream
-	refresh   time.Duration
+	refresh   zonerefresh.Options
 
 	zMu   sync.RWMutex
 	zones zones
@@ -41,6 +42,10 @@ type zone struct {
 	id  string
 	z   *file.Zone
 	dns string
+
+	// version and fetched are only read and modified by runZone, after the first refresh.
+	version string    // the number of resource record sets of the hosted zone when its records were fetched
+	fetched time.Time // when the records were fetched
 }
 
 type zones map[string][]*zone
@@ -68,40 +73,42 @@ func New(ctx context.Context, c route53Client, keys map[string][]string, refresh
 			zones[dns] = append(zones[dns], &zone{id: hostedZoneID, dns: dns, z: file.NewZone(dns, "")})
 		}
 	}
+	opts := zonerefresh.New()
+	opts.Interval = refresh
 	return &Route53{
 		client:    c,
 		zoneNames: zoneNames,
 		zones:     zones,
 		upstream:  upstream.New(),
-		refresh:   refresh,
+		refresh:   opts,
 	}, nil
 }
 
-// Run executes first update, spins up an update forever-loop.
+// Run executes first update, spins up an update forever-loop for each hosted zone.
 // Returns error if first update fails.
 func (h *Route53) Run(ctx context.Context) error {
 	if err := h.updateZones(ctx); err != nil {
 		return err
 	}
-	go func() {
-		timer := time.NewTimer(h.refresh)
-		defer timer.Stop()
-		for {
-			timer.Reset(h.refresh)
-			select {
-			case <-ctx.Done():
-				log.Debugf("Breaking out of Route53 update loop for %v: %v", h.zoneNames, ctx.Err())
-				return
-			case <-timer.C:
-				if err := h.updateZones(ctx); err != nil && ctx.Err() == nil /* Don't log error if ctx expired. */ {
-					log.Errorf("Failed to update zones %v: %v", h.zoneNames, err)
-				}
-			}
+	for _, z := range h.zones {
+		for _, hostedZone := range z {
+			go h.runZone(ctx, hostedZone)
 		}
-	}()
+	}
 	return nil
 }
 
+// runZone refreshes hostedZone every refresh interval of its zone, until ctx is done. A failed refresh is
+// only logged, it is retried at the next interval.
+func (h *Route53) runZone(ctx context.Context, hostedZone *zone) {
+	h.refresh.Run(ctx, hostedZone.dns, func(ctx context.Context) {
+		if err := h.updateZone(ctx, hostedZone); err != nil && ctx.Err() == nil /* Don't log error if ctx expired. */ {
+			log.Errorf("Failed to update zone %v:%v, serving the records fetched before: %v", hostedZone.dns, hostedZone.id, err)
+		}
+	})
+	log.Debugf("Breaking out of Route53 update loop for %v:%v: %v", hostedZone.dns, hostedZone.id, ctx.Err())
+}
+
 // ServeDNS implements the plugin.Handler.ServeDNS.
 func (h *Route53) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 	state := request.Request{W: w, Req: r}
@@ -241,46 +248,19 @@ func updateZoneFromRRS(rrs *types.ResourceRecordSet, z *file.Zone) error {
 func (h *Route53) updateZones(ctx context.Context) error {
 	errc := make(chan error)
 	defer close(errc)
-	for zName, z := range h.zones {
-		go func(zName string, z []*zone) {
+	for _, z := range h.zones {
+		go func(z []*zone) {
 			var err error
 			defer func() {
 				errc <- err
 			}()
 
-			for i, hostedZone := range z {
-				newZ := file.NewZone(zName, "")
-				newZ.Upstream = h.upstream
-				in := &route53.ListResourceRecordSetsInput{
-					HostedZoneId: aws.String(hostedZone.id),
-					MaxItems:     aws.Int32(1000),
-				}
-				complete := false
-				var out *route53.ListResourceRecordSetsOutput
-				for out, err = h.client.ListResourceRecordSets(ctx, in); !complete; out, err = h.client.ListResourceRecordSets(ctx, in) {
-					if err != nil {
-						err = fmt.Errorf("failed to list resource records for %v:%v from route53: %v", zName, hostedZone.id, err)
-						return
-					}
-					for _, rrs := range out.ResourceRecordSets {
-						if err := updateZoneFromRRS(&rrs, newZ); err != nil {
-							// Maybe unsupported record type. Log and carry on.
-							log.Warningf("Failed to process resource record set: %v", err)
-						}
-					}
-					if out.IsTruncated {
-						in.StartRecordName = out.NextRecordName
-						in.StartRecordType = out.NextRecordType
-						in.StartRecordIdentifier = out.NextRecordIdentifier
-					} else {
-						complete = true
-					}
+			for _, hostedZone := range z {
+				if err = h.updateZone(ctx, hostedZone); err != nil {
+					return
 				}
-				h.zMu.Lock()
-				(*z[i]).z = newZ
-				h.zMu.Unlock()
 			}
-		}(zName, z)
+		}(z)
 	}
 	// Collect errors (if any). This will also sync on all zones updates
 	// completion.
@@ -297,5 +277,85 @@ func (h *Route53) updateZones(ctx context.Context) error {
 	return nil
 }
 
+// updateZone re-queries the resource record sets of hostedZone. When incremental refresh is enabled, they
+// are only re-queried if the number of resource record sets changed, or a full refresh is due. If this
+// fails, the records fetched before are kept.
+func (h *Route53) updateZone(ctx context.Context, hostedZone *zone) (err error) {
+	defer func() {
+		if err != nil {
+			zoneStale.WithLabelValues(hostedZone.dns, hostedZone.id).Set(1)
+			zoneRefreshCount.WithLabelValues(hostedZone.dns, hostedZone.id, "failed").Inc()
+			return
+		}
+		zoneStale.WithLabelValues(hostedZone.dns, hostedZone.id).Set(0)
+		zoneRefreshTime.WithLabelValues(hostedZone.dns, hostedZone.id).SetToCurrentTime()
+	}()
+
+	var version string
+	if h.refresh.Incremental {
+		version, err = h.zoneVersion(ctx, hostedZone)
+		if err != nil {
+			return fmt.Errorf("failed to get hosted zone %v:%v from route53: %v", hostedZone.dns, hostedZone.id, err)
+		}
+		if h.refresh.Unchanged(hostedZone.dns, hostedZone.version, version, hostedZone.fetched) {
+			zoneRefreshCount.WithLabelValues(hostedZone.dns, hostedZone.id, "unchanged").Inc()
+			return nil
+		}
+	}
+
+	newZ := file.NewZone(hostedZone.dns, "")
+	newZ.Upstream = h.upstream
+	in := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZone.id),
+		MaxItems:     aws.Int32(1000),
+	}
+	for {
+		out, err := h.client.ListResourceRecordSets(ctx, in)
+		if err != nil {
+			return fmt.Errorf("failed to list resource records for %v:%v from route53: %v", hostedZone.dns, hostedZone.id, err)
+		}
+		for _, rrs := range out.ResourceRecordSets {
+			if err := updateZoneFromRRS(&rrs, newZ); err != nil {
+				// Maybe unsupported record type. Log and carry on.
+				log.Warningf("Failed to process resource record set: %v", err)
+			}
+		}
+		if !out.IsTruncated {
+			break
+		}
+		in.StartRecordName = out.NextRecordName
+		in.StartRecordType = out.NextRecordType
+		in.StartRecordIdentifier = out.NextRecordIdentifier
+	}
+	h.zMu.Lock()
+	hostedZone.z = newZ
+	h.zMu.Unlock()
+	hostedZone.version, hostedZone.fetched = version, time.Now()
+	zoneRefreshCount.WithLabelValues(hostedZone.dns, hostedZone.id, "updated").Inc()
+	return nil
+}
+
+// zoneVersion returns the number of resource record sets of hostedZone, Route 53 has no version or change ID
+// of a hosted zone. Edits of record values don't change the number, those are picked up by a full refresh.
+func (h *Route53) zoneVersion(ctx context.Context, hostedZone *zone) (string, error) {
+	out, err := h.client.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName:      aws.String(hostedZone.dns),
+		HostedZoneId: aws.String(hostedZone.id),
+		MaxItems:     aws.Int32(1),
+	})
+	if err != nil {
+		return "", err
+	}
+	if out != nil {
+		for _, hz := range out.HostedZones {
+			// The ID is returned with a /hostedzone/ prefix.
+			if strings.TrimPrefix(aws.ToString(hz.Id), "/hostedzone/") == strings.TrimPrefix(hostedZone.id, "/hostedzone/") {
+				return strconv.FormatInt(aws.ToInt64(hz.ResourceRecordSetCount), 10), nil
+			}
+		}
+	}
+	return "", errors.New("hosted zone not found")
+}
+
 // Name implements plugin.Handler.Name.
 func (h *Route53) Name() string { return "route53" }
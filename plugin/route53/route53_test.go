@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 type fakeRoute53 struct {
@@ -23,7 +25,9 @@ type fakeRoute53 struct {
 }
 
 func (fakeRoute53) ListHostedZonesByName(_ context.Context, input *route53.ListHostedZonesByNameInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesByNameOutput, error) {
-	return nil, nil
+	return &route53.ListHostedZonesByNameOutput{HostedZones: []types.HostedZone{
+		{Id: aws.String("/hostedzone/" + aws.ToString(input.HostedZoneId)), Name: input.DNSName, ResourceRecordSetCount: aws.Int64(1)},
+	}}, nil
 }
 
 func (fakeRoute53) ListResourceRecordSets(_ context.Context, in *route53.ListResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error) {
@@ -293,3 +297,184 @@ func TestMaybeUnescape(t *testing.T) {
 		}
 	}
 }
+
+// fakeChangingRoute53 is a Route 53 of which the records can change, and which can fail.
+type fakeChangingRoute53 struct {
+	route53Client
+
+	mu      sync.Mutex
+	records map[string]string // name -> address
+	count   int64             // the number of resource record sets reported for the hosted zone
+	lists   int               // the number of times the records were listed
+	fail    bool
+}
+
+func (f *fakeChangingRoute53) ListHostedZonesByName(_ context.Context, in *route53.ListHostedZonesByNameInput, _ ...func(*route53.Options)) (*route53.ListHostedZonesByNameOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return nil, errors.New("throttled")
+	}
+	return &route53.ListHostedZonesByNameOutput{HostedZones: []types.HostedZone{
+		{Id: aws.String("/hostedzone/" + aws.ToString(in.HostedZoneId)), Name: in.DNSName, ResourceRecordSetCount: aws.Int64(f.count)},
+	}}, nil
+}
+
+func (f *fakeChangingRoute53) ListResourceRecordSets(_ context.Context, _ *route53.ListResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return nil, errors.New("throttled")
+	}
+	f.lists++
+	out := &route53.ListResourceRecordSetsOutput{ResourceRecordSets: []types.ResourceRecordSet{{
+		Type:            "SOA",
+		Name:            aws.String("example.org."),
+		ResourceRecords: []types.ResourceRecord{{Value: aws.String("ns-15.awsdns-00.co.uk. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400")}},
+		TTL:             aws.Int64(300),
+	}}}
+	for name, addr := range f.records {
+		out.ResourceRecordSets = append(out.ResourceRecordSets, types.ResourceRecordSet{
+			Type:            "A",
+			Name:            aws.String(name),
+			ResourceRecords: []types.ResourceRecord{{Value: aws.String(addr)}},
+			TTL:             aws.Int64(300),
+		})
+	}
+	return out, nil
+}
+
+func (f *fakeChangingRoute53) set(name, addr string, count int64, fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records[name], f.count, f.fail = addr, count, fail
+}
+
+func TestRoute53Incremental(t *testing.T) {
+	ctx := t.Context()
+	fake := &fakeChangingRoute53{records: map[string]string{"www.example.org.": "1.2.3.4"}, count: 1}
+	r, err := New(ctx, fake, map[string][]string{"example.org.": {"1234567890"}}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create route53: %v", err)
+	}
+	r.refresh.Incremental = true
+	if err := r.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+	hostedZone := r.zones["example.org."][0]
+
+	check := func(step, expected string, lists int) {
+		t.Helper()
+		req := new(dns.Msg)
+		req.SetQuestion("www.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(ctx, rec, req); err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != expected {
+			t.Errorf("%s: expected %s, got %v", step, expected, rec.Msg.Answer)
+		}
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if fake.lists != lists {
+			t.Errorf("%s: expected the records to be listed %d times, got %d", step, lists, fake.lists)
+		}
+	}
+	check("initial", "1.2.3.4", 1)
+
+	// The number of record sets didn't change, so the records are not listed again.
+	fake.set("www.example.org.", "1.2.3.5", 1, false)
+	if err := r.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("unchanged", "1.2.3.4", 1)
+
+	// After five refresh intervals the records are listed anyway, value edits don't change the count.
+	hostedZone.fetched = time.Now().Add(-5 * time.Minute)
+	if err := r.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("full refresh", "1.2.3.5", 2)
+
+	fake.set("api.example.org.", "1.2.3.6", 2, false)
+	if err := r.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("changed", "1.2.3.5", 3)
+
+	// When Route 53 fails, the records fetched before are served, and the hosted zone is reported stale.
+	fake.set("www.example.org.", "1.2.3.7", 3, true)
+	if err := r.updateZone(ctx, hostedZone); err == nil {
+		t.Fatal("Expected an error when Route 53 fails")
+	}
+	check("failed", "1.2.3.5", 3)
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org.", "1234567890")); stale != 1 {
+		t.Errorf("Expected the hosted zone to be reported stale, got %v", stale)
+	}
+
+	fake.set("www.example.org.", "1.2.3.7", 3, false)
+	if err := r.updateZone(ctx, hostedZone); err != nil {
+		t.Fatal(err)
+	}
+	check("recovered", "1.2.3.7", 4)
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org.", "1234567890")); stale != 0 {
+		t.Errorf("Expected the hosted zone not to be reported stale, got %v", stale)
+	}
+}
+
+func TestRoute53Refresh(t *testing.T) {
+	r, err := New(t.Context(), fakeRoute53{}, map[string][]string{"example.org": {"1234567890", "1357986420"}, "example.com.": {"Z098765432"}}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create route53: %v", err)
+	}
+	r.refresh.Zones["example.org."] = 10 * time.Second
+	if err := r.refresh.Validate(r.zoneNames); err != nil {
+		t.Fatalf("Expected the refresh interval of example.org. to be valid: %v", err)
+	}
+	for name, z := range r.zones {
+		expected := time.Minute
+		if name == "example.org" {
+			expected = 10 * time.Second
+		}
+		for _, hostedZone := range z {
+			if d := r.refresh.Refresh(hostedZone.dns); d != expected {
+				t.Errorf("Expected refresh %s for %s:%s, got %s", expected, name, hostedZone.id, d)
+			}
+		}
+	}
+
+	r.refresh.Zones["example.net."] = 10 * time.Second
+	if err := r.refresh.Validate(r.zoneNames); err == nil {
+		t.Error("Expected an error for a refresh interval of an unknown zone")
+	}
+}
+
+// fakeMissingRoute53 is a Route 53 that doesn't return the hosted zones when they are listed.
+type fakeMissingRoute53 struct {
+	fakeRoute53
+}
+
+func (fakeMissingRoute53) ListHostedZonesByName(_ context.Context, _ *route53.ListHostedZonesByNameInput, _ ...func(*route53.Options)) (*route53.ListHostedZonesByNameOutput, error) {
+	return &route53.ListHostedZonesByNameOutput{}, nil
+}
+
+func TestRoute53ZoneNotFound(t *testing.T) {
+	ctx := t.Context()
+	r, err := New(ctx, fakeMissingRoute53{}, map[string][]string{"example.org.": {"1234567890"}}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create route53: %v", err)
+	}
+	if err := r.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+
+	// The hosted zone isn't returned by ListHostedZonesByName, so it can't be known if it changed.
+	r.refresh.Incremental = true
+	hostedZone := r.zones["example.org."][0]
+	if err := r.updateZone(ctx, hostedZone); err == nil {
+		t.Fatal("Expected an error for a hosted zone that isn't found")
+	}
+	if stale := testutil.ToFloat64(zoneStale.WithLabelValues("example.org.", "1234567890")); stale != 1 {
+		t.Errorf("Expected the hosted zone to be reported stale, got %v", stale)
+	}
+}
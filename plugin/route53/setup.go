@@ -6,13 +6,13 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/zonerefresh"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -65,7 +65,7 @@ func setup(c *caddy.Controller) error {
 		clientOpts := []func(*route53.Options){}
 		var fall fall.F
 
-		refresh := time.Duration(1) * time.Minute // default update frequency to 1 minute
+		refresh := zonerefresh.New()
 
 		args := c.RemainingArgs()
 
@@ -122,25 +122,14 @@ func setup(c *caddy.Controller) error {
 				}
 			case "fallthrough":
 				fall.SetZonesFromArgs(c.RemainingArgs())
-			case "refresh":
-				if c.NextArg() {
-					refreshStr := c.Val()
-					_, err := strconv.Atoi(refreshStr)
-					if err == nil {
-						refreshStr = c.Val() + "s"
-					}
-					refresh, err = time.ParseDuration(refreshStr)
-					if err != nil {
-						return plugin.Error("route53", c.Errf("Unable to parse duration: %v", err))
-					}
-					if refresh <= 0 {
-						return plugin.Error("route53", c.Errf("refresh interval must be greater than 0: %q", refreshStr))
-					}
-				} else {
-					return plugin.Error("route53", c.ArgErr())
-				}
 			default:
-				return plugin.Error("route53", c.Errf("unknown property %q", c.Val()))
+				ok, err := refresh.Parse(c)
+				if err != nil {
+					return plugin.Error("route53", err)
+				}
+				if !ok {
+					return plugin.Error("route53", c.Errf("unknown property %q", c.Val()))
+				}
 			}
 		}
 
@@ -150,12 +139,17 @@ func setup(c *caddy.Controller) error {
 			cancel()
 			return plugin.Error("route53", c.Errf("failed to create route53 client: %v", err))
 		}
-		h, err := New(ctx, client, keys, refresh)
+		h, err := New(ctx, client, keys, refresh.Interval)
 		if err != nil {
 			cancel()
 			return plugin.Error("route53", c.Errf("failed to create route53 plugin: %v", err))
 		}
 		h.Fall = fall
+		h.refresh = refresh
+		if err := refresh.Validate(h.zoneNames); err != nil {
+			cancel()
+			return plugin.Error("route53", c.Err(err.Error()))
+		}
 		if err := h.Run(ctx); err != nil {
 			cancel()
 			return plugin.Error("route53", c.Errf("failed to initialize route53 plugin: %v", err))
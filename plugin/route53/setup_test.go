@@ -68,6 +68,25 @@ func TestSetupRoute53(t *testing.T) {
 		{`route53 example.org:12345678 {
 	refresh -1m
 }`, true},
+		{`route53 example.org:12345678 example.com:87654321 {
+	refresh 5m
+	refresh 30s example.com.
+}`, false},
+		{`route53 example.org:12345678 {
+	refresh 30s example.com
+}`, true},
+		{`route53 example.org:12345678 {
+	incremental
+}`, false},
+		{`route53 example.org:12345678 {
+	incremental 6h
+}`, false},
+		{`route53 example.org:12345678 {
+	incremental 0
+}`, true},
+		{`route53 example.org:12345678 {
+	incremental 1h 2h
+}`, true},
 
 		{`route53 example.org {
 	}`, true},
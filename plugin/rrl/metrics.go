@@ -0,0 +1,25 @@
+package rrl
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// DropCount is the number of responses being dropped.
+	DropCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "dropped_responses_total",
+		Help:      "Counter of responses dropped because they exceeded the rate limit.",
+	}, []string{"server", "class", "view"})
+	// SlipCount is the number of responses being replaced by a truncated response.
+	SlipCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "slipped_responses_total",
+		Help:      "Counter of responses replaced by a truncated response because they exceeded the rate limit.",
+	}, []string{"server", "class", "view"})
+)
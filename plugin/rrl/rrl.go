@@ -0,0 +1,220 @@
+// Package rrl implements response rate limiting.
+package rrl
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/cookie"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// RRL is the response rate limiting plugin.
+type RRL struct {
+	Next  plugin.Handler
+	Zones []string
+
+	window   float64 // seconds
+	ipv4Mask net.IPMask
+	ipv6Mask net.IPMask
+	rates    [classes]float64 // responses per second for each class, zero disables limiting.
+	slip     uint64
+	exempt   []*net.IPNet
+	logOnly  bool
+
+	exemptCookie bool // don't limit clients that sent a valid server cookie
+
+	table *table
+}
+
+// class is the class of a response, each class is accounted separately.
+type class uint8
+
+const (
+	classAnswer class = iota
+	classNoData
+	classNXDomain
+	classReferral
+	classError
+	classes
+)
+
+var classToString = [classes]string{
+	classAnswer:   "answer",
+	classNoData:   "nodata",
+	classNXDomain: "nxdomain",
+	classReferral: "referral",
+	classError:    "error",
+}
+
+func (c class) String() string { return classToString[c] }
+
+// ServeDNS implements the plugin.Handler interface.
+func (rl *RRL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	// TCP can't be used for reflection, so only UDP is limited.
+	if state.Proto() != "udp" {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+	// A valid server cookie proves the client owns its address, just like TCP does.
+	if rl.exemptCookie && cookie.Valid(ctx) {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+	zone := plugin.Zones(rl.Zones).Matches(state.Name())
+	if zone == "" {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	prefix := rl.prefix(state.IP())
+	if prefix == nil {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	rw := &ResponseWriter{ResponseWriter: w, rl: rl, ctx: ctx, state: state, zone: zone, prefix: prefix}
+	return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, rw, r)
+}
+
+// prefix returns the masked client address, or nil if the client is exempt.
+func (rl *RRL) prefix(addr string) net.IP {
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil
+	}
+	for _, n := range rl.exempt {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(rl.ipv4Mask)
+	}
+	return ip.Mask(rl.ipv6Mask)
+}
+
+// Name implements the plugin.Handler interface.
+func (rl *RRL) Name() string { return "rrl" }
+
+// ResponseWriter accounts each response and drops, truncates or writes it.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	rl     *RRL
+	ctx    context.Context
+	state  request.Request
+	zone   string
+	prefix net.IP
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	c, name, ok := classify(res, w.zone)
+	if !ok || w.rl.rates[c] == 0 {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	limited, n := w.rl.table.debit(key(w.prefix, c, res, name), w.rl.rates[c], w.rl.window)
+	if !limited {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	server, view := metrics.WithServer(w.ctx), metrics.WithView(w.ctx)
+	slip := w.rl.slip > 0 && n%w.rl.slip == 0
+	if slip {
+		SlipCount.WithLabelValues(server, c.String(), view).Inc()
+	} else {
+		DropCount.WithLabelValues(server, c.String(), view).Inc()
+	}
+
+	if w.rl.logOnly {
+		if slip {
+			log.Infof("Would slip %s response to %s for %s/%s", c, w.state.IP(), w.state.Name(), w.state.Type())
+		} else {
+			log.Infof("Would drop %s response to %s for %s/%s", c, w.state.IP(), w.state.Name(), w.state.Type())
+		}
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	if !slip {
+		return nil
+	}
+
+	// Send an empty truncated response, this makes legitimate clients retry over TCP.
+	tc := new(dns.Msg)
+	tc.SetReply(w.state.Req)
+	tc.Truncated = true
+	return w.ResponseWriter.WriteMsg(tc)
+}
+
+// classify returns the class of res and the name to account it under. Transfers, notifies and
+// updates are not classified and never limited.
+func classify(res *dns.Msg, zone string) (class, string, bool) {
+	t, _ := response.Typify(res, time.Now())
+	switch t {
+	case response.Meta, response.Update:
+		return 0, "", false
+	}
+
+	switch res.Rcode {
+	case dns.RcodeSuccess:
+	case dns.RcodeNameError:
+		// NXDOMAINs are accounted per zone, otherwise random sub domains would evade the limit.
+		return classNXDomain, owner(res.Ns, dns.TypeSOA, zone), true
+	default:
+		return classError, "", true
+	}
+
+	switch t {
+	case response.NoData:
+		return classNoData, qname(res), true
+	case response.Delegation:
+		return classReferral, owner(res.Ns, dns.TypeNS, zone), true
+	case response.NoError:
+		if len(res.Answer) == 0 {
+			return classNoData, qname(res), true
+		}
+		return classAnswer, qname(res), true
+	}
+	return classError, "", true
+}
+
+// owner returns the owner name of the first RR of type typ in rrs, or zone if there is none.
+func owner(rrs []dns.RR, typ uint16, zone string) string {
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == typ {
+			return strings.ToLower(rr.Header().Name)
+		}
+	}
+	return zone
+}
+
+func qname(res *dns.Msg) string {
+	if len(res.Question) == 0 {
+		return "."
+	}
+	return strings.ToLower(res.Question[0].Name)
+}
+
+// key returns the key of the account for a response. Answers are accounted per name and type, all
+// other classes only per name, errors don't have a name.
+func key(prefix net.IP, c class, res *dns.Msg, name string) uint64 {
+	buf := make([]byte, 0, len(prefix)+3+len(name))
+	buf = append(buf, prefix...)
+	buf = append(buf, byte(c))
+	if c == classAnswer && len(res.Question) > 0 {
+		buf = binary.BigEndian.AppendUint16(buf, res.Question[0].Qtype)
+	}
+	buf = append(buf, name...)
+	return cache.Hash(buf)
+}
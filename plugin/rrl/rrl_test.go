@@ -0,0 +1,230 @@
+package rrl
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cookie"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// answer is a handler that replies with a fixed answer, NXDOMAIN for nx.example.org. and a
+// referral for names below sub.example.org.
+func answer() plugin.Handler {
+	return test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch {
+		case dns.IsSubDomain("sub.example.org.", r.Question[0].Name):
+			m.Ns = []dns.RR{test.NS("sub.example.org. 3600 IN NS ns.sub.example.org.")}
+		case dns.IsSubDomain("nx.example.org.", r.Question[0].Name):
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300")}
+		default:
+			m.Answer = []dns.RR{test.A(r.Question[0].Name + " 3600 IN A 127.0.0.1")}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func newTestRRL(rate float64, slip uint64) *RRL {
+	rl := &RRL{
+		Next:     answer(),
+		Zones:    []string{"example.org."},
+		window:   defaultWindow,
+		ipv4Mask: net.CIDRMask(defaultIPv4Prefix, 32),
+		ipv6Mask: net.CIDRMask(defaultIPv6Prefix, 128),
+		slip:     slip,
+		table:    newTable(defaultMaxTableSize),
+	}
+	for c := range rl.rates {
+		rl.rates[c] = rate
+	}
+	now := time.Now()
+	rl.table.now = func() time.Time { return now }
+	return rl
+}
+
+// query sends a query for qname from ip and returns the response written, nil if it was dropped.
+func query(rl *RRL, qname, ip string, tcp bool) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(qname, dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: ip, TCP: tcp})
+	rl.ServeDNS(context.TODO(), rec, m)
+	return rec.Msg
+}
+
+func TestRRLLimit(t *testing.T) {
+	rl := newTestRRL(2, 0)
+
+	for i := range 2 {
+		if query(rl, "www.example.org.", "10.0.0.1", false) == nil {
+			t.Fatalf("Expected response %d to be written", i)
+		}
+	}
+	if query(rl, "www.example.org.", "10.0.0.1", false) != nil {
+		t.Fatalf("Expected response to be dropped")
+	}
+	// Same /24, same account.
+	if query(rl, "www.example.org.", "10.0.0.2", false) != nil {
+		t.Fatalf("Expected response to be dropped for the same prefix")
+	}
+	// Different name, different account.
+	if query(rl, "mail.example.org.", "10.0.0.1", false) == nil {
+		t.Fatalf("Expected response for other name to be written")
+	}
+	// Different prefix, different account.
+	if query(rl, "www.example.org.", "10.0.1.1", false) == nil {
+		t.Fatalf("Expected response for other prefix to be written")
+	}
+	// TCP is never limited.
+	if query(rl, "www.example.org.", "10.0.0.1", true) == nil {
+		t.Fatalf("Expected TCP response to be written")
+	}
+	// Outside of the zones.
+	if query(rl, "www.example.net.", "10.0.0.1", false) == nil {
+		t.Fatalf("Expected response outside of zones to be written")
+	}
+}
+
+func TestRRLRecover(t *testing.T) {
+	rl := newTestRRL(1, 0)
+	now := time.Now()
+	rl.table.now = func() time.Time { return now }
+
+	query(rl, "www.example.org.", "10.0.0.1", false)
+	for range 10 {
+		query(rl, "www.example.org.", "10.0.0.1", false)
+	}
+
+	// One second later we are still in debt.
+	now = now.Add(time.Second)
+	if query(rl, "www.example.org.", "10.0.0.1", false) != nil {
+		t.Fatalf("Expected response to be dropped")
+	}
+	// After the window we are credited again.
+	now = now.Add(defaultWindow * time.Second)
+	if query(rl, "www.example.org.", "10.0.0.1", false) == nil {
+		t.Fatalf("Expected response to be written after the window")
+	}
+}
+
+func TestRRLSlip(t *testing.T) {
+	rl := newTestRRL(1, 2)
+
+	query(rl, "www.example.org.", "10.0.0.1", false)
+
+	dropped, slipped := 0, 0
+	for range 10 {
+		m := query(rl, "www.example.org.", "10.0.0.1", false)
+		if m == nil {
+			dropped++
+			continue
+		}
+		if !m.Truncated || len(m.Answer) != 0 {
+			t.Fatalf("Expected empty truncated response, got %s", m)
+		}
+		slipped++
+	}
+	if dropped != 5 || slipped != 5 {
+		t.Errorf("Expected 5 dropped and 5 slipped responses, got %d and %d", dropped, slipped)
+	}
+}
+
+func TestRRLNXDomainPerZone(t *testing.T) {
+	rl := newTestRRL(2, 0)
+
+	// Random names in the same zone share an account.
+	query(rl, "a.nx.example.org.", "10.0.0.1", false)
+	query(rl, "b.nx.example.org.", "10.0.0.1", false)
+	if query(rl, "c.nx.example.org.", "10.0.0.1", false) != nil {
+		t.Fatalf("Expected NXDOMAIN response to be dropped")
+	}
+	// But answers are accounted separately.
+	if query(rl, "www.example.org.", "10.0.0.1", false) == nil {
+		t.Fatalf("Expected answer to be written")
+	}
+}
+
+func TestRRLLogOnly(t *testing.T) {
+	rl := newTestRRL(1, 0)
+	rl.logOnly = true
+
+	for range 5 {
+		if query(rl, "www.example.org.", "10.0.0.1", false) == nil {
+			t.Fatalf("Expected response to be written in log only mode")
+		}
+	}
+}
+
+func TestRRLExempt(t *testing.T) {
+	rl := newTestRRL(1, 0)
+	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+	rl.exempt = []*net.IPNet{n}
+
+	for range 5 {
+		if query(rl, "www.example.org.", "10.0.0.1", false) == nil {
+			t.Fatalf("Expected response to exempt client to be written")
+		}
+	}
+	query(rl, "www.example.org.", "192.0.2.1", false)
+	if query(rl, "www.example.org.", "192.0.2.1", false) != nil {
+		t.Fatalf("Expected response to be dropped")
+	}
+}
+
+func TestRRLExemptCookie(t *testing.T) {
+	rl := newTestRRL(1, 0)
+	rl.exemptCookie = true
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	ctx := cookie.WithValid(context.TODO())
+	for range 5 {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: "10.0.0.1"})
+		rl.ServeDNS(ctx, rec, m)
+		if rec.Msg == nil {
+			t.Fatalf("Expected response to client with a valid cookie to be written")
+		}
+	}
+	query(rl, "www.example.org.", "10.0.0.1", false)
+	if query(rl, "www.example.org.", "10.0.0.1", false) != nil {
+		t.Fatalf("Expected response without cookie to be dropped")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		msg   *dns.Msg
+		class class
+		name  string
+	}{
+		{&dns.Msg{Question: []dns.Question{{Name: "WWW.example.org.", Qtype: dns.TypeA}}, Answer: []dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.1")}}, classAnswer, "www.example.org."},
+		{&dns.Msg{Question: []dns.Question{{Name: "www.example.org.", Qtype: dns.TypeMX}}, Ns: []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300")}}, classNoData, "www.example.org."},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError}, Question: []dns.Question{{Name: "x.example.org.", Qtype: dns.TypeA}}}, classNXDomain, "example.org."},
+		{&dns.Msg{Question: []dns.Question{{Name: "a.sub.example.org.", Qtype: dns.TypeA}}, Ns: []dns.RR{test.NS("sub.example.org. 3600 IN NS ns.sub.example.org.")}}, classReferral, "sub.example.org."},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeServerFailure}, Question: []dns.Question{{Name: "x.example.org.", Qtype: dns.TypeA}}}, classError, ""},
+	}
+	for i, tc := range tests {
+		c, name, ok := classify(tc.msg, "example.org.")
+		if !ok {
+			t.Fatalf("Test %d: expected message to be classified", i)
+		}
+		if c != tc.class || name != tc.name {
+			t.Errorf("Test %d: expected %s/%s, got %s/%s", i, tc.class, tc.name, c, name)
+		}
+	}
+
+	axfr := new(dns.Msg)
+	axfr.SetAxfr("example.org.")
+	if _, _, ok := classify(axfr, "example.org."); ok {
+		t.Errorf("Expected transfer not to be classified")
+	}
+}
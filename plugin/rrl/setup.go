@@ -0,0 +1,185 @@
+package rrl
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+var log = clog.NewWithPlugin("rrl")
+
+func init() { plugin.Register("rrl", setup) }
+
+const (
+	defaultWindow       = 15
+	defaultIPv4Prefix   = 24
+	defaultIPv6Prefix   = 56
+	defaultSlip         = 2
+	defaultMaxTableSize = 100000
+)
+
+func setup(c *caddy.Controller) error {
+	rl, err := rrlParse(c)
+	if err != nil {
+		return plugin.Error("rrl", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rl.Next = next
+		return rl
+	})
+
+	return nil
+}
+
+func rrlParse(c *caddy.Controller) (*RRL, error) {
+	rl := &RRL{
+		window:   defaultWindow,
+		ipv4Mask: net.CIDRMask(defaultIPv4Prefix, 32),
+		ipv6Mask: net.CIDRMask(defaultIPv6Prefix, 128),
+		slip:     defaultSlip,
+	}
+	size := defaultMaxTableSize
+
+	// Rates of classes that aren't configured default to the rate for answers.
+	var set [classes]bool
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		rl.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch v := c.Val(); v {
+			case "window":
+				n, err := positiveInt(c)
+				if err != nil {
+					return nil, err
+				}
+				if n == 0 {
+					return nil, c.Errf("window must be larger than zero")
+				}
+				rl.window = float64(n)
+			case "ipv4_prefix_length":
+				n, err := positiveInt(c)
+				if err != nil {
+					return nil, err
+				}
+				if n == 0 || n > 32 {
+					return nil, c.Errf("invalid IPv4 prefix length %d", n)
+				}
+				rl.ipv4Mask = net.CIDRMask(n, 32)
+			case "ipv6_prefix_length":
+				n, err := positiveInt(c)
+				if err != nil {
+					return nil, err
+				}
+				if n == 0 || n > 128 {
+					return nil, c.Errf("invalid IPv6 prefix length %d", n)
+				}
+				rl.ipv6Mask = net.CIDRMask(n, 128)
+			case "responses_per_second", "nodata_per_second", "nxdomains_per_second", "referrals_per_second", "errors_per_second":
+				n, err := positiveInt(c)
+				if err != nil {
+					return nil, err
+				}
+				cl := perSecond[v]
+				rl.rates[cl] = float64(n)
+				set[cl] = true
+			case "slip_ratio":
+				n, err := positiveInt(c)
+				if err != nil {
+					return nil, err
+				}
+				if n > 10 {
+					return nil, c.Errf("slip_ratio must be between 0 and 10")
+				}
+				rl.slip = uint64(n)
+			case "exempt":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					_, n, err := net.ParseCIDR(normalize(a))
+					if err != nil {
+						return nil, c.Errf("illegal CIDR notation %q", a)
+					}
+					rl.exempt = append(rl.exempt, n)
+				}
+			case "log_only":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rl.logOnly = true
+			case "exempt_cookie":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rl.exemptCookie = true
+			case "max_table_size":
+				n, err := positiveInt(c)
+				if err != nil {
+					return nil, err
+				}
+				if n == 0 {
+					return nil, c.Errf("max_table_size must be larger than zero")
+				}
+				size = n
+			default:
+				return nil, c.Errf("unknown property '%s'", v)
+			}
+		}
+	}
+
+	for cl := classNoData; cl < classes; cl++ {
+		if !set[cl] {
+			rl.rates[cl] = rl.rates[classAnswer]
+		}
+	}
+	rl.table = newTable(size)
+
+	return rl, nil
+}
+
+var perSecond = map[string]class{
+	"responses_per_second": classAnswer,
+	"nodata_per_second":    classNoData,
+	"nxdomains_per_second": classNXDomain,
+	"referrals_per_second": classReferral,
+	"errors_per_second":    classError,
+}
+
+// positiveInt parses the single argument of the current property as a non-negative integer.
+func positiveInt(c *caddy.Controller) (int, error) {
+	prop := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return 0, c.Errf("%s needs a positive number, got %q", prop, args[0])
+	}
+	return n, nil
+}
+
+// normalize appends '/32' for any single IPv4 address and '/128' for IPv6.
+func normalize(rawNet string) string {
+	if strings.Contains(rawNet, "/") {
+		return rawNet
+	}
+	if strings.Contains(rawNet, ":") {
+		return rawNet + "/128"
+	}
+	return rawNet + "/32"
+}
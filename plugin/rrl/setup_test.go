@@ -0,0 +1,105 @@
+package rrl
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rates     [classes]float64
+		slip      uint64
+		window    float64
+		ipv4      int
+		ipv6      int
+		exempt    int
+		logOnly   bool
+	}{
+		{`rrl`, false, [classes]float64{}, defaultSlip, defaultWindow, defaultIPv4Prefix, defaultIPv6Prefix, 0, false},
+		{`rrl {
+			responses_per_second 10
+		}`, false, [classes]float64{10, 10, 10, 10, 10}, defaultSlip, defaultWindow, defaultIPv4Prefix, defaultIPv6Prefix, 0, false},
+		{`rrl example.org {
+			responses_per_second 10
+			nxdomains_per_second 5
+			errors_per_second 0
+			window 5
+			ipv4_prefix_length 32
+			ipv6_prefix_length 64
+			slip_ratio 0
+			exempt 10.0.0.0/8 2001:db8::1
+			log_only
+			exempt_cookie
+			max_table_size 1000
+		}`, false, [classes]float64{10, 10, 5, 10, 0}, 0, 5, 32, 64, 2, true},
+		// errors
+		{`rrl {
+			responses_per_second
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			responses_per_second -1
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			window 0
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			ipv4_prefix_length 33
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			slip_ratio 11
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			exempt 10.0.0.0/33
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			log_only yes
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			exempt_cookie yes
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl {
+			unknown
+		}`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+		{`rrl
+		rrl`, true, [classes]float64{}, 0, 0, 0, 0, 0, false},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		rl, err := rrlParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s: %s", i, tc.input, err)
+			continue
+		}
+		if rl.rates != tc.rates {
+			t.Errorf("Test %d: expected rates %v, got %v", i, tc.rates, rl.rates)
+		}
+		if rl.slip != tc.slip {
+			t.Errorf("Test %d: expected slip %d, got %d", i, tc.slip, rl.slip)
+		}
+		if rl.window != tc.window {
+			t.Errorf("Test %d: expected window %f, got %f", i, tc.window, rl.window)
+		}
+		if ones, _ := rl.ipv4Mask.Size(); ones != tc.ipv4 {
+			t.Errorf("Test %d: expected IPv4 prefix length %d, got %d", i, tc.ipv4, ones)
+		}
+		if ones, _ := rl.ipv6Mask.Size(); ones != tc.ipv6 {
+			t.Errorf("Test %d: expected IPv6 prefix length %d, got %d", i, tc.ipv6, ones)
+		}
+		if len(rl.exempt) != tc.exempt {
+			t.Errorf("Test %d: expected %d exempt networks, got %d", i, tc.exempt, len(rl.exempt))
+		}
+		if rl.logOnly != tc.logOnly {
+			t.Errorf("Test %d: expected log only %t, got %t", i, tc.logOnly, rl.logOnly)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package rrl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+)
+
+// table holds the token buckets of all accounts. It is bounded in size, when full a random account
+// is evicted to make room for a new one. This keeps memory flat under floods from spoofed sources.
+type table struct {
+	c   *cache.Cache
+	now func() time.Time
+}
+
+func newTable(size int) *table { return &table{c: cache.New(size), now: time.Now} }
+
+// bucket is a token bucket for a single account. The balance is credited with rate tokens each
+// second, up to rate, and each response debits one token. A negative balance means the account is
+// over its rate. The debt is capped at window seconds worth of tokens, so a client that keeps below
+// the rate for window seconds is no longer limited.
+type bucket struct {
+	sync.Mutex
+	balance float64
+	last    time.Time
+	limited uint64 // number of limited responses, used to decide when to slip.
+}
+
+// debit debits the account under key. It returns true if the response is over the rate limit,
+// together with the number of limited responses seen for this account.
+func (t *table) debit(key uint64, rate, window float64) (bool, uint64) {
+	el, ok := t.c.Get(key)
+	if !ok {
+		// Concurrent queries for a new account must share a single bucket.
+		el, _ = t.c.GetOrAdd(key, &bucket{balance: rate})
+	}
+	b := el.(*bucket)
+
+	now := t.now()
+
+	b.Lock()
+	defer b.Unlock()
+	if !b.last.IsZero() {
+		b.balance += now.Sub(b.last).Seconds() * rate
+	}
+	b.last = now
+	if b.balance > rate {
+		b.balance = rate
+	}
+	b.balance--
+	if debt := -window * rate; b.balance < debt {
+		b.balance = debt
+	}
+
+	if b.balance >= 0 {
+		return false, 0
+	}
+	b.limited++
+	return true, b.limited
+}
+
+// Len returns the number of accounts in the table.
+func (t *table) Len() int { return t.c.Len() }
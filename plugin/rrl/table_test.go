@@ -0,0 +1,82 @@
+package rrl
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTableDebit(t *testing.T) {
+	tb := newTable(10)
+	now := time.Now()
+	tb.now = func() time.Time { return now }
+
+	// A new account starts with a full balance of rate tokens.
+	for i := range 5 {
+		if limited, _ := tb.debit(1, 5, 2); limited {
+			t.Fatalf("Expected response %d not to be limited", i)
+		}
+	}
+	for i := range 3 {
+		limited, n := tb.debit(1, 5, 2)
+		if !limited {
+			t.Fatalf("Expected response to be limited")
+		}
+		if n != uint64(i+1) {
+			t.Errorf("Expected limited count %d, got %d", i+1, n)
+		}
+	}
+
+	// Other accounts are not affected.
+	if limited, _ := tb.debit(2, 5, 2); limited {
+		t.Fatalf("Expected other account not to be limited")
+	}
+
+	// The debt is capped at window * rate, so after window seconds we have a full balance again.
+	for range 100 {
+		tb.debit(1, 5, 2)
+	}
+	now = now.Add(2*time.Second + 200*time.Millisecond)
+	if limited, _ := tb.debit(1, 5, 2); limited {
+		t.Fatalf("Expected response not to be limited after the window")
+	}
+}
+
+func TestTableSize(t *testing.T) {
+	tb := newTable(1)
+	for i := range uint64(10000) {
+		tb.debit(i, 1, 1)
+	}
+	// The cache has 256 shards of at least 4 elements.
+	if tb.Len() > 256*4 {
+		t.Errorf("Expected table to be bounded, got %d accounts", tb.Len())
+	}
+}
+
+func TestTableDebitConcurrent(t *testing.T) {
+	tb := newTable(10)
+	now := time.Now()
+	tb.now = func() time.Time { return now }
+
+	// A burst for a new account from many goroutines must not be allowed more than rate responses.
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limited, _ := tb.debit(1, 5, 2); !limited {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 5 {
+		t.Errorf("Expected 5 responses to be allowed, got %d", allowed)
+	}
+}
@@ -0,0 +1,127 @@
+package secondary
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/catalog"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/miekg/dns"
+)
+
+// catalogZone provisions the member zones of a catalog zone (RFC 9432). Member zones are transferred from
+// the primaries of their group, or from the primaries of the catalog zone when their group isn't
+// configured.
+type catalogZone struct {
+	name     string
+	zone     *file.Zone
+	groups   map[string][]string // Primaries of member zones per group.
+	ixfr     int
+	upstream *upstream.Upstream
+	static   []string // Zones from the configuration, these are never provisioned from the catalog.
+
+	mu      sync.Mutex
+	members map[string]*member
+	zones   atomic.Pointer[file.Zones] // Member zones being served, replaced on every change.
+}
+
+// member is a member zone of a catalog zone, kept up to date until shutdown is closed.
+type member struct {
+	id       string
+	from     []string
+	zone     *file.Zone
+	shutdown chan bool
+}
+
+func newCatalogZone(name string, z *file.Zone) *catalogZone {
+	c := &catalogZone{
+		name:    name,
+		zone:    z,
+		groups:  map[string][]string{},
+		members: map[string]*member{},
+	}
+	c.zones.Store(&file.Zones{Z: map[string]*file.Zone{}})
+	return c
+}
+
+// primaries returns the primaries for a member zone in groups.
+func (c *catalogZone) primaries(groups []string) []string {
+	for _, g := range groups {
+		if from, ok := c.groups[g]; ok {
+			return from
+		}
+	}
+	return c.zone.TransferFrom
+}
+
+// update adds and removes member zones, so they match the member zones listed in the catalog zone. A
+// member zone is reset when its ID changes, or when its group now has other primaries.
+func (c *catalogZone) update() {
+	members, err := catalog.Members(c.name, records(c.zone))
+	if err != nil {
+		log.Warningf("Invalid catalog zone %q: %s", c.name, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, m := range c.members {
+		if n, ok := members[name]; ok && n.ID == m.id && slices.Equal(c.primaries(n.Groups), m.from) {
+			continue
+		}
+		close(m.shutdown)
+		delete(c.members, name)
+		log.Infof("Removing member zone %q of catalog zone %q", name, c.name)
+	}
+
+	for name, n := range members {
+		if _, ok := c.members[name]; ok {
+			continue
+		}
+		if slices.Contains(c.static, name) {
+			log.Warningf("Member zone %q of catalog zone %q is already configured, ignoring", name, c.name)
+			continue
+		}
+		m := &member{id: n.ID, from: c.primaries(n.Groups), zone: file.NewZone(name, "stdin"), shutdown: make(chan bool)}
+		m.zone.TransferFrom = m.from
+		m.zone.IXFRHistory = c.ixfr
+		m.zone.Upstream = c.upstream
+		c.members[name] = m
+		go transferIn(name, m.zone, m.shutdown)
+
+		log.Infof("Adding member zone %q of catalog zone %q", name, c.name)
+	}
+
+	zones := &file.Zones{Z: make(map[string]*file.Zone, len(c.members))}
+	for name, m := range c.members {
+		zones.Z[name] = m.zone
+		zones.Names = append(zones.Names, name)
+	}
+	c.zones.Store(zones)
+}
+
+// stop stops keeping the member zones up to date.
+func (c *catalogZone) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, m := range c.members {
+		close(m.shutdown)
+		delete(c.members, name)
+	}
+	c.zones.Store(&file.Zones{Z: map[string]*file.Zone{}})
+}
+
+// records returns all records in z, except the apex records.
+func records(z *file.Zone) []dns.RR {
+	z.RLock()
+	defer z.RUnlock()
+	rrs := []dns.RR{}
+	for _, e := range z.All() {
+		rrs = append(rrs, e.All()...)
+	}
+	return rrs
+}
@@ -0,0 +1,151 @@
+package secondary
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const dbCatalog = `catalog.invalid. 0 IN SOA invalid. invalid. 1 60 60 3600 0
+catalog.invalid. 0 IN NS invalid.
+version.catalog.invalid. 0 IN TXT "2"
+a.zones.catalog.invalid. 0 IN PTR example.org.
+`
+
+const dbMember = `example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300
+example.org. 3600 IN NS ns.example.org.
+www.example.org. 3600 IN A 192.0.2.1
+`
+
+// primary returns a server transferring the zones in zones.
+func primary(t *testing.T, zones map[string]*file.Zone) *dnstest.Server {
+	t.Helper()
+	return dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		z, ok := zones[r.Question[0].Name]
+		if !ok {
+			m.Rcode = dns.RcodeRefused
+			w.WriteMsg(m)
+			return
+		}
+		switch r.Question[0].Qtype {
+		case dns.TypeSOA:
+			z.RLock()
+			m.Answer = []dns.RR{z.SOA}
+			z.RUnlock()
+		case dns.TypeAXFR, dns.TypeIXFR:
+			ch, err := z.Transfer(0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for rrs := range ch {
+				m.Answer = append(m.Answer, rrs...)
+			}
+		}
+		w.WriteMsg(m)
+	})
+}
+
+func parseZone(t *testing.T, db, origin string) *file.Zone {
+	t.Helper()
+	z, err := file.Parse(strings.NewReader(db), origin, "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+func TestCatalog(t *testing.T) {
+	zones := map[string]*file.Zone{
+		"catalog.invalid.": parseZone(t, dbCatalog, "catalog.invalid."),
+		"example.org.":     parseZone(t, dbMember, "example.org."),
+	}
+	s := primary(t, zones)
+	defer s.Close()
+
+	z := file.NewZone("catalog.invalid.", "stdin")
+	z.TransferFrom = []string{s.Addr}
+	cat := newCatalogZone("catalog.invalid.", z)
+	z.OnTransfer = cat.update
+	defer cat.stop()
+
+	sec := Secondary{File: file.File{Zones: file.Zones{Z: map[string]*file.Zone{"catalog.invalid.": z}, Names: []string{"catalog.invalid."}}}, catalogs: []*catalogZone{cat}}
+
+	if err := z.TransferIn(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	var rec *dnstest.Recorder
+	for range 50 {
+		rec = dnstest.NewRecorder(&test.ResponseWriter{})
+		sec.ServeDNS(context.TODO(), rec, m)
+		if rec.Msg != nil && len(rec.Msg.Answer) == 1 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+		t.Fatalf("Expected answer from member zone example.org., got %v", rec.Msg)
+	}
+
+	// Transfers of the member zone are served.
+	ch, err := sec.Transfer("example.org.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+
+	// Removing the member zone from the catalog stops serving it.
+	zones["catalog.invalid."] = parseZone(t, strings.Replace(strings.Replace(dbCatalog, " 1 60", " 2 60", 1), "a.zones", "b.zones", 1), "catalog.invalid.")
+	if err := z.TransferIn(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cat.members) != 1 || cat.members["example.org."].id != "b" {
+		t.Errorf("Expected member example.org. to be reset with ID b")
+	}
+
+	zones["catalog.invalid."] = parseZone(t, strings.Replace(strings.Replace(dbCatalog, " 1 60", " 3 60", 1), "a.zones.catalog.invalid. 0 IN PTR example.org.", "", 1), "catalog.invalid.")
+	if err := z.TransferIn(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cat.members) != 0 {
+		t.Errorf("Expected no member zones, got %d", len(cat.members))
+	}
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	if code, _ := sec.ServeDNS(context.TODO(), rec, m); code != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED for removed member zone, got %s", dns.RcodeToString[code])
+	}
+}
+
+func TestCatalogGroups(t *testing.T) {
+	z := file.NewZone("catalog.invalid.", "stdin")
+	z.TransferFrom = []string{"192.0.2.1:53"}
+	cat := newCatalogZone("catalog.invalid.", z)
+	cat.groups = map[string][]string{"internal": {"192.0.2.2:53"}}
+
+	tests := []struct {
+		groups []string
+		from   string
+	}{
+		{nil, "192.0.2.1:53"},
+		{[]string{"external"}, "192.0.2.1:53"},
+		{[]string{"external", "internal"}, "192.0.2.2:53"},
+	}
+	for i, tc := range tests {
+		if from := cat.primaries(tc.groups); len(from) != 1 || from[0] != tc.from {
+			t.Errorf("Test %d: expected primaries %s, got %v", i, tc.from, from)
+		}
+	}
+}
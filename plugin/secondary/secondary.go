@@ -1,12 +1,54 @@
 // Package secondary implements a secondary plugin.
 package secondary
 
-import "github.com/coredns/coredns/plugin/file"
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
 
 // Secondary implements a secondary plugin that allows CoreDNS to retrieve (via AXFR)
 // zone information from a primary server.
 type Secondary struct {
 	file.File
+
+	catalogs []*catalogZone
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (s Secondary) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+	if f, zone := s.member(qname); len(zone) > len(plugin.Zones(s.Names).Matches(qname)) {
+		return f.ServeDNS(ctx, w, r)
+	}
+	return s.File.ServeDNS(ctx, w, r)
+}
+
+// Transfer implements the transfer.Transferer interface.
+func (s Secondary) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	if f, z := s.member(zone); z == zone {
+		return f.Transfer(zone, serial)
+	}
+	return s.File.Transfer(zone, serial)
+}
+
+// member returns a file.File serving the member zones of the catalog zone that has a member zone for
+// qname, and the name of that member zone. If there is no such member zone, the name is empty.
+func (s Secondary) member(qname string) (file.File, string) {
+	for _, c := range s.catalogs {
+		zones := c.zones.Load()
+		if zone := plugin.Zones(zones.Names).Matches(qname); zone != "" {
+			f := s.File
+			f.Zones = *zones
+			return f, zone
+		}
+	}
+	return file.File{}, ""
 }
 
 // Name implements the Handler interface.
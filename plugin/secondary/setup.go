@@ -1,6 +1,7 @@
 package secondary
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/coredns/caddy"
@@ -9,6 +10,7 @@ import (
 	"github.com/coredns/coredns/plugin/file"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 )
 
@@ -17,43 +19,22 @@ var log = clog.NewWithPlugin("secondary")
 func init() { plugin.Register("secondary", setup) }
 
 func setup(c *caddy.Controller) error {
-	zones, err := secondaryParse(c)
+	s, err := secondaryParse(c)
 	if err != nil {
 		return plugin.Error("secondary", err)
 	}
 
 	// Add startup functions to retrieve the zone and keep it up to date.
-	for i := range zones.Names {
-		n := zones.Names[i]
-		z := zones.Z[n]
+	for i := range s.Names {
+		n := s.Names[i]
+		z := s.Z[n]
 		if len(z.TransferFrom) > 0 {
 			// In order to support secondary plugin reloading.
 			updateShutdown := make(chan bool)
 
 			c.OnStartup(func() error {
 				z.StartupOnce.Do(func() {
-					go func() {
-						dur := time.Millisecond * 250
-						max := time.Second * 10
-						for {
-							err := z.TransferIn()
-							if err == nil {
-								break
-							}
-							log.Warningf("All '%s' masters failed to transfer, retrying in %s: %s", n, dur.String(), err)
-							time.Sleep(dur)
-							dur <<= 1 // double the duration
-							if dur > max {
-								dur = max
-							}
-							select {
-							case <-updateShutdown:
-								return
-							default:
-							}
-						}
-						z.Update(updateShutdown)
-					}()
+					go transferIn(n, z, updateShutdown)
 				})
 				return nil
 			})
@@ -63,17 +44,50 @@ func setup(c *caddy.Controller) error {
 			})
 		}
 	}
+	for _, cat := range s.catalogs {
+		c.OnShutdown(func() error {
+			cat.stop()
+			return nil
+		})
+	}
 
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
-		return Secondary{file.File{Next: next, Zones: zones}}
+		s.Next = next
+		return s
 	})
 
 	return nil
 }
 
-func secondaryParse(c *caddy.Controller) (file.Zones, error) {
+// transferIn retrieves the zone z named n, retrying until it succeeds, and then keeps it up to date
+// until updateShutdown is signalled.
+func transferIn(n string, z *file.Zone, updateShutdown chan bool) {
+	dur := time.Millisecond * 250
+	max := time.Second * 10
+	for {
+		err := z.TransferIn()
+		if err == nil {
+			break
+		}
+		log.Warningf("All '%s' masters failed to transfer, retrying in %s: %s", n, dur.String(), err)
+		time.Sleep(dur)
+		dur <<= 1 // double the duration
+		if dur > max {
+			dur = max
+		}
+		select {
+		case <-updateShutdown:
+			return
+		default:
+		}
+	}
+	z.Update(updateShutdown)
+}
+
+func secondaryParse(c *caddy.Controller) (Secondary, error) {
 	z := make(map[string]*file.Zone)
 	names := []string{}
+	catalogs := []*catalogZone{}
 	for c.Next() {
 		if c.Val() == "secondary" {
 			// secondary [origin]
@@ -83,7 +97,8 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 				names = append(names, origins[i])
 			}
 
-			hasTransfer := false
+			hasTransfer, isCatalog := false, false
+			groups := map[string][]string{}
 			for c.NextBlock() {
 				var f []string
 
@@ -92,11 +107,44 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 					var err error
 					f, err = parse.TransferIn(c)
 					if err != nil {
-						return file.Zones{}, err
+						return Secondary{}, err
 					}
 					hasTransfer = true
+				case "ixfr":
+					args := c.RemainingArgs()
+					if len(args) > 1 {
+						return Secondary{}, c.ArgErr()
+					}
+					n := file.DefaultIXFRHistory
+					if len(args) == 1 {
+						var err error
+						n, err = strconv.Atoi(args[0])
+						if err != nil || n < 0 {
+							return Secondary{}, c.Errf("invalid ixfr value %q", args[0])
+						}
+					}
+					for _, origin := range origins {
+						z[origin].IXFRHistory = n
+					}
+				case "catalog":
+					if c.NextArg() {
+						return Secondary{}, c.ArgErr()
+					}
+					isCatalog = true
+				case "group":
+					args := c.RemainingArgs()
+					if len(args) < 2 {
+						return Secondary{}, c.ArgErr()
+					}
+					for _, addr := range args[1:] {
+						h, err := parse.HostPort(addr, transport.Port)
+						if err != nil {
+							return Secondary{}, c.Err(err.Error())
+						}
+						groups[args[0]] = append(groups[args[0]], h)
+					}
 				default:
-					return file.Zones{}, c.Errf("unknown property '%s'", c.Val())
+					return Secondary{}, c.Errf("unknown property '%s'", c.Val())
 				}
 
 				for _, origin := range origins {
@@ -107,9 +155,25 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 				}
 			}
 			if !hasTransfer {
-				return file.Zones{}, c.Err("secondary zones require a transfer from property")
+				return Secondary{}, c.Err("secondary zones require a transfer from property")
+			}
+			if len(groups) > 0 && !isCatalog {
+				return Secondary{}, c.Err("group requires a catalog zone")
+			}
+			if isCatalog {
+				for _, origin := range origins {
+					cat := newCatalogZone(origin, z[origin])
+					cat.groups = groups
+					cat.ixfr = z[origin].IXFRHistory
+					cat.upstream = z[origin].Upstream
+					z[origin].OnTransfer = cat.update
+					catalogs = append(catalogs, cat)
+				}
 			}
 		}
 	}
-	return file.Zones{Z: z, Names: names}, nil
+	for _, cat := range catalogs {
+		cat.static = names
+	}
+	return Secondary{File: file.File{Zones: file.Zones{Z: z, Names: names}}, catalogs: catalogs}, nil
 }
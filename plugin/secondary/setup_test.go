@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/file"
 )
 
 func TestSecondaryParse(t *testing.T) {
@@ -29,6 +30,53 @@ func TestSecondaryParse(t *testing.T) {
 			"127.0.0.1:53",
 			[]string{"example.org."},
 		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				ixfr 500
+			}`,
+			false,
+			"127.0.0.1:53",
+			[]string{"example.org."},
+		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				ixfr -1
+			}`,
+			true,
+			"",
+			nil,
+		},
+		{
+			`secondary catalog.invalid {
+				transfer from 127.0.0.1
+				catalog
+				group internal 10.0.0.1 10.0.0.2:5300
+			}`,
+			false,
+			"127.0.0.1:53",
+			[]string{"catalog.invalid."},
+		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				group internal 10.0.0.1
+			}`,
+			true,
+			"",
+			nil,
+		},
+		{
+			`secondary catalog.invalid {
+				transfer from 127.0.0.1
+				catalog
+				group internal
+			}`,
+			true,
+			"",
+			nil,
+		},
 		{
 			`secondary`,
 			true,
@@ -69,3 +117,42 @@ func TestSecondaryParse(t *testing.T) {
 		}
 	}
 }
+
+func TestSecondaryParseIXFR(t *testing.T) {
+	tests := []struct {
+		input   string
+		history int
+	}{
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+			}`,
+			0,
+		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				ixfr
+			}`,
+			file.DefaultIXFRHistory,
+		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				ixfr 500
+			}`,
+			500,
+		},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error: %s", i, err)
+		}
+		if x := s.Z["example.org."].IXFRHistory; x != test.history {
+			t.Errorf("Test %d expected ixfr history to be %d, but got %d", i, test.history, x)
+		}
+	}
+}
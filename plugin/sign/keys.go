@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -25,10 +26,10 @@ type Pair struct {
 	Private crypto.Signer
 }
 
-// keyParse reads the public and private key from disk.
-func keyParse(c *caddy.Controller) ([]Pair, error) {
+// keyParse reads the public and private key from disk, or returns the policy for generating keys.
+func keyParse(c *caddy.Controller) ([]Pair, *policy, error) {
 	if !c.NextArg() {
-		return nil, c.ArgErr()
+		return nil, nil, c.ArgErr()
 	}
 	pairs := []Pair{}
 	config := dnsserver.GetConfig(c)
@@ -37,7 +38,7 @@ func keyParse(c *caddy.Controller) ([]Pair, error) {
 	case "file":
 		ks := c.RemainingArgs()
 		if len(ks) == 0 {
-			return nil, c.ArgErr()
+			return nil, nil, c.ArgErr()
 		}
 		for _, k := range ks {
 			base := k
@@ -54,15 +55,31 @@ func keyParse(c *caddy.Controller) ([]Pair, error) {
 
 			pair, err := readKeyPair(base+".key", base+".private")
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			pairs = append(pairs, pair)
 		}
 	case "directory":
-		return nil, fmt.Errorf("directory: not implemented")
+		return nil, nil, fmt.Errorf("directory: not implemented")
+	case "policy":
+		p := &policy{algorithm: dns.ECDSAP256SHA256, kskLifetime: durationKSKLifetime, zskLifetime: durationZSKLifetime}
+		args := c.RemainingArgs()
+		if len(args) > 1 {
+			return nil, nil, c.ArgErr()
+		}
+		if len(args) == 1 {
+			alg, ok := dns.StringToAlgorithm[strings.ToUpper(args[0])]
+			if !ok || bits(alg) == 0 {
+				return nil, nil, c.Errf("unsupported algorithm %q", args[0])
+			}
+			p.algorithm = alg
+		}
+		return nil, p, nil
+	default:
+		return nil, nil, c.Errf("unknown key type %q", c.Val())
 	}
 
-	return pairs, nil
+	return pairs, nil, nil
 }
 
 func readKeyPair(public, private string) (Pair, error) {
@@ -81,9 +98,8 @@ func readKeyPair(public, private string) (Pair, error) {
 	if _, ok := dnskey.(*dns.DNSKEY); !ok {
 		return Pair{}, fmt.Errorf("RR in %q is not a DNSKEY: %d", public, dnskey.Header().Rrtype)
 	}
-	ksk := dnskey.(*dns.DNSKEY).Flags&(1<<8) == (1<<8) && dnskey.(*dns.DNSKEY).Flags&1 == 1
-	if !ksk {
-		return Pair{}, fmt.Errorf("DNSKEY in %q is not a CSK/KSK", public)
+	if dnskey.(*dns.DNSKEY).Flags&dns.ZONE != dns.ZONE {
+		return Pair{}, fmt.Errorf("DNSKEY in %q is not a zone key", public)
 	}
 
 	rp, err := os.Open(filepath.Clean(private))
@@ -106,6 +122,91 @@ func readKeyPair(public, private string) (Pair, error) {
 	}
 }
 
+// ksk returns true if p is a Key Signing Key, or a Combined Signing Key.
+func (p Pair) ksk() bool { return p.Public.Flags&dns.SEP == dns.SEP }
+
+// keySet holds the keys a zone is signed with.
+type keySet struct {
+	dnskeys []*dns.DNSKEY // Keys published in the zone.
+	ksks    []Pair        // Keys signing the DNSKEY, CDS and CDNSKEY RRsets.
+	zsks    []Pair        // Keys signing all other RRsets.
+	cds     []*dns.DNSKEY // Keys that have CDS and CDNSKEY records.
+}
+
+// newKeySet returns the key set for pairs. Keys with the SEP flag are KSKs, the others are ZSKs. If there are
+// no ZSKs, the KSKs sign the entire zone, i.e. they are Combined Signing Keys.
+func newKeySet(pairs []Pair) keySet {
+	ks := keySet{}
+	for _, p := range pairs {
+		ks.dnskeys = append(ks.dnskeys, p.Public)
+		if p.ksk() {
+			ks.ksks = append(ks.ksks, p)
+			ks.cds = append(ks.cds, p.Public)
+			continue
+		}
+		ks.zsks = append(ks.zsks, p)
+	}
+	if len(ks.zsks) == 0 {
+		ks.zsks = ks.ksks
+	}
+	return ks
+}
+
+// pairs returns the keys in ks that sign.
+func (ks keySet) pairs() []Pair {
+	ps := append([]Pair{}, ks.ksks...)
+	for _, z := range ks.zsks {
+		if !slices.ContainsFunc(ps, func(p Pair) bool { return p.KeyTag == z.KeyTag }) {
+			ps = append(ps, z)
+		}
+	}
+	return ps
+}
+
+// generateKeyPair generates a new key for origin with algorithm and writes it to dir, using the BIND9 file
+// names. The base name of the files is returned as well.
+func generateKeyPair(origin string, algorithm uint8, ksk bool, dir string) (Pair, string, error) {
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE,
+		Protocol:  3,
+		Algorithm: algorithm,
+	}
+	if ksk {
+		k.Flags |= dns.SEP
+	}
+	priv, err := k.Generate(bits(algorithm))
+	if err != nil {
+		return Pair{}, "", err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return Pair{}, "", fmt.Errorf("unsupported algorithm %s", dns.AlgorithmToString[algorithm])
+	}
+
+	base := fmt.Sprintf("K%s+%03d+%05d", origin, algorithm, k.KeyTag())
+	if err := os.WriteFile(filepath.Join(dir, base+".key"), []byte(k.String()+"\n"), 0644); err != nil {
+		return Pair{}, "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, base+".private"), []byte(k.PrivateKeyString(priv)), 0600); err != nil {
+		return Pair{}, "", err
+	}
+	return Pair{Public: k, KeyTag: k.KeyTag(), Private: signer}, base, nil
+}
+
+// bits returns the key size used when generating keys for algorithm, or 0 if the algorithm isn't supported.
+func bits(algorithm uint8) int {
+	switch algorithm {
+	case dns.RSASHA256, dns.RSASHA512:
+		return 2048
+	case dns.ECDSAP256SHA256, dns.ED25519:
+		return 256
+	case dns.ECDSAP384SHA384:
+		return 384
+	}
+	return 0
+}
+
 // keyTag returns the key tags of the keys in ps as a formatted string.
 func keyTag(ps []Pair) string {
 	if len(ps) == 0 {
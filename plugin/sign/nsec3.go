@@ -0,0 +1,123 @@
+package sign
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// nsec3Params are the parameters of the NSEC3 chain (RFC 5155) of a zone. The defaults follow RFC 9276: no
+// extra iterations and no salt.
+type nsec3Params struct {
+	iterations uint16
+	salt       string // Hex encoded, empty for no salt.
+	optout     bool   // Insecure delegations are not in the chain.
+}
+
+// param returns the NSEC3PARAM record for the chain of origin.
+func (p nsec3Params) param(origin string) *dns.NSEC3PARAM {
+	return &dns.NSEC3PARAM{
+		Hdr:        dns.RR_Header{Name: origin, Rrtype: dns.TypeNSEC3PARAM, Class: dns.ClassINET, Ttl: 0},
+		Hash:       dns.SHA1,
+		Iterations: p.iterations,
+		SaltLength: uint8(len(p.salt) / 2),
+		Salt:       p.salt,
+	}
+}
+
+// nsec3Names returns the names in z that get an NSEC3 record, with the types in their bitmaps. These are the
+// authoritative names, the delegations (with opt-out only the ones that have DS records) and the empty
+// non-terminals leading to these names.
+func nsec3Names(origin string, z *file.Zone, optout bool) map[string][]uint16 {
+	names := map[string][]uint16{}
+	delegations := map[string]struct{}{}
+	z.AuthWalk(func(e *tree.Elem, _ map[uint16][]dns.RR, auth bool) error {
+		name := e.Name()
+		if auth {
+			types := append(e.Types(), dns.TypeRRSIG)
+			if name == origin {
+				types = append(types, dns.TypeNS, dns.TypeSOA)
+			}
+			names[name] = types
+			return nil
+		}
+		// Only delegations remain, names below these are glue.
+		if e.Type(dns.TypeNS) == nil || below(name, delegations) {
+			return nil
+		}
+		delegations[name] = struct{}{}
+		if e.Type(dns.TypeDS) != nil {
+			names[name] = []uint16{dns.TypeNS, dns.TypeDS, dns.TypeRRSIG}
+		} else if !optout {
+			names[name] = []uint16{dns.TypeNS}
+		}
+		return nil
+	})
+
+	existing := make([]string, 0, len(names))
+	for name := range names {
+		existing = append(existing, name)
+	}
+	for _, name := range existing {
+		for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+			parent := name[off:]
+			if !dns.IsSubDomain(origin, parent) {
+				break
+			}
+			if _, ok := names[parent]; ok {
+				break
+			}
+			names[parent] = []uint16{}
+		}
+	}
+	return names
+}
+
+// below returns true if name is below one of the names in delegations.
+func below(name string, delegations map[string]struct{}) bool {
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		if _, ok := delegations[name[off:]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NSEC3 returns the NSEC3 records for names, which maps the names to the types in their bitmaps, of the zone
+// origin. Note that the bitmaps are sorted before use.
+func NSEC3(origin string, names map[string][]uint16, ttl uint32, p nsec3Params) []*dns.NSEC3 {
+	hashes := make(map[string][]uint16, len(names))
+	sorted := make([]string, 0, len(names))
+	for name, types := range names {
+		h := strings.ToLower(dns.HashName(name, dns.SHA1, p.iterations, p.salt))
+		slices.Sort(types)
+		hashes[h] = slices.Compact(types)
+		sorted = append(sorted, h)
+	}
+	sort.Strings(sorted)
+
+	var flags uint8
+	if p.optout {
+		flags = 1
+	}
+	nsec3s := make([]*dns.NSEC3, len(sorted))
+	for i, h := range sorted {
+		nsec3s[i] = &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: h + "." + origin, Ttl: ttl, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET},
+			Hash:       dns.SHA1,
+			Flags:      flags,
+			Iterations: p.iterations,
+			SaltLength: uint8(len(p.salt) / 2),
+			Salt:       p.salt,
+			HashLength: 20,
+			NextDomain: strings.ToUpper(sorted[(i+1)%len(sorted)]),
+			TypeBitMap: hashes[h],
+		}
+	}
+	return nsec3s
+}
@@ -0,0 +1,376 @@
+package sign
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// policy generates the keys of a zone and rolls them when they reach the end of their lifetime. A ZSK is
+// rolled by pre-publishing its successor and a KSK by double signing the DNSKEY RRset, see RFC 7583. The
+// old KSK is only retired once the DS records of its successor are seen at the parent. The state of the
+// keys is kept in the directory of the signer.
+type policy struct {
+	algorithm   uint8
+	kskLifetime time.Duration // Zero means the KSK is never rolled.
+	zskLifetime time.Duration // Zero means the ZSK is never rolled.
+}
+
+// keyState holds the key in the files with base name File and the times of the stages of its life cycle.
+// A zero time means that stage isn't planned (yet).
+type keyState struct {
+	File        string    `json:"file"`
+	KSK         bool      `json:"ksk"`
+	Publish     time.Time `json:"publish"`      // DNSKEY is added to the zone.
+	Activate    time.Time `json:"activate"`     // Key starts signing.
+	Inactive    time.Time `json:"inactive"`     // Key stops signing.
+	Delete      time.Time `json:"delete"`       // DNSKEY is removed from the zone.
+	SyncPublish time.Time `json:"sync_publish"` // CDS and CDNSKEY are added to the zone.
+	SyncDelete  time.Time `json:"sync_delete"`  // CDS and CDNSKEY are removed from the zone.
+	DSSeen      time.Time `json:"ds_seen"`      // DS records of the KSK were seen at the parent, may be set by hand.
+}
+
+// during returns true if now is in the period that starts at from and ends at to. A zero to never ends
+// the period; a zero from never starts it.
+func during(now, from, to time.Time) bool {
+	return !from.IsZero() && !now.Before(from) && (to.IsZero() || now.Before(to))
+}
+
+func (k keyState) published(now time.Time) bool { return during(now, k.Publish, k.Delete) }
+func (k keyState) active(now time.Time) bool    { return during(now, k.Activate, k.Inactive) }
+func (k keyState) synced(now time.Time) bool    { return during(now, k.SyncPublish, k.SyncDelete) }
+
+// timings holds the intervals that dictate when the next stage of a rollover may start.
+type timings struct {
+	publish time.Duration // Until a new DNSKEY is known to all resolvers: the DNSKEY TTL and propagation delay.
+	retire  time.Duration // Until the signatures of a retired ZSK have expired from all caches.
+	ds      time.Duration // Until the replaced DS records have expired from all caches, after the new ones were seen.
+}
+
+// zoneTimings returns the timings for the zone z, which has DNSKEY records with ttl.
+func zoneTimings(z *file.Zone, ttl uint32) timings {
+	maxTTL := ttl
+	z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			maxTTL = max(maxTTL, rr.Header().Ttl)
+		}
+		return nil
+	})
+	return timings{
+		publish: time.Duration(ttl)*time.Second + durationPropagation,
+		retire:  time.Duration(maxTTL)*time.Second + durationPropagation,
+		ds:      durationDSExpire,
+	}
+}
+
+// plan returns the keys after planning the next rollovers at now. New keys are created with generate. It
+// returns true if keys were added or removed.
+func (p *policy) plan(now time.Time, keys []keyState, t timings, generate func(ksk bool) (string, error)) ([]keyState, bool, error) {
+	changed := false
+
+	// Forget keys that have been removed from the zone.
+	current := keys[:0:0]
+	for _, k := range keys {
+		if !k.Delete.IsZero() && !now.Before(k.Delete) {
+			changed = true
+			continue
+		}
+		current = append(current, k)
+	}
+	keys = current
+
+	for _, ksk := range []bool{true, false} {
+		lifetime := p.zskLifetime
+		if ksk {
+			lifetime = p.kskLifetime
+		}
+
+		// The key of this type without a successor, i.e. that isn't retiring. A new rollover isn't started
+		// while a key is still retiring.
+		i, rolling := -1, false
+		for j := range keys {
+			if keys[j].KSK != ksk {
+				continue
+			}
+			if keys[j].Inactive.IsZero() && keys[j].SyncDelete.IsZero() {
+				i = j
+			} else {
+				rolling = true
+			}
+		}
+
+		// A KSK whose CDS records were replaced is retired once the DS records of its successor are seen at
+		// the parent, and the replaced DS records have expired from caches. Until then it keeps signing.
+		if ksk && i != -1 && !keys[i].DSSeen.IsZero() {
+			for j := range keys {
+				if keys[j].KSK && j != i && keys[j].Inactive.IsZero() {
+					keys[j].Inactive = keys[i].DSSeen.Add(t.ds)
+					keys[j].Delete = keys[j].Inactive
+					changed = true
+				}
+			}
+		}
+
+		switch {
+		case i == -1: // No key yet, or it's gone, create one that is used right away.
+			base, err := generate(ksk)
+			if err != nil {
+				return nil, false, err
+			}
+			k := keyState{File: base, KSK: ksk, Publish: now, Activate: now}
+			if ksk {
+				k.SyncPublish = now
+			}
+			keys = append(keys, k)
+			changed = true
+
+		case !rolling && lifetime > 0 && !now.Before(keys[i].Activate.Add(lifetime-t.publish)):
+			base, err := generate(ksk)
+			if err != nil {
+				return nil, false, err
+			}
+			old := &keys[i]
+			if ksk {
+				// Double-Signature: the new KSK signs the DNSKEY RRset right away. Once it is known to all
+				// resolvers, the CDS records are changed to have the parent replace the DS records. The old
+				// KSK is retired above, when the parent has done so.
+				ready := now.Add(t.publish)
+				k := keyState{File: base, KSK: true, Publish: now, Activate: now, SyncPublish: ready}
+				old.SyncDelete = ready
+				keys = append(keys, k)
+			} else {
+				// Pre-Publication: the new ZSK signs once it is known to all resolvers. The old ZSK is removed
+				// when its signatures have expired from all caches.
+				active := now.Add(t.publish)
+				k := keyState{File: base, Publish: now, Activate: active}
+				old.Inactive = active
+				old.Delete = active.Add(t.retire)
+				keys = append(keys, k)
+			}
+			changed = true
+		}
+	}
+	return keys, changed, nil
+}
+
+// stateFile returns the path of the file with the state of the keys of s.
+func (s *Signer) stateFile() string {
+	return filepath.Join(s.directory, fmt.Sprintf("K%sstate", s.origin))
+}
+
+// readState reads the state of the keys of s. If there is no state yet, no keys are returned.
+func (s *Signer) readState() ([]keyState, error) {
+	b, err := os.ReadFile(filepath.Clean(s.stateFile()))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := []keyState{}
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, fmt.Errorf("key state %q: %s", s.stateFile(), err)
+	}
+	return keys, nil
+}
+
+// writeState writes the state of the keys of s, it is written to a temporary file which is then moved into place.
+func (s *Signer) writeState(keys []keyState) error {
+	b, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.directory, "state-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	f.Close()
+	return os.Rename(f.Name(), s.stateFile())
+}
+
+// rollover plans the rollovers of the keys of s at now, and generates the new keys that are needed.
+func (s *Signer) rollover(now time.Time) error {
+	if s.policy == nil {
+		return nil
+	}
+	rd, err := os.Open(filepath.Clean(s.dbfile))
+	if err != nil {
+		return err
+	}
+	z, err := Parse(rd, s.origin, s.dbfile)
+	rd.Close()
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.readState()
+	if err != nil {
+		return err
+	}
+	seen := s.checkDS(now, keys)
+	generate := func(ksk bool) (string, error) {
+		_, base, err := generateKeyPair(s.origin, s.policy.algorithm, ksk, s.directory)
+		if err == nil {
+			log.Infof("Generated key %q for zone %q", base, s.origin)
+		}
+		return base, err
+	}
+	keys, changed, err := s.policy.plan(now, keys, zoneTimings(z, z.SOA.Header().Ttl), generate)
+	if err != nil {
+		return err
+	}
+	if !changed && !seen {
+		return nil
+	}
+	return s.writeState(keys)
+}
+
+// checkDS looks up the DS records of the zone at the parent while a KSK is being replaced, and records
+// when those of the new KSK are first seen. It returns true if that happened.
+func (s *Signer) checkDS(now time.Time, keys []keyState) bool {
+	replacing := false
+	for _, k := range keys {
+		if k.KSK && !k.SyncDelete.IsZero() && k.Inactive.IsZero() {
+			replacing = true
+		}
+	}
+	if !replacing {
+		return false
+	}
+
+	var ds []*dns.DS
+	looked, seen := false, false
+	for i := range keys {
+		k := &keys[i]
+		if !k.KSK || !k.DSSeen.IsZero() || !k.synced(now) {
+			continue
+		}
+		if !looked {
+			var err error
+			if ds, err = s.parentDS(); err != nil {
+				log.Warningf("Error looking up the DS records of %q at the parent: %s", s.origin, err)
+				return seen
+			}
+			looked = true
+		}
+		base := filepath.Join(s.directory, k.File)
+		pair, err := readKeyPair(base+".key", base+".private")
+		if err != nil {
+			log.Warningf("Error reading key %q: %s", base, err)
+			continue
+		}
+		pair.Public.Header().Name = s.origin
+		if matchDS(pair.Public, ds) {
+			log.Infof("DS records of key %q for zone %q are published by the parent", k.File, s.origin)
+			k.DSSeen = now
+			seen = true
+		}
+	}
+	return seen
+}
+
+// parentDS returns the DS records of the zone of s, as answered by the servers in s.parents, or by the
+// resolvers in /etc/resolv.conf when none are configured.
+func (s *Signer) parentDS() ([]*dns.DS, error) {
+	servers := s.parents
+	if len(servers) == 0 {
+		cc, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range cc.Servers {
+			servers = append(servers, net.JoinHostPort(srv, cc.Port))
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(s.origin, dns.TypeDS)
+	m.SetEdns0(4096, false)
+	err := fmt.Errorf("no servers to query")
+	for _, srv := range servers {
+		var r *dns.Msg
+		r, _, err = (&dns.Client{}).Exchange(m, srv)
+		if err == nil && r.Truncated {
+			r, _, err = (&dns.Client{Net: "tcp"}).Exchange(m, srv)
+		}
+		if err != nil {
+			continue
+		}
+		if r.Rcode != dns.RcodeSuccess {
+			err = fmt.Errorf("%s answered %s", srv, dns.RcodeToString[r.Rcode])
+			continue
+		}
+		ds := []*dns.DS{}
+		for _, rr := range r.Answer {
+			if x, ok := rr.(*dns.DS); ok && strings.EqualFold(x.Header().Name, s.origin) {
+				ds = append(ds, x)
+			}
+		}
+		return ds, nil
+	}
+	return nil, err
+}
+
+// matchDS returns true if one of the records in ds is a DS record of key.
+func matchDS(key *dns.DNSKEY, ds []*dns.DS) bool {
+	for _, d := range ds {
+		x := key.ToDS(d.DigestType)
+		if x != nil && x.KeyTag == d.KeyTag && x.Algorithm == d.Algorithm && strings.EqualFold(x.Digest, d.Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// keySet returns the keys that s signs with at now.
+func (s *Signer) keySet(now time.Time) (keySet, error) {
+	if s.policy == nil {
+		return newKeySet(s.keys), nil
+	}
+	keys, err := s.readState()
+	if err != nil {
+		return keySet{}, err
+	}
+	ks := keySet{}
+	for _, k := range keys {
+		if !k.published(now) {
+			continue
+		}
+		base := filepath.Join(s.directory, k.File)
+		pair, err := readKeyPair(base+".key", base+".private")
+		if err != nil {
+			return keySet{}, err
+		}
+		pair.Public.Header().Name = s.origin
+		ks.dnskeys = append(ks.dnskeys, pair.Public)
+		if k.synced(now) {
+			ks.cds = append(ks.cds, pair.Public)
+		}
+		if !k.active(now) {
+			continue
+		}
+		if k.KSK {
+			ks.ksks = append(ks.ksks, pair)
+		} else {
+			ks.zsks = append(ks.zsks, pair)
+		}
+	}
+	if len(ks.ksks) == 0 || len(ks.zsks) == 0 {
+		return keySet{}, fmt.Errorf("no active keys in %q", s.stateFile())
+	}
+	return ks, nil
+}
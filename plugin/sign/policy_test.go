@@ -0,0 +1,237 @@
+package sign
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+
+	"github.com/miekg/dns"
+)
+
+func TestPlan(t *testing.T) {
+	p := &policy{kskLifetime: 365 * 24 * time.Hour, zskLifetime: 30 * 24 * time.Hour}
+	tm := timings{publish: 2 * time.Hour, retire: 25 * time.Hour, ds: 48 * time.Hour}
+	n := 0
+	generate := func(ksk bool) (string, error) {
+		n++
+		return fmt.Sprintf("K%d", n), nil
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Bootstrap: a KSK and a ZSK that are used right away.
+	keys, changed, err := p.plan(now, nil, tm, generate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || len(keys) != 2 {
+		t.Fatalf("Expected 2 new keys, got %d", len(keys))
+	}
+	for _, k := range keys {
+		if !k.published(now) || !k.active(now) {
+			t.Errorf("Expected key %s to be published and active", k.File)
+		}
+		if k.synced(now) != k.KSK {
+			t.Errorf("Expected only the KSK to have CDS records")
+		}
+	}
+
+	// Nothing to do yet.
+	if _, changed, _ := p.plan(now.Add(time.Hour), keys, tm, generate); changed {
+		t.Errorf("Expected no changes")
+	}
+
+	// ZSK rollover with pre-publication.
+	then := now.Add(p.zskLifetime - tm.publish)
+	keys, changed, err = p.plan(then, keys, tm, generate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || len(keys) != 3 {
+		t.Fatalf("Expected a new ZSK, got %d keys", len(keys))
+	}
+	zsk, newZSK := keys[1], keys[2]
+	if newZSK.KSK || !newZSK.published(then) || newZSK.active(then) {
+		t.Errorf("Expected new ZSK to be published, but not active")
+	}
+	if !zsk.active(then) || zsk.active(then.Add(tm.publish)) || !newZSK.active(then.Add(tm.publish)) {
+		t.Errorf("Expected new ZSK to take over after %s", tm.publish)
+	}
+	if !zsk.published(then.Add(tm.publish)) || zsk.published(then.Add(tm.publish+tm.retire)) {
+		t.Errorf("Expected old ZSK to be removed %s after it retired", tm.retire)
+	}
+
+	// No new rollover while the old ZSK is still in the zone.
+	if _, changed, _ := p.plan(then.Add(p.zskLifetime), keys, tm, generate); !changed {
+		t.Errorf("Expected the old ZSK to be removed")
+	}
+	keys, _, _ = p.plan(then.Add(tm.publish+tm.retire), keys, tm, generate)
+	if len(keys) != 2 {
+		t.Fatalf("Expected the old ZSK to be forgotten, got %d keys", len(keys))
+	}
+
+	// KSK rollover with double signatures. The ZSK is no longer rolled, to only see the changes to the KSKs.
+	p.zskLifetime = 0
+	then = now.Add(p.kskLifetime - tm.publish)
+	keys, _, err = p.plan(then, keys, tm, generate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ksk, newKSK keyState
+	for _, k := range keys {
+		if k.KSK && k.File == "K1" {
+			ksk = k
+		} else if k.KSK {
+			newKSK = k
+		}
+	}
+	if newKSK.File == "" {
+		t.Fatalf("Expected a new KSK")
+	}
+	if !newKSK.active(then) || newKSK.synced(then) || !ksk.synced(then) {
+		t.Errorf("Expected new KSK to sign right away, but CDS records to stay with the old KSK")
+	}
+	ready := then.Add(tm.publish)
+	if !newKSK.synced(ready) || ksk.synced(ready) {
+		t.Errorf("Expected CDS records to change after %s", tm.publish)
+	}
+
+	// The old KSK keeps signing until the DS records of the new KSK are seen at the parent.
+	later := ready.Add(30 * 24 * time.Hour)
+	keys, changed, err = p.plan(later, keys, tm, generate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || len(keys) != 3 {
+		t.Fatalf("Expected no changes while the DS records aren't replaced, got %d keys", len(keys))
+	}
+	if !ksk.active(later) || !ksk.published(later) {
+		t.Errorf("Expected old KSK to keep signing until the DS records are replaced")
+	}
+
+	seen := later.Add(time.Hour)
+	for i := range keys {
+		if keys[i].File == newKSK.File {
+			keys[i].DSSeen = seen
+		}
+	}
+	keys, changed, err = p.plan(seen, keys, tm, generate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatalf("Expected the old KSK to be retired")
+	}
+	for _, k := range keys {
+		if k.File != ksk.File {
+			continue
+		}
+		if !k.active(seen) || k.published(seen.Add(tm.ds)) {
+			t.Errorf("Expected old KSK to be removed %s after the DS records were seen", tm.ds)
+		}
+	}
+	if keys, _, _ = p.plan(seen.Add(tm.ds), keys, tm, generate); len(keys) != 2 {
+		t.Errorf("Expected the old KSK to be forgotten, got %d keys", len(keys))
+	}
+}
+
+func TestRolloverDSSeen(t *testing.T) {
+	dir := t.TempDir()
+	input := fmt.Sprintf(`sign testdata/db.miek.nl miek.nl {
+		key policy
+		rollover zsk 0s
+		directory %s
+	}`, dir)
+	c := caddy.NewTestController("dns", input)
+	sign, err := parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sign.signers[0]
+
+	var (
+		mu sync.Mutex
+		ds []dns.RR
+	)
+	parent := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		mu.Lock()
+		m.Answer = ds
+		mu.Unlock()
+		w.WriteMsg(m)
+	})
+	defer parent.Close()
+	s.parents = []string{parent.Addr}
+
+	// ksks returns the current KSK and the one replacing it, if any.
+	ksks := func() (cur, next keyState) {
+		keys, err := s.readState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range keys {
+			if !k.KSK {
+				continue
+			}
+			if k.SyncDelete.IsZero() {
+				next = k
+			} else {
+				cur = k
+			}
+		}
+		return cur, next
+	}
+	publishDS := func(k keyState) {
+		base := filepath.Join(dir, k.File)
+		pair, err := readKeyPair(base+".key", base+".private")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pair.Public.Header().Name = "miek.nl."
+		mu.Lock()
+		ds = []dns.RR{pair.Public.ToDS(dns.SHA256)}
+		mu.Unlock()
+	}
+
+	now := time.Now().UTC()
+	if err := s.rollover(now); err != nil {
+		t.Fatal(err)
+	}
+	_, first := ksks()
+	publishDS(first)
+
+	then := now.Add(durationKSKLifetime)
+	if err := s.rollover(then); err != nil {
+		t.Fatal(err)
+	}
+	cur, next := ksks()
+	if cur.File != first.File || next.File == first.File {
+		t.Fatalf("Expected a KSK rollover")
+	}
+
+	// The parent still has the DS records of the old KSK.
+	ready := then.Add(24 * time.Hour)
+	if err := s.rollover(ready); err != nil {
+		t.Fatal(err)
+	}
+	if cur, next = ksks(); !next.DSSeen.IsZero() || !cur.Inactive.IsZero() {
+		t.Fatalf("Expected the old KSK to stay while the parent has its DS records")
+	}
+
+	publishDS(next)
+	if err := s.rollover(ready); err != nil {
+		t.Fatal(err)
+	}
+	cur, next = ksks()
+	if !next.DSSeen.Equal(ready) {
+		t.Errorf("Expected the DS records of the new KSK to be seen at %s, got %s", ready, next.DSSeen)
+	}
+	if !cur.Inactive.Equal(ready.Add(durationDSExpire)) || !cur.Delete.Equal(cur.Inactive) {
+		t.Errorf("Expected the old KSK to be removed %s after the DS records were seen", durationDSExpire)
+	}
+}
@@ -1,15 +1,18 @@
 package sign
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	pkgparse "github.com/coredns/coredns/plugin/pkg/parse"
 )
 
 func init() { plugin.Register("sign", setup) }
@@ -73,7 +76,7 @@ func parse(c *caddy.Controller) (*Sign, error) {
 		for c.NextBlock() {
 			switch c.Val() {
 			case "key":
-				pairs, err := keyParse(c)
+				pairs, pol, err := keyParse(c)
 				if err != nil {
 					return sign, err
 				}
@@ -82,6 +85,54 @@ func parse(c *caddy.Controller) (*Sign, error) {
 						p.Public.Header().Name = signers[i].origin
 					}
 					signers[i].keys = append(signers[i].keys, pairs...)
+					if pol != nil {
+						p := *pol
+						signers[i].policy = &p
+					}
+				}
+			case "rollover":
+				// rollover ksk|zsk DURATION
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return sign, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[1])
+				if err != nil || d < 0 {
+					return sign, c.Errf("invalid rollover duration %q", args[1])
+				}
+				for i := range signers {
+					if signers[i].policy == nil {
+						return sign, c.Err("rollover requires a key policy")
+					}
+					switch args[0] {
+					case "ksk":
+						signers[i].policy.kskLifetime = d
+					case "zsk":
+						signers[i].policy.zskLifetime = d
+					default:
+						return sign, c.Errf("unknown key type %q", args[0])
+					}
+				}
+			case "parent":
+				// parent ADDRESS...
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return sign, c.ArgErr()
+				}
+				servers, err := pkgparse.HostPortOrFile(args...)
+				if err != nil {
+					return sign, err
+				}
+				for i := range signers {
+					signers[i].parents = servers
+				}
+			case "nsec3":
+				p, err := nsec3Parse(c)
+				if err != nil {
+					return sign, err
+				}
+				for i := range signers {
+					signers[i].nsec3 = p
 				}
 			case "directory":
 				dir := c.RemainingArgs()
@@ -99,8 +150,51 @@ func parse(c *caddy.Controller) (*Sign, error) {
 				return nil, c.Errf("unknown property '%s'", c.Val())
 			}
 		}
+		for i := range signers {
+			if signers[i].policy != nil && len(signers[i].keys) > 0 {
+				return sign, c.Err("key policy can not be combined with key files")
+			}
+		}
 		sign.signers = append(sign.signers, signers...)
 	}
 
 	return sign, nil
 }
+
+// nsec3Parse parses the NSEC3 parameters: nsec3 [iterations N] [salt SALT] [optout].
+func nsec3Parse(c *caddy.Controller) (*nsec3Params, error) {
+	p := &nsec3Params{}
+	args := c.RemainingArgs()
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "optout":
+			p.optout = true
+		case "iterations":
+			i++
+			if i == len(args) {
+				return nil, c.ArgErr()
+			}
+			n, err := strconv.ParseUint(args[i], 10, 16)
+			if err != nil {
+				return nil, c.Errf("invalid iterations %q", args[i])
+			}
+			p.iterations = uint16(n)
+		case "salt":
+			i++
+			if i == len(args) {
+				return nil, c.ArgErr()
+			}
+			if args[i] == "-" {
+				p.salt = ""
+				continue
+			}
+			if _, err := hex.DecodeString(args[i]); err != nil || len(args[i]) > 2*255 {
+				return nil, c.Errf("invalid salt %q", args[i])
+			}
+			p.salt = strings.ToUpper(args[i])
+		default:
+			return nil, c.Errf("unknown nsec3 property %q", args[i])
+		}
+	}
+	return p, nil
+}
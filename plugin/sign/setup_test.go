@@ -1,9 +1,13 @@
 package sign
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/coredns/caddy"
+
+	"github.com/miekg/dns"
 )
 
 func TestParse(t *testing.T) {
@@ -37,6 +41,24 @@ func TestParse(t *testing.T) {
 				signedfile: "db.example.org.signed",
 			},
 		},
+		{`sign testdata/db.miek.nl miek.nl {
+			key policy ED25519
+			rollover zsk 720h
+			parent 192.0.2.1 192.0.2.2:5300
+			nsec3 iterations 1 salt aabbccdd optout
+		 }`,
+			false,
+			&Signer{
+				keys:       []Pair{},
+				policy:     &policy{algorithm: dns.ED25519, kskLifetime: durationKSKLifetime, zskLifetime: 720 * time.Hour},
+				parents:    []string{"192.0.2.1:53", "192.0.2.2:5300"},
+				nsec3:      &nsec3Params{iterations: 1, salt: "AABBCCDD", optout: true},
+				origin:     "miek.nl.",
+				dbfile:     "testdata/db.miek.nl",
+				directory:  "/var/lib/coredns",
+				signedfile: "db.miek.nl.signed",
+			},
+		},
 		// errors
 		{`sign db.example.org {
 			key file /etc/coredns/keys/Kexample.org
@@ -44,6 +66,47 @@ func TestParse(t *testing.T) {
 			true,
 			nil,
 		},
+		{`sign testdata/db.miek.nl miek.nl {
+			key policy RSAMD5
+		 }`,
+			true,
+			nil,
+		},
+		{`sign testdata/db.miek.nl miek.nl {
+			key file testdata/Kmiek.nl.+013+59725
+			rollover zsk 720h
+		 }`,
+			true,
+			nil,
+		},
+		{`sign testdata/db.miek.nl miek.nl {
+			key file testdata/Kmiek.nl.+013+59725
+			key policy
+		 }`,
+			true,
+			nil,
+		},
+		{`sign testdata/db.miek.nl miek.nl {
+			key policy
+			rollover csk 720h
+		 }`,
+			true,
+			nil,
+		},
+		{`sign testdata/db.miek.nl miek.nl {
+			key policy
+			parent
+		 }`,
+			true,
+			nil,
+		},
+		{`sign testdata/db.miek.nl miek.nl {
+			key file testdata/Kmiek.nl.+013+59725
+			nsec3 salt xyz
+		 }`,
+			true,
+			nil,
+		},
 	}
 	for i, tc := range tests {
 		c := caddy.NewTestController("dns", tc.input)
@@ -71,6 +134,15 @@ func TestParse(t *testing.T) {
 		if x := signer.signedfile; x != tc.exp.signedfile {
 			t.Errorf("Test %d expected %s as signedfile, got %s", i, tc.exp.signedfile, x)
 		}
+		if x := signer.policy; (x == nil) != (tc.exp.policy == nil) || (x != nil && *x != *tc.exp.policy) {
+			t.Errorf("Test %d expected %v as key policy, got %v", i, tc.exp.policy, x)
+		}
+		if x := signer.parents; fmt.Sprint(x) != fmt.Sprint(tc.exp.parents) {
+			t.Errorf("Test %d expected %v as parents, got %v", i, tc.exp.parents, x)
+		}
+		if x := signer.nsec3; (x == nil) != (tc.exp.nsec3 == nil) || (x != nil && *x != *tc.exp.nsec3) {
+			t.Errorf("Test %d expected %v as NSEC3 parameters, got %v", i, tc.exp.nsec3, x)
+		}
 	}
 }
 
@@ -14,6 +14,9 @@ type Sign struct {
 // OnStartup scans all signers and signs or resigns zones if needed.
 func (s *Sign) OnStartup() error {
 	for _, signer := range s.signers {
+		if err := signer.rollover(time.Now().UTC()); err != nil {
+			log.Warningf("Error rolling keys of %q: %s", signer.origin, err)
+		}
 		why := signer.resign()
 		if why == nil {
 			log.Infof("Skipping signing zone %q in %q: signatures are valid", signer.origin, filepath.Join(signer.directory, signer.signedfile))
@@ -33,6 +36,11 @@ const (
 	durationInceptionJitter         = -18 * time.Hour     // default max jitter for the inception
 	durationExpirationDayJitter     = 5 * 24 * time.Hour  // default max jitter for the expiration
 	durationSignatureInceptionHours = -3 * time.Hour      // -(2+1) hours, be sure to catch daylight saving time and such, jitter is subtracted
+
+	durationKSKLifetime = 365 * 24 * time.Hour // default lifetime of a KSK generated by a key policy
+	durationZSKLifetime = 90 * 24 * time.Hour  // default lifetime of a ZSK generated by a key policy
+	durationPropagation = 1 * time.Hour        // time for a change in the zone to reach all secondaries
+	durationDSExpire    = 2 * 24 * time.Hour   // time for replaced DS records to expire from caches, the largest DS TTL we expect
 )
 
 const timeFmt = "2006-01-02T15:04:05.000Z07:00"
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/coredns/coredns/plugin/file"
@@ -19,6 +20,9 @@ var log = clog.NewWithPlugin("sign")
 // Signer holds the data needed to sign a zone file.
 type Signer struct {
 	keys        []Pair
+	policy      *policy      // If set, keys are generated and rolled according to this policy.
+	parents     []string     // Servers queried for the DS records of the zone during a KSK rollover.
+	nsec3       *nsec3Params // If set, NSEC3 is used instead of NSEC.
 	origin      string
 	dbfile      string
 	directory   string
@@ -31,6 +35,16 @@ type Signer struct {
 
 // Sign signs a zone file according to the parameters in s.
 func (s *Signer) Sign(now time.Time) (*file.Zone, error) {
+	ks, err := s.keySet(now)
+	if err != nil {
+		return nil, err
+	}
+	return s.sign(now, ks)
+}
+
+// sign signs a zone file with the keys in ks. The KSKs sign the DNSKEY, CDS and CDNSKEY RRsets, the ZSKs sign
+// all other RRsets.
+func (s *Signer) sign(now time.Time, ks keySet) (*file.Zone, error) {
 	rd, err := os.Open(s.dbfile)
 	if err != nil {
 		return nil, err
@@ -46,18 +60,21 @@ func (s *Signer) Sign(now time.Time) (*file.Zone, error) {
 	inception, expiration := lifetime(now, s.jitterIncep, s.jitterExpir)
 	z.SOA.Serial = uint32(now.Unix())
 
-	for _, pair := range s.keys {
-		pair.Public.Header().Ttl = ttl // set TTL on key so it matches the RRSIG.
-		z.Insert(pair.Public)
-		z.Insert(pair.Public.ToDS(dns.SHA1).ToCDS())
-		z.Insert(pair.Public.ToDS(dns.SHA256).ToCDS())
-		z.Insert(pair.Public.ToCDNSKEY())
+	for _, k := range ks.dnskeys {
+		k.Header().Ttl = ttl // set TTL on key so it matches the RRSIG.
+		z.Insert(k)
+	}
+	for _, k := range ks.cds {
+		k.Header().Ttl = ttl
+		z.Insert(k.ToDS(dns.SHA1).ToCDS())
+		z.Insert(k.ToDS(dns.SHA256).ToCDS())
+		z.Insert(k.ToCDNSKEY())
+	}
+	if s.nsec3 != nil {
+		z.Insert(s.nsec3.param(s.origin))
 	}
 
-	names := names(s.origin, z)
-	ln := len(names)
-
-	for _, pair := range s.keys {
+	for _, pair := range ks.zsks {
 		rrsig, err := pair.signRRs([]dns.RR{z.SOA}, s.origin, ttl, inception, expiration)
 		if err != nil {
 			return nil, err
@@ -73,19 +90,48 @@ func (s *Signer) Sign(now time.Time) (*file.Zone, error) {
 		}
 	}
 
-	// We are walking the tree in the same direction, so names[] can be used here to indicated the next element.
+	var chain []string
+	if s.nsec3 == nil {
+		chain = names(s.origin, z)
+	} else {
+		for _, nsec3 := range NSEC3(s.origin, nsec3Names(s.origin, z, s.nsec3.optout), mttl, *s.nsec3) {
+			for _, pair := range ks.zsks {
+				rrsig, err := pair.signRRs([]dns.RR{nsec3}, s.origin, mttl, inception, expiration)
+				if err != nil {
+					return nil, err
+				}
+				z.Insert(rrsig)
+			}
+			z.Insert(nsec3)
+		}
+	}
+	ln := len(chain)
+
+	// We are walking the tree in the same direction, so chain[] can be used here to indicated the next element.
 	i := 1
 	err = z.AuthWalk(func(e *tree.Elem, zrrs map[uint16][]dns.RR, auth bool) error {
 		if !auth {
+			// DS records at a delegation are the only records we are authoritative for.
+			if ds := e.Type(dns.TypeDS); ds != nil && e.Type(dns.TypeNS) != nil {
+				for _, pair := range ks.zsks {
+					rrsig, err := pair.signRRs(ds, s.origin, ds[0].Header().Ttl, inception, expiration)
+					if err != nil {
+						return err
+					}
+					e.Insert(rrsig)
+				}
+			}
 			return nil
 		}
 
-		if e.Name() == s.origin {
-			nsec := NSEC(e.Name(), names[(ln+i)%ln], mttl, append(e.Types(), dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC))
-			z.Insert(nsec)
-		} else {
-			nsec := NSEC(e.Name(), names[(ln+i)%ln], mttl, append(e.Types(), dns.TypeRRSIG, dns.TypeNSEC))
-			z.Insert(nsec)
+		if s.nsec3 == nil {
+			if e.Name() == s.origin {
+				nsec := NSEC(e.Name(), chain[(ln+i)%ln], mttl, append(e.Types(), dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC))
+				z.Insert(nsec)
+			} else {
+				nsec := NSEC(e.Name(), chain[(ln+i)%ln], mttl, append(e.Types(), dns.TypeRRSIG, dns.TypeNSEC))
+				z.Insert(nsec)
+			}
 		}
 
 		for t, rrs := range zrrs {
@@ -94,7 +140,12 @@ func (s *Signer) Sign(now time.Time) (*file.Zone, error) {
 			if t == dns.TypeRRSIG || t == dns.TypeNS {
 				continue
 			}
-			for _, pair := range s.keys {
+			pairs := ks.zsks
+			switch t {
+			case dns.TypeDNSKEY, dns.TypeCDS, dns.TypeCDNSKEY:
+				pairs = ks.ksks
+			}
+			for _, pair := range pairs {
 				rrsig, err := pair.signRRs(rrs, s.origin, rrs[0].Header().Ttl, inception, expiration)
 				if err != nil {
 					return err
@@ -117,7 +168,85 @@ func (s *Signer) resign() error {
 	}
 
 	now := time.Now().UTC()
-	return resign(rd, now)
+	if why := resign(rd, now); why != nil || s.policy == nil {
+		return why
+	}
+
+	// With a key policy, the keys change over time as well.
+	ks, err := s.keySet(now)
+	if err != nil {
+		return err
+	}
+	rd, err = os.Open(filepath.Clean(signedfile))
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+	return signedWith(rd, s.origin, ks)
+}
+
+// signedWith checks if the zone origin in rd is signed with the keys in ks: the apex must have the DNSKEY
+// and CDNSKEY records of ks, and the DNSKEY RRset and the SOA must be signed by the KSKs and ZSKs of ks.
+func signedWith(rd io.Reader, origin string, ks keySet) error {
+	zp := dns.NewZoneParser(rd, ".", "resign")
+	zp.SetIncludeAllowed(true)
+
+	var dnskeys, cdnskeys, ksks, zsks []uint16
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if rr.Header().Name != origin {
+			break // The apex records are written first.
+		}
+		switch x := rr.(type) {
+		case *dns.DNSKEY:
+			dnskeys = append(dnskeys, x.KeyTag())
+		case *dns.CDNSKEY:
+			cdnskeys = append(cdnskeys, x.KeyTag())
+		case *dns.RRSIG:
+			switch x.TypeCovered {
+			case dns.TypeDNSKEY:
+				ksks = append(ksks, x.KeyTag)
+			case dns.TypeSOA:
+				zsks = append(zsks, x.KeyTag)
+			}
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return err
+	}
+
+	want := func(keys []*dns.DNSKEY) []uint16 {
+		tags := []uint16{}
+		for _, k := range keys {
+			tags = append(tags, k.KeyTag())
+		}
+		return tags
+	}
+	wantPairs := func(pairs []Pair) []uint16 {
+		tags := []uint16{}
+		for _, p := range pairs {
+			tags = append(tags, p.KeyTag)
+		}
+		return tags
+	}
+	switch {
+	case !sameTags(dnskeys, want(ks.dnskeys)):
+		return fmt.Errorf("DNSKEY records changed to key tags %v", want(ks.dnskeys))
+	case !sameTags(cdnskeys, want(ks.cds)):
+		return fmt.Errorf("CDNSKEY records changed to key tags %v", want(ks.cds))
+	case !sameTags(ksks, wantPairs(ks.ksks)):
+		return fmt.Errorf("KSKs changed to key tags %v", wantPairs(ks.ksks))
+	case !sameTags(zsks, wantPairs(ks.zsks)):
+		return fmt.Errorf("ZSKs changed to key tags %v", wantPairs(ks.zsks))
+	}
+	return nil
+}
+
+// sameTags returns true if a and b hold the same key tags.
+func sameTags(a, b []uint16) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
 }
 
 // resign will scan rd and check the signature on the SOA record. We will resign on the basis
@@ -167,10 +296,15 @@ func resign(rd io.Reader, now time.Time) (why error) {
 
 func signAndLog(s *Signer, why error) {
 	now := time.Now().UTC()
-	z, err := s.Sign(now)
 	log.Infof("Signing %q because %s", s.origin, why)
+	ks, err := s.keySet(now)
+	if err != nil {
+		log.Warningf("Error signing %q: %s, next: %s", s.origin, err, now.Add(durationRefreshHours).Format(timeFmt))
+		return
+	}
+	z, err := s.sign(now, ks)
 	if err != nil {
-		log.Warningf("Error signing %q with key tags %q in %s: %s, next: %s", s.origin, keyTag(s.keys), time.Since(now), err, now.Add(durationRefreshHours).Format(timeFmt))
+		log.Warningf("Error signing %q with key tags %q in %s: %s, next: %s", s.origin, keyTag(ks.pairs()), time.Since(now), err, now.Add(durationRefreshHours).Format(timeFmt))
 		return
 	}
 
@@ -178,7 +312,7 @@ func signAndLog(s *Signer, why error) {
 		log.Warningf("Error signing %q: failed to move zone file into place: %s", s.origin, err)
 		return
 	}
-	log.Infof("Successfully signed zone %q in %q with key tags %q and %d SOA serial, elapsed %f, next: %s", s.origin, filepath.Join(s.directory, s.signedfile), keyTag(s.keys), z.SOA.Serial, time.Since(now).Seconds(), now.Add(durationRefreshHours).Format(timeFmt))
+	log.Infof("Successfully signed zone %q in %q with key tags %q and %d SOA serial, elapsed %f, next: %s", s.origin, filepath.Join(s.directory, s.signedfile), keyTag(ks.pairs()), z.SOA.Serial, time.Since(now).Seconds(), now.Add(durationRefreshHours).Format(timeFmt))
 }
 
 // refresh checks every val if some zones need to be resigned.
@@ -190,6 +324,9 @@ func (s *Signer) refresh(val time.Duration) {
 		case <-s.stop:
 			return
 		case <-tick.C:
+			if err := s.rollover(time.Now().UTC()); err != nil {
+				log.Warningf("Error rolling keys of %q: %s", s.origin, err)
+			}
 			why := s.resign()
 			if why == nil {
 				continue
@@ -1,11 +1,15 @@
 package sign
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/file"
 
 	"github.com/miekg/dns"
 )
@@ -175,3 +179,117 @@ func TestSignDS(t *testing.T) {
 		t.Errorf("Expected no NSEC TTL to be %d for %s, got %d", minttl, "www.miek.nl.", x)
 	}
 }
+
+func TestSignNSEC3(t *testing.T) {
+	input := `sign testdata/db.miek.nl_ns miek.nl {
+		key file testdata/Kmiek.nl.+013+59725
+		directory testdata
+		nsec3
+	}`
+	c := caddy.NewTestController("dns", input)
+	sign, err := parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := sign.signers[0].Sign(time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apex, _ := z.Search("miek.nl.")
+	if x := apex.Type(dns.TypeNSEC3PARAM); len(x) != 1 {
+		t.Errorf("Expected 1 NSEC3PARAM record, got %d", len(x))
+	}
+	if x := apex.Type(dns.TypeNSEC); len(x) != 0 {
+		t.Errorf("Expected no NSEC records, got %d", len(x))
+	}
+	if z.NSEC3 == nil {
+		t.Fatal("Expected NSEC3 records")
+	}
+
+	// The signed zone can be read back and proves the absence of names.
+	buf := &bytes.Buffer{}
+	if err := write(buf, z); err != nil {
+		t.Fatal(err)
+	}
+	z1, err := file.Parse(buf, "miek.nl.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z1.NSEC3 == nil || z1.NSEC3.Count != z.NSEC3.Count {
+		t.Fatalf("Expected %d NSEC3 names after reading the signed zone back", z.NSEC3.Count)
+	}
+	name := "child.miek.nl." // secure delegation
+	h := strings.ToLower(dns.HashName(name, dns.SHA1, 0, "")) + ".miek.nl."
+	e, _ := z1.NSEC3.Search(h)
+	if e == nil {
+		t.Fatalf("Expected NSEC3 record for %s", name)
+	}
+	if x := e.Type(dns.TypeNSEC3); len(x) != 1 || !x[0].(*dns.NSEC3).Match(name) {
+		t.Errorf("Expected NSEC3 record matching %s", name)
+	}
+	if x := e.Type(dns.TypeRRSIG); len(x) != 1 {
+		t.Errorf("Expected 1 RRSIG for the NSEC3 record of %s, got %d", name, len(x))
+	}
+}
+
+func TestSignKeyPolicy(t *testing.T) {
+	dir := t.TempDir()
+	input := fmt.Sprintf(`sign testdata/db.miek.nl miek.nl {
+		key policy
+		directory %s
+	}`, dir)
+	c := caddy.NewTestController("dns", input)
+	sign, err := parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sign.signers[0]
+	now := time.Now().UTC()
+	if err := s.rollover(now); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := s.keySet(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ks.ksks) != 1 || len(ks.zsks) != 1 || ks.ksks[0].KeyTag == ks.zsks[0].KeyTag {
+		t.Fatalf("Expected a KSK and a ZSK, got %d and %d", len(ks.ksks), len(ks.zsks))
+	}
+	z, err := s.sign(now, ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apex, _ := z.Search("miek.nl.")
+	if x := apex.Type(dns.TypeDNSKEY); len(x) != 2 {
+		t.Errorf("Expected 2 DNSKEY records, got %d", len(x))
+	}
+	if x := apex.Type(dns.TypeCDNSKEY); len(x) != 1 || x[0].(*dns.CDNSKEY).Flags&dns.SEP != dns.SEP {
+		t.Errorf("Expected 1 CDNSKEY record for the KSK, got %d", len(x))
+	}
+	for _, rr := range apex.Type(dns.TypeRRSIG) {
+		sig := rr.(*dns.RRSIG)
+		want := ks.zsks[0].KeyTag
+		switch sig.TypeCovered {
+		case dns.TypeDNSKEY, dns.TypeCDS, dns.TypeCDNSKEY:
+			want = ks.ksks[0].KeyTag
+		}
+		if sig.KeyTag != want {
+			t.Errorf("Expected RRSIG(%s) by key %d, got %d", dns.TypeToString[sig.TypeCovered], want, sig.KeyTag)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := write(buf, z); err != nil {
+		t.Fatal(err)
+	}
+	signed := buf.String()
+	if err := signedWith(strings.NewReader(signed), "miek.nl.", ks); err != nil {
+		t.Errorf("Expected zone to be signed with the current keys: %s", err)
+	}
+	ks.zsks = ks.ksks
+	if err := signedWith(strings.NewReader(signed), "miek.nl.", ks); err == nil {
+		t.Errorf("Expected changed ZSKs to require signing the zone")
+	}
+}
@@ -0,0 +1,4 @@
+package sql
+
+// The pure-Go SQLite driver, registered as sqlite, is compiled in. Import other drivers here to use them.
+import _ "modernc.org/sqlite"
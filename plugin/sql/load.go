@@ -0,0 +1,162 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	zonesQuery   = "SELECT name, serial FROM zones"
+	recordsQuery = "SELECT name, type, ttl, content FROM records WHERE zone = %s"
+)
+
+// placeholder returns the bind parameter syntax of driver.
+func placeholder(driver string) string {
+	switch driver {
+	case "postgres", "pgx":
+		return "$1"
+	}
+	return "?"
+}
+
+// Refresh reads the serials of the zones in the database and loads the zones that are new or whose serial
+// changed. Zones that are no longer in the database are dropped. A zone that fails to load keeps being served
+// from its previous snapshot. Refresh returns the names of the zones that changed.
+func (s *SQL) Refresh(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, zonesQuery)
+	if err != nil {
+		return nil, err
+	}
+	serials := map[string]int64{}
+	dbNames := map[string]string{}
+	for rows.Next() {
+		var (
+			name   string
+			serial int64
+		)
+		if err := rows.Scan(&name, &serial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		origin := strings.ToLower(dns.Fqdn(name))
+		if _, ok := dns.IsDomainName(origin); !ok || plugin.Zones(s.origins).Matches(origin) == "" {
+			continue
+		}
+		serials[origin] = serial
+		dbNames[origin] = name
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for origin, serial := range serials {
+		s.RLock()
+		_, ok := s.Z[origin]
+		same := ok && s.serials[origin] == serial
+		s.RUnlock()
+		if same {
+			continue
+		}
+
+		z, err := s.load(ctx, origin, dbNames[origin], serial)
+		if err != nil {
+			log.Errorf("Failed to load zone %q: %s", origin, err)
+			continue
+		}
+		log.Infof("Loaded zone %q with serial %d", origin, serial)
+
+		s.Lock()
+		s.Z[origin] = z
+		s.serials[origin] = serial
+		s.Unlock()
+		changed = append(changed, origin)
+	}
+
+	s.Lock()
+	for origin := range s.Z {
+		if _, ok := serials[origin]; !ok {
+			log.Infof("Dropped zone %q", origin)
+			delete(s.Z, origin)
+			delete(s.serials, origin)
+		}
+	}
+	names := make([]string, 0, len(s.Z))
+	for origin := range s.Z {
+		names = append(names, origin)
+	}
+	sort.Strings(names)
+	s.names = names
+	s.Unlock()
+
+	return changed, nil
+}
+
+// load reads the records of the zone origin, named name in the database, into a new zone. The serial of
+// its SOA record is set to serial.
+func (s *SQL) load(ctx context.Context, origin, name string, serial int64) (*file.Zone, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(recordsQuery, placeholder(s.driver)), name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	z := file.NewZone(origin, "")
+	for rows.Next() {
+		var (
+			owner, typ, content string
+			ttl                 uint32
+		)
+		if err := rows.Scan(&owner, &typ, &ttl, &content); err != nil {
+			return nil, err
+		}
+		rr, err := parseRR(origin, owner, typ, ttl, content)
+		if err != nil {
+			return nil, err
+		}
+		if soa, ok := rr.(*dns.SOA); ok {
+			if !strings.EqualFold(soa.Hdr.Name, origin) {
+				return nil, fmt.Errorf("SOA record for %s is not at the apex", soa.Hdr.Name)
+			}
+			soa.Serial = uint32(serial)
+		}
+		if err := z.Insert(rr); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if z.SOA == nil {
+		return nil, fmt.Errorf("no SOA record")
+	}
+
+	z.Upstream = s.upstream
+	return z, nil
+}
+
+// parseRR parses a record from the records table. Names that are not fully qualified, in the owner as well
+// as in the content, are relative to origin; @ is origin itself.
+func parseRR(origin, owner, typ string, ttl uint32, content string) (dns.RR, error) {
+	line := fmt.Sprintf("%s %d IN %s %s", owner, ttl, typ, content)
+	zp := dns.NewZoneParser(strings.NewReader(line), origin, "")
+	rr, ok := zp.Next()
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("empty record for %q", owner)
+	}
+	if !dns.IsSubDomain(origin, rr.Header().Name) {
+		return nil, fmt.Errorf("record for %s is not in the zone", rr.Header().Name)
+	}
+	return rr, nil
+}
@@ -0,0 +1,154 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
+)
+
+var log = clog.NewWithPlugin("sql")
+
+func init() { plugin.Register("sql", setup) }
+
+const defaultRefresh = 30 * time.Second
+
+func setup(c *caddy.Controller) error {
+	s, dsn, err := sqlParse(c)
+	if err != nil {
+		return plugin.Error("sql", err)
+	}
+	if err := registered(s.driver); err != nil {
+		return plugin.Error("sql", err)
+	}
+	s.db, err = sql.Open(s.driver, dsn)
+	if err != nil {
+		return plugin.Error("sql", err)
+	}
+
+	c.OnStartup(func() error {
+		t := dnsserver.GetConfig(c).Handler("transfer")
+		if t != nil {
+			s.transfer = t.(*transfer.Transfer)
+		}
+		return nil
+	})
+
+	stop := make(chan bool)
+	c.OnStartup(func() error {
+		s.poll()
+		go func() {
+			ticker := time.NewTicker(s.refresh)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					s.poll()
+				}
+			}
+		}()
+		return nil
+	})
+
+	c.OnShutdown(func() error {
+		close(stop)
+		return s.db.Close()
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		s.Next = next
+		return s
+	})
+
+	return nil
+}
+
+// registered returns an error if no database driver with name is compiled into CoreDNS.
+func registered(name string) error {
+	drivers := sql.Drivers()
+	if slices.Contains(drivers, name) {
+		return nil
+	}
+	return fmt.Errorf("driver %q is not registered, the compiled in drivers are: %s", name, strings.Join(drivers, ", "))
+}
+
+// poll refreshes the zones from the database and notifies the secondaries of the changed zones.
+func (s *SQL) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.refresh)
+	defer cancel()
+	changed, err := s.Refresh(ctx)
+	if err != nil {
+		log.Errorf("Failed to refresh zones: %s", err)
+		return
+	}
+	if err := s.Notify(changed); err != nil {
+		log.Warning(err)
+	}
+}
+
+func sqlParse(c *caddy.Controller) (*SQL, string, error) {
+	s := &SQL{
+		Zones:    &Zones{Z: map[string]*file.Zone{}, serials: map[string]int64{}},
+		refresh:  defaultRefresh,
+		upstream: upstream.New(),
+	}
+	dsn := ""
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, "", plugin.ErrOnce
+		}
+		i++
+
+		// sql [ZONES...]
+		s.origins = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "driver":
+				if !c.NextArg() {
+					return nil, "", c.ArgErr()
+				}
+				s.driver = c.Val()
+			case "dsn":
+				if !c.NextArg() {
+					return nil, "", c.ArgErr()
+				}
+				dsn = c.Val()
+			case "refresh":
+				if !c.NextArg() {
+					return nil, "", c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil || d <= 0 {
+					return nil, "", c.Errf("invalid refresh %q", c.Val())
+				}
+				s.refresh = d
+			case "fallthrough":
+				s.Fall.SetZonesFromArgs(c.RemainingArgs())
+			default:
+				return nil, "", c.Errf("unknown property '%s'", x)
+			}
+		}
+	}
+	if s.driver == "" {
+		return nil, "", c.Err("no driver given")
+	}
+	if dsn == "" {
+		return nil, "", c.Err("no dsn given")
+	}
+	return s, dsn, nil
+}
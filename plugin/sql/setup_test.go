@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		origins   []string
+		driver    string
+		dsn       string
+		refresh   time.Duration
+	}{
+		{`sql {
+			driver sqlite
+			dsn /var/lib/coredns/zones.db
+		}`, false, nil, "sqlite", "/var/lib/coredns/zones.db", defaultRefresh},
+		{`sql example.org example.net {
+			driver postgres
+			dsn "host=db user=coredns dbname=dns"
+			refresh 5s
+			fallthrough
+		}`, false, []string{"example.org.", "example.net."}, "postgres", "host=db user=coredns dbname=dns", 5 * time.Second},
+		// errors
+		{`sql`, true, nil, "", "", 0},
+		{`sql {
+			driver sqlite
+		}`, true, nil, "", "", 0},
+		{`sql {
+			dsn zones.db
+		}`, true, nil, "", "", 0},
+		{`sql {
+			driver
+		}`, true, nil, "", "", 0},
+		{`sql {
+			driver sqlite
+			dsn zones.db
+			refresh 0s
+		}`, true, nil, "", "", 0},
+		{`sql {
+			driver sqlite
+			dsn zones.db
+			blah
+		}`, true, nil, "", "", 0},
+		{`sql {
+			driver sqlite
+			dsn zones.db
+		}
+		sql`, true, nil, "", "", 0},
+	}
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		s, dsn, err := sqlParse(c)
+		if err == nil && tc.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		}
+		if err != nil && !tc.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if tc.shouldErr {
+			continue
+		}
+		if len(s.origins) != len(tc.origins) || len(tc.origins) > 0 && s.origins[1] != tc.origins[1] {
+			t.Errorf("Test %d expected origins %v, got %v", i, tc.origins, s.origins)
+		}
+		if s.driver != tc.driver || dsn != tc.dsn {
+			t.Errorf("Test %d expected driver %q and dsn %q, got %q and %q", i, tc.driver, tc.dsn, s.driver, dsn)
+		}
+		if s.refresh != tc.refresh {
+			t.Errorf("Test %d expected refresh %s, got %s", i, tc.refresh, s.refresh)
+		}
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if err := registered("sqlite"); err != nil {
+		t.Errorf("Expected the sqlite driver to be registered, got %s", err)
+	}
+	if err := registered("postgres"); err == nil {
+		t.Errorf("Expected an error for a driver that isn't compiled in")
+	}
+}
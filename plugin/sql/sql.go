@@ -0,0 +1,117 @@
+// Package sql implements a plugin that serves zones from a database.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// SQL serves the zones stored in a database. The zones are held in memory and reloaded when their serial in
+// the database changes.
+type SQL struct {
+	Next plugin.Handler
+	Fall fall.F
+	*Zones
+
+	db      *sql.DB
+	driver  string
+	refresh time.Duration
+
+	transfer *transfer.Transfer
+	upstream *upstream.Upstream
+}
+
+// Zones holds the zones loaded from the database.
+type Zones struct {
+	Z       map[string]*file.Zone // Zones by origin.
+	names   []string              // All the keys from Z.
+	serials map[string]int64      // The serials of the loaded zones in the database.
+
+	origins []string // The origins from the server block, only zones below these are loaded.
+
+	sync.RWMutex
+}
+
+// Names returns the names of the loaded zones.
+func (z *Zones) Names() []string {
+	z.RLock()
+	n := z.names
+	z.RUnlock()
+	return n
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (s *SQL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	zone := plugin.Zones(s.origins).Matches(qname)
+	if zone == "" {
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+
+	zone = plugin.Zones(s.Names()).Matches(qname)
+	if zone == "" {
+		// If no next plugin is configured, it's more correct to return REFUSED as sql acts as an authoritative server
+		if s.Next == nil {
+			return dns.RcodeRefused, nil
+		}
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+
+	s.RLock()
+	z, ok := s.Z[zone]
+	s.RUnlock()
+	if !ok || z == nil {
+		return dns.RcodeServerFailure, nil
+	}
+
+	// If transfer is not loaded, we'll see these, answer with refused (no transfer allowed).
+	if state.QType() == dns.TypeAXFR || state.QType() == dns.TypeIXFR {
+		return dns.RcodeRefused, nil
+	}
+
+	answer, ns, extra, result := z.Lookup(ctx, state, qname)
+
+	// Only on NXDOMAIN we will fallthrough, see the file plugin.
+	if len(answer) == 0 && (result == file.NameError || result == file.Success) && s.Fall.Through(qname) {
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	m.Answer, m.Ns, m.Extra = answer, ns, extra
+
+	switch result {
+	case file.Success:
+	case file.NoData:
+	case file.NameError:
+		m.Rcode = dns.RcodeNameError
+	case file.Delegation:
+		m.Authoritative = false
+	case file.ServerFailure:
+		// A SERVFAIL with an answer comes from an external CNAME lookup, see the file plugin.
+		if len(m.Answer) == 0 {
+			return dns.RcodeServerFailure, nil
+		}
+		m.Rcode = dns.RcodeServerFailure
+	}
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (s *SQL) Name() string { return "sql" }
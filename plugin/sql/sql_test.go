@@ -0,0 +1,300 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/dnssec"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+var exampleOrg = []string{
+	"@ SOA 3600 ns1 admin 1 3600 600 86400 300",
+	"@ NS 3600 ns1",
+	"ns1 A 3600 192.0.2.53",
+	"www A 300 192.0.2.1",
+	"www AAAA 300 2001:db8::1",
+	"alias.example.org. CNAME 300 www",
+	"@ MX 300 10 mail.example.net.",
+	"sub NS 3600 ns.sub",
+	"ns.sub A 3600 192.0.2.54",
+}
+
+const schema = `
+CREATE TABLE zones (name TEXT PRIMARY KEY, serial INTEGER NOT NULL);
+CREATE TABLE records (zone TEXT NOT NULL, name TEXT NOT NULL, type TEXT NOT NULL, ttl INTEGER NOT NULL, content TEXT NOT NULL);
+`
+
+// testDB is an in-memory SQLite database with the tables of the plugin.
+type testDB struct {
+	t  *testing.T
+	db *sql.DB
+}
+
+// setZone replaces the zone in the database, each record is "name type ttl content".
+func (d testDB) setZone(zone string, serial int64, records ...string) {
+	d.t.Helper()
+	d.removeZone(zone)
+	d.exec("INSERT INTO zones VALUES (?, ?)", zone, serial)
+	for _, r := range records {
+		fields := strings.SplitN(r, " ", 4)
+		d.exec("INSERT INTO records VALUES (?, ?, ?, ?, ?)", zone, fields[0], fields[1], fields[2], fields[3])
+	}
+}
+
+func (d testDB) removeZone(zone string) {
+	d.t.Helper()
+	d.exec("DELETE FROM zones WHERE name = ?", zone)
+	d.exec("DELETE FROM records WHERE zone = ?", zone)
+}
+
+func (d testDB) exec(query string, args ...any) {
+	d.t.Helper()
+	if _, err := d.db.Exec(query, args...); err != nil {
+		d.t.Fatal(err)
+	}
+}
+
+func newSQL(t *testing.T) (*SQL, testDB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a new database, so only use one.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	s := &SQL{
+		Zones:   &Zones{Z: map[string]*file.Zone{}, serials: map[string]int64{}, origins: []string{"."}},
+		db:      db,
+		driver:  "sqlite",
+		refresh: defaultRefresh,
+	}
+	return s, testDB{t: t, db: db}
+}
+
+func TestSQL(t *testing.T) {
+	ns := []dns.RR{test.NS("example.org. 3600 IN NS ns1.example.org.")}
+
+	s, db := newSQL(t)
+	db.setZone("example.org", 2024010100, exampleOrg...)
+	if _, err := s.Refresh(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []test.Case{
+		{
+			Qname: "www.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example.org. 300 IN A 192.0.2.1")},
+			Ns:     ns,
+		},
+		{
+			Qname: "alias.example.org.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{
+				test.CNAME("alias.example.org. 300 IN CNAME www.example.org."),
+				test.AAAA("www.example.org. 300 IN AAAA 2001:db8::1"),
+			},
+			Ns: ns,
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeMX,
+			Answer: []dns.RR{test.MX("example.org. 300 IN MX 10 mail.example.net.")},
+			Ns:     ns,
+		},
+		{
+			// The serial of the SOA record is the one from the zones table.
+			Qname: "example.org.", Qtype: dns.TypeSOA,
+			Answer: []dns.RR{test.SOA("example.org. 3600 IN SOA ns1.example.org. admin.example.org. 2024010100 3600 600 86400 300")},
+			Ns:     ns,
+		},
+		{
+			Qname: "nx.example.org.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{test.SOA("example.org. 3600 IN SOA ns1.example.org. admin.example.org. 2024010100 3600 600 86400 300")},
+		},
+		{
+			Qname: "www.sub.example.org.", Qtype: dns.TypeA,
+			Ns:    []dns.RR{test.NS("sub.example.org. 3600 IN NS ns.sub.example.org.")},
+			Extra: []dns.RR{test.A("ns.sub.example.org. 3600 IN A 192.0.2.54")},
+		},
+	}
+	for _, tc := range tests {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := s.ServeDNS(context.TODO(), rec, tc.Msg()); err != nil {
+			t.Fatalf("Expected no error for %s, got %s", tc.Qname, err)
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %s %s: %s", tc.Qname, dns.TypeToString[tc.Qtype], err)
+		}
+	}
+
+	// Names outside of the zones in the database are refused.
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.net.", dns.TypeA)
+	if rcode, _ := s.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m); rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED for a zone that isn't in the database, got %s", dns.RcodeToString[rcode])
+	}
+}
+
+func TestSQLRefresh(t *testing.T) {
+	s, db := newSQL(t)
+	db.setZone("example.org.", 1, exampleOrg...)
+	db.setZone("example.net.", 1, "@ SOA 3600 ns admin 1 3600 600 86400 300", "www A 300 192.0.2.10")
+
+	changed, err := s.Refresh(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 2 || len(s.Names()) != 2 {
+		t.Fatalf("Expected 2 zones to be loaded, got %v", changed)
+	}
+
+	// Nothing changed.
+	if changed, _ = s.Refresh(context.TODO()); len(changed) != 0 {
+		t.Errorf("Expected no changed zones, got %v", changed)
+	}
+
+	// A new serial reloads the zone, a removed zone is dropped.
+	db.setZone("example.org.", 2, append(exampleOrg, "new A 300 192.0.2.2")...)
+	db.removeZone("example.net.")
+	if changed, _ = s.Refresh(context.TODO()); len(changed) != 1 || changed[0] != "example.org." {
+		t.Errorf("Expected example.org. to be changed, got %v", changed)
+	}
+	if names := s.Names(); len(names) != 1 || names[0] != "example.org." {
+		t.Errorf("Expected only example.org. to be loaded, got %v", names)
+	}
+	if got := lookup(t, s, "new.example.org."); len(got) != 1 {
+		t.Errorf("Expected new record to be served, got %v", got)
+	}
+
+	// A zone that fails to load keeps its previous snapshot.
+	db.setZone("example.org.", 3, append(exampleOrg, "bad A 300 not-an-address")...)
+	if changed, _ = s.Refresh(context.TODO()); len(changed) != 0 {
+		t.Errorf("Expected no changed zones, got %v", changed)
+	}
+	if got := lookup(t, s, "new.example.org."); len(got) != 1 {
+		t.Errorf("Expected previous snapshot to be served, got %v", got)
+	}
+}
+
+func TestSQLInvalidZones(t *testing.T) {
+	tests := map[string][]string{
+		"no SOA":           {"www A 300 192.0.2.1"},
+		"out of zone":      {"@ SOA 3600 ns1 admin 1 3600 600 86400 300", "www.example.net. A 300 192.0.2.1"},
+		"SOA not at apex":  {"@ SOA 3600 ns1 admin 1 3600 600 86400 300", "www SOA 3600 ns1 admin 1 3600 600 86400 300"},
+		"unknown type":     {"@ SOA 3600 ns1 admin 1 3600 600 86400 300", "www BLAH 300 192.0.2.1"},
+		"invalid rdata":    {"@ SOA 3600 ns1 admin 1 3600 600 86400 300", "www MX 300 mail"},
+		"invalid TTL type": {"@ SOA 3600 ns1 admin 1 3600 600 86400 300", "www A soon 192.0.2.1"},
+	}
+	for name, records := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, db := newSQL(t)
+			db.setZone("example.org.", 1, records...)
+			if _, err := s.Refresh(context.TODO()); err != nil {
+				t.Fatal(err)
+			}
+			if len(s.Names()) != 0 {
+				t.Errorf("Expected zone not to be loaded")
+			}
+		})
+	}
+}
+
+func TestSQLTransfer(t *testing.T) {
+	s, db := newSQL(t)
+	db.setZone("example.org.", 5, exampleOrg...)
+	s.Refresh(context.TODO())
+
+	ch, err := s.Transfer("example.org.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	var first, last dns.RR
+	for rrs := range ch {
+		for _, rr := range rrs {
+			if first == nil {
+				first = rr
+			}
+			last = rr
+			n++
+		}
+	}
+	// All records, plus the SOA record at the end.
+	if n != len(exampleOrg)+1 {
+		t.Errorf("Expected %d records, got %d", len(exampleOrg)+1, n)
+	}
+	if first.Header().Rrtype != dns.TypeSOA || last.Header().Rrtype != dns.TypeSOA || first.(*dns.SOA).Serial != 5 {
+		t.Errorf("Expected transfer to start and end with the SOA record with serial 5, got %s and %s", first, last)
+	}
+
+	if _, err := s.Transfer("example.net.", 0); err == nil {
+		t.Errorf("Expected error for a zone that isn't in the database")
+	}
+}
+
+func TestSQLDNSSEC(t *testing.T) {
+	s, db := newSQL(t)
+	db.setZone("example.org.", 1, exampleOrg...)
+	s.Refresh(context.TODO())
+
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	pub, private := filepath.Join(dir, "Kexample.org.key"), filepath.Join(dir, "Kexample.org.private")
+	os.WriteFile(pub, []byte(k.String()), 0644)
+	os.WriteFile(private, []byte(k.PrivateKeyString(priv)), 0600)
+	key, err := dnssec.ParseKeyFile(pub, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := dnssec.New([]string{"example.org."}, []*dnssec.DNSKEY{key}, false, s, cache.New(100))
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	m.SetEdns0(4096, true)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	d.ServeDNS(context.TODO(), rec, m)
+
+	sigs := 0
+	for _, rr := range rec.Msg.Answer {
+		if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == dns.TypeA {
+			sigs++
+		}
+	}
+	if sigs != 1 {
+		t.Errorf("Expected the answer to be signed, got %v", rec.Msg.Answer)
+	}
+}
+
+func lookup(t *testing.T, s *SQL, qname string) []dns.RR {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(qname, dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	s.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg == nil {
+		return nil
+	}
+	return rec.Msg.Answer
+}
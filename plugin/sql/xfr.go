@@ -0,0 +1,33 @@
+package sql
+
+import (
+	"github.com/coredns/coredns/plugin/transfer"
+
+	"github.com/miekg/dns"
+)
+
+// Transfer implements the transfer.Transfer interface.
+func (s *SQL) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	s.RLock()
+	z, ok := s.Z[zone]
+	s.RUnlock()
+
+	if !ok || z == nil {
+		return nil, transfer.ErrNotAuthoritative
+	}
+	return z.Transfer(serial)
+}
+
+// Notify sends notifies for zones, with secondaries configured with the transfer plugin.
+func (s *SQL) Notify(zones []string) error {
+	if s.transfer == nil {
+		return nil
+	}
+	var err error
+	for _, origin := range zones {
+		if e := s.transfer.Notify(origin); e != nil {
+			err = e
+		}
+	}
+	return err
+}
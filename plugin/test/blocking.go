@@ -0,0 +1,57 @@
+package test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Index keeps the index of a stand-in for an HTTP API with blocking queries, like the ones of Consul and Nomad:
+// a query with an index waits until the index moved past it.
+type Index struct {
+	mu      sync.Mutex
+	index   uint64
+	changed chan struct{}
+}
+
+// NewIndex returns an Index that starts at 1.
+func NewIndex() *Index { return &Index{index: 1, changed: make(chan struct{})} }
+
+// Change increments the index and wakes up the waiting queries.
+func (i *Index) Change() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.index++
+	close(i.changed)
+	i.changed = make(chan struct{})
+}
+
+// String returns the current index, to be set in a response header.
+func (i *Index) String() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return strconv.FormatUint(i.index, 10)
+}
+
+// Wait blocks while the index in query is current, until the index changes or a second passed. An empty or zero
+// query doesn't block. It returns false if ctx is done first.
+func (i *Index) Wait(ctx context.Context, query string) bool {
+	index, _ := strconv.ParseUint(query, 10, 64)
+	if index == 0 {
+		return true
+	}
+	i.mu.Lock()
+	current, changed := i.index, i.changed
+	i.mu.Unlock()
+	if index < current {
+		return true
+	}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+	case <-ctx.Done():
+		return false
+	}
+	return true
+}
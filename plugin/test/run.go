@@ -0,0 +1,43 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+)
+
+// Run sends the query of each case to h and checks the response against the case with SortAndCheck.
+func Run(t *testing.T, h Handler, cases []Case) {
+	t.Helper()
+	for i, tc := range cases {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&ResponseWriter{})
+
+		if _, err := h.ServeDNS(context.Background(), w, r); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+			continue
+		}
+		if w.Msg == nil {
+			t.Errorf("Test %d: no response written", i)
+			continue
+		}
+		if err := SortAndCheck(w.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
+
+// WaitFor calls f until it returns true, and fails the test if it doesn't within 5 seconds. It is meant for
+// plugins that fill a cache in the background.
+func WaitFor(t *testing.T, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the condition")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
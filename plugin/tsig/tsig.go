@@ -75,6 +75,7 @@ func (t *TSIGServer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.
 	}
 
 	if rcode == dns.RcodeSuccess {
+		ctx = context.WithValue(ctx, keyNameKey{}, tsigRR.Hdr.Name)
 		rcode, err = plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
 		if err != nil {
 			log.Errorf("request handler returned an error: %v\n", err)
@@ -98,6 +99,15 @@ func (t *TSIGServer) tsigRequired(qtype uint16) bool {
 	return false
 }
 
+type keyNameKey struct{}
+
+// KeyName returns the name of the TSIG key the request being handled was signed with. It returns
+// the empty string if the request wasn't signed, or if the *tsig* plugin didn't validate it.
+func KeyName(ctx context.Context) string {
+	k, _ := ctx.Value(keyNameKey{}).(string)
+	return k
+}
+
 // restoreTsigWriter Implement Response Writer, and adds a TSIG RR to a response
 type restoreTsigWriter struct {
 	dns.ResponseWriter
@@ -224,6 +224,34 @@ func TestServeDNSTsigErrors(t *testing.T) {
 	}
 }
 
+func TestKeyName(t *testing.T) {
+	key := ""
+	tsig := TSIGServer{
+		Zones: []string{"."},
+		Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			key = KeyName(ctx)
+			return testHandler()(ctx, w, r)
+		}),
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion("test.example.", dns.TypeA)
+	if _, err := tsig.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r); err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		t.Errorf("Expected no key name for unsigned request, got %q", key)
+	}
+
+	r.SetTsig("test.key.", dns.HmacSHA256, 300, time.Now().Unix())
+	if _, err := tsig.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r); err != nil {
+		t.Fatal(err)
+	}
+	if key != "test.key." {
+		t.Errorf("Expected key name %q, got %q", "test.key.", key)
+	}
+}
+
 func testHandler() test.HandlerFunc {
 	return func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
 		state := request.Request{W: w, Req: r}
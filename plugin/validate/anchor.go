@@ -0,0 +1,112 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// trustAnchor is the XML format of trust anchors defined in RFC 7958, as published by IANA for the root zone.
+type trustAnchor struct {
+	Zone       string      `xml:"Zone"`
+	KeyDigests []keyDigest `xml:"KeyDigest"`
+}
+
+type keyDigest struct {
+	ValidFrom  string `xml:"validFrom,attr"`
+	ValidUntil string `xml:"validUntil,attr"`
+	KeyTag     uint16 `xml:"KeyTag"`
+	Algorithm  uint8  `xml:"Algorithm"`
+	DigestType uint8  `xml:"DigestType"`
+	Digest     string `xml:"Digest"`
+}
+
+// parseAnchors parses the trust anchors in r, which are either in the XML format of RFC 7958, or DS and
+// DNSKEY records in zone file format. Key digests in the XML format that aren't valid at now are skipped.
+// The anchors are returned by zone.
+func parseAnchors(r io.Reader, file string, now time.Time) (map[string][]dns.RR, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(buf), []byte("<")) {
+		return parseXMLAnchors(buf, file, now)
+	}
+
+	anchors := map[string][]dns.RR{}
+	zp := dns.NewZoneParser(bytes.NewReader(buf), ".", file)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch x := rr.(type) {
+		case *dns.DS:
+		case *dns.DNSKEY:
+			if x.Flags&dns.ZONE == 0 {
+				return nil, fmt.Errorf("DNSKEY for %s in %q is not a zone key", x.Header().Name, file)
+			}
+		default:
+			return nil, fmt.Errorf("%s record for %s in %q is not a trust anchor", dns.TypeToString[rr.Header().Rrtype], rr.Header().Name, file)
+		}
+		name := strings.ToLower(rr.Header().Name)
+		anchors[name] = append(anchors[name], rr)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if len(anchors) == 0 {
+		return nil, fmt.Errorf("no trust anchors in %q", file)
+	}
+	return anchors, nil
+}
+
+func parseXMLAnchors(buf []byte, file string, now time.Time) (map[string][]dns.RR, error) {
+	ta := trustAnchor{}
+	if err := xml.Unmarshal(buf, &ta); err != nil {
+		return nil, fmt.Errorf("trust anchor %q: %s", file, err)
+	}
+	if _, ok := dns.IsDomainName(ta.Zone); !ok || ta.Zone == "" {
+		return nil, fmt.Errorf("trust anchor %q: invalid zone %q", file, ta.Zone)
+	}
+	zone := strings.ToLower(dns.Fqdn(ta.Zone))
+
+	anchors := map[string][]dns.RR{}
+	for _, kd := range ta.KeyDigests {
+		if kd.ValidFrom != "" {
+			from, err := time.Parse(time.RFC3339, kd.ValidFrom)
+			if err != nil {
+				return nil, fmt.Errorf("trust anchor %q: %s", file, err)
+			}
+			if now.Before(from) {
+				continue
+			}
+		}
+		if kd.ValidUntil != "" {
+			until, err := time.Parse(time.RFC3339, kd.ValidUntil)
+			if err != nil {
+				return nil, fmt.Errorf("trust anchor %q: %s", file, err)
+			}
+			if !now.Before(until) {
+				continue
+			}
+		}
+		digest := strings.TrimSpace(kd.Digest)
+		if _, err := hex.DecodeString(digest); err != nil {
+			return nil, fmt.Errorf("trust anchor %q: invalid digest for key %d", file, kd.KeyTag)
+		}
+		anchors[zone] = append(anchors[zone], &dns.DS{
+			Hdr:        dns.RR_Header{Name: zone, Rrtype: dns.TypeDS, Class: dns.ClassINET},
+			KeyTag:     kd.KeyTag,
+			Algorithm:  kd.Algorithm,
+			DigestType: kd.DigestType,
+			Digest:     strings.ToUpper(digest),
+		})
+	}
+	if len(anchors) == 0 {
+		return nil, fmt.Errorf("no valid trust anchors in %q", file)
+	}
+	return anchors, nil
+}
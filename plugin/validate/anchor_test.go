@@ -0,0 +1,82 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// From https://data.iana.org/root-anchors/root-anchors.xml.
+const rootAnchors = `<?xml version="1.0" encoding="UTF-8"?>
+<TrustAnchor id="380DC50D-484E-40D0-A3AE-68F2B18F61C7" source="http://data.iana.org/root-anchors/root-anchors.xml">
+<Zone>.</Zone>
+<KeyDigest id="Kjqmt7v" validFrom="2010-07-15T00:00:00+00:00" validUntil="2019-01-11T00:00:00+00:00">
+<KeyTag>19036</KeyTag>
+<Algorithm>8</Algorithm>
+<DigestType>2</DigestType>
+<Digest>49AAC11D7B6F6446702E54A1607371607A1A41855200FD2CE1CDDE32F24E8FB5</Digest>
+</KeyDigest>
+<KeyDigest id="Klajeyz" validFrom="2017-02-02T00:00:00+00:00">
+<KeyTag>20326</KeyTag>
+<Algorithm>8</Algorithm>
+<DigestType>2</DigestType>
+<Digest>E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D</Digest>
+</KeyDigest>
+</TrustAnchor>
+`
+
+func TestParseAnchorsXML(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	anchors, err := parseAnchors(strings.NewReader(rootAnchors), "root-anchors.xml", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anchors["."]) != 1 {
+		t.Fatalf("Expected 1 valid trust anchor for the root zone, got %d", len(anchors["."]))
+	}
+	ds := anchors["."][0].(*dns.DS)
+	if ds.KeyTag != 20326 || ds.Algorithm != dns.RSASHA256 || ds.DigestType != dns.SHA256 {
+		t.Errorf("Expected DS for key 20326, got %s", ds)
+	}
+
+	// Before 2017 only the first key is valid.
+	anchors, err = parseAnchors(strings.NewReader(rootAnchors), "root-anchors.xml", time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := anchors["."][0].(*dns.DS).KeyTag; len(anchors["."]) != 1 || x != 19036 {
+		t.Errorf("Expected only trust anchor for key 19036, got %v", anchors["."])
+	}
+}
+
+func TestParseAnchors(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     int
+	}{
+		{`. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D`, false, 1},
+		{`example.org. IN DS 60485 13 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A
+example.net. IN DNSKEY 257 3 13 sfzRg5nDVxbeUc51su4MzjgwpOpUwnuu81SlRHqJuXe3SOYOeypR69tZ52XLmE56TAmPHsiB8Rgk+NTpf0o1Cw==`, false, 2},
+		// errors
+		{``, true, 0},
+		{`example.org. IN A 192.0.2.1`, true, 0},
+		{`example.org. IN DNSKEY 0 3 13 sfzRg5nDVxbeUc51su4MzjgwpOpUwnuu81SlRHqJuXe3SOYOeypR69tZ52XLmE56TAmPHsiB8Rgk+NTpf0o1Cw==`, true, 0},
+		{`<TrustAnchor><Zone>.</Zone></TrustAnchor>`, true, 0},
+		{`<TrustAnchor><Zone>.</Zone><KeyDigest><KeyTag>1</KeyTag><Algorithm>8</Algorithm><DigestType>2</DigestType><Digest>XYZ</Digest></KeyDigest></TrustAnchor>`, true, 0},
+	}
+	for i, tc := range tests {
+		anchors, err := parseAnchors(strings.NewReader(tc.input), "stdin", time.Now())
+		if err == nil && tc.shouldErr {
+			t.Errorf("Test %d expected errors, but got no error", i)
+		}
+		if err != nil && !tc.shouldErr {
+			t.Errorf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if len(anchors) != tc.zones {
+			t.Errorf("Test %d expected trust anchors for %d zones, got %d", i, tc.zones, len(anchors))
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	maxDepth = 30        // Longest chain of trust we follow, a name can't have more labels than this anyway.
+	minTTL   = 5         // Minimum time, in seconds, validated keys are cached.
+	maxTTL   = 3600 * 24 // Maximum time, in seconds, validated keys are cached.
+)
+
+// zoneKeys holds the validated DNSKEYs of a zone. If there are none, the zone is insecure.
+type zoneKeys struct {
+	keys   []*dns.DNSKEY
+	expire time.Time
+}
+
+func (z *zoneKeys) insecure() bool { return len(z.keys) == 0 }
+
+// zoneKeys returns the validated keys of zone, building the chain of trust from the closest trust anchor
+// above zone. The keys are cached until their TTL expires.
+func (v *Validate) zoneKeys(ctx context.Context, w dns.ResponseWriter, zone string, depth int) (*zoneKeys, error) {
+	if depth > maxDepth {
+		return nil, bogusf(dns.ExtendedErrorCodeDNSBogus, "Chain of trust for %s is too long", zone)
+	}
+	zone = strings.ToLower(zone)
+	key := cache.Hash([]byte(zone))
+	if zk, ok := v.keys.Get(key); ok && v.now().Before(zk.(*zoneKeys).expire) {
+		return zk.(*zoneKeys), nil
+	}
+
+	var (
+		zk  *zoneKeys
+		err error
+	)
+	switch anchors, ok := v.anchors[zone]; {
+	case ok:
+		zk, err = v.anchoredKeys(ctx, w, zone, anchors)
+	case v.anchor(zone) == "":
+		zk = &zoneKeys{expire: v.now().Add(maxTTL * time.Second)}
+	default:
+		zk, err = v.delegatedKeys(ctx, w, zone, depth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.keys.Add(key, zk)
+	return zk, nil
+}
+
+// anchoredKeys returns the keys of zone, which has trust anchors.
+func (v *Validate) anchoredKeys(ctx context.Context, w dns.ResponseWriter, zone string, anchors []dns.RR) (*zoneKeys, error) {
+	keys, ttl, err := v.dnskeys(ctx, w, zone, anchors)
+	if err != nil {
+		return nil, err
+	}
+	return &zoneKeys{keys: keys, expire: v.expire(ttl)}, nil
+}
+
+// delegatedKeys returns the keys of zone, which are validated with the DS records in its parent. If the
+// parent proves that there are no DS records, or only ones we don't support, the zone is insecure.
+func (v *Validate) delegatedKeys(ctx context.Context, w dns.ResponseWriter, zone string, depth int) (*zoneKeys, error) {
+	m, err := v.query(ctx, w, zone, dns.TypeDS)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &rrset{}
+	for _, s := range rrsets(m.Answer) {
+		if s.rrtype() == dns.TypeDS && strings.EqualFold(s.name(), zone) {
+			ds = s
+		}
+	}
+
+	if len(ds.rrs) == 0 {
+		// No DS records: zone is an insecure delegation, or the parent is insecure.
+		ok, err := v.insecure(ctx, w, zone, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, bogusf(dns.ExtendedErrorCodeDNSKEYMissing, "No DS records for %s", zone)
+		}
+		return &zoneKeys{expire: v.expire(minimalTTL(m.Ns))}, nil
+	}
+
+	if len(ds.sigs) == 0 {
+		// Unsigned DS records are fine in an insecure parent only.
+		i, _ := dns.NextLabel(zone, 0)
+		ok, err := v.insecure(ctx, w, zone[i:], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, bogusf(dns.ExtendedErrorCodeRRSIGsMissing, "No signatures for %s DS", zone)
+		}
+		return &zoneKeys{expire: v.expire(minimalTTL(ds.rrs))}, nil
+	}
+	res, err := v.verify(ctx, w, ds, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	if res == insecure {
+		return &zoneKeys{expire: v.expire(minimalTTL(ds.rrs))}, nil
+	}
+
+	supported := []dns.RR{}
+	for _, rr := range ds.rrs {
+		if x := rr.(*dns.DS); supportedDS(x) {
+			supported = append(supported, x)
+		}
+	}
+	if len(supported) == 0 {
+		// RFC 4035 section 5.2: a zone with only unsupported algorithms is treated as insecure.
+		return &zoneKeys{expire: v.expire(minimalTTL(ds.rrs))}, nil
+	}
+
+	keys, ttl, err := v.dnskeys(ctx, w, zone, supported)
+	if err != nil {
+		return nil, err
+	}
+	return &zoneKeys{keys: keys, expire: v.expire(min(ttl, minimalTTL(ds.rrs)))}, nil
+}
+
+// dnskeys queries the DNSKEY records of zone, and validates them with trusted, the DS or DNSKEY records that
+// are trusted for zone. The DNSKEY RRset must be signed by one of the trusted keys.
+func (v *Validate) dnskeys(ctx context.Context, w dns.ResponseWriter, zone string, trusted []dns.RR) ([]*dns.DNSKEY, uint32, error) {
+	m, err := v.query(ctx, w, zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, 0, err
+	}
+	set := &rrset{}
+	for _, s := range rrsets(m.Answer) {
+		if s.rrtype() == dns.TypeDNSKEY && strings.EqualFold(s.name(), zone) {
+			set = s
+		}
+	}
+
+	keys, sep := []*dns.DNSKEY{}, []*dns.DNSKEY{}
+	for _, rr := range set.rrs {
+		k := rr.(*dns.DNSKEY)
+		if k.Flags&dns.ZONE == 0 || k.Protocol != 3 {
+			continue
+		}
+		keys = append(keys, k)
+		if trustedKey(k, trusted) {
+			sep = append(sep, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, 0, bogusf(dns.ExtendedErrorCodeDNSKEYMissing, "No DNSKEY records for %s", zone)
+	}
+	if len(sep) == 0 {
+		return nil, 0, bogusf(dns.ExtendedErrorCodeDNSKEYMissing, "No DNSKEY of %s matches its DS records or trust anchors", zone)
+	}
+	if err := verifyRRset(set, sep, v.now()); err != nil {
+		return nil, 0, err
+	}
+	return keys, minimalTTL(set.rrs), nil
+}
+
+// insecure returns true if name is in an insecure zone: there is no trust anchor above it, or there is proof
+// that a zone between the trust anchor and name isn't signed.
+func (v *Validate) insecure(ctx context.Context, w dns.ResponseWriter, name string, depth int) (bool, error) {
+	if depth > maxDepth {
+		return false, bogusf(dns.ExtendedErrorCodeDNSBogus, "Chain of trust for %s is too long", name)
+	}
+	name = strings.ToLower(name)
+	anchor := v.anchor(name)
+	if anchor == "" {
+		return true, nil
+	}
+
+	m, err := v.query(ctx, w, name, dns.TypeDS)
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range rrsets(m.Answer) {
+		if s.rrtype() == dns.TypeDS && strings.EqualFold(s.name(), name) {
+			// name is a zone cut, its keys tell us if it is insecure.
+			zk, err := v.zoneKeys(ctx, w, name, depth+1)
+			if err != nil {
+				return false, err
+			}
+			return zk.insecure(), nil
+		}
+	}
+
+	// A negative response, the zone it comes from must be secure and the proof must show name is an
+	// insecure delegation.
+	auth := []*rrset{}
+	for _, s := range rrsets(m.Ns) {
+		if s.rrtype() != dns.TypeNS {
+			auth = append(auth, s)
+		}
+	}
+	signed := false
+	for _, s := range auth {
+		if len(s.sigs) > 0 {
+			signed = true
+		}
+	}
+	if !signed {
+		// The response is from an unsigned zone, which must be insecure itself.
+		if name == anchor {
+			return false, bogusf(dns.ExtendedErrorCodeRRSIGsMissing, "No signatures in the DS response for %s", name)
+		}
+		i, _ := dns.NextLabel(name, 0)
+		return v.insecure(ctx, w, name[i:], depth+1)
+	}
+
+	for _, s := range auth {
+		res, err := v.verify(ctx, w, s, depth+1)
+		if err != nil {
+			return false, err
+		}
+		if res == insecure {
+			return true, nil
+		}
+	}
+
+	d := newDenial(m.Ns)
+	if x := d.matchNSEC(name); x != nil {
+		return isDelegation(x.TypeBitMap), nil
+	}
+	if x := d.match3(name); x != nil {
+		return isDelegation(x.TypeBitMap), nil
+	}
+	if _, ok := d.closestEncloser(name); ok && d.optout {
+		return true, nil
+	}
+	return false, nil
+}
+
+// isDelegation returns true if types are the types at an insecure delegation.
+func isDelegation(types []uint16) bool {
+	ns, ds, soa := false, false, false
+	for _, t := range types {
+		switch t {
+		case dns.TypeNS:
+			ns = true
+		case dns.TypeDS:
+			ds = true
+		case dns.TypeSOA:
+			soa = true
+		}
+	}
+	return ns && !ds && !soa
+}
+
+// query sends a query for name and qtype to the next plugin.
+func (v *Validate) query(ctx context.Context, w dns.ResponseWriter, name string, qtype uint16) (*dns.Msg, error) {
+	req := new(dns.Msg)
+	req.SetQuestion(name, qtype)
+	req.SetEdns0(dns.DefaultMsgSize, true)
+	req.CheckingDisabled = true
+
+	m, rcode, err := v.next(ctx, w, req)
+	if m == nil {
+		if err == nil {
+			err = fmt.Errorf("%s", dns.RcodeToString[rcode])
+		}
+		return nil, bogusf(dns.ExtendedErrorCodeNoReachableAuthority, "Failed to query %s %s: %s", name, dns.TypeToString[qtype], err)
+	}
+	if m.Rcode != dns.RcodeSuccess && m.Rcode != dns.RcodeNameError {
+		return nil, bogusf(dns.ExtendedErrorCodeNoReachableAuthority, "Failed to query %s %s: %s", name, dns.TypeToString[qtype], dns.RcodeToString[m.Rcode])
+	}
+	if m.Truncated {
+		return nil, bogusf(dns.ExtendedErrorCodeNoReachableAuthority, "Failed to query %s %s: truncated, even over TCP", name, dns.TypeToString[qtype])
+	}
+	return m, nil
+}
+
+// anchor returns the closest zone at or above name that has trust anchors, or the empty string if there is none.
+func (v *Validate) anchor(name string) string {
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		if _, ok := v.anchors[name[off:]]; ok {
+			return name[off:]
+		}
+	}
+	if _, ok := v.anchors["."]; ok {
+		return "."
+	}
+	return ""
+}
+
+// expire returns the time keys with ttl expire from the cache.
+func (v *Validate) expire(ttl uint32) time.Time {
+	return v.now().Add(time.Duration(min(max(ttl, minTTL), maxTTL)) * time.Second)
+}
+
+// trustedKey returns true if k matches one of the DS or DNSKEY records in trusted.
+func trustedKey(k *dns.DNSKEY, trusted []dns.RR) bool {
+	for _, rr := range trusted {
+		switch x := rr.(type) {
+		case *dns.DS:
+			if x.KeyTag != k.KeyTag() || x.Algorithm != k.Algorithm {
+				continue
+			}
+			if ds := k.ToDS(x.DigestType); ds != nil && strings.EqualFold(ds.Digest, x.Digest) {
+				return true
+			}
+		case *dns.DNSKEY:
+			if x.Algorithm == k.Algorithm && x.PublicKey == k.PublicKey && x.Flags == k.Flags {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// supportedDS returns true if we can validate the keys of a DS record with the algorithm and digest type of ds.
+func supportedDS(ds *dns.DS) bool {
+	switch ds.DigestType {
+	case dns.SHA1, dns.SHA256, dns.SHA384:
+	default:
+		return false
+	}
+	switch ds.Algorithm {
+	case dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512, dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+		return true
+	}
+	return false
+}
+
+// minimalTTL returns the smallest TTL of the records in rrs, or maxTTL if there are none.
+func minimalTTL(rrs []dns.RR) uint32 {
+	ttl := uint32(maxTTL)
+	for _, rr := range rrs {
+		ttl = min(ttl, rr.Header().Ttl)
+	}
+	return ttl
+}
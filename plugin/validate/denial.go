@@ -0,0 +1,219 @@
+package validate
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// maxIterations is the largest number of NSEC3 iterations we compute hashes for. Responses with more
+// iterations are treated as insecure, see RFC 9276 section 3.2.
+const maxIterations = 150
+
+// denial holds the NSEC and NSEC3 records in the authority section of a response, which prove that names or
+// types don't exist (RFC 4035 section 5.4 and RFC 5155 section 8). The records must have been verified.
+type denial struct {
+	nsec  []*dns.NSEC
+	nsec3 []*dns.NSEC3
+
+	// optout is set when a proof relies on an NSEC3 record with the opt-out flag, or with too many
+	// iterations. The response is insecure then.
+	optout bool
+}
+
+func newDenial(rrs []dns.RR) *denial {
+	d := &denial{}
+	for _, rr := range rrs {
+		switch x := rr.(type) {
+		case *dns.NSEC:
+			d.nsec = append(d.nsec, x)
+		case *dns.NSEC3:
+			if x.Hash != dns.SHA1 {
+				continue
+			}
+			if x.Iterations > maxIterations {
+				d.optout = true
+				continue
+			}
+			d.nsec3 = append(d.nsec3, x)
+		}
+	}
+	return d
+}
+
+// result returns the security status of the response the proofs were taken from.
+func (d *denial) result() result {
+	if d.optout {
+		return insecure
+	}
+	return secure
+}
+
+// nxdomain returns true if qname doesn't exist, nor a wildcard that could have been used to answer it.
+func (d *denial) nxdomain(qname string) bool {
+	if d.optout && len(d.nsec)+len(d.nsec3) == 0 {
+		return true
+	}
+	if len(d.nsec3) > 0 {
+		ce, ok := d.closestEncloser(qname)
+		return ok && d.cover3(wildcardName(ce)) != nil
+	}
+	n := d.coverNSEC(qname)
+	if n == nil {
+		return false
+	}
+	return d.coverNSEC(wildcardName(closestEncloser(qname, n))) != nil
+}
+
+// nodata returns true if qname exists, but doesn't have qtype. This includes the case where qname is
+// synthesized from a wildcard that doesn't have qtype.
+func (d *denial) nodata(qname string, qtype uint16) bool {
+	if d.optout && len(d.nsec)+len(d.nsec3) == 0 {
+		return true
+	}
+	if len(d.nsec3) > 0 {
+		if x := d.match3(qname); x != nil {
+			return !hasType(x.TypeBitMap, qtype)
+		}
+		ce, ok := d.closestEncloser(qname)
+		if !ok {
+			return false
+		}
+		// An insecure delegation covered by an opt-out NSEC3 record, RFC 5155 section 8.6.
+		if qtype == dns.TypeDS && d.optout {
+			return true
+		}
+		x := d.match3(wildcardName(ce))
+		return x != nil && !hasType(x.TypeBitMap, qtype)
+	}
+
+	if x := d.matchNSEC(qname); x != nil {
+		return !hasType(x.TypeBitMap, qtype)
+	}
+	n := d.coverNSEC(qname)
+	if n == nil {
+		return false
+	}
+	// An empty non-terminal: the next name is below qname, RFC 4035 section 3.1.3.2.
+	if dns.IsSubDomain(qname, n.NextDomain) {
+		return true
+	}
+	x := d.matchNSEC(wildcardName(closestEncloser(qname, n)))
+	return x != nil && !hasType(x.TypeBitMap, qtype)
+}
+
+// wildcard returns true if qname, which is answered from a wildcard whose signature has labels labels,
+// doesn't exist itself.
+func (d *denial) wildcard(qname string, labels uint8) bool {
+	if len(d.nsec3) > 0 {
+		// The next closer name is the closest encloser, the name the wildcard is at, with one more label.
+		i, _ := dns.PrevLabel(qname, int(labels)+1)
+		x := d.cover3(qname[i:])
+		if x != nil && x.Flags&1 == 1 {
+			d.optout = true
+		}
+		return x != nil
+	}
+	return d.coverNSEC(qname) != nil
+}
+
+// closestEncloser returns the closest encloser of qname proven by the NSEC3 records, see RFC 5155 section
+// 8.3: an ancestor with a matching NSEC3 record and a next closer name that is covered.
+func (d *denial) closestEncloser(qname string) (string, bool) {
+	labels := dns.Split(qname)
+	for i := 1; i <= len(labels); i++ {
+		ce := "."
+		if i < len(labels) {
+			ce = qname[labels[i]:]
+		}
+		if d.match3(ce) == nil {
+			continue
+		}
+		x := d.cover3(qname[labels[i-1]:])
+		if x == nil {
+			return "", false
+		}
+		if x.Flags&1 == 1 {
+			d.optout = true
+		}
+		return ce, true
+	}
+	return "", false
+}
+
+// match3 returns the NSEC3 record matching name, or nil if there is none.
+func (d *denial) match3(name string) *dns.NSEC3 {
+	for _, x := range d.nsec3 {
+		if x.Match(name) {
+			return x
+		}
+	}
+	return nil
+}
+
+// cover3 returns the NSEC3 record covering name, or nil if there is none. Note that NSEC3.Cover also
+// returns true for the record matching name.
+func (d *denial) cover3(name string) *dns.NSEC3 {
+	for _, x := range d.nsec3 {
+		if x.Cover(name) && !x.Match(name) {
+			return x
+		}
+	}
+	return nil
+}
+
+// matchNSEC returns the NSEC record with owner name, or nil if there is none.
+func (d *denial) matchNSEC(name string) *dns.NSEC {
+	for _, x := range d.nsec {
+		if strings.EqualFold(x.Header().Name, name) {
+			return x
+		}
+	}
+	return nil
+}
+
+// coverNSEC returns the NSEC record covering name, or nil if there is none. The last NSEC record in a zone
+// has the apex as the next name, and covers all names after its owner name.
+func (d *denial) coverNSEC(name string) *dns.NSEC {
+	for _, x := range d.nsec {
+		owner, next := x.Header().Name, x.NextDomain
+		if compare(owner, name) >= 0 {
+			continue
+		}
+		if compare(name, next) < 0 || compare(next, owner) <= 0 && dns.IsSubDomain(next, name) {
+			return x
+		}
+	}
+	return nil
+}
+
+// closestEncloser returns the closest encloser of qname, which is covered by n: the longest ancestor that
+// qname shares with the owner or the next name of n.
+func closestEncloser(qname string, n *dns.NSEC) string {
+	ce := ""
+	for _, name := range []string{n.Header().Name, n.NextDomain} {
+		labels := dns.CompareDomainName(qname, name)
+		if i, _ := dns.PrevLabel(qname, labels); len(qname)-i > len(ce) {
+			ce = qname[i:]
+		}
+	}
+	return ce
+}
+
+// wildcardName returns the name of the wildcard at ce.
+func wildcardName(ce string) string {
+	if ce == "." {
+		return "*."
+	}
+	return "*." + ce
+}
+
+// hasType returns true if types holds qtype, or CNAME which would have been returned instead.
+func hasType(types []uint16, qtype uint16) bool {
+	for _, t := range types {
+		if t == qtype || t == dns.TypeCNAME {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// NSEC3 chain (no salt, no extra iterations) of a zone with the names example.org, ns, a, b.c, *.w, deleg
+// (an insecure delegation) and secure (a secure delegation).
+const nsec3Chain = `1s1pi9tjgnu6e58j6vburdor8b34boav.example.org. 300 IN NSEC3 1 0 0 - 3GEMHHITRH1RLDACME4C3L7B2DFDER2C A RRSIG
+3gemhhitrh1rldacme4c3l7b2dfder2c.example.org. 300 IN NSEC3 1 0 0 - 5VQM4IQG11NEC1VV12HP2AONVG05A83I NS RRSIG
+5vqm4iqg11nec1vv12hp2aonvg05a83i.example.org. 300 IN NSEC3 1 0 0 - 6HSUDPCUGOVCSU6RIB34SA6RM87TQM57 A RRSIG
+6hsudpcugovcsu6rib34sa6rm87tqm57.example.org. 300 IN NSEC3 1 0 0 - 8UM1KJCJMOFVVMQ7CB0OP7JT39LG8R9J A RRSIG
+8um1kjcjmofvvmq7cb0op7jt39lg8r9j.example.org. 300 IN NSEC3 1 0 0 - GQO7H7R357FJ31QJIUDOG4AMTM030PLU NS SOA RRSIG NSEC3PARAM
+gqo7h7r357fj31qjiudog4amtm030plu.example.org. 300 IN NSEC3 1 0 0 - H0K0TC6LVJGBU028K6QCVDUJ3JT9URL5
+h0k0tc6lvjgbu028k6qcvduj3jt9url5.example.org. 300 IN NSEC3 1 0 0 - JRFH8DK3OOFI50C0CT4KAU7H45DL0K8C NS DS RRSIG
+jrfh8dk3oofi50c0ct4kau7h45dl0k8c.example.org. 300 IN NSEC3 1 0 0 - L9QCRTNKG05MBACGV440V6VLRI1DUP6M
+l9qcrtnkg05mbacgv440v6vlri1dup6m.example.org. 300 IN NSEC3 1 0 0 - 1S1PI9TJGNU6E58J6VBURDOR8B34BOAV A RRSIG
+`
+
+func TestDenialNSEC3(t *testing.T) {
+	rrs := []dns.RR{}
+	zp := dns.NewZoneParser(strings.NewReader(nsec3Chain), "", "stdin")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+
+	d := newDenial(rrs)
+	if !d.nxdomain("x.example.org.") || !d.nxdomain("x.y.a.example.org.") {
+		t.Errorf("Expected proof that x.example.org. and x.y.a.example.org. don't exist")
+	}
+	if d.nxdomain("a.example.org.") || d.nxdomain("foo.w.example.org.") {
+		t.Errorf("Expected no proof that a.example.org. or foo.w.example.org. don't exist")
+	}
+	if !d.nodata("a.example.org.", dns.TypeTXT) || !d.nodata("c.example.org.", dns.TypeA) {
+		t.Errorf("Expected proof that a.example.org. TXT and c.example.org. A don't exist")
+	}
+	if d.nodata("a.example.org.", dns.TypeA) {
+		t.Errorf("Expected no proof that a.example.org. A doesn't exist")
+	}
+	if !d.nodata("foo.w.example.org.", dns.TypeTXT) || d.nodata("foo.w.example.org.", dns.TypeA) {
+		t.Errorf("Expected only proof that foo.w.example.org. TXT doesn't exist")
+	}
+	if !d.wildcard("foo.w.example.org.", 3) {
+		t.Errorf("Expected proof that foo.w.example.org. is answered from a wildcard")
+	}
+	if d.result() != secure {
+		t.Errorf("Expected secure proofs without opt-out")
+	}
+}
+
+func TestDenialNSEC(t *testing.T) {
+	rrs := []dns.RR{}
+	for _, s := range []string{
+		"example.org. 300 IN NSEC a.example.org. NS SOA RRSIG NSEC DNSKEY",
+		"a.example.org. 300 IN NSEC b.c.example.org. A RRSIG NSEC",
+		"b.c.example.org. 300 IN NSEC *.w.example.org. A RRSIG NSEC",
+		"*.w.example.org. 300 IN NSEC example.org. A RRSIG NSEC",
+	} {
+		rr, _ := dns.NewRR(s)
+		rrs = append(rrs, rr)
+	}
+
+	d := newDenial(rrs)
+	if !d.nxdomain("b.example.org.") || !d.nxdomain("z.example.org.") {
+		t.Errorf("Expected proof that b.example.org. and z.example.org. don't exist")
+	}
+	if d.nxdomain("foo.w.example.org.") {
+		t.Errorf("Expected no proof that foo.w.example.org. doesn't exist, it matches the wildcard")
+	}
+	if !d.nodata("a.example.org.", dns.TypeTXT) || !d.nodata("c.example.org.", dns.TypeA) {
+		t.Errorf("Expected proof that a.example.org. TXT and c.example.org. A, an empty non-terminal, don't exist")
+	}
+	if !d.nodata("foo.w.example.org.", dns.TypeTXT) || d.nodata("foo.w.example.org.", dns.TypeA) {
+		t.Errorf("Expected only proof that foo.w.example.org. TXT doesn't exist")
+	}
+}
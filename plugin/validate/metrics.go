@@ -0,0 +1,16 @@
+package validate
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// responses is the number of validated responses by their security status.
+var responses = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "validate",
+	Name:      "responses_total",
+	Help:      "Counter of validated responses by their security status.",
+}, []string{"server", "result"})
@@ -0,0 +1,92 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("validate", setup) }
+
+const defaultCap = 10000 // default capacity of the key cache, in zones.
+
+func setup(c *caddy.Controller) error {
+	v, err := validateParse(c)
+	if err != nil {
+		return plugin.Error("validate", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		v.Next = next
+		return v
+	})
+
+	return nil
+}
+
+func validateParse(c *caddy.Controller) (*Validate, error) {
+	config := dnsserver.GetConfig(c)
+	zones := []string{}
+	anchors := map[string][]dns.RR{}
+	capacity := defaultCap
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		// validate [zones...]
+		zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "trust_anchor":
+				files := c.RemainingArgs()
+				if len(files) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, file := range files {
+					if !filepath.IsAbs(file) && config.Root != "" {
+						file = filepath.Join(config.Root, file)
+					}
+					f, err := os.Open(filepath.Clean(file))
+					if err != nil {
+						return nil, err
+					}
+					as, err := parseAnchors(f, file, time.Now().UTC())
+					f.Close()
+					if err != nil {
+						return nil, err
+					}
+					for zone, rrs := range as {
+						anchors[zone] = append(anchors[zone], rrs...)
+					}
+				}
+			case "cache_capacity":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(c.Val())
+				if err != nil || n <= 0 {
+					return nil, c.Errf("invalid cache_capacity %q", c.Val())
+				}
+				capacity = n
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+	}
+	if len(anchors) == 0 {
+		return nil, c.Err("no trust_anchor given")
+	}
+	return New(zones, anchors, capacity, nil), nil
+}
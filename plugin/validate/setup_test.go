@@ -0,0 +1,74 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	dir := t.TempDir()
+	anchor := filepath.Join(dir, "anchors")
+	if err := os.WriteFile(anchor, []byte(". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	xml := filepath.Join(dir, "root-anchors.xml")
+	if err := os.WriteFile(xml, []byte(rootAnchors), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     []string
+	}{
+		{`validate {
+			trust_anchor ` + anchor + `
+		}`, false, []string{}},
+		{`validate example.org {
+			trust_anchor ` + anchor + ` ` + xml + `
+			cache_capacity 100
+		}`, false, []string{"example.org."}},
+		// errors
+		{`validate`, true, nil},
+		{`validate {
+			trust_anchor
+		}`, true, nil},
+		{`validate {
+			trust_anchor /does/not/exist
+		}`, true, nil},
+		{`validate {
+			trust_anchor ` + anchor + `
+			cache_capacity -1
+		}`, true, nil},
+		{`validate {
+			trust_anchor ` + anchor + `
+			blah
+		}`, true, nil},
+		{`validate {
+			trust_anchor ` + anchor + `
+		}
+		validate`, true, nil},
+	}
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		v, err := validateParse(c)
+		if err == nil && tc.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		}
+		if err != nil && !tc.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if tc.shouldErr {
+			continue
+		}
+		if len(v.Zones) != len(tc.zones) || len(v.Zones) > 0 && v.Zones[0] != tc.zones[0] {
+			t.Errorf("Test %d expected zones %v, got %v", i, tc.zones, v.Zones)
+		}
+		if len(v.anchors["."]) == 0 {
+			t.Errorf("Test %d expected trust anchors for the root zone", i)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package validate implements a plugin that validates DNSSEC signed responses.
+package validate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("validate")
+
+// Validate validates the responses of the next plugin, usually forward, with DNSSEC (RFC 4035). The keys
+// needed to build the chains of trust are queried from the next plugin as well.
+type Validate struct {
+	Next  plugin.Handler
+	Zones []string
+
+	anchors map[string][]dns.RR // Trust anchors, DS or DNSKEY records, by zone.
+	keys    *cache.Cache        // Validated DNSKEYs by zone.
+
+	now func() time.Time
+}
+
+// New returns a new Validate with the trust anchors in anchors, caching the keys of at most capacity zones.
+func New(zones []string, anchors map[string][]dns.RR, capacity int, next plugin.Handler) *Validate {
+	return &Validate{Next: next, Zones: zones, anchors: anchors, keys: cache.New(capacity), now: time.Now}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (v *Validate) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones(v.Zones).Matches(state.Name()) == "" || r.CheckingDisabled {
+		// With the CD bit set the client does the validation itself.
+		return plugin.NextOrFailure(v.Name(), v.Next, ctx, w, r)
+	}
+
+	m, rcode, err := v.next(ctx, w, dnssecRequest(r))
+	if m == nil {
+		return rcode, err
+	}
+	m.Id = r.Id
+
+	server := metrics.WithServer(ctx)
+	res := insecure
+	switch {
+	case m.Truncated:
+		err = bogusf(dns.ExtendedErrorCodeDNSBogus, "Response for %s %s is truncated, even over TCP", state.Name(), state.Type())
+	case m.Rcode != dns.RcodeSuccess && m.Rcode != dns.RcodeNameError:
+	default:
+		res, err = v.validate(ctx, w, r, m)
+	}
+
+	if err != nil {
+		responses.WithLabelValues(server, "bogus").Inc()
+		log.Debugf("Response for %s %s is bogus: %s", state.Name(), state.Type(), err)
+
+		code, text := uint16(dns.ExtendedErrorCodeDNSBogus), err.Error()
+		var berr *bogusError
+		if errors.As(err, &berr) {
+			code = berr.code
+		}
+		fail := new(dns.Msg)
+		fail.SetRcode(r, dns.RcodeServerFailure)
+		edns.SetExtendedError(r, fail, code, text)
+		w.WriteMsg(fail)
+		return dns.RcodeSuccess, nil
+	}
+
+	responses.WithLabelValues(server, res.String()).Inc()
+	m.AuthenticatedData = res == secure && (state.Do() || r.AuthenticatedData)
+	m.CheckingDisabled = false
+	if !state.Do() {
+		strip(m, state.QType())
+	}
+	if !state.SizeAndDo(m) {
+		m.Extra = removeOPT(m.Extra)
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (v *Validate) Name() string { return "validate" }
+
+// next sends req to the next plugin and returns its response. A truncated response is retried over TCP,
+// as only a complete response can be validated; the server truncates it again for the client if needed.
+func (v *Validate) next(ctx context.Context, w dns.ResponseWriter, req *dns.Msg) (*dns.Msg, int, error) {
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(v.Name(), v.Next, ctx, nw, req)
+	if nw.Msg == nil || !nw.Msg.Truncated {
+		return nw.Msg, rcode, err
+	}
+	nw = nonwriter.New(tcpWriter{w})
+	rcode, err = plugin.NextOrFailure(v.Name(), v.Next, ctx, nw, req)
+	return nw.Msg, rcode, err
+}
+
+// tcpWriter is a ResponseWriter with a TCP client address, the next plugin then uses TCP as well.
+type tcpWriter struct {
+	dns.ResponseWriter
+}
+
+// RemoteAddr implements the dns.ResponseWriter interface.
+func (w tcpWriter) RemoteAddr() net.Addr {
+	if u, ok := w.ResponseWriter.RemoteAddr().(*net.UDPAddr); ok {
+		return &net.TCPAddr{IP: u.IP, Port: u.Port, Zone: u.Zone}
+	}
+	return w.ResponseWriter.RemoteAddr()
+}
+
+// dnssecRequest returns a copy of r that asks for DNSSEC records and disables validation upstream, so we
+// get to see bogus data as well.
+func dnssecRequest(r *dns.Msg) *dns.Msg {
+	req := r.Copy()
+	req.CheckingDisabled = true
+	if o := req.IsEdns0(); o != nil {
+		o.SetDo()
+		return req
+	}
+	req.SetEdns0(dns.DefaultMsgSize, true)
+	return req
+}
+
+// strip removes the DNSSEC records from m, for clients that didn't ask for them. Records of qtype are kept.
+func strip(m *dns.Msg, qtype uint16) {
+	filter := func(rrs []dns.RR) []dns.RR {
+		keep := rrs[:0]
+		for _, rr := range rrs {
+			switch t := rr.Header().Rrtype; t {
+			case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+				if t != qtype {
+					continue
+				}
+			}
+			keep = append(keep, rr)
+		}
+		return keep
+	}
+	m.Answer = filter(m.Answer)
+	m.Ns = filter(m.Ns)
+	m.Extra = filter(m.Extra)
+}
+
+// removeOPT returns rrs without the OPT record.
+func removeOPT(rrs []dns.RR) []dns.RR {
+	keep := rrs[:0]
+	for _, rr := range rrs {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			keep = append(keep, rr)
+		}
+	}
+	return keep
+}
@@ -0,0 +1,334 @@
+package validate
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const dbExampleOrg = `example.org.          3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300
+example.org.          3600 IN NS  ns.example.org.
+example.org.           300 IN NSEC a.example.org. NS SOA RRSIG NSEC DNSKEY
+a.example.org.        3600 IN A   192.0.2.1
+a.example.org.         300 IN NSEC bad.example.org. A RRSIG NSEC
+bad.example.org.      3600 IN A   192.0.2.2
+bad.example.org.       300 IN NSEC d.example.org. A RRSIG NSEC
+d.example.org.        3600 IN DNAME example.org.
+d.example.org.         300 IN NSEC insecure.example.org. DNAME RRSIG NSEC
+insecure.example.org. 3600 IN NS  ns.insecure.example.org.
+insecure.example.org.  300 IN NSEC ns.example.org. NS RRSIG NSEC
+ns.example.org.       3600 IN A   192.0.2.53
+ns.example.org.        300 IN NSEC secure.example.org. A RRSIG NSEC
+secure.example.org.   3600 IN NS  ns.secure.example.org.
+secure.example.org.    300 IN NSEC *.w.example.org. NS DS RRSIG NSEC
+*.w.example.org.      3600 IN A   192.0.2.3
+*.w.example.org.       300 IN NSEC example.org. A RRSIG NSEC
+`
+
+const dbSecureExampleOrg = `secure.example.org.     3600 IN SOA ns.secure.example.org. admin.example.org. 1 3600 600 86400 300
+secure.example.org.     3600 IN NS  ns.secure.example.org.
+secure.example.org.      300 IN NSEC ns.secure.example.org. NS SOA RRSIG NSEC DNSKEY
+ns.secure.example.org.  3600 IN A   192.0.2.54
+ns.secure.example.org.   300 IN NSEC www.secure.example.org. A RRSIG NSEC
+www.secure.example.org. 3600 IN A   192.0.2.4
+www.secure.example.org.  300 IN NSEC secure.example.org. A RRSIG NSEC
+`
+
+const dbInsecureExampleOrg = `insecure.example.org.     3600 IN SOA ns.insecure.example.org. admin.example.org. 1 3600 600 86400 300
+insecure.example.org.     3600 IN NS  ns.insecure.example.org.
+www.insecure.example.org. 3600 IN A   192.0.2.5
+`
+
+// key is a zone key for signing test zones.
+type key struct {
+	*dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newKey(t *testing.T, origin string) key {
+	t.Helper()
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key{k, priv.(crypto.Signer)}
+}
+
+// signZone returns the zone origin with the records in db, signed with k. The signatures are valid from an
+// hour before now, until a day after now. With k nil the zone is not signed.
+func signZone(t *testing.T, origin, db string, k *key, extra ...dns.RR) *file.Zone {
+	t.Helper()
+	rrs := []dns.RR{}
+	zp := dns.NewZoneParser(strings.NewReader(db), origin, "stdin")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rrs = append(rrs, extra...)
+	if k != nil {
+		rrs = append(rrs, k.DNSKEY)
+	}
+
+	z := file.NewZone(origin, "stdin")
+	for _, rr := range rrs {
+		if err := z.Insert(rr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if k == nil {
+		return z
+	}
+
+	cuts := []string{}
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeNS && rr.Header().Name != origin {
+			cuts = append(cuts, rr.Header().Name)
+		}
+	}
+	now := time.Now().UTC()
+	for _, s := range rrsets(rrs) {
+		if s.rrtype() == dns.TypeNS && s.name() != origin || below(s.name(), cuts) {
+			continue // delegations and glue are not signed
+		}
+		sig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Ttl: s.rrs[0].Header().Ttl},
+			Algorithm:  k.Algorithm,
+			KeyTag:     k.KeyTag(),
+			SignerName: origin,
+			Inception:  uint32(now.Add(-time.Hour).Unix()),
+			Expiration: uint32(now.Add(24 * time.Hour).Unix()),
+		}
+		if err := sig.Sign(k.priv, s.rrs); err != nil {
+			t.Fatal(err)
+		}
+		if err := z.Insert(sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return z
+}
+
+// upstream serves zones like a recursive resolver would: DS queries are answered by the parent zone.
+func upstream(zones map[string]*file.Zone) plugin.Handler {
+	names := []string{}
+	for name := range zones {
+		names = append(names, name)
+	}
+	f := file.File{Next: test.ErrorHandler(), Zones: file.Zones{Z: zones, Names: names}}
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		qname := r.Question[0].Name
+		if _, ok := zones[qname]; ok && r.Question[0].Qtype == dns.TypeDS {
+			i, _ := dns.NextLabel(qname, 0)
+			parent := file.File{Next: test.ErrorHandler(), Zones: file.Zones{Z: zones, Names: []string{plugin.Zones(names).Matches(qname[i:])}}}
+			return parent.ServeDNS(ctx, w, r)
+		}
+		return f.ServeDNS(ctx, w, r)
+	})
+}
+
+// testZones returns the test zones, and the key example.org. is signed with.
+func testZones(t *testing.T) (map[string]*file.Zone, key) {
+	t.Helper()
+	k, sk := newKey(t, "example.org."), newKey(t, "secure.example.org.")
+	ds := sk.ToDS(dns.SHA256)
+	ds.Hdr.Ttl = 3600
+	zones := map[string]*file.Zone{
+		"example.org.":          signZone(t, "example.org.", dbExampleOrg, &k, ds),
+		"secure.example.org.":   signZone(t, "secure.example.org.", dbSecureExampleOrg, &sk),
+		"insecure.example.org.": signZone(t, "insecure.example.org.", dbInsecureExampleOrg, nil),
+	}
+	// Change the data, after it has been signed.
+	e, _ := zones["example.org."].Search("bad.example.org.")
+	e.Type(dns.TypeA)[0].(*dns.A).A[3] = 99
+	return zones, k
+}
+
+func TestValidate(t *testing.T) {
+	zones, k := testZones(t)
+	v := New([]string{"."}, map[string][]dns.RR{"example.org.": {k.ToDS(dns.SHA256)}}, defaultCap, upstream(zones))
+
+	tests := []struct {
+		qname string
+		qtype uint16
+		rcode int
+		ad    bool
+		ede   int // expected Extended DNS Error, -1 for none
+	}{
+		{"a.example.org.", dns.TypeA, dns.RcodeSuccess, true, -1},
+		{"a.example.org.", dns.TypeTXT, dns.RcodeSuccess, true, -1},
+		{"x.example.org.", dns.TypeA, dns.RcodeNameError, true, -1},
+		{"foo.w.example.org.", dns.TypeA, dns.RcodeSuccess, true, -1},
+		{"a.d.example.org.", dns.TypeA, dns.RcodeSuccess, true, -1},
+		{"www.secure.example.org.", dns.TypeA, dns.RcodeSuccess, true, -1},
+		{"x.secure.example.org.", dns.TypeA, dns.RcodeNameError, true, -1},
+		{"www.insecure.example.org.", dns.TypeA, dns.RcodeSuccess, false, -1},
+		{"bad.example.org.", dns.TypeA, dns.RcodeServerFailure, false, int(dns.ExtendedErrorCodeDNSBogus)},
+	}
+
+	for _, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		m.SetEdns0(4096, true)
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := v.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("%s %s: %s", tc.qname, dns.TypeToString[tc.qtype], err)
+		}
+		resp := rec.Msg
+		if resp.Rcode != tc.rcode {
+			t.Errorf("%s %s: expected rcode %s, got %s", tc.qname, dns.TypeToString[tc.qtype], dns.RcodeToString[tc.rcode], dns.RcodeToString[resp.Rcode])
+		}
+		if resp.AuthenticatedData != tc.ad {
+			t.Errorf("%s %s: expected AD bit %t, got %t", tc.qname, dns.TypeToString[tc.qtype], tc.ad, resp.AuthenticatedData)
+		}
+		ede := edns.ExtendedError(resp)
+		switch {
+		case tc.ede == -1 && ede != nil:
+			t.Errorf("%s %s: expected no extended error, got %d: %s", tc.qname, dns.TypeToString[tc.qtype], ede.InfoCode, ede.ExtraText)
+		case tc.ede != -1 && (ede == nil || ede.InfoCode != uint16(tc.ede)):
+			t.Errorf("%s %s: expected extended error %d, got %v", tc.qname, dns.TypeToString[tc.qtype], tc.ede, ede)
+		}
+	}
+}
+
+func TestValidateBogus(t *testing.T) {
+	zones, k := testZones(t)
+	other := newKey(t, "example.org.")
+
+	tests := []struct {
+		name    string
+		qname   string
+		anchors map[string][]dns.RR
+		now     time.Time
+		ede     uint16
+	}{
+		{"expired", "a.example.org.", map[string][]dns.RR{"example.org.": {k.DNSKEY}}, time.Now().Add(48 * time.Hour), dns.ExtendedErrorCodeSignatureExpired},
+		{"not yet valid", "a.example.org.", map[string][]dns.RR{"example.org.": {k.DNSKEY}}, time.Now().Add(-48 * time.Hour), dns.ExtendedErrorCodeSignatureNotYetValid},
+		{"wrong anchor", "a.example.org.", map[string][]dns.RR{"example.org.": {other.ToDS(dns.SHA256)}}, time.Now(), dns.ExtendedErrorCodeDNSKEYMissing},
+		{"stripped signatures", "ns.example.org.", map[string][]dns.RR{"example.org.": {k.DNSKEY}}, time.Now(), dns.ExtendedErrorCodeRRSIGsMissing},
+		{"stripped proof", "x.example.org.", map[string][]dns.RR{"example.org.": {k.DNSKEY}}, time.Now(), dns.ExtendedErrorCodeNSECMissing},
+		{"forged CNAME", "a.d.example.org.", map[string][]dns.RR{"example.org.": {k.DNSKEY}}, time.Now(), dns.ExtendedErrorCodeDNSBogus},
+	}
+	for _, tc := range tests {
+		next := upstream(zones)
+		// An attacker between us and the upstream removes the DNSSEC records for ns.example.org and the
+		// NSEC records for x.example.org, and changes the CNAME synthesized for a.d.example.org.
+		strip := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			nw := nonwriter.New(w)
+			rcode, err := next.ServeDNS(ctx, nw, r)
+			switch {
+			case r.Question[0].Qtype != dns.TypeA:
+			case r.Question[0].Name == "ns.example.org.":
+				nw.Msg.Answer = nw.Msg.Answer[:1]
+			case r.Question[0].Name == "x.example.org.":
+				nw.Msg.Ns = nw.Msg.Ns[:2] // SOA and its RRSIG
+			case r.Question[0].Name == "a.d.example.org.":
+				nw.Msg.Answer[2].(*dns.CNAME).Target = "a.example.net."
+				nw.Msg.Answer = nw.Msg.Answer[:3] // DNAME, its RRSIG and the CNAME
+			}
+			w.WriteMsg(nw.Msg)
+			return rcode, err
+		})
+		v := New([]string{"."}, tc.anchors, defaultCap, strip)
+		v.now = func() time.Time { return tc.now }
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		m.SetEdns0(4096, false)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		v.ServeDNS(context.TODO(), rec, m)
+		if rec.Msg.Rcode != dns.RcodeServerFailure {
+			t.Errorf("Test %q: expected SERVFAIL, got %s", tc.name, dns.RcodeToString[rec.Msg.Rcode])
+		}
+		if ede := edns.ExtendedError(rec.Msg); ede == nil || ede.InfoCode != tc.ede {
+			t.Errorf("Test %q: expected extended error %d, got %v", tc.name, tc.ede, ede)
+		}
+	}
+}
+
+func TestValidateStrip(t *testing.T) {
+	zones, k := testZones(t)
+	v := New([]string{"."}, map[string][]dns.RR{"example.org.": {k.DNSKEY}}, defaultCap, upstream(zones))
+
+	// Without the DO bit the DNSSEC records are removed, and AD is only set when asked for.
+	m := new(dns.Msg)
+	m.SetQuestion("a.example.org.", dns.TypeA)
+	m.AuthenticatedData = true
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	v.ServeDNS(context.TODO(), rec, m)
+	if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].Header().Rrtype != dns.TypeA {
+		t.Errorf("Expected only the A record, got %v", rec.Msg.Answer)
+	}
+	if !rec.Msg.AuthenticatedData {
+		t.Errorf("Expected AD bit to be set")
+	}
+	if rec.Msg.IsEdns0() != nil {
+		t.Errorf("Expected no OPT record")
+	}
+
+	// With the CD bit bogus data is returned, the client validates.
+	m = new(dns.Msg)
+	m.SetQuestion("bad.example.org.", dns.TypeA)
+	m.SetEdns0(4096, true)
+	m.CheckingDisabled = true
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	v.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeSuccess || len(rec.Msg.Answer) != 2 {
+		t.Errorf("Expected bogus answer with its signature, got %v", rec.Msg)
+	}
+}
+
+func TestValidateTruncated(t *testing.T) {
+	zones, k := testZones(t)
+	next := upstream(zones)
+
+	for _, always := range []bool{false, true} {
+		// Responses over UDP are truncated, and with always over TCP as well.
+		truncate := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			nw := nonwriter.New(w)
+			rcode, err := next.ServeDNS(ctx, nw, r)
+			if _, udp := w.RemoteAddr().(*net.UDPAddr); udp || always {
+				nw.Msg.Truncated = true
+				nw.Msg.Answer, nw.Msg.Ns = nil, nil
+			}
+			w.WriteMsg(nw.Msg)
+			return rcode, err
+		})
+		v := New([]string{"."}, map[string][]dns.RR{"example.org.": {k.DNSKEY}}, defaultCap, truncate)
+
+		m := new(dns.Msg)
+		m.SetQuestion("a.example.org.", dns.TypeA)
+		m.SetEdns0(4096, true)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		v.ServeDNS(context.TODO(), rec, m)
+		if always {
+			if rec.Msg.Rcode != dns.RcodeServerFailure {
+				t.Errorf("Expected SERVFAIL for a response that is always truncated, got %s", dns.RcodeToString[rec.Msg.Rcode])
+			}
+			continue
+		}
+		if rec.Msg.Truncated || !rec.Msg.AuthenticatedData || len(rec.Msg.Answer) != 2 {
+			t.Errorf("Expected the validated response retried over TCP, got %v", rec.Msg)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// result is the security status of a response, see RFC 4035 section 4.3. Bogus responses are returned as an error.
+type result int
+
+const (
+	secure   result = iota // Chain of trust from a trust anchor to the data.
+	insecure               // Proven that there is no chain of trust to the data.
+)
+
+func (r result) String() string {
+	if r == secure {
+		return "secure"
+	}
+	return "insecure"
+}
+
+// bogusError is the reason validation failed. The code is the Extended DNS Error (RFC 8914) to return.
+type bogusError struct {
+	code uint16
+	text string
+}
+
+func (e *bogusError) Error() string { return e.text }
+
+func bogusf(code uint16, format string, a ...any) error {
+	return &bogusError{code: code, text: fmt.Sprintf(format, a...)}
+}
+
+// rrset is an RRset and the signatures covering it.
+type rrset struct {
+	rrs  []dns.RR
+	sigs []*dns.RRSIG
+}
+
+func (s *rrset) name() string   { return s.rrs[0].Header().Name }
+func (s *rrset) rrtype() uint16 { return s.rrs[0].Header().Rrtype }
+
+// rrsets groups the records in rrs in RRsets and adds the signatures covering them, in the order the RRsets
+// appear in rrs. Signatures that cover none of the RRsets are dropped.
+func rrsets(rrs []dns.RR) []*rrset {
+	type key struct {
+		name   string
+		rrtype uint16
+	}
+	sets := []*rrset{}
+	index := map[key]*rrset{}
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeRRSIG {
+			continue
+		}
+		k := key{strings.ToLower(rr.Header().Name), rr.Header().Rrtype}
+		if s, ok := index[k]; ok {
+			s.rrs = append(s.rrs, rr)
+			continue
+		}
+		s := &rrset{rrs: []dns.RR{rr}}
+		index[k] = s
+		sets = append(sets, s)
+	}
+	for _, rr := range rrs {
+		sig, ok := rr.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+		if s, ok := index[key{strings.ToLower(sig.Header().Name), sig.TypeCovered}]; ok {
+			s.sigs = append(s.sigs, sig)
+		}
+	}
+	return sets
+}
+
+// verifyRRset verifies the signatures of s with keys at now. At least one signature must be valid.
+func verifyRRset(s *rrset, keys []*dns.DNSKEY, now time.Time) error {
+	name, typ := s.name(), dns.TypeToString[s.rrtype()]
+	if len(s.sigs) == 0 {
+		return bogusf(dns.ExtendedErrorCodeRRSIGsMissing, "No signatures for %s %s", name, typ)
+	}
+	var err error
+	for _, sig := range s.sigs {
+		for _, k := range keys {
+			if k.KeyTag() != sig.KeyTag || k.Algorithm != sig.Algorithm || !strings.EqualFold(k.Header().Name, sig.SignerName) {
+				continue
+			}
+			if e := sig.Verify(k, s.rrs); e != nil {
+				err = bogusf(dns.ExtendedErrorCodeDNSBogus, "Signature of %s %s by key %d doesn't verify: %s", name, typ, sig.KeyTag, e)
+				continue
+			}
+			if !sig.ValidityPeriod(now) {
+				err = bogusf(dns.ExtendedErrorCodeSignatureNotYetValid, "Signature of %s %s by key %d is not yet valid", name, typ, sig.KeyTag)
+				if now.Unix() > int64(sig.Expiration) {
+					err = bogusf(dns.ExtendedErrorCodeSignatureExpired, "Signature of %s %s by key %d has expired", name, typ, sig.KeyTag)
+				}
+				continue
+			}
+			return nil
+		}
+	}
+	if err == nil {
+		return bogusf(dns.ExtendedErrorCodeDNSKEYMissing, "No DNSKEY for the signatures of %s %s by %s", name, typ, s.sigs[0].SignerName)
+	}
+	return err
+}
+
+// validate validates m, the response to r. The records in m are verified and a negative response must
+// prove that the name or type doesn't exist.
+func (v *Validate) validate(ctx context.Context, w dns.ResponseWriter, r, m *dns.Msg) (result, error) {
+	// NS records in the authority section are not signed by the parent, and not needed for validation.
+	auth := []dns.RR{}
+	for _, rr := range m.Ns {
+		if rr.Header().Rrtype != dns.TypeNS {
+			auth = append(auth, rr)
+		}
+	}
+	sets := append(rrsets(m.Answer), rrsets(auth)...)
+	dnames, owners := []*dns.DNAME{}, []string{}
+	for _, s := range sets {
+		if s.rrtype() == dns.TypeDNAME {
+			dnames = append(dnames, s.rrs[0].(*dns.DNAME))
+			owners = append(owners, s.name())
+		}
+	}
+
+	res := secure
+	for _, s := range sets {
+		if s.rrtype() == dns.TypeCNAME && len(s.sigs) == 0 && below(s.name(), owners) {
+			// An unsigned CNAME is synthesized from a DNAME, which is verified itself. Its target must be
+			// the owner name with the DNAME substituted, see RFC 6672 section 5.3.3.
+			if !synthesized(s.rrs[0].(*dns.CNAME), dnames) {
+				return insecure, bogusf(dns.ExtendedErrorCodeDNSBogus, "Unsigned CNAME %s doesn't match a DNAME", s.name())
+			}
+			continue
+		}
+		sres, err := v.verify(ctx, w, s, 0)
+		if err != nil {
+			return insecure, err
+		}
+		if sres == insecure {
+			res = insecure
+		}
+	}
+	if res == insecure {
+		return insecure, nil
+	}
+
+	// Follow the CNAMEs in the answer to the name that holds, or doesn't hold, the data.
+	qname, qtype := strings.ToLower(r.Question[0].Name), r.Question[0].Qtype
+	answered := false
+	d := newDenial(auth)
+	for _, s := range rrsets(m.Answer) {
+		if !strings.EqualFold(s.name(), qname) {
+			continue
+		}
+		// A signature with fewer labels than the owner name means the answer was synthesized from a
+		// wildcard, that the owner name doesn't exist must be proven.
+		if labels := uint8(dns.CountLabel(s.name())); len(s.sigs) > 0 && s.sigs[0].Labels < labels {
+			if !d.wildcard(s.name(), s.sigs[0].Labels) {
+				return v.unproven(ctx, w, qname, "No proof that %s, synthesized from a wildcard, doesn't exist", s.name())
+			}
+		}
+		switch {
+		case s.rrtype() == qtype:
+			answered = true
+		case s.rrtype() == dns.TypeCNAME && qtype != dns.TypeCNAME:
+			qname = strings.ToLower(s.rrs[0].(*dns.CNAME).Target)
+		}
+	}
+	if answered {
+		return d.result(), nil
+	}
+
+	switch {
+	case m.Rcode == dns.RcodeNameError:
+		if !d.nxdomain(qname) {
+			return v.unproven(ctx, w, qname, "No proof that %s doesn't exist", qname)
+		}
+	default:
+		if !d.nodata(qname, qtype) {
+			return v.unproven(ctx, w, qname, "No proof that %s %s doesn't exist", qname, dns.TypeToString[qtype])
+		}
+	}
+	return d.result(), nil
+}
+
+// unproven is called when the denial of existence for name is missing. That is fine if name is in an
+// insecure zone, otherwise the response is bogus.
+func (v *Validate) unproven(ctx context.Context, w dns.ResponseWriter, name, format string, a ...any) (result, error) {
+	ok, err := v.insecure(ctx, w, name, 0)
+	if err != nil {
+		return insecure, err
+	}
+	if ok {
+		return insecure, nil
+	}
+	return insecure, bogusf(dns.ExtendedErrorCodeNSECMissing, format, a...)
+}
+
+// verify verifies the signatures of s. If s isn't signed, its owner name must be in an insecure zone.
+func (v *Validate) verify(ctx context.Context, w dns.ResponseWriter, s *rrset, depth int) (result, error) {
+	if len(s.sigs) == 0 {
+		ok, err := v.insecure(ctx, w, s.name(), depth)
+		if err != nil {
+			return insecure, err
+		}
+		if !ok {
+			return insecure, bogusf(dns.ExtendedErrorCodeRRSIGsMissing, "No signatures for %s %s", s.name(), dns.TypeToString[s.rrtype()])
+		}
+		return insecure, nil
+	}
+
+	// The RRset must be signed by its own zone, which the owner name is in.
+	signer := strings.ToLower(s.sigs[0].SignerName)
+	if !dns.IsSubDomain(signer, s.name()) {
+		return insecure, bogusf(dns.ExtendedErrorCodeDNSBogus, "Signer %s of %s %s is not an ancestor", signer, s.name(), dns.TypeToString[s.rrtype()])
+	}
+	zk, err := v.zoneKeys(ctx, w, signer, depth)
+	if err != nil {
+		return insecure, err
+	}
+	if zk.insecure() {
+		return insecure, nil
+	}
+	if err := verifyRRset(s, zk.keys, v.now()); err != nil {
+		return insecure, err
+	}
+	return secure, nil
+}
+
+// synthesized returns true if cname is the CNAME synthesized from one of the DNAME records in dnames: its
+// owner name is below the DNAME and its target is the owner name with the DNAME's owner replaced by its
+// target.
+func synthesized(cname *dns.CNAME, dnames []*dns.DNAME) bool {
+	name := strings.ToLower(cname.Hdr.Name)
+	for _, d := range dnames {
+		if !below(name, []string{d.Hdr.Name}) {
+			continue
+		}
+		target := name[:len(name)-len(d.Hdr.Name)]
+		if d.Target != "." {
+			target += strings.ToLower(d.Target)
+		}
+		if strings.EqualFold(cname.Target, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// below returns true if name is below one of the names in ancestors.
+func below(name string, ancestors []string) bool {
+	for _, a := range ancestors {
+		if !strings.EqualFold(a, name) && dns.IsSubDomain(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares the names a and b in canonical order, see RFC 4034 section 6.1.
+func compare(a, b string) int {
+	la, lb := dns.SplitDomainName(strings.ToLower(a)), dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
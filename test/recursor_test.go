@@ -0,0 +1,149 @@
+package test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const recursorRoot = `$TTL 3600
+.                   IN SOA a.root-servers.test. admin.test. 1 3600 600 86400 300
+.                   IN NS  a.root-servers.test.
+a.root-servers.test. IN A  127.0.0.1
+org.                IN NS  ns.org.
+ns.org.             IN A   127.0.0.2
+`
+
+const recursorOrg = `$TTL 3600
+$ORIGIN org.
+@              IN SOA ns.org. admin.org. 1 3600 600 86400 300
+               IN NS  ns
+ns             IN A   127.0.0.2
+example        IN NS  ns.example
+ns.example     IN A   127.0.0.3
+`
+
+const recursorExampleOrg = `$TTL 300
+$ORIGIN example.org.
+@              IN SOA ns admin 1 3600 600 86400 300
+               IN NS  ns
+ns             IN A   127.0.0.3
+www            IN A   192.0.2.1
+alias          IN CNAME www
+a.b.c          IN A   192.0.2.2
+`
+
+// freePort returns a port that is free on 127.0.0.1 for both UDP and TCP.
+func freePort(t *testing.T) string {
+	t.Helper()
+	for range 10 {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, port, _ := net.SplitHostPort(l.Addr().String())
+		pc, err := net.ListenPacket("udp", "127.0.0.1:"+port)
+		l.Close()
+		if err == nil {
+			pc.Close()
+			return port
+		}
+	}
+	t.Fatal("Could not find a free port")
+	return ""
+}
+
+func TestRecursorHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"db.root":        recursorRoot,
+		"db.org":         recursorOrg,
+		"db.example.org": recursorExampleOrg,
+		"named.root":     ". NS a.root-servers.test.\na.root-servers.test. A 127.0.0.1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The root, org and example.org name servers each listen on their own address, on the same port.
+	port := freePort(t)
+	corefile := `.:` + port + ` {
+		bind 127.0.0.1
+		file ` + filepath.Join(dir, "db.root") + ` .
+	}
+	org:` + port + ` {
+		bind 127.0.0.2
+		file ` + filepath.Join(dir, "db.org") + `
+	}
+	example.org:` + port + ` {
+		bind 127.0.0.3
+		file ` + filepath.Join(dir, "db.example.org") + `
+	}`
+	auth, err := CoreDNSServer(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS authoritative instance: %s", err)
+	}
+	defer auth.Stop()
+
+	corefile = `.:0 {
+		cache
+		recursor {
+			root_hints ` + filepath.Join(dir, "named.root") + `
+			port ` + port + `
+		}
+	}`
+	resolver, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS recursor instance: %s", err)
+	}
+	defer resolver.Stop()
+
+	tests := []test.Case{
+		{
+			Qname: "www.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("www.example.org. 300 IN A 192.0.2.1")},
+		},
+		{
+			Qname: "alias.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("alias.example.org. 300 IN CNAME www.example.org."),
+				test.A("www.example.org. 300 IN A 192.0.2.1"),
+			},
+		},
+		{
+			Qname: "a.b.c.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("a.b.c.example.org. 300 IN A 192.0.2.2")},
+		},
+		{
+			Qname: "nx.example.org.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300")},
+		},
+	}
+	// The second round is answered from the cache.
+	for i := range 2 {
+		for _, tc := range tests {
+			resp, err := dns.Exchange(tc.Msg(), udp)
+			if err != nil {
+				t.Fatalf("Expected to receive reply for %s, but got: %s", tc.Qname, err)
+			}
+			if !resp.RecursionAvailable {
+				t.Errorf("Expected RA bit for %s", tc.Qname)
+			}
+			if err := test.Header(tc, resp); err != nil {
+				t.Errorf("Round %d, %s: %s", i, tc.Qname, err)
+				continue
+			}
+			if len(resp.Answer) != len(tc.Answer) {
+				t.Errorf("Round %d, %s: expected %d answers, got %d", i, tc.Qname, len(tc.Answer), len(resp.Answer))
+			}
+		}
+	}
+}
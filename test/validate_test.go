@@ -0,0 +1,186 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+
+	"github.com/miekg/dns"
+)
+
+const validateExampleOrg = `$TTL 3600
+$ORIGIN example.org.
+@        IN SOA ns admin 1 3600 600 86400 300
+         IN NS  ns
+ns       IN A   192.0.2.53
+www      IN A   192.0.2.1
+bad      IN A   192.0.2.2
+secure   IN NS  ns.secure
+ns.secure IN A  192.0.2.54
+insecure IN NS  ns.insecure
+ns.insecure IN A 192.0.2.55
+`
+
+const validateSecureExampleOrg = `$TTL 3600
+$ORIGIN secure.example.org.
+@        IN SOA ns admin 1 3600 600 86400 300
+         IN NS  ns
+ns       IN A   192.0.2.54
+www      IN A   192.0.2.3
+`
+
+const validateInsecureExampleOrg = `$TTL 3600
+$ORIGIN insecure.example.org.
+@        IN SOA ns admin 1 3600 600 86400 300
+         IN NS  ns
+ns       IN A   192.0.2.55
+www      IN A   192.0.2.4
+`
+
+// writeKey generates a CSK for origin and writes it to dir, it returns the base name of the key files.
+func writeKey(t *testing.T, dir, origin string) (*dns.DNSKEY, string) {
+	t.Helper()
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(dir, "K"+origin)
+	if err := os.WriteFile(base+".key", []byte(k.String()+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".private", []byte(k.PrivateKeyString(priv)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return k, base
+}
+
+func TestValidateSignedZones(t *testing.T) {
+	dir := t.TempDir()
+	key, keyfile := writeKey(t, dir, "example.org.")
+	secureKey, secureKeyfile := writeKey(t, dir, "secure.example.org.")
+
+	ds := secureKey.ToDS(dns.SHA256)
+	zones := map[string]string{
+		"db.example.org":          validateExampleOrg + ds.String() + "\n",
+		"db.secure.example.org":   validateSecureExampleOrg,
+		"db.insecure.example.org": validateInsecureExampleOrg,
+	}
+	for name, db := range zones {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(db), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Sign the zones, example.org with NSEC and secure.example.org with NSEC3.
+	corefile := `.:0 {
+		sign ` + filepath.Join(dir, "db.example.org") + ` example.org {
+			key file ` + keyfile + `
+			directory ` + dir + `
+		}
+		sign ` + filepath.Join(dir, "db.secure.example.org") + ` secure.example.org {
+			key file ` + secureKeyfile + `
+			directory ` + dir + `
+			nsec3
+		}
+	}`
+	i, err := CoreDNSServer(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS signing instance: %s", err)
+	}
+	signed := []string{filepath.Join(dir, "db.example.org.signed"), filepath.Join(dir, "db.secure.example.org.signed")}
+	for _, name := range signed {
+		for range 50 {
+			if _, err := os.Stat(name); err == nil {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	i.Stop()
+
+	// Change the data of bad.example.org after it has been signed.
+	buf, err := os.ReadFile(signed[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(signed[0], []byte(strings.Replace(string(buf), "192.0.2.2", "192.0.2.99", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	corefile = `example.org:0 {
+		file ` + signed[0] + `
+	}
+	secure.example.org:0 {
+		file ` + signed[1] + `
+	}
+	insecure.example.org:0 {
+		file ` + filepath.Join(dir, "db.insecure.example.org") + `
+	}`
+	auth, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS authoritative instance: %s", err)
+	}
+	defer auth.Stop()
+
+	anchor := filepath.Join(dir, "anchor")
+	if err := os.WriteFile(anchor, []byte(key.String()+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	corefile = `.:0 {
+		validate {
+			trust_anchor ` + anchor + `
+		}
+		forward . ` + udp + `
+	}`
+	resolver, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS validating instance: %s", err)
+	}
+	defer resolver.Stop()
+
+	tests := []struct {
+		qname string
+		rcode int
+		ad    bool
+		ede   int // expected Extended DNS Error, -1 for none
+	}{
+		{"www.example.org.", dns.RcodeSuccess, true, -1},
+		{"nx.example.org.", dns.RcodeNameError, true, -1},
+		{"www.secure.example.org.", dns.RcodeSuccess, true, -1},
+		{"nx.secure.example.org.", dns.RcodeNameError, true, -1},
+		{"www.insecure.example.org.", dns.RcodeSuccess, false, -1},
+		{"bad.example.org.", dns.RcodeServerFailure, false, int(dns.ExtendedErrorCodeDNSBogus)},
+	}
+	for _, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		m.SetEdns0(4096, true)
+		resp, err := dns.Exchange(m, udp)
+		if err != nil {
+			t.Fatalf("Expected to receive reply for %s, but got: %s", tc.qname, err)
+		}
+		if resp.Rcode != tc.rcode {
+			t.Errorf("%s: expected rcode %s, got %s", tc.qname, dns.RcodeToString[tc.rcode], dns.RcodeToString[resp.Rcode])
+		}
+		if resp.AuthenticatedData != tc.ad {
+			t.Errorf("%s: expected AD bit %t, got %t", tc.qname, tc.ad, resp.AuthenticatedData)
+		}
+		ede := edns.ExtendedError(resp)
+		switch {
+		case tc.ede == -1 && ede != nil:
+			t.Errorf("%s: expected no extended error, got %d: %s", tc.qname, ede.InfoCode, ede.ExtraText)
+		case tc.ede != -1 && (ede == nil || ede.InfoCode != uint16(tc.ede)):
+			t.Errorf("%s: expected extended error %d, got %v", tc.qname, tc.ede, ede)
+		}
+	}
+}